@@ -0,0 +1,134 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+const (
+	// AddonProfileLabelName is the label signifying which AddonProfile a HelmChartProxy was expanded from.
+	AddonProfileLabelName = "addonprofile.addons.cluster.x-k8s.io/name"
+
+	// AddonProfileChartLabelName is the label signifying which chart of its AddonProfile a HelmChartProxy was expanded from.
+	AddonProfileChartLabelName = "addonprofile.addons.cluster.x-k8s.io/chart"
+)
+
+// AddonProfileSpec defines the desired state of AddonProfile.
+type AddonProfileSpec struct {
+	// ClusterSelector selects Clusters in the same namespace with a label that matches the specified label selector. The
+	// charts of the profile are installed on all selected Clusters.
+	ClusterSelector metav1.LabelSelector `json:"clusterSelector"`
+
+	// Charts are the charts of the profile. Each chart is expanded into a HelmChartProxy named after the profile and the
+	// chart, and a chart removed from the profile is uninstalled along with its HelmChartProxy.
+	// +kubebuilder:validation:MinItems=1
+	// +listType=map
+	// +listMapKey=name
+	Charts []AddonProfileChart `json:"charts"`
+}
+
+// AddonProfileChart defines a chart of an AddonProfile.
+type AddonProfileChart struct {
+	// Name is the name of the chart in the profile. The HelmChartProxy of the chart is named `<profile name>-<name>`.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+
+	// Weight orders the charts of the profile. The HelmChartProxies of charts with a higher weight are only created or
+	// updated once the HelmChartProxies of all charts with a lower weight are ready, e.g. to install a CNI before the
+	// addons depending on it. Charts with the same weight are rolled out together. Defaults to 0.
+	// +optional
+	Weight int32 `json:"weight,omitempty"`
+
+	// Template is the spec of the HelmChartProxy of the chart. Its clusterSelector is always the ClusterSelector of the
+	// profile.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Template runtime.RawExtension `json:"template"`
+}
+
+// AddonProfileStatus defines the observed state of AddonProfile.
+type AddonProfileStatus struct {
+	// Conditions defines current state of the AddonProfile.
+	// +optional
+	Conditions clusterv1.Conditions `json:"conditions,omitempty"`
+
+	// Charts are the states of the HelmChartProxies of the charts of the profile, by ascending weight.
+	// +optional
+	Charts []AddonProfileChartStatus `json:"charts,omitempty"`
+
+	// ObservedGeneration is the latest generation observed by the controller.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// AddonProfileChartStatus describes the HelmChartProxy of a chart of an AddonProfile.
+type AddonProfileChartStatus struct {
+	// Name is the name of the chart in the profile.
+	Name string `json:"name"`
+
+	// HelmChartProxyName is the name of the HelmChartProxy of the chart. It is not set until the HelmChartProxy is created.
+	// +optional
+	HelmChartProxyName string `json:"helmChartProxyName,omitempty"`
+
+	// Ready is true if the HelmChartProxy of the chart is ready and reconciled from its latest spec.
+	Ready bool `json:"ready"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="Reason",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].reason"
+// +kubebuilder:printcolumn:name="Message",type="string",priority=1,JSONPath=".status.conditions[?(@.type=='Ready')].message"
+// +kubebuilder:resource:shortName=ap
+
+// AddonProfile is the Schema for the addonprofiles API. It bundles the charts installed on a fleet of Clusters, e.g. the
+// addons of a platform profile, into a single object with a single ClusterSelector. The controller expands each chart into
+// a HelmChartProxy, rolls them out by weight and aggregates their readiness.
+type AddonProfile struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AddonProfileSpec   `json:"spec,omitempty"`
+	Status AddonProfileStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// AddonProfileList contains a list of AddonProfile.
+type AddonProfileList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AddonProfile `json:"items"`
+}
+
+// GetConditions returns the list of conditions for an AddonProfile API object.
+func (p *AddonProfile) GetConditions() clusterv1.Conditions {
+	return p.Status.Conditions
+}
+
+// SetConditions will set the given conditions on an AddonProfile object.
+func (p *AddonProfile) SetConditions(conditions clusterv1.Conditions) {
+	p.Status.Conditions = conditions
+}
+
+func init() {
+	SchemeBuilder.Register(&AddonProfile{}, &AddonProfileList{})
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// ChartBundleSpec defines the desired state of ChartBundle.
+type ChartBundleSpec struct {
+	// Source is the location of the bundle tarball in the management cluster. The tarball is a tar archive, optionally
+	// gzipped, of Helm chart archives as created by `helm package`, e.g. `cert-manager-v1.14.4.tgz`. Exactly one source
+	// must be specified. Bundles stored on a PersistentVolumeClaim can be served from an OCI registry running in the
+	// management cluster.
+	Source ChartBundleSource `json:"source"`
+}
+
+// ChartBundleSource defines where the bundle tarball of a ChartBundle is stored.
+// +kubebuilder:validation:XValidation:rule="has(self.secret) != has(self.oci)",message="exactly one of secret or oci must be specified"
+type ChartBundleSource struct {
+	// Secret is a Secret in the namespace of the ChartBundle holding the bundle tarball. Secrets are limited to 1 MiB, so
+	// this is only suitable for small bundles.
+	// +optional
+	Secret *ChartBundleSecretSource `json:"secret,omitempty"`
+
+	// OCI is an OCI artifact holding the bundle tarball as its only layer, e.g. in a registry running in the management
+	// cluster.
+	// +optional
+	OCI *ChartBundleOCISource `json:"oci,omitempty"`
+}
+
+// ChartBundleSecretSource references a key of a Secret holding a bundle tarball.
+type ChartBundleSecretSource struct {
+	// Name is the name of the Secret.
+	Name string `json:"name"`
+
+	// Key is the key in the Secret holding the bundle tarball.
+	Key string `json:"key"`
+}
+
+// ChartBundleOCISource references an OCI artifact holding a bundle tarball.
+type ChartBundleOCISource struct {
+	// URL is the reference of the OCI artifact, e.g. `oci://registry.local:5000/bundles/addons:v1`.
+	// +kubebuilder:validation:Pattern=`^oci://`
+	URL string `json:"url"`
+
+	// Credentials is a reference to an object containing the OCI credentials. If it is not specified, no credentials will
+	// be used.
+	// +optional
+	Credentials *Credentials `json:"credentials,omitempty"`
+
+	// InsecureSkipTLSVerify controls whether the certificate of the registry is verified.
+	// +optional
+	InsecureSkipTLSVerify bool `json:"insecureSkipTLSVerify,omitempty"`
+}
+
+// ChartBundleStatus defines the observed state of ChartBundle.
+type ChartBundleStatus struct {
+	// Conditions defines current state of the ChartBundle.
+	// +optional
+	Conditions clusterv1.Conditions `json:"conditions,omitempty"`
+
+	// Charts are the chart versions found in the bundle tarball.
+	// +optional
+	Charts []ChartBundleEntry `json:"charts,omitempty"`
+
+	// ObservedGeneration is the latest generation observed by the controller.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// ChartBundleEntry describes a chart version contained in a bundle tarball.
+type ChartBundleEntry struct {
+	// Name is the name of the chart.
+	Name string `json:"name"`
+
+	// Version is the version of the chart.
+	Version string `json:"version"`
+
+	// Digest is the digest of the chart archive, e.g. `sha256:<hex>`.
+	Digest string `json:"digest"`
+}
+
+// ChartBundleReference references a ChartBundle.
+type ChartBundleReference struct {
+	// Name is the name of the ChartBundle.
+	Name string `json:"name"`
+
+	// Namespace is the namespace of the ChartBundle. If it is not specified, the namespace of the HelmChartProxy is used.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="Reason",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].reason"
+// +kubebuilder:printcolumn:name="Message",type="string",priority=1,JSONPath=".status.conditions[?(@.type=='Ready')].message"
+// +kubebuilder:resource:shortName=cb
+
+// ChartBundle is the Schema for the chartbundles API. It makes the Helm charts of a tarball stored in the management
+// cluster available to HelmChartProxies, so addons can be installed without access to any chart repository.
+type ChartBundle struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ChartBundleSpec   `json:"spec,omitempty"`
+	Status ChartBundleStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ChartBundleList contains a list of ChartBundle.
+type ChartBundleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ChartBundle `json:"items"`
+}
+
+// GetConditions returns the list of conditions for a ChartBundle API object.
+func (b *ChartBundle) GetConditions() clusterv1.Conditions {
+	return b.Status.Conditions
+}
+
+// SetConditions will set the given conditions on a ChartBundle object.
+func (b *ChartBundle) SetConditions(conditions clusterv1.Conditions) {
+	b.Status.Conditions = conditions
+}
+
+// Entry returns the entry of the chart version in the bundle, or false if the bundle does not contain it.
+func (b *ChartBundle) Entry(chartName, version string) (ChartBundleEntry, bool) {
+	for _, entry := range b.Status.Charts {
+		if entry.Name == chartName && entry.Version == version {
+			return entry, true
+		}
+	}
+
+	return ChartBundleEntry{}, false
+}
+
+func init() {
+	SchemeBuilder.Register(&ChartBundle{}, &ChartBundleList{})
+}
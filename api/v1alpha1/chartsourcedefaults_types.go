@@ -0,0 +1,83 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ChartSourceDefaultsName is the name of the ChartSourceDefaults of a namespace. Only the ChartSourceDefaults with this name
+// applies to the HelmChartProxies of its namespace.
+const ChartSourceDefaultsName = "default"
+
+// ChartSourceDefaultsSpec defines the desired state of ChartSourceDefaults. Each field applies to the HelmChartProxies in
+// the namespace of the ChartSourceDefaults that do not specify it themselves.
+type ChartSourceDefaultsSpec struct {
+	// Credentials is a reference to an object containing the OCI credentials. If the namespace of the Secret is not
+	// specified, the namespace of the ChartSourceDefaults is used.
+	// +optional
+	Credentials *Credentials `json:"credentials,omitempty"`
+
+	// RepositoryHeaders is a reference to a Secret whose keys and values are sent as extra HTTP headers with every request
+	// to an HTTP chart repository. If the namespace is not specified, the namespace of the ChartSourceDefaults is used.
+	// +optional
+	RepositoryHeaders *corev1.SecretReference `json:"repositoryHeaders,omitempty"`
+
+	// RepositoryCredentials is a reference to a Secret containing the credentials of an HTTP chart repository, see
+	// HelmChartProxySpec.RepositoryCredentials. If the namespace is not specified, the namespace of the ChartSourceDefaults
+	// is used.
+	// +optional
+	RepositoryCredentials *corev1.SecretReference `json:"repositoryCredentials,omitempty"`
+
+	// TLSConfig contains the TLS configuration used to fetch charts. If the namespaces of its references are not specified,
+	// the namespace of the ChartSourceDefaults is used.
+	// +optional
+	TLSConfig *TLSConfig `json:"tlsConfig,omitempty"`
+
+	// ProxyURL is the URL of the HTTP proxy used to fetch charts, e.g. `http://proxy.corp.local:3128`.
+	// +kubebuilder:validation:Pattern=`^https?://`
+	// +optional
+	ProxyURL string `json:"proxyURL,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=csd
+// +kubebuilder:validation:XValidation:rule="self.metadata.name == 'default'",message="the name of a ChartSourceDefaults must be default"
+
+// ChartSourceDefaults is the Schema for the chartsourcedefaults API. It holds the credentials, TLS configuration and proxy
+// used to fetch the charts of all HelmChartProxies in its namespace, so they do not have to be repeated on every
+// HelmChartProxy. A HelmChartProxy overrides a default by specifying the field itself.
+type ChartSourceDefaults struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ChartSourceDefaultsSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ChartSourceDefaultsList contains a list of ChartSourceDefaults.
+type ChartSourceDefaultsList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ChartSourceDefaults `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ChartSourceDefaults{}, &ChartSourceDefaultsList{})
+}
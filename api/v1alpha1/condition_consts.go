@@ -36,12 +36,32 @@ const (
 	// HelmReleaseProxyReinstallingReason indicates that the HelmChartProxy controller is reinstalling a HelmReleaseProxy.
 	HelmReleaseProxyReinstallingReason = "HelmReleaseProxyReinstalling"
 
+	// ReleaseNameConflictReason indicates that another HelmChartProxy already manages a Helm release with the same release name
+	// and release namespace on a selected Cluster.
+	ReleaseNameConflictReason = "ReleaseNameConflict"
+
 	// ValueParsingFailedReason indicates that the HelmChartProxy controller failed to parse the values.
 	ValueParsingFailedReason = "ValueParsingFailed"
 
 	// ClusterSelectionFailedReason indicates that the HelmChartProxy controller failed to select the workload Clusters.
 	ClusterSelectionFailedReason = "ClusterSelectionFailed"
 
+	// GloballyPausedReason indicates that the provider is paused by the global pause ConfigMap, so the HelmReleaseProxies
+	// are not created, updated or deleted until the pause is lifted.
+	GloballyPausedReason = "GloballyPaused"
+
+	// ReconcileClusterNotSelectedReason indicates that the Cluster named by the ReconcileClusterAnnotation is not selected
+	// by the HelmChartProxy.
+	ReconcileClusterNotSelectedReason = "ReconcileClusterNotSelected"
+
+	// RolledBackToRevisionReason indicates that the spec of the HelmChartProxy was restored from the HelmChartProxyRevision
+	// named by the RollbackToRevisionAnnotation.
+	RolledBackToRevisionReason = "RolledBackToRevision"
+
+	// RevisionNotFoundReason indicates that the HelmChartProxyRevision named by the RollbackToRevisionAnnotation does not
+	// exist or cannot be restored.
+	RevisionNotFoundReason = "RevisionNotFound"
+
 	// HelmReleaseProxiesRolloutNotCompleteReason indicates that the initial rollout
 	// of HelmReleaseProxies has not been completed.
 	HelmReleaseProxiesRolloutNotCompleteReason = "HelmReleaseProxiesRolloutNotComplete"
@@ -50,12 +70,97 @@ const (
 	// use Rollout Step Size to reconcile HelmReleaseProxies.
 	HelmReleaseProxiesRolloutUndefinedReason = "HelmReleaseProxiesRolloutUndefined"
 
+	// RolloutVerificationFailedReason indicates that the verification queries
+	// did not pass after a rollout batch, so the next batch is not rolled out.
+	RolloutVerificationFailedReason = "RolloutVerificationFailed"
+
+	// RolloutReleaseCheckFailedReason indicates that the Helm releases of the previous rollout batch did not pass the
+	// re-verification on the workload Clusters, so the next batch is not rolled out.
+	RolloutReleaseCheckFailedReason = "RolloutReleaseCheckFailed"
+
+	// RolloutHookPendingReason indicates that the rollout waits for its pre-rollout or post-rollout hooks to succeed.
+	RolloutHookPendingReason = "RolloutHookPending"
+
+	// RolloutHookFailedReason indicates that a pre-rollout or post-rollout hook failed, so the rollout does not proceed or
+	// complete.
+	RolloutHookFailedReason = "RolloutHookFailed"
+
+	// RolloutProgressDeadlineExceededReason indicates that a batch of HelmReleaseProxies did not become ready within the
+	// rollout progress deadline.
+	RolloutProgressDeadlineExceededReason = "RolloutProgressDeadlineExceeded"
+
+	// RolloutHaltedReason indicates that MaxFailures of the Helm releases of the rollout failed, so no new batches are
+	// rolled out until the rollout is resumed with the ResumeRolloutAnnotation.
+	RolloutHaltedReason = "RolloutHalted"
+
+	// RolloutAwaitingApprovalReason indicates that the rollout requires approval and is paused until the next batch of
+	// HelmReleaseProxies is approved with the ApproveRolloutAnnotation.
+	RolloutAwaitingApprovalReason = "RolloutAwaitingApproval"
+
 	// HelmReleaseProxiesReadyCondition indicates that the HelmReleaseProxies are ready, meaning that the Helm installation, upgrade
 	// or deletion is complete.
 	HelmReleaseProxiesReadyCondition clusterv1.ConditionType = "HelmReleaseProxiesReady"
 
+	// ReadinessThresholdMetReason indicates that not all HelmReleaseProxies are ready, but enough of them to meet the
+	// ReadinessThreshold of the HelmChartProxy.
+	ReadinessThresholdMetReason = "ReadinessThresholdMet"
+
+	// HelmReleaseProxiesHealthyCondition indicates that no HelmReleaseProxy reports a problem with a condition aggregated
+	// with the Warning or Info severity by the ConditionAggregation of the HelmChartProxy. Unlike HelmReleaseProxiesReady,
+	// it does not block the Ready condition of the HelmChartProxy. It is only set with such a ConditionAggregation.
+	HelmReleaseProxiesHealthyCondition clusterv1.ConditionType = "HelmReleaseProxiesHealthy"
+
 	// HelmReleaseProxiesRolloutCompletedCondition indicates if the initial rollout of HelmReleaseProxies is complete.
 	HelmReleaseProxiesRolloutCompletedCondition clusterv1.ConditionType = "HelmReleaseProxiesRolloutCompleted"
+
+	// ValuesReadyCondition indicates that the values of all ValuesFrom sources of the HelmChartProxy were resolved, so that
+	// configuration errors are reported separately from errors installing or upgrading the Helm releases. It is only set
+	// if the HelmChartProxy has ValuesFrom sources.
+	ValuesReadyCondition clusterv1.ConditionType = "ValuesReady"
+
+	// ValuesSourceNotFoundReason indicates that the ConfigMap or Secret of a ValuesFrom source does not exist.
+	ValuesSourceNotFoundReason = "ValuesSourceNotFound"
+
+	// ValuesKeyNotFoundReason indicates that the ConfigMap or Secret of a ValuesFrom source does not have the values key.
+	ValuesKeyNotFoundReason = "ValuesKeyNotFound"
+
+	// ValuesSourceInvalidReason indicates that the values of a ValuesFrom source are not valid YAML or the kind of the
+	// source is not supported.
+	ValuesSourceInvalidReason = "ValuesSourceInvalid"
+
+	// ValuesSourceGetFailedReason indicates that the ConfigMap or Secret of a ValuesFrom source could not be read, e.g.
+	// because the controller is not permitted to.
+	ValuesSourceGetFailedReason = "ValuesSourceGetFailed"
+
+	// ReleasesDiscoveredCondition indicates that the Helm releases present on the selected Clusters have been discovered
+	// while the HelmChartProxy is in discovery mode.
+	ReleasesDiscoveredCondition clusterv1.ConditionType = "ReleasesDiscovered"
+
+	// ReleaseDiscoveryFailedReason indicates that the Helm releases could not be listed on one or more selected Clusters.
+	ReleaseDiscoveryFailedReason = "ReleaseDiscoveryFailed"
+
+	// UninstallsConfirmedCondition indicates whether the HelmReleaseProxies of Clusters that are no longer selected are
+	// deleted. It is only set while they are held back.
+	UninstallsConfirmedCondition clusterv1.ConditionType = "UninstallsConfirmed"
+
+	// UninstallDryRunReason indicates that the HelmReleaseProxies of Clusters that are no longer selected are not deleted
+	// because the HelmChartProxy has the UninstallDryRunAnnotation.
+	UninstallDryRunReason = "UninstallDryRun"
+
+	// UninstallConfirmationRequiredReason indicates that the Helm release would be uninstalled from more Clusters than the
+	// UninstallConfirmationThreshold, so the uninstall awaits the ConfirmUninstallAnnotation.
+	UninstallConfirmationRequiredReason = "UninstallConfirmationRequired"
+)
+
+// Conditions and Reasons shared by HelmChartProxies and HelmReleaseProxies.
+const (
+	// ReconciledRecentlyCondition indicates whether the object was reconciled without error within the staleness threshold
+	// of the controller. It is only set while the last successful reconcile is older than the threshold.
+	ReconciledRecentlyCondition clusterv1.ConditionType = "ReconciledRecently"
+
+	// ReconcileStaleReason indicates that the object was not reconciled without error within the staleness threshold, e.g.
+	// because of expired credentials.
+	ReconcileStaleReason = "ReconcileStale"
 )
 
 // HelmReleaseProxy Conditions and Reasons.
@@ -72,27 +177,190 @@ const (
 	// HelmInstallOrUpgradeFailedReason indicates that the HelmReleaseProxy failed to install or upgrade the Helm release.
 	HelmInstallOrUpgradeFailedReason = "HelmInstallOrUpgradeFailed"
 
+	// RegistryUnavailableReason indicates that the chart was not fetched because its registry kept failing, so the install
+	// or upgrade is retried once the registry circuit closes.
+	RegistryUnavailableReason = "RegistryUnavailable"
+
+	// MissingRequiredAPIsReason indicates that the Cluster does not serve the APIs required by the Helm chart, so the upgrade
+	// was not attempted.
+	MissingRequiredAPIsReason = "MissingRequiredAPIs"
+
+	// KubeVersionIncompatibleReason indicates that the Kubernetes version of the Cluster is outside the range supported by
+	// the Helm chart, so the Helm release is not installed or upgraded.
+	KubeVersionIncompatibleReason = "KubeVersionIncompatible"
+
+	// QuotaExceededReason indicates that installing the Helm chart would exceed a ResourceQuota of the release namespace, so
+	// the Helm release is not installed.
+	QuotaExceededReason = "QuotaExceeded"
+
+	// HelmReleaseChangePendingReason indicates that the controller runs with --observe-only and would install, upgrade or
+	// uninstall the Helm release.
+	HelmReleaseChangePendingReason = "HelmReleaseChangePending"
+
+	// HelmReleaseDiffFailedReason indicates that the controller runs with --observe-only and failed to compute the change
+	// to the Helm release.
+	HelmReleaseDiffFailedReason = "HelmReleaseDiffFailed"
+
+	// ManifestsApplyFailedReason indicates that the HelmReleaseProxy failed to apply its Manifests to the Cluster or to
+	// delete the resources removed from them.
+	ManifestsApplyFailedReason = "ManifestsApplyFailed"
+
 	// HelmReleaseDeletionFailedReason is indicates that the HelmReleaseProxy failed to delete the Helm release.
 	HelmReleaseDeletionFailedReason = "HelmReleaseDeletionFailed"
 
 	// HelmReleaseDeletedReason indicates that the HelmReleaseProxy deleted the Helm release.
 	HelmReleaseDeletedReason = "HelmReleaseDeleted"
 
+	// UninstallProtectedReason indicates that the HelmReleaseProxy is being deleted but the Helm release is protected by
+	// Options.Protect, so it is not uninstalled until the allow-uninstall annotation is set.
+	UninstallProtectedReason = "UninstallProtected"
+
+	// HelmReleaseRolledBackReason indicates that the Helm release was rolled back with RollbackTo, so installs and upgrades
+	// are held until the chart or values of the HelmReleaseProxy change.
+	HelmReleaseRolledBackReason = "HelmReleaseRolledBack"
+
+	// HelmReleaseRollbackFailedReason indicates that the rollback of the Helm release requested with RollbackTo failed.
+	HelmReleaseRollbackFailedReason = "HelmReleaseRollbackFailed"
+
 	// HelmReleaseGetFailedReason indicates that the HelmReleaseProxy failed to get the Helm release.
 	HelmReleaseGetFailedReason = "HelmReleaseGetFailed"
 
+	// UpgradeRolledBackCondition indicates that the last upgrade of the Helm release failed and was rolled back because
+	// Options.Atomic is set. Unlike the other conditions it signals a problem when it is True. It is removed once an
+	// install or upgrade of the Helm release succeeds.
+	UpgradeRolledBackCondition clusterv1.ConditionType = "UpgradeRolledBack"
+
+	// UpgradeRolledBackReason indicates that an atomic upgrade of the Helm release failed and the Helm release was rolled
+	// back to the revision deployed before it. The upgrade is retried on the next reconcile.
+	UpgradeRolledBackReason = "UpgradeRolledBack"
+
+	// ReleaseTestedCondition indicates whether the tests of the Helm release passed after its last install or upgrade. It
+	// is only set while Options.Test is enabled.
+	ReleaseTestedCondition clusterv1.ConditionType = "ReleaseTested"
+
+	// ReleaseTestFailedReason indicates that tests of the Helm release failed or could not be run.
+	ReleaseTestFailedReason = "ReleaseTestFailed"
+
+	// ChartVerificationFailedCondition indicates that the signature of the Helm chart could not be verified or that its
+	// vendored dependencies do not match its Chart.lock, so the chart was not installed or upgraded. Unlike the other
+	// conditions it signals a problem when it is True. It is removed once an install or upgrade of the Helm release
+	// succeeds.
+	ChartVerificationFailedCondition clusterv1.ConditionType = "ChartVerificationFailed"
+
+	// ChartVerificationFailedReason indicates that the provenance or cosign signature of the Helm chart could not be
+	// verified.
+	ChartVerificationFailedReason = "ChartVerificationFailed"
+
+	// ChartLockMismatchReason indicates that the vendored dependencies of the Helm chart or of one of its subcharts do not
+	// match its Chart.lock, e.g. because they were tampered with or vendored at another version.
+	ChartLockMismatchReason = "ChartLockMismatch"
+
+	// DriftDetectedCondition indicates whether resources of the Helm release drifted from its manifest at the last drift
+	// check. Unlike the other conditions it signals a problem when it is True. It is only set while DriftDetection is
+	// enabled and the DriftPolicy is not Ignore.
+	DriftDetectedCondition clusterv1.ConditionType = "DriftDetected"
+
+	// DriftDetectedReason indicates that resources of the Helm release drifted from its manifest and were left as they are,
+	// because the DriftPolicy is Warn or the controller runs with --observe-only.
+	DriftDetectedReason = "DriftDetected"
+
+	// DriftCorrectedReason indicates that resources of the Helm release drifted from its manifest and were re-applied.
+	DriftCorrectedReason = "DriftCorrected"
+
+	// DriftCheckFailedReason indicates that the HelmReleaseProxy failed to compare the resources of the Helm release against
+	// its manifest or to re-apply the drifted ones.
+	DriftCheckFailedReason = "DriftCheckFailed"
+
 	// ClusterAvailableCondition indicates that the Cluster to install the Helm release on is available.
 	ClusterAvailableCondition clusterv1.ConditionType = "ClusterAvailable"
 
 	// GetClusterFailedReason indicates that the HelmReleaseProxy failed to get the Cluster.
 	GetClusterFailedReason = "GetClusterFailed"
 
+	// ClusterDeletingReason indicates that the Cluster is being deleted or is gone, so the Helm release is neither installed
+	// nor uninstalled, as it goes away with the Cluster.
+	ClusterDeletingReason = "ClusterDeleting"
+
+	// WaitingForClusterCapacityReason indicates that the HelmReleaseProxy is waiting for the Cluster to reach the capacity
+	// required by ClusterReadiness before installing the Helm release.
+	WaitingForClusterCapacityReason = "WaitingForClusterCapacity"
+
+	// ClusterCapacityCheckFailedReason indicates that the HelmReleaseProxy failed to check the capacity of the Cluster.
+	ClusterCapacityCheckFailedReason = "ClusterCapacityCheckFailed"
+
+	// WaitingForClusterUpgradeReason indicates that the HelmReleaseProxy is holding the upgrade of the Helm release until
+	// the Kubernetes version upgrade of the Cluster completes.
+	WaitingForClusterUpgradeReason = "WaitingForClusterUpgrade"
+
+	// ClusterUpgradeCheckFailedReason indicates that the HelmReleaseProxy failed to check whether the Cluster is upgrading.
+	ClusterUpgradeCheckFailedReason = "ClusterUpgradeCheckFailed"
+
+	// WaitingForFailoverLeaseReason indicates that the HelmReleaseProxy is standing by because another management cluster
+	// holds the ownership lease on the Cluster.
+	WaitingForFailoverLeaseReason = "WaitingForFailoverLease"
+
+	// FailoverLeaseFailedReason indicates that the HelmReleaseProxy failed to acquire or renew the ownership lease on the
+	// Cluster.
+	FailoverLeaseFailedReason = "FailoverLeaseFailed"
+
 	// GetKubeconfigFailedReason indicates that the HelmReleaseProxy failed to get the kubeconfig for the Cluster.
 	GetKubeconfigFailedReason = "GetKubeconfigFailed"
 
+	// WaitingForKubeconfigReason indicates that the HelmReleaseProxy is waiting for the kubeconfig Secret of the Cluster to
+	// be created, which is expected while the Cluster is being provisioned.
+	WaitingForKubeconfigReason = "WaitingForKubeconfig"
+
 	// GetCredentialsFailedReason indicates that the HelmReleaseProxy failed to get the credentials for the Helm registry.
 	GetCredentialsFailedReason = "GetCredentialsFailed"
 
 	// GetCACertificateFailedReason indicates that the HelmReleaseProxy failed to get the CA certiicate for the Helm registry.
 	GetCACertificateFailedReason = "GetCACertificateFailed"
+
+	// GetClientCertificateFailedReason indicates that the HelmReleaseProxy failed to get the client certificate for the Helm
+	// registry from the referenced cert-manager Certificate.
+	GetClientCertificateFailedReason = "GetClientCertificateFailed"
+
+	// ChartBundleUnavailableReason indicates that the HelmReleaseProxy failed to get its Helm chart from the referenced
+	// ChartBundle.
+	ChartBundleUnavailableReason = "ChartBundleUnavailable"
+
+	// HelmRepositoryUnavailableReason indicates that the HelmReleaseProxy failed to get the referenced HelmRepository.
+	HelmRepositoryUnavailableReason = "HelmRepositoryUnavailable"
+
+	// ValuesUnavailableReason indicates that the HelmReleaseProxy failed to get the values it references from their
+	// ConfigMaps, or that they do not match their hash.
+	ValuesUnavailableReason = "ValuesUnavailable"
+)
+
+// ChartBundle Conditions and Reasons.
+const (
+	// ChartBundleLoadFailedReason indicates that the ChartBundle controller failed to load the bundle tarball from its
+	// source or to read the charts it contains.
+	ChartBundleLoadFailedReason = "ChartBundleLoadFailed"
+)
+
+// AddonProfile Conditions and Reasons.
+const (
+	// HelmChartProxiesAppliedCondition indicates that the HelmChartProxies of all charts of the AddonProfile are created
+	// and up to date with their templates, and that the HelmChartProxies of removed charts are deleted.
+	HelmChartProxiesAppliedCondition clusterv1.ConditionType = "HelmChartProxiesApplied"
+
+	// WaitingForLowerWeightChartsReason indicates that the HelmChartProxies of charts are not created or updated yet, as
+	// the HelmChartProxies of charts with a lower weight are not ready.
+	WaitingForLowerWeightChartsReason = "WaitingForLowerWeightCharts"
+
+	// ChartTemplateInvalidReason indicates that the template of a chart of the AddonProfile is not a valid
+	// HelmChartProxy spec.
+	ChartTemplateInvalidReason = "ChartTemplateInvalid"
+
+	// HelmChartProxyApplyFailedReason indicates that the AddonProfile controller failed to create, update or delete the
+	// HelmChartProxy of a chart.
+	HelmChartProxyApplyFailedReason = "HelmChartProxyApplyFailed"
+
+	// HelmChartProxiesReadyCondition indicates that the HelmChartProxies of all charts of the AddonProfile are
+	// ready and reconciled from their latest spec.
+	HelmChartProxiesReadyCondition clusterv1.ConditionType = "HelmChartProxiesReady"
+
+	// HelmChartProxiesNotReadyReason indicates that the HelmChartProxies of some charts of the AddonProfile are not ready.
+	HelmChartProxiesNotReadyReason = "HelmChartProxiesNotReady"
 )
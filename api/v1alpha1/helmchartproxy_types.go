@@ -17,6 +17,10 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -34,6 +38,110 @@ const (
 	// DefaultOCIKey is the default file name of the OCI secret key.
 	DefaultOCIKey = "config.json"
 
+	// DefaultValuesFromKey is the default key of the values in a ConfigMap or Secret referenced by ValuesFrom.
+	DefaultValuesFromKey = "values.yaml"
+
+	// RepositoryUsernameKey is the key of the username in the Secret referenced by RepositoryCredentials.
+	RepositoryUsernameKey = "username"
+
+	// RepositoryPasswordKey is the key of the password in the Secret referenced by RepositoryCredentials.
+	RepositoryPasswordKey = "password"
+
+	// RepositoryTokenKey is the key of the bearer token in the Secret referenced by RepositoryCredentials.
+	RepositoryTokenKey = "token"
+
+	// ProvenanceKeyringKey is the key of the GPG public keyring in the Secret referenced by Verify.Provenance.
+	ProvenanceKeyringKey = "keyring.gpg"
+
+	// CosignPublicKeyKey is the key of the PEM encoded public key in the Secret referenced by Verify.Cosign.
+	CosignPublicKeyKey = "cosign.pub"
+
+	// FulcioCertificatesKey is the key of the PEM encoded Fulcio root and intermediate certificates in the Secret referenced
+	// by Verify.Cosign.Keyless.
+	FulcioCertificatesKey = "fulcio.crt"
+
+	// RekorPublicKeyKey is the key of the PEM encoded Rekor public key in the Secret referenced by Verify.Cosign.Keyless.
+	RekorPublicKeyKey = "rekor.pub"
+
+	// DefaultDriftDetectionInterval is the default interval between two drift checks of a Helm release.
+	DefaultDriftDetectionInterval = 5 * time.Minute
+
+	// DefaultRolloutHookURLTimeout is the default timeout of the call to the URL of a rollout hook.
+	DefaultRolloutHookURLTimeout = 30 * time.Second
+
+	// DefaultFailoverLeaseDuration is the default duration of the ownership lease of a HelmChartProxy with Failover.
+	DefaultFailoverLeaseDuration = time.Minute
+
+	// DefaultVersionResolutionInterval is the default interval at which a Version constraint is resolved again to roll out
+	// the latest matching chart version.
+	DefaultVersionResolutionInterval = 10 * time.Minute
+
+	// DefaultRevisionHistoryLimit is the default number of HelmChartProxyRevisions kept for a HelmChartProxy.
+	DefaultRevisionHistoryLimit = 10
+
+	// TemplateLibraryLabelName is the label signifying that a ConfigMap holds template partials. When set to "true", every
+	// key of the ConfigMap is parsed into the valuesTemplate of all HelmChartProxies in the same namespace.
+	TemplateLibraryLabelName = "helmchartproxy.addons.cluster.x-k8s.io/template-library"
+
+	// ProxySettingsLabelName is the label on a Cluster behind a proxy naming the ConfigMap in the namespace of the Cluster
+	// that holds its proxy settings and trust bundle. They are injected into the values of every HelmChartProxy with
+	// ProxyValues selecting the Cluster.
+	ProxySettingsLabelName = "addons.cluster.x-k8s.io/proxy-settings"
+
+	// ProxySettingsHTTPProxyKey is the key of the HTTP proxy URL in a proxy settings ConfigMap.
+	ProxySettingsHTTPProxyKey = "httpProxy"
+
+	// ProxySettingsHTTPSProxyKey is the key of the HTTPS proxy URL in a proxy settings ConfigMap.
+	ProxySettingsHTTPSProxyKey = "httpsProxy"
+
+	// ProxySettingsNoProxyKey is the key of the comma-separated hosts excluded from the proxy in a proxy settings ConfigMap.
+	ProxySettingsNoProxyKey = "noProxy"
+
+	// ProxySettingsTrustBundleKey is the key of the PEM-encoded CA certificates in a proxy settings ConfigMap.
+	ProxySettingsTrustBundleKey = "trustBundle"
+
+	// GlobalPausePausedKey is the key of the global pause ConfigMap named by --pause-configmap. When set to "true", no Helm
+	// release is installed, upgraded, rolled back or uninstalled and no HelmReleaseProxy is created, updated or deleted,
+	// while the status of HelmChartProxies and HelmReleaseProxies is still updated.
+	GlobalPausePausedKey = "paused"
+
+	// GlobalPauseReasonKey is the key of the optional reason for the pause in the global pause ConfigMap, which is reported
+	// in the conditions of the paused HelmChartProxies and HelmReleaseProxies.
+	GlobalPauseReasonKey = "reason"
+
+	// DiscoveryModeAnnotation is the annotation signifying that a HelmChartProxy is in read-only discovery mode. When set
+	// to "true", no HelmReleaseProxies are created; instead the Helm releases already present on the selected Clusters are
+	// reported in the status, so that existing Clusters can be onboarded safely before management is enabled.
+	DiscoveryModeAnnotation = "helmchartproxy.addons.cluster.x-k8s.io/discovery-mode"
+
+	// ReconcileClusterAnnotation is the annotation naming a selected Cluster whose HelmReleaseProxy should be reconciled
+	// immediately, outside of the rollout ordering. The annotation is removed once the Cluster has been reconciled.
+	ReconcileClusterAnnotation = "helmchartproxy.addons.cluster.x-k8s.io/reconcile-cluster"
+
+	// UninstallDryRunAnnotation is the annotation signifying that the HelmReleaseProxies of Clusters that are no longer
+	// selected are not deleted. When set to "true", the Clusters that would lose the Helm release are only listed in the
+	// PendingUninstalls status, so that the impact of a ClusterSelector change can be previewed.
+	UninstallDryRunAnnotation = "helmchartproxy.addons.cluster.x-k8s.io/uninstall-dry-run"
+
+	// ConfirmUninstallAnnotation is the annotation confirming the uninstall of the Helm release from as many Clusters as its
+	// value when that number exceeds the UninstallConfirmationThreshold. The annotation is removed once no Cluster is
+	// pending uninstall.
+	ConfirmUninstallAnnotation = "helmchartproxy.addons.cluster.x-k8s.io/confirm-uninstall"
+
+	// RollbackToRevisionAnnotation is the annotation naming the number of a HelmChartProxyRevision the spec of the
+	// HelmChartProxy is restored from. The annotation is removed once the HelmChartProxy has been rolled back, and the
+	// restored spec is rolled out to the selected Clusters like any other change.
+	RollbackToRevisionAnnotation = "helmchartproxy.addons.cluster.x-k8s.io/rollback-to-revision"
+
+	// ResumeRolloutAnnotation is the annotation resuming a rollout that was halted because MaxFailures of its Helm releases
+	// failed. The annotation is removed once the rollout has been resumed; it is halted again if the failures persist.
+	ResumeRolloutAnnotation = "helmchartproxy.addons.cluster.x-k8s.io/resume-rollout"
+
+	// ApproveRolloutAnnotation is the annotation approving the next batch of a rollout whose RolloutOptions require
+	// approval. It may be set before the current batch is ready; set before the first batch, it approves the batch after
+	// the first one. The annotation is removed once the approval has been recorded in the rollout status.
+	ApproveRolloutAnnotation = "helmchartproxy.addons.cluster.x-k8s.io/approve-rollout"
+
 	// ReconcileStrategyContinuous is the default reconciliation strategy for HelmChartProxy. It will attempt to install the Helm
 	// chart on a selected Cluster, update the Helm release to match the current HelmChartProxy spec, and delete the Helm release
 	// if the Cluster no longer selected.
@@ -44,19 +152,126 @@ const (
 	ReconcileStrategyInstallOnce ReconcileStrategy = "InstallOnce"
 )
 
+// ClusterLabelPolicy is a string representation of how the cluster label of HelmReleaseProxy metrics is handled once a
+// HelmChartProxy selects more Clusters than the configured threshold.
+type ClusterLabelPolicy string
+
+const (
+	// ClusterLabelPolicyAggregate collapses the per-Cluster metric series of a HelmChartProxy into a single series without
+	// a cluster label.
+	ClusterLabelPolicyAggregate ClusterLabelPolicy = "Aggregate"
+)
+
+// ValuesFromKind is a string representation of the kind of object a ValuesFromSource references.
+type ValuesFromKind string
+
+const (
+	// ValuesFromKindConfigMap references a ConfigMap.
+	ValuesFromKindConfigMap ValuesFromKind = "ConfigMap"
+
+	// ValuesFromKindSecret references a Secret.
+	ValuesFromKindSecret ValuesFromKind = "Secret"
+)
+
+// ConditionPolarity is a string representation of which status of a HelmReleaseProxy condition signals a problem.
+type ConditionPolarity string
+
+const (
+	// ConditionPolarityPositive signals a problem when the condition is False, e.g. for Ready.
+	ConditionPolarityPositive ConditionPolarity = "Positive"
+
+	// ConditionPolarityNegative signals a problem when the condition is True, e.g. for a drift condition.
+	ConditionPolarityNegative ConditionPolarity = "Negative"
+)
+
+// MissingKeyPolicy is a string representation of how a valuesTemplate handles references to keys that are not present.
+type MissingKeyPolicy string
+
+const (
+	// MissingKeyPolicyDefault renders missing keys as "<no value>", which is the default Go template behavior.
+	MissingKeyPolicyDefault MissingKeyPolicy = "Default"
+
+	// MissingKeyPolicyZero renders missing keys as empty values.
+	MissingKeyPolicyZero MissingKeyPolicy = "Zero"
+
+	// MissingKeyPolicyError stops rendering with an error when a missing key is referenced.
+	MissingKeyPolicyError MissingKeyPolicy = "Error"
+)
+
+// DeletionPolicy is a string representation of what happens to an InstallOnce Helm release when its HelmReleaseProxy is deleted.
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyOrphan is the default deletion policy for the InstallOnce strategy. It leaves the Helm release installed
+	// on the Cluster when the HelmReleaseProxy is deleted.
+	DeletionPolicyOrphan DeletionPolicy = "Orphan"
+
+	// DeletionPolicyUninstall uninstalls the Helm release from the Cluster when the HelmReleaseProxy is deleted.
+	DeletionPolicyUninstall DeletionPolicy = "Uninstall"
+)
+
+// DriftPolicy is a string representation of how drift of the objects of a Helm release from its manifest is handled.
+type DriftPolicy string
+
+const (
+	// DriftPolicyCorrect re-applies the Helm release when drift is detected.
+	DriftPolicyCorrect DriftPolicy = "Correct"
+
+	// DriftPolicyWarn only reports drift on the HelmReleaseProxy, leaving the objects as they are.
+	DriftPolicyWarn DriftPolicy = "Warn"
+
+	// DriftPolicyIgnore neither reports nor corrects drift.
+	DriftPolicyIgnore DriftPolicy = "Ignore"
+)
+
 // HelmChartProxySpec defines the desired state of HelmChartProxy.
 type HelmChartProxySpec struct {
 	// ClusterSelector selects Clusters in the same namespace with a label that matches the specified label selector. The Helm
 	// chart will be installed on all selected Clusters. If a Cluster is no longer selected, the Helm release will be uninstalled.
 	ClusterSelector metav1.LabelSelector `json:"clusterSelector"`
 
+	// ClusterWatchFilterValue is the value of the cluster.x-k8s.io/watch-filter label selected Clusters must have, in
+	// addition to matching the ClusterSelector. It composes with the --watch-filter of the controller, which still only
+	// reconciles HelmChartProxies with its own label value, so that Clusters managed under different watch filters can
+	// share a single controller deployment. If it is not specified, Clusters are selected regardless of the label.
+	// +optional
+	ClusterWatchFilterValue string `json:"clusterWatchFilterValue,omitempty"`
+
+	// PropagateClusterLabels lists the keys of the labels of each selected Cluster that are copied onto its HelmReleaseProxy
+	// and set as labels of the Helm release, e.g. team or environment labels, so that releases can be queried consistently
+	// in the management and workload Clusters. Labels the Cluster does not have are skipped. The keys must not be reserved
+	// by Helm or by the HelmReleaseProxy labels.
+	// +optional
+	PropagateClusterLabels []string `json:"propagateClusterLabels,omitempty"`
+
 	// ChartName is the name of the Helm chart in the repository.
 	// e.g. chart-path oci://repo-url/chart-name as chartName: chart-name and https://repo-url/chart-name as chartName: chart-name
 	ChartName string `json:"chartName"`
 
 	// RepoURL is the URL of the Helm chart repository.
 	// e.g. chart-path oci://repo-url/chart-name as repoURL: oci://repo-url and https://repo-url/chart-name as repoURL: https://repo-url
-	RepoURL string `json:"repoURL"`
+	// It must be specified unless ChartBundleRef, RepositoryRef or Git is.
+	// +optional
+	RepoURL string `json:"repoURL,omitempty"`
+
+	// RepositoryRef is a reference to the HelmRepository holding the URL, credentials and TLS configuration of the Helm
+	// chart repository. It is mutually exclusive with RepoURL and ChartBundleRef, and the repository configured on the
+	// HelmRepository takes precedence over the credentials, TLS configuration and proxy of the HelmChartProxy. If the
+	// namespace is not specified, the namespace of the HelmChartProxy is used.
+	// +optional
+	RepositoryRef *HelmRepositoryReference `json:"repositoryRef,omitempty"`
+
+	// ChartBundleRef is a reference to a ChartBundle containing the Helm chart, used instead of RepoURL in environments
+	// without access to a chart repository. The Version must be specified and contained in the bundle.
+	// +optional
+	ChartBundleRef *ChartBundleReference `json:"chartBundleRef,omitempty"`
+
+	// Git is the Git repository the Helm chart is pulled from instead of a chart repository, e.g. to install a chart from the
+	// repository it is developed in without publishing it. It is mutually exclusive with RepoURL, RepositoryRef and
+	// ChartBundleRef. The ChartName must match the name of the chart in its Chart.yaml, and the Version, if specified, its
+	// version.
+	// +optional
+	Git *GitChartSource `json:"git,omitempty"`
 
 	// ReleaseName is the release name of the installed Helm chart. If it is not specified, a name will be generated.
 	// +optional
@@ -68,15 +283,80 @@ type HelmChartProxySpec struct {
 	ReleaseNamespace string `json:"namespace,omitempty"`
 
 	// Version is the version of the Helm chart. If it is not specified, the chart will use
-	// and be kept up to date with the latest version.
+	// and be kept up to date with the latest version. It can also be a semver constraint, e.g. `>=1.2.0 <2.0.0` or `~1.4`,
+	// which is resolved to the latest matching version of the chart repository or OCI registry and kept up to date with
+	// it. The constraint is resolved again every ResyncPeriod, or every 10 minutes if it is not specified, and the Helm
+	// releases are upgraded once a newer matching version is published. The resolved version is reported in the
+	// chartVersion of the HelmReleaseProxy status.
 	// +optional
 	Version string `json:"version,omitempty"`
 
+	// Digest pins the OCI artifact of the Helm chart to the digest of its manifest, e.g. `sha256:<hex>`, so that exactly
+	// this artifact is installed even if the tag of the Version is moved. The chart is pulled by the digest and must be of
+	// the Version if it is specified, which cannot be a semver constraint. Once set, the Version and the Digest can only be
+	// changed together. Only charts of OCI registries can be pinned by digest.
+	// +kubebuilder:validation:Pattern=`^sha256:[a-f0-9]{64}$`
+	// +optional
+	Digest string `json:"digest,omitempty"`
+
 	// ValuesTemplate is an inline YAML representing the values for the Helm chart. This YAML supports Go templating to reference
 	// fields from each selected workload Cluster and programatically create and set values.
 	// +optional
 	ValuesTemplate string `json:"valuesTemplate,omitempty"`
 
+	// Manifests is an inline multi-document YAML of plain Kubernetes resources applied to each selected Cluster instead of
+	// a Helm chart, for addons too small to warrant one, e.g. a single ConfigMap. It supports the same Go templating as the
+	// ValuesTemplate. The rendered resources are applied with server-side apply, and the resources removed from the
+	// Manifests are deleted from the Cluster, as are all of them when the HelmReleaseProxy is deleted. Namespaced resources
+	// without a namespace are applied to the ReleaseNamespace. It is mutually exclusive with RepoURL, RepositoryRef,
+	// ChartBundleRef and Git, and the ChartName only names the addon. It cannot be added to or removed from an existing
+	// HelmChartProxy.
+	// +optional
+	Manifests string `json:"manifests,omitempty"`
+
+	// ValuesFrom lists ConfigMaps and Secrets in the namespace of the HelmChartProxy holding values for the Helm chart, e.g.
+	// credentials and tunables kept out of the HelmChartProxy. The values of the sources are merged in order, with later
+	// sources taking precedence, and the values rendered from the ValuesTemplate are merged over them. The HelmReleaseProxies
+	// are updated when a referenced ConfigMap or Secret changes. Note that the merged values, including those of Secrets,
+	// are stored in the HelmReleaseProxies.
+	// +optional
+	ValuesFrom []ValuesFromSource `json:"valuesFrom,omitempty"`
+
+	// ValuesTemplateOptions controls how the ValuesTemplate is rendered. If it is not specified, the ValuesTemplate is
+	// rendered with the default Go template options and delimiters.
+	// +optional
+	ValuesTemplateOptions *ValuesTemplateOptions `json:"valuesTemplateOptions,omitempty"`
+
+	// ProxyValues designates the value paths of the chart the proxy settings and trust bundle of Clusters behind a proxy
+	// are injected into. Only Clusters with the ProxySettingsLabelName label are affected. If it is not specified, no
+	// proxy settings are injected.
+	// +optional
+	ProxyValues *ProxyValues `json:"proxyValues,omitempty"`
+
+	// ValuesByReference stores the large top-level blocks of the rendered values in ConfigMaps referenced by hash from the
+	// HelmReleaseProxies instead of inlining them, keeping the HelmReleaseProxies small and their diffs readable. If it is
+	// not specified, the rendered values are inlined in full.
+	// +optional
+	ValuesByReference *ValuesByReference `json:"valuesByReference,omitempty"`
+
+	// Environments are named groups of the selected Clusters, e.g. dev, stage and prod, each with its own chart version
+	// and values overlay. A selected Cluster belongs to the first environment whose ClusterSelector matches it, and
+	// Clusters that match no environment are not selected. Environments are promoted in order: an environment without a
+	// version is upgraded to the version of the previous environment once all of its HelmReleaseProxies are ready with
+	// it. If it is not specified, all selected Clusters get the same version and values.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Environments []Environment `json:"environments,omitempty"`
+
+	// ClusterResourceSetSignatures identify the ConfigMaps and Secrets of ClusterResourceSets that already apply the addon,
+	// so that fleets can be migrated from ClusterResourceSets gradually. The Helm chart is not installed on a selected
+	// Cluster whose ClusterResourceSetBinding lists a resource matching a signature; the Cluster is reported in the
+	// ClusterResourceSetClusters status instead. Clusters with an existing HelmReleaseProxy are not affected. If it is not
+	// specified, ClusterResourceSets are not considered.
+	// +optional
+	ClusterResourceSetSignatures []ClusterResourceSetSignature `json:"clusterResourceSetSignatures,omitempty"`
+
 	// ReconcileStrategy indicates whether a Helm chart should be continuously installed, updated, and uninstalled on selected Clusters,
 	// or if it should be reconciled until it is successfully installed on selected Clusters and not otherwise updated or uninstalled.
 	// If not specified, the default behavior will be to reconcile continuously. This field is immutable.
@@ -85,6 +365,35 @@ type HelmChartProxySpec struct {
 	// +optional
 	ReconcileStrategy string `json:"reconcileStrategy,omitempty"`
 
+	// DeletionPolicy indicates whether Helm releases installed with the `InstallOnce` ReconcileStrategy are uninstalled when the
+	// HelmChartProxy is deleted or a Cluster is no longer selected. If not specified, the Helm releases are left in place.
+	// This field is ignored for the `Continuous` ReconcileStrategy, which always uninstalls Helm releases.
+	// Possible values are `Orphan`, `Uninstall`, or unset.
+	// +kubebuilder:validation:Enum="";Orphan;Uninstall
+	// +optional
+	DeletionPolicy string `json:"deletionPolicy,omitempty"`
+
+	// UninstallConfirmationThreshold is the maximum number of Clusters the Helm release is uninstalled from when they are
+	// no longer selected without confirmation. If more Clusters would lose the Helm release, e.g. after a mistaken
+	// ClusterSelector change, their HelmReleaseProxies are not deleted but listed in the PendingUninstalls status until the
+	// ConfirmUninstallAnnotation is set to their number. If it is not specified, no confirmation is required.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	UninstallConfirmationThreshold *int32 `json:"uninstallConfirmationThreshold,omitempty"`
+
+	// ReadinessThreshold is the minimum number, e.g. 990, or percentage, e.g. 95%, of HelmReleaseProxies that must be ready
+	// for the HelmReleaseProxiesReady condition to be true, so that a few failing Clusters do not mark a HelmChartProxy
+	// selecting many Clusters as not ready. The message of the condition counts the ready HelmReleaseProxies. If it is not
+	// specified, all HelmReleaseProxies must be ready.
+	// +optional
+	ReadinessThreshold *intstr.IntOrString `json:"readinessThreshold,omitempty"`
+
+	// ConditionAggregation configures which conditions of the HelmReleaseProxies feed the HelmReleaseProxiesReady condition
+	// and with what severity, so that e.g. a condition can be reported as a warning rather than blocking the Ready
+	// condition. If it is not specified, the Ready condition of the HelmReleaseProxies is aggregated.
+	// +optional
+	ConditionAggregation *ConditionAggregation `json:"conditionAggregation,omitempty"`
+
 	// Rollout is used to define install and upgrade level rollout options that
 	// will be used when rolling out HelmReleaseProxy resources changes. If
 	// undefined, it defaults to no rollout; i.e it applies changes to all
@@ -97,13 +406,130 @@ type HelmChartProxySpec struct {
 	// +optional
 	Options HelmOptions `json:"options,omitempty"`
 
-	// Credentials is a reference to an object containing the OCI credentials. If it is not specified, no credentials will be used.
+	// Credentials is a reference to an object containing the OCI credentials. If it is not specified, the credentials of the
+	// ChartSourceDefaults of the namespace are used, if any, or else no credentials will be used.
 	// +optional
 	Credentials *Credentials `json:"credentials,omitempty"`
 
-	// TLSConfig contains the TLS configuration for a HelmChartProxy.
+	// RepositoryHeaders is a reference to a Secret whose keys and values are sent as extra HTTP headers with every request
+	// to an HTTP chart repository, e.g. for artifact proxies requiring custom tokens. If the namespace is not specified, the
+	// namespace of the HelmChartProxy is used.
+	// +optional
+	RepositoryHeaders *corev1.SecretReference `json:"repositoryHeaders,omitempty"`
+
+	// RepositoryCredentials is a reference to a Secret containing the credentials of an HTTP chart repository. The Secret
+	// contains either the keys `username` and `password` for basic authentication or the key `token` for bearer token
+	// authentication. The credentials are only sent to the host of the repository. If the namespace is not specified, the
+	// namespace of the HelmChartProxy is used. OCI registries use Credentials instead.
+	// +optional
+	RepositoryCredentials *corev1.SecretReference `json:"repositoryCredentials,omitempty"`
+
+	// TLSConfig contains the TLS configuration for a HelmChartProxy. If it is not specified, the TLS configuration of the
+	// ChartSourceDefaults of the namespace is used, if any.
 	// +optional
 	TLSConfig *TLSConfig `json:"tlsConfig,omitempty"`
+
+	// ProxyURL is the URL of the HTTP proxy used to fetch the chart, e.g. `http://proxy.corp.local:3128`. If it is not
+	// specified, the proxy of the ChartSourceDefaults of the namespace is used, if any, or else the proxy environment
+	// variables of the controller.
+	// +kubebuilder:validation:Pattern=`^https?://`
+	// +optional
+	ProxyURL string `json:"proxyURL,omitempty"`
+
+	// Verify verifies the signature of the Helm chart before it is installed or upgraded. A chart failing verification is
+	// not installed, and the failure is reported in the ChartVerificationFailed condition of the HelmReleaseProxies.
+	// +optional
+	Verify *ChartVerification `json:"verify,omitempty"`
+
+	// PostRenderer patches the manifests rendered from the Helm chart before they are installed or upgraded, e.g. to add
+	// nodeSelectors or tolerations that the chart does not expose as values.
+	// +optional
+	PostRenderer *PostRenderer `json:"postRenderer,omitempty"`
+
+	// Metrics controls the cardinality of the metrics emitted for the HelmReleaseProxies of this HelmChartProxy.
+	// If it is not specified, metrics are labeled with the name of every selected Cluster.
+	// +optional
+	Metrics *MetricsOptions `json:"metrics,omitempty"`
+
+	// ClusterReadiness defines the capacity a selected Cluster must have before the Helm chart is first installed on it, in
+	// addition to an initialized control plane. If it is not specified, the Helm chart is installed as soon as the control
+	// plane is initialized.
+	// +optional
+	ClusterReadiness *ClusterReadinessOptions `json:"clusterReadiness,omitempty"`
+
+	// ResyncPeriod is the interval at which the HelmReleaseProxies of this HelmChartProxy are periodically reconciled
+	// against the workload Clusters, e.g. `1m` for critical addons or `1h` for low-priority ones. If it is not
+	// specified, the controller's --sync-period is used.
+	// +optional
+	ResyncPeriod *metav1.Duration `json:"resyncPeriod,omitempty"`
+
+	// ReconcileInterval is the interval at which the HelmChartProxy is periodically reconciled even without changes,
+	// re-selecting the Clusters and re-rendering the values of its HelmReleaseProxies. It also defaults the
+	// ResyncPeriod, so that the Helm releases are re-verified and re-synced on the workload Clusters at the same
+	// interval. If it is not specified, the HelmChartProxy is only reconciled on changes and every --sync-period.
+	// +optional
+	ReconcileInterval *metav1.Duration `json:"reconcileInterval,omitempty"`
+
+	// HoldDuringClusterUpgrade indicates whether upgrades of the Helm release on a selected Cluster are held while the
+	// Kubernetes version of the Cluster's control plane is being upgraded. Initial installs are not held.
+	// +optional
+	HoldDuringClusterUpgrade bool `json:"holdDuringClusterUpgrade,omitempty"`
+
+	// KubeVersion is a semver range of the Kubernetes versions the Helm chart supports, e.g. `>=1.27.0-0 <1.31.0-0`. The
+	// Helm chart is not installed or upgraded on selected Clusters whose Kubernetes version is outside the range. The
+	// kubeVersion of the Chart.yaml is always enforced in addition to this range.
+	// +optional
+	KubeVersion string `json:"kubeVersion,omitempty"`
+
+	// CopySBOMs indicates whether the SBOMs attached to the OCI artifact of the Helm chart are copied to a ConfigMap next
+	// to each HelmReleaseProxy, so compliance tooling can map the addons deployed on each Cluster to their SBOMs without
+	// access to the registry.
+	// +optional
+	CopySBOMs bool `json:"copySBOMs,omitempty"`
+
+	// RecordValuesOverrides indicates whether the default values of the Helm chart overridden by the rendered values are
+	// recorded in a ConfigMap next to each HelmReleaseProxy, showing which defaults are actually overridden on each Cluster.
+	// +optional
+	RecordValuesOverrides bool `json:"recordValuesOverrides,omitempty"`
+
+	// DriftPolicy indicates whether drift of the objects of the Helm releases from their manifests is corrected by
+	// re-applying the Helm release, only reported, or ignored. If it is not specified, it defaults to `Correct`.
+	// Possible values are `Correct`, `Warn`, `Ignore`, or unset.
+	// +kubebuilder:validation:Enum="";Correct;Warn;Ignore
+	// +optional
+	DriftPolicy string `json:"driftPolicy,omitempty"`
+
+	// DriftIgnoreRules exclude fields of the objects of the Helm releases from drift detection, e.g. the replicas of a
+	// Deployment scaled by a HorizontalPodAutoscaler or fields set by admission webhooks, so that legitimate in-cluster
+	// mutations are neither reported nor reverted.
+	// +optional
+	DriftIgnoreRules []DriftIgnoreRule `json:"driftIgnoreRules,omitempty"`
+
+	// DriftDetection periodically compares the objects of the Helm releases against their manifests and handles drift
+	// according to the DriftPolicy. If it is not specified, drift is not detected.
+	// +optional
+	DriftDetection *DriftDetection `json:"driftDetection,omitempty"`
+
+	// Failover enables failover between identical HelmChartProxies in different management clusters that manage the same
+	// Clusters, e.g. in disaster recovery setups. Only the management cluster holding the ownership lease on a Cluster
+	// reconciles the Helm release on it, while the others stand by until the lease expires. Each management cluster must
+	// run the controller with a distinct --failover-identity.
+	// +optional
+	Failover *FailoverOptions `json:"failover,omitempty"`
+
+	// Impersonation is the identity the Helm releases are installed, upgraded and uninstalled as on the workload Clusters,
+	// so that their audit logs attribute the changes to a meaningful identity, e.g. `caaph:platform-team`, rather than the
+	// user of the kubeconfig of the Cluster. The user of the kubeconfig must be allowed to impersonate it. If it is not
+	// specified, the user of the kubeconfig is used.
+	// +optional
+	Impersonation *Impersonation `json:"impersonation,omitempty"`
+
+	// RevisionHistoryLimit is the number of HelmChartProxyRevisions recording the previous specs of the HelmChartProxy
+	// that are kept, including the current one. The oldest revisions are deleted first. If it is not specified, it
+	// defaults to 10.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
 }
 
 // Rollout defines install and upgrade level rollout options when rolling out
@@ -118,6 +544,314 @@ type Rollout struct {
 	// applies changes to all matching clusters at once.
 	// +optional
 	Upgrade *RolloutOptions `json:"upgrade,omitempty"`
+
+	// Uninstall rollout options for the deletion of the HelmReleaseProxies of Clusters that are no longer selected. Each
+	// batch is only deleted once the HelmReleaseProxies of the previous batch are gone and the Verification queries pass.
+	// The step sizes are relative to the number of HelmReleaseProxies to delete. FailureDomainLabel and
+	// MaxPerFailureDomain are not used, as the Clusters may no longer exist. If left empty, it defaults to no rollout;
+	// i.e. it deletes all of them at once.
+	// +optional
+	Uninstall *RolloutOptions `json:"uninstall,omitempty"`
+
+	// Verification defines Prometheus queries that must pass after each
+	// rollout batch before the next batch is rolled out. If left empty, the
+	// next batch is rolled out as soon as the previous one is ready.
+	// +optional
+	Verification *RolloutVerification `json:"verification,omitempty"`
+
+	// ReleaseCheck re-verifies the Helm releases of the previous batch directly on the workload Clusters before the next
+	// batch is rolled out, rather than trusting the Ready conditions of their HelmReleaseProxies, which may lag behind. If
+	// left empty, the next batch is rolled out as soon as the HelmReleaseProxies of the previous one are ready.
+	// +optional
+	ReleaseCheck *RolloutReleaseCheck `json:"releaseCheck,omitempty"`
+
+	// Hooks run a Job on the management cluster or call a URL before and after the rollout of each generation of the
+	// HelmChartProxy, e.g. to file a change ticket or warm caches. The rollout is gated on their success.
+	// +optional
+	Hooks *RolloutHooks `json:"hooks,omitempty"`
+
+	// ProgressDeadline is the maximum time a rollout may wait for a batch of HelmReleaseProxies to become ready before it
+	// is considered stalled. A stalled rollout is reported on the HelmReleaseProxiesRolloutCompleted condition and with
+	// an event, but keeps waiting for the batch. If it is not specified, a rollout is never considered stalled.
+	// +optional
+	ProgressDeadline *metav1.Duration `json:"progressDeadline,omitempty"`
+}
+
+// DriftDetection defines how drift of the objects of a Helm release from its manifest is detected.
+type DriftDetection struct {
+	// Enabled enables drift detection.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Interval is the minimum interval between two drift checks of a Helm release. If it is not specified, it defaults to
+	// 5m.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// IgnoreRules exclude fields of the objects of the Helm release from drift detection, in addition to the
+	// DriftIgnoreRules that also apply to manifest diffs.
+	// +optional
+	IgnoreRules []DriftIgnoreRule `json:"ignoreRules,omitempty"`
+}
+
+// DriftIgnoreRule excludes fields of the objects of a Helm release from drift detection.
+type DriftIgnoreRule struct {
+	// Paths are the JSON pointers, e.g. `/spec/replicas`, or JSONPaths, e.g. `.spec.template.spec.containers[*].resources`,
+	// of the ignored fields. A `*` segment matches every key or list item.
+	// +kubebuilder:validation:MinItems=1
+	Paths []string `json:"paths"`
+
+	// Kind is the kind of the objects the rule applies to, e.g. `Deployment`. If it is not specified, the rule applies to
+	// objects of any kind.
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// Name is the name of the objects the rule applies to. If it is not specified, the rule applies to objects of any name.
+	// +optional
+	Name string `json:"name,omitempty"`
+}
+
+// Impersonation is an identity to impersonate on a workload Cluster.
+type Impersonation struct {
+	// User is the user name to impersonate.
+	// +kubebuilder:validation:MinLength=1
+	User string `json:"user"`
+
+	// Groups are the groups to impersonate.
+	// +optional
+	Groups []string `json:"groups,omitempty"`
+}
+
+// ClusterResourceSetSignature identifies resources of ClusterResourceSets that apply an addon. At least one of
+// ClusterResourceSetName and Name must be specified.
+type ClusterResourceSetSignature struct {
+	// ClusterResourceSetName is the name of the ClusterResourceSet. If it is not specified, the resources of any
+	// ClusterResourceSet match.
+	// +optional
+	ClusterResourceSetName string `json:"clusterResourceSetName,omitempty"`
+
+	// Kind is the kind of the resource. If it is not specified, both ConfigMaps and Secrets match.
+	// +kubebuilder:validation:Enum=ConfigMap;Secret
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// Name is the name of the resource. If it is not specified, any resource of the ClusterResourceSet matches.
+	// +optional
+	Name string `json:"name,omitempty"`
+}
+
+// ConditionAggregation configures how the conditions of the HelmReleaseProxies are aggregated into the conditions of their
+// HelmChartProxy.
+type ConditionAggregation struct {
+	// Conditions are the conditions of each HelmReleaseProxy that are aggregated in place of its Ready condition, which
+	// must be listed to be aggregated as well. A HelmReleaseProxy without one of the conditions is not affected by it.
+	// +listType=map
+	// +listMapKey=type
+	// +kubebuilder:validation:MinItems=1
+	Conditions []AggregatedCondition `json:"conditions"`
+}
+
+// AggregatedCondition defines how a condition of the HelmReleaseProxies is aggregated.
+type AggregatedCondition struct {
+	// Type is the type of the condition, e.g. Ready or ReconciledRecently.
+	Type clusterv1.ConditionType `json:"type"`
+
+	// Polarity indicates whether the condition signals a problem when it is False, which is the case for Positive
+	// conditions such as Ready, or when it is True, for Negative conditions. Defaults to Positive.
+	// +kubebuilder:validation:Enum=Positive;Negative
+	// +optional
+	Polarity string `json:"polarity,omitempty"`
+
+	// Severity is the severity the condition is reported with when it signals a problem. A condition with the Error
+	// severity marks its HelmReleaseProxy as not ready in the HelmReleaseProxiesReady condition, which blocks the Ready
+	// condition and rollouts of the HelmChartProxy. A condition with the Warning or Info severity is only reported in the
+	// HelmReleaseProxiesHealthy condition, which does not block them. Defaults to Error.
+	// +kubebuilder:validation:Enum=Error;Warning;Info
+	// +optional
+	Severity clusterv1.ConditionSeverity `json:"severity,omitempty"`
+}
+
+// RolloutReleaseCheck defines how the Helm releases of a rollout batch are re-verified on the workload Clusters.
+type RolloutReleaseCheck struct {
+	// CheckResources indicates whether the resources of each Helm release must also be ready, in addition to the release
+	// being deployed with the chart version of its HelmReleaseProxy.
+	// +optional
+	CheckResources bool `json:"checkResources,omitempty"`
+}
+
+// RolloutHookPhase is a string representation of when a rollout hook runs.
+type RolloutHookPhase string
+
+const (
+	// RolloutHookPhasePreRollout hooks run before the HelmReleaseProxies of a generation of the HelmChartProxy are
+	// created or updated.
+	RolloutHookPhasePreRollout RolloutHookPhase = "PreRollout"
+
+	// RolloutHookPhasePostRollout hooks run once the HelmReleaseProxies of a generation of the HelmChartProxy are rolled
+	// out and ready.
+	RolloutHookPhasePostRollout RolloutHookPhase = "PostRollout"
+)
+
+// RolloutHookState is a string representation of the state of a rollout hook.
+type RolloutHookState string
+
+const (
+	// RolloutHookStateRunning indicates that the Job of the rollout hook is running.
+	RolloutHookStateRunning RolloutHookState = "Running"
+
+	// RolloutHookStateSucceeded indicates that the rollout hook succeeded.
+	RolloutHookStateSucceeded RolloutHookState = "Succeeded"
+
+	// RolloutHookStateFailed indicates that the rollout hook failed. A failed Job is run again once it is deleted, while a
+	// failed call to a URL is retried.
+	RolloutHookStateFailed RolloutHookState = "Failed"
+)
+
+// RolloutHooks defines the hooks run around the rollout of each generation of a HelmChartProxy.
+type RolloutHooks struct {
+	// PreRollout hooks must all succeed before the HelmReleaseProxies of a new generation of the HelmChartProxy are
+	// created or updated.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	PreRollout []RolloutHook `json:"preRollout,omitempty"`
+
+	// PostRollout hooks run once the HelmReleaseProxies of a generation of the HelmChartProxy are rolled out and ready.
+	// The rollout is only completed once they all succeed.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	PostRollout []RolloutHook `json:"postRollout,omitempty"`
+}
+
+// RolloutHook defines a Job run on the management cluster or a URL called around a rollout. Exactly one of Job and URL
+// must be specified.
+type RolloutHook struct {
+	// Name identifies the hook in the status, conditions and the name of its Job.
+	// +kubebuilder:validation:MaxLength=20
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+
+	// Job is run in the namespace of the HelmChartProxy. The hook succeeds once the Job completes.
+	// +optional
+	Job *RolloutHookJob `json:"job,omitempty"`
+
+	// URL is called with a POST request whose JSON body identifies the HelmChartProxy, its generation, the phase and the
+	// hook. The hook succeeds if the response has a 2xx status code.
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// Timeout is the maximum duration of the hook. A Job running longer fails. If it is not specified, Jobs are not timed
+	// out and calls to a URL time out after 30s.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// RolloutHookJob defines the container of the Job of a rollout hook. The HELM_CHART_PROXY_NAME,
+// HELM_CHART_PROXY_NAMESPACE, HELM_CHART_PROXY_GENERATION and ROLLOUT_HOOK_PHASE environment variables are set in it.
+type RolloutHookJob struct {
+	// Image is the container image of the Job.
+	Image string `json:"image"`
+
+	// Command is the entrypoint of the container. If it is not specified, the entrypoint of the image is used.
+	// +optional
+	Command []string `json:"command,omitempty"`
+
+	// Args are the arguments of the entrypoint.
+	// +optional
+	Args []string `json:"args,omitempty"`
+
+	// Env are additional environment variables of the container, e.g. credentials from a Secret.
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+
+	// ServiceAccountName is the name of the ServiceAccount the Job runs as. If it is not specified, the default
+	// ServiceAccount of the namespace is used.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// BackoffLimit is the number of retries before the Job is marked failed. If it is not specified, it defaults to the
+	// default of the Job API.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	BackoffLimit *int32 `json:"backoffLimit,omitempty"`
+}
+
+// RolloutHookStatus is the state of a rollout hook for a generation of the HelmChartProxy.
+type RolloutHookStatus struct {
+	// Name is the name of the hook.
+	Name string `json:"name"`
+
+	// Phase is the phase the hook runs in.
+	// +kubebuilder:validation:Enum=PreRollout;PostRollout
+	Phase RolloutHookPhase `json:"phase"`
+
+	// Generation is the generation of the HelmChartProxy the hook ran for.
+	Generation int64 `json:"generation"`
+
+	// State is the state of the hook.
+	// +kubebuilder:validation:Enum=Running;Succeeded;Failed
+	State RolloutHookState `json:"state"`
+
+	// Message describes why the hook failed.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// CompletionTime is the time the hook succeeded or failed.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// VerificationOperator is a string representation of the comparison of a verification query result against its threshold.
+type VerificationOperator string
+
+const (
+	// VerificationOperatorLessThan passes if the query result is less than the threshold.
+	VerificationOperatorLessThan VerificationOperator = "LessThan"
+
+	// VerificationOperatorLessThanOrEqual passes if the query result is less than or equal to the threshold.
+	VerificationOperatorLessThanOrEqual VerificationOperator = "LessThanOrEqual"
+
+	// VerificationOperatorGreaterThan passes if the query result is greater than the threshold.
+	VerificationOperatorGreaterThan VerificationOperator = "GreaterThan"
+
+	// VerificationOperatorGreaterThanOrEqual passes if the query result is greater than or equal to the threshold.
+	VerificationOperatorGreaterThanOrEqual VerificationOperator = "GreaterThanOrEqual"
+)
+
+// RolloutVerification defines the canary analysis run after each rollout
+// batch.
+type RolloutVerification struct {
+	// PrometheusURL is the address of the Prometheus API the queries are run
+	// against, e.g. http://prometheus.monitoring:9090. It must be one of the
+	// addresses allowed by the --rollout-verification-prometheus-urls flag of
+	// the controller.
+	PrometheusURL string `json:"prometheusURL"`
+
+	// Queries are the queries that must all pass for the rollout to proceed.
+	// +kubebuilder:validation:MinItems=1
+	Queries []VerificationQuery `json:"queries"`
+}
+
+// VerificationQuery defines a PromQL query and the threshold its result must
+// satisfy.
+type VerificationQuery struct {
+	// Name identifies the query in conditions and logs.
+	Name string `json:"name"`
+
+	// Query is a PromQL query returning a scalar or a vector with a single
+	// sample.
+	Query string `json:"query"`
+
+	// Operator is the comparison of the query result against the threshold.
+	// Possible values are `LessThan`, `LessThanOrEqual`, `GreaterThan` and `GreaterThanOrEqual`.
+	// +kubebuilder:validation:Enum=LessThan;LessThanOrEqual;GreaterThan;GreaterThanOrEqual
+	Operator string `json:"operator"`
+
+	// Threshold is the decimal number the query result is compared against,
+	// e.g. 0.05.
+	// +kubebuilder:validation:Pattern=`^-?[0-9]+(\.[0-9]+)?$`
+	Threshold string `json:"threshold"`
 }
 
 // RolloutOptions defines rollout options to be used when rolling out
@@ -144,6 +878,32 @@ type RolloutOptions struct {
 	// e.g. an int (5) or percentage of count of total matching clusters (25%)
 	// +optional
 	StepLimit *intstr.IntOrString `json:"stepLimit,omitempty"`
+
+	// FailureDomainLabel is the label of a Cluster whose value identifies its failure domain, e.g. its region.
+	// If defined, each rollout batch includes at most MaxPerFailureDomain Clusters with the same label value, so a
+	// bad change never reaches all Clusters of a failure domain at once. Clusters without the label are not limited.
+	// +optional
+	FailureDomainLabel string `json:"failureDomainLabel,omitempty"`
+
+	// MaxPerFailureDomain is the maximum number of Clusters of a single failure domain included in a rollout batch.
+	// It is only used if FailureDomainLabel is defined, and defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxPerFailureDomain *int32 `json:"maxPerFailureDomain,omitempty"`
+
+	// MaxFailures is the number of HelmReleaseProxies of the rollout whose Helm release may fail before the rollout is
+	// halted. Once it is reached, no new batches are rolled out until the ResumeRolloutAnnotation is set on the
+	// HelmChartProxy. It is not used by the uninstall rollout.
+	// If undefined, the rollout is never halted because of failed Helm releases.
+	// e.g. an int (5) or percentage of count of total matching clusters (25%)
+	// +optional
+	MaxFailures *intstr.IntOrString `json:"maxFailures,omitempty"`
+
+	// ApprovalRequired pauses the rollout after each batch of HelmReleaseProxies until the next batch is approved with
+	// the ApproveRolloutAnnotation on the HelmChartProxy. The first batch is rolled out without approval. It is not used
+	// by the uninstall rollout.
+	// +optional
+	ApprovalRequired bool `json:"approvalRequired,omitempty"`
 }
 
 type HelmOptions struct {
@@ -165,10 +925,17 @@ type HelmOptions struct {
 
 	// Timeout is the time to wait for any individual Kubernetes operation (like
 	// resource creation, Jobs for hooks, etc.) during the performance of a Helm install action.
+	// It does not include the time to fetch the chart, which is limited by FetchTimeout.
 	// Defaults to '10 min'.
 	// +optional
 	Timeout *metav1.Duration `json:"timeout,omitempty"`
 
+	// FetchTimeout is the time to wait for the chart to be fetched from its repository or OCI registry before a Helm
+	// install/upgrade action, separately from the Timeout of the Kubernetes operations of the action.
+	// If not set, fetching the chart is not limited.
+	// +optional
+	FetchTimeout *metav1.Duration `json:"fetchTimeout,omitempty"`
+
 	// SkipCRDs controls whether CRDs should be installed during install/upgrade operation.
 	// By default, CRDs are installed if not already present.
 	// If set, no CRDs will be installed.
@@ -185,6 +952,8 @@ type HelmOptions struct {
 
 	// Atomic indicates the installation/upgrade process to delete the installation or rollback on failure.
 	// If 'Atomic' is set, wait will be enabled automatically during helm install/upgrade operation.
+	// A failed upgrade is rolled back to the revision deployed before it, which is recorded in the UpgradeRollback status
+	// and the UpgradeRolledBack condition of the HelmReleaseProxy.
 	// +optional
 	Atomic bool `json:"atomic,omitempty"`
 
@@ -203,16 +972,63 @@ type HelmOptions struct {
 	// +optional
 	Uninstall *HelmUninstallOptions `json:"uninstall,omitempty"`
 
+	// Test runs the tests of the Helm chart, as `helm test` does, after each install or upgrade of the Helm release.
+	// The results are recorded in the Test status and the ReleaseTested condition of the HelmReleaseProxy.
+	// +optional
+	Test *HelmTestOptions `json:"test,omitempty"`
+
 	// EnableClientCache is a flag to enable Helm client cache. If it is not specified, it will be set to true.
 	// +kubebuilder:default=false
 	// +optional
 	EnableClientCache bool `json:"enableClientCache,omitempty"`
+
+	// Protect prevents the Helm release from being uninstalled, whether the Cluster is no longer selected or the
+	// HelmChartProxy or HelmReleaseProxy is deleted, unless the HelmReleaseProxy or its HelmChartProxy has the
+	// allow-uninstall annotation. This protects CNIs and other critical addons from accidental label changes.
+	// +optional
+	Protect bool `json:"protect,omitempty"`
+
+	// PendingRecovery recovers the Helm release once it is stuck in a pending-install, pending-upgrade or pending-rollback
+	// state, e.g. because the controller was restarted in the middle of an operation, which otherwise fails every later
+	// operation with "another operation (install/upgrade/rollback) is in progress". If it is not specified, a stuck Helm
+	// release is only reported with the HelmReleasePending reason.
+	// +optional
+	PendingRecovery *HelmPendingRecoveryOptions `json:"pendingRecovery,omitempty"`
+}
+
+// PendingRecoveryPolicy is a string representation of how a Helm release stuck in a pending state is recovered.
+type PendingRecoveryPolicy string
+
+const (
+	// PendingRecoveryPolicyRollback rolls the Helm release back to its last deployed revision. A stuck first revision,
+	// which has nothing to roll back to, is deleted.
+	PendingRecoveryPolicyRollback PendingRecoveryPolicy = "Rollback"
+
+	// PendingRecoveryPolicyDelete deletes the stuck revision from the history of the Helm release, so that the next
+	// install or upgrade starts over from the revision before it.
+	PendingRecoveryPolicyDelete PendingRecoveryPolicy = "Delete"
+)
+
+// HelmPendingRecoveryOptions configures the recovery of a Helm release stuck in a pending state.
+type HelmPendingRecoveryOptions struct {
+	// Policy indicates whether a stuck Helm release is rolled back to its last deployed revision or its stuck revision is
+	// deleted. If it is not specified, it defaults to `Rollback`.
+	// Possible values are `Rollback`, `Delete`, or unset.
+	// +kubebuilder:validation:Enum="";Rollback;Delete
+	// +optional
+	Policy string `json:"policy,omitempty"`
+
+	// StuckAfter is how long the Helm release must have been pending before it is considered stuck, so that an operation
+	// still in progress is not interrupted. If it is not specified, it defaults to Options.Timeout plus one minute, after
+	// which no operation of the controller can still be running.
+	// +optional
+	StuckAfter *metav1.Duration `json:"stuckAfter,omitempty"`
 }
 
 type HelmInstallOptions struct {
 	// CreateNamespace indicates the Helm install/upgrade action to create the
 	// HelmChartProxySpec.ReleaseNamespace if it does not exist yet.
-	// On uninstall, the namespace will not be garbage collected.
+	// On uninstall, the namespace will not be garbage collected unless Uninstall.DeleteCreatedNamespace is set.
 	// If it is not specified by user, will be set to default 'true'.
 	// +kubebuilder:default=true
 	// +optional
@@ -221,6 +1037,28 @@ type HelmInstallOptions struct {
 	// IncludeCRDs determines whether CRDs stored as a part of helm templates directory should be installed.
 	// +optional
 	IncludeCRDs bool `json:"includeCRDs,omitempty"`
+
+	// CheckResourceQuota checks before install that the pods of the workloads of the chart fit into the pods, CPU and memory
+	// left in the ResourceQuotas of the release namespace, and fails the install right away with the QuotaExceeded reason
+	// otherwise, rather than waiting for pods that are never admitted.
+	// +optional
+	CheckResourceQuota bool `json:"checkResourceQuota,omitempty"`
+}
+
+// HelmTestOptions configures the tests of the Helm chart run after each install or upgrade of the Helm release.
+type HelmTestOptions struct {
+	// Enable enables running the tests of the Helm chart after each install or upgrade of the Helm release.
+	// +optional
+	Enable bool `json:"enable,omitempty"`
+
+	// Timeout is the time to wait for the tests to complete. If it is not specified, Options.Timeout is used.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// Filters selects the tests to run by name. Tests named with a "!" prefix are not run. If any name has no prefix,
+	// only the named tests are run.
+	// +optional
+	Filters []string `json:"filters,omitempty"`
 }
 
 type HelmUpgradeOptions struct {
@@ -265,6 +1103,12 @@ type HelmUninstallOptions struct {
 	// Description represents human readable information to be shown on release uninstall.
 	// +optional
 	Description string `json:"description,omitempty"`
+
+	// DeleteCreatedNamespace indicates the helm uninstall operation to delete the release namespace if it was created for
+	// the release by Install.CreateNamespace and no workloads, Services, PersistentVolumeClaims, Secrets or ConfigMaps
+	// remain in it.
+	// +optional
+	DeleteCreatedNamespace bool `json:"deleteCreatedNamespace,omitempty"`
 }
 
 type Credentials struct {
@@ -284,11 +1128,347 @@ type TLSConfig struct {
 	// InsecureSkipTLSVerify controls whether the Helm client should verify the server's certificate.
 	// +optional
 	InsecureSkipTLSVerify bool `json:"insecureSkipTLSVerify,omitempty"`
+
+	// CertManagerRef is a reference to a cert-manager Certificate whose Secret holds the client certificate and key, at the
+	// keys tls.crt and tls.key, presented to chart repositories and registries requiring mutual TLS. The Secret is read on
+	// every reconcile, so renewed certificates are used without restarting the controller.
+	// +optional
+	CertManagerRef *CertManagerReference `json:"certManagerRef,omitempty"`
+}
+
+// ChartVerification defines how the signature of a Helm chart is verified.
+type ChartVerification struct {
+	// Provenance verifies the provenance file (.prov) of charts from HTTP repositories, as `helm install --verify` does.
+	// +optional
+	Provenance *ProvenanceVerification `json:"provenance,omitempty"`
+
+	// Cosign verifies the cosign signatures of charts from OCI registries with the cosign binary of the controller, as
+	// `cosign verify` does.
+	// +optional
+	Cosign *CosignVerification `json:"cosign,omitempty"`
+}
+
+// ProvenanceVerification defines the keys the provenance of a Helm chart is verified with.
+type ProvenanceVerification struct {
+	// KeyringSecretRef is a reference to a Secret containing the GPG public keyring at the key keyring.gpg. If its namespace
+	// is not specified, it defaults to the namespace of the HelmChartProxy.
+	KeyringSecretRef corev1.SecretReference `json:"keyringSecretRef"`
+}
+
+// CosignVerification defines the keys the cosign signatures of an OCI chart are verified with. Exactly one of
+// PublicKeySecretRef and Keyless must be specified.
+type CosignVerification struct {
+	// PublicKeySecretRef is a reference to a Secret containing the PEM encoded public key of key-based signatures at the
+	// key cosign.pub. If its namespace is not specified, it defaults to the namespace of the HelmChartProxy.
+	// +optional
+	PublicKeySecretRef *corev1.SecretReference `json:"publicKeySecretRef,omitempty"`
+
+	// Keyless verifies keyless signatures, made with short-lived certificates issued by Fulcio to an OIDC identity and
+	// recorded in the Rekor transparency log.
+	// +optional
+	Keyless *CosignKeylessVerification `json:"keyless,omitempty"`
+}
+
+// CosignKeylessVerification defines the identity keyless cosign signatures must be made by and the roots they are
+// verified with.
+type CosignKeylessVerification struct {
+	// Issuer is the OIDC issuer of the identity the chart must be signed by, e.g.
+	// `https://token.actions.githubusercontent.com`.
+	// +kubebuilder:validation:MinLength=1
+	Issuer string `json:"issuer"`
+
+	// Subject is the identity the chart must be signed by, i.e. the email address or URI in the signing certificate, e.g.
+	// `https://github.com/org/repo/.github/workflows/release.yaml@refs/heads/main`.
+	// +kubebuilder:validation:MinLength=1
+	Subject string `json:"subject"`
+
+	// TrustedRootSecretRef is a reference to a Secret containing the PEM encoded Fulcio root and intermediate certificates
+	// at the key fulcio.crt and the PEM encoded Rekor public key at the key rekor.pub. If its namespace is not specified,
+	// it defaults to the namespace of the HelmChartProxy.
+	TrustedRootSecretRef corev1.SecretReference `json:"trustedRootSecretRef"`
+}
+
+// GitChartSource defines a Helm chart stored in a directory of a Git repository.
+type GitChartSource struct {
+	// URL is the HTTP or HTTPS URL of the Git repository, e.g. https://github.com/org/repo.git. The commit is fetched
+	// with a shallow fetch of the git binary of the controller.
+	// +kubebuilder:validation:Pattern=`^https?://`
+	URL string `json:"url"`
+
+	// Ref is the branch, tag or full commit SHA the chart is checked out at. Branches and tags are resolved to their commit
+	// whenever the Helm releases are checked for an upgrade, e.g. every ResyncPeriod, and releases are upgraded when the
+	// commit changes. If it is not specified, the default branch of the repository is checked out.
+	// +optional
+	Ref string `json:"ref,omitempty"`
+
+	// Path is the path of the chart directory in the repository. If it is not specified, the chart is at the root of the
+	// repository.
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// AuthSecretRef is a reference to a Secret holding the credentials of the Git repository, either the keys username and
+	// password for basic authentication or the key token for bearer token authentication. Tokens of Git hosts accepting them
+	// as basic authentication passwords, e.g. GitHub, are set as password. If the namespace is not specified, the namespace
+	// of the HelmChartProxy is used.
+	// +optional
+	AuthSecretRef *corev1.SecretReference `json:"authSecretRef,omitempty"`
+}
+
+// PostRenderer defines the patches applied to the manifests rendered from a Helm chart.
+type PostRenderer struct {
+	// Patches are Kustomize patches applied to the rendered manifests in order.
+	// +kubebuilder:validation:MinItems=1
+	Patches []KustomizePatch `json:"patches"`
+}
+
+// KustomizePatch is a strategic merge patch or a JSON 6902 patch applied to the rendered manifests, as in the patches of a
+// Kustomization.
+type KustomizePatch struct {
+	// Patch is the patch in YAML or JSON. A strategic merge patch is a partial Kubernetes object, a JSON 6902 patch is a
+	// list of operations.
+	// +kubebuilder:validation:MinLength=1
+	Patch string `json:"patch"`
+
+	// Target selects the rendered resources the patch is applied to. It is required for JSON 6902 patches. If it is not
+	// specified, a strategic merge patch is applied to the resource matching its apiVersion, kind, name and namespace.
+	// +optional
+	Target *PatchSelector `json:"target,omitempty"`
+}
+
+// PatchSelector selects the rendered resources a patch is applied to. Resources must match all specified fields.
+type PatchSelector struct {
+	// Group is the API group of the resources.
+	// +optional
+	Group string `json:"group,omitempty"`
+
+	// Version is the API version of the resources.
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// Kind is the kind of the resources.
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// Name is the name of the resources. It is a regular expression.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Namespace is the namespace of the resources. It is a regular expression.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// LabelSelector selects the resources by their labels, in the format of `kubectl get -l`.
+	// +optional
+	LabelSelector string `json:"labelSelector,omitempty"`
+
+	// AnnotationSelector selects the resources by their annotations, in the format of a label selector.
+	// +optional
+	AnnotationSelector string `json:"annotationSelector,omitempty"`
+}
+
+// CertManagerReference references a cert-manager Certificate.
+type CertManagerReference struct {
+	// Name is the name of the Certificate.
+	Name string `json:"name"`
+
+	// Namespace is the namespace of the Certificate. If it is not specified, it defaults to the namespace of the
+	// HelmChartProxy.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// ValuesTemplateOptions defines the Go template options used to render a valuesTemplate.
+type ValuesTemplateOptions struct {
+	// MissingKey controls how references to keys that are not present are rendered. `Default` renders "<no value>",
+	// `Zero` renders an empty value, and `Error` fails rendering. If not specified, it defaults to `Default`.
+	// Possible values are `Default`, `Zero`, `Error`, or unset.
+	// +kubebuilder:validation:Enum="";Default;Zero;Error
+	// +optional
+	MissingKey string `json:"missingKey,omitempty"`
+
+	// LeftDelimiter is the left action delimiter of the valuesTemplate. Changing the delimiters allows Helm-style `{{ }}`
+	// expressions to be passed through to the chart without escaping. If not specified, it defaults to `{{`.
+	// LeftDelimiter and RightDelimiter must be set together.
+	// +optional
+	LeftDelimiter string `json:"leftDelimiter,omitempty"`
+
+	// RightDelimiter is the right action delimiter of the valuesTemplate. If not specified, it defaults to `}}`.
+	// LeftDelimiter and RightDelimiter must be set together.
+	// +optional
+	RightDelimiter string `json:"rightDelimiter,omitempty"`
+}
+
+// ValuesFromSource references a key of a ConfigMap or Secret holding values for a Helm chart.
+type ValuesFromSource struct {
+	// Kind is the kind of the object holding the values.
+	// Possible values are `ConfigMap` and `Secret`.
+	// +kubebuilder:validation:Enum=ConfigMap;Secret
+	Kind string `json:"kind"`
+
+	// Name is the name of the object in the namespace of the HelmChartProxy.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// ValuesKey is the key in the object holding the values as YAML. If it is not specified, it defaults to `values.yaml`.
+	// +optional
+	ValuesKey string `json:"valuesKey,omitempty"`
+
+	// Optional ignores the source if the object or key does not exist, instead of failing to render the values.
+	// +optional
+	Optional bool `json:"optional,omitempty"`
+}
+
+// ProxyValues defines the value paths, in dot notation, e.g. `global.proxy.httpProxy`, that the proxy settings of a Cluster
+// are injected into. Proxy settings are only set at paths that the rendered values leave unset, while the trust bundle is
+// appended to the certificates at its path.
+type ProxyValues struct {
+	// HTTPProxyPath is the value path of the HTTP proxy URL.
+	// +optional
+	HTTPProxyPath string `json:"httpProxyPath,omitempty"`
+
+	// HTTPSProxyPath is the value path of the HTTPS proxy URL.
+	// +optional
+	HTTPSProxyPath string `json:"httpsProxyPath,omitempty"`
+
+	// NoProxyPath is the value path of the comma-separated hosts excluded from the proxy.
+	// +optional
+	NoProxyPath string `json:"noProxyPath,omitempty"`
+
+	// TrustBundlePath is the value path of the PEM-encoded CA certificates to trust.
+	// +optional
+	TrustBundlePath string `json:"trustBundlePath,omitempty"`
+}
+
+// Environment is a named group of the selected Clusters with its own chart version and values overlay.
+type Environment struct {
+	// Name is the name of the environment, e.g. prod.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// ClusterSelector selects the Clusters of the environment among the Clusters selected by the ClusterSelector of the
+	// HelmChartProxy.
+	ClusterSelector metav1.LabelSelector `json:"clusterSelector"`
+
+	// Version is the version of the Helm chart on the Clusters of the environment. If it is not specified, the first
+	// environment uses the Version of the HelmChartProxy and every other environment is promoted the version of the
+	// previous environment once all of its HelmReleaseProxies are ready with it. Clusters of an environment that has not
+	// been promoted a version yet are not installed.
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// ValuesTemplate is an inline YAML of values merged over the values rendered from the ValuesTemplate of the
+	// HelmChartProxy on the Clusters of the environment. It supports the same Go templating.
+	// +optional
+	ValuesTemplate string `json:"valuesTemplate,omitempty"`
+}
+
+// ValuesByReference defines which blocks of the rendered values are stored by reference.
+type ValuesByReference struct {
+	// MinSize is the size in bytes of the YAML of a top-level value from which it is stored in a ConfigMap rather than
+	// inline. If it is not specified, it defaults to 4096.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MinSize *int32 `json:"minSize,omitempty"`
+}
+
+// MetricsOptions defines how metrics are emitted for the HelmReleaseProxies of a HelmChartProxy.
+type MetricsOptions struct {
+	// ClusterLabelThreshold is the number of selected Clusters above which HelmReleaseProxy metrics are no longer
+	// labeled per Cluster. If it is not specified, metrics are always labeled per Cluster.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	ClusterLabelThreshold *int32 `json:"clusterLabelThreshold,omitempty"`
+
+	// ClusterLabelPolicy indicates how HelmReleaseProxy metrics are emitted once ClusterLabelThreshold is exceeded.
+	// `Aggregate` emits a single series per HelmChartProxy with an empty cluster label. If not specified, it defaults to
+	// `Aggregate`.
+	// Possible values are `Aggregate`, or unset.
+	// +kubebuilder:validation:Enum="";Aggregate
+	// +optional
+	ClusterLabelPolicy string `json:"clusterLabelPolicy,omitempty"`
+}
+
+// ClusterReadinessOptions defines the schedulable capacity a Cluster must reach before a Helm chart is installed on it.
+type ClusterReadinessOptions struct {
+	// ReadyWorkerNodes is the minimum number of Ready nodes without the control plane role on the Cluster.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	ReadyWorkerNodes *int32 `json:"readyWorkerNodes,omitempty"`
+
+	// MachineDeployment is a MachineDeployment of the Cluster that must reach a minimum number of ready replicas.
+	// +optional
+	MachineDeployment *MachineDeploymentReadiness `json:"machineDeployment,omitempty"`
+
+	// MachinePool is a MachinePool of the Cluster that must reach a minimum number of ready replicas, e.g. for Clusters
+	// whose workers are only managed node groups.
+	// +optional
+	MachinePool *MachinePoolReadiness `json:"machinePool,omitempty"`
+}
+
+// FailoverOptions defines the ownership lease of the Helm releases on a Cluster shared by several management clusters.
+type FailoverOptions struct {
+	// LeaseDuration is the duration after its last renewal that the ownership lease expires, so that a standby management
+	// cluster takes over. The holder renews the lease every half of the duration. If it is not specified, it defaults to
+	// 1m.
+	// +optional
+	LeaseDuration *metav1.Duration `json:"leaseDuration,omitempty"`
+}
+
+// MachineDeploymentReadiness defines the ready replicas a MachineDeployment must reach.
+type MachineDeploymentReadiness struct {
+	// Name is the name of the MachineDeployment in the namespace of the Cluster.
+	Name string `json:"name"`
+
+	// MinReadyReplicas is the minimum number of ready replicas of the MachineDeployment. If it is not specified, it
+	// defaults to the desired replicas of the MachineDeployment.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinReadyReplicas *int32 `json:"minReadyReplicas,omitempty"`
+}
+
+// MachinePoolReadiness defines the ready replicas a MachinePool must reach.
+type MachinePoolReadiness struct {
+	// Name is the name of the MachinePool in the namespace of the Cluster.
+	Name string `json:"name"`
+
+	// MinReadyReplicas is the minimum number of ready replicas of the MachinePool. If it is not specified, it defaults
+	// to the desired replicas of the MachinePool.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinReadyReplicas *int32 `json:"minReadyReplicas,omitempty"`
 }
 
 type RolloutStatus struct {
 	Count    *int `json:"count,omitempty"`
 	StepSize *int `json:"stepSize,omitempty"`
+
+	// LastProgressTime is the last time a batch of HelmReleaseProxies was rolled out.
+	// +optional
+	LastProgressTime *metav1.Time `json:"lastProgressTime,omitempty"`
+
+	// AverageBatchDuration is the average time between the most recent batches of the rollout, including the time spent
+	// waiting for the HelmReleaseProxies to become ready and for the verification queries to pass.
+	// +optional
+	AverageBatchDuration *metav1.Duration `json:"averageBatchDuration,omitempty"`
+
+	// ObservedBatches is the number of batch durations AverageBatchDuration is averaged over.
+	// +optional
+	ObservedBatches int32 `json:"observedBatches,omitempty"`
+
+	// EstimatedCompletionTime is the time the rollout is estimated to complete, extrapolated from the step sizes of the
+	// remaining batches and AverageBatchDuration. It is only set once a batch duration has been observed, and cleared
+	// once the rollout completes.
+	// +optional
+	EstimatedCompletionTime *metav1.Time `json:"estimatedCompletionTime,omitempty"`
+
+	// LastBatch is the names of the Clusters whose HelmReleaseProxies were rolled out in the most recent batch.
+	// +optional
+	LastBatch []string `json:"lastBatch,omitempty"`
+
+	// ApprovedCount is the Count of HelmReleaseProxies rolled out when the next batch was last approved with the
+	// ApproveRolloutAnnotation. A rollout requiring approval only proceeds to the next batch while it equals Count.
+	// +optional
+	ApprovedCount *int `json:"approvedCount,omitempty"`
 }
 
 // HelmChartProxyStatus defines the observed state of HelmChartProxy.
@@ -301,8 +1481,72 @@ type HelmChartProxyStatus struct {
 	// +optional
 	MatchingClusters []corev1.ObjectReference `json:"matchingClusters"`
 
+	// MatchingClusterCount is the number of Clusters selected by the ClusterSelector. Unlike the MatchingClusters, it is
+	// also reported when the controller runs with a lightweight status.
+	// +optional
+	MatchingClusterCount int32 `json:"matchingClusterCount,omitempty"`
+
 	Rollout *RolloutStatus `json:"rollout,omitempty"`
 
+	// UninstallRollout is the status of the batched deletion of the HelmReleaseProxies of Clusters that are no longer
+	// selected. Count is the number of HelmReleaseProxies deleted so far. It is cleared once all of them are gone.
+	// +optional
+	UninstallRollout *RolloutStatus `json:"uninstallRollout,omitempty"`
+
+	// RolloutHooks is the state of the rollout hooks of the current generation of the HelmChartProxy.
+	// +optional
+	RolloutHooks []RolloutHookStatus `json:"rolloutHooks,omitempty"`
+
+	// PendingUninstalls is the list of references to Clusters that are no longer selected but whose Helm release is not
+	// uninstalled because the HelmChartProxy has the UninstallDryRunAnnotation or the uninstall is awaiting confirmation.
+	// +optional
+	PendingUninstalls []corev1.ObjectReference `json:"pendingUninstalls,omitempty"`
+
+	// OutOfDateReleases is the list of references to HelmReleaseProxies whose spec does not yet match the desired state
+	// rendered from the HelmChartProxy, e.g. because they are waiting for their rollout batch or were installed with the
+	// InstallOnce ReconcileStrategy.
+	// +optional
+	OutOfDateReleases []corev1.ObjectReference `json:"outOfDateReleases,omitempty"`
+
+	// DiscoveredReleases is the list of Helm releases found on the selected Clusters while the HelmChartProxy is in
+	// discovery mode.
+	// +optional
+	DiscoveredReleases []DiscoveredRelease `json:"discoveredReleases,omitempty"`
+
+	// ClusterResourceSetClusters is the names of the selected Clusters the Helm chart is not installed on, because a
+	// ClusterResourceSet matching the ClusterResourceSetSignatures already applies the addon to them.
+	// +optional
+	ClusterResourceSetClusters []string `json:"clusterResourceSetClusters,omitempty"`
+
+	// DeployedCharts is the history of the charts deployed by the HelmReleaseProxies, with the time each was first deployed
+	// and the time it no longer was on any Cluster. Only the most recent entries are kept.
+	// +optional
+	DeployedCharts []DeployedChart `json:"deployedCharts,omitempty"`
+
+	// Environments is the status of the promotion of the environments of the HelmChartProxy, in the order of the
+	// environments.
+	// +optional
+	Environments []EnvironmentStatus `json:"environments,omitempty"`
+
+	// ChartVersions describes the versions of the chart available in its OCI repository, to tell which version the
+	// Version resolves to and what the chart can be upgraded to. It is only set if the controller lists chart versions.
+	// +optional
+	ChartVersions *ChartVersions `json:"chartVersions,omitempty"`
+
+	// ClusterOperations is the list of selected Clusters with queued or in-flight Helm operations, of any HelmChartProxy,
+	// when the HelmChartProxy was last reconciled.
+	// +optional
+	ClusterOperations []ClusterOperations `json:"clusterOperations,omitempty"`
+
+	// LastSuccessfulReconcileTime is the time the HelmChartProxy was last reconciled without error, updated at most once a
+	// minute.
+	// +optional
+	LastSuccessfulReconcileTime *metav1.Time `json:"lastSuccessfulReconcileTime,omitempty"`
+
+	// Revision is the number of the HelmChartProxyRevision recording the current spec of the HelmChartProxy.
+	// +optional
+	Revision int64 `json:"revision,omitempty"`
+
 	// ObservedGeneration is the latest generation observed by the controller.
 	// +optional
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
@@ -343,6 +1587,137 @@ func (c *HelmChartProxy) SetConditions(conditions clusterv1.Conditions) {
 	c.Status.Conditions = conditions
 }
 
+// GetLastSuccessfulReconcileTime returns the time an HelmChartProxy object was last reconciled without error.
+func (c *HelmChartProxy) GetLastSuccessfulReconcileTime() *metav1.Time {
+	return c.Status.LastSuccessfulReconcileTime
+}
+
+// SetLastSuccessfulReconcileTime will set the time an HelmChartProxy object was last reconciled without error.
+func (c *HelmChartProxy) SetLastSuccessfulReconcileTime(time *metav1.Time) {
+	c.Status.LastSuccessfulReconcileTime = time
+}
+
+// ChartVersions describes the versions of the chart of a HelmChartProxy available in its repository.
+type ChartVersions struct {
+	// Latest is the most recent stable version of the chart.
+	// +optional
+	Latest string `json:"latest,omitempty"`
+
+	// Resolved is the most recent version of the chart satisfying the Version of the HelmChartProxy, or the latest stable
+	// version if the Version is not specified. It is empty if no version satisfies the Version.
+	// +optional
+	Resolved string `json:"resolved,omitempty"`
+
+	// Upgrades are the most recent stable versions of the chart newer than the resolved version, newest first.
+	// +optional
+	Upgrades []string `json:"upgrades,omitempty"`
+}
+
+// EnvironmentStatus describes the version promoted to an environment of a HelmChartProxy.
+type EnvironmentStatus struct {
+	// Name is the name of the environment.
+	Name string `json:"name"`
+
+	// Promoted is true once the environment has been promoted a version. Clusters of an environment that has not been
+	// promoted a version yet are not installed.
+	// +optional
+	Promoted bool `json:"promoted,omitempty"`
+
+	// Version is the version of the Helm chart promoted to the environment.
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// Clusters is the number of selected Clusters in the environment.
+	// +optional
+	Clusters int32 `json:"clusters,omitempty"`
+
+	// ReadyClusters is the number of Clusters of the environment whose HelmReleaseProxy is ready with the promoted
+	// version.
+	// +optional
+	ReadyClusters int32 `json:"readyClusters,omitempty"`
+}
+
+// DeployedChart describes a chart deployed by the HelmReleaseProxies of a HelmChartProxy.
+type DeployedChart struct {
+	// RepoURL is the URL of the Helm chart repository.
+	RepoURL string `json:"repoURL,omitempty"`
+
+	// ChartName is the name of the Helm chart in the repository.
+	ChartName string `json:"chartName"`
+
+	// Version is the version of the Helm chart.
+	Version string `json:"version"`
+
+	// ChartDigest is the digest of the contents of the Helm chart, as in the chart digest label of the HelmReleaseProxies.
+	ChartDigest string `json:"chartDigest"`
+
+	// DeployedTime is the time the chart was first deployed to a Cluster.
+	DeployedTime metav1.Time `json:"deployedTime"`
+
+	// RetiredTime is the time the chart was no longer deployed to any Cluster. It is not set while it is still deployed.
+	// +optional
+	RetiredTime *metav1.Time `json:"retiredTime,omitempty"`
+}
+
+// ClusterOperations describes the Helm operations queued and in flight on a workload Cluster.
+type ClusterOperations struct {
+	// ClusterName is the name of the Cluster.
+	ClusterName string `json:"clusterName"`
+
+	// Queued is the number of Helm operations waiting to run on the Cluster.
+	Queued int32 `json:"queued"`
+
+	// InFlight is the number of Helm operations running on the Cluster.
+	InFlight int32 `json:"inFlight"`
+}
+
+// DiscoveredRelease describes a Helm release found on a workload Cluster in discovery mode.
+type DiscoveredRelease struct {
+	// ClusterName is the name of the Cluster the Helm release was found on.
+	ClusterName string `json:"clusterName"`
+
+	// ReleaseName is the name of the Helm release.
+	ReleaseName string `json:"releaseName"`
+
+	// ReleaseNamespace is the namespace the Helm release is installed in.
+	ReleaseNamespace string `json:"releaseNamespace"`
+
+	// ChartName is the name of the chart of the Helm release.
+	// +optional
+	ChartName string `json:"chartName,omitempty"`
+
+	// Version is the version of the chart of the Helm release.
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// Revision is the current revision of the Helm release.
+	// +optional
+	Revision int `json:"revision,omitempty"`
+
+	// Status is the status of the current revision of the Helm release.
+	// +optional
+	Status string `json:"status,omitempty"`
+}
+
+// IsInDiscoveryMode returns true if the HelmChartProxy has the DiscoveryModeAnnotation set to "true".
+func (c *HelmChartProxy) IsInDiscoveryMode() bool {
+	return c.GetAnnotations()[DiscoveryModeAnnotation] == "true"
+}
+
+// IsVersionConstraint returns true if the chart version is a valid semver constraint rather than an exact version, e.g.
+// `>=1.2.0 <2.0.0`. Exact versions may be prefixed with a v.
+func IsVersionConstraint(version string) bool {
+	if version == "" {
+		return false
+	}
+	if _, err := semver.StrictNewVersion(strings.TrimPrefix(version, "v")); err == nil {
+		return false
+	}
+	_, err := semver.NewConstraint(version)
+
+	return err == nil
+}
+
 // SetMatchingClusters will set the given list of matching clusters on an HelmChartProxy object.
 func (c *HelmChartProxy) SetMatchingClusters(clusterList []clusterv1.Cluster) {
 	matchingClusters := make([]corev1.ObjectReference, 0, len(clusterList))
@@ -356,6 +1731,7 @@ func (c *HelmChartProxy) SetMatchingClusters(clusterList []clusterv1.Cluster) {
 	}
 
 	c.Status.MatchingClusters = matchingClusters
+	c.Status.MatchingClusterCount = int32(len(clusterList))
 }
 
 func init() {
@@ -20,26 +20,37 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/Masterminds/semver/v3"
+	"github.com/opencontainers/go-digest"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/yaml"
 )
 
 // log is for logging in this package.
 var helmchartproxylog = logf.Log.WithName("helmchartproxy-resource")
 
-func (r *HelmChartProxy) SetupWebhookWithManager(mgr ctrl.Manager) error {
-	w := new(helmChartProxyWebhook)
-
+// SetupWebhookWithManager sets up the HelmChartProxy webhook with the Manager.
+func (w *HelmChartProxyWebhook) SetupWebhookWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewWebhookManagedBy(mgr).
-		For(r).
+		For(&HelmChartProxy{}).
 		WithDefaulter(w).
 		WithValidator(w).
 		Complete()
@@ -47,43 +58,58 @@ func (r *HelmChartProxy) SetupWebhookWithManager(mgr ctrl.Manager) error {
 
 //+kubebuilder:webhook:path=/mutate-addons-cluster-x-k8s-io-v1alpha1-helmchartproxy,mutating=true,failurePolicy=fail,sideEffects=None,groups=addons.cluster.x-k8s.io,resources=helmchartproxies,verbs=create;update,versions=v1alpha1,name=helmchartproxy.kb.io,admissionReviewVersions=v1
 
-type helmChartProxyWebhook struct{}
+// HelmChartProxyWebhook defaults and validates HelmChartProxies.
+type HelmChartProxyWebhook struct {
+	// BlastRadiusWarningThreshold is the number of matching Clusters above which an update of a HelmChartProxy without an
+	// upgrade rollout is warned about. A threshold of 0 disables the warning.
+	BlastRadiusWarningThreshold int
+}
 
 var (
-	_ webhook.CustomValidator = &helmChartProxyWebhook{}
-	_ webhook.CustomDefaulter = &helmChartProxyWebhook{}
+	_ webhook.CustomValidator = &HelmChartProxyWebhook{}
+	_ webhook.CustomDefaulter = &HelmChartProxyWebhook{}
 )
 
 const helmTimeout = 10 * time.Minute
 
+// DefaultBlastRadiusWarningThreshold is the default number of matching Clusters above which an update of a HelmChartProxy
+// without an upgrade rollout is warned about.
+const DefaultBlastRadiusWarningThreshold = 10
+
 // Default implements webhook.Defaulter so a webhook will be registered for the type.
-func (*helmChartProxyWebhook) Default(_ context.Context, objRaw runtime.Object) error {
+func (*HelmChartProxyWebhook) Default(_ context.Context, objRaw runtime.Object) error {
 	newObj, ok := objRaw.(*HelmChartProxy)
 	if !ok {
 		return apierrors.NewBadRequest(fmt.Sprintf("expected a HelmChartProxy but got a %T", objRaw))
 	}
 	helmchartproxylog.Info("default", "name", newObj.Name)
 
-	if newObj.Spec.ReleaseNamespace == "" {
-		newObj.Spec.ReleaseNamespace = "default"
+	newObj.Spec.SetDefaults()
+
+	return nil
+}
+
+// SetDefaults sets the defaults the defaulting webhook sets on the spec of a HelmChartProxy, so that controllers creating
+// HelmChartProxies can compare their desired spec to the one stored.
+func (s *HelmChartProxySpec) SetDefaults() {
+	if s.ReleaseNamespace == "" {
+		s.ReleaseNamespace = "default"
 	}
 
-	if newObj.Spec.Options.Atomic {
-		newObj.Spec.Options.Wait = true
+	if s.Options.Atomic {
+		s.Options.Wait = true
 	}
 
 	// Note: timeout is also needed to ensure that Spec.Options.Wait works.
-	if newObj.Spec.Options.Timeout == nil {
-		newObj.Spec.Options.Timeout = &metav1.Duration{Duration: helmTimeout}
+	if s.Options.Timeout == nil {
+		s.Options.Timeout = &metav1.Duration{Duration: helmTimeout}
 	}
-
-	return nil
 }
 
 //+kubebuilder:webhook:path=/validate-addons-cluster-x-k8s-io-v1alpha1-helmchartproxy,mutating=false,failurePolicy=fail,sideEffects=None,groups=addons.cluster.x-k8s.io,resources=helmchartproxies,verbs=create;update,versions=v1alpha1,name=vhelmchartproxy.kb.io,admissionReviewVersions=v1
 
 // ValidateCreate implements webhook.Validator so a webhook will be registered for the type.
-func (*helmChartProxyWebhook) ValidateCreate(_ context.Context, objRaw runtime.Object) (admission.Warnings, error) {
+func (*HelmChartProxyWebhook) ValidateCreate(_ context.Context, objRaw runtime.Object) (admission.Warnings, error) {
 	newObj, ok := objRaw.(*HelmChartProxy)
 	if !ok {
 		return nil, apierrors.NewBadRequest(fmt.Sprintf("expected a HelmChartProxy but got a %T", objRaw))
@@ -91,15 +117,46 @@ func (*helmChartProxyWebhook) ValidateCreate(_ context.Context, objRaw runtime.O
 
 	helmchartproxylog.Info("validate create", "name", newObj.Name)
 
-	if err := isUrlValid(newObj.Spec.RepoURL); err != nil {
-		return nil, err
+	if newObj.Spec.ChartBundleRef == nil && newObj.Spec.RepositoryRef == nil && newObj.Spec.Git == nil && newObj.Spec.Manifests == "" {
+		if err := isUrlValid(newObj.Spec.RepoURL); err != nil {
+			return nil, err
+		}
 	}
 
-	return nil, nil
+	allErrs := validateValuesTemplateOptions(newObj.Spec.ValuesTemplateOptions)
+	allErrs = append(allErrs, validateVersion(newObj.Spec)...)
+	allErrs = append(allErrs, validateDigest(newObj.Spec)...)
+	allErrs = append(allErrs, validateChartBundleRef(newObj.Spec)...)
+	allErrs = append(allErrs, validateRepositoryRef(newObj.Spec)...)
+	allErrs = append(allErrs, validateGit(newObj.Spec)...)
+	allErrs = append(allErrs, validateManifests(newObj.Spec)...)
+	allErrs = append(allErrs, validateRepositoryCredentials(newObj.Spec)...)
+	allErrs = append(allErrs, validateVerify(newObj.Spec)...)
+	allErrs = append(allErrs, validatePostRenderer(newObj.Spec.PostRenderer)...)
+	allErrs = append(allErrs, validateResyncPeriod(newObj.Spec.ResyncPeriod)...)
+	allErrs = append(allErrs, validateReconcileInterval(newObj.Spec.ReconcileInterval)...)
+	allErrs = append(allErrs, validateFetchTimeout(newObj.Spec.Options.FetchTimeout)...)
+	allErrs = append(allErrs, validateTestOptions(newObj.Spec.Options.Test)...)
+	allErrs = append(allErrs, validateFailover(newObj.Spec.Failover)...)
+	allErrs = append(allErrs, validateDriftIgnoreRules(newObj.Spec.DriftIgnoreRules, field.NewPath("spec", "driftIgnoreRules"))...)
+	allErrs = append(allErrs, validateDriftDetection(newObj.Spec.DriftDetection)...)
+	allErrs = append(allErrs, validateKubeVersion(newObj.Spec.KubeVersion)...)
+	allErrs = append(allErrs, validateEnvironments(newObj.Spec.Environments)...)
+	allErrs = append(allErrs, validateClusterWatchFilterValue(newObj.Spec)...)
+	allErrs = append(allErrs, validatePropagateClusterLabels(newObj.Spec.PropagateClusterLabels)...)
+	allErrs = append(allErrs, validateClusterResourceSetSignatures(newObj.Spec.ClusterResourceSetSignatures)...)
+	allErrs = append(allErrs, validateReadinessThreshold(newObj.Spec.ReadinessThreshold)...)
+	rolloutErrs, warnings := validateRollout(newObj.Spec)
+	allErrs = append(allErrs, rolloutErrs...)
+	if len(allErrs) > 0 {
+		return warnings, apierrors.NewInvalid(GroupVersion.WithKind("HelmChartProxy").GroupKind(), newObj.Name, allErrs)
+	}
+
+	return warnings, nil
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type.
-func (*helmChartProxyWebhook) ValidateUpdate(_ context.Context, oldRaw, newRaw runtime.Object) (admission.Warnings, error) {
+func (w *HelmChartProxyWebhook) ValidateUpdate(_ context.Context, oldRaw, newRaw runtime.Object) (admission.Warnings, error) {
 	var allErrs field.ErrorList
 	oldObj, ok := oldRaw.(*HelmChartProxy)
 	if !ok {
@@ -112,11 +169,13 @@ func (*helmChartProxyWebhook) ValidateUpdate(_ context.Context, oldRaw, newRaw r
 
 	helmchartproxylog.Info("validate update", "name", newObj.Name)
 
-	if err := isUrlValid(newObj.Spec.RepoURL); err != nil {
-		allErrs = append(allErrs,
-			field.Invalid(field.NewPath("spec", "RepoURL"),
-				newObj.Spec.ReleaseNamespace, err.Error()),
-		)
+	if newObj.Spec.ChartBundleRef == nil && newObj.Spec.RepositoryRef == nil && newObj.Spec.Git == nil && newObj.Spec.Manifests == "" {
+		if err := isUrlValid(newObj.Spec.RepoURL); err != nil {
+			allErrs = append(allErrs,
+				field.Invalid(field.NewPath("spec", "RepoURL"),
+					newObj.Spec.ReleaseNamespace, err.Error()),
+			)
+		}
 	}
 
 	if newObj.Spec.ReconcileStrategy != oldObj.Spec.ReconcileStrategy {
@@ -126,15 +185,48 @@ func (*helmChartProxyWebhook) ValidateUpdate(_ context.Context, oldRaw, newRaw r
 		)
 	}
 
+	allErrs = append(allErrs, validateValuesTemplateOptions(newObj.Spec.ValuesTemplateOptions)...)
+	allErrs = append(allErrs, validateVersion(newObj.Spec)...)
+	allErrs = append(allErrs, validateDigest(newObj.Spec)...)
+	allErrs = append(allErrs, validateDigestUpdate(oldObj.Spec, newObj.Spec)...)
+	if (newObj.Spec.Manifests == "") != (oldObj.Spec.Manifests == "") {
+		allErrs = append(allErrs,
+			field.Forbidden(field.NewPath("spec", "manifests"), "manifests cannot be added to or removed from an existing HelmChartProxy"),
+		)
+	}
+	allErrs = append(allErrs, validateChartBundleRef(newObj.Spec)...)
+	allErrs = append(allErrs, validateRepositoryRef(newObj.Spec)...)
+	allErrs = append(allErrs, validateGit(newObj.Spec)...)
+	allErrs = append(allErrs, validateManifests(newObj.Spec)...)
+	allErrs = append(allErrs, validateRepositoryCredentials(newObj.Spec)...)
+	allErrs = append(allErrs, validateVerify(newObj.Spec)...)
+	allErrs = append(allErrs, validatePostRenderer(newObj.Spec.PostRenderer)...)
+	allErrs = append(allErrs, validateResyncPeriod(newObj.Spec.ResyncPeriod)...)
+	allErrs = append(allErrs, validateReconcileInterval(newObj.Spec.ReconcileInterval)...)
+	allErrs = append(allErrs, validateFetchTimeout(newObj.Spec.Options.FetchTimeout)...)
+	allErrs = append(allErrs, validateTestOptions(newObj.Spec.Options.Test)...)
+	allErrs = append(allErrs, validateFailover(newObj.Spec.Failover)...)
+	allErrs = append(allErrs, validateDriftIgnoreRules(newObj.Spec.DriftIgnoreRules, field.NewPath("spec", "driftIgnoreRules"))...)
+	allErrs = append(allErrs, validateDriftDetection(newObj.Spec.DriftDetection)...)
+	allErrs = append(allErrs, validateKubeVersion(newObj.Spec.KubeVersion)...)
+	allErrs = append(allErrs, validateEnvironments(newObj.Spec.Environments)...)
+	allErrs = append(allErrs, validateClusterWatchFilterValue(newObj.Spec)...)
+	allErrs = append(allErrs, validatePropagateClusterLabels(newObj.Spec.PropagateClusterLabels)...)
+	allErrs = append(allErrs, validateClusterResourceSetSignatures(newObj.Spec.ClusterResourceSetSignatures)...)
+	allErrs = append(allErrs, validateReadinessThreshold(newObj.Spec.ReadinessThreshold)...)
+	rolloutErrs, warnings := validateRollout(newObj.Spec)
+	allErrs = append(allErrs, rolloutErrs...)
+	warnings = append(warnings, w.blastRadiusWarnings(oldObj, newObj)...)
+
 	if len(allErrs) > 0 {
-		return nil, apierrors.NewInvalid(GroupVersion.WithKind("HelmChartProxy").GroupKind(), newObj.Name, allErrs)
+		return warnings, apierrors.NewInvalid(GroupVersion.WithKind("HelmChartProxy").GroupKind(), newObj.Name, allErrs)
 	}
 
-	return nil, nil
+	return warnings, nil
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type.
-func (*helmChartProxyWebhook) ValidateDelete(_ context.Context, objRaw runtime.Object) (admission.Warnings, error) {
+func (*HelmChartProxyWebhook) ValidateDelete(_ context.Context, objRaw runtime.Object) (admission.Warnings, error) {
 	obj, ok := objRaw.(*HelmChartProxy)
 	if !ok {
 		return nil, fmt.Errorf("expected a HelmChartProxy object but got %T", objRaw)
@@ -146,6 +238,23 @@ func (*helmChartProxyWebhook) ValidateDelete(_ context.Context, objRaw runtime.O
 	return nil, nil
 }
 
+// validateValuesTemplateOptions returns an error for each invalid field of the ValuesTemplateOptions.
+func validateValuesTemplateOptions(opts *ValuesTemplateOptions) field.ErrorList {
+	var allErrs field.ErrorList
+	if opts == nil {
+		return allErrs
+	}
+
+	if (opts.LeftDelimiter == "") != (opts.RightDelimiter == "") {
+		allErrs = append(allErrs,
+			field.Invalid(field.NewPath("spec", "valuesTemplateOptions"),
+				fmt.Sprintf("%s %s", opts.LeftDelimiter, opts.RightDelimiter), "leftDelimiter and rightDelimiter must be set together"),
+		)
+	}
+
+	return allErrs
+}
+
 // isUrlValid returns true if specified repoURL is valid as per go doc https://pkg.go.dev/net/url#ParseRequestURI.
 func isUrlValid(repoURL string) error {
 	if _, err := url.ParseRequestURI(repoURL); err != nil {
@@ -154,3 +263,686 @@ func isUrlValid(repoURL string) error {
 
 	return nil
 }
+
+// validateClusterWatchFilterValue returns an error if the ClusterWatchFilterValue is not a valid label value or conflicts
+// with a watch filter label required by the ClusterSelector, which would select no Cluster.
+func validateClusterWatchFilterValue(spec HelmChartProxySpec) field.ErrorList {
+	var allErrs field.ErrorList
+	if spec.ClusterWatchFilterValue == "" {
+		return allErrs
+	}
+
+	path := field.NewPath("spec", "clusterWatchFilterValue")
+	for _, msg := range validation.IsValidLabelValue(spec.ClusterWatchFilterValue) {
+		allErrs = append(allErrs, field.Invalid(path, spec.ClusterWatchFilterValue, msg))
+	}
+	if value, ok := spec.ClusterSelector.MatchLabels[clusterv1.WatchLabel]; ok && value != spec.ClusterWatchFilterValue {
+		allErrs = append(allErrs,
+			field.Invalid(path, spec.ClusterWatchFilterValue,
+				fmt.Sprintf("clusterWatchFilterValue conflicts with the %s label %q required by clusterSelector", clusterv1.WatchLabel, value)),
+		)
+	}
+
+	return allErrs
+}
+
+// helmReservedReleaseLabels are the labels Helm sets on its release storage, which it rejects as release labels.
+var helmReservedReleaseLabels = sets.New("name", "owner", "status", "version", "createdAt", "modifiedAt")
+
+// validatePropagateClusterLabels returns an error for each label key that is not a valid label key or that is reserved by
+// Helm or by the HelmReleaseProxy labels.
+func validatePropagateClusterLabels(keys []string) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, key := range keys {
+		path := field.NewPath("spec", "propagateClusterLabels").Index(i)
+		for _, msg := range validation.IsQualifiedName(key) {
+			allErrs = append(allErrs, field.Invalid(path, key, msg))
+		}
+		if helmReservedReleaseLabels.Has(key) || key == clusterv1.ClusterNameLabel || key == HelmChartProxyLabelName || key == PostRendererHashLabelName {
+			allErrs = append(allErrs, field.Invalid(path, key, "label is reserved and cannot be propagated"))
+		}
+	}
+
+	return allErrs
+}
+
+// validateClusterResourceSetSignatures returns an error for each ClusterResourceSetSignature that would match every
+// resource of every ClusterResourceSet.
+func validateClusterResourceSetSignatures(signatures []ClusterResourceSetSignature) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, signature := range signatures {
+		if signature.ClusterResourceSetName == "" && signature.Name == "" {
+			allErrs = append(allErrs,
+				field.Required(field.NewPath("spec", "clusterResourceSetSignatures").Index(i), "clusterResourceSetName or name must be specified"),
+			)
+		}
+	}
+
+	return allErrs
+}
+
+// validateVersion returns an error if the Version is neither a semver version nor a valid semver constraint, or if it is a
+// constraint for a chart of a ChartBundle, which is only looked up by its exact version.
+func validateVersion(spec HelmChartProxySpec) field.ErrorList {
+	var allErrs field.ErrorList
+	if spec.Version == "" {
+		return allErrs
+	}
+
+	if _, err := semver.NewConstraint(spec.Version); err != nil {
+		allErrs = append(allErrs,
+			field.Invalid(field.NewPath("spec", "version"), spec.Version, fmt.Sprintf("version is neither a semver version nor a valid semver constraint: %s", err)),
+		)
+	} else if IsVersionConstraint(spec.Version) && spec.ChartBundleRef != nil {
+		allErrs = append(allErrs,
+			field.Invalid(field.NewPath("spec", "version"), spec.Version, "version must be an exact version when chartBundleRef is set"),
+		)
+	}
+
+	return allErrs
+}
+
+// validateDigest returns an error if the Digest is not a sha256 digest, or if it pins a chart that is not pulled from an
+// OCI registry or whose Version is a constraint or differs per environment, as the digest pins a single artifact.
+func validateDigest(spec HelmChartProxySpec) field.ErrorList {
+	var allErrs field.ErrorList
+	if spec.Digest == "" {
+		return allErrs
+	}
+
+	fldPath := field.NewPath("spec", "digest")
+	if dgst, err := digest.Parse(spec.Digest); err != nil || dgst.Algorithm() != digest.SHA256 {
+		allErrs = append(allErrs,
+			field.Invalid(fldPath, spec.Digest, "digest must be of the form sha256:<64 lowercase hex characters>"),
+		)
+	}
+	switch {
+	case spec.ChartBundleRef != nil:
+		allErrs = append(allErrs, field.Invalid(fldPath, spec.Digest, "digest cannot be set when chartBundleRef is set"))
+	case spec.Git != nil:
+		allErrs = append(allErrs, field.Invalid(fldPath, spec.Digest, "digest cannot be set when git is set"))
+	case spec.RepoURL != "" && !strings.HasPrefix(spec.RepoURL, "oci://"):
+		allErrs = append(allErrs, field.Invalid(fldPath, spec.Digest, "digest can only be set for charts of OCI registries"))
+	}
+	if IsVersionConstraint(spec.Version) {
+		allErrs = append(allErrs,
+			field.Invalid(field.NewPath("spec", "version"), spec.Version, "version must be an exact version when digest is set"),
+		)
+	}
+	for i, environment := range spec.Environments {
+		if environment.Version != "" {
+			allErrs = append(allErrs,
+				field.Invalid(field.NewPath("spec", "environments").Index(i).Child("version"), environment.Version, "environments cannot set a version when digest is set"),
+			)
+		}
+	}
+
+	return allErrs
+}
+
+// validateDigestUpdate returns an error if the Version of a HelmChartProxy pinned by digest is changed without its Digest,
+// i.e. the tag is moved to the pinned artifact, or its Digest is changed without its Version, i.e. the pinned tag is
+// expected to point to another artifact.
+func validateDigestUpdate(oldSpec, newSpec HelmChartProxySpec) field.ErrorList {
+	var allErrs field.ErrorList
+	if oldSpec.Digest == "" || newSpec.Digest == "" || oldSpec.Version == "" || newSpec.Version == "" {
+		return allErrs
+	}
+
+	switch {
+	case newSpec.Version != oldSpec.Version && newSpec.Digest == oldSpec.Digest:
+		allErrs = append(allErrs,
+			field.Invalid(field.NewPath("spec", "version"), newSpec.Version, fmt.Sprintf("version cannot be changed without the digest %s it is pinned to", newSpec.Digest)),
+		)
+	case newSpec.Digest != oldSpec.Digest && newSpec.Version == oldSpec.Version:
+		allErrs = append(allErrs,
+			field.Invalid(field.NewPath("spec", "digest"), newSpec.Digest, fmt.Sprintf("digest does not match the digest %s version %s is pinned to", oldSpec.Digest, newSpec.Version)),
+		)
+	}
+
+	return allErrs
+}
+
+// validateChartBundleRef returns an error if the ChartBundleRef is set together with the RepoURL or without a Version.
+func validateChartBundleRef(spec HelmChartProxySpec) field.ErrorList {
+	var allErrs field.ErrorList
+	if spec.ChartBundleRef == nil {
+		return allErrs
+	}
+
+	if spec.RepoURL != "" {
+		allErrs = append(allErrs,
+			field.Invalid(field.NewPath("spec", "repoURL"),
+				spec.RepoURL, "repoURL and chartBundleRef are mutually exclusive"),
+		)
+	}
+	if spec.Version == "" {
+		allErrs = append(allErrs,
+			field.Required(field.NewPath("spec", "version"), "version must be specified when chartBundleRef is set"),
+		)
+	}
+
+	return allErrs
+}
+
+// validateRepositoryRef returns an error if the RepositoryRef is set together with the RepoURL or the ChartBundleRef.
+func validateRepositoryRef(spec HelmChartProxySpec) field.ErrorList {
+	var allErrs field.ErrorList
+	if spec.RepositoryRef == nil {
+		return allErrs
+	}
+
+	if spec.RepoURL != "" {
+		allErrs = append(allErrs,
+			field.Invalid(field.NewPath("spec", "repoURL"),
+				spec.RepoURL, "repoURL and repositoryRef are mutually exclusive"),
+		)
+	}
+	if spec.ChartBundleRef != nil {
+		allErrs = append(allErrs,
+			field.Invalid(field.NewPath("spec", "chartBundleRef"),
+				spec.ChartBundleRef.Name, "chartBundleRef and repositoryRef are mutually exclusive"),
+		)
+	}
+
+	return allErrs
+}
+
+// validateGit returns an error if the Git source is set together with another source of the chart, or if its URL is not
+// an HTTP or HTTPS URL or its path leaves the repository.
+func validateGit(spec HelmChartProxySpec) field.ErrorList {
+	var allErrs field.ErrorList
+	if spec.Git == nil {
+		return allErrs
+	}
+
+	fldPath := field.NewPath("spec", "git")
+	if spec.RepoURL != "" {
+		allErrs = append(allErrs,
+			field.Invalid(field.NewPath("spec", "repoURL"), spec.RepoURL, "repoURL and git are mutually exclusive"),
+		)
+	}
+	if spec.RepositoryRef != nil {
+		allErrs = append(allErrs,
+			field.Invalid(field.NewPath("spec", "repositoryRef"), spec.RepositoryRef.Name, "repositoryRef and git are mutually exclusive"),
+		)
+	}
+	if spec.ChartBundleRef != nil {
+		allErrs = append(allErrs,
+			field.Invalid(field.NewPath("spec", "chartBundleRef"), spec.ChartBundleRef.Name, "chartBundleRef and git are mutually exclusive"),
+		)
+	}
+	if spec.RepositoryCredentials != nil {
+		allErrs = append(allErrs,
+			field.Invalid(field.NewPath("spec", "repositoryCredentials"), spec.RepositoryCredentials.Name, "repositoryCredentials are not used for Git repositories, use git.authSecretRef"),
+		)
+	}
+
+	u, err := url.Parse(spec.Git.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("url"), spec.Git.URL, "must be an HTTP or HTTPS URL"))
+	}
+	if chartPath := spec.Git.Path; chartPath != "" {
+		if cleaned := path.Clean(chartPath); path.IsAbs(chartPath) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("path"), chartPath, "must be a relative path within the repository"))
+		}
+	}
+	if spec.Git.AuthSecretRef != nil && spec.Git.AuthSecretRef.Name == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("authSecretRef", "name"), "must be specified"))
+	}
+
+	return allErrs
+}
+
+// validateManifests returns an error if the Manifests are set together with a source of a Helm chart.
+func validateManifests(spec HelmChartProxySpec) field.ErrorList {
+	var allErrs field.ErrorList
+	if spec.Manifests == "" {
+		return allErrs
+	}
+
+	if spec.RepoURL != "" {
+		allErrs = append(allErrs,
+			field.Invalid(field.NewPath("spec", "repoURL"), spec.RepoURL, "repoURL and manifests are mutually exclusive"),
+		)
+	}
+	if spec.RepositoryRef != nil {
+		allErrs = append(allErrs,
+			field.Invalid(field.NewPath("spec", "repositoryRef"), spec.RepositoryRef.Name, "repositoryRef and manifests are mutually exclusive"),
+		)
+	}
+	if spec.ChartBundleRef != nil {
+		allErrs = append(allErrs,
+			field.Invalid(field.NewPath("spec", "chartBundleRef"), spec.ChartBundleRef.Name, "chartBundleRef and manifests are mutually exclusive"),
+		)
+	}
+	if spec.Git != nil {
+		allErrs = append(allErrs,
+			field.Invalid(field.NewPath("spec", "git"), spec.Git.URL, "git and manifests are mutually exclusive"),
+		)
+	}
+
+	return allErrs
+}
+
+// validateRepositoryCredentials returns an error if the RepositoryCredentials are set for an OCI registry, whose credentials
+// are set with Credentials.
+func validateRepositoryCredentials(spec HelmChartProxySpec) field.ErrorList {
+	var allErrs field.ErrorList
+	if spec.RepositoryCredentials != nil && strings.HasPrefix(spec.RepoURL, "oci://") {
+		allErrs = append(allErrs,
+			field.Invalid(field.NewPath("spec", "repositoryCredentials"),
+				spec.RepositoryCredentials.Name, "repositoryCredentials are only used for HTTP chart repositories, use credentials for OCI registries"),
+		)
+	}
+
+	return allErrs
+}
+
+// validateVerify returns an error if the cosign verification does not set exactly one of a public key and keyless
+// verification, if a Secret reference has no name, or if the verification does not apply to the source of the chart,
+// i.e. provenance files for OCI registries, cosign signatures for HTTP chart repositories or any for ChartBundles and Git
+// repositories.
+func validateVerify(spec HelmChartProxySpec) field.ErrorList {
+	var allErrs field.ErrorList
+	if spec.Verify == nil {
+		return allErrs
+	}
+
+	fldPath := field.NewPath("spec", "verify")
+	if spec.ChartBundleRef != nil {
+		allErrs = append(allErrs, field.Forbidden(fldPath, "charts of ChartBundles have no signature to verify"))
+	}
+	if spec.Git != nil {
+		allErrs = append(allErrs, field.Forbidden(fldPath, "charts of Git repositories have no signature to verify"))
+	}
+	isOCI := strings.HasPrefix(spec.RepoURL, "oci://")
+	if provenance := spec.Verify.Provenance; provenance != nil {
+		if provenance.KeyringSecretRef.Name == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("provenance", "keyringSecretRef", "name"), "must be specified"))
+		}
+		if isOCI {
+			allErrs = append(allErrs,
+				field.Invalid(fldPath.Child("provenance"), spec.RepoURL, "provenance files are only verified for HTTP chart repositories, use cosign for OCI registries"),
+			)
+		}
+	}
+
+	if cosign := spec.Verify.Cosign; cosign != nil {
+		cosignPath := fldPath.Child("cosign")
+		switch {
+		case cosign.PublicKeySecretRef == nil && cosign.Keyless == nil:
+			allErrs = append(allErrs, field.Required(cosignPath, "one of publicKeySecretRef and keyless must be specified"))
+		case cosign.PublicKeySecretRef != nil && cosign.Keyless != nil:
+			allErrs = append(allErrs, field.Forbidden(cosignPath, "publicKeySecretRef and keyless are mutually exclusive"))
+		}
+		if cosign.PublicKeySecretRef != nil && cosign.PublicKeySecretRef.Name == "" {
+			allErrs = append(allErrs, field.Required(cosignPath.Child("publicKeySecretRef", "name"), "must be specified"))
+		}
+		if cosign.Keyless != nil && cosign.Keyless.TrustedRootSecretRef.Name == "" {
+			allErrs = append(allErrs, field.Required(cosignPath.Child("keyless", "trustedRootSecretRef", "name"), "must be specified"))
+		}
+		if spec.RepoURL != "" && !isOCI {
+			allErrs = append(allErrs,
+				field.Invalid(cosignPath, spec.RepoURL, "cosign signatures are only verified for OCI registries, use provenance for HTTP chart repositories"),
+			)
+		}
+	}
+
+	return allErrs
+}
+
+// validatePostRenderer returns an error for each patch of the PostRenderer that is neither a strategic merge patch nor a
+// JSON 6902 patch, for each JSON 6902 patch without a target, and for each strategic merge patch without a target that
+// does not name the resource it patches.
+func validatePostRenderer(postRenderer *PostRenderer) field.ErrorList {
+	var allErrs field.ErrorList
+	if postRenderer == nil {
+		return allErrs
+	}
+
+	for i, patch := range postRenderer.Patches {
+		fldPath := field.NewPath("spec", "postRenderer", "patches").Index(i)
+		if patch.Target != nil && patch.Target.LabelSelector != "" {
+			if _, err := labels.Parse(patch.Target.LabelSelector); err != nil {
+				allErrs = append(allErrs, field.Invalid(fldPath.Child("target", "labelSelector"), patch.Target.LabelSelector, err.Error()))
+			}
+		}
+		if patch.Target != nil && patch.Target.AnnotationSelector != "" {
+			if _, err := labels.Parse(patch.Target.AnnotationSelector); err != nil {
+				allErrs = append(allErrs, field.Invalid(fldPath.Child("target", "annotationSelector"), patch.Target.AnnotationSelector, err.Error()))
+			}
+		}
+
+		var content interface{}
+		if err := yaml.Unmarshal([]byte(patch.Patch), &content); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("patch"), patch.Patch, fmt.Sprintf("failed to parse patch: %v", err)))
+			continue
+		}
+		switch content := content.(type) {
+		case []interface{}:
+			if patch.Target == nil {
+				allErrs = append(allErrs, field.Required(fldPath.Child("target"), "must be specified for JSON 6902 patches"))
+			}
+		case map[string]interface{}:
+			metadata, _ := content["metadata"].(map[string]interface{})
+			if patch.Target == nil && (content["kind"] == nil || metadata["name"] == nil) {
+				allErrs = append(allErrs,
+					field.Required(fldPath.Child("target"), "must be specified for strategic merge patches without a kind and metadata.name"),
+				)
+			}
+		default:
+			allErrs = append(allErrs,
+				field.Invalid(fldPath.Child("patch"), patch.Patch, "must be a strategic merge patch or a JSON 6902 patch"),
+			)
+		}
+	}
+
+	return allErrs
+}
+
+// blastRadiusWarnings returns warnings for an update of a HelmChartProxy that changes the Helm releases of more Clusters
+// than the blast radius warning threshold at once, or that changes the major version of the chart, so that users can
+// consider rollout options before the change lands on the whole fleet. The Clusters affected are the matching Clusters
+// in the status of the old HelmChartProxy, which are only counted if the controller runs with a lightweight status.
+func (w *HelmChartProxyWebhook) blastRadiusWarnings(oldObj, newObj *HelmChartProxy) admission.Warnings {
+	var warnings admission.Warnings
+
+	oldSpec, newSpec := oldObj.Spec.DeepCopy(), newObj.Spec.DeepCopy()
+	oldSpec.Rollout, newSpec.Rollout = nil, nil
+	if reflect.DeepEqual(oldSpec, newSpec) {
+		return warnings
+	}
+
+	upgradeRolloutPath := field.NewPath("spec", "rollout", "upgrade")
+	hasUpgradeRollout := newObj.Spec.Rollout != nil && newObj.Spec.Rollout.Upgrade != nil
+	clusters := max(len(oldObj.Status.MatchingClusters), int(oldObj.Status.MatchingClusterCount))
+	if !hasUpgradeRollout && w.BlastRadiusWarningThreshold > 0 && clusters > w.BlastRadiusWarningThreshold {
+		warnings = append(warnings, fmt.Sprintf("update changes the Helm releases of %d Clusters at once, consider setting %s to roll it out in batches",
+			clusters, upgradeRolloutPath))
+	}
+
+	oldVersion, oldErr := semver.StrictNewVersion(strings.TrimPrefix(oldObj.Spec.Version, "v"))
+	newVersion, newErr := semver.StrictNewVersion(strings.TrimPrefix(newObj.Spec.Version, "v"))
+	if oldErr == nil && newErr == nil && oldVersion.Major() != newVersion.Major() {
+		warning := fmt.Sprintf("update changes the major version of chart %s from %s to %s, which may contain breaking changes",
+			newObj.Spec.ChartName, oldObj.Spec.Version, newObj.Spec.Version)
+		if !hasUpgradeRollout {
+			warning += fmt.Sprintf(", consider setting %s to roll it out in batches", upgradeRolloutPath)
+		}
+		warnings = append(warnings, warning)
+	}
+
+	return warnings
+}
+
+// validateRollout returns an error for each invalid step of the install and upgrade RolloutOptions, and for an upgrade
+// rollout combined with the InstallOnce ReconcileStrategy, which never upgrades. Options that are valid but have no
+// effect are returned as warnings.
+func validateRollout(spec HelmChartProxySpec) (field.ErrorList, admission.Warnings) {
+	var allErrs field.ErrorList
+	var warnings admission.Warnings
+	if spec.Rollout == nil {
+		return allErrs, warnings
+	}
+
+	rolloutPath := field.NewPath("spec", "rollout")
+	for _, rollout := range []struct {
+		name string
+		opts *RolloutOptions
+	}{
+		{name: "install", opts: spec.Rollout.Install},
+		{name: "upgrade", opts: spec.Rollout.Upgrade},
+		{name: "uninstall", opts: spec.Rollout.Uninstall},
+	} {
+		opts := rollout.opts
+		if opts == nil {
+			continue
+		}
+		path := rolloutPath.Child(rollout.name)
+
+		if opts.StepInit == nil {
+			allErrs = append(allErrs, field.Required(path.Child("stepInit"), "stepInit must be specified"))
+		} else {
+			allErrs = append(allErrs, validateRolloutStep(path.Child("stepInit"), opts.StepInit, 1)...)
+		}
+		if opts.StepIncrement != nil {
+			allErrs = append(allErrs, validateRolloutStep(path.Child("stepIncrement"), opts.StepIncrement, 0)...)
+		}
+		if opts.StepLimit != nil {
+			allErrs = append(allErrs, validateRolloutStep(path.Child("stepLimit"), opts.StepLimit, 0)...)
+			if opts.StepIncrement == nil {
+				warnings = append(warnings, fmt.Sprintf("%s has no effect without %s", path.Child("stepLimit"), path.Child("stepIncrement")))
+			}
+		}
+		if opts.StepInit != nil && opts.StepLimit != nil && opts.StepInit.Type == opts.StepLimit.Type &&
+			opts.StepLimit.IntValue() < opts.StepInit.IntValue() {
+			warnings = append(warnings, fmt.Sprintf("%s is less than %s, so the step size is not limited", path.Child("stepLimit"), path.Child("stepInit")))
+		}
+		if opts.MaxPerFailureDomain != nil && opts.FailureDomainLabel == "" {
+			warnings = append(warnings, fmt.Sprintf("%s has no effect without %s", path.Child("maxPerFailureDomain"), path.Child("failureDomainLabel")))
+		}
+		if opts.MaxFailures != nil {
+			allErrs = append(allErrs, validateRolloutStep(path.Child("maxFailures"), opts.MaxFailures, 1)...)
+		}
+	}
+
+	if spec.Rollout.Upgrade != nil && spec.ReconcileStrategy == string(ReconcileStrategyInstallOnce) {
+		allErrs = append(allErrs,
+			field.Forbidden(rolloutPath.Child("upgrade"), "upgrade rollout cannot be combined with the InstallOnce reconcileStrategy, which never upgrades"),
+		)
+	}
+	if uninstall := spec.Rollout.Uninstall; uninstall != nil {
+		if spec.ReconcileStrategy == string(ReconcileStrategyInstallOnce) {
+			warnings = append(warnings, fmt.Sprintf("%s has no effect with the InstallOnce reconcileStrategy, which never deletes HelmReleaseProxies of Clusters that are no longer selected", rolloutPath.Child("uninstall")))
+		}
+		if uninstall.FailureDomainLabel != "" {
+			warnings = append(warnings, fmt.Sprintf("%s is not used by the uninstall rollout", rolloutPath.Child("uninstall", "failureDomainLabel")))
+		}
+		if uninstall.ApprovalRequired {
+			warnings = append(warnings, fmt.Sprintf("%s is not used by the uninstall rollout", rolloutPath.Child("uninstall", "approvalRequired")))
+		}
+	}
+	if hooks := spec.Rollout.Hooks; hooks != nil {
+		allErrs = append(allErrs, validateRolloutHooks(rolloutPath.Child("hooks", "preRollout"), hooks.PreRollout)...)
+		allErrs = append(allErrs, validateRolloutHooks(rolloutPath.Child("hooks", "postRollout"), hooks.PostRollout)...)
+	}
+
+	return allErrs, warnings
+}
+
+// validateRolloutHooks returns an error for each hook that does not specify exactly one of a Job and a URL, whose URL is
+// not an absolute HTTP or HTTPS URL, or whose timeout is set but not positive.
+func validateRolloutHooks(path *field.Path, hooks []RolloutHook) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, hook := range hooks {
+		hookPath := path.Index(i)
+		switch {
+		case hook.Job == nil && hook.URL == "":
+			allErrs = append(allErrs, field.Required(hookPath, "one of job and url must be specified"))
+		case hook.Job != nil && hook.URL != "":
+			allErrs = append(allErrs, field.Forbidden(hookPath, "only one of job and url may be specified"))
+		case hook.URL != "":
+			if u, err := url.ParseRequestURI(hook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				allErrs = append(allErrs, field.Invalid(hookPath.Child("url"), hook.URL, "must be an absolute http or https URL"))
+			}
+		}
+		if hook.Timeout != nil && hook.Timeout.Duration <= 0 {
+			allErrs = append(allErrs, field.Invalid(hookPath.Child("timeout"), hook.Timeout.Duration.String(), "must be greater than zero"))
+		}
+	}
+
+	return allErrs
+}
+
+// validateRolloutStep returns an error if the rollout step is not an integer or a percentage of at least the minimum,
+// or if it is a percentage above 100%.
+func validateRolloutStep(path *field.Path, step *intstr.IntOrString, minimum int) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if step.Type == intstr.Int {
+		if step.IntValue() < minimum {
+			allErrs = append(allErrs, field.Invalid(path, step.IntValue(), fmt.Sprintf("must be greater than or equal to %d", minimum)))
+		}
+
+		return allErrs
+	}
+
+	value, ok := strings.CutSuffix(step.StrVal, "%")
+	percent, err := strconv.Atoi(value)
+	switch {
+	case !ok || err != nil:
+		allErrs = append(allErrs, field.Invalid(path, step.StrVal, "must be an integer or a percentage, e.g. 5 or 25%"))
+	case percent < minimum || percent > 100:
+		allErrs = append(allErrs, field.Invalid(path, step.StrVal, fmt.Sprintf("must be a percentage between %d%% and 100%%", minimum)))
+	}
+
+	return allErrs
+}
+
+// validateReadinessThreshold returns an error if the ReadinessThreshold is set but not a non-negative integer or a
+// percentage between 0% and 100%.
+func validateReadinessThreshold(threshold *intstr.IntOrString) field.ErrorList {
+	if threshold == nil {
+		return nil
+	}
+
+	return validateRolloutStep(field.NewPath("spec", "readinessThreshold"), threshold, 0)
+}
+
+// validateResyncPeriod returns an error if the ResyncPeriod is set but not positive.
+func validateResyncPeriod(resyncPeriod *metav1.Duration) field.ErrorList {
+	var allErrs field.ErrorList
+	if resyncPeriod != nil && resyncPeriod.Duration <= 0 {
+		allErrs = append(allErrs,
+			field.Invalid(field.NewPath("spec", "resyncPeriod"),
+				resyncPeriod.Duration.String(), "must be greater than zero"),
+		)
+	}
+
+	return allErrs
+}
+
+// validateFetchTimeout returns an error if the FetchTimeout is set but not positive.
+func validateFetchTimeout(fetchTimeout *metav1.Duration) field.ErrorList {
+	var allErrs field.ErrorList
+	if fetchTimeout != nil && fetchTimeout.Duration <= 0 {
+		allErrs = append(allErrs,
+			field.Invalid(field.NewPath("spec", "options", "fetchTimeout"),
+				fetchTimeout.Duration.String(), "must be greater than zero"),
+		)
+	}
+
+	return allErrs
+}
+
+// validateTestOptions returns an error if the Timeout of the Test options is set but not positive, or a filter names no test.
+func validateTestOptions(test *HelmTestOptions) field.ErrorList {
+	var allErrs field.ErrorList
+	if test == nil {
+		return allErrs
+	}
+
+	fldPath := field.NewPath("spec", "options", "test")
+	if test.Timeout != nil && test.Timeout.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("timeout"), test.Timeout.Duration.String(), "must be greater than zero"))
+	}
+	for i, filter := range test.Filters {
+		if strings.TrimPrefix(filter, "!") == "" {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("filters").Index(i), filter, "must name a test"))
+		}
+	}
+
+	return allErrs
+}
+
+// validateReconcileInterval returns an error if the ReconcileInterval is set but not positive.
+func validateReconcileInterval(reconcileInterval *metav1.Duration) field.ErrorList {
+	var allErrs field.ErrorList
+	if reconcileInterval != nil && reconcileInterval.Duration <= 0 {
+		allErrs = append(allErrs,
+			field.Invalid(field.NewPath("spec", "reconcileInterval"),
+				reconcileInterval.Duration.String(), "must be greater than zero"),
+		)
+	}
+
+	return allErrs
+}
+
+// validateFailover returns an error if the lease duration of the Failover is set but not positive.
+func validateFailover(failover *FailoverOptions) field.ErrorList {
+	var allErrs field.ErrorList
+	if failover != nil && failover.LeaseDuration != nil && failover.LeaseDuration.Duration <= 0 {
+		allErrs = append(allErrs,
+			field.Invalid(field.NewPath("spec", "failover", "leaseDuration"),
+				failover.LeaseDuration.Duration.String(), "must be greater than zero"),
+		)
+	}
+
+	return allErrs
+}
+
+// validateDriftIgnoreRules returns an error for each path of the DriftIgnoreRules at fldPath that does not select a field.
+func validateDriftIgnoreRules(rules []DriftIgnoreRule, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, rule := range rules {
+		for j, path := range rule.Paths {
+			if strings.Trim(path, "/$.") == "" {
+				allErrs = append(allErrs,
+					field.Invalid(fldPath.Index(i).Child("paths").Index(j), path, "must select a field"),
+				)
+			}
+		}
+	}
+
+	return allErrs
+}
+
+// validateDriftDetection returns an error if the interval of the DriftDetection is set but not positive, or if one of its
+// IgnoreRules does not select a field.
+func validateDriftDetection(driftDetection *DriftDetection) field.ErrorList {
+	if driftDetection == nil {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	if driftDetection.Interval != nil && driftDetection.Interval.Duration <= 0 {
+		allErrs = append(allErrs,
+			field.Invalid(field.NewPath("spec", "driftDetection", "interval"),
+				driftDetection.Interval.Duration.String(), "must be greater than zero"),
+		)
+	}
+	allErrs = append(allErrs, validateDriftIgnoreRules(driftDetection.IgnoreRules, field.NewPath("spec", "driftDetection", "ignoreRules"))...)
+
+	return allErrs
+}
+
+// validateEnvironments returns an error for each environment whose ClusterSelector is not a valid label selector.
+func validateEnvironments(environments []Environment) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, environment := range environments {
+		if _, err := metav1.LabelSelectorAsSelector(&environment.ClusterSelector); err != nil {
+			allErrs = append(allErrs,
+				field.Invalid(field.NewPath("spec", "environments").Index(i).Child("clusterSelector"), environment.ClusterSelector, err.Error()),
+			)
+		}
+	}
+
+	return allErrs
+}
+
+// validateKubeVersion returns an error if the KubeVersion is set but is not a valid semver range.
+func validateKubeVersion(kubeVersion string) field.ErrorList {
+	var allErrs field.ErrorList
+	if kubeVersion == "" {
+		return allErrs
+	}
+
+	if _, err := semver.NewConstraint(kubeVersion); err != nil {
+		allErrs = append(allErrs,
+			field.Invalid(field.NewPath("spec", "kubeVersion"), kubeVersion, err.Error()),
+		)
+	}
+
+	return allErrs
+}
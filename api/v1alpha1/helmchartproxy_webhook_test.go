@@ -0,0 +1,647 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/ptr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+func TestValidateRollout(t *testing.T) {
+	step := func(s string) *intstr.IntOrString {
+		return ptr.To(intstr.Parse(s))
+	}
+
+	testcases := []struct {
+		name              string
+		reconcileStrategy ReconcileStrategy
+		rollout           *Rollout
+		expectedErrors    []string
+		expectedWarnings  []string
+	}{
+		{
+			name: "no rollout",
+		},
+		{
+			name: "valid integer and percentage steps",
+			rollout: &Rollout{
+				Install: &RolloutOptions{StepInit: step("2"), StepIncrement: step("2"), StepLimit: step("10")},
+				Upgrade: &RolloutOptions{StepInit: step("10%"), StepIncrement: step("0%"), StepLimit: step("100%")},
+			},
+		},
+		{
+			name: "invalid percentages",
+			rollout: &Rollout{
+				Install: &RolloutOptions{StepInit: step("ten%"), StepIncrement: step("120%"), StepLimit: step("10")},
+			},
+			expectedErrors: []string{
+				"spec.rollout.install.stepInit: Invalid value: \"ten%\": must be an integer or a percentage, e.g. 5 or 25%",
+				"spec.rollout.install.stepIncrement: Invalid value: \"120%\": must be a percentage between 0% and 100%",
+			},
+		},
+		{
+			name: "invalid maxFailures",
+			rollout: &Rollout{
+				Install: &RolloutOptions{StepInit: step("1"), MaxFailures: step("0")},
+				Upgrade: &RolloutOptions{StepInit: step("1"), MaxFailures: step("0%")},
+			},
+			expectedErrors: []string{
+				"spec.rollout.install.maxFailures: Invalid value: 0: must be greater than or equal to 1",
+				"spec.rollout.upgrade.maxFailures: Invalid value: \"0%\": must be a percentage between 1% and 100%",
+			},
+		},
+		{
+			name: "negative and zero steps",
+			rollout: &Rollout{
+				Upgrade: &RolloutOptions{StepInit: step("0"), StepIncrement: step("-1"), StepLimit: step("-5%")},
+			},
+			expectedErrors: []string{
+				"spec.rollout.upgrade.stepInit: Invalid value: 0: must be greater than or equal to 1",
+				"spec.rollout.upgrade.stepIncrement: Invalid value: -1: must be greater than or equal to 0",
+				"spec.rollout.upgrade.stepLimit: Invalid value: \"-5%\": must be a percentage between 0% and 100%",
+			},
+		},
+		{
+			name: "missing stepInit",
+			rollout: &Rollout{
+				Install: &RolloutOptions{},
+			},
+			expectedErrors: []string{"spec.rollout.install.stepInit: Required value: stepInit must be specified"},
+		},
+		{
+			name: "options without effect",
+			rollout: &Rollout{
+				Install: &RolloutOptions{StepInit: step("5"), StepLimit: step("2"), MaxPerFailureDomain: ptr.To[int32](2)},
+			},
+			expectedWarnings: []string{
+				"spec.rollout.install.stepLimit has no effect without spec.rollout.install.stepIncrement",
+				"spec.rollout.install.stepLimit is less than spec.rollout.install.stepInit, so the step size is not limited",
+				"spec.rollout.install.maxPerFailureDomain has no effect without spec.rollout.install.failureDomainLabel",
+			},
+		},
+		{
+			name:              "upgrade rollout with InstallOnce",
+			reconcileStrategy: ReconcileStrategyInstallOnce,
+			rollout: &Rollout{
+				Install: &RolloutOptions{StepInit: step("1")},
+				Upgrade: &RolloutOptions{StepInit: step("1")},
+			},
+			expectedErrors: []string{
+				"spec.rollout.upgrade: Forbidden: upgrade rollout cannot be combined with the InstallOnce reconcileStrategy, which never upgrades",
+			},
+		},
+		{
+			name:              "uninstall rollout with InstallOnce, failure domains and approval",
+			reconcileStrategy: ReconcileStrategyInstallOnce,
+			rollout: &Rollout{
+				Uninstall: &RolloutOptions{StepInit: step("1"), FailureDomainLabel: "topology.kubernetes.io/region", ApprovalRequired: true},
+			},
+			expectedWarnings: []string{
+				"spec.rollout.uninstall has no effect with the InstallOnce reconcileStrategy, which never deletes HelmReleaseProxies of Clusters that are no longer selected",
+				"spec.rollout.uninstall.failureDomainLabel is not used by the uninstall rollout",
+				"spec.rollout.uninstall.approvalRequired is not used by the uninstall rollout",
+			},
+		},
+		{
+			name: "valid hooks",
+			rollout: &Rollout{
+				Hooks: &RolloutHooks{
+					PreRollout:  []RolloutHook{{Name: "ticket", URL: "https://change.example.com/api/tickets"}},
+					PostRollout: []RolloutHook{{Name: "warm", Job: &RolloutHookJob{Image: "curlimages/curl"}, Timeout: &metav1.Duration{Duration: time.Minute}}},
+				},
+			},
+		},
+		{
+			name: "invalid hooks",
+			rollout: &Rollout{
+				Hooks: &RolloutHooks{
+					PreRollout: []RolloutHook{
+						{Name: "none"},
+						{Name: "both", URL: "https://change.example.com", Job: &RolloutHookJob{Image: "curlimages/curl"}},
+						{Name: "relative", URL: "/api/tickets", Timeout: &metav1.Duration{}},
+					},
+				},
+			},
+			expectedErrors: []string{
+				"spec.rollout.hooks.preRollout[0]: Required value: one of job and url must be specified",
+				"spec.rollout.hooks.preRollout[1]: Forbidden: only one of job and url may be specified",
+				"spec.rollout.hooks.preRollout[2].url: Invalid value: \"/api/tickets\": must be an absolute http or https URL",
+				"spec.rollout.hooks.preRollout[2].timeout: Invalid value: \"0s\": must be greater than zero",
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			allErrs, warnings := validateRollout(HelmChartProxySpec{
+				ReconcileStrategy: string(tc.reconcileStrategy),
+				Rollout:           tc.rollout,
+			})
+
+			errs := make([]string, 0, len(allErrs))
+			for _, err := range allErrs {
+				errs = append(errs, err.Error())
+			}
+			g.Expect(errs).To(ConsistOf(tc.expectedErrors))
+			g.Expect(warnings).To(ConsistOf(tc.expectedWarnings))
+		})
+	}
+}
+
+func TestValidateReadinessThreshold(t *testing.T) {
+	testcases := []struct {
+		name           string
+		threshold      *intstr.IntOrString
+		expectedErrors []string
+	}{
+		{
+			name: "no threshold",
+		},
+		{
+			name:      "valid percentage",
+			threshold: ptr.To(intstr.FromString("95%")),
+		},
+		{
+			name:      "valid integer",
+			threshold: ptr.To(intstr.FromInt32(0)),
+		},
+		{
+			name:           "percentage above 100%",
+			threshold:      ptr.To(intstr.FromString("101%")),
+			expectedErrors: []string{"spec.readinessThreshold: Invalid value: \"101%\": must be a percentage between 0% and 100%"},
+		},
+		{
+			name:           "negative integer",
+			threshold:      ptr.To(intstr.FromInt32(-1)),
+			expectedErrors: []string{"spec.readinessThreshold: Invalid value: -1: must be greater than or equal to 0"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			errs := []string{}
+			for _, err := range validateReadinessThreshold(tc.threshold) {
+				errs = append(errs, err.Error())
+			}
+			g.Expect(errs).To(ConsistOf(tc.expectedErrors))
+		})
+	}
+}
+
+func TestBlastRadiusWarnings(t *testing.T) {
+	matchingClusters := func(n int) []corev1.ObjectReference {
+		refs := make([]corev1.ObjectReference, 0, n)
+		for i := 0; i < n; i++ {
+			refs = append(refs, corev1.ObjectReference{Kind: "Cluster", Name: fmt.Sprintf("cluster-%d", i)})
+		}
+		return refs
+	}
+	upgradeRollout := &Rollout{Upgrade: &RolloutOptions{StepInit: ptr.To(intstr.FromInt32(1))}}
+
+	testcases := []struct {
+		name             string
+		clusters         int
+		lightweight      bool
+		threshold        *int
+		oldSpec          HelmChartProxySpec
+		newSpec          HelmChartProxySpec
+		expectedWarnings []string
+	}{
+		{
+			name:     "unchanged spec of many clusters",
+			clusters: 20,
+			oldSpec:  HelmChartProxySpec{ChartName: "nginx", Version: "1.0.0"},
+			newSpec:  HelmChartProxySpec{ChartName: "nginx", Version: "1.0.0"},
+		},
+		{
+			name:     "only rollout changed",
+			clusters: 20,
+			oldSpec:  HelmChartProxySpec{ChartName: "nginx", Version: "1.0.0"},
+			newSpec:  HelmChartProxySpec{ChartName: "nginx", Version: "1.0.0", Rollout: upgradeRollout},
+		},
+		{
+			name:     "minor version change of few clusters",
+			clusters: 10,
+			oldSpec:  HelmChartProxySpec{ChartName: "nginx", Version: "1.0.0"},
+			newSpec:  HelmChartProxySpec{ChartName: "nginx", Version: "1.1.0"},
+		},
+		{
+			name:     "values change of many clusters",
+			clusters: 11,
+			oldSpec:  HelmChartProxySpec{ChartName: "nginx", Version: "1.0.0", ValuesTemplate: "replicas: 1"},
+			newSpec:  HelmChartProxySpec{ChartName: "nginx", Version: "1.0.0", ValuesTemplate: "replicas: 2"},
+			expectedWarnings: []string{
+				"update changes the Helm releases of 11 Clusters at once, consider setting spec.rollout.upgrade to roll it out in batches",
+			},
+		},
+		{
+			name:        "values change of many clusters with lightweight status",
+			clusters:    11,
+			lightweight: true,
+			oldSpec:     HelmChartProxySpec{ChartName: "nginx", Version: "1.0.0", ValuesTemplate: "replicas: 1"},
+			newSpec:     HelmChartProxySpec{ChartName: "nginx", Version: "1.0.0", ValuesTemplate: "replicas: 2"},
+			expectedWarnings: []string{
+				"update changes the Helm releases of 11 Clusters at once, consider setting spec.rollout.upgrade to roll it out in batches",
+			},
+		},
+		{
+			name:      "values change of many clusters with disabled warning",
+			clusters:  11,
+			threshold: ptr.To(0),
+			oldSpec:   HelmChartProxySpec{ChartName: "nginx", Version: "1.0.0", ValuesTemplate: "replicas: 1"},
+			newSpec:   HelmChartProxySpec{ChartName: "nginx", Version: "1.0.0", ValuesTemplate: "replicas: 2"},
+		},
+		{
+			name:     "change of many clusters with upgrade rollout",
+			clusters: 11,
+			oldSpec:  HelmChartProxySpec{ChartName: "nginx", Version: "1.0.0", Rollout: upgradeRollout},
+			newSpec:  HelmChartProxySpec{ChartName: "nginx", Version: "1.1.0", Rollout: upgradeRollout},
+		},
+		{
+			name:     "major version change",
+			clusters: 1,
+			oldSpec:  HelmChartProxySpec{ChartName: "nginx", Version: "v1.2.0"},
+			newSpec:  HelmChartProxySpec{ChartName: "nginx", Version: "v2.0.0"},
+			expectedWarnings: []string{
+				"update changes the major version of chart nginx from v1.2.0 to v2.0.0, which may contain breaking changes, consider setting spec.rollout.upgrade to roll it out in batches",
+			},
+		},
+		{
+			name:     "major version change of many clusters with upgrade rollout",
+			clusters: 20,
+			oldSpec:  HelmChartProxySpec{ChartName: "nginx", Version: "1.2.0", Rollout: upgradeRollout},
+			newSpec:  HelmChartProxySpec{ChartName: "nginx", Version: "2.0.0", Rollout: upgradeRollout},
+			expectedWarnings: []string{
+				"update changes the major version of chart nginx from 1.2.0 to 2.0.0, which may contain breaking changes",
+			},
+		},
+		{
+			name:     "unpinned version",
+			clusters: 1,
+			oldSpec:  HelmChartProxySpec{ChartName: "nginx", Version: "1.2.0"},
+			newSpec:  HelmChartProxySpec{ChartName: "nginx"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			oldObj := &HelmChartProxy{Spec: tc.oldSpec, Status: HelmChartProxyStatus{MatchingClusters: matchingClusters(tc.clusters)}}
+			if tc.lightweight {
+				oldObj.Status = HelmChartProxyStatus{MatchingClusterCount: int32(tc.clusters)}
+			}
+			newObj := &HelmChartProxy{Spec: tc.newSpec, Status: oldObj.Status}
+			w := &HelmChartProxyWebhook{BlastRadiusWarningThreshold: ptr.Deref(tc.threshold, DefaultBlastRadiusWarningThreshold)}
+
+			g.Expect(w.blastRadiusWarnings(oldObj, newObj)).To(ConsistOf(tc.expectedWarnings))
+		})
+	}
+}
+
+func TestValidateEnvironments(t *testing.T) {
+	g := NewWithT(t)
+
+	environments := []Environment{
+		{Name: "dev", ClusterSelector: metav1.LabelSelector{MatchLabels: map[string]string{"env": "dev"}}},
+		{Name: "prod", ClusterSelector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: "env", Operator: "Matches", Values: []string{"prod"}},
+		}}},
+	}
+
+	allErrs := validateEnvironments(environments)
+	g.Expect(allErrs).To(HaveLen(1))
+	g.Expect(allErrs[0].Field).To(Equal("spec.environments[1].clusterSelector"))
+}
+
+func TestValidateClusterWatchFilterValue(t *testing.T) {
+	g := NewWithT(t)
+
+	spec := HelmChartProxySpec{
+		ClusterSelector:         metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+		ClusterWatchFilterValue: "team-a",
+	}
+	g.Expect(validateClusterWatchFilterValue(spec)).To(BeEmpty())
+
+	spec.ClusterSelector.MatchLabels[clusterv1.WatchLabel] = "team-b"
+	g.Expect(validateClusterWatchFilterValue(spec)).To(HaveLen(1))
+
+	spec.ClusterSelector.MatchLabels[clusterv1.WatchLabel] = "team-a"
+	g.Expect(validateClusterWatchFilterValue(spec)).To(BeEmpty())
+
+	spec.ClusterWatchFilterValue = "team a"
+	g.Expect(validateClusterWatchFilterValue(spec)).NotTo(BeEmpty())
+}
+
+func TestValidatePropagateClusterLabels(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(validatePropagateClusterLabels(nil)).To(BeEmpty())
+	g.Expect(validatePropagateClusterLabels([]string{"team", "example.com/env"})).To(BeEmpty())
+	g.Expect(validatePropagateClusterLabels([]string{"team", "not a label"})).To(HaveLen(1))
+	g.Expect(validatePropagateClusterLabels([]string{"owner", clusterv1.ClusterNameLabel, HelmChartProxyLabelName, PostRendererHashLabelName})).To(HaveLen(4))
+}
+
+func TestValidateRepositoryRef(t *testing.T) {
+	g := NewWithT(t)
+
+	spec := HelmChartProxySpec{RepoURL: "https://charts.corp.local"}
+	g.Expect(validateRepositoryRef(spec)).To(BeEmpty())
+
+	spec.RepositoryRef = &HelmRepositoryReference{Name: "corp"}
+	g.Expect(validateRepositoryRef(spec)).To(HaveLen(1))
+
+	spec.RepoURL = ""
+	g.Expect(validateRepositoryRef(spec)).To(BeEmpty())
+
+	spec.ChartBundleRef = &ChartBundleReference{Name: "bundle"}
+	g.Expect(validateRepositoryRef(spec)).To(HaveLen(1))
+}
+
+func TestValidateVersion(t *testing.T) {
+	g := NewWithT(t)
+
+	for _, version := range []string{"", "1.2.3", "v1.2.3", ">=1.2.0 <2.0.0", "~1.4", "^2", "1.x"} {
+		g.Expect(validateVersion(HelmChartProxySpec{Version: version})).To(BeEmpty(), version)
+	}
+	g.Expect(IsVersionConstraint("v1.2.3")).To(BeFalse())
+	g.Expect(IsVersionConstraint(">=1.2.0 <2.0.0")).To(BeTrue())
+
+	allErrs := validateVersion(HelmChartProxySpec{Version: "latest"})
+	g.Expect(allErrs).To(HaveLen(1))
+	g.Expect(allErrs[0].Field).To(Equal("spec.version"))
+
+	g.Expect(validateVersion(HelmChartProxySpec{Version: "1.2.3", ChartBundleRef: &ChartBundleReference{Name: "bundle"}})).To(BeEmpty())
+	g.Expect(validateVersion(HelmChartProxySpec{Version: "~1.2", ChartBundleRef: &ChartBundleReference{Name: "bundle"}})).To(HaveLen(1))
+}
+
+func TestValidateDigest(t *testing.T) {
+	g := NewWithT(t)
+
+	digest1 := "sha256:" + strings.Repeat("a", 64)
+	digest2 := "sha256:" + strings.Repeat("b", 64)
+
+	g.Expect(validateDigest(HelmChartProxySpec{RepoURL: "oci://registry.corp.local/charts"})).To(BeEmpty())
+	g.Expect(validateDigest(HelmChartProxySpec{RepoURL: "oci://registry.corp.local/charts", Digest: digest1})).To(BeEmpty())
+	g.Expect(validateDigest(HelmChartProxySpec{RepoURL: "oci://registry.corp.local/charts", Version: "1.2.3", Digest: digest1})).To(BeEmpty())
+	g.Expect(validateDigest(HelmChartProxySpec{RepositoryRef: &HelmRepositoryReference{Name: "corp"}, Digest: digest1})).To(BeEmpty())
+
+	for _, dgst := range []string{"sha256:abc", "sha512:" + strings.Repeat("a", 128), strings.Repeat("a", 64), "sha256:" + strings.Repeat("A", 64)} {
+		allErrs := validateDigest(HelmChartProxySpec{RepoURL: "oci://registry.corp.local/charts", Digest: dgst})
+		g.Expect(allErrs).To(HaveLen(1), dgst)
+		g.Expect(allErrs[0].Field).To(Equal("spec.digest"))
+	}
+
+	g.Expect(validateDigest(HelmChartProxySpec{RepoURL: "https://charts.corp.local", Digest: digest1})).To(HaveLen(1))
+	g.Expect(validateDigest(HelmChartProxySpec{ChartBundleRef: &ChartBundleReference{Name: "bundle"}, Version: "1.2.3", Digest: digest1})).To(HaveLen(1))
+	g.Expect(validateDigest(HelmChartProxySpec{Git: &GitChartSource{URL: "https://git.corp.local/charts.git"}, Digest: digest1})).To(HaveLen(1))
+	g.Expect(validateDigest(HelmChartProxySpec{RepoURL: "oci://registry.corp.local/charts", Version: "~1.2", Digest: digest1})).To(HaveLen(1))
+	g.Expect(validateDigest(HelmChartProxySpec{
+		RepoURL:      "oci://registry.corp.local/charts",
+		Version:      "1.2.3",
+		Digest:       digest1,
+		Environments: []Environment{{Name: "dev", Version: "1.3.0"}, {Name: "prod"}},
+	})).To(HaveLen(1))
+
+	oldSpec := HelmChartProxySpec{RepoURL: "oci://registry.corp.local/charts", Version: "1.2.3", Digest: digest1}
+	g.Expect(validateDigestUpdate(oldSpec, oldSpec)).To(BeEmpty())
+	g.Expect(validateDigestUpdate(oldSpec, HelmChartProxySpec{Version: "1.3.0", Digest: digest2})).To(BeEmpty())
+	g.Expect(validateDigestUpdate(oldSpec, HelmChartProxySpec{Version: "1.3.0"})).To(BeEmpty(), "the digest can be unpinned")
+	g.Expect(validateDigestUpdate(HelmChartProxySpec{Version: "1.2.3"}, oldSpec)).To(BeEmpty(), "a version can be pinned")
+
+	allErrs := validateDigestUpdate(oldSpec, HelmChartProxySpec{Version: "1.3.0", Digest: digest1})
+	g.Expect(allErrs).To(HaveLen(1), "the tag cannot be moved to the pinned artifact")
+	g.Expect(allErrs[0].Field).To(Equal("spec.version"))
+
+	allErrs = validateDigestUpdate(oldSpec, HelmChartProxySpec{Version: "1.2.3", Digest: digest2})
+	g.Expect(allErrs).To(HaveLen(1), "the pinned tag cannot point to another artifact")
+	g.Expect(allErrs[0].Field).To(Equal("spec.digest"))
+}
+
+func TestValidateGit(t *testing.T) {
+	g := NewWithT(t)
+
+	spec := HelmChartProxySpec{RepoURL: "https://charts.corp.local"}
+	g.Expect(validateGit(spec)).To(BeEmpty())
+
+	spec.Git = &GitChartSource{URL: "https://git.corp.local/charts.git", Ref: "main", Path: "charts/nginx"}
+	g.Expect(validateGit(spec)).To(HaveLen(1))
+
+	spec.RepoURL = ""
+	g.Expect(validateGit(spec)).To(BeEmpty())
+
+	spec.RepositoryRef = &HelmRepositoryReference{Name: "corp"}
+	spec.ChartBundleRef = &ChartBundleReference{Name: "bundle"}
+	spec.RepositoryCredentials = &corev1.SecretReference{Name: "credentials"}
+	g.Expect(validateGit(spec)).To(HaveLen(3))
+
+	spec = HelmChartProxySpec{Git: &GitChartSource{URL: "ssh://git@git.corp.local/charts.git"}}
+	g.Expect(validateGit(spec)).To(HaveLen(1))
+
+	for _, chartPath := range []string{"/charts/nginx", "..", "charts/../../nginx"} {
+		spec.Git = &GitChartSource{URL: "https://git.corp.local/charts.git", Path: chartPath}
+		g.Expect(validateGit(spec)).To(HaveLen(1), chartPath)
+	}
+
+	spec.Git = &GitChartSource{URL: "https://git.corp.local/charts.git", AuthSecretRef: &corev1.SecretReference{}}
+	g.Expect(validateGit(spec)).To(HaveLen(1))
+
+	spec.Verify = &ChartVerification{Provenance: &ProvenanceVerification{KeyringSecretRef: corev1.SecretReference{Name: "keyring"}}}
+	g.Expect(validateVerify(spec)).To(HaveLen(1))
+}
+
+func TestValidateManifests(t *testing.T) {
+	g := NewWithT(t)
+
+	spec := HelmChartProxySpec{RepoURL: "https://charts.corp.local"}
+	g.Expect(validateManifests(spec)).To(BeEmpty())
+
+	spec.Manifests = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n"
+	g.Expect(validateManifests(spec)).To(HaveLen(1))
+
+	spec.RepoURL = ""
+	g.Expect(validateManifests(spec)).To(BeEmpty())
+
+	spec.RepositoryRef = &HelmRepositoryReference{Name: "corp"}
+	spec.ChartBundleRef = &ChartBundleReference{Name: "bundle"}
+	spec.Git = &GitChartSource{URL: "https://git.corp.local/charts.git"}
+	g.Expect(validateManifests(spec)).To(HaveLen(3))
+}
+
+func TestValidateReconcileInterval(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(validateReconcileInterval(nil)).To(BeEmpty())
+	g.Expect(validateReconcileInterval(&metav1.Duration{Duration: 10 * time.Minute})).To(BeEmpty())
+
+	allErrs := validateReconcileInterval(&metav1.Duration{Duration: -time.Minute})
+	g.Expect(allErrs).To(HaveLen(1))
+	g.Expect(allErrs[0].Field).To(Equal("spec.reconcileInterval"))
+}
+
+func TestValidateTestOptions(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(validateTestOptions(nil)).To(BeEmpty())
+	g.Expect(validateTestOptions(&HelmTestOptions{
+		Enable:  true,
+		Timeout: &metav1.Duration{Duration: 5 * time.Minute},
+		Filters: []string{"test-connection", "!test-slow"},
+	})).To(BeEmpty())
+
+	allErrs := validateTestOptions(&HelmTestOptions{
+		Timeout: &metav1.Duration{},
+		Filters: []string{"test-connection", "!"},
+	})
+	g.Expect(allErrs).To(HaveLen(2))
+	g.Expect(allErrs[0].Field).To(Equal("spec.options.test.timeout"))
+	g.Expect(allErrs[1].Field).To(Equal("spec.options.test.filters[1]"))
+}
+
+func TestValidateVerify(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(validateVerify(HelmChartProxySpec{RepoURL: "https://charts.example.com"})).To(BeEmpty())
+	g.Expect(validateVerify(HelmChartProxySpec{
+		RepoURL: "https://charts.example.com",
+		Verify: &ChartVerification{
+			Provenance: &ProvenanceVerification{KeyringSecretRef: corev1.SecretReference{Name: "keyring"}},
+		},
+	})).To(BeEmpty())
+	g.Expect(validateVerify(HelmChartProxySpec{
+		RepoURL: "oci://registry.example.com/charts",
+		Verify: &ChartVerification{
+			Cosign: &CosignVerification{
+				Keyless: &CosignKeylessVerification{
+					Issuer:               "https://token.actions.githubusercontent.com",
+					Subject:              "https://github.com/org/repo/.github/workflows/release.yaml@refs/heads/main",
+					TrustedRootSecretRef: corev1.SecretReference{Name: "sigstore"},
+				},
+			},
+		},
+	})).To(BeEmpty())
+
+	allErrs := validateVerify(HelmChartProxySpec{
+		RepoURL: "oci://registry.example.com/charts",
+		Verify: &ChartVerification{
+			Provenance: &ProvenanceVerification{},
+			Cosign:     &CosignVerification{},
+		},
+	})
+	g.Expect(allErrs).To(HaveLen(3))
+	g.Expect(allErrs[0].Field).To(Equal("spec.verify.provenance.keyringSecretRef.name"))
+	g.Expect(allErrs[1].Field).To(Equal("spec.verify.provenance"))
+	g.Expect(allErrs[2].Field).To(Equal("spec.verify.cosign"))
+
+	allErrs = validateVerify(HelmChartProxySpec{
+		RepoURL: "https://charts.example.com",
+		Verify: &ChartVerification{
+			Cosign: &CosignVerification{
+				PublicKeySecretRef: &corev1.SecretReference{Name: "cosign"},
+				Keyless:            &CosignKeylessVerification{TrustedRootSecretRef: corev1.SecretReference{Name: "sigstore"}},
+			},
+		},
+	})
+	g.Expect(allErrs).To(HaveLen(2))
+	g.Expect(allErrs[0].Type).To(Equal(field.ErrorTypeForbidden))
+	g.Expect(allErrs[1].Field).To(Equal("spec.verify.cosign"))
+
+	g.Expect(validateVerify(HelmChartProxySpec{
+		ChartBundleRef: &ChartBundleReference{Name: "bundle"},
+		Verify:         &ChartVerification{Provenance: &ProvenanceVerification{KeyringSecretRef: corev1.SecretReference{Name: "keyring"}}},
+	})).To(HaveLen(1))
+}
+
+func TestValidatePostRenderer(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(validatePostRenderer(nil)).To(BeEmpty())
+	g.Expect(validatePostRenderer(&PostRenderer{Patches: []KustomizePatch{
+		{Patch: "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: controller\nspec:\n  replicas: 2\n"},
+		{
+			Patch:  "- op: add\n  path: /spec/template/spec/nodeSelector\n  value:\n    role: infra\n",
+			Target: &PatchSelector{Kind: "Deployment", LabelSelector: "app.kubernetes.io/part-of=ingress"},
+		},
+		{Patch: "spec:\n  replicas: 2\n", Target: &PatchSelector{Kind: "Deployment"}},
+	}})).To(BeEmpty())
+
+	allErrs := validatePostRenderer(&PostRenderer{Patches: []KustomizePatch{
+		{Patch: "- op: remove\n  path: /spec/replicas\n"},
+		{Patch: "spec:\n  replicas: 2\n"},
+		{Patch: "spec: [", Target: &PatchSelector{Kind: "Deployment"}},
+		{Patch: "replicas", Target: &PatchSelector{Kind: "Deployment"}},
+		{Patch: "spec: {}", Target: &PatchSelector{LabelSelector: "app in (", AnnotationSelector: "!!"}},
+	}})
+	g.Expect(allErrs).To(HaveLen(6))
+	g.Expect(allErrs[0].Field).To(Equal("spec.postRenderer.patches[0].target"))
+	g.Expect(allErrs[1].Field).To(Equal("spec.postRenderer.patches[1].target"))
+	g.Expect(allErrs[2].Field).To(Equal("spec.postRenderer.patches[2].patch"))
+	g.Expect(allErrs[3].Field).To(Equal("spec.postRenderer.patches[3].patch"))
+	g.Expect(allErrs[4].Field).To(Equal("spec.postRenderer.patches[4].target.labelSelector"))
+	g.Expect(allErrs[5].Field).To(Equal("spec.postRenderer.patches[4].target.annotationSelector"))
+}
+
+func TestValidateDriftIgnoreRules(t *testing.T) {
+	g := NewWithT(t)
+
+	fldPath := field.NewPath("spec", "driftIgnoreRules")
+	g.Expect(validateDriftIgnoreRules([]DriftIgnoreRule{{Kind: "Deployment", Paths: []string{"/spec/replicas"}}}, fldPath)).To(BeEmpty())
+	g.Expect(validateDriftIgnoreRules([]DriftIgnoreRule{{Paths: []string{".spec.template.spec.containers[*].resources"}}}, fldPath)).To(BeEmpty())
+	g.Expect(validateDriftIgnoreRules([]DriftIgnoreRule{{Paths: []string{"/spec/replicas", "/", "$"}}}, fldPath)).To(HaveLen(2))
+}
+
+func TestValidateDriftDetection(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(validateDriftDetection(nil)).To(BeEmpty())
+	g.Expect(validateDriftDetection(&DriftDetection{Enabled: true, Interval: &metav1.Duration{Duration: time.Minute}})).To(BeEmpty())
+
+	allErrs := validateDriftDetection(&DriftDetection{
+		Enabled:     true,
+		Interval:    &metav1.Duration{},
+		IgnoreRules: []DriftIgnoreRule{{Paths: []string{"/"}}},
+	})
+	g.Expect(allErrs).To(HaveLen(2))
+	g.Expect(allErrs[0].Field).To(Equal("spec.driftDetection.interval"))
+	g.Expect(allErrs[1].Field).To(Equal("spec.driftDetection.ignoreRules[0].paths[0]"))
+}
+
+func TestValidateClusterResourceSetSignatures(t *testing.T) {
+	g := NewWithT(t)
+
+	allErrs := validateClusterResourceSetSignatures([]ClusterResourceSetSignature{
+		{ClusterResourceSetName: "calico"},
+		{Kind: "ConfigMap", Name: "calico-manifests"},
+		{Kind: "Secret"},
+	})
+	g.Expect(allErrs).To(HaveLen(1))
+	g.Expect(allErrs[0].Field).To(Equal("spec.clusterResourceSetSignatures[2]"))
+}
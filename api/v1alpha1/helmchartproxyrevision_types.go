@@ -0,0 +1,68 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// HelmChartProxyRevisionSpec defines the snapshot of the spec of a HelmChartProxy.
+type HelmChartProxyRevisionSpec struct {
+	// HelmChartProxyName is the name of the HelmChartProxy in the namespace of the HelmChartProxyRevision whose spec is
+	// recorded.
+	// +kubebuilder:validation:MinLength=1
+	HelmChartProxyName string `json:"helmChartProxyName"`
+
+	// Revision is the number of the revision. It is incremented each time the spec of the HelmChartProxy changes, and a
+	// spec that returns to a previously recorded one moves that revision to the latest number.
+	Revision int64 `json:"revision"`
+
+	// Data is the spec of the HelmChartProxy at this revision.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Data runtime.RawExtension `json:"data"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=hcprev
+// +kubebuilder:printcolumn:name="HelmChartProxy",type="string",JSONPath=".spec.helmChartProxyName"
+// +kubebuilder:printcolumn:name="Revision",type="integer",JSONPath=".spec.revision"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// HelmChartProxyRevision is the Schema for the helmchartproxyrevisions API. It is an immutable snapshot of the spec of a
+// HelmChartProxy, created by the controller each time the spec changes, like a ControllerRevision. The revisions are an
+// audit trail of the addon configuration, and a HelmChartProxy is rolled back to one of them with the
+// RollbackToRevisionAnnotation.
+type HelmChartProxyRevision struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec HelmChartProxyRevisionSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// HelmChartProxyRevisionList contains a list of HelmChartProxyRevision.
+type HelmChartProxyRevisionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HelmChartProxyRevision `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HelmChartProxyRevision{}, &HelmChartProxyRevisionList{})
+}
@@ -30,12 +30,85 @@ const (
 	// HelmChartProxyLabelName is the label signifying which HelmChartProxy a HelmReleaseProxy is associated with.
 	HelmChartProxyLabelName = "helmreleaseproxy.addons.cluster.x-k8s.io/helmchartproxy-name"
 
+	// ValuesHashLabelName is the label set by the controller to a hash of the values of the Helm release deployed on the
+	// Cluster, so that HelmReleaseProxies running stale values can be found with a label selector.
+	ValuesHashLabelName = "helmreleaseproxy.addons.cluster.x-k8s.io/values-hash"
+
+	// ChartDigestLabelName is the label set by the controller to a digest of the chart of the Helm release deployed on the
+	// Cluster, so that HelmReleaseProxies running a stale chart can be found with a label selector.
+	ChartDigestLabelName = "helmreleaseproxy.addons.cluster.x-k8s.io/chart-digest"
+
+	// OwnerNamespaceLabelName is the label set on the release namespace and Helm storage Secrets on the workload Cluster
+	// signifying the management Cluster namespace of the HelmChartProxy and HelmReleaseProxy managing the release.
+	OwnerNamespaceLabelName = "addons.cluster.x-k8s.io/owner-namespace"
+
+	// OwnerHelmChartProxyLabelName is the label set on the release namespace and Helm storage Secrets on the workload Cluster
+	// signifying the HelmChartProxy managing the release.
+	OwnerHelmChartProxyLabelName = "addons.cluster.x-k8s.io/owner-helmchartproxy"
+
+	// OwnerHelmReleaseProxyLabelName is the label set on the release namespace and Helm storage Secrets on the workload Cluster
+	// signifying the HelmReleaseProxy managing the release.
+	OwnerHelmReleaseProxyLabelName = "addons.cluster.x-k8s.io/owner-helmreleaseproxy"
+
+	// PostRendererHashLabelName is the label set on a Helm release to a hash of the PostRenderer it was rendered with, so
+	// that the release is upgraded when the PostRenderer changes.
+	PostRendererHashLabelName = "addons.cluster.x-k8s.io/post-renderer-hash"
+
+	// GitCommitAnnotation is the annotation set on the Chart.yaml of a chart pulled from a Git repository to the commit it
+	// was checked out at, so that the release is upgraded when the commit changes even if the chart version does not.
+	GitCommitAnnotation = "addons.cluster.x-k8s.io/git-commit"
+
+	// CreatedForReleaseAnnotation is the annotation set on a release namespace on the workload Cluster signifying that the
+	// namespace was created by the install of the Helm release named in its value.
+	CreatedForReleaseAnnotation = "addons.cluster.x-k8s.io/created-for-release"
+
 	// IsReleaseNameGeneratedAnnotation is the annotation signifying the Helm release name is auto-generated.
 	IsReleaseNameGeneratedAnnotation = "helmreleaseproxy.addons.cluster.x-k8s.io/is-release-name-generated"
 
 	// ReleaseSuccessfullyInstalledAnnotation is the annotation signifying the Helm release has been successfully installed at least once.
 	// This is used to determine if the HelmReleaseProxy is in a ready state for the InstallOnce strategy.
 	ReleaseSuccessfullyInstalledAnnotation = "helmreleaseproxy.addons.cluster.x-k8s.io/release-successfully-installed"
+
+	// FailoverLeaseHolderAnnotation is the annotation set on the ownership lease ConfigMap on the workload Cluster
+	// signifying the failover identity of the management cluster holding the lease.
+	FailoverLeaseHolderAnnotation = "addons.cluster.x-k8s.io/lease-holder"
+
+	// FailoverLeaseRenewTimeAnnotation is the annotation set on the ownership lease ConfigMap on the workload Cluster
+	// signifying the RFC 3339 time the lease was last renewed.
+	FailoverLeaseRenewTimeAnnotation = "addons.cluster.x-k8s.io/lease-renew-time"
+
+	// FailoverLeaseDurationAnnotation is the annotation set on the ownership lease ConfigMap on the workload Cluster
+	// signifying the duration after the renew time that the lease expires.
+	FailoverLeaseDurationAnnotation = "addons.cluster.x-k8s.io/lease-duration"
+
+	// ShowValuesAnnotation is the annotation signifying that the values of the Helm release deployed on the workload
+	// Cluster are copied, with sensitive values redacted, to a ConfigMap owned by the HelmReleaseProxy. The ConfigMap is
+	// deleted once the annotation is removed.
+	ShowValuesAnnotation = "addons.cluster.x-k8s.io/show-values"
+
+	// AllowUninstallAnnotation is the annotation set on a HelmReleaseProxy or its HelmChartProxy signifying that the Helm
+	// release may be uninstalled even though it is protected by Options.Protect.
+	AllowUninstallAnnotation = "addons.cluster.x-k8s.io/allow-uninstall"
+
+	// SkipWaitAnnotation is the annotation set on a HelmReleaseProxy signifying that installs and upgrades of its Helm
+	// release do not wait for the resources to become ready, regardless of Options.Wait, Options.WaitForJobs and
+	// Options.Atomic. It is meant for troubleshooting the release on one Cluster without changing the HelmChartProxy.
+	SkipWaitAnnotation = "addons.cluster.x-k8s.io/skip-wait"
+
+	// SkipDriftCheckAnnotation is the annotation set on a HelmReleaseProxy signifying that its Helm release is not
+	// checked for drift, so that resources of the release can be changed manually on one Cluster while troubleshooting.
+	SkipDriftCheckAnnotation = "addons.cluster.x-k8s.io/skip-drift-check"
+
+	// SkipTestsAnnotation is the annotation set on a HelmReleaseProxy signifying that the tests of its Helm release are
+	// not run after installs and upgrades, regardless of Options.Test.
+	SkipTestsAnnotation = "addons.cluster.x-k8s.io/skip-tests"
+
+	// ValuesBlockLabelName is the label set on the ConfigMaps storing values referenced by HelmReleaseProxies, so that
+	// unreferenced ones can be found and deleted.
+	ValuesBlockLabelName = "addons.cluster.x-k8s.io/values-block"
+
+	// ValuesBlockKey is the key of the YAML of the value in a ConfigMap storing values referenced by HelmReleaseProxies.
+	ValuesBlockKey = "value.yaml"
 )
 
 // HelmReleaseProxySpec defines the desired state of HelmReleaseProxy.
@@ -49,7 +122,23 @@ type HelmReleaseProxySpec struct {
 
 	// RepoURL is the URL of the Helm chart repository.
 	// e.g. chart-path oci://repo-url/chart-name as repoURL: oci://repo-url and https://repo-url/chart-name as repoURL: https://repo-url
-	RepoURL string `json:"repoURL"`
+	// It is empty if the Helm chart is installed from a ChartBundle or a Git repository.
+	// +optional
+	RepoURL string `json:"repoURL,omitempty"`
+
+	// ChartBundleRef is a reference to the ChartBundle containing the Helm chart.
+	// +optional
+	ChartBundleRef *ChartBundleReference `json:"chartBundleRef,omitempty"`
+
+	// RepositoryRef is a reference to the HelmRepository of the Helm chart. The repository is resolved whenever the
+	// HelmReleaseProxy is reconciled and takes precedence over RepoURL, Credentials, RepositoryHeaders,
+	// RepositoryCredentials, TLSConfig and ProxyURL.
+	// +optional
+	RepositoryRef *HelmRepositoryReference `json:"repositoryRef,omitempty"`
+
+	// Git is the Git repository the Helm chart is pulled from.
+	// +optional
+	Git *GitChartSource `json:"git,omitempty"`
 
 	// ReleaseName is the release name of the installed Helm chart. If it is not specified, a name will be generated.
 	// +optional
@@ -61,15 +150,38 @@ type HelmReleaseProxySpec struct {
 	ReleaseNamespace string `json:"namespace"`
 
 	// Version is the version of the Helm chart. If it is not specified, the chart will use
-	// and be kept up to date with the latest version.
+	// and be kept up to date with the latest version. It can also be a semver constraint, e.g. `>=1.2.0 <2.0.0`, which
+	// is resolved to the latest matching version every ResyncPeriod, or every 10 minutes if it is not specified.
 	// +optional
 	Version string `json:"version,omitempty"`
 
+	// Digest pins the OCI artifact of the Helm chart to the digest of its manifest, e.g. `sha256:<hex>`. The chart is
+	// pulled by the digest and must be of the Version if it is specified.
+	// +kubebuilder:validation:Pattern=`^sha256:[a-f0-9]{64}$`
+	// +optional
+	Digest string `json:"digest,omitempty"`
+
+	// ReleaseLabels are the labels set on the Helm release, e.g. the labels propagated from the Cluster by the
+	// HelmChartProxy. The Helm release is upgraded when they change.
+	// +optional
+	ReleaseLabels map[string]string `json:"releaseLabels,omitempty"`
+
 	// Values is an inline YAML representing the values for the Helm chart. This YAML is the result of the rendered
 	// Go templating with the values from the referenced workload Cluster.
 	// +optional
 	Values string `json:"values,omitempty"`
 
+	// ValuesRefs are the top-level values of the Helm chart stored in ConfigMaps instead of inline, which are merged into
+	// Values before the Helm release is installed or upgraded.
+	// +optional
+	ValuesRefs []ValuesReference `json:"valuesRefs,omitempty"`
+
+	// Manifests is the multi-document YAML of plain Kubernetes resources applied to the Cluster with server-side apply
+	// instead of installing a Helm chart. This YAML is the result of the rendered Go templating of the Manifests of the
+	// HelmChartProxy.
+	// +optional
+	Manifests string `json:"manifests,omitempty"`
+
 	// ReconcileStrategy indicates whether a Helm chart should be continuously installed, updated, and uninstalled on the Cluster,
 	// or if it should be reconciled until it is successfully installed on the Cluster and not otherwise updated or uninstalled.
 	// If not specified, the default behavior will be to reconcile continuously. This field is immutable.
@@ -78,6 +190,14 @@ type HelmReleaseProxySpec struct {
 	// +optional
 	ReconcileStrategy string `json:"reconcileStrategy,omitempty"`
 
+	// DeletionPolicy indicates whether a Helm release installed with the `InstallOnce` ReconcileStrategy is uninstalled when the
+	// HelmReleaseProxy is deleted. If not specified, the Helm release is left in place. This field is ignored for the `Continuous`
+	// ReconcileStrategy, which always uninstalls the Helm release.
+	// Possible values are `Orphan`, `Uninstall`, or unset.
+	// +kubebuilder:validation:Enum="";Orphan;Uninstall
+	// +optional
+	DeletionPolicy string `json:"deletionPolicy,omitempty"`
+
 	// Options represents the helm setting options which can be used to control behaviour of helm operations(Install, Upgrade, Delete, etc)
 	// via options like wait, skipCrds, timeout, waitForJobs, etc.
 	// +optional
@@ -87,8 +207,161 @@ type HelmReleaseProxySpec struct {
 	// +optional
 	Credentials *Credentials `json:"credentials,omitempty"`
 
+	// RepositoryHeaders is a reference to a Secret whose keys and values are sent as extra HTTP headers with every request
+	// to an HTTP chart repository.
+	// +optional
+	RepositoryHeaders *corev1.SecretReference `json:"repositoryHeaders,omitempty"`
+
+	// RepositoryCredentials is a reference to a Secret containing the credentials of an HTTP chart repository. The Secret
+	// contains either the keys `username` and `password` for basic authentication or the key `token` for bearer token
+	// authentication.
+	// +optional
+	RepositoryCredentials *corev1.SecretReference `json:"repositoryCredentials,omitempty"`
+
+	// ClusterReadiness defines the capacity the Cluster must have before the Helm release is first installed, in addition
+	// to an initialized control plane. If it is not specified, the Helm release is installed as soon as the control plane
+	// is initialized.
+	// +optional
+	ClusterReadiness *ClusterReadinessOptions `json:"clusterReadiness,omitempty"`
+
+	// ResyncPeriod is the interval at which the HelmReleaseProxy is periodically reconciled against the workload
+	// Cluster. If it is not specified, the controller's --sync-period is used.
+	// +optional
+	ResyncPeriod *metav1.Duration `json:"resyncPeriod,omitempty"`
+
+	// HoldDuringClusterUpgrade indicates whether upgrades of the Helm release are held while the Kubernetes version of the
+	// Cluster's control plane is being upgraded. Initial installs are not held.
+	// +optional
+	HoldDuringClusterUpgrade bool `json:"holdDuringClusterUpgrade,omitempty"`
+
+	// KubeVersion is a semver range of the Kubernetes versions the Helm chart supports. The Helm release is not installed or
+	// upgraded if the Kubernetes version of the Cluster is outside the range.
+	// +optional
+	KubeVersion string `json:"kubeVersion,omitempty"`
+
+	// CopySBOMs indicates whether the SBOMs attached to the OCI artifact of the Helm chart are copied to a ConfigMap owned
+	// by the HelmReleaseProxy.
+	// +optional
+	CopySBOMs bool `json:"copySBOMs,omitempty"`
+
+	// RecordValuesOverrides indicates whether the default values of the Helm chart overridden by the values of the Helm
+	// release are recorded in a ConfigMap owned by the HelmReleaseProxy.
+	// +optional
+	RecordValuesOverrides bool `json:"recordValuesOverrides,omitempty"`
+
+	// Failover enables the ownership lease on the Cluster, so that the Helm release is only reconciled by the management
+	// cluster holding the lease.
+	// +optional
+	Failover *FailoverOptions `json:"failover,omitempty"`
+
+	// DriftPolicy indicates whether drift of the objects of the Helm release from its manifest is corrected, only
+	// reported, or ignored. If it is not specified, it defaults to `Correct`.
+	// +kubebuilder:validation:Enum="";Correct;Warn;Ignore
+	// +optional
+	DriftPolicy string `json:"driftPolicy,omitempty"`
+
+	// DriftIgnoreRules exclude fields of the objects of the Helm release from drift detection.
+	// +optional
+	DriftIgnoreRules []DriftIgnoreRule `json:"driftIgnoreRules,omitempty"`
+
+	// DriftDetection periodically compares the objects of the Helm release against its manifest and handles drift
+	// according to the DriftPolicy. If it is not specified, drift is not detected.
+	// +optional
+	DriftDetection *DriftDetection `json:"driftDetection,omitempty"`
+
+	// Impersonation is the identity the Helm release is installed, upgraded and uninstalled as on the Cluster. If it is not
+	// specified, the user of the kubeconfig of the Cluster is used.
+	// +optional
+	Impersonation *Impersonation `json:"impersonation,omitempty"`
+
 	// TLSConfig contains the TLS configuration for the HelmReleaseProxy.
 	TLSConfig *TLSConfig `json:"tlsConfig,omitempty"`
+
+	// ProxyURL is the URL of the HTTP proxy used to fetch the chart. If it is not specified, the proxy environment
+	// variables of the controller are used.
+	// +kubebuilder:validation:Pattern=`^https?://`
+	// +optional
+	ProxyURL string `json:"proxyURL,omitempty"`
+
+	// Verify verifies the signature of the Helm chart before it is installed or upgraded.
+	// +optional
+	Verify *ChartVerification `json:"verify,omitempty"`
+
+	// PostRenderer patches the manifests rendered from the Helm chart before they are installed or upgraded.
+	// +optional
+	PostRenderer *PostRenderer `json:"postRenderer,omitempty"`
+
+	// RollbackTo triggers a rollback of the Helm release on the Cluster to the given revision. It is cleared once the
+	// rollback has been performed. Installs and upgrades are then held until the chart or values of the HelmReleaseProxy
+	// change, so that the rollback is not undone.
+	// +optional
+	RollbackTo *RollbackTo `json:"rollbackTo,omitempty"`
+}
+
+// RollbackTo defines the revision to roll back a Helm release to.
+type RollbackTo struct {
+	// Revision is the revision of the Helm release to roll back to.
+	// +kubebuilder:validation:Minimum=1
+	Revision int `json:"revision"`
+}
+
+// RollbackStatus describes the last rollback of a Helm release requested with RollbackTo.
+type RollbackStatus struct {
+	// Revision is the revision the Helm release was rolled back to.
+	Revision int `json:"revision"`
+
+	// Time is the time the rollback was performed.
+	Time metav1.Time `json:"time"`
+
+	// SpecHash is the hash of the chart and values of the HelmReleaseProxy at the time of the rollback. Installs and
+	// upgrades are held as long as they are unchanged.
+	SpecHash string `json:"specHash"`
+}
+
+// UpgradeRollbackStatus describes a failed atomic upgrade of a Helm release that was rolled back.
+type UpgradeRollbackStatus struct {
+	// FailedRevision is the revision of the failed upgrade.
+	FailedRevision int `json:"failedRevision"`
+
+	// Revision is the revision the Helm release was rolled back to.
+	Revision int `json:"revision"`
+
+	// Time is the time the rollback was performed.
+	Time metav1.Time `json:"time"`
+
+	// Message is the error of the failed upgrade.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// ReleaseTestStatus describes the last run of the tests of a Helm release.
+type ReleaseTestStatus struct {
+	// Revision is the revision of the Helm release the tests were run for.
+	Revision int `json:"revision"`
+
+	// Time is the time the tests were run.
+	Time metav1.Time `json:"time"`
+
+	// Results are the results of the tests.
+	// +optional
+	Results []ReleaseTestResult `json:"results,omitempty"`
+}
+
+// ReleaseTestResult is the result of a test of a Helm release.
+type ReleaseTestResult struct {
+	// Name is the name of the test.
+	Name string `json:"name"`
+
+	// Phase is the phase of the test, i.e. Succeeded, Failed, Running or Unknown.
+	Phase string `json:"phase"`
+
+	// StartedAt is the time the test was started.
+	// +optional
+	StartedAt *metav1.Time `json:"startedAt,omitempty"`
+
+	// CompletedAt is the time the test completed.
+	// +optional
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
 }
 
 // HelmReleaseProxyStatus defines the observed state of HelmReleaseProxy.
@@ -105,11 +378,170 @@ type HelmReleaseProxyStatus struct {
 	// +optional
 	Revision int `json:"revision,omitempty"`
 
+	// ChartVersion is the version of the chart of the deployed Helm release. If the Version is a semver constraint, it is
+	// the version the constraint was resolved to.
+	// +optional
+	ChartVersion string `json:"chartVersion,omitempty"`
+
+	// ResolvedDigest is the digest of the manifest of the OCI chart of the deployed Helm release, e.g. `sha256:<hex>`, i.e.
+	// the pinned Digest or the digest the tag of the Version resolved to. It is not set for charts of other repositories or
+	// charts without an exact version.
+	// +optional
+	ResolvedDigest string `json:"resolvedDigest,omitempty"`
+
+	// DefaultValuesDigest is the digest of the default values of the chart of the deployed Helm release, e.g.
+	// `sha256:<hex>`, telling whether a new chart version changed the defaults the values are layered on.
+	// +optional
+	DefaultValuesDigest string `json:"defaultValuesDigest,omitempty"`
+
+	// ManifestDigest is the digest of the rendered manifest of the deployed Helm release, e.g. `sha256:<hex>`.
+	// +optional
+	ManifestDigest string `json:"manifestDigest,omitempty"`
+
+	// AppliedSpecDigest is the digest of the spec, with its referenced values resolved, the deployed Helm release was
+	// installed or upgraded from, e.g. `sha256:<hex>`. While it matches the spec and the resync period has not elapsed since
+	// the last successful reconcile, the controller does not fetch the Helm release from the workload Cluster, so that a
+	// restarted controller does not fetch the Helm releases of all HelmReleaseProxies at once.
+	// +optional
+	AppliedSpecDigest string `json:"appliedSpecDigest,omitempty"`
+
+	// Progress reports the readiness of the resources of the Helm release while an install or upgrade waits for them to
+	// become ready.
+	// +optional
+	Progress *ReleaseProgress `json:"progress,omitempty"`
+
+	// PendingChange describes the install, upgrade or uninstall of the Helm release that the controller would perform,
+	// including a diff of the values. It is only set when the controller runs with --observe-only.
+	// +optional
+	PendingChange string `json:"pendingChange,omitempty"`
+
+	// SBOMs are the SBOMs attached to the OCI artifact of the installed chart, either as layers of the chart manifest or as
+	// referrers of it.
+	// +optional
+	SBOMs []SBOMReference `json:"sboms,omitempty"`
+
+	// SBOMConfigMapName is the name of the ConfigMap in the namespace of the HelmReleaseProxy the SBOMs are copied to, keyed
+	// by their digest with the algorithm separated by a dash, e.g. `sha256-<hex>`.
+	// +optional
+	SBOMConfigMapName string `json:"sbomConfigMapName,omitempty"`
+
+	// ValuesOverridesConfigMapName is the name of the ConfigMap in the namespace of the HelmReleaseProxy the default values of
+	// the chart overridden by the values of the deployed Helm release are recorded in, with sensitive values redacted.
+	// +optional
+	ValuesOverridesConfigMapName string `json:"valuesOverridesConfigMapName,omitempty"`
+
+	// ValuesConfigMapName is the name of the ConfigMap in the namespace of the HelmReleaseProxy the redacted values of the
+	// deployed Helm release are copied to while the HelmReleaseProxy has the show-values annotation.
+	// +optional
+	ValuesConfigMapName string `json:"valuesConfigMapName,omitempty"`
+
+	// Rollback describes the last rollback of the Helm release requested with RollbackTo, while installs and upgrades are
+	// held for it.
+	// +optional
+	Rollback *RollbackStatus `json:"rollback,omitempty"`
+
+	// UpgradeRollback describes the last failed atomic upgrade of the Helm release that was rolled back.
+	// +optional
+	UpgradeRollback *UpgradeRollbackStatus `json:"upgradeRollback,omitempty"`
+
+	// Test describes the last run of the tests of the Helm release.
+	// +optional
+	Test *ReleaseTestStatus `json:"test,omitempty"`
+
+	// ManifestInventory references the resources of the Manifests applied to the Cluster, which are deleted once they are
+	// removed from the Manifests or the HelmReleaseProxy is deleted.
+	// +optional
+	ManifestInventory []corev1.ObjectReference `json:"manifestInventory,omitempty"`
+
+	// LastDriftCheckTime is the time the objects of the Helm release were last compared against its manifest.
+	// +optional
+	LastDriftCheckTime *metav1.Time `json:"lastDriftCheckTime,omitempty"`
+
+	// DriftedResources are the resources of the Helm release that drifted from its manifest at the last drift check, e.g.
+	// `Deployment ingress-nginx/ingress-nginx-controller`.
+	// +optional
+	DriftedResources []string `json:"driftedResources,omitempty"`
+
+	// LastSuccessfulReconcileTime is the time the HelmReleaseProxy was last reconciled without error, updated at most once a
+	// minute.
+	// +optional
+	LastSuccessfulReconcileTime *metav1.Time `json:"lastSuccessfulReconcileTime,omitempty"`
+
 	// ObservedGeneration is the latest generation observed by the controller.
 	// +optional
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 }
 
+// ValuesReference references a top-level value of a Helm chart stored in a ConfigMap.
+type ValuesReference struct {
+	// Key is the top-level key of the value.
+	Key string `json:"key"`
+
+	// ConfigMapName is the name of the ConfigMap in the namespace of the HelmReleaseProxy storing the YAML of the value.
+	ConfigMapName string `json:"configMapName"`
+
+	// Hash is the hex-encoded SHA-256 hash of the YAML of the value, which is verified before it is used.
+	Hash string `json:"hash"`
+}
+
+// SBOMReference references an SBOM attached to the OCI artifact of a Helm chart.
+type SBOMReference struct {
+	// MediaType is the media type of the SBOM, e.g. application/spdx+json.
+	MediaType string `json:"mediaType"`
+
+	// Digest is the digest of the SBOM layer, or of the referrer manifest if the SBOM is attached as a referrer.
+	Digest string `json:"digest"`
+
+	// Location is the OCI reference the SBOM can be fetched from, e.g. `registry.example.com/charts/nginx@sha256:<hex>`.
+	Location string `json:"location"`
+}
+
+// ReleaseProgress defines the readiness of the resources of a Helm release.
+type ReleaseProgress struct {
+	// Ready is the number of resources of the Helm release that are ready.
+	Ready int `json:"ready"`
+
+	// Pending is the number of resources of the Helm release that are not ready yet.
+	Pending int `json:"pending"`
+
+	// LastUpdated is the time at which the progress was last observed.
+	// +optional
+	LastUpdated *metav1.Time `json:"lastUpdated,omitempty"`
+
+	// Events are the most recent notable warning events of the resources of the Helm release and their pods on the
+	// workload Cluster since the install or upgrade started, e.g. CrashLoopBackOff or FailedScheduling.
+	// +optional
+	Events []ReleaseEvent `json:"events,omitempty"`
+}
+
+// ReleaseEvent describes a warning event of a resource of a Helm release, or of one of its pods, on the workload Cluster.
+type ReleaseEvent struct {
+	// Kind is the kind of the object the event is about.
+	Kind string `json:"kind"`
+
+	// Namespace is the namespace of the object the event is about.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name is the name of the object the event is about.
+	Name string `json:"name"`
+
+	// Reason is the reason of the event, e.g. BackOff or FailedScheduling.
+	Reason string `json:"reason"`
+
+	// Message is the message of the event.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Count is the number of times the event occurred.
+	// +optional
+	Count int32 `json:"count,omitempty"`
+
+	// LastTimestamp is the time the event last occurred.
+	// +optional
+	LastTimestamp *metav1.Time `json:"lastTimestamp,omitempty"`
+}
+
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".spec.clusterRef.name",description="Cluster to which this HelmReleaseProxy belongs"
@@ -148,6 +580,16 @@ func (r *HelmReleaseProxy) SetConditions(conditions clusterv1.Conditions) {
 	r.Status.Conditions = conditions
 }
 
+// GetLastSuccessfulReconcileTime returns the time an HelmReleaseProxy object was last reconciled without error.
+func (r *HelmReleaseProxy) GetLastSuccessfulReconcileTime() *metav1.Time {
+	return r.Status.LastSuccessfulReconcileTime
+}
+
+// SetLastSuccessfulReconcileTime will set the time an HelmReleaseProxy object was last reconciled without error.
+func (r *HelmReleaseProxy) SetLastSuccessfulReconcileTime(time *metav1.Time) {
+	r.Status.LastSuccessfulReconcileTime = time
+}
+
 // SetReleaseStatus will set the given status on an HelmReleaseProxy object.
 func (r *HelmReleaseProxy) SetReleaseStatus(status string) {
 	r.Status.Status = status // See pkg/release/status.go in Helm for possible values
@@ -0,0 +1,99 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// HelmRepositorySpec defines the desired state of HelmRepository. The namespaces of its Secret references default to the
+// namespace of the HelmRepository.
+type HelmRepositorySpec struct {
+	// URL is the URL of the Helm chart repository, e.g. `https://charts.corp.local` or `oci://registry.corp.local/charts`.
+	// +kubebuilder:validation:Pattern=`^(https?|oci)://`
+	URL string `json:"url"`
+
+	// Credentials is a reference to an object containing the OCI credentials.
+	// +optional
+	Credentials *Credentials `json:"credentials,omitempty"`
+
+	// RepositoryHeaders is a reference to a Secret whose keys and values are sent as extra HTTP headers with every request
+	// to an HTTP chart repository.
+	// +optional
+	RepositoryHeaders *corev1.SecretReference `json:"repositoryHeaders,omitempty"`
+
+	// RepositoryCredentials is a reference to a Secret containing the credentials of an HTTP chart repository, see
+	// HelmChartProxySpec.RepositoryCredentials.
+	// +optional
+	RepositoryCredentials *corev1.SecretReference `json:"repositoryCredentials,omitempty"`
+
+	// TLSConfig contains the TLS configuration used to connect to the repository.
+	// +optional
+	TLSConfig *TLSConfig `json:"tlsConfig,omitempty"`
+
+	// ProxyURL is the URL of the HTTP proxy used to connect to the repository, e.g. `http://proxy.corp.local:3128`.
+	// +kubebuilder:validation:Pattern=`^https?://`
+	// +optional
+	ProxyURL string `json:"proxyURL,omitempty"`
+
+	// PlainHTTP connects to an OCI registry over plain HTTP instead of HTTPS, e.g. for a registry inside the management
+	// cluster. It has no effect for HTTP chart repositories.
+	// +optional
+	PlainHTTP bool `json:"plainHTTP,omitempty"`
+}
+
+// HelmRepositoryReference is a reference to a HelmRepository.
+type HelmRepositoryReference struct {
+	// Name is the name of the HelmRepository.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Namespace is the namespace of the HelmRepository. If it is not specified, the namespace of the referencing object
+	// is used.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=hrepo
+// +kubebuilder:printcolumn:name="URL",type="string",JSONPath=".spec.url"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// HelmRepository is the Schema for the helmrepositories API. It holds the URL, credentials and TLS configuration of a
+// Helm chart repository shared by the HelmChartProxies referencing it, so they do not have to be repeated on every
+// HelmChartProxy. The HelmReleaseProxies resolve the repository whenever they are reconciled, so changes to it are
+// picked up right away.
+type HelmRepository struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec HelmRepositorySpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// HelmRepositoryList contains a list of HelmRepository.
+type HelmRepositoryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HelmRepository `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HelmRepository{}, &HelmRepositoryList{})
+}
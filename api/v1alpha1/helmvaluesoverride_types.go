@@ -0,0 +1,73 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// HelmValuesOverrideSpec defines the desired state of HelmValuesOverride.
+type HelmValuesOverrideSpec struct {
+	// HelmChartProxyName is the name of the HelmChartProxy in the namespace of the HelmValuesOverride whose values are
+	// overridden.
+	// +kubebuilder:validation:MinLength=1
+	HelmChartProxyName string `json:"helmChartProxyName"`
+
+	// ClusterSelector selects the Clusters of the HelmChartProxy whose values are overridden. An empty selector selects
+	// all Clusters of the HelmChartProxy.
+	ClusterSelector metav1.LabelSelector `json:"clusterSelector"`
+
+	// ValuesTemplate is an inline YAML representing the values merged over the values of the HelmChartProxy. It supports
+	// the same Go templating as the ValuesTemplate of the HelmChartProxy. Maps are merged recursively, while any other
+	// value, including lists, replaces the value of the HelmChartProxy.
+	ValuesTemplate string `json:"valuesTemplate"`
+
+	// Priority orders the HelmValuesOverrides selecting the same Cluster. They are merged in ascending order of priority,
+	// and by name for equal priorities, so that the override with the highest priority takes precedence. Defaults to 0.
+	// +optional
+	Priority int32 `json:"priority,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=hvo
+// +kubebuilder:printcolumn:name="HelmChartProxy",type="string",JSONPath=".spec.helmChartProxyName"
+// +kubebuilder:printcolumn:name="Priority",type="integer",JSONPath=".spec.priority"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// HelmValuesOverride is the Schema for the helmvaluesoverrides API. It overrides a subset of the values of a HelmChartProxy
+// for the Clusters it selects, so that the HelmChartProxy does not have to be cloned for a few Clusters with different
+// values. The overrides are merged over the values of the HelmChartProxy, including the values of its environment, before
+// the HelmReleaseProxies are generated.
+type HelmValuesOverride struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec HelmValuesOverrideSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// HelmValuesOverrideList contains a list of HelmValuesOverride.
+type HelmValuesOverrideList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HelmValuesOverride `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HelmValuesOverride{}, &HelmValuesOverrideList{})
+}
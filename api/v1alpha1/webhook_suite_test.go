@@ -104,7 +104,7 @@ var _ = BeforeSuite(func() {
 	})
 	Expect(err).NotTo(HaveOccurred())
 
-	err = (&HelmChartProxy{}).SetupWebhookWithManager(mgr)
+	err = (&HelmChartProxyWebhook{BlastRadiusWarningThreshold: DefaultBlastRadiusWarningThreshold}).SetupWebhookWithManager(mgr)
 	Expect(err).NotTo(HaveOccurred())
 
 	err = (&HelmReleaseProxy{}).SetupWebhookWithManager(mgr)
@@ -21,82 +21,97 @@ limitations under the License.
 package v1alpha1
 
 import (
-	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/cluster-api/api/v1beta1"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Credentials) DeepCopyInto(out *Credentials) {
+func (in *AddonProfile) DeepCopyInto(out *AddonProfile) {
 	*out = *in
-	out.Secret = in.Secret
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Credentials.
-func (in *Credentials) DeepCopy() *Credentials {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AddonProfile.
+func (in *AddonProfile) DeepCopy() *AddonProfile {
 	if in == nil {
 		return nil
 	}
-	out := new(Credentials)
+	out := new(AddonProfile)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AddonProfile) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HelmChartProxy) DeepCopyInto(out *HelmChartProxy) {
+func (in *AddonProfileChart) DeepCopyInto(out *AddonProfileChart) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
+	in.Template.DeepCopyInto(&out.Template)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmChartProxy.
-func (in *HelmChartProxy) DeepCopy() *HelmChartProxy {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AddonProfileChart.
+func (in *AddonProfileChart) DeepCopy() *AddonProfileChart {
 	if in == nil {
 		return nil
 	}
-	out := new(HelmChartProxy)
+	out := new(AddonProfileChart)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *HelmChartProxy) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AddonProfileChartStatus) DeepCopyInto(out *AddonProfileChartStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AddonProfileChartStatus.
+func (in *AddonProfileChartStatus) DeepCopy() *AddonProfileChartStatus {
+	if in == nil {
+		return nil
 	}
-	return nil
+	out := new(AddonProfileChartStatus)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HelmChartProxyList) DeepCopyInto(out *HelmChartProxyList) {
+func (in *AddonProfileList) DeepCopyInto(out *AddonProfileList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]HelmChartProxy, len(*in))
+		*out = make([]AddonProfile, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmChartProxyList.
-func (in *HelmChartProxyList) DeepCopy() *HelmChartProxyList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AddonProfileList.
+func (in *AddonProfileList) DeepCopy() *AddonProfileList {
 	if in == nil {
 		return nil
 	}
-	out := new(HelmChartProxyList)
+	out := new(AddonProfileList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *HelmChartProxyList) DeepCopyObject() runtime.Object {
+func (in *AddonProfileList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -104,39 +119,30 @@ func (in *HelmChartProxyList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HelmChartProxySpec) DeepCopyInto(out *HelmChartProxySpec) {
+func (in *AddonProfileSpec) DeepCopyInto(out *AddonProfileSpec) {
 	*out = *in
 	in.ClusterSelector.DeepCopyInto(&out.ClusterSelector)
-	if in.Rollout != nil {
-		in, out := &in.Rollout, &out.Rollout
-		*out = new(Rollout)
-		(*in).DeepCopyInto(*out)
-	}
-	in.Options.DeepCopyInto(&out.Options)
-	if in.Credentials != nil {
-		in, out := &in.Credentials, &out.Credentials
-		*out = new(Credentials)
-		**out = **in
-	}
-	if in.TLSConfig != nil {
-		in, out := &in.TLSConfig, &out.TLSConfig
-		*out = new(TLSConfig)
-		(*in).DeepCopyInto(*out)
+	if in.Charts != nil {
+		in, out := &in.Charts, &out.Charts
+		*out = make([]AddonProfileChart, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmChartProxySpec.
-func (in *HelmChartProxySpec) DeepCopy() *HelmChartProxySpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AddonProfileSpec.
+func (in *AddonProfileSpec) DeepCopy() *AddonProfileSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(HelmChartProxySpec)
+	out := new(AddonProfileSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HelmChartProxyStatus) DeepCopyInto(out *HelmChartProxyStatus) {
+func (in *AddonProfileStatus) DeepCopyInto(out *AddonProfileStatus) {
 	*out = *in
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
@@ -145,72 +151,55 @@ func (in *HelmChartProxyStatus) DeepCopyInto(out *HelmChartProxyStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
-	if in.MatchingClusters != nil {
-		in, out := &in.MatchingClusters, &out.MatchingClusters
-		*out = make([]corev1.ObjectReference, len(*in))
+	if in.Charts != nil {
+		in, out := &in.Charts, &out.Charts
+		*out = make([]AddonProfileChartStatus, len(*in))
 		copy(*out, *in)
 	}
-	if in.Rollout != nil {
-		in, out := &in.Rollout, &out.Rollout
-		*out = new(RolloutStatus)
-		(*in).DeepCopyInto(*out)
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmChartProxyStatus.
-func (in *HelmChartProxyStatus) DeepCopy() *HelmChartProxyStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AddonProfileStatus.
+func (in *AddonProfileStatus) DeepCopy() *AddonProfileStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(HelmChartProxyStatus)
+	out := new(AddonProfileStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HelmInstallOptions) DeepCopyInto(out *HelmInstallOptions) {
+func (in *AggregatedCondition) DeepCopyInto(out *AggregatedCondition) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmInstallOptions.
-func (in *HelmInstallOptions) DeepCopy() *HelmInstallOptions {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AggregatedCondition.
+func (in *AggregatedCondition) DeepCopy() *AggregatedCondition {
 	if in == nil {
 		return nil
 	}
-	out := new(HelmInstallOptions)
+	out := new(AggregatedCondition)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HelmOptions) DeepCopyInto(out *HelmOptions) {
+func (in *CertManagerReference) DeepCopyInto(out *CertManagerReference) {
 	*out = *in
-	if in.Timeout != nil {
-		in, out := &in.Timeout, &out.Timeout
-		*out = new(v1.Duration)
-		**out = **in
-	}
-	out.Install = in.Install
-	out.Upgrade = in.Upgrade
-	if in.Uninstall != nil {
-		in, out := &in.Uninstall, &out.Uninstall
-		*out = new(HelmUninstallOptions)
-		**out = **in
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmOptions.
-func (in *HelmOptions) DeepCopy() *HelmOptions {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertManagerReference.
+func (in *CertManagerReference) DeepCopy() *CertManagerReference {
 	if in == nil {
 		return nil
 	}
-	out := new(HelmOptions)
+	out := new(CertManagerReference)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HelmReleaseProxy) DeepCopyInto(out *HelmReleaseProxy) {
+func (in *ChartBundle) DeepCopyInto(out *ChartBundle) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
@@ -218,18 +207,18 @@ func (in *HelmReleaseProxy) DeepCopyInto(out *HelmReleaseProxy) {
 	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmReleaseProxy.
-func (in *HelmReleaseProxy) DeepCopy() *HelmReleaseProxy {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChartBundle.
+func (in *ChartBundle) DeepCopy() *ChartBundle {
 	if in == nil {
 		return nil
 	}
-	out := new(HelmReleaseProxy)
+	out := new(ChartBundle)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *HelmReleaseProxy) DeepCopyObject() runtime.Object {
+func (in *ChartBundle) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -237,31 +226,46 @@ func (in *HelmReleaseProxy) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HelmReleaseProxyList) DeepCopyInto(out *HelmReleaseProxyList) {
+func (in *ChartBundleEntry) DeepCopyInto(out *ChartBundleEntry) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChartBundleEntry.
+func (in *ChartBundleEntry) DeepCopy() *ChartBundleEntry {
+	if in == nil {
+		return nil
+	}
+	out := new(ChartBundleEntry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChartBundleList) DeepCopyInto(out *ChartBundleList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]HelmReleaseProxy, len(*in))
+		*out = make([]ChartBundle, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmReleaseProxyList.
-func (in *HelmReleaseProxyList) DeepCopy() *HelmReleaseProxyList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChartBundleList.
+func (in *ChartBundleList) DeepCopy() *ChartBundleList {
 	if in == nil {
 		return nil
 	}
-	out := new(HelmReleaseProxyList)
+	out := new(ChartBundleList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *HelmReleaseProxyList) DeepCopyObject() runtime.Object {
+func (in *ChartBundleList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -269,180 +273,2194 @@ func (in *HelmReleaseProxyList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HelmReleaseProxySpec) DeepCopyInto(out *HelmReleaseProxySpec) {
+func (in *ChartBundleOCISource) DeepCopyInto(out *ChartBundleOCISource) {
 	*out = *in
-	out.ClusterRef = in.ClusterRef
-	in.Options.DeepCopyInto(&out.Options)
 	if in.Credentials != nil {
 		in, out := &in.Credentials, &out.Credentials
 		*out = new(Credentials)
 		**out = **in
 	}
-	if in.TLSConfig != nil {
-		in, out := &in.TLSConfig, &out.TLSConfig
-		*out = new(TLSConfig)
-		(*in).DeepCopyInto(*out)
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmReleaseProxySpec.
-func (in *HelmReleaseProxySpec) DeepCopy() *HelmReleaseProxySpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChartBundleOCISource.
+func (in *ChartBundleOCISource) DeepCopy() *ChartBundleOCISource {
 	if in == nil {
 		return nil
 	}
-	out := new(HelmReleaseProxySpec)
+	out := new(ChartBundleOCISource)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HelmReleaseProxyStatus) DeepCopyInto(out *HelmReleaseProxyStatus) {
+func (in *ChartBundleReference) DeepCopyInto(out *ChartBundleReference) {
 	*out = *in
-	if in.Conditions != nil {
-		in, out := &in.Conditions, &out.Conditions
-		*out = make(v1beta1.Conditions, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChartBundleReference.
+func (in *ChartBundleReference) DeepCopy() *ChartBundleReference {
+	if in == nil {
+		return nil
 	}
+	out := new(ChartBundleReference)
+	in.DeepCopyInto(out)
+	return out
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmReleaseProxyStatus.
-func (in *HelmReleaseProxyStatus) DeepCopy() *HelmReleaseProxyStatus {
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChartBundleSecretSource) DeepCopyInto(out *ChartBundleSecretSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChartBundleSecretSource.
+func (in *ChartBundleSecretSource) DeepCopy() *ChartBundleSecretSource {
 	if in == nil {
 		return nil
 	}
-	out := new(HelmReleaseProxyStatus)
+	out := new(ChartBundleSecretSource)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HelmUninstallOptions) DeepCopyInto(out *HelmUninstallOptions) {
+func (in *ChartBundleSource) DeepCopyInto(out *ChartBundleSource) {
 	*out = *in
+	if in.Secret != nil {
+		in, out := &in.Secret, &out.Secret
+		*out = new(ChartBundleSecretSource)
+		**out = **in
+	}
+	if in.OCI != nil {
+		in, out := &in.OCI, &out.OCI
+		*out = new(ChartBundleOCISource)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmUninstallOptions.
-func (in *HelmUninstallOptions) DeepCopy() *HelmUninstallOptions {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChartBundleSource.
+func (in *ChartBundleSource) DeepCopy() *ChartBundleSource {
 	if in == nil {
 		return nil
 	}
-	out := new(HelmUninstallOptions)
+	out := new(ChartBundleSource)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HelmUpgradeOptions) DeepCopyInto(out *HelmUpgradeOptions) {
+func (in *ChartBundleSpec) DeepCopyInto(out *ChartBundleSpec) {
 	*out = *in
+	in.Source.DeepCopyInto(&out.Source)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmUpgradeOptions.
-func (in *HelmUpgradeOptions) DeepCopy() *HelmUpgradeOptions {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChartBundleSpec.
+func (in *ChartBundleSpec) DeepCopy() *ChartBundleSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(HelmUpgradeOptions)
+	out := new(ChartBundleSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Rollout) DeepCopyInto(out *Rollout) {
+func (in *ChartBundleStatus) DeepCopyInto(out *ChartBundleStatus) {
 	*out = *in
-	if in.Install != nil {
-		in, out := &in.Install, &out.Install
-		*out = new(RolloutOptions)
-		(*in).DeepCopyInto(*out)
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make(v1beta1.Conditions, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
-	if in.Upgrade != nil {
-		in, out := &in.Upgrade, &out.Upgrade
-		*out = new(RolloutOptions)
-		(*in).DeepCopyInto(*out)
+	if in.Charts != nil {
+		in, out := &in.Charts, &out.Charts
+		*out = make([]ChartBundleEntry, len(*in))
+		copy(*out, *in)
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Rollout.
-func (in *Rollout) DeepCopy() *Rollout {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChartBundleStatus.
+func (in *ChartBundleStatus) DeepCopy() *ChartBundleStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(Rollout)
+	out := new(ChartBundleStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RolloutOptions) DeepCopyInto(out *RolloutOptions) {
+func (in *ChartSourceDefaults) DeepCopyInto(out *ChartSourceDefaults) {
 	*out = *in
-	if in.StepInit != nil {
-		in, out := &in.StepInit, &out.StepInit
-		*out = new(intstr.IntOrString)
-		**out = **in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChartSourceDefaults.
+func (in *ChartSourceDefaults) DeepCopy() *ChartSourceDefaults {
+	if in == nil {
+		return nil
 	}
-	if in.StepIncrement != nil {
-		in, out := &in.StepIncrement, &out.StepIncrement
-		*out = new(intstr.IntOrString)
-		**out = **in
+	out := new(ChartSourceDefaults)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ChartSourceDefaults) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
 	}
-	if in.StepLimit != nil {
-		in, out := &in.StepLimit, &out.StepLimit
-		*out = new(intstr.IntOrString)
-		**out = **in
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChartSourceDefaultsList) DeepCopyInto(out *ChartSourceDefaultsList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ChartSourceDefaults, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutOptions.
-func (in *RolloutOptions) DeepCopy() *RolloutOptions {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChartSourceDefaultsList.
+func (in *ChartSourceDefaultsList) DeepCopy() *ChartSourceDefaultsList {
 	if in == nil {
 		return nil
 	}
-	out := new(RolloutOptions)
+	out := new(ChartSourceDefaultsList)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ChartSourceDefaultsList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RolloutStatus) DeepCopyInto(out *RolloutStatus) {
+func (in *ChartSourceDefaultsSpec) DeepCopyInto(out *ChartSourceDefaultsSpec) {
 	*out = *in
-	if in.Count != nil {
-		in, out := &in.Count, &out.Count
-		*out = new(int)
+	if in.Credentials != nil {
+		in, out := &in.Credentials, &out.Credentials
+		*out = new(Credentials)
 		**out = **in
 	}
-	if in.StepSize != nil {
-		in, out := &in.StepSize, &out.StepSize
-		*out = new(int)
+	if in.RepositoryHeaders != nil {
+		in, out := &in.RepositoryHeaders, &out.RepositoryHeaders
+		*out = new(v1.SecretReference)
+		**out = **in
+	}
+	if in.RepositoryCredentials != nil {
+		in, out := &in.RepositoryCredentials, &out.RepositoryCredentials
+		*out = new(v1.SecretReference)
 		**out = **in
 	}
+	if in.TLSConfig != nil {
+		in, out := &in.TLSConfig, &out.TLSConfig
+		*out = new(TLSConfig)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutStatus.
-func (in *RolloutStatus) DeepCopy() *RolloutStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChartSourceDefaultsSpec.
+func (in *ChartSourceDefaultsSpec) DeepCopy() *ChartSourceDefaultsSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(RolloutStatus)
+	out := new(ChartSourceDefaultsSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *TLSConfig) DeepCopyInto(out *TLSConfig) {
+func (in *ChartVerification) DeepCopyInto(out *ChartVerification) {
 	*out = *in
-	if in.CASecretRef != nil {
-		in, out := &in.CASecretRef, &out.CASecretRef
-		*out = new(corev1.SecretReference)
+	if in.Provenance != nil {
+		in, out := &in.Provenance, &out.Provenance
+		*out = new(ProvenanceVerification)
 		**out = **in
 	}
+	if in.Cosign != nil {
+		in, out := &in.Cosign, &out.Cosign
+		*out = new(CosignVerification)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSConfig.
-func (in *TLSConfig) DeepCopy() *TLSConfig {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChartVerification.
+func (in *ChartVerification) DeepCopy() *ChartVerification {
 	if in == nil {
 		return nil
 	}
-	out := new(TLSConfig)
+	out := new(ChartVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChartVersions) DeepCopyInto(out *ChartVersions) {
+	*out = *in
+	if in.Upgrades != nil {
+		in, out := &in.Upgrades, &out.Upgrades
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChartVersions.
+func (in *ChartVersions) DeepCopy() *ChartVersions {
+	if in == nil {
+		return nil
+	}
+	out := new(ChartVersions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterOperations) DeepCopyInto(out *ClusterOperations) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterOperations.
+func (in *ClusterOperations) DeepCopy() *ClusterOperations {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterOperations)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterReadinessOptions) DeepCopyInto(out *ClusterReadinessOptions) {
+	*out = *in
+	if in.ReadyWorkerNodes != nil {
+		in, out := &in.ReadyWorkerNodes, &out.ReadyWorkerNodes
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MachineDeployment != nil {
+		in, out := &in.MachineDeployment, &out.MachineDeployment
+		*out = new(MachineDeploymentReadiness)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MachinePool != nil {
+		in, out := &in.MachinePool, &out.MachinePool
+		*out = new(MachinePoolReadiness)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterReadinessOptions.
+func (in *ClusterReadinessOptions) DeepCopy() *ClusterReadinessOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterReadinessOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterResourceSetSignature) DeepCopyInto(out *ClusterResourceSetSignature) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterResourceSetSignature.
+func (in *ClusterResourceSetSignature) DeepCopy() *ClusterResourceSetSignature {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterResourceSetSignature)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConditionAggregation) DeepCopyInto(out *ConditionAggregation) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]AggregatedCondition, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConditionAggregation.
+func (in *ConditionAggregation) DeepCopy() *ConditionAggregation {
+	if in == nil {
+		return nil
+	}
+	out := new(ConditionAggregation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CosignKeylessVerification) DeepCopyInto(out *CosignKeylessVerification) {
+	*out = *in
+	out.TrustedRootSecretRef = in.TrustedRootSecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CosignKeylessVerification.
+func (in *CosignKeylessVerification) DeepCopy() *CosignKeylessVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(CosignKeylessVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CosignVerification) DeepCopyInto(out *CosignVerification) {
+	*out = *in
+	if in.PublicKeySecretRef != nil {
+		in, out := &in.PublicKeySecretRef, &out.PublicKeySecretRef
+		*out = new(v1.SecretReference)
+		**out = **in
+	}
+	if in.Keyless != nil {
+		in, out := &in.Keyless, &out.Keyless
+		*out = new(CosignKeylessVerification)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CosignVerification.
+func (in *CosignVerification) DeepCopy() *CosignVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(CosignVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Credentials) DeepCopyInto(out *Credentials) {
+	*out = *in
+	out.Secret = in.Secret
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Credentials.
+func (in *Credentials) DeepCopy() *Credentials {
+	if in == nil {
+		return nil
+	}
+	out := new(Credentials)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeployedChart) DeepCopyInto(out *DeployedChart) {
+	*out = *in
+	in.DeployedTime.DeepCopyInto(&out.DeployedTime)
+	if in.RetiredTime != nil {
+		in, out := &in.RetiredTime, &out.RetiredTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeployedChart.
+func (in *DeployedChart) DeepCopy() *DeployedChart {
+	if in == nil {
+		return nil
+	}
+	out := new(DeployedChart)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiscoveredRelease) DeepCopyInto(out *DiscoveredRelease) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiscoveredRelease.
+func (in *DiscoveredRelease) DeepCopy() *DiscoveredRelease {
+	if in == nil {
+		return nil
+	}
+	out := new(DiscoveredRelease)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriftDetection) DeepCopyInto(out *DriftDetection) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.IgnoreRules != nil {
+		in, out := &in.IgnoreRules, &out.IgnoreRules
+		*out = make([]DriftIgnoreRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriftDetection.
+func (in *DriftDetection) DeepCopy() *DriftDetection {
+	if in == nil {
+		return nil
+	}
+	out := new(DriftDetection)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriftIgnoreRule) DeepCopyInto(out *DriftIgnoreRule) {
+	*out = *in
+	if in.Paths != nil {
+		in, out := &in.Paths, &out.Paths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriftIgnoreRule.
+func (in *DriftIgnoreRule) DeepCopy() *DriftIgnoreRule {
+	if in == nil {
+		return nil
+	}
+	out := new(DriftIgnoreRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Environment) DeepCopyInto(out *Environment) {
+	*out = *in
+	in.ClusterSelector.DeepCopyInto(&out.ClusterSelector)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Environment.
+func (in *Environment) DeepCopy() *Environment {
+	if in == nil {
+		return nil
+	}
+	out := new(Environment)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EnvironmentStatus) DeepCopyInto(out *EnvironmentStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnvironmentStatus.
+func (in *EnvironmentStatus) DeepCopy() *EnvironmentStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(EnvironmentStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FailoverOptions) DeepCopyInto(out *FailoverOptions) {
+	*out = *in
+	if in.LeaseDuration != nil {
+		in, out := &in.LeaseDuration, &out.LeaseDuration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FailoverOptions.
+func (in *FailoverOptions) DeepCopy() *FailoverOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(FailoverOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitChartSource) DeepCopyInto(out *GitChartSource) {
+	*out = *in
+	if in.AuthSecretRef != nil {
+		in, out := &in.AuthSecretRef, &out.AuthSecretRef
+		*out = new(v1.SecretReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitChartSource.
+func (in *GitChartSource) DeepCopy() *GitChartSource {
+	if in == nil {
+		return nil
+	}
+	out := new(GitChartSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmChartProxy) DeepCopyInto(out *HelmChartProxy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmChartProxy.
+func (in *HelmChartProxy) DeepCopy() *HelmChartProxy {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmChartProxy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HelmChartProxy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmChartProxyList) DeepCopyInto(out *HelmChartProxyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HelmChartProxy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmChartProxyList.
+func (in *HelmChartProxyList) DeepCopy() *HelmChartProxyList {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmChartProxyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HelmChartProxyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmChartProxyRevision) DeepCopyInto(out *HelmChartProxyRevision) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmChartProxyRevision.
+func (in *HelmChartProxyRevision) DeepCopy() *HelmChartProxyRevision {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmChartProxyRevision)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HelmChartProxyRevision) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmChartProxyRevisionList) DeepCopyInto(out *HelmChartProxyRevisionList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HelmChartProxyRevision, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmChartProxyRevisionList.
+func (in *HelmChartProxyRevisionList) DeepCopy() *HelmChartProxyRevisionList {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmChartProxyRevisionList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HelmChartProxyRevisionList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmChartProxyRevisionSpec) DeepCopyInto(out *HelmChartProxyRevisionSpec) {
+	*out = *in
+	in.Data.DeepCopyInto(&out.Data)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmChartProxyRevisionSpec.
+func (in *HelmChartProxyRevisionSpec) DeepCopy() *HelmChartProxyRevisionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmChartProxyRevisionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmChartProxySpec) DeepCopyInto(out *HelmChartProxySpec) {
+	*out = *in
+	in.ClusterSelector.DeepCopyInto(&out.ClusterSelector)
+	if in.PropagateClusterLabels != nil {
+		in, out := &in.PropagateClusterLabels, &out.PropagateClusterLabels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RepositoryRef != nil {
+		in, out := &in.RepositoryRef, &out.RepositoryRef
+		*out = new(HelmRepositoryReference)
+		**out = **in
+	}
+	if in.ChartBundleRef != nil {
+		in, out := &in.ChartBundleRef, &out.ChartBundleRef
+		*out = new(ChartBundleReference)
+		**out = **in
+	}
+	if in.Git != nil {
+		in, out := &in.Git, &out.Git
+		*out = new(GitChartSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ValuesFrom != nil {
+		in, out := &in.ValuesFrom, &out.ValuesFrom
+		*out = make([]ValuesFromSource, len(*in))
+		copy(*out, *in)
+	}
+	if in.ValuesTemplateOptions != nil {
+		in, out := &in.ValuesTemplateOptions, &out.ValuesTemplateOptions
+		*out = new(ValuesTemplateOptions)
+		**out = **in
+	}
+	if in.ProxyValues != nil {
+		in, out := &in.ProxyValues, &out.ProxyValues
+		*out = new(ProxyValues)
+		**out = **in
+	}
+	if in.ValuesByReference != nil {
+		in, out := &in.ValuesByReference, &out.ValuesByReference
+		*out = new(ValuesByReference)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Environments != nil {
+		in, out := &in.Environments, &out.Environments
+		*out = make([]Environment, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ClusterResourceSetSignatures != nil {
+		in, out := &in.ClusterResourceSetSignatures, &out.ClusterResourceSetSignatures
+		*out = make([]ClusterResourceSetSignature, len(*in))
+		copy(*out, *in)
+	}
+	if in.UninstallConfirmationThreshold != nil {
+		in, out := &in.UninstallConfirmationThreshold, &out.UninstallConfirmationThreshold
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ReadinessThreshold != nil {
+		in, out := &in.ReadinessThreshold, &out.ReadinessThreshold
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.ConditionAggregation != nil {
+		in, out := &in.ConditionAggregation, &out.ConditionAggregation
+		*out = new(ConditionAggregation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(Rollout)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Options.DeepCopyInto(&out.Options)
+	if in.Credentials != nil {
+		in, out := &in.Credentials, &out.Credentials
+		*out = new(Credentials)
+		**out = **in
+	}
+	if in.RepositoryHeaders != nil {
+		in, out := &in.RepositoryHeaders, &out.RepositoryHeaders
+		*out = new(v1.SecretReference)
+		**out = **in
+	}
+	if in.RepositoryCredentials != nil {
+		in, out := &in.RepositoryCredentials, &out.RepositoryCredentials
+		*out = new(v1.SecretReference)
+		**out = **in
+	}
+	if in.TLSConfig != nil {
+		in, out := &in.TLSConfig, &out.TLSConfig
+		*out = new(TLSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Verify != nil {
+		in, out := &in.Verify, &out.Verify
+		*out = new(ChartVerification)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PostRenderer != nil {
+		in, out := &in.PostRenderer, &out.PostRenderer
+		*out = new(PostRenderer)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Metrics != nil {
+		in, out := &in.Metrics, &out.Metrics
+		*out = new(MetricsOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ClusterReadiness != nil {
+		in, out := &in.ClusterReadiness, &out.ClusterReadiness
+		*out = new(ClusterReadinessOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ResyncPeriod != nil {
+		in, out := &in.ResyncPeriod, &out.ResyncPeriod
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.ReconcileInterval != nil {
+		in, out := &in.ReconcileInterval, &out.ReconcileInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Failover != nil {
+		in, out := &in.Failover, &out.Failover
+		*out = new(FailoverOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DriftIgnoreRules != nil {
+		in, out := &in.DriftIgnoreRules, &out.DriftIgnoreRules
+		*out = make([]DriftIgnoreRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DriftDetection != nil {
+		in, out := &in.DriftDetection, &out.DriftDetection
+		*out = new(DriftDetection)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Impersonation != nil {
+		in, out := &in.Impersonation, &out.Impersonation
+		*out = new(Impersonation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RevisionHistoryLimit != nil {
+		in, out := &in.RevisionHistoryLimit, &out.RevisionHistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmChartProxySpec.
+func (in *HelmChartProxySpec) DeepCopy() *HelmChartProxySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmChartProxySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmChartProxyStatus) DeepCopyInto(out *HelmChartProxyStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make(v1beta1.Conditions, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.MatchingClusters != nil {
+		in, out := &in.MatchingClusters, &out.MatchingClusters
+		*out = make([]v1.ObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(RolloutStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.UninstallRollout != nil {
+		in, out := &in.UninstallRollout, &out.UninstallRollout
+		*out = new(RolloutStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RolloutHooks != nil {
+		in, out := &in.RolloutHooks, &out.RolloutHooks
+		*out = make([]RolloutHookStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PendingUninstalls != nil {
+		in, out := &in.PendingUninstalls, &out.PendingUninstalls
+		*out = make([]v1.ObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.OutOfDateReleases != nil {
+		in, out := &in.OutOfDateReleases, &out.OutOfDateReleases
+		*out = make([]v1.ObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.DiscoveredReleases != nil {
+		in, out := &in.DiscoveredReleases, &out.DiscoveredReleases
+		*out = make([]DiscoveredRelease, len(*in))
+		copy(*out, *in)
+	}
+	if in.ClusterResourceSetClusters != nil {
+		in, out := &in.ClusterResourceSetClusters, &out.ClusterResourceSetClusters
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DeployedCharts != nil {
+		in, out := &in.DeployedCharts, &out.DeployedCharts
+		*out = make([]DeployedChart, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Environments != nil {
+		in, out := &in.Environments, &out.Environments
+		*out = make([]EnvironmentStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.ChartVersions != nil {
+		in, out := &in.ChartVersions, &out.ChartVersions
+		*out = new(ChartVersions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ClusterOperations != nil {
+		in, out := &in.ClusterOperations, &out.ClusterOperations
+		*out = make([]ClusterOperations, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastSuccessfulReconcileTime != nil {
+		in, out := &in.LastSuccessfulReconcileTime, &out.LastSuccessfulReconcileTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmChartProxyStatus.
+func (in *HelmChartProxyStatus) DeepCopy() *HelmChartProxyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmChartProxyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmInstallOptions) DeepCopyInto(out *HelmInstallOptions) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmInstallOptions.
+func (in *HelmInstallOptions) DeepCopy() *HelmInstallOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmInstallOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmOptions) DeepCopyInto(out *HelmOptions) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.FetchTimeout != nil {
+		in, out := &in.FetchTimeout, &out.FetchTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	out.Install = in.Install
+	out.Upgrade = in.Upgrade
+	if in.Uninstall != nil {
+		in, out := &in.Uninstall, &out.Uninstall
+		*out = new(HelmUninstallOptions)
+		**out = **in
+	}
+	if in.Test != nil {
+		in, out := &in.Test, &out.Test
+		*out = new(HelmTestOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PendingRecovery != nil {
+		in, out := &in.PendingRecovery, &out.PendingRecovery
+		*out = new(HelmPendingRecoveryOptions)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmOptions.
+func (in *HelmOptions) DeepCopy() *HelmOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmPendingRecoveryOptions) DeepCopyInto(out *HelmPendingRecoveryOptions) {
+	*out = *in
+	if in.StuckAfter != nil {
+		in, out := &in.StuckAfter, &out.StuckAfter
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmPendingRecoveryOptions.
+func (in *HelmPendingRecoveryOptions) DeepCopy() *HelmPendingRecoveryOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmPendingRecoveryOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmReleaseProxy) DeepCopyInto(out *HelmReleaseProxy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmReleaseProxy.
+func (in *HelmReleaseProxy) DeepCopy() *HelmReleaseProxy {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmReleaseProxy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HelmReleaseProxy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmReleaseProxyList) DeepCopyInto(out *HelmReleaseProxyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HelmReleaseProxy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmReleaseProxyList.
+func (in *HelmReleaseProxyList) DeepCopy() *HelmReleaseProxyList {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmReleaseProxyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HelmReleaseProxyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmReleaseProxySpec) DeepCopyInto(out *HelmReleaseProxySpec) {
+	*out = *in
+	out.ClusterRef = in.ClusterRef
+	if in.ChartBundleRef != nil {
+		in, out := &in.ChartBundleRef, &out.ChartBundleRef
+		*out = new(ChartBundleReference)
+		**out = **in
+	}
+	if in.RepositoryRef != nil {
+		in, out := &in.RepositoryRef, &out.RepositoryRef
+		*out = new(HelmRepositoryReference)
+		**out = **in
+	}
+	if in.Git != nil {
+		in, out := &in.Git, &out.Git
+		*out = new(GitChartSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ReleaseLabels != nil {
+		in, out := &in.ReleaseLabels, &out.ReleaseLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ValuesRefs != nil {
+		in, out := &in.ValuesRefs, &out.ValuesRefs
+		*out = make([]ValuesReference, len(*in))
+		copy(*out, *in)
+	}
+	in.Options.DeepCopyInto(&out.Options)
+	if in.Credentials != nil {
+		in, out := &in.Credentials, &out.Credentials
+		*out = new(Credentials)
+		**out = **in
+	}
+	if in.RepositoryHeaders != nil {
+		in, out := &in.RepositoryHeaders, &out.RepositoryHeaders
+		*out = new(v1.SecretReference)
+		**out = **in
+	}
+	if in.RepositoryCredentials != nil {
+		in, out := &in.RepositoryCredentials, &out.RepositoryCredentials
+		*out = new(v1.SecretReference)
+		**out = **in
+	}
+	if in.ClusterReadiness != nil {
+		in, out := &in.ClusterReadiness, &out.ClusterReadiness
+		*out = new(ClusterReadinessOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ResyncPeriod != nil {
+		in, out := &in.ResyncPeriod, &out.ResyncPeriod
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Failover != nil {
+		in, out := &in.Failover, &out.Failover
+		*out = new(FailoverOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DriftIgnoreRules != nil {
+		in, out := &in.DriftIgnoreRules, &out.DriftIgnoreRules
+		*out = make([]DriftIgnoreRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DriftDetection != nil {
+		in, out := &in.DriftDetection, &out.DriftDetection
+		*out = new(DriftDetection)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Impersonation != nil {
+		in, out := &in.Impersonation, &out.Impersonation
+		*out = new(Impersonation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TLSConfig != nil {
+		in, out := &in.TLSConfig, &out.TLSConfig
+		*out = new(TLSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Verify != nil {
+		in, out := &in.Verify, &out.Verify
+		*out = new(ChartVerification)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PostRenderer != nil {
+		in, out := &in.PostRenderer, &out.PostRenderer
+		*out = new(PostRenderer)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RollbackTo != nil {
+		in, out := &in.RollbackTo, &out.RollbackTo
+		*out = new(RollbackTo)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmReleaseProxySpec.
+func (in *HelmReleaseProxySpec) DeepCopy() *HelmReleaseProxySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmReleaseProxySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmReleaseProxyStatus) DeepCopyInto(out *HelmReleaseProxyStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make(v1beta1.Conditions, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Progress != nil {
+		in, out := &in.Progress, &out.Progress
+		*out = new(ReleaseProgress)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SBOMs != nil {
+		in, out := &in.SBOMs, &out.SBOMs
+		*out = make([]SBOMReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.Rollback != nil {
+		in, out := &in.Rollback, &out.Rollback
+		*out = new(RollbackStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.UpgradeRollback != nil {
+		in, out := &in.UpgradeRollback, &out.UpgradeRollback
+		*out = new(UpgradeRollbackStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Test != nil {
+		in, out := &in.Test, &out.Test
+		*out = new(ReleaseTestStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ManifestInventory != nil {
+		in, out := &in.ManifestInventory, &out.ManifestInventory
+		*out = make([]v1.ObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastDriftCheckTime != nil {
+		in, out := &in.LastDriftCheckTime, &out.LastDriftCheckTime
+		*out = (*in).DeepCopy()
+	}
+	if in.DriftedResources != nil {
+		in, out := &in.DriftedResources, &out.DriftedResources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastSuccessfulReconcileTime != nil {
+		in, out := &in.LastSuccessfulReconcileTime, &out.LastSuccessfulReconcileTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmReleaseProxyStatus.
+func (in *HelmReleaseProxyStatus) DeepCopy() *HelmReleaseProxyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmReleaseProxyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmRepository) DeepCopyInto(out *HelmRepository) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmRepository.
+func (in *HelmRepository) DeepCopy() *HelmRepository {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmRepository)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HelmRepository) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmRepositoryList) DeepCopyInto(out *HelmRepositoryList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HelmRepository, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmRepositoryList.
+func (in *HelmRepositoryList) DeepCopy() *HelmRepositoryList {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmRepositoryList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HelmRepositoryList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmRepositoryReference) DeepCopyInto(out *HelmRepositoryReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmRepositoryReference.
+func (in *HelmRepositoryReference) DeepCopy() *HelmRepositoryReference {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmRepositoryReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmRepositorySpec) DeepCopyInto(out *HelmRepositorySpec) {
+	*out = *in
+	if in.Credentials != nil {
+		in, out := &in.Credentials, &out.Credentials
+		*out = new(Credentials)
+		**out = **in
+	}
+	if in.RepositoryHeaders != nil {
+		in, out := &in.RepositoryHeaders, &out.RepositoryHeaders
+		*out = new(v1.SecretReference)
+		**out = **in
+	}
+	if in.RepositoryCredentials != nil {
+		in, out := &in.RepositoryCredentials, &out.RepositoryCredentials
+		*out = new(v1.SecretReference)
+		**out = **in
+	}
+	if in.TLSConfig != nil {
+		in, out := &in.TLSConfig, &out.TLSConfig
+		*out = new(TLSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmRepositorySpec.
+func (in *HelmRepositorySpec) DeepCopy() *HelmRepositorySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmRepositorySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmTestOptions) DeepCopyInto(out *HelmTestOptions) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Filters != nil {
+		in, out := &in.Filters, &out.Filters
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmTestOptions.
+func (in *HelmTestOptions) DeepCopy() *HelmTestOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmTestOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmUninstallOptions) DeepCopyInto(out *HelmUninstallOptions) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmUninstallOptions.
+func (in *HelmUninstallOptions) DeepCopy() *HelmUninstallOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmUninstallOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmUpgradeOptions) DeepCopyInto(out *HelmUpgradeOptions) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmUpgradeOptions.
+func (in *HelmUpgradeOptions) DeepCopy() *HelmUpgradeOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmUpgradeOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmValuesOverride) DeepCopyInto(out *HelmValuesOverride) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmValuesOverride.
+func (in *HelmValuesOverride) DeepCopy() *HelmValuesOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmValuesOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HelmValuesOverride) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmValuesOverrideList) DeepCopyInto(out *HelmValuesOverrideList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HelmValuesOverride, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmValuesOverrideList.
+func (in *HelmValuesOverrideList) DeepCopy() *HelmValuesOverrideList {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmValuesOverrideList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HelmValuesOverrideList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmValuesOverrideSpec) DeepCopyInto(out *HelmValuesOverrideSpec) {
+	*out = *in
+	in.ClusterSelector.DeepCopyInto(&out.ClusterSelector)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmValuesOverrideSpec.
+func (in *HelmValuesOverrideSpec) DeepCopy() *HelmValuesOverrideSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmValuesOverrideSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Impersonation) DeepCopyInto(out *Impersonation) {
+	*out = *in
+	if in.Groups != nil {
+		in, out := &in.Groups, &out.Groups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Impersonation.
+func (in *Impersonation) DeepCopy() *Impersonation {
+	if in == nil {
+		return nil
+	}
+	out := new(Impersonation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KustomizePatch) DeepCopyInto(out *KustomizePatch) {
+	*out = *in
+	if in.Target != nil {
+		in, out := &in.Target, &out.Target
+		*out = new(PatchSelector)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KustomizePatch.
+func (in *KustomizePatch) DeepCopy() *KustomizePatch {
+	if in == nil {
+		return nil
+	}
+	out := new(KustomizePatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MachineDeploymentReadiness) DeepCopyInto(out *MachineDeploymentReadiness) {
+	*out = *in
+	if in.MinReadyReplicas != nil {
+		in, out := &in.MinReadyReplicas, &out.MinReadyReplicas
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MachineDeploymentReadiness.
+func (in *MachineDeploymentReadiness) DeepCopy() *MachineDeploymentReadiness {
+	if in == nil {
+		return nil
+	}
+	out := new(MachineDeploymentReadiness)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MachinePoolReadiness) DeepCopyInto(out *MachinePoolReadiness) {
+	*out = *in
+	if in.MinReadyReplicas != nil {
+		in, out := &in.MinReadyReplicas, &out.MinReadyReplicas
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MachinePoolReadiness.
+func (in *MachinePoolReadiness) DeepCopy() *MachinePoolReadiness {
+	if in == nil {
+		return nil
+	}
+	out := new(MachinePoolReadiness)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetricsOptions) DeepCopyInto(out *MetricsOptions) {
+	*out = *in
+	if in.ClusterLabelThreshold != nil {
+		in, out := &in.ClusterLabelThreshold, &out.ClusterLabelThreshold
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricsOptions.
+func (in *MetricsOptions) DeepCopy() *MetricsOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(MetricsOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PatchSelector) DeepCopyInto(out *PatchSelector) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PatchSelector.
+func (in *PatchSelector) DeepCopy() *PatchSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(PatchSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostRenderer) DeepCopyInto(out *PostRenderer) {
+	*out = *in
+	if in.Patches != nil {
+		in, out := &in.Patches, &out.Patches
+		*out = make([]KustomizePatch, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostRenderer.
+func (in *PostRenderer) DeepCopy() *PostRenderer {
+	if in == nil {
+		return nil
+	}
+	out := new(PostRenderer)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvenanceVerification) DeepCopyInto(out *ProvenanceVerification) {
+	*out = *in
+	out.KeyringSecretRef = in.KeyringSecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvenanceVerification.
+func (in *ProvenanceVerification) DeepCopy() *ProvenanceVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvenanceVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxyValues) DeepCopyInto(out *ProxyValues) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxyValues.
+func (in *ProxyValues) DeepCopy() *ProxyValues {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxyValues)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReleaseEvent) DeepCopyInto(out *ReleaseEvent) {
+	*out = *in
+	if in.LastTimestamp != nil {
+		in, out := &in.LastTimestamp, &out.LastTimestamp
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReleaseEvent.
+func (in *ReleaseEvent) DeepCopy() *ReleaseEvent {
+	if in == nil {
+		return nil
+	}
+	out := new(ReleaseEvent)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReleaseProgress) DeepCopyInto(out *ReleaseProgress) {
+	*out = *in
+	if in.LastUpdated != nil {
+		in, out := &in.LastUpdated, &out.LastUpdated
+		*out = (*in).DeepCopy()
+	}
+	if in.Events != nil {
+		in, out := &in.Events, &out.Events
+		*out = make([]ReleaseEvent, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReleaseProgress.
+func (in *ReleaseProgress) DeepCopy() *ReleaseProgress {
+	if in == nil {
+		return nil
+	}
+	out := new(ReleaseProgress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReleaseTestResult) DeepCopyInto(out *ReleaseTestResult) {
+	*out = *in
+	if in.StartedAt != nil {
+		in, out := &in.StartedAt, &out.StartedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletedAt != nil {
+		in, out := &in.CompletedAt, &out.CompletedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReleaseTestResult.
+func (in *ReleaseTestResult) DeepCopy() *ReleaseTestResult {
+	if in == nil {
+		return nil
+	}
+	out := new(ReleaseTestResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReleaseTestStatus) DeepCopyInto(out *ReleaseTestStatus) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	if in.Results != nil {
+		in, out := &in.Results, &out.Results
+		*out = make([]ReleaseTestResult, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReleaseTestStatus.
+func (in *ReleaseTestStatus) DeepCopy() *ReleaseTestStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReleaseTestStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RollbackStatus) DeepCopyInto(out *RollbackStatus) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RollbackStatus.
+func (in *RollbackStatus) DeepCopy() *RollbackStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RollbackStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RollbackTo) DeepCopyInto(out *RollbackTo) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RollbackTo.
+func (in *RollbackTo) DeepCopy() *RollbackTo {
+	if in == nil {
+		return nil
+	}
+	out := new(RollbackTo)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Rollout) DeepCopyInto(out *Rollout) {
+	*out = *in
+	if in.Install != nil {
+		in, out := &in.Install, &out.Install
+		*out = new(RolloutOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Upgrade != nil {
+		in, out := &in.Upgrade, &out.Upgrade
+		*out = new(RolloutOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Uninstall != nil {
+		in, out := &in.Uninstall, &out.Uninstall
+		*out = new(RolloutOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Verification != nil {
+		in, out := &in.Verification, &out.Verification
+		*out = new(RolloutVerification)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ReleaseCheck != nil {
+		in, out := &in.ReleaseCheck, &out.ReleaseCheck
+		*out = new(RolloutReleaseCheck)
+		**out = **in
+	}
+	if in.Hooks != nil {
+		in, out := &in.Hooks, &out.Hooks
+		*out = new(RolloutHooks)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ProgressDeadline != nil {
+		in, out := &in.ProgressDeadline, &out.ProgressDeadline
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Rollout.
+func (in *Rollout) DeepCopy() *Rollout {
+	if in == nil {
+		return nil
+	}
+	out := new(Rollout)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutHook) DeepCopyInto(out *RolloutHook) {
+	*out = *in
+	if in.Job != nil {
+		in, out := &in.Job, &out.Job
+		*out = new(RolloutHookJob)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutHook.
+func (in *RolloutHook) DeepCopy() *RolloutHook {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutHook)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutHookJob) DeepCopyInto(out *RolloutHookJob) {
+	*out = *in
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]v1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.BackoffLimit != nil {
+		in, out := &in.BackoffLimit, &out.BackoffLimit
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutHookJob.
+func (in *RolloutHookJob) DeepCopy() *RolloutHookJob {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutHookJob)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutHookStatus) DeepCopyInto(out *RolloutHookStatus) {
+	*out = *in
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutHookStatus.
+func (in *RolloutHookStatus) DeepCopy() *RolloutHookStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutHookStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutHooks) DeepCopyInto(out *RolloutHooks) {
+	*out = *in
+	if in.PreRollout != nil {
+		in, out := &in.PreRollout, &out.PreRollout
+		*out = make([]RolloutHook, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PostRollout != nil {
+		in, out := &in.PostRollout, &out.PostRollout
+		*out = make([]RolloutHook, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutHooks.
+func (in *RolloutHooks) DeepCopy() *RolloutHooks {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutHooks)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutOptions) DeepCopyInto(out *RolloutOptions) {
+	*out = *in
+	if in.StepInit != nil {
+		in, out := &in.StepInit, &out.StepInit
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.StepIncrement != nil {
+		in, out := &in.StepIncrement, &out.StepIncrement
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.StepLimit != nil {
+		in, out := &in.StepLimit, &out.StepLimit
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.MaxPerFailureDomain != nil {
+		in, out := &in.MaxPerFailureDomain, &out.MaxPerFailureDomain
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxFailures != nil {
+		in, out := &in.MaxFailures, &out.MaxFailures
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutOptions.
+func (in *RolloutOptions) DeepCopy() *RolloutOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutReleaseCheck) DeepCopyInto(out *RolloutReleaseCheck) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutReleaseCheck.
+func (in *RolloutReleaseCheck) DeepCopy() *RolloutReleaseCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutReleaseCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutStatus) DeepCopyInto(out *RolloutStatus) {
+	*out = *in
+	if in.Count != nil {
+		in, out := &in.Count, &out.Count
+		*out = new(int)
+		**out = **in
+	}
+	if in.StepSize != nil {
+		in, out := &in.StepSize, &out.StepSize
+		*out = new(int)
+		**out = **in
+	}
+	if in.LastProgressTime != nil {
+		in, out := &in.LastProgressTime, &out.LastProgressTime
+		*out = (*in).DeepCopy()
+	}
+	if in.AverageBatchDuration != nil {
+		in, out := &in.AverageBatchDuration, &out.AverageBatchDuration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.EstimatedCompletionTime != nil {
+		in, out := &in.EstimatedCompletionTime, &out.EstimatedCompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastBatch != nil {
+		in, out := &in.LastBatch, &out.LastBatch
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ApprovedCount != nil {
+		in, out := &in.ApprovedCount, &out.ApprovedCount
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutStatus.
+func (in *RolloutStatus) DeepCopy() *RolloutStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutVerification) DeepCopyInto(out *RolloutVerification) {
+	*out = *in
+	if in.Queries != nil {
+		in, out := &in.Queries, &out.Queries
+		*out = make([]VerificationQuery, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutVerification.
+func (in *RolloutVerification) DeepCopy() *RolloutVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SBOMReference) DeepCopyInto(out *SBOMReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SBOMReference.
+func (in *SBOMReference) DeepCopy() *SBOMReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SBOMReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSConfig) DeepCopyInto(out *TLSConfig) {
+	*out = *in
+	if in.CASecretRef != nil {
+		in, out := &in.CASecretRef, &out.CASecretRef
+		*out = new(v1.SecretReference)
+		**out = **in
+	}
+	if in.CertManagerRef != nil {
+		in, out := &in.CertManagerRef, &out.CertManagerRef
+		*out = new(CertManagerReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSConfig.
+func (in *TLSConfig) DeepCopy() *TLSConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeRollbackStatus) DeepCopyInto(out *UpgradeRollbackStatus) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeRollbackStatus.
+func (in *UpgradeRollbackStatus) DeepCopy() *UpgradeRollbackStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeRollbackStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ValuesByReference) DeepCopyInto(out *ValuesByReference) {
+	*out = *in
+	if in.MinSize != nil {
+		in, out := &in.MinSize, &out.MinSize
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ValuesByReference.
+func (in *ValuesByReference) DeepCopy() *ValuesByReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ValuesByReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ValuesFromSource) DeepCopyInto(out *ValuesFromSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ValuesFromSource.
+func (in *ValuesFromSource) DeepCopy() *ValuesFromSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ValuesFromSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ValuesReference) DeepCopyInto(out *ValuesReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ValuesReference.
+func (in *ValuesReference) DeepCopy() *ValuesReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ValuesReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ValuesTemplateOptions) DeepCopyInto(out *ValuesTemplateOptions) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ValuesTemplateOptions.
+func (in *ValuesTemplateOptions) DeepCopy() *ValuesTemplateOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(ValuesTemplateOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VerificationQuery) DeepCopyInto(out *VerificationQuery) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VerificationQuery.
+func (in *VerificationQuery) DeepCopy() *VerificationQuery {
+	if in == nil {
+		return nil
+	}
+	out := new(VerificationQuery)
 	in.DeepCopyInto(out)
 	return out
 }
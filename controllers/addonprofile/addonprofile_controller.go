@@ -0,0 +1,340 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addonprofile
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/cluster-api/util/predicates"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// AddonProfileReconciler reconciles an AddonProfile object.
+type AddonProfileReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// WatchFilterValue is the label value used to filter events prior to reconciliation.
+	WatchFilterValue string
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *AddonProfileReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager, options controller.Options) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	return ctrl.NewControllerManagedBy(mgr).
+		WithOptions(options).
+		For(&addonsv1alpha1.AddonProfile{}).
+		// The HelmChartProxies of a profile trigger a reconcile of it when their readiness changes, so that the charts
+		// with the next weight are rolled out.
+		Owns(&addonsv1alpha1.HelmChartProxy{}).
+		WithEventFilter(predicates.ResourceNotPausedAndHasFilterLabel(mgr.GetScheme(), log, r.WatchFilterValue)).
+		Complete(r)
+}
+
+//+kubebuilder:rbac:groups=addons.cluster.x-k8s.io,resources=addonprofiles,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=addons.cluster.x-k8s.io,resources=addonprofiles/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=addons.cluster.x-k8s.io,resources=addonprofiles/finalizers,verbs=update
+//+kubebuilder:rbac:groups=addons.cluster.x-k8s.io,resources=helmchartproxies,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile expands the charts of an AddonProfile into HelmChartProxies owned by it, rolling them out by ascending weight,
+// and aggregates their readiness into its status. The HelmChartProxies of a deleted AddonProfile are garbage collected
+// along with it, which uninstalls their charts.
+func (r *AddonProfileReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	log.V(2).Info("Beginning reconciliation for AddonProfile", "requestNamespace", req.Namespace, "requestName", req.Name)
+
+	addonProfile := &addonsv1alpha1.AddonProfile{}
+	if err := r.Get(ctx, req.NamespacedName, addonProfile); err != nil {
+		if apierrors.IsNotFound(err) {
+			log.V(2).Info("AddonProfile resource not found, skipping reconciliation", "addonProfile", req.NamespacedName)
+			return ctrl.Result{}, nil
+		}
+
+		return ctrl.Result{}, err
+	}
+
+	if !addonProfile.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	patchHelper, err := patch.NewHelper(addonProfile, r.Client)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "failed to init patch helper")
+	}
+
+	defer func() {
+		conditions.SetSummary(addonProfile,
+			conditions.WithConditions(
+				addonsv1alpha1.HelmChartProxiesAppliedCondition,
+				addonsv1alpha1.HelmChartProxiesReadyCondition,
+			),
+		)
+
+		err := patchHelper.Patch(
+			ctx,
+			addonProfile,
+			patch.WithOwnedConditions{Conditions: []clusterv1.ConditionType{
+				clusterv1.ReadyCondition,
+				addonsv1alpha1.HelmChartProxiesAppliedCondition,
+				addonsv1alpha1.HelmChartProxiesReadyCondition,
+			}},
+			patch.WithStatusObservedGeneration{},
+		)
+		if err != nil && reterr == nil {
+			reterr = err
+			log.Error(err, "failed to patch AddonProfile", "addonProfile", addonProfile.Name)
+		}
+	}()
+
+	return ctrl.Result{}, r.reconcileCharts(ctx, addonProfile)
+}
+
+// reconcileCharts deletes the HelmChartProxies of charts removed from the AddonProfile and creates or updates the
+// HelmChartProxies of its charts by ascending weight. The charts of a weight are only applied once the HelmChartProxies
+// of all charts with a lower weight are ready.
+func (r *AddonProfileReconciler) reconcileCharts(ctx context.Context, addonProfile *addonsv1alpha1.AddonProfile) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	charts := slices.Clone(addonProfile.Spec.Charts)
+	sort.SliceStable(charts, func(i, j int) bool {
+		return charts[i].Weight < charts[j].Weight
+	})
+
+	specs := make(map[string]addonsv1alpha1.HelmChartProxySpec, len(charts))
+	for _, chart := range charts {
+		spec, err := helmChartProxySpecFor(addonProfile, chart)
+		if err != nil {
+			// The template does not change until the AddonProfile is updated, so the reconcile is not retried.
+			conditions.MarkFalse(addonProfile, addonsv1alpha1.HelmChartProxiesAppliedCondition, addonsv1alpha1.ChartTemplateInvalidReason, clusterv1.ConditionSeverityError, "%s", err.Error())
+
+			return nil
+		}
+		specs[chart.Name] = spec
+	}
+
+	existing, err := r.listHelmChartProxies(ctx, addonProfile)
+	if err != nil {
+		return err
+	}
+
+	for name, helmChartProxy := range existing {
+		if _, ok := specs[name]; ok {
+			continue
+		}
+		log.V(2).Info("Deleting HelmChartProxy of chart removed from AddonProfile", "addonProfile", addonProfile.Name, "chart", name, "helmChartProxy", helmChartProxy.Name)
+		if err := r.Delete(ctx, helmChartProxy); err != nil && !apierrors.IsNotFound(err) {
+			err = errors.Wrapf(err, "failed to delete HelmChartProxy %s of chart %s", helmChartProxy.Name, name)
+			conditions.MarkFalse(addonProfile, addonsv1alpha1.HelmChartProxiesAppliedCondition, addonsv1alpha1.HelmChartProxyApplyFailedReason, clusterv1.ConditionSeverityError, "%s", err.Error())
+
+			return err
+		}
+	}
+
+	statuses := make([]addonsv1alpha1.AddonProfileChartStatus, 0, len(charts))
+	var waiting, notReady []string
+	blocked := false
+	for i := 0; i < len(charts); {
+		weight := charts[i].Weight
+		weightReady := true
+		for ; i < len(charts) && charts[i].Weight == weight; i++ {
+			chart := charts[i]
+			helmChartProxy := existing[chart.Name]
+			spec := specs[chart.Name]
+
+			switch {
+			case !blocked:
+				helmChartProxy, err = r.applyHelmChartProxy(ctx, addonProfile, chart.Name, spec, helmChartProxy)
+				if err != nil {
+					conditions.MarkFalse(addonProfile, addonsv1alpha1.HelmChartProxiesAppliedCondition, addonsv1alpha1.HelmChartProxyApplyFailedReason, clusterv1.ConditionSeverityError, "%s", err.Error())
+
+					return err
+				}
+			case helmChartProxy == nil || !isUpToDate(helmChartProxy, spec):
+				waiting = append(waiting, chart.Name)
+			}
+
+			status := addonsv1alpha1.AddonProfileChartStatus{Name: chart.Name}
+			if helmChartProxy != nil {
+				status.HelmChartProxyName = helmChartProxy.Name
+				status.Ready = isReady(helmChartProxy)
+			}
+			if !status.Ready {
+				notReady = append(notReady, chart.Name)
+				weightReady = false
+			}
+			statuses = append(statuses, status)
+		}
+		blocked = blocked || !weightReady
+	}
+	addonProfile.Status.Charts = statuses
+
+	if len(waiting) > 0 {
+		conditions.MarkFalse(addonProfile, addonsv1alpha1.HelmChartProxiesAppliedCondition, addonsv1alpha1.WaitingForLowerWeightChartsReason, clusterv1.ConditionSeverityInfo,
+			"Waiting for the charts with a lower weight to be ready before applying %s", strings.Join(waiting, ", "))
+	} else {
+		conditions.MarkTrue(addonProfile, addonsv1alpha1.HelmChartProxiesAppliedCondition)
+	}
+
+	if len(notReady) > 0 {
+		conditions.MarkFalse(addonProfile, addonsv1alpha1.HelmChartProxiesReadyCondition, addonsv1alpha1.HelmChartProxiesNotReadyReason, clusterv1.ConditionSeverityInfo,
+			"HelmChartProxies of charts %s are not ready", strings.Join(notReady, ", "))
+	} else {
+		conditions.MarkTrue(addonProfile, addonsv1alpha1.HelmChartProxiesReadyCondition)
+	}
+
+	return nil
+}
+
+// applyHelmChartProxy creates the HelmChartProxy of the chart of the AddonProfile with the spec if it does not exist yet,
+// or updates it if its spec differs, and returns it. A HelmChartProxy of the same name not controlled by the profile is
+// not adopted.
+func (r *AddonProfileReconciler) applyHelmChartProxy(ctx context.Context, addonProfile *addonsv1alpha1.AddonProfile, chartName string, spec addonsv1alpha1.HelmChartProxySpec, helmChartProxy *addonsv1alpha1.HelmChartProxy) (*addonsv1alpha1.HelmChartProxy, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	name := helmChartProxyName(addonProfile.Name, chartName)
+	if helmChartProxy == nil {
+		helmChartProxy = &addonsv1alpha1.HelmChartProxy{}
+		err := r.Get(ctx, client.ObjectKey{Namespace: addonProfile.Namespace, Name: name}, helmChartProxy)
+		switch {
+		case err == nil:
+			return nil, errors.Errorf("HelmChartProxy %s of chart %s already exists and is not managed by AddonProfile %s", name, chartName, addonProfile.Name)
+		case !apierrors.IsNotFound(err):
+			return nil, errors.Wrapf(err, "failed to get HelmChartProxy %s of chart %s", name, chartName)
+		}
+
+		helmChartProxy = &addonsv1alpha1.HelmChartProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: addonProfile.Namespace,
+				Labels:    helmChartProxyLabels(addonProfile, chartName),
+			},
+			Spec: spec,
+		}
+		if err := controllerutil.SetControllerReference(addonProfile, helmChartProxy, r.Client.Scheme()); err != nil {
+			return nil, errors.Wrapf(err, "failed to set owner of HelmChartProxy %s", name)
+		}
+		if err := r.Create(ctx, helmChartProxy); err != nil {
+			return nil, errors.Wrapf(err, "failed to create HelmChartProxy %s of chart %s", name, chartName)
+		}
+		log.V(2).Info("Created HelmChartProxy of chart of AddonProfile", "addonProfile", addonProfile.Name, "chart", chartName, "helmChartProxy", name)
+
+		return helmChartProxy, nil
+	}
+
+	if isUpToDate(helmChartProxy, spec) {
+		return helmChartProxy, nil
+	}
+
+	before := helmChartProxy.DeepCopy()
+	helmChartProxy.Spec = spec
+	for key, value := range helmChartProxyLabels(addonProfile, chartName) {
+		if helmChartProxy.Labels == nil {
+			helmChartProxy.Labels = map[string]string{}
+		}
+		helmChartProxy.Labels[key] = value
+	}
+	if err := r.Patch(ctx, helmChartProxy, client.MergeFrom(before)); err != nil {
+		return nil, errors.Wrapf(err, "failed to patch HelmChartProxy %s of chart %s", helmChartProxy.Name, chartName)
+	}
+	log.V(2).Info("Updated HelmChartProxy of chart of AddonProfile", "addonProfile", addonProfile.Name, "chart", chartName, "helmChartProxy", helmChartProxy.Name)
+
+	return helmChartProxy, nil
+}
+
+// listHelmChartProxies returns the HelmChartProxies controlled by the AddonProfile by the name of their chart.
+func (r *AddonProfileReconciler) listHelmChartProxies(ctx context.Context, addonProfile *addonsv1alpha1.AddonProfile) (map[string]*addonsv1alpha1.HelmChartProxy, error) {
+	helmChartProxyList := &addonsv1alpha1.HelmChartProxyList{}
+	if err := r.List(ctx, helmChartProxyList, client.InNamespace(addonProfile.Namespace), client.MatchingLabels{addonsv1alpha1.AddonProfileLabelName: addonProfile.Name}); err != nil {
+		return nil, errors.Wrapf(err, "failed to list HelmChartProxies of AddonProfile %s", addonProfile.Name)
+	}
+
+	helmChartProxies := map[string]*addonsv1alpha1.HelmChartProxy{}
+	for i := range helmChartProxyList.Items {
+		helmChartProxy := &helmChartProxyList.Items[i]
+		if !metav1.IsControlledBy(helmChartProxy, addonProfile) {
+			continue
+		}
+		helmChartProxies[helmChartProxy.Labels[addonsv1alpha1.AddonProfileChartLabelName]] = helmChartProxy
+	}
+
+	return helmChartProxies, nil
+}
+
+// helmChartProxySpecFor returns the defaulted spec of the HelmChartProxy of the chart of the AddonProfile, i.e. its
+// template with the ClusterSelector of the profile.
+func helmChartProxySpecFor(addonProfile *addonsv1alpha1.AddonProfile, chart addonsv1alpha1.AddonProfileChart) (addonsv1alpha1.HelmChartProxySpec, error) {
+	spec := addonsv1alpha1.HelmChartProxySpec{}
+	decoder := json.NewDecoder(bytes.NewReader(chart.Template.Raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&spec); err != nil {
+		return spec, errors.Wrapf(err, "template of chart %s is not a valid HelmChartProxy spec", chart.Name)
+	}
+
+	spec.ClusterSelector = *addonProfile.Spec.ClusterSelector.DeepCopy()
+	spec.SetDefaults()
+
+	return spec, nil
+}
+
+// helmChartProxyLabels returns the labels of the HelmChartProxy of the chart of the AddonProfile. The watch filter label of
+// the profile is propagated, so that its HelmChartProxies are reconciled by the same controller.
+func helmChartProxyLabels(addonProfile *addonsv1alpha1.AddonProfile, chartName string) map[string]string {
+	labels := map[string]string{
+		addonsv1alpha1.AddonProfileLabelName:      addonProfile.Name,
+		addonsv1alpha1.AddonProfileChartLabelName: chartName,
+	}
+	if value, ok := addonProfile.Labels[clusterv1.WatchLabel]; ok {
+		labels[clusterv1.WatchLabel] = value
+	}
+
+	return labels
+}
+
+// helmChartProxyName returns the name of the HelmChartProxy of the chart of the AddonProfile.
+func helmChartProxyName(addonProfileName, chartName string) string {
+	return fmt.Sprintf("%s-%s", addonProfileName, chartName)
+}
+
+// isUpToDate returns true if the HelmChartProxy has the spec.
+func isUpToDate(helmChartProxy *addonsv1alpha1.HelmChartProxy, spec addonsv1alpha1.HelmChartProxySpec) bool {
+	return equality.Semantic.DeepEqual(helmChartProxy.Spec, spec)
+}
+
+// isReady returns true if the HelmChartProxy is ready and was reconciled from its latest spec.
+func isReady(helmChartProxy *addonsv1alpha1.HelmChartProxy) bool {
+	return helmChartProxy.Status.ObservedGeneration == helmChartProxy.Generation && conditions.IsTrue(helmChartProxy, clusterv1.ReadyCondition)
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addonprofile
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcile(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = addonsv1alpha1.AddToScheme(scheme)
+
+	addonProfile := &addonsv1alpha1.AddonProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "platform", Namespace: "default", UID: "profile-uid"},
+		Spec: addonsv1alpha1.AddonProfileSpec{
+			ClusterSelector: metav1.LabelSelector{MatchLabels: map[string]string{"profile": "platform"}},
+			Charts: []addonsv1alpha1.AddonProfileChart{
+				{
+					Name:     "ingress",
+					Weight:   10,
+					Template: runtime.RawExtension{Raw: []byte(`{"repoURL":"https://kubernetes.github.io/ingress-nginx","chartName":"ingress-nginx"}`)},
+				},
+				{
+					Name:     "cni",
+					Template: runtime.RawExtension{Raw: []byte(`{"repoURL":"https://docs.tigera.io/calico/charts","chartName":"tigera-operator"}`)},
+				},
+			},
+		},
+	}
+
+	readyHelmChartProxy := func(name, chartName string, spec addonsv1alpha1.HelmChartProxySpec) *addonsv1alpha1.HelmChartProxy {
+		helmChartProxy := &addonsv1alpha1.HelmChartProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				Labels: map[string]string{
+					addonsv1alpha1.AddonProfileLabelName:      addonProfile.Name,
+					addonsv1alpha1.AddonProfileChartLabelName: chartName,
+				},
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: addonsv1alpha1.GroupVersion.String(),
+					Kind:       "AddonProfile",
+					Name:       addonProfile.Name,
+					UID:        addonProfile.UID,
+					Controller: ptr.To(true),
+				}},
+			},
+			Spec: spec,
+		}
+		conditions.MarkTrue(helmChartProxy, clusterv1.ReadyCondition)
+
+		return helmChartProxy
+	}
+
+	cniSpec, err := helmChartProxySpecFor(addonProfile, addonProfile.Spec.Charts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := []struct {
+		name          string
+		addonProfile  func() *addonsv1alpha1.AddonProfile
+		objects       []client.Object
+		expectedError string
+		expect        func(g *WithT, c client.Client, addonProfile *addonsv1alpha1.AddonProfile)
+	}{
+		{
+			name: "charts with a higher weight wait for the charts with a lower weight",
+			expect: func(g *WithT, c client.Client, addonProfile *addonsv1alpha1.AddonProfile) {
+				cni := &addonsv1alpha1.HelmChartProxy{}
+				g.Expect(c.Get(context.TODO(), client.ObjectKey{Namespace: "default", Name: "platform-cni"}, cni)).To(Succeed())
+				g.Expect(cni.Spec.ClusterSelector.MatchLabels).To(Equal(map[string]string{"profile": "platform"}))
+				g.Expect(cni.Spec.ChartName).To(Equal("tigera-operator"))
+				g.Expect(cni.Spec.ReleaseNamespace).To(Equal("default"))
+				g.Expect(metav1.IsControlledBy(cni, addonProfile)).To(BeTrue())
+
+				err := c.Get(context.TODO(), client.ObjectKey{Namespace: "default", Name: "platform-ingress"}, &addonsv1alpha1.HelmChartProxy{})
+				g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+				g.Expect(conditions.GetReason(addonProfile, addonsv1alpha1.HelmChartProxiesAppliedCondition)).To(Equal(addonsv1alpha1.WaitingForLowerWeightChartsReason))
+				g.Expect(conditions.IsFalse(addonProfile, addonsv1alpha1.HelmChartProxiesReadyCondition)).To(BeTrue())
+				g.Expect(addonProfile.Status.Charts).To(Equal([]addonsv1alpha1.AddonProfileChartStatus{
+					{Name: "cni", HelmChartProxyName: "platform-cni"},
+					{Name: "ingress"},
+				}))
+			},
+		},
+		{
+			name:    "charts with a higher weight are applied once the charts with a lower weight are ready",
+			objects: []client.Object{readyHelmChartProxy("platform-cni", "cni", cniSpec)},
+			expect: func(g *WithT, c client.Client, addonProfile *addonsv1alpha1.AddonProfile) {
+				g.Expect(c.Get(context.TODO(), client.ObjectKey{Namespace: "default", Name: "platform-ingress"}, &addonsv1alpha1.HelmChartProxy{})).To(Succeed())
+
+				g.Expect(conditions.IsTrue(addonProfile, addonsv1alpha1.HelmChartProxiesAppliedCondition)).To(BeTrue())
+				g.Expect(conditions.GetReason(addonProfile, addonsv1alpha1.HelmChartProxiesReadyCondition)).To(Equal(addonsv1alpha1.HelmChartProxiesNotReadyReason))
+				g.Expect(addonProfile.Status.Charts).To(Equal([]addonsv1alpha1.AddonProfileChartStatus{
+					{Name: "cni", HelmChartProxyName: "platform-cni", Ready: true},
+					{Name: "ingress", HelmChartProxyName: "platform-ingress"},
+				}))
+			},
+		},
+		{
+			name:    "HelmChartProxy of a removed chart is deleted",
+			objects: []client.Object{readyHelmChartProxy("platform-cni", "cni", cniSpec), readyHelmChartProxy("platform-monitoring", "monitoring", cniSpec)},
+			expect: func(g *WithT, c client.Client, _ *addonsv1alpha1.AddonProfile) {
+				err := c.Get(context.TODO(), client.ObjectKey{Namespace: "default", Name: "platform-monitoring"}, &addonsv1alpha1.HelmChartProxy{})
+				g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			},
+		},
+		{
+			name: "invalid template is reported",
+			addonProfile: func() *addonsv1alpha1.AddonProfile {
+				addonProfile := addonProfile.DeepCopy()
+				addonProfile.Spec.Charts[1].Template.Raw = []byte(`{"chart":"tigera-operator"}`)
+
+				return addonProfile
+			},
+			expect: func(g *WithT, c client.Client, addonProfile *addonsv1alpha1.AddonProfile) {
+				g.Expect(conditions.GetReason(addonProfile, addonsv1alpha1.HelmChartProxiesAppliedCondition)).To(Equal(addonsv1alpha1.ChartTemplateInvalidReason))
+				g.Expect(conditions.IsFalse(addonProfile, clusterv1.ReadyCondition)).To(BeTrue())
+
+				helmChartProxyList := &addonsv1alpha1.HelmChartProxyList{}
+				g.Expect(c.List(context.TODO(), helmChartProxyList)).To(Succeed())
+				g.Expect(helmChartProxyList.Items).To(BeEmpty())
+			},
+		},
+		{
+			name: "HelmChartProxy not managed by the profile is not adopted",
+			objects: []client.Object{&addonsv1alpha1.HelmChartProxy{
+				ObjectMeta: metav1.ObjectMeta{Name: "platform-cni", Namespace: "default"},
+			}},
+			expectedError: "HelmChartProxy platform-cni of chart cni already exists and is not managed by AddonProfile platform",
+			expect: func(g *WithT, _ client.Client, addonProfile *addonsv1alpha1.AddonProfile) {
+				g.Expect(conditions.GetReason(addonProfile, addonsv1alpha1.HelmChartProxiesAppliedCondition)).To(Equal(addonsv1alpha1.HelmChartProxyApplyFailedReason))
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			profile := addonProfile.DeepCopy()
+			if tc.addonProfile != nil {
+				profile = tc.addonProfile()
+			}
+
+			c := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(append(tc.objects, profile)...).
+				WithStatusSubresource(&addonsv1alpha1.AddonProfile{}).
+				Build()
+			r := &AddonProfileReconciler{Client: c, Scheme: scheme}
+
+			_, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(profile)})
+			if tc.expectedError != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tc.expectedError)))
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+
+			result := &addonsv1alpha1.AddonProfile{}
+			g.Expect(c.Get(context.TODO(), client.ObjectKeyFromObject(profile), result)).To(Succeed())
+			tc.expect(g, c, result)
+		})
+	}
+}
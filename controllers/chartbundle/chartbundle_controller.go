@@ -0,0 +1,100 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartbundle
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/cluster-api-addon-provider-helm/internal"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/cluster-api/util/predicates"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+)
+
+// ChartBundleReconciler reconciles a ChartBundle object.
+type ChartBundleReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// WatchFilterValue is the label value used to filter events prior to reconciliation.
+	WatchFilterValue string
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ChartBundleReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager, options controller.Options) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	return ctrl.NewControllerManagedBy(mgr).
+		WithOptions(options).
+		For(&addonsv1alpha1.ChartBundle{}).
+		WithEventFilter(predicates.ResourceNotPausedAndHasFilterLabel(mgr.GetScheme(), log, r.WatchFilterValue)).
+		Complete(r)
+}
+
+//+kubebuilder:rbac:groups=addons.cluster.x-k8s.io,resources=chartbundles,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=addons.cluster.x-k8s.io,resources=chartbundles/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+
+// Reconcile reads the charts contained in the bundle tarball of a ChartBundle into its status, so HelmReleaseProxies can
+// look up the chart versions they reference.
+func (r *ChartBundleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	log.V(2).Info("Beginning reconciliation for ChartBundle", "requestNamespace", req.Namespace, "requestName", req.Name)
+
+	chartBundle := &addonsv1alpha1.ChartBundle{}
+	if err := r.Get(ctx, req.NamespacedName, chartBundle); err != nil {
+		if apierrors.IsNotFound(err) {
+			log.V(2).Info("ChartBundle resource not found, skipping reconciliation", "chartBundle", req.NamespacedName)
+			return ctrl.Result{}, nil
+		}
+
+		return ctrl.Result{}, err
+	}
+
+	patchHelper, err := patch.NewHelper(chartBundle, r.Client)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "failed to init patch helper")
+	}
+
+	defer func() {
+		if err := patchHelper.Patch(ctx, chartBundle, patch.WithOwnedConditions{Conditions: []clusterv1.ConditionType{clusterv1.ReadyCondition}}, patch.WithStatusObservedGeneration{}); err != nil && reterr == nil {
+			reterr = err
+			log.Error(err, "failed to patch ChartBundle", "chartBundle", chartBundle.Name)
+		}
+	}()
+
+	entries, err := internal.ReadChartBundle(ctx, r.Client, chartBundle)
+	if err != nil {
+		conditions.MarkFalse(chartBundle, clusterv1.ReadyCondition, addonsv1alpha1.ChartBundleLoadFailedReason, clusterv1.ConditionSeverityError, "%s", err.Error())
+
+		return ctrl.Result{}, err
+	}
+
+	chartBundle.Status.Charts = entries
+	conditions.MarkTrue(chartBundle, clusterv1.ReadyCondition)
+
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartbundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcile(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = addonsv1alpha1.AddToScheme(scheme)
+
+	var emptyTarball bytes.Buffer
+	if err := tar.NewWriter(&emptyTarball).Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	chartBundle := &addonsv1alpha1.ChartBundle{
+		ObjectMeta: metav1.ObjectMeta{Name: "addons", Namespace: "default"},
+		Spec: addonsv1alpha1.ChartBundleSpec{
+			Source: addonsv1alpha1.ChartBundleSource{
+				Secret: &addonsv1alpha1.ChartBundleSecretSource{Name: "bundle", Key: "bundle.tar"},
+			},
+		},
+	}
+
+	testcases := []struct {
+		name          string
+		objects       []client.Object
+		expectedError string
+		expect        func(g *WithT, chartBundle *addonsv1alpha1.ChartBundle)
+	}{
+		{
+			name: "bundle is read",
+			objects: []client.Object{&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "bundle", Namespace: "default"},
+				Data:       map[string][]byte{"bundle.tar": emptyTarball.Bytes()},
+			}},
+			expect: func(g *WithT, chartBundle *addonsv1alpha1.ChartBundle) {
+				g.Expect(conditions.IsTrue(chartBundle, clusterv1.ReadyCondition)).To(BeTrue())
+				g.Expect(chartBundle.Status.Charts).To(BeEmpty())
+			},
+		},
+		{
+			name:          "bundle Secret is missing",
+			expectedError: "failed to get Secret bundle of ChartBundle addons",
+			expect: func(g *WithT, chartBundle *addonsv1alpha1.ChartBundle) {
+				g.Expect(conditions.IsFalse(chartBundle, clusterv1.ReadyCondition)).To(BeTrue())
+				g.Expect(conditions.GetReason(chartBundle, clusterv1.ReadyCondition)).To(Equal(addonsv1alpha1.ChartBundleLoadFailedReason))
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			c := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(append(tc.objects, chartBundle.DeepCopy())...).
+				WithStatusSubresource(&addonsv1alpha1.ChartBundle{}).
+				Build()
+			r := &ChartBundleReconciler{Client: c}
+
+			_, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(chartBundle)})
+			if tc.expectedError != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tc.expectedError)))
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+
+			result := &addonsv1alpha1.ChartBundle{}
+			g.Expect(c.Get(context.TODO(), client.ObjectKeyFromObject(chartBundle), result)).To(Succeed())
+			tc.expect(g, result)
+		})
+	}
+}
@@ -18,9 +18,12 @@ package helmchartproxy
 
 import (
 	"context"
+	"fmt"
 	"slices"
+	"time"
 
 	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -28,17 +31,23 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/ptr"
 	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/cluster-api-addon-provider-helm/internal"
+	addonsv1 "sigs.k8s.io/cluster-api/api/addons/v1beta1"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util"
 	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/cluster-api/util/patch"
 	"sigs.k8s.io/cluster-api/util/predicates"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 )
 
 // HelmChartProxyReconciler reconciles a HelmChartProxy object.
@@ -46,10 +55,295 @@ type HelmChartProxyReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
 
+	// Recorder is used to emit events for the HelmChartProxy.
+	Recorder record.EventRecorder
+
+	// HelmClient is used to list the Helm releases on the selected Clusters in discovery mode.
+	HelmClient internal.Client
+
+	// WarmupCharts enables downloading the chart of each HelmChartProxy in the background before it is installed on a Cluster.
+	WarmupCharts bool
+
+	// RegistryBreaker stops warming up charts from registries that keep failing. It is shared with the HelmClient, so that
+	// both track the failures of the same registries. If it is nil, charts are always fetched.
+	RegistryBreaker *internal.RegistryBreaker
+
+	// ListChartVersions enables listing the versions of the OCI chart of each HelmChartProxy in its status.
+	ListChartVersions bool
+
 	// WatchFilterValue is the label value used to filter events prior to reconciliation.
 	WatchFilterValue string
+
+	// GlobalPause pauses the creation, update and deletion of HelmReleaseProxies while it is set. If it is nil, the
+	// controller is never paused.
+	GlobalPause *internal.GlobalPause
+
+	// StalenessThreshold is the duration without a reconcile without error after which the ReconciledRecentlyCondition of
+	// a HelmChartProxy is marked false. If it is 0, the condition is never set.
+	StalenessThreshold time.Duration
+
+	// LightweightStatus omits the lists of matching Clusters, out of date HelmReleaseProxies and Cluster operations and the
+	// true conditions summarized by the Ready condition from the status, to keep HelmChartProxies selecting many Clusters
+	// small. The omitted details are exposed as metrics instead.
+	LightweightStatus bool
+
+	// ClusterOperations tracks the Helm operations queued and in flight on each workload Cluster, which are reported in the
+	// status. It is shared with the HelmReleaseProxy controller running the operations.
+	ClusterOperations *internal.ClusterOperationQueue
+
+	// TemplateOptions configures the rendering of the values templates and manifests of HelmChartProxies.
+	TemplateOptions internal.TemplateOptions
+
+	// RolloutVerifier runs the verification queries of rollouts against the allowed Prometheus endpoints.
+	RolloutVerifier internal.RolloutVerifier
+}
+
+// reconcileRequestedCluster reconciles the Cluster named by the ReconcileClusterAnnotation ahead of the rollout ordering, so
+// that a single Cluster can be hotfixed without waiting for its batch. The annotation is removed once it has been handled.
+func (r *HelmChartProxyReconciler) reconcileRequestedCluster(ctx context.Context, helmChartProxy *addonsv1alpha1.HelmChartProxy, clusters []clusterv1.Cluster, helmReleaseProxies []addonsv1alpha1.HelmReleaseProxy) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	clusterName, ok := helmChartProxy.GetAnnotations()[addonsv1alpha1.ReconcileClusterAnnotation]
+	if !ok {
+		return nil
+	}
+
+	idx := slices.IndexFunc(clusters, func(c clusterv1.Cluster) bool { return c.Name == clusterName })
+	if idx < 0 {
+		log.Info("Cluster requested for reconciliation is not selected by HelmChartProxy", "name", helmChartProxy.Name, "cluster", clusterName)
+		r.Recorder.Eventf(helmChartProxy, corev1.EventTypeWarning, addonsv1alpha1.ReconcileClusterNotSelectedReason, "Cluster %s requested for reconciliation is not selected by the HelmChartProxy", clusterName)
+	} else {
+		log.Info("Reconciling requested cluster outside of the rollout ordering", "name", helmChartProxy.Name, "cluster", clusterName)
+		if err := r.reconcileForCluster(ctx, helmChartProxy, clusters[idx]); err != nil {
+			return err
+		}
+
+		// Count a newly created HelmReleaseProxy towards the rollout, as the rollout skips Clusters that already have one.
+		hrpExists := slices.ContainsFunc(helmReleaseProxies, func(h addonsv1alpha1.HelmReleaseProxy) bool { return h.Spec.ClusterRef.Name == clusterName })
+		if !hrpExists && helmChartProxy.Status.Rollout != nil {
+			helmChartProxy.Status.Rollout.Count = ptr.To(ptr.Deref(helmChartProxy.Status.Rollout.Count, 0) + 1)
+		}
+	}
+
+	annotations := helmChartProxy.GetAnnotations()
+	delete(annotations, addonsv1alpha1.ReconcileClusterAnnotation)
+	helmChartProxy.SetAnnotations(annotations)
+
+	return nil
+}
+
+// maxObservedBatches is the number of most recent batch durations the average batch duration of a rollout is weighted over.
+const maxObservedBatches = 5
+
+// setRolloutStatus sets the rollout status of the HelmChartProxy, recording the current time as the last progress time if
+// the count of rolled out HelmReleaseProxies changed. The time since the previous progress is averaged into the batch
+// duration when the count increased.
+func setRolloutStatus(helmChartProxy *addonsv1alpha1.HelmChartProxy, count, stepSize int) {
+	now := metav1.Now()
+	status := &addonsv1alpha1.RolloutStatus{Count: ptr.To(count), StepSize: ptr.To(stepSize), LastProgressTime: &now}
+	if previous := helmChartProxy.Status.Rollout; previous != nil {
+		status.AverageBatchDuration = previous.AverageBatchDuration
+		status.ObservedBatches = previous.ObservedBatches
+		status.EstimatedCompletionTime = previous.EstimatedCompletionTime
+		status.LastBatch = previous.LastBatch
+		status.ApprovedCount = previous.ApprovedCount
+
+		previousCount := ptr.Deref(previous.Count, 0)
+		switch {
+		case previous.LastProgressTime != nil && previousCount == count:
+			status.LastProgressTime = previous.LastProgressTime
+		case previous.LastProgressTime != nil && previousCount < count:
+			observeBatchDuration(status, now.Sub(previous.LastProgressTime.Time))
+		}
+	}
+
+	helmChartProxy.Status.Rollout = status
+}
+
+// setRolloutLastBatch records the names of the Clusters rolled out in the current batch. The previous batch is kept if no
+// Cluster was rolled out.
+func setRolloutLastBatch(helmChartProxy *addonsv1alpha1.HelmChartProxy, batch []string) {
+	if len(batch) > 0 && helmChartProxy.Status.Rollout != nil {
+		helmChartProxy.Status.Rollout.LastBatch = batch
+	}
+}
+
+// observeBatchDuration averages the batch duration into the average batch duration of the rollout status. Once
+// maxObservedBatches durations have been observed, older durations are weighted down exponentially.
+func observeBatchDuration(status *addonsv1alpha1.RolloutStatus, duration time.Duration) {
+	if status.ObservedBatches < maxObservedBatches {
+		status.ObservedBatches++
+	}
+
+	average := time.Duration(0)
+	if status.AverageBatchDuration != nil {
+		average = status.AverageBatchDuration.Duration
+	}
+	average += (duration - average) / time.Duration(status.ObservedBatches)
+	status.AverageBatchDuration = &metav1.Duration{Duration: average.Round(time.Second)}
+}
+
+// nextRolloutStepSize returns the step size of the next batch of a rollout, which grows by the step increment from the
+// previous step size up to the step limit, if the limit is greater than the initial step.
+func nextRolloutStepSize(oldStepSize, stepIncrement, stepInit, stepLimit int) int {
+	stepSize := oldStepSize + stepIncrement
+	if stepLimit > stepInit && stepSize > stepLimit {
+		stepSize = stepLimit
+	}
+
+	return stepSize
+}
+
+// setRolloutEstimatedCompletion sets the estimated completion time of the rollout of the HelmChartProxy to the Clusters,
+// extrapolated from the last progress time by the average batch duration for each of the batches still needed at the step
+// sizes of the rollout options. The estimate is cleared if no batch duration has been observed yet.
+func setRolloutEstimatedCompletion(helmChartProxy *addonsv1alpha1.HelmChartProxy, rolloutOptions *addonsv1alpha1.RolloutOptions, clusters int) {
+	status := helmChartProxy.Status.Rollout
+	if status == nil {
+		return
+	}
+	status.EstimatedCompletionTime = nil
+	if status.AverageBatchDuration == nil || status.LastProgressTime == nil {
+		return
+	}
+
+	scaled := func(value *intstr.IntOrString) (int, error) {
+		if value == nil {
+			return 0, nil
+		}
+
+		return intstr.GetScaledValueFromIntOrPercent(value, clusters, true)
+	}
+	stepInit, err := scaled(rolloutOptions.StepInit)
+	if err != nil {
+		return
+	}
+	stepIncrement, err := scaled(rolloutOptions.StepIncrement)
+	if err != nil {
+		return
+	}
+	stepLimit, err := scaled(rolloutOptions.StepLimit)
+	if err != nil {
+		return
+	}
+
+	batches := 0
+	stepSize := ptr.Deref(status.StepSize, stepInit)
+	for remaining := clusters - ptr.Deref(status.Count, 0); remaining > 0; remaining -= stepSize {
+		stepSize = nextRolloutStepSize(stepSize, stepIncrement, stepInit, stepLimit)
+		if stepSize <= 0 {
+			return
+		}
+		batches++
+	}
+
+	status.EstimatedCompletionTime = &metav1.Time{Time: status.LastProgressTime.Add(time.Duration(batches) * status.AverageBatchDuration.Duration)}
+}
+
+// setClusterOperations sets the Helm operations queued and in flight on the Clusters in the status of the HelmChartProxy,
+// omitting the Clusters without any.
+func setClusterOperations(helmChartProxy *addonsv1alpha1.HelmChartProxy, clusters []clusterv1.Cluster, queue *internal.ClusterOperationQueue) {
+	var clusterOperations []addonsv1alpha1.ClusterOperations
+	for _, cluster := range clusters {
+		queued, inFlight := queue.Counts(util.ObjectKey(&cluster))
+		if queued == 0 && inFlight == 0 {
+			continue
+		}
+		clusterOperations = append(clusterOperations, addonsv1alpha1.ClusterOperations{
+			ClusterName: cluster.Name,
+			Queued:      int32(queued),
+			InFlight:    int32(inFlight),
+		})
+	}
+
+	helmChartProxy.Status.ClusterOperations = clusterOperations
+}
+
+// maxDeployedCharts is the maximum number of entries kept in the deployed charts history of a HelmChartProxy.
+const maxDeployedCharts = 10
+
+// setDeployedCharts updates the deployed charts history of the HelmChartProxy with the charts of the deployed Helm releases
+// of the HelmReleaseProxies, appending the charts that were not deployed before and retiring those no longer deployed. The
+// oldest entries are dropped once the history exceeds maxDeployedCharts.
+func setDeployedCharts(helmChartProxy *addonsv1alpha1.HelmChartProxy, helmReleaseProxies []addonsv1alpha1.HelmReleaseProxy, now metav1.Time) {
+	deployed := []addonsv1alpha1.DeployedChart{}
+	for _, helmReleaseProxy := range helmReleaseProxies {
+		digest := helmReleaseProxy.Labels[addonsv1alpha1.ChartDigestLabelName]
+		if digest == "" || helmReleaseProxy.Status.ChartVersion == "" {
+			continue
+		}
+		chart := addonsv1alpha1.DeployedChart{
+			RepoURL:     helmReleaseProxy.Spec.RepoURL,
+			ChartName:   helmReleaseProxy.Spec.ChartName,
+			Version:     helmReleaseProxy.Status.ChartVersion,
+			ChartDigest: digest,
+		}
+		if !slices.ContainsFunc(deployed, func(c addonsv1alpha1.DeployedChart) bool { return isSameChart(c, chart) }) {
+			deployed = append(deployed, chart)
+		}
+	}
+
+	history := helmChartProxy.Status.DeployedCharts
+	for i := range history {
+		if history[i].RetiredTime == nil && !slices.ContainsFunc(deployed, func(c addonsv1alpha1.DeployedChart) bool { return isSameChart(c, history[i]) }) {
+			history[i].RetiredTime = ptr.To(now)
+		}
+	}
+	for _, chart := range deployed {
+		if !slices.ContainsFunc(history, func(c addonsv1alpha1.DeployedChart) bool { return c.RetiredTime == nil && isSameChart(c, chart) }) {
+			chart.DeployedTime = now
+			history = append(history, chart)
+		}
+	}
+	if len(history) > maxDeployedCharts {
+		history = history[len(history)-maxDeployedCharts:]
+	}
+
+	helmChartProxy.Status.DeployedCharts = history
+}
+
+// setChartVersions lists the versions of the chart of the HelmChartProxy and sets the version its Version resolves to and
+// the versions it can be upgraded to in its status. The previous status is kept if the versions cannot be listed, as they
+// are only informational.
+func setChartVersions(ctx context.Context, helmChartProxy *addonsv1alpha1.HelmChartProxy) {
+	log := ctrl.LoggerFrom(ctx)
+
+	versions, err := internal.ListChartVersions(ctx, helmChartProxy.Spec)
+	if err != nil {
+		log.Error(err, "failed to list chart versions", "helmChartProxy", helmChartProxy.Name, "chart", helmChartProxy.Spec.ChartName)
+		return
+	}
+	if len(versions) == 0 {
+		helmChartProxy.Status.ChartVersions = nil
+		return
+	}
+
+	chartVersions, err := internal.ChartVersionsFor(versions, helmChartProxy.Spec.Version)
+	if err != nil {
+		log.Error(err, "failed to resolve chart version", "helmChartProxy", helmChartProxy.Name, "version", helmChartProxy.Spec.Version)
+		return
+	}
+	helmChartProxy.Status.ChartVersions = chartVersions
+}
+
+// isSameChart returns true if both deployed charts are the same chart, regardless of when they were deployed.
+func isSameChart(a, b addonsv1alpha1.DeployedChart) bool {
+	return a.RepoURL == b.RepoURL && a.ChartName == b.ChartName && a.Version == b.Version && a.ChartDigest == b.ChartDigest
 }
 
+// isRolloutStalled returns true if the rollout has not progressed within the progress deadline.
+func isRolloutStalled(status *addonsv1alpha1.RolloutStatus, progressDeadline time.Duration) bool {
+	if status == nil || status.LastProgressTime == nil {
+		return false
+	}
+
+	return time.Since(status.LastProgressTime.Time) > progressDeadline
+}
+
+// rolloutVerificationRequeueInterval is the interval at which the rollout verification queries are run again after they did
+// not pass.
+const rolloutVerificationRequeueInterval = time.Minute
+
 // helmReleaseProxyRolloutMeta is used to gather HelmReleaseProxy  rollout
 // metadata for matching clusters.
 type helmReleaseProxyRolloutMeta struct {
@@ -60,6 +354,42 @@ type helmReleaseProxyRolloutMeta struct {
 
 	// Identifies whether HelmReleaseProxy's ready condition is True.
 	hrpReady bool
+
+	// Identifies whether HelmReleaseProxy's ready condition is False with the Error severity.
+	hrpFailed bool
+}
+
+// isNextBatchApproved returns true if the next batch of a rollout requiring approval was approved after count
+// HelmReleaseProxies were rolled out.
+func isNextBatchApproved(status *addonsv1alpha1.RolloutStatus, count int) bool {
+	return status != nil && status.ApprovedCount != nil && *status.ApprovedCount == count
+}
+
+// countFailedHelmReleaseProxies returns the number of HelmReleaseProxies of the rollout whose Helm release failed.
+func countFailedHelmReleaseProxies(rolloutMeta []*helmReleaseProxyRolloutMeta) int {
+	failed := 0
+	for _, meta := range rolloutMeta {
+		if meta.hrpFailed {
+			failed++
+		}
+	}
+
+	return failed
+}
+
+// isRolloutHalted returns true if the rollout must be halted because at least maxFailures of its HelmReleaseProxies
+// failed. A rollout without maxFailures is never halted.
+func isRolloutHalted(maxFailures *intstr.IntOrString, failed, clusters int) (bool, error) {
+	if maxFailures == nil || failed == 0 {
+		return false, nil
+	}
+
+	threshold, err := intstr.GetScaledValueFromIntOrPercent(maxFailures, clusters, true)
+	if err != nil {
+		return false, err
+	}
+
+	return failed >= threshold, nil
 }
 
 // installOrUpgrade defines the install vs upgrade rolling reconcile type event.
@@ -74,19 +404,71 @@ const (
 // SetupWithManager sets up the controller with the Manager.
 func (r *HelmChartProxyReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager, options controller.Options) error {
 	log := ctrl.LoggerFrom(ctx)
+	filter := predicates.ResourceNotPausedAndHasFilterLabel(mgr.GetScheme(), log, r.WatchFilterValue)
 
 	return ctrl.NewControllerManagedBy(mgr).
 		WithOptions(options).
-		For(&addonsv1alpha1.HelmChartProxy{}).
-		WithEventFilter(predicates.ResourceNotPausedAndHasFilterLabel(mgr.GetScheme(), log, r.WatchFilterValue)).
+		For(&addonsv1alpha1.HelmChartProxy{}, builder.WithPredicates(filter)).
+		// Clusters are not filtered by the watch filter label, as HelmChartProxies may select Clusters of other watch
+		// filters with their ClusterWatchFilterValue. The mapper only maps them to HelmChartProxies of this controller.
 		Watches(
 			&clusterv1.Cluster{},
 			handler.EnqueueRequestsFromMapFunc(r.ClusterToHelmChartProxiesMapper),
+			builder.WithPredicates(predicates.ResourceNotPaused(mgr.GetScheme(), log)),
 		).
 		Watches(
 			&addonsv1alpha1.HelmReleaseProxy{},
 			handler.EnqueueRequestsFromMapFunc(HelmReleaseProxyToHelmChartProxyMapper),
+			builder.WithPredicates(filter),
+		).
+		Watches(
+			&corev1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(r.TemplateLibraryToHelmChartProxiesMapper),
+			builder.WithPredicates(filter),
 		).
+		Watches(
+			&corev1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(r.ProxySettingsToHelmChartProxiesMapper),
+			builder.WithPredicates(filter),
+		).
+		Watches(
+			&corev1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(r.ValuesFromToHelmChartProxiesMapper),
+			builder.WithPredicates(filter),
+		).
+		Watches(
+			&corev1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.ValuesFromToHelmChartProxiesMapper),
+			builder.WithPredicates(filter),
+		).
+		Watches(
+			&clusterv1.ClusterClass{},
+			handler.EnqueueRequestsFromMapFunc(r.ClusterClassToHelmChartProxiesMapper),
+			builder.WithPredicates(filter),
+		).
+		Watches(
+			&addonsv1.ClusterResourceSetBinding{},
+			handler.EnqueueRequestsFromMapFunc(r.ClusterResourceSetBindingToHelmChartProxiesMapper),
+			builder.WithPredicates(filter),
+		).
+		Watches(
+			&addonsv1alpha1.HelmValuesOverride{},
+			handler.EnqueueRequestsFromMapFunc(r.HelmValuesOverrideToHelmChartProxyMapper),
+			builder.WithPredicates(filter),
+		).
+		Watches(
+			&addonsv1alpha1.ChartSourceDefaults{},
+			handler.EnqueueRequestsFromMapFunc(r.ChartSourceDefaultsToHelmChartProxiesMapper),
+			builder.WithPredicates(filter),
+		).
+		// The global pause ConfigMap is not filtered by the watch filter label, as it is shared by all controllers.
+		Watches(
+			&corev1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(r.GlobalPauseToHelmChartProxiesMapper),
+			builder.WithPredicates(predicate.NewPredicateFuncs(r.GlobalPause.IsConfigMap)),
+		).
+		// The Jobs of rollout hooks trigger a reconcile of their HelmChartProxy once they finish.
+		Owns(&batchv1.Job{}).
 		Complete(r)
 }
 
@@ -94,15 +476,23 @@ func (r *HelmChartProxyReconciler) SetupWithManager(ctx context.Context, mgr ctr
 //+kubebuilder:rbac:groups=addons.cluster.x-k8s.io,resources=helmchartproxies/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=addons.cluster.x-k8s.io,resources=helmchartproxies/finalizers,verbs=update
 //+kubebuilder:rbac:groups=addons.cluster.x-k8s.io,resources=helmreleaseproxies,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=addons.cluster.x-k8s.io,resources=helmchartproxyrevisions,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=addons.cluster.x-k8s.io,resources=chartsourcedefaults,verbs=get;list;watch
+//+kubebuilder:rbac:groups=addons.cluster.x-k8s.io,resources=helmvaluesoverrides,verbs=get;list;watch
 //+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters,verbs=list;watch
+//+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusterclasses,verbs=get;list;watch
+//+kubebuilder:rbac:groups=addons.cluster.x-k8s.io,resources=clusterresourcesetbindings,verbs=get;list;watch
+//+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machinepools,verbs=get;list;watch
 //+kubebuilder:rbac:groups=controlplane.cluster.x-k8s.io,resources=kubeadmcontrolplanes,verbs=list;get;watch
-//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io;bootstrap.cluster.x-k8s.io;controlplane.cluster.x-k8s.io;clusterctl.cluster.x-k8s.io,resources=*,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=namespaces,verbs=list;
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;delete
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;patch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
-func (r *HelmChartProxyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	var reterr error
+func (r *HelmChartProxyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
 	log := ctrl.LoggerFrom(ctx)
 
 	log.V(2).Info("Beginning reconciliation for HelmChartProxy", "requestNamespace", req.Namespace, "requestName", req.Name)
@@ -125,8 +515,19 @@ func (r *HelmChartProxyReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	}
 
 	defer func() {
+		// The metrics of a HelmChartProxy whose finalizer was removed are deleted along with it.
+		recordMetrics := helmChartProxy.DeletionTimestamp.IsZero() || controllerutil.ContainsFinalizer(helmChartProxy, addonsv1alpha1.HelmChartProxyFinalizer)
+		if recordMetrics {
+			internal.RecordReconcileResult(helmChartProxy, "HelmChartProxy", reterr, r.StalenessThreshold, time.Now())
+		}
+
 		log.V(2).Info("Preparing to patch HelmChartProxy", "helmChartProxy", helmChartProxy.Name)
-		if err := patchHelmChartProxy(ctx, patchHelper, helmChartProxy); err != nil && reterr == nil {
+		err := patchHelmChartProxy(ctx, patchHelper, helmChartProxy, r.LightweightStatus)
+		// The status metrics are recorded once the patch summarized the conditions into the Ready condition.
+		if recordMetrics {
+			internal.RecordHelmChartProxyStatusMetrics(helmChartProxy)
+		}
+		if err != nil && reterr == nil {
 			reterr = err
 			log.Error(err, "failed to patch HelmChartProxy", "helmChartProxy", helmChartProxy.Name)
 
@@ -135,20 +536,35 @@ func (r *HelmChartProxyReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		log.V(2).Info("Successfully patched HelmChartProxy", "helmChartProxy", helmChartProxy.Name)
 	}()
 
-	selector := helmChartProxy.Spec.ClusterSelector
+	// Each spec of the HelmChartProxy is recorded in a revision, and a rolled back spec is patched by the deferred patch, so
+	// that it is reconciled again as a new generation.
+	if helmChartProxy.DeletionTimestamp.IsZero() {
+		if rolledBack, err := r.rollbackToRevision(ctx, helmChartProxy); err != nil || rolledBack {
+			return ctrl.Result{}, err
+		}
+		if err := r.reconcileRevisions(ctx, helmChartProxy); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	selector := clusterSelectorFor(helmChartProxy)
 
 	log.V(2).Info("Finding matching clusters for HelmChartProxy with selector selector", "helmChartProxy", helmChartProxy.Name, "selector", selector)
-	// TODO: When a Cluster is being deleted, it will show up in the list of clusters even though we can't Reconcile on it.
-	// This is because of ownerRefs and how the Cluster gets deleted. It will be eventually consistent but it would be better
-	// to not have errors. An idea would be to check the deletion timestamp.
 	clusterList, err := r.listClustersWithLabels(ctx, helmChartProxy.Namespace, selector)
 	if err != nil {
 		conditions.MarkFalse(helmChartProxy, addonsv1alpha1.HelmReleaseProxySpecsUpToDateCondition, addonsv1alpha1.ClusterSelectionFailedReason, clusterv1.ConditionSeverityError, "%s", err.Error())
 
 		return ctrl.Result{}, err
 	}
+	clusters, err := selectEnvironmentClusters(helmChartProxy, clusterList.Items)
+	if err != nil {
+		conditions.MarkFalse(helmChartProxy, addonsv1alpha1.HelmReleaseProxySpecsUpToDateCondition, addonsv1alpha1.ClusterSelectionFailedReason, clusterv1.ConditionSeverityError, "%s", err.Error())
+
+		return ctrl.Result{}, err
+	}
 	// conditions.MarkTrue(helmChartProxy, addonsv1alpha1.HelmReleaseProxySpecsReadyCondition)
-	helmChartProxy.SetMatchingClusters(clusterList.Items)
+	helmChartProxy.SetMatchingClusters(clusters)
+	setClusterOperations(helmChartProxy, clusters, r.ClusterOperations)
 
 	log.V(2).Info("Finding HelmRelease for HelmChartProxy", "helmChartProxy", helmChartProxy.Name)
 	label := map[string]string{
@@ -158,6 +574,26 @@ func (r *HelmChartProxyReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	if err != nil {
 		return ctrl.Result{}, err
 	}
+	setDeployedCharts(helmChartProxy, releaseList.Items, metav1.Now())
+	setEnvironments(helmChartProxy, clusters, releaseList.Items)
+
+	// While the provider is paused globally, the status is still updated, but no HelmReleaseProxies are created, updated
+	// or deleted and no rollout hooks are run. The pause ConfigMap is watched, so the HelmChartProxy is reconciled again
+	// once the pause is lifted.
+	paused, pauseMessage, err := r.GlobalPause.IsPaused(ctx)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if paused {
+		log.V(2).Info("Not changing HelmReleaseProxies while the provider is paused", "helmChartProxy", helmChartProxy.Name, "message", pauseMessage)
+		conditions.MarkFalse(helmChartProxy, addonsv1alpha1.HelmReleaseProxySpecsUpToDateCondition, addonsv1alpha1.GloballyPausedReason, clusterv1.ConditionSeverityInfo, "%s", pauseMessage)
+
+		if err := r.setOutOfDateReleases(ctx, helmChartProxy, clusters); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{}, r.aggregateHelmReleaseProxyReadyCondition(ctx, helmChartProxy)
+	}
 
 	// examine DeletionTimestamp to determine if object is under deletion
 	if helmChartProxy.DeletionTimestamp.IsZero() {
@@ -166,7 +602,7 @@ func (r *HelmChartProxyReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		// registering our finalizer.
 		if !controllerutil.ContainsFinalizer(helmChartProxy, addonsv1alpha1.HelmChartProxyFinalizer) {
 			controllerutil.AddFinalizer(helmChartProxy, addonsv1alpha1.HelmChartProxyFinalizer)
-			if err := patchHelmChartProxy(ctx, patchHelper, helmChartProxy); err != nil {
+			if err := patchHelmChartProxy(ctx, patchHelper, helmChartProxy, r.LightweightStatus); err != nil {
 				// TODO: Should we try to set the error here? If we can't add the finalizer we likely can't update the status either.
 				return ctrl.Result{}, err
 			}
@@ -181,9 +617,13 @@ func (r *HelmChartProxyReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 				return ctrl.Result{}, err
 			}
 
+			internal.DeleteHelmReleaseProxyMetrics(helmChartProxy)
+			internal.DeleteHelmChartProxyStatusMetrics(helmChartProxy)
+			internal.DeleteReconcileMetrics("HelmChartProxy", helmChartProxy.Namespace, helmChartProxy.Name)
+
 			// remove our finalizer from the list and update it.
 			controllerutil.RemoveFinalizer(helmChartProxy, addonsv1alpha1.HelmChartProxyFinalizer)
-			if err := patchHelmChartProxy(ctx, patchHelper, helmChartProxy); err != nil {
+			if err := patchHelmChartProxy(ctx, patchHelper, helmChartProxy, r.LightweightStatus); err != nil {
 				// TODO: Should we try to set the error here? If we can't remove the finalizer we likely can't update the status either.
 				return ctrl.Result{}, err
 			}
@@ -193,35 +633,112 @@ func (r *HelmChartProxyReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		return ctrl.Result{}, nil
 	}
 
+	if helmChartProxy.IsInDiscoveryMode() {
+		log.V(2).Info("HelmChartProxy is in discovery mode, discovering Helm releases", "helmChartProxy", helmChartProxy.Name)
+
+		return ctrl.Result{}, r.reconcileDiscovery(ctx, helmChartProxy, clusters)
+	}
+	helmChartProxy.Status.DiscoveredReleases = nil
+	conditions.Delete(helmChartProxy, addonsv1alpha1.ReleasesDiscoveredCondition)
+
 	log.V(2).Info("Reconciling HelmChartProxy", "randomName", helmChartProxy.Name)
-	res, err := r.reconcileNormal(ctx, helmChartProxy, clusterList.Items, releaseList.Items)
+	res, err := r.reconcileNormal(ctx, helmChartProxy, clusters, releaseList.Items)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 	conditions.MarkTrue(helmChartProxy, addonsv1alpha1.HelmReleaseProxySpecsUpToDateCondition)
 
+	if err := r.setOutOfDateReleases(ctx, helmChartProxy, clusters); err != nil {
+		log.Error(err, "failed to determine out of date HelmReleaseProxies", "helmChartProxy", helmChartProxy.Name)
+		return ctrl.Result{}, err
+	}
+
+	// Unreferenced values only take up space, so a failure to delete them does not fail the reconcile.
+	if err := r.deleteUnreferencedValuesConfigMaps(ctx, helmChartProxy); err != nil {
+		log.Error(err, "failed to delete unreferenced values ConfigMaps", "helmChartProxy", helmChartProxy.Name)
+	}
+
 	err = r.aggregateHelmReleaseProxyReadyCondition(ctx, helmChartProxy)
 	if err != nil {
 		log.Error(err, "failed to aggregate HelmReleaseProxy ready condition", "helmChartProxy", helmChartProxy.Name)
 		return ctrl.Result{}, err
 	}
 
+	// The post-rollout hooks run once every HelmReleaseProxy is rendered from the current generation and ready.
+	if isRolloutFinished(helmChartProxy) {
+		if succeeded, err := r.reconcileRolloutHooks(ctx, helmChartProxy, addonsv1alpha1.RolloutHookPhasePostRollout); err != nil || !succeeded {
+			return util.LowestNonZeroResult(res, ctrl.Result{RequeueAfter: rolloutVerificationRequeueInterval}), err
+		}
+	}
+
+	// Without changes, the HelmChartProxy is only reconciled again after the ReconcileInterval.
+	if interval := helmChartProxy.Spec.ReconcileInterval; interval != nil && interval.Duration > 0 {
+		res = util.LowestNonZeroResult(res, ctrl.Result{RequeueAfter: interval.Duration})
+	}
+
 	return res, nil
 }
 
+// isRolloutFinished returns true if the rollout of the HelmChartProxy completed and all of its HelmReleaseProxies are up
+// to date and ready.
+func isRolloutFinished(helmChartProxy *addonsv1alpha1.HelmChartProxy) bool {
+	return conditions.IsTrue(helmChartProxy, addonsv1alpha1.HelmReleaseProxiesRolloutCompletedCondition) &&
+		conditions.IsTrue(helmChartProxy, addonsv1alpha1.HelmReleaseProxySpecsUpToDateCondition) &&
+		conditions.IsTrue(helmChartProxy, addonsv1alpha1.HelmReleaseProxiesReadyCondition) &&
+		len(helmChartProxy.Status.OutOfDateReleases) == 0
+}
+
 // reconcileNormal handles the reconciliation of a HelmChartProxy when it is not being deleted. It takes a list of selected Clusters and HelmReleaseProxies
 // to uninstall the Helm chart from any Clusters that are no longer selected and to install or update the Helm chart on any Clusters that currently selected.
-func (r *HelmChartProxyReconciler) reconcileNormal(ctx context.Context, helmChartProxy *addonsv1alpha1.HelmChartProxy, clusters []clusterv1.Cluster, helmReleaseProxies []addonsv1alpha1.HelmReleaseProxy) (ctrl.Result, error) {
+func (r *HelmChartProxyReconciler) reconcileNormal(ctx context.Context, helmChartProxy *addonsv1alpha1.HelmChartProxy, clusters []clusterv1.Cluster, helmReleaseProxies []addonsv1alpha1.HelmReleaseProxy) (result ctrl.Result, reterr error) {
 	log := ctrl.LoggerFrom(ctx)
 
 	log.V(2).Info("Starting reconcileNormal for chart proxy", "name", helmChartProxy.Name, "strategy", helmChartProxy.Spec.ReconcileStrategy)
 
+	if r.WarmupCharts {
+		internal.WarmupChart(ctx, helmChartProxy.Spec, r.RegistryBreaker)
+	}
+
+	if r.ListChartVersions {
+		setChartVersions(ctx, helmChartProxy)
+	}
+
+	r.reconcileValuesFrom(ctx, helmChartProxy)
+
+	if err := r.reconcileRequestedCluster(ctx, helmChartProxy, clusters, helmReleaseProxies); err != nil {
+		return ctrl.Result{}, err
+	}
+
 	// If Reconcile strategy is not InstallOnce, delete orphaned HelmReleaseProxies
 	if helmChartProxy.Spec.ReconcileStrategy != string(addonsv1alpha1.ReconcileStrategyInstallOnce) {
-		err := r.deleteOrphanedHelmReleaseProxies(ctx, helmChartProxy, clusters, helmReleaseProxies)
+		deleteResult, err := r.deleteOrphanedHelmReleaseProxies(ctx, helmChartProxy, clusters, helmReleaseProxies)
 		if err != nil {
 			return ctrl.Result{}, err
 		}
+
+		// Requeue for the uninstall rollout even if the rest of the reconciliation does not.
+		defer func() {
+			if reterr == nil {
+				result = util.LowestNonZeroResult(result, deleteResult)
+			}
+		}()
+	}
+
+	// Clusters of environments that have not been promoted a version yet are left out until they are, but are still
+	// selected so that their HelmReleaseProxies are not deleted.
+	clusters = promotedClusters(helmChartProxy, clusters)
+
+	// Clusters to which a ClusterResourceSet already applies the addon are left out, but are still selected so that the
+	// HelmReleaseProxies of Clusters migrated from ClusterResourceSets are not deleted.
+	clusters, err := r.excludeClusterResourceSetClusters(ctx, helmChartProxy, clusters, helmReleaseProxies)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// The HelmReleaseProxies are only created or updated for a generation once its pre-rollout hooks succeeded. Jobs
+	// trigger a reconcile once they finish, while failed calls to URLs are retried after an interval.
+	if succeeded, err := r.reconcileRolloutHooks(ctx, helmChartProxy, addonsv1alpha1.RolloutHookPhasePreRollout); err != nil || !succeeded {
+		return ctrl.Result{RequeueAfter: rolloutVerificationRequeueInterval}, err
 	}
 
 	if helmChartProxy.Spec.Rollout == nil {
@@ -320,22 +837,58 @@ func (r *HelmChartProxyReconciler) rolloutReconcile(ctx context.Context, helmCha
 		rolloutCount = ptr.Deref(helmChartProxy.Status.Rollout.Count, rolloutCount)
 	}
 
+	// Remember whether the rollout was already stalled, so that the stall is only reported with an event once.
+	wasStalled := conditions.GetReason(helmChartProxy, addonsv1alpha1.HelmReleaseProxiesRolloutCompletedCondition) == addonsv1alpha1.RolloutProgressDeadlineExceededReason
+
+	// A halted rollout stays halted until it is resumed with the ResumeRolloutAnnotation.
+	wasHalted := conditions.GetReason(helmChartProxy, addonsv1alpha1.HelmReleaseProxiesRolloutCompletedCondition) == addonsv1alpha1.RolloutHaltedReason
+	if _, ok := helmChartProxy.GetAnnotations()[addonsv1alpha1.ResumeRolloutAnnotation]; ok {
+		if wasHalted {
+			log.Info("Resuming halted rollout", "name", helmChartProxy.Name)
+			wasHalted = false
+		}
+
+		annotations := helmChartProxy.GetAnnotations()
+		delete(annotations, addonsv1alpha1.ResumeRolloutAnnotation)
+		helmChartProxy.SetAnnotations(annotations)
+	}
+
+	// Remember whether the rollout was already awaiting approval, so that it is only reported with an event once.
+	wasAwaitingApproval := conditions.GetReason(helmChartProxy, addonsv1alpha1.HelmReleaseProxiesRolloutCompletedCondition) == addonsv1alpha1.RolloutAwaitingApprovalReason
+	// An approval given before the first batch is rolled out is kept until there is a rollout status to record it in, so
+	// that it approves the batch after the first one.
+	if _, ok := helmChartProxy.GetAnnotations()[addonsv1alpha1.ApproveRolloutAnnotation]; ok && helmChartProxy.Status.Rollout != nil {
+		log.Info("Approving next batch of rollout", "name", helmChartProxy.Name, "count", rolloutCount)
+		helmChartProxy.Status.Rollout.ApprovedCount = ptr.To(rolloutCount)
+
+		annotations := helmChartProxy.GetAnnotations()
+		delete(annotations, addonsv1alpha1.ApproveRolloutAnnotation)
+		helmChartProxy.SetAnnotations(annotations)
+	}
+
 	if len(clusters) == rolloutCount {
 		// RolloutStepSize is defined and all HelmReleaseProxies have been rolled out.
 		conditions.MarkTrue(helmChartProxy, addonsv1alpha1.HelmReleaseProxiesRolloutCompletedCondition)
+		if helmChartProxy.Status.Rollout != nil {
+			helmChartProxy.Status.Rollout.EstimatedCompletionTime = nil
+		}
 
 		return ctrl.Result{}, nil
 	}
 
 	// Set HelmReleaseProxiesRolloutCompletedCondition to false as
 	// HelmReleaseProxies are being rolled out.
+	message := fmt.Sprintf("%d Helm release proxies not yet rolled out", len(clusters)-rolloutCount)
+	if helmChartProxy.Status.Rollout != nil && helmChartProxy.Status.Rollout.EstimatedCompletionTime != nil {
+		message += fmt.Sprintf(", estimated completion at %s", helmChartProxy.Status.Rollout.EstimatedCompletionTime.UTC().Format(time.RFC3339))
+	}
 	conditions.MarkFalse(
 		helmChartProxy,
 		addonsv1alpha1.HelmReleaseProxiesRolloutCompletedCondition,
 		addonsv1alpha1.HelmReleaseProxiesRolloutNotCompleteReason,
 		clusterv1.ConditionSeverityInfo,
-		"%d Helm release proxies not yet rolled out",
-		len(clusters)-rolloutCount,
+		"%s",
+		message,
 	)
 
 	// Identifies clusters by their NamespacedName and gathers their
@@ -350,9 +903,15 @@ func (r *HelmChartProxyReconciler) rolloutReconcile(ctx context.Context, helmCha
 	for _, h := range helmReleaseProxies {
 		ref := h.Spec.ClusterRef
 		nn := getNamespacedNameStringFor(ref.Namespace, ref.Name)
-		meta := clusterNnRolloutMeta[nn]
+		meta, ok := clusterNnRolloutMeta[nn]
+		if !ok {
+			// The Cluster is no longer selected and its HelmReleaseProxy is waiting for the uninstall rollout.
+			continue
+		}
 		meta.hrpExists = true
 		meta.hrpReady = conditions.IsTrue(&h, addonsv1alpha1.HelmReleaseReadyCondition)
+		meta.hrpFailed = conditions.IsFalse(&h, addonsv1alpha1.HelmReleaseReadyCondition) &&
+			ptr.Deref(conditions.GetSeverity(&h, addonsv1alpha1.HelmReleaseReadyCondition), "") == clusterv1.ConditionSeverityError
 	}
 
 	// Sort helmReleaseProxy rollout metadata by cluster namespaced name to
@@ -381,6 +940,31 @@ func (r *HelmChartProxyReconciler) rolloutReconcile(ctx context.Context, helmCha
 		return 0
 	})
 
+	failed := countFailedHelmReleaseProxies(rolloutMetaSorted)
+	halted, err := isRolloutHalted(rolloutOptions.MaxFailures, failed, len(clusters))
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if wasHalted || halted {
+		log.Info("Rollout is halted; not proceeding to the next batch of HelmReleaseProxies", "name", helmChartProxy.Name, "failed", failed)
+		if !wasHalted {
+			r.Recorder.Eventf(helmChartProxy, corev1.EventTypeWarning, addonsv1alpha1.RolloutHaltedReason, "Rollout was halted after %d Helm releases failed", failed)
+		}
+		conditions.MarkFalse(helmChartProxy, addonsv1alpha1.HelmReleaseProxiesRolloutCompletedCondition, addonsv1alpha1.RolloutHaltedReason, clusterv1.ConditionSeverityError,
+			"Rollout was halted with %d failed Helm releases, set the %s annotation to resume it", failed, addonsv1alpha1.ResumeRolloutAnnotation)
+
+		// The HelmReleaseProxies already rolled out are still reconciled, so that a fix of the HelmChartProxy reaches them.
+		for _, meta := range rolloutMetaSorted {
+			if meta.hrpExists {
+				if err := r.reconcileForCluster(ctx, helmChartProxy, meta.cluster); err != nil {
+					return ctrl.Result{}, err
+				}
+			}
+		}
+
+		return ctrl.Result{}, nil
+	}
+
 	// If HelmReleaseProxiesReadyCondition is Unknown, create the first batch
 	// of HelmReleaseProxies and exit.
 	if conditions.IsUnknown(helmChartProxy, addonsv1alpha1.HelmReleaseProxiesReadyCondition) {
@@ -389,6 +973,7 @@ func (r *HelmChartProxyReconciler) rolloutReconcile(ctx context.Context, helmCha
 		}
 
 		count := 0
+		batch := []string{}
 		stepSize, err := intstr.GetScaledValueFromIntOrPercent(rolloutOptions.StepInit, len(clusters), true)
 		if err != nil {
 			return ctrl.Result{}, err
@@ -396,7 +981,9 @@ func (r *HelmChartProxyReconciler) rolloutReconcile(ctx context.Context, helmCha
 
 		defer func() {
 			log.V(2).Info("Updating rollout status", "name", helmChartProxy.Name, "HelmReleaseProxiesReadyCondition", corev1.ConditionUnknown, "count", count, "stepSize", stepSize)
-			helmChartProxy.Status.Rollout = &addonsv1alpha1.RolloutStatus{Count: ptr.To(count), StepSize: ptr.To(stepSize)}
+			setRolloutStatus(helmChartProxy, count, stepSize)
+			setRolloutLastBatch(helmChartProxy, batch)
+			setRolloutEstimatedCompletion(helmChartProxy, rolloutOptions, len(clusters))
 		}()
 
 		// If HelmReleaseProxiesReadyCondition is Unknown and the first batch of HelmReleaseProxies have
@@ -405,18 +992,26 @@ func (r *HelmChartProxyReconciler) rolloutReconcile(ctx context.Context, helmCha
 			return ctrl.Result{Requeue: true}, nil
 		}
 
+		quota := newFailureDomainQuota(rolloutOptions)
 		for _, meta := range rolloutMetaSorted {
 			// The first batch of helmReleaseProxies have been reconciled.
 			if count >= stepSize {
 				return ctrl.Result{Requeue: true}, nil
 			}
 
+			// Leave the cluster to a later batch if its failure domain is already full in this one.
+			if !quota.allows(meta.cluster) {
+				continue
+			}
+			quota.add(meta.cluster)
+
 			err := r.reconcileForCluster(ctx, helmChartProxy, meta.cluster)
 			log.V(2).Info("Reconciling for cluster", "name", helmChartProxy.Name, "HelmReleaseProxiesReadyCondition", corev1.ConditionUnknown, "cluster", meta.cluster.Name)
 			if err != nil {
 				return ctrl.Result{}, err
 			}
 			count++
+			batch = append(batch, meta.cluster.Name)
 		}
 
 		// In cases where the count of remaining HelmReleaseProxies to be rolled
@@ -429,6 +1024,14 @@ func (r *HelmChartProxyReconciler) rolloutReconcile(ctx context.Context, helmCha
 	if conditions.IsFalse(helmChartProxy, addonsv1alpha1.HelmReleaseProxiesReadyCondition) {
 		log.V(2).Info("HelmReleaseProxiesReady condition false; reconciling existing HelmReleaseProxies", "name", helmChartProxy.Name)
 
+		if deadline := helmChartProxy.Spec.Rollout.ProgressDeadline; deadline != nil && isRolloutStalled(helmChartProxy.Status.Rollout, deadline.Duration) {
+			log.Info("Rollout has not progressed within the progress deadline", "name", helmChartProxy.Name, "progressDeadline", deadline.Duration)
+			if !wasStalled {
+				r.Recorder.Eventf(helmChartProxy, corev1.EventTypeWarning, addonsv1alpha1.RolloutProgressDeadlineExceededReason, "Rollout has not progressed within the progress deadline of %s", deadline.Duration)
+			}
+			conditions.MarkFalse(helmChartProxy, addonsv1alpha1.HelmReleaseProxiesRolloutCompletedCondition, addonsv1alpha1.RolloutProgressDeadlineExceededReason, clusterv1.ConditionSeverityError, "Rollout has not progressed within the progress deadline of %s", deadline.Duration)
+		}
+
 		for _, meta := range rolloutMetaSorted {
 			if meta.hrpExists {
 				err := r.reconcileForCluster(ctx, helmChartProxy, meta.cluster)
@@ -442,6 +1045,47 @@ func (r *HelmChartProxyReconciler) rolloutReconcile(ctx context.Context, helmCha
 		return ctrl.Result{Requeue: true}, nil
 	}
 
+	if verification := helmChartProxy.Spec.Rollout.Verification; verification != nil {
+		failed, err := r.RolloutVerifier.VerifyRollout(ctx, verification)
+		if err != nil {
+			conditions.MarkFalse(helmChartProxy, addonsv1alpha1.HelmReleaseProxiesRolloutCompletedCondition, addonsv1alpha1.RolloutVerificationFailedReason, clusterv1.ConditionSeverityError, "%s", err.Error())
+
+			return ctrl.Result{}, err
+		}
+
+		if failed != "" {
+			log.Info("Rollout verification failed; not proceeding to the next batch of HelmReleaseProxies", "name", helmChartProxy.Name, "failed", failed)
+			conditions.MarkFalse(helmChartProxy, addonsv1alpha1.HelmReleaseProxiesRolloutCompletedCondition, addonsv1alpha1.RolloutVerificationFailedReason, clusterv1.ConditionSeverityWarning, "Rollout verification failed: %s", failed)
+
+			return ctrl.Result{RequeueAfter: rolloutVerificationRequeueInterval}, nil
+		}
+	}
+
+	if helmChartProxy.Spec.Rollout.ReleaseCheck != nil {
+		if failed := r.checkLastBatchReleases(ctx, helmChartProxy, helmReleaseProxies); failed != "" {
+			log.Info("Release check failed; not proceeding to the next batch of HelmReleaseProxies", "name", helmChartProxy.Name, "failed", failed)
+			conditions.MarkFalse(helmChartProxy, addonsv1alpha1.HelmReleaseProxiesRolloutCompletedCondition, addonsv1alpha1.RolloutReleaseCheckFailedReason, clusterv1.ConditionSeverityWarning, "Release check failed: %s", failed)
+
+			return ctrl.Result{RequeueAfter: rolloutVerificationRequeueInterval}, nil
+		}
+	}
+
+	if rolloutOptions.ApprovalRequired && !isNextBatchApproved(helmChartProxy.Status.Rollout, rolloutCount) {
+		// Wait for the HelmReleaseProxies of the current batch to be ready before asking for approval of the next one.
+		if slices.ContainsFunc(rolloutMetaSorted, func(meta *helmReleaseProxyRolloutMeta) bool { return meta.hrpExists && !meta.hrpReady }) {
+			return ctrl.Result{Requeue: true}, nil
+		}
+
+		log.Info("Rollout is awaiting approval; not proceeding to the next batch of HelmReleaseProxies", "name", helmChartProxy.Name, "count", rolloutCount)
+		if !wasAwaitingApproval {
+			r.Recorder.Eventf(helmChartProxy, corev1.EventTypeNormal, addonsv1alpha1.RolloutAwaitingApprovalReason, "Rollout is awaiting approval of the next batch after %d Helm release proxies", rolloutCount)
+		}
+		conditions.MarkFalse(helmChartProxy, addonsv1alpha1.HelmReleaseProxiesRolloutCompletedCondition, addonsv1alpha1.RolloutAwaitingApprovalReason, clusterv1.ConditionSeverityInfo,
+			"Rollout is awaiting approval of the next batch after %d Helm release proxies, set the %s annotation to approve it", rolloutCount, addonsv1alpha1.ApproveRolloutAnnotation)
+
+		return ctrl.Result{}, nil
+	}
+
 	log.V(2).Info("HelmReleaseProxiesReady condition true; proceeding to reconcile the next batch of HelmReleaseProxies", "name", helmChartProxy.Name)
 	// HelmReleaseProxyReadyCondition is True; continue with reconciling the
 	// next batch of HelmReleaseProxies.
@@ -451,7 +1095,6 @@ func (r *HelmChartProxyReconciler) rolloutReconcile(ctx context.Context, helmCha
 	}
 
 	var stepIncrement int
-	var err error
 	if rolloutOptions.StepIncrement != nil {
 		stepIncrement, err = intstr.GetScaledValueFromIntOrPercent(rolloutOptions.StepIncrement, len(clusters), true)
 		if err != nil {
@@ -475,12 +1118,10 @@ func (r *HelmChartProxyReconciler) rolloutReconcile(ctx context.Context, helmCha
 		}
 	}
 
-	stepSize := oldStepSize + stepIncrement
-	if stepLimit > stepInit && stepSize > stepLimit {
-		stepSize = stepLimit
-	}
+	stepSize := nextRolloutStepSize(oldStepSize, stepIncrement, stepInit, stepLimit)
 
 	count := 0
+	batch := []string{}
 	defer func() {
 		var oldCount int
 		if helmChartProxy.Status.Rollout != nil {
@@ -488,9 +1129,12 @@ func (r *HelmChartProxyReconciler) rolloutReconcile(ctx context.Context, helmCha
 		}
 		newCount := oldCount + count
 		log.V(2).Info("Updating rollout status", "name", helmChartProxy.Name, "HelmReleaseProxiesReadyCondition", corev1.ConditionTrue, "count", newCount, "stepSize", stepSize)
-		helmChartProxy.Status.Rollout = &addonsv1alpha1.RolloutStatus{Count: ptr.To(newCount), StepSize: ptr.To(stepSize)}
+		setRolloutStatus(helmChartProxy, newCount, stepSize)
+		setRolloutLastBatch(helmChartProxy, batch)
+		setRolloutEstimatedCompletion(helmChartProxy, rolloutOptions, len(clusters))
 	}()
 
+	quota := newFailureDomainQuota(rolloutOptions)
 	for _, meta := range rolloutMetaSorted {
 		// Exit if HelmReleaseProxyReadyCondition has not caught up to existing
 		// HelmReleaseProxies status.
@@ -507,12 +1151,19 @@ func (r *HelmChartProxyReconciler) rolloutReconcile(ctx context.Context, helmCha
 		if meta.hrpExists {
 			continue
 		}
+
+		// Leave the cluster to a later batch if its failure domain is already full in this one.
+		if !quota.allows(meta.cluster) {
+			continue
+		}
+		quota.add(meta.cluster)
 		err := r.reconcileForCluster(ctx, helmChartProxy, meta.cluster)
 		log.V(2).Info("Reconciling for cluster", "name", helmChartProxy.Name, "HelmReleaseProxiesReadyCondition", corev1.ConditionTrue, "cluster", meta.cluster.Name)
 		if err != nil {
 			return ctrl.Result{}, err
 		}
 		count++
+		batch = append(batch, meta.cluster.Name)
 	}
 
 	// In cases where the count of remaining HelmReleaseProxies to be rolled
@@ -520,6 +1171,40 @@ func (r *HelmChartProxyReconciler) rolloutReconcile(ctx context.Context, helmCha
 	return ctrl.Result{Requeue: true}, nil
 }
 
+// failureDomainQuota limits the number of Clusters of each failure domain that are rolled out in a single batch.
+type failureDomainQuota struct {
+	label  string
+	max    int
+	counts map[string]int
+}
+
+// newFailureDomainQuota returns the failureDomainQuota for the rollout options. If no FailureDomainLabel is defined, the
+// quota allows every Cluster.
+func newFailureDomainQuota(rolloutOptions *addonsv1alpha1.RolloutOptions) *failureDomainQuota {
+	return &failureDomainQuota{
+		label:  rolloutOptions.FailureDomainLabel,
+		max:    int(ptr.Deref(rolloutOptions.MaxPerFailureDomain, 1)),
+		counts: map[string]int{},
+	}
+}
+
+// allows returns true if the Cluster can be added to the current batch without exceeding the quota of its failure domain.
+func (q *failureDomainQuota) allows(cluster clusterv1.Cluster) bool {
+	domain, ok := cluster.Labels[q.label]
+	if q.label == "" || !ok {
+		return true
+	}
+
+	return q.counts[domain] < q.max
+}
+
+// add counts the Cluster against the quota of its failure domain.
+func (q *failureDomainQuota) add(cluster clusterv1.Cluster) {
+	if domain, ok := cluster.Labels[q.label]; q.label != "" && ok {
+		q.counts[domain]++
+	}
+}
+
 // reconcileDelete handles the deletion of a HelmChartProxy. It takes a list of HelmReleaseProxies to uninstall the Helm chart from all selected Clusters.
 func (r *HelmChartProxyReconciler) reconcileDelete(ctx context.Context, helmChartProxy *addonsv1alpha1.HelmChartProxy, releases []addonsv1alpha1.HelmReleaseProxy) (ctrl.Result, error) {
 	log := ctrl.LoggerFrom(ctx)
@@ -557,7 +1242,22 @@ func (r *HelmChartProxyReconciler) reconcileDelete(ctx context.Context, helmChar
 	return ctrl.Result{}, nil
 }
 
-// listClustersWithLabels returns a list of Clusters that match the given label selector.
+// clusterSelectorFor returns the ClusterSelector of the HelmChartProxy, additionally requiring the watch filter label of
+// its ClusterWatchFilterValue if it is set.
+func clusterSelectorFor(helmChartProxy *addonsv1alpha1.HelmChartProxy) metav1.LabelSelector {
+	selector := helmChartProxy.Spec.ClusterSelector.DeepCopy()
+	if value := helmChartProxy.Spec.ClusterWatchFilterValue; value != "" {
+		if selector.MatchLabels == nil {
+			selector.MatchLabels = map[string]string{}
+		}
+		selector.MatchLabels[clusterv1.WatchLabel] = value
+	}
+
+	return *selector
+}
+
+// listClustersWithLabels returns a list of Clusters that match the given label selector. Clusters being deleted are left
+// out, as no Helm release can be reconciled on them, so that their HelmReleaseProxies are deleted along with them.
 func (r *HelmChartProxyReconciler) listClustersWithLabels(ctx context.Context, namespace string, selector metav1.LabelSelector) (*clusterv1.ClusterList, error) {
 	clusterList := &clusterv1.ClusterList{}
 	// To support for the matchExpressions field, convert LabelSelector to labels.Selector to specify labels.Selector for ListOption. (Issue #15)
@@ -570,6 +1270,10 @@ func (r *HelmChartProxyReconciler) listClustersWithLabels(ctx context.Context, n
 		return nil, err
 	}
 
+	clusterList.Items = slices.DeleteFunc(clusterList.Items, func(cluster clusterv1.Cluster) bool {
+		return !cluster.DeletionTimestamp.IsZero()
+	})
+
 	return clusterList, nil
 }
 
@@ -600,6 +1304,8 @@ func (r *HelmChartProxyReconciler) aggregateHelmReleaseProxyReadyCondition(ctx c
 		return err
 	}
 
+	internal.RecordHelmReleaseProxyMetrics(helmChartProxy, releaseList.Items)
+
 	if len(releaseList.Items) == 0 {
 		// Consider it to be vacuously true if there are no releases. This should only be reached if we previously had HelmReleaseProxies but they were all deleted
 		// due to the Clusters being unselected. In that case, we should consider the condition to be true.
@@ -617,22 +1323,129 @@ func (r *HelmChartProxyReconciler) aggregateHelmReleaseProxyReadyCondition(ctx c
 		getters = append(getters, helmReleaseProxy)
 	}
 
-	conditions.SetAggregate(helmChartProxy, addonsv1alpha1.HelmReleaseProxiesReadyCondition, getters, conditions.AddSourceRef(), conditions.WithStepCounterIf(false))
+	if aggregation := helmChartProxy.Spec.ConditionAggregation; aggregation != nil {
+		getters = aggregationGetters(releaseList.Items, aggregation, true)
+
+		if healthGetters := aggregationGetters(releaseList.Items, aggregation, false); healthGetters != nil {
+			conditions.SetAggregate(helmChartProxy, addonsv1alpha1.HelmReleaseProxiesHealthyCondition, healthGetters, conditions.AddSourceRef())
+		} else {
+			conditions.Delete(helmChartProxy, addonsv1alpha1.HelmReleaseProxiesHealthyCondition)
+		}
+	} else {
+		conditions.Delete(helmChartProxy, addonsv1alpha1.HelmReleaseProxiesHealthyCondition)
+	}
+
+	threshold := helmChartProxy.Spec.ReadinessThreshold
+	if threshold != nil {
+		ready := 0
+		for _, getter := range getters {
+			if conditions.IsTrue(getter, clusterv1.ReadyCondition) {
+				ready++
+			}
+		}
+
+		required, err := intstr.GetScaledValueFromIntOrPercent(threshold, len(releaseList.Items), true)
+		if err != nil {
+			return errors.Wrapf(err, "failed to scale readiness threshold %s", threshold.String())
+		}
+
+		if ready < len(releaseList.Items) && ready >= required {
+			conditions.Set(helmChartProxy, &clusterv1.Condition{
+				Type:    addonsv1alpha1.HelmReleaseProxiesReadyCondition,
+				Status:  corev1.ConditionTrue,
+				Reason:  addonsv1alpha1.ReadinessThresholdMetReason,
+				Message: fmt.Sprintf("%d of %d HelmReleaseProxies are ready, meeting the readiness threshold of %s", ready, len(releaseList.Items), threshold.String()),
+			})
+
+			return nil
+		}
+	}
+
+	// With a readiness threshold, the message of a false condition counts the ready HelmReleaseProxies as well.
+	conditions.SetAggregate(helmChartProxy, addonsv1alpha1.HelmReleaseProxiesReadyCondition, getters, conditions.AddSourceRef(), conditions.WithStepCounterIf(threshold != nil))
 
 	return nil
 }
 
+// aggregationGetters returns a copy of each HelmReleaseProxy whose Ready condition is replaced by the aggregation of its
+// conditions in the ConditionAggregation with the blocking Error severity, or with a non-blocking severity if blocking
+// is false. Without blocking conditions every HelmReleaseProxy is ready, while nil is returned without non-blocking ones.
+func aggregationGetters(helmReleaseProxies []addonsv1alpha1.HelmReleaseProxy, aggregation *addonsv1alpha1.ConditionAggregation, blocking bool) []conditions.Getter {
+	aggregated := []addonsv1alpha1.AggregatedCondition{}
+	for _, c := range aggregation.Conditions {
+		if c.Severity == "" {
+			c.Severity = clusterv1.ConditionSeverityError
+		}
+		if (c.Severity == clusterv1.ConditionSeverityError) == blocking {
+			aggregated = append(aggregated, c)
+		}
+	}
+	if len(aggregated) == 0 && !blocking {
+		return nil
+	}
+
+	getters := make([]conditions.Getter, 0, len(helmReleaseProxies))
+	for i := range helmReleaseProxies {
+		helmReleaseProxy := helmReleaseProxies[i].DeepCopy()
+		helmReleaseProxy.SetConditions(clusterv1.Conditions{*aggregatedReadyCondition(helmReleaseProxy, aggregated)})
+		getters = append(getters, helmReleaseProxy)
+	}
+
+	return getters
+}
+
+// aggregatedReadyCondition returns the Ready condition of the HelmReleaseProxy from the aggregated conditions. It is false
+// with the severity of the first condition signaling a problem, unknown if a condition is unknown, and true otherwise.
+func aggregatedReadyCondition(helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy, aggregated []addonsv1alpha1.AggregatedCondition) *clusterv1.Condition {
+	var unknown *clusterv1.Condition
+	for _, c := range aggregated {
+		condition := conditions.Get(helmReleaseProxy, c.Type)
+		if condition == nil {
+			continue
+		}
+
+		problem := corev1.ConditionFalse
+		if c.Polarity == string(addonsv1alpha1.ConditionPolarityNegative) {
+			problem = corev1.ConditionTrue
+		}
+
+		switch {
+		case condition.Status == problem:
+			reason := condition.Reason
+			if reason == "" {
+				reason = string(c.Type)
+			}
+
+			return conditions.FalseCondition(clusterv1.ReadyCondition, reason, c.Severity, "%s", condition.Message)
+		case condition.Status == corev1.ConditionUnknown && unknown == nil:
+			unknown = conditions.UnknownCondition(clusterv1.ReadyCondition, condition.Reason, "%s", condition.Message)
+		}
+	}
+	if unknown != nil {
+		return unknown
+	}
+
+	return conditions.TrueCondition(clusterv1.ReadyCondition)
+}
+
 // patchHelmChartProxy patches the HelmChartProxy object and sets the ReadyCondition as an aggregate of the other condition set.
 // TODO: Is this preferable to client.Update() calls? Based on testing it seems like it avoids race conditions.
-func patchHelmChartProxy(ctx context.Context, patchHelper *patch.Helper, helmChartProxy *addonsv1alpha1.HelmChartProxy) error {
+func patchHelmChartProxy(ctx context.Context, patchHelper *patch.Helper, helmChartProxy *addonsv1alpha1.HelmChartProxy, lightweightStatus bool) error {
 	conditions.SetSummary(helmChartProxy,
 		conditions.WithConditions(
+			addonsv1alpha1.ValuesReadyCondition,
 			addonsv1alpha1.HelmReleaseProxySpecsUpToDateCondition,
 			addonsv1alpha1.HelmReleaseProxiesReadyCondition,
 			addonsv1alpha1.HelmReleaseProxiesRolloutCompletedCondition,
 		),
 	)
 
+	// A copy is compacted, so that the rest of the reconcile still sees the full status.
+	if lightweightStatus {
+		helmChartProxy = helmChartProxy.DeepCopy()
+		compactStatus(helmChartProxy)
+	}
+
 	// Patch the object, ignoring conflicts on the conditions owned by this controller.
 	return patchHelper.Patch(
 		ctx,
@@ -642,11 +1455,33 @@ func patchHelmChartProxy(ctx context.Context, patchHelper *patch.Helper, helmCha
 			addonsv1alpha1.HelmReleaseProxySpecsUpToDateCondition,
 			addonsv1alpha1.HelmReleaseProxiesReadyCondition,
 			addonsv1alpha1.HelmReleaseProxiesRolloutCompletedCondition,
+			addonsv1alpha1.HelmReleaseProxiesHealthyCondition,
+			addonsv1alpha1.ReleasesDiscoveredCondition,
+			addonsv1alpha1.UninstallsConfirmedCondition,
+			addonsv1alpha1.ReconciledRecentlyCondition,
+			addonsv1alpha1.ValuesReadyCondition,
 		}},
 		patch.WithStatusObservedGeneration{},
 	)
 }
 
+// compactStatus omits the details of the status of the HelmChartProxy that grow with the number of selected Clusters, and
+// the conditions that are true without a severity other than the Ready condition, as they are summarized by it. The
+// number of matching Clusters is kept.
+func compactStatus(helmChartProxy *addonsv1alpha1.HelmChartProxy) {
+	helmChartProxy.Status.MatchingClusters = nil
+	helmChartProxy.Status.OutOfDateReleases = nil
+	helmChartProxy.Status.ClusterOperations = nil
+
+	compacted := clusterv1.Conditions{}
+	for _, condition := range helmChartProxy.GetConditions() {
+		if condition.Type == clusterv1.ReadyCondition || condition.Status != corev1.ConditionTrue || condition.Severity != clusterv1.ConditionSeverityNone {
+			compacted = append(compacted, condition)
+		}
+	}
+	helmChartProxy.SetConditions(compacted)
+}
+
 // ClusterToHelmChartProxiesMapper is a mapper function that maps a Cluster to the HelmChartProxies that would select the Cluster.
 func (r *HelmChartProxyReconciler) ClusterToHelmChartProxiesMapper(ctx context.Context, o client.Object) []ctrl.Request {
 	log := ctrl.LoggerFrom(ctx)
@@ -668,7 +1503,16 @@ func (r *HelmChartProxyReconciler) ClusterToHelmChartProxiesMapper(ctx context.C
 
 	results := []ctrl.Request{}
 	for _, helmChartProxy := range helmChartProxies.Items {
-		selector, err := metav1.LabelSelectorAsSelector(&helmChartProxy.Spec.ClusterSelector)
+		// Clusters of other watch filters only trigger HelmChartProxies reconciled by this controller that select them.
+		if r.WatchFilterValue != "" && helmChartProxy.Labels[clusterv1.WatchLabel] != r.WatchFilterValue {
+			continue
+		}
+		if helmChartProxy.Spec.ClusterWatchFilterValue == "" && r.WatchFilterValue != "" && cluster.Labels[clusterv1.WatchLabel] != r.WatchFilterValue {
+			continue
+		}
+
+		clusterSelector := clusterSelectorFor(&helmChartProxy)
+		selector, err := metav1.LabelSelectorAsSelector(&clusterSelector)
 		if err != nil {
 			// Suppress the error for now
 			log.Error(err, "failed to parse ClusterSelector for HelmChartProxy", "helmChartProxy", helmChartProxy.Name)
@@ -686,6 +1530,111 @@ func (r *HelmChartProxyReconciler) ClusterToHelmChartProxiesMapper(ctx context.C
 	return results
 }
 
+// TemplateLibraryToHelmChartProxiesMapper is a mapper function that maps a template library ConfigMap to all HelmChartProxies
+// in its namespace. This is used to re-render the values of the HelmChartProxies when a template partial changes.
+func (r *HelmChartProxyReconciler) TemplateLibraryToHelmChartProxiesMapper(ctx context.Context, o client.Object) []ctrl.Request {
+	if o.GetLabels()[addonsv1alpha1.TemplateLibraryLabelName] != "true" {
+		return nil
+	}
+
+	helmChartProxies := &addonsv1alpha1.HelmChartProxyList{}
+	if err := r.List(ctx, helmChartProxies, client.InNamespace(o.GetNamespace())); err != nil {
+		return nil
+	}
+
+	results := []ctrl.Request{}
+	for _, helmChartProxy := range helmChartProxies.Items {
+		results = append(results, ctrl.Request{
+			NamespacedName: client.ObjectKey{Namespace: helmChartProxy.Namespace, Name: helmChartProxy.Name},
+		})
+	}
+
+	return results
+}
+
+// ProxySettingsToHelmChartProxiesMapper is a mapper function that maps a proxy settings ConfigMap to the HelmChartProxies
+// with ProxyValues in its namespace, if a Cluster refers to it. This is used to re-render the values of the HelmChartProxies
+// when the proxy settings or trust bundle change.
+func (r *HelmChartProxyReconciler) ProxySettingsToHelmChartProxiesMapper(ctx context.Context, o client.Object) []ctrl.Request {
+	clusters := &clusterv1.ClusterList{}
+	if err := r.List(ctx, clusters, client.InNamespace(o.GetNamespace()), client.MatchingLabels{addonsv1alpha1.ProxySettingsLabelName: o.GetName()}); err != nil {
+		return nil
+	}
+	if len(clusters.Items) == 0 {
+		return nil
+	}
+
+	helmChartProxies := &addonsv1alpha1.HelmChartProxyList{}
+	if err := r.List(ctx, helmChartProxies, client.InNamespace(o.GetNamespace())); err != nil {
+		return nil
+	}
+
+	results := []ctrl.Request{}
+	for _, helmChartProxy := range helmChartProxies.Items {
+		if helmChartProxy.Spec.ProxyValues != nil {
+			results = append(results, ctrl.Request{
+				NamespacedName: client.ObjectKey{Namespace: helmChartProxy.Namespace, Name: helmChartProxy.Name},
+			})
+		}
+	}
+
+	return results
+}
+
+// GlobalPauseToHelmChartProxiesMapper is a mapper function that maps the global pause ConfigMap to all HelmChartProxies of
+// this controller, so that the changes held back during the pause are rolled out as soon as it is lifted.
+func (r *HelmChartProxyReconciler) GlobalPauseToHelmChartProxiesMapper(ctx context.Context, o client.Object) []ctrl.Request {
+	if !r.GlobalPause.IsConfigMap(o) {
+		return nil
+	}
+
+	helmChartProxies := &addonsv1alpha1.HelmChartProxyList{}
+	if err := r.List(ctx, helmChartProxies); err != nil {
+		return nil
+	}
+
+	results := []ctrl.Request{}
+	for _, helmChartProxy := range helmChartProxies.Items {
+		if r.WatchFilterValue != "" && helmChartProxy.Labels[clusterv1.WatchLabel] != r.WatchFilterValue {
+			continue
+		}
+		results = append(results, ctrl.Request{
+			NamespacedName: client.ObjectKey{Namespace: helmChartProxy.Namespace, Name: helmChartProxy.Name},
+		})
+	}
+
+	return results
+}
+
+// ClusterClassToHelmChartProxiesMapper is a mapper function that maps a ClusterClass to the HelmChartProxies selecting the
+// Clusters using it. This is used to re-render the values of the HelmChartProxies when the variables or defaults of the
+// ClusterClass change, as those do not change the Clusters themselves.
+func (r *HelmChartProxyReconciler) ClusterClassToHelmChartProxiesMapper(ctx context.Context, o client.Object) []ctrl.Request {
+	// Clusters may use a ClusterClass from another namespace, so Clusters of all namespaces are considered.
+	clusters := &clusterv1.ClusterList{}
+	if err := r.List(ctx, clusters); err != nil {
+		return nil
+	}
+
+	classKey := client.ObjectKeyFromObject(o)
+	seen := map[client.ObjectKey]bool{}
+	results := []ctrl.Request{}
+	for i := range clusters.Items {
+		if clusters.Items[i].GetClassKey() != classKey {
+			continue
+		}
+
+		for _, request := range r.ClusterToHelmChartProxiesMapper(ctx, &clusters.Items[i]) {
+			if !seen[request.NamespacedName] {
+				seen[request.NamespacedName] = true
+				results = append(results, request)
+			}
+		}
+	}
+
+	return results
+}
+
 // HelmReleaseProxyToHelmChartProxyMapper is a mapper function that maps a HelmReleaseProxy to the HelmChartProxy that owns it.
 // This is used to trigger an update of the HelmChartProxy when a HelmReleaseProxy is changed.
 func HelmReleaseProxyToHelmChartProxyMapper(ctx context.Context, o client.Object) []ctrl.Request {
@@ -0,0 +1,90 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helmchartproxy
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// withChartSourceDefaults returns the HelmChartProxy with the credentials, TLS configuration and proxy of the
+// ChartSourceDefaults of its namespace filled in where the HelmChartProxy does not specify them. The HelmChartProxy is
+// returned unchanged if its namespace has no ChartSourceDefaults, and copied otherwise so that the defaults are never
+// persisted in its spec.
+func (r *HelmChartProxyReconciler) withChartSourceDefaults(ctx context.Context, helmChartProxy *addonsv1alpha1.HelmChartProxy) (*addonsv1alpha1.HelmChartProxy, error) {
+	defaults := &addonsv1alpha1.ChartSourceDefaults{}
+	key := client.ObjectKey{Namespace: helmChartProxy.Namespace, Name: addonsv1alpha1.ChartSourceDefaultsName}
+	if err := r.Get(ctx, key, defaults); err != nil {
+		if apierrors.IsNotFound(err) {
+			return helmChartProxy, nil
+		}
+
+		return nil, errors.Wrapf(err, "failed to get ChartSourceDefaults of namespace %s", helmChartProxy.Namespace)
+	}
+
+	return applyChartSourceDefaults(helmChartProxy, &defaults.Spec), nil
+}
+
+// applyChartSourceDefaults returns a copy of the HelmChartProxy with the defaults filled in where it does not specify them.
+// Each field is defaulted as a whole, e.g. a HelmChartProxy with its own TLSConfig does not inherit the CA of the defaults.
+func applyChartSourceDefaults(helmChartProxy *addonsv1alpha1.HelmChartProxy, defaults *addonsv1alpha1.ChartSourceDefaultsSpec) *addonsv1alpha1.HelmChartProxy {
+	defaulted := helmChartProxy.DeepCopy()
+	if defaulted.Spec.Credentials == nil {
+		defaulted.Spec.Credentials = defaults.Credentials.DeepCopy()
+	}
+	if defaulted.Spec.RepositoryHeaders == nil {
+		defaulted.Spec.RepositoryHeaders = defaults.RepositoryHeaders.DeepCopy()
+	}
+	if defaulted.Spec.RepositoryCredentials == nil {
+		defaulted.Spec.RepositoryCredentials = defaults.RepositoryCredentials.DeepCopy()
+	}
+	if defaulted.Spec.TLSConfig == nil {
+		defaulted.Spec.TLSConfig = defaults.TLSConfig.DeepCopy()
+	}
+	if defaulted.Spec.ProxyURL == "" {
+		defaulted.Spec.ProxyURL = defaults.ProxyURL
+	}
+
+	return defaulted
+}
+
+// ChartSourceDefaultsToHelmChartProxiesMapper is a mapper function that maps a ChartSourceDefaults to the HelmChartProxies
+// in its namespace. This is used to update the HelmReleaseProxies when the defaults change.
+func (r *HelmChartProxyReconciler) ChartSourceDefaultsToHelmChartProxiesMapper(ctx context.Context, o client.Object) []ctrl.Request {
+	if o.GetName() != addonsv1alpha1.ChartSourceDefaultsName {
+		return nil
+	}
+
+	helmChartProxies := &addonsv1alpha1.HelmChartProxyList{}
+	if err := r.List(ctx, helmChartProxies, client.InNamespace(o.GetNamespace())); err != nil {
+		return nil
+	}
+
+	results := []ctrl.Request{}
+	for _, helmChartProxy := range helmChartProxies.Items {
+		results = append(results, ctrl.Request{
+			NamespacedName: client.ObjectKey{Namespace: helmChartProxy.Namespace, Name: helmChartProxy.Name},
+		})
+	}
+
+	return results
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helmchartproxy
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestWithChartSourceDefaults(t *testing.T) {
+	g := NewWithT(t)
+
+	defaults := &addonsv1alpha1.ChartSourceDefaults{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      addonsv1alpha1.ChartSourceDefaultsName,
+			Namespace: "test-namespace",
+		},
+		Spec: addonsv1alpha1.ChartSourceDefaultsSpec{
+			Credentials:           &addonsv1alpha1.Credentials{Secret: corev1.SecretReference{Name: "oci-credentials"}},
+			RepositoryCredentials: &corev1.SecretReference{Name: "repository-credentials"},
+			TLSConfig:             &addonsv1alpha1.TLSConfig{CASecretRef: &corev1.SecretReference{Name: "default-ca"}},
+			ProxyURL:              "http://proxy.corp.local:3128",
+		},
+	}
+	helmChartProxy := &addonsv1alpha1.HelmChartProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-hcp",
+			Namespace: "test-namespace",
+		},
+		Spec: addonsv1alpha1.HelmChartProxySpec{
+			TLSConfig: &addonsv1alpha1.TLSConfig{InsecureSkipTLSVerify: true},
+		},
+	}
+
+	r := &HelmChartProxyReconciler{
+		Client: fake.NewClientBuilder().WithScheme(fakeScheme).Build(),
+	}
+	defaulted, err := r.withChartSourceDefaults(ctx, helmChartProxy)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(defaulted).To(BeIdenticalTo(helmChartProxy), "HelmChartProxies are unchanged without ChartSourceDefaults")
+
+	r.Client = fake.NewClientBuilder().WithScheme(fakeScheme).WithObjects(defaults).Build()
+	defaulted, err = r.withChartSourceDefaults(ctx, helmChartProxy)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(defaulted.Spec.Credentials).To(Equal(defaults.Spec.Credentials))
+	g.Expect(defaulted.Spec.RepositoryCredentials).To(Equal(defaults.Spec.RepositoryCredentials))
+	g.Expect(defaulted.Spec.RepositoryHeaders).To(BeNil())
+	g.Expect(defaulted.Spec.TLSConfig).To(Equal(&addonsv1alpha1.TLSConfig{InsecureSkipTLSVerify: true}), "fields of the HelmChartProxy override the defaults")
+	g.Expect(defaulted.Spec.ProxyURL).To(Equal("http://proxy.corp.local:3128"))
+	g.Expect(helmChartProxy.Spec.Credentials).To(BeNil(), "the defaults are not persisted in the HelmChartProxy")
+	g.Expect(helmChartProxy.Spec.ProxyURL).To(BeEmpty())
+
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", Namespace: "test-namespace"}}
+	helmReleaseProxy := constructHelmReleaseProxy(nil, defaulted, "", cluster)
+	g.Expect(helmReleaseProxy.Spec.Credentials).To(Equal(&addonsv1alpha1.Credentials{
+		Secret: corev1.SecretReference{Name: "oci-credentials", Namespace: "test-namespace"},
+		Key:    addonsv1alpha1.DefaultOCIKey,
+	}))
+	g.Expect(helmReleaseProxy.Spec.ProxyURL).To(Equal("http://proxy.corp.local:3128"))
+	g.Expect(hasHelmReleaseProxySpecChanged(helmReleaseProxy, defaulted, "", cluster)).To(BeFalse())
+	g.Expect(hasHelmReleaseProxySpecChanged(helmReleaseProxy, helmChartProxy, "", cluster)).To(BeTrue(), "removing the defaults updates the HelmReleaseProxies")
+}
+
+func TestChartSourceDefaultsToHelmChartProxiesMapper(t *testing.T) {
+	g := NewWithT(t)
+
+	objects := []client.Object{
+		&addonsv1alpha1.HelmChartProxy{ObjectMeta: metav1.ObjectMeta{Name: "hcp-1", Namespace: "test-namespace"}},
+		&addonsv1alpha1.HelmChartProxy{ObjectMeta: metav1.ObjectMeta{Name: "hcp-2", Namespace: "test-namespace"}},
+		&addonsv1alpha1.HelmChartProxy{ObjectMeta: metav1.ObjectMeta{Name: "hcp-3", Namespace: "other-namespace"}},
+	}
+	r := &HelmChartProxyReconciler{
+		Client: fake.NewClientBuilder().WithScheme(fakeScheme).WithObjects(objects...).Build(),
+	}
+
+	requests := r.ChartSourceDefaultsToHelmChartProxiesMapper(ctx, &addonsv1alpha1.ChartSourceDefaults{
+		ObjectMeta: metav1.ObjectMeta{Name: addonsv1alpha1.ChartSourceDefaultsName, Namespace: "test-namespace"},
+	})
+	g.Expect(requests).To(HaveLen(2))
+	for _, request := range requests {
+		g.Expect(request.Namespace).To(Equal("test-namespace"))
+	}
+
+	g.Expect(r.ChartSourceDefaultsToHelmChartProxiesMapper(ctx, &addonsv1alpha1.ChartSourceDefaults{
+		ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "test-namespace"},
+	})).To(BeEmpty())
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helmchartproxy
+
+import (
+	"context"
+	"slices"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	addonsv1 "sigs.k8s.io/cluster-api/api/addons/v1beta1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// excludeClusterResourceSetClusters returns the Clusters the Helm chart may be installed on, leaving out the Clusters
+// without a HelmReleaseProxy to which a ClusterResourceSet matching the ClusterResourceSetSignatures already applies the
+// addon. Those Clusters are recorded in the ClusterResourceSetClusters status.
+func (r *HelmChartProxyReconciler) excludeClusterResourceSetClusters(ctx context.Context, helmChartProxy *addonsv1alpha1.HelmChartProxy, clusters []clusterv1.Cluster, helmReleaseProxies []addonsv1alpha1.HelmReleaseProxy) ([]clusterv1.Cluster, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	helmChartProxy.Status.ClusterResourceSetClusters = nil
+	if len(helmChartProxy.Spec.ClusterResourceSetSignatures) == 0 {
+		return clusters, nil
+	}
+
+	included := []clusterv1.Cluster{}
+	for _, cluster := range clusters {
+		hrpExists := slices.ContainsFunc(helmReleaseProxies, func(h addonsv1alpha1.HelmReleaseProxy) bool { return h.Spec.ClusterRef.Name == cluster.Name })
+		if hrpExists {
+			included = append(included, cluster)
+			continue
+		}
+
+		managed, err := r.isClusterResourceSetManaged(ctx, helmChartProxy, cluster)
+		if err != nil {
+			return nil, err
+		}
+		if managed {
+			log.V(2).Info("Not installing Helm chart on Cluster whose addon is applied by a ClusterResourceSet", "cluster", cluster.Name)
+			helmChartProxy.Status.ClusterResourceSetClusters = append(helmChartProxy.Status.ClusterResourceSetClusters, cluster.Name)
+
+			continue
+		}
+		included = append(included, cluster)
+	}
+
+	return included, nil
+}
+
+// isClusterResourceSetManaged returns true if the ClusterResourceSetBinding of the Cluster lists an applied resource
+// matching one of the ClusterResourceSetSignatures of the HelmChartProxy.
+func (r *HelmChartProxyReconciler) isClusterResourceSetManaged(ctx context.Context, helmChartProxy *addonsv1alpha1.HelmChartProxy, cluster clusterv1.Cluster) (bool, error) {
+	binding := &addonsv1.ClusterResourceSetBinding{}
+	if err := r.Get(ctx, util.ObjectKey(&cluster), binding); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+
+		return false, errors.Wrapf(err, "failed to get ClusterResourceSetBinding of Cluster %s", cluster.Name)
+	}
+
+	for _, resourceSetBinding := range binding.Spec.Bindings {
+		if resourceSetBinding == nil {
+			continue
+		}
+		for _, resource := range resourceSetBinding.Resources {
+			if !resource.Applied {
+				continue
+			}
+			for _, signature := range helmChartProxy.Spec.ClusterResourceSetSignatures {
+				if matchesClusterResourceSetSignature(signature, resourceSetBinding.ClusterResourceSetName, resource.ResourceRef) {
+					return true, nil
+				}
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// matchesClusterResourceSetSignature returns true if the resource of the named ClusterResourceSet matches the signature.
+func matchesClusterResourceSetSignature(signature addonsv1alpha1.ClusterResourceSetSignature, clusterResourceSetName string, resource addonsv1.ResourceRef) bool {
+	if signature.ClusterResourceSetName != "" && signature.ClusterResourceSetName != clusterResourceSetName {
+		return false
+	}
+	if signature.Kind != "" && signature.Kind != resource.Kind {
+		return false
+	}
+	if signature.Name != "" && signature.Name != resource.Name {
+		return false
+	}
+
+	return true
+}
+
+// ClusterResourceSetBindingToHelmChartProxiesMapper is a mapper function that maps a ClusterResourceSetBinding to the
+// HelmChartProxies selecting its Cluster, so that the Helm chart is installed once a ClusterResourceSet no longer applies
+// the addon.
+func (r *HelmChartProxyReconciler) ClusterResourceSetBindingToHelmChartProxiesMapper(ctx context.Context, o client.Object) []ctrl.Request {
+	binding, ok := o.(*addonsv1.ClusterResourceSetBinding)
+	if !ok {
+		ctrl.LoggerFrom(ctx).Error(errors.Errorf("expected a ClusterResourceSetBinding but got %T", o), "failed to map object to HelmChartProxy")
+		return nil
+	}
+
+	clusterName := binding.Spec.ClusterName
+	if clusterName == "" {
+		clusterName = binding.Name
+	}
+
+	cluster := &clusterv1.Cluster{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: binding.Namespace, Name: clusterName}, cluster); err != nil {
+		return nil
+	}
+
+	return r.ClusterToHelmChartProxiesMapper(ctx, cluster)
+}
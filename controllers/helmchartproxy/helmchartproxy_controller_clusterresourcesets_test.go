@@ -0,0 +1,94 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helmchartproxy
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	addonsv1 "sigs.k8s.io/cluster-api/api/addons/v1beta1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestMatchesClusterResourceSetSignature(t *testing.T) {
+	g := NewWithT(t)
+
+	resource := addonsv1.ResourceRef{Kind: "ConfigMap", Name: "calico-manifests"}
+
+	g.Expect(matchesClusterResourceSetSignature(addonsv1alpha1.ClusterResourceSetSignature{ClusterResourceSetName: "calico"}, "calico", resource)).To(BeTrue())
+	g.Expect(matchesClusterResourceSetSignature(addonsv1alpha1.ClusterResourceSetSignature{ClusterResourceSetName: "cilium"}, "calico", resource)).To(BeFalse())
+	g.Expect(matchesClusterResourceSetSignature(addonsv1alpha1.ClusterResourceSetSignature{Kind: "ConfigMap", Name: "calico-manifests"}, "calico", resource)).To(BeTrue())
+	g.Expect(matchesClusterResourceSetSignature(addonsv1alpha1.ClusterResourceSetSignature{Kind: "Secret", Name: "calico-manifests"}, "calico", resource)).To(BeFalse())
+	g.Expect(matchesClusterResourceSetSignature(addonsv1alpha1.ClusterResourceSetSignature{ClusterResourceSetName: "calico", Name: "calico-crds"}, "calico", resource)).To(BeFalse())
+}
+
+func TestExcludeClusterResourceSetClusters(t *testing.T) {
+	g := NewWithT(t)
+
+	binding := func(clusterName string, applied bool) client.Object {
+		return &addonsv1.ClusterResourceSetBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      clusterName,
+				Namespace: "test-namespace",
+			},
+			Spec: addonsv1.ClusterResourceSetBindingSpec{
+				ClusterName: clusterName,
+				Bindings: []*addonsv1.ResourceSetBinding{
+					{
+						ClusterResourceSetName: "calico",
+						Resources: []addonsv1.ResourceBinding{
+							{ResourceRef: addonsv1.ResourceRef{Kind: "ConfigMap", Name: "calico-manifests"}, Applied: applied},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	r := &HelmChartProxyReconciler{
+		Client: fake.NewClientBuilder().
+			WithScheme(fakeScheme).
+			WithObjects(binding("test-cluster-1", true), binding("test-cluster-2", false), binding("test-cluster-3", true)).
+			Build(),
+	}
+
+	helmChartProxy := continuousProxy.DeepCopy()
+	helmChartProxy.Spec.ClusterResourceSetSignatures = []addonsv1alpha1.ClusterResourceSetSignature{{ClusterResourceSetName: "calico"}}
+	helmReleaseProxies := []addonsv1alpha1.HelmReleaseProxy{
+		{Spec: addonsv1alpha1.HelmReleaseProxySpec{ClusterRef: corev1.ObjectReference{Namespace: "test-namespace", Name: "test-cluster-3"}}},
+	}
+
+	// The first Cluster is managed by the ClusterResourceSet, the second Cluster has a binding whose resource was not
+	// applied, and the third Cluster already has a HelmReleaseProxy.
+	included, err := r.excludeClusterResourceSetClusters(ctx, helmChartProxy, []clusterv1.Cluster{*cluster1, *cluster2, *cluster3}, helmReleaseProxies)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(included).To(HaveLen(2))
+	g.Expect(included[0].Name).To(Equal(cluster2.Name))
+	g.Expect(included[1].Name).To(Equal(cluster3.Name))
+	g.Expect(helmChartProxy.Status.ClusterResourceSetClusters).To(Equal([]string{"test-cluster-1"}))
+
+	helmChartProxy.Spec.ClusterResourceSetSignatures = nil
+	included, err = r.excludeClusterResourceSetClusters(ctx, helmChartProxy, []clusterv1.Cluster{*cluster1, *cluster2, *cluster3}, helmReleaseProxies)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(included).To(HaveLen(3))
+	g.Expect(helmChartProxy.Status.ClusterResourceSetClusters).To(BeEmpty())
+}
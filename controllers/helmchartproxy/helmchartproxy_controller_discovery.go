@@ -0,0 +1,118 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helmchartproxy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	helmRelease "helm.sh/helm/v3/pkg/release"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/controllers/remote"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// reconcileDiscovery lists the Helm releases on each selected Cluster and reports the ones matching the HelmChartProxy in
+// its status. It does not create, update or delete any HelmReleaseProxies or Helm releases.
+func (r *HelmChartProxyReconciler) reconcileDiscovery(ctx context.Context, helmChartProxy *addonsv1alpha1.HelmChartProxy, clusters []clusterv1.Cluster) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	discovered := []addonsv1alpha1.DiscoveredRelease{}
+	failed := []string{}
+	for _, cluster := range clusters {
+		if !conditions.IsTrue(&cluster, clusterv1.ControlPlaneInitializedCondition) {
+			log.V(2).Info("Skipping discovery on Cluster until the control plane is initialized", "cluster", cluster.Name)
+			continue
+		}
+
+		restConfig, err := remote.RESTConfig(ctx, "caaph", r.Client, types.NamespacedName{Namespace: cluster.Namespace, Name: cluster.Name})
+		if apierrors.IsNotFound(err) {
+			log.V(2).Info("Skipping discovery on Cluster until its kubeconfig Secret is created", "cluster", cluster.Name)
+			continue
+		}
+		if err != nil {
+			log.Error(err, "failed to get kubeconfig for cluster", "cluster", cluster.Name)
+			failed = append(failed, fmt.Sprintf("%s: failed to get kubeconfig: %v", cluster.Name, err))
+
+			continue
+		}
+
+		// List the releases of all namespaces, as an existing release may not be installed in the namespace of the spec.
+		releases, err := r.HelmClient.ListHelmReleases(ctx, restConfig, addonsv1alpha1.HelmReleaseProxySpec{ReleaseNamespace: metav1.NamespaceAll})
+		if err != nil {
+			log.Error(err, "failed to list Helm releases on cluster", "cluster", cluster.Name)
+			failed = append(failed, fmt.Sprintf("%s: failed to list Helm releases: %v", cluster.Name, err))
+
+			continue
+		}
+
+		discovered = append(discovered, matchingReleases(helmChartProxy.Spec, cluster.Name, releases)...)
+	}
+
+	helmChartProxy.Status.DiscoveredReleases = discovered
+
+	if len(failed) > 0 {
+		conditions.MarkFalse(helmChartProxy, addonsv1alpha1.ReleasesDiscoveredCondition, addonsv1alpha1.ReleaseDiscoveryFailedReason, clusterv1.ConditionSeverityWarning, "%s", strings.Join(failed, "; "))
+
+		return nil
+	}
+	conditions.MarkTrue(helmChartProxy, addonsv1alpha1.ReleasesDiscoveredCondition)
+
+	return nil
+}
+
+// matchingReleases returns the releases that would be managed by a HelmChartProxy with the given spec. If the spec sets a
+// release name, releases are matched by name, otherwise by chart name.
+func matchingReleases(spec addonsv1alpha1.HelmChartProxySpec, clusterName string, releases []*helmRelease.Release) []addonsv1alpha1.DiscoveredRelease {
+	matching := []addonsv1alpha1.DiscoveredRelease{}
+	for _, release := range releases {
+		var chartName, version string
+		if release.Chart != nil && release.Chart.Metadata != nil {
+			chartName = release.Chart.Metadata.Name
+			version = release.Chart.Metadata.Version
+		}
+
+		if spec.ReleaseName != "" {
+			if release.Name != spec.ReleaseName {
+				continue
+			}
+		} else if chartName != spec.ChartName {
+			continue
+		}
+
+		discovered := addonsv1alpha1.DiscoveredRelease{
+			ClusterName:      clusterName,
+			ReleaseName:      release.Name,
+			ReleaseNamespace: release.Namespace,
+			ChartName:        chartName,
+			Version:          version,
+			Revision:         release.Version,
+		}
+		if release.Info != nil {
+			discovered.Status = release.Info.Status.String()
+		}
+		matching = append(matching, discovered)
+	}
+
+	return matching
+}
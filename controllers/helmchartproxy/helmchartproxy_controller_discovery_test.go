@@ -0,0 +1,80 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helmchartproxy
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	helmChart "helm.sh/helm/v3/pkg/chart"
+	helmRelease "helm.sh/helm/v3/pkg/release"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+)
+
+func TestMatchingReleases(t *testing.T) {
+	release := func(name, namespace, chartName, version string) *helmRelease.Release {
+		return &helmRelease.Release{
+			Name:      name,
+			Namespace: namespace,
+			Version:   2,
+			Info:      &helmRelease.Info{Status: helmRelease.StatusDeployed},
+			Chart: &helmChart.Chart{
+				Metadata: &helmChart.Metadata{Name: chartName, Version: version},
+			},
+		}
+	}
+	releases := []*helmRelease.Release{
+		release("nginx", "ingress", "nginx-ingress", "4.0.0"),
+		release("cilium", "kube-system", "cilium", "1.15.0"),
+		release("my-nginx", "default", "nginx-ingress", "3.0.0"),
+	}
+
+	testcases := []struct {
+		name     string
+		spec     addonsv1alpha1.HelmChartProxySpec
+		expected []addonsv1alpha1.DiscoveredRelease
+	}{
+		{
+			name: "matches by release name when it is set",
+			spec: addonsv1alpha1.HelmChartProxySpec{ChartName: "nginx-ingress", ReleaseName: "nginx"},
+			expected: []addonsv1alpha1.DiscoveredRelease{
+				{ClusterName: "test-cluster", ReleaseName: "nginx", ReleaseNamespace: "ingress", ChartName: "nginx-ingress", Version: "4.0.0", Revision: 2, Status: "deployed"},
+			},
+		},
+		{
+			name: "matches by chart name when release name is generated",
+			spec: addonsv1alpha1.HelmChartProxySpec{ChartName: "nginx-ingress"},
+			expected: []addonsv1alpha1.DiscoveredRelease{
+				{ClusterName: "test-cluster", ReleaseName: "nginx", ReleaseNamespace: "ingress", ChartName: "nginx-ingress", Version: "4.0.0", Revision: 2, Status: "deployed"},
+				{ClusterName: "test-cluster", ReleaseName: "my-nginx", ReleaseNamespace: "default", ChartName: "nginx-ingress", Version: "3.0.0", Revision: 2, Status: "deployed"},
+			},
+		},
+		{
+			name:     "no matching release",
+			spec:     addonsv1alpha1.HelmChartProxySpec{ChartName: "calico"},
+			expected: []addonsv1alpha1.DiscoveredRelease{},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			g.Expect(matchingReleases(tc.spec, "test-cluster", releases)).To(Equal(tc.expected))
+		})
+	}
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helmchartproxy
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/cluster-api-addon-provider-helm/internal"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+)
+
+// selectEnvironmentClusters returns the Clusters that belong to an environment of the HelmChartProxy, or all Clusters if
+// it has no environments.
+func selectEnvironmentClusters(helmChartProxy *addonsv1alpha1.HelmChartProxy, clusters []clusterv1.Cluster) ([]clusterv1.Cluster, error) {
+	if len(helmChartProxy.Spec.Environments) == 0 {
+		return clusters, nil
+	}
+
+	for _, environment := range helmChartProxy.Spec.Environments {
+		if _, err := metav1.LabelSelectorAsSelector(&environment.ClusterSelector); err != nil {
+			return nil, errors.Wrapf(err, "invalid cluster selector of environment %s", environment.Name)
+		}
+	}
+
+	selected := []clusterv1.Cluster{}
+	for _, cluster := range clusters {
+		if environmentFor(helmChartProxy, &cluster) != nil {
+			selected = append(selected, cluster)
+		}
+	}
+
+	return selected, nil
+}
+
+// environmentFor returns the first environment of the HelmChartProxy whose ClusterSelector matches the Cluster, or nil if
+// there is none.
+func environmentFor(helmChartProxy *addonsv1alpha1.HelmChartProxy, cluster *clusterv1.Cluster) *addonsv1alpha1.Environment {
+	for i := range helmChartProxy.Spec.Environments {
+		environment := &helmChartProxy.Spec.Environments[i]
+		selector, err := metav1.LabelSelectorAsSelector(&environment.ClusterSelector)
+		if err != nil {
+			continue
+		}
+		if selector.Matches(labels.Set(cluster.Labels)) {
+			return environment
+		}
+	}
+
+	return nil
+}
+
+// environmentStatusFor returns the status of the environment with the name, or nil if there is none.
+func environmentStatusFor(helmChartProxy *addonsv1alpha1.HelmChartProxy, name string) *addonsv1alpha1.EnvironmentStatus {
+	for i := range helmChartProxy.Status.Environments {
+		if helmChartProxy.Status.Environments[i].Name == name {
+			return &helmChartProxy.Status.Environments[i]
+		}
+	}
+
+	return nil
+}
+
+// isEnvironmentReady returns true if the environment has been promoted a version and the HelmReleaseProxies of all of
+// its Clusters are ready with it.
+func isEnvironmentReady(status addonsv1alpha1.EnvironmentStatus) bool {
+	return status.Promoted && status.ReadyClusters == status.Clusters
+}
+
+// setEnvironments promotes the versions of the environments of the HelmChartProxy in order and sets their status. An
+// environment with a version always has it, the first environment otherwise has the version of the HelmChartProxy, and
+// every other environment is promoted the version of the previous environment once that environment is ready. Until
+// then, an environment keeps the version it was promoted before.
+func setEnvironments(helmChartProxy *addonsv1alpha1.HelmChartProxy, clusters []clusterv1.Cluster, helmReleaseProxies []addonsv1alpha1.HelmReleaseProxy) {
+	if len(helmChartProxy.Spec.Environments) == 0 {
+		helmChartProxy.Status.Environments = nil
+		return
+	}
+
+	releasesByCluster := map[string]*addonsv1alpha1.HelmReleaseProxy{}
+	for i := range helmReleaseProxies {
+		releasesByCluster[helmReleaseProxies[i].Spec.ClusterRef.Name] = &helmReleaseProxies[i]
+	}
+
+	statuses := make([]addonsv1alpha1.EnvironmentStatus, 0, len(helmChartProxy.Spec.Environments))
+	for i, environment := range helmChartProxy.Spec.Environments {
+		status := addonsv1alpha1.EnvironmentStatus{Name: environment.Name}
+		switch {
+		case environment.Version != "":
+			status.Promoted, status.Version = true, environment.Version
+		case i == 0:
+			status.Promoted, status.Version = true, helmChartProxy.Spec.Version
+		case isEnvironmentReady(statuses[i-1]):
+			status.Promoted, status.Version = true, statuses[i-1].Version
+		default:
+			if previous := environmentStatusFor(helmChartProxy, environment.Name); previous != nil {
+				status.Promoted, status.Version = previous.Promoted, previous.Version
+			}
+		}
+
+		for j := range clusters {
+			if environmentFor(helmChartProxy, &clusters[j]) != &helmChartProxy.Spec.Environments[i] {
+				continue
+			}
+			status.Clusters++
+
+			helmReleaseProxy, ok := releasesByCluster[clusters[j].Name]
+			if ok && status.Promoted && helmReleaseProxy.Spec.Version == status.Version &&
+				helmReleaseProxy.Status.ObservedGeneration == helmReleaseProxy.Generation &&
+				conditions.IsTrue(helmReleaseProxy, addonsv1alpha1.HelmReleaseReadyCondition) {
+				status.ReadyClusters++
+			}
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	helmChartProxy.Status.Environments = statuses
+}
+
+// helmChartProxyForCluster returns the HelmChartProxy with the version promoted to the environment of the Cluster, along
+// with the environment. The HelmChartProxy is returned unchanged if the Cluster is in no environment. False is returned if
+// the environment of the Cluster has not been promoted a version yet.
+func helmChartProxyForCluster(helmChartProxy *addonsv1alpha1.HelmChartProxy, cluster *clusterv1.Cluster) (*addonsv1alpha1.HelmChartProxy, *addonsv1alpha1.Environment, bool) {
+	environment := environmentFor(helmChartProxy, cluster)
+	if environment == nil {
+		return helmChartProxy, nil, true
+	}
+
+	status := environmentStatusFor(helmChartProxy, environment.Name)
+	if status == nil || !status.Promoted {
+		return nil, environment, false
+	}
+
+	desired := helmChartProxy.DeepCopy()
+	desired.Spec.Version = status.Version
+
+	return desired, environment, true
+}
+
+// RenderValues renders the values of the HelmChartProxy for the Cluster as they are set on its HelmReleaseProxy, i.e. with
+// the ChartSourceDefaults of its namespace, the values of its ValuesFrom sources, the values overlay of the environment of
+// the Cluster and the HelmValuesOverrides selecting the Cluster. It only reads from the client, so that templates can be
+// validated without reconciling. An error is returned if the environment of the Cluster has not been promoted a version yet.
+func (r *HelmChartProxyReconciler) RenderValues(ctx context.Context, helmChartProxy *addonsv1alpha1.HelmChartProxy, cluster *clusterv1.Cluster) (string, error) {
+	desiredHelmChartProxy, environment, promoted := helmChartProxyForCluster(helmChartProxy, cluster)
+	if !promoted {
+		return "", errors.Errorf("environment %s of cluster %s has not been promoted a version yet", environment.Name, cluster.Name)
+	}
+	desiredHelmChartProxy, err := r.withChartSourceDefaults(ctx, desiredHelmChartProxy)
+	if err != nil {
+		return "", err
+	}
+
+	return r.parseValuesForCluster(ctx, desiredHelmChartProxy, environment, cluster)
+}
+
+// parseValuesForCluster renders the values of the HelmChartProxy for the Cluster, merges them over the values of its
+// ValuesFrom sources and merges the rendered values overlay of the environment, if any, and the HelmValuesOverrides
+// selecting the Cluster over the result.
+func (r *HelmChartProxyReconciler) parseValuesForCluster(ctx context.Context, helmChartProxy *addonsv1alpha1.HelmChartProxy, environment *addonsv1alpha1.Environment, cluster *clusterv1.Cluster) (string, error) {
+	values, err := internal.ParseValues(ctx, r.Client, helmChartProxy.Spec, cluster, r.TemplateOptions)
+	if err != nil {
+		return "", err
+	}
+	if len(helmChartProxy.Spec.ValuesFrom) > 0 {
+		base, err := internal.GetValuesFrom(ctx, r.Client, helmChartProxy.Namespace, helmChartProxy.Spec.ValuesFrom)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to get values from sources")
+		}
+		if values, err = internal.MergeValues(base, values); err != nil {
+			return "", errors.Wrap(err, "failed to merge values over values from sources")
+		}
+	}
+	if environment == nil || environment.ValuesTemplate == "" {
+		return r.mergeValuesOverrides(ctx, helmChartProxy, cluster, values)
+	}
+
+	// The proxy settings are already injected into the base values.
+	overlaySpec := helmChartProxy.Spec
+	overlaySpec.ValuesTemplate = environment.ValuesTemplate
+	overlaySpec.ProxyValues = nil
+	overlay, err := internal.ParseValues(ctx, r.Client, overlaySpec, cluster, r.TemplateOptions)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse values of environment %s", environment.Name)
+	}
+	if values, err = internal.MergeValues(values, overlay); err != nil {
+		return "", err
+	}
+
+	return r.mergeValuesOverrides(ctx, helmChartProxy, cluster, values)
+}
+
+// promotedClusters returns the Clusters that are in no environment or in an environment that has been promoted a version.
+func promotedClusters(helmChartProxy *addonsv1alpha1.HelmChartProxy, clusters []clusterv1.Cluster) []clusterv1.Cluster {
+	if len(helmChartProxy.Spec.Environments) == 0 {
+		return clusters
+	}
+
+	promoted := []clusterv1.Cluster{}
+	for i := range clusters {
+		if _, _, ok := helmChartProxyForCluster(helmChartProxy, &clusters[i]); ok {
+			promoted = append(promoted, clusters[i])
+		}
+	}
+
+	return promoted
+}
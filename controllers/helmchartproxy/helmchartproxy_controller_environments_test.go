@@ -0,0 +1,209 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helmchartproxy
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func environmentCluster(name, environment string) clusterv1.Cluster {
+	return clusterv1.Cluster{
+		TypeMeta: metav1.TypeMeta{APIVersion: clusterv1.GroupVersion.String(), Kind: "Cluster"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "test-namespace",
+			Labels:    map[string]string{"env": environment},
+		},
+	}
+}
+
+func environmentHelmChartProxy(version string, environments ...addonsv1alpha1.Environment) *addonsv1alpha1.HelmChartProxy {
+	return &addonsv1alpha1.HelmChartProxy{
+		TypeMeta:   metav1.TypeMeta{APIVersion: addonsv1alpha1.GroupVersion.String(), Kind: "HelmChartProxy"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-hcp", Namespace: "test-namespace"},
+		Spec: addonsv1alpha1.HelmChartProxySpec{
+			ChartName:        "test-chart-name",
+			RepoURL:          "https://test-repo-url",
+			ReleaseNamespace: "test-release-namespace",
+			Version:          version,
+			Environments:     environments,
+		},
+	}
+}
+
+func environment(name, version string) addonsv1alpha1.Environment {
+	return addonsv1alpha1.Environment{
+		Name:            name,
+		ClusterSelector: metav1.LabelSelector{MatchLabels: map[string]string{"env": name}},
+		Version:         version,
+	}
+}
+
+func environmentHelmReleaseProxy(cluster, version string, ready bool) addonsv1alpha1.HelmReleaseProxy {
+	helmReleaseProxy := addonsv1alpha1.HelmReleaseProxy{
+		ObjectMeta: metav1.ObjectMeta{Name: cluster + "-hrp", Namespace: "test-namespace", Generation: 1},
+		Spec: addonsv1alpha1.HelmReleaseProxySpec{
+			ClusterRef: corev1.ObjectReference{Name: cluster, Namespace: "test-namespace"},
+			Version:    version,
+		},
+		Status: addonsv1alpha1.HelmReleaseProxyStatus{ObservedGeneration: 1},
+	}
+	if ready {
+		conditions.MarkTrue(&helmReleaseProxy, addonsv1alpha1.HelmReleaseReadyCondition)
+	}
+
+	return helmReleaseProxy
+}
+
+func TestSelectEnvironmentClusters(t *testing.T) {
+	g := NewWithT(t)
+
+	clusters := []clusterv1.Cluster{environmentCluster("dev-1", "dev"), environmentCluster("prod-1", "prod"), environmentCluster("other-1", "other")}
+
+	selected, err := selectEnvironmentClusters(environmentHelmChartProxy("1.0.0"), clusters)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(selected).To(HaveLen(3), "all Clusters are selected without environments")
+
+	selected, err = selectEnvironmentClusters(environmentHelmChartProxy("1.0.0", environment("dev", ""), environment("prod", "")), clusters)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(selected).To(HaveLen(2))
+	g.Expect(selected[0].Name).To(Equal("dev-1"))
+	g.Expect(selected[1].Name).To(Equal("prod-1"))
+}
+
+func TestSetEnvironments(t *testing.T) {
+	clusters := []clusterv1.Cluster{environmentCluster("dev-1", "dev"), environmentCluster("stage-1", "stage"), environmentCluster("prod-1", "prod")}
+
+	testcases := []struct {
+		name               string
+		helmChartProxy     *addonsv1alpha1.HelmChartProxy
+		previous           []addonsv1alpha1.EnvironmentStatus
+		helmReleaseProxies []addonsv1alpha1.HelmReleaseProxy
+		expected           []addonsv1alpha1.EnvironmentStatus
+	}{
+		{
+			name:           "first environment gets the version of the HelmChartProxy and holds the others until ready",
+			helmChartProxy: environmentHelmChartProxy("2.0.0", environment("dev", ""), environment("stage", ""), environment("prod", "")),
+			helmReleaseProxies: []addonsv1alpha1.HelmReleaseProxy{
+				environmentHelmReleaseProxy("dev-1", "2.0.0", false),
+			},
+			expected: []addonsv1alpha1.EnvironmentStatus{
+				{Name: "dev", Promoted: true, Version: "2.0.0", Clusters: 1},
+				{Name: "stage", Clusters: 1},
+				{Name: "prod", Clusters: 1},
+			},
+		},
+		{
+			name:           "version is promoted through ready environments",
+			helmChartProxy: environmentHelmChartProxy("2.0.0", environment("dev", ""), environment("stage", ""), environment("prod", "")),
+			previous: []addonsv1alpha1.EnvironmentStatus{
+				{Name: "dev", Promoted: true, Version: "2.0.0", Clusters: 1},
+				{Name: "stage", Promoted: true, Version: "1.0.0", Clusters: 1, ReadyClusters: 1},
+				{Name: "prod", Promoted: true, Version: "1.0.0", Clusters: 1, ReadyClusters: 1},
+			},
+			helmReleaseProxies: []addonsv1alpha1.HelmReleaseProxy{
+				environmentHelmReleaseProxy("dev-1", "2.0.0", true),
+				environmentHelmReleaseProxy("stage-1", "1.0.0", true),
+				environmentHelmReleaseProxy("prod-1", "1.0.0", true),
+			},
+			expected: []addonsv1alpha1.EnvironmentStatus{
+				{Name: "dev", Promoted: true, Version: "2.0.0", Clusters: 1, ReadyClusters: 1},
+				{Name: "stage", Promoted: true, Version: "2.0.0", Clusters: 1},
+				{Name: "prod", Promoted: true, Version: "1.0.0", Clusters: 1, ReadyClusters: 1},
+			},
+		},
+		{
+			name:           "environment with a version is not promoted",
+			helmChartProxy: environmentHelmChartProxy("2.0.0", environment("dev", ""), environment("stage", "1.5.0"), environment("prod", "")),
+			helmReleaseProxies: []addonsv1alpha1.HelmReleaseProxy{
+				environmentHelmReleaseProxy("dev-1", "2.0.0", true),
+				environmentHelmReleaseProxy("stage-1", "1.5.0", false),
+			},
+			expected: []addonsv1alpha1.EnvironmentStatus{
+				{Name: "dev", Promoted: true, Version: "2.0.0", Clusters: 1, ReadyClusters: 1},
+				{Name: "stage", Promoted: true, Version: "1.5.0", Clusters: 1},
+				{Name: "prod", Clusters: 1},
+			},
+		},
+		{
+			name:           "HelmReleaseProxy not yet reconciled with the version is not ready",
+			helmChartProxy: environmentHelmChartProxy("2.0.0", environment("dev", ""), environment("prod", "")),
+			helmReleaseProxies: func() []addonsv1alpha1.HelmReleaseProxy {
+				helmReleaseProxy := environmentHelmReleaseProxy("dev-1", "2.0.0", true)
+				helmReleaseProxy.Generation = 2
+
+				return []addonsv1alpha1.HelmReleaseProxy{helmReleaseProxy}
+			}(),
+			expected: []addonsv1alpha1.EnvironmentStatus{
+				{Name: "dev", Promoted: true, Version: "2.0.0", Clusters: 1},
+				{Name: "prod", Clusters: 1},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			tc.helmChartProxy.Status.Environments = tc.previous
+			setEnvironments(tc.helmChartProxy, clusters, tc.helmReleaseProxies)
+			g.Expect(tc.helmChartProxy.Status.Environments).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestReconcileForClusterWithEnvironments(t *testing.T) {
+	g := NewWithT(t)
+
+	dev := environment("dev", "")
+	dev.ValuesTemplate = "resources:\n  limits:\n    memory: {{ .Cluster.metadata.labels.env }}-memory\n"
+	helmChartProxy := environmentHelmChartProxy("2.0.0", dev, environment("prod", ""))
+	helmChartProxy.Spec.ValuesTemplate = "replicas: 1\nresources:\n  limits:\n    cpu: 100m\n"
+	helmChartProxy.Status.Environments = []addonsv1alpha1.EnvironmentStatus{
+		{Name: "dev", Promoted: true, Version: "2.0.0"},
+		{Name: "prod"},
+	}
+	devCluster := environmentCluster("dev-1", "dev")
+	prodCluster := environmentCluster("prod-1", "prod")
+
+	r := &HelmChartProxyReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(fakeScheme).WithObjects(helmChartProxy, &devCluster, &prodCluster).Build(),
+		Recorder: record.NewFakeRecorder(10),
+	}
+	g.Expect(r.reconcileForCluster(ctx, helmChartProxy, devCluster)).To(Succeed())
+	g.Expect(r.reconcileForCluster(ctx, helmChartProxy, prodCluster)).To(Succeed())
+
+	helmReleaseProxies := &addonsv1alpha1.HelmReleaseProxyList{}
+	g.Expect(r.List(ctx, helmReleaseProxies, client.InNamespace("test-namespace"))).To(Succeed())
+	g.Expect(helmReleaseProxies.Items).To(HaveLen(1), "prod has not been promoted a version yet")
+	helmReleaseProxy := helmReleaseProxies.Items[0]
+	g.Expect(helmReleaseProxy.Spec.ClusterRef.Name).To(Equal("dev-1"))
+	g.Expect(helmReleaseProxy.Spec.Version).To(Equal("2.0.0"))
+	g.Expect(helmReleaseProxy.Spec.Values).To(MatchYAML("replicas: 1\nresources:\n  limits:\n    cpu: 100m\n    memory: dev-memory\n"))
+
+	g.Expect(promotedClusters(helmChartProxy, []clusterv1.Cluster{devCluster, prodCluster})).To(ConsistOf(devCluster))
+}
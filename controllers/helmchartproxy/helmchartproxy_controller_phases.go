@@ -19,12 +19,17 @@ package helmchartproxy
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
 	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
 	"sigs.k8s.io/cluster-api-addon-provider-helm/internal"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
@@ -36,10 +41,41 @@ import (
 )
 
 // deleteOrphanedHelmReleaseProxies deletes any HelmReleaseProxy resources that belong to a Cluster that is not selected by its parent HelmChartProxy.
-func (r *HelmChartProxyReconciler) deleteOrphanedHelmReleaseProxies(ctx context.Context, helmChartProxy *addonsv1alpha1.HelmChartProxy, clusters []clusterv1.Cluster, helmReleaseProxies []addonsv1alpha1.HelmReleaseProxy) error {
+// If the HelmChartProxy has an uninstall rollout, they are deleted in batches.
+func (r *HelmChartProxyReconciler) deleteOrphanedHelmReleaseProxies(ctx context.Context, helmChartProxy *addonsv1alpha1.HelmChartProxy, clusters []clusterv1.Cluster, helmReleaseProxies []addonsv1alpha1.HelmReleaseProxy) (ctrl.Result, error) {
 	log := ctrl.LoggerFrom(ctx)
 
 	releasesToDelete := getOrphanedHelmReleaseProxies(ctx, clusters, helmReleaseProxies)
+
+	// The Helm releases of Clusters being deleted go away with them, so their HelmReleaseProxies are deleted right away
+	// rather than held or rolled out.
+	clusterDeleting, releasesToDelete, err := r.splitClusterDeletingHelmReleaseProxies(ctx, releasesToDelete)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	for i := range clusterDeleting {
+		release := clusterDeleting[i]
+		if !release.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		log.V(2).Info("Deleting release of Cluster being deleted", "release", release.Name, "cluster", release.Spec.ClusterRef.Name)
+		if err := r.deleteHelmReleaseProxy(ctx, &release); err != nil {
+			conditions.MarkFalse(helmChartProxy, addonsv1alpha1.HelmReleaseProxySpecsUpToDateCondition, addonsv1alpha1.HelmReleaseProxyDeletionFailedReason, clusterv1.ConditionSeverityError, "%s", err.Error())
+
+			return ctrl.Result{}, err
+		}
+	}
+
+	if holdUninstalls(ctx, helmChartProxy, releasesToDelete) {
+		return ctrl.Result{}, nil
+	}
+
+	if helmChartProxy.Spec.Rollout != nil && helmChartProxy.Spec.Rollout.Uninstall != nil {
+		return r.rolloutDeleteOrphanedHelmReleaseProxies(ctx, helmChartProxy, releasesToDelete)
+	}
+	helmChartProxy.Status.UninstallRollout = nil
+
 	log.V(2).Info("Deleting orphaned releases")
 	for i := range releasesToDelete {
 		release := releasesToDelete[i]
@@ -48,11 +84,199 @@ func (r *HelmChartProxyReconciler) deleteOrphanedHelmReleaseProxies(ctx context.
 		if err := r.deleteHelmReleaseProxy(ctx, &release); err != nil {
 			conditions.MarkFalse(helmChartProxy, addonsv1alpha1.HelmReleaseProxySpecsUpToDateCondition, addonsv1alpha1.HelmReleaseProxyDeletionFailedReason, clusterv1.ConditionSeverityError, "%s", err.Error())
 
-			return err
+			return ctrl.Result{}, err
 		}
 	}
 
-	return nil
+	return ctrl.Result{}, nil
+}
+
+// splitClusterDeletingHelmReleaseProxies splits the orphaned HelmReleaseProxies into those whose Cluster is being deleted and
+// the others.
+func (r *HelmChartProxyReconciler) splitClusterDeletingHelmReleaseProxies(ctx context.Context, helmReleaseProxies []addonsv1alpha1.HelmReleaseProxy) (clusterDeleting, others []addonsv1alpha1.HelmReleaseProxy, err error) {
+	for _, helmReleaseProxy := range helmReleaseProxies {
+		clusterRef := helmReleaseProxy.Spec.ClusterRef
+		cluster := &clusterv1.Cluster{}
+		if err := r.Get(ctx, client.ObjectKey{Namespace: clusterRef.Namespace, Name: clusterRef.Name}, cluster); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return nil, nil, errors.Wrapf(err, "failed to get Cluster %s/%s", clusterRef.Namespace, clusterRef.Name)
+			}
+		} else if !cluster.DeletionTimestamp.IsZero() {
+			clusterDeleting = append(clusterDeleting, helmReleaseProxy)
+			continue
+		}
+		others = append(others, helmReleaseProxy)
+	}
+
+	return clusterDeleting, others, nil
+}
+
+// holdUninstalls returns true if the orphaned HelmReleaseProxies must not be deleted, because the HelmChartProxy is in
+// uninstall dry-run or the Helm release would be uninstalled from more Clusters than the UninstallConfirmationThreshold
+// without confirmation. The Clusters are then listed in the PendingUninstalls status. The confirmation holds as long as no
+// more Clusters than confirmed are pending, so that an uninstall rollout can proceed, and is removed once none are left.
+func holdUninstalls(ctx context.Context, helmChartProxy *addonsv1alpha1.HelmChartProxy, releasesToDelete []addonsv1alpha1.HelmReleaseProxy) bool {
+	log := ctrl.LoggerFrom(ctx)
+
+	annotations := helmChartProxy.GetAnnotations()
+	pending := len(releasesToDelete)
+	if _, ok := annotations[addonsv1alpha1.ConfirmUninstallAnnotation]; ok && pending == 0 {
+		delete(annotations, addonsv1alpha1.ConfirmUninstallAnnotation)
+		helmChartProxy.SetAnnotations(annotations)
+	}
+
+	var reason, message string
+	threshold := helmChartProxy.Spec.UninstallConfirmationThreshold
+	switch {
+	case pending == 0:
+	case annotations[addonsv1alpha1.UninstallDryRunAnnotation] == "true":
+		reason = addonsv1alpha1.UninstallDryRunReason
+		message = fmt.Sprintf("Helm release would be uninstalled from %d Clusters that are no longer selected", pending)
+	case threshold != nil && pending > int(*threshold):
+		// An invalid confirmation confirms nothing.
+		confirmed, _ := strconv.Atoi(annotations[addonsv1alpha1.ConfirmUninstallAnnotation])
+		if pending > confirmed {
+			reason = addonsv1alpha1.UninstallConfirmationRequiredReason
+			message = fmt.Sprintf("Helm release would be uninstalled from %d Clusters, more than the threshold of %d; set the %s annotation to %d to confirm",
+				pending, *threshold, addonsv1alpha1.ConfirmUninstallAnnotation, pending)
+		}
+	}
+
+	if reason == "" {
+		helmChartProxy.Status.PendingUninstalls = nil
+		conditions.Delete(helmChartProxy, addonsv1alpha1.UninstallsConfirmedCondition)
+
+		return false
+	}
+
+	pendingUninstalls := make([]corev1.ObjectReference, 0, pending)
+	for _, release := range releasesToDelete {
+		pendingUninstalls = append(pendingUninstalls, release.Spec.ClusterRef)
+	}
+	slices.SortFunc(pendingUninstalls, func(a, b corev1.ObjectReference) int {
+		return strings.Compare(getNamespacedNameStringFor(a.Namespace, a.Name), getNamespacedNameStringFor(b.Namespace, b.Name))
+	})
+	helmChartProxy.Status.PendingUninstalls = pendingUninstalls
+
+	log.Info("Not uninstalling Helm release from Clusters that are no longer selected", "name", helmChartProxy.Name, "reason", reason, "clusters", pending)
+	conditions.MarkFalse(helmChartProxy, addonsv1alpha1.UninstallsConfirmedCondition, reason, clusterv1.ConditionSeverityWarning, "%s", message)
+
+	return true
+}
+
+// rolloutDeleteOrphanedHelmReleaseProxies deletes the orphaned HelmReleaseProxies in batches sized by the uninstall rollout
+// options, in the order of their Cluster namespaced names. The next batch is only deleted once the HelmReleaseProxies of
+// the previous batch are gone, i.e. their Helm releases are uninstalled, and the rollout verification queries pass.
+func (r *HelmChartProxyReconciler) rolloutDeleteOrphanedHelmReleaseProxies(ctx context.Context, helmChartProxy *addonsv1alpha1.HelmChartProxy, releasesToDelete []addonsv1alpha1.HelmReleaseProxy) (ctrl.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	if len(releasesToDelete) == 0 {
+		helmChartProxy.Status.UninstallRollout = nil
+
+		return ctrl.Result{}, nil
+	}
+
+	pending := make([]addonsv1alpha1.HelmReleaseProxy, 0, len(releasesToDelete))
+	for _, release := range releasesToDelete {
+		if release.DeletionTimestamp.IsZero() {
+			pending = append(pending, release)
+		}
+	}
+	if deleting := len(releasesToDelete) - len(pending); deleting > 0 {
+		// The HelmReleaseProxies are watched, so their removal requeues the HelmChartProxy.
+		log.V(2).Info("Waiting for the previous batch of orphaned HelmReleaseProxies to be deleted", "name", helmChartProxy.Name, "deleting", deleting)
+
+		return ctrl.Result{}, nil
+	}
+
+	status := helmChartProxy.Status.UninstallRollout
+	if verification := helmChartProxy.Spec.Rollout.Verification; status != nil && verification != nil {
+		failed, err := r.RolloutVerifier.VerifyRollout(ctx, verification)
+		if err != nil {
+			conditions.MarkFalse(helmChartProxy, addonsv1alpha1.HelmReleaseProxiesRolloutCompletedCondition, addonsv1alpha1.RolloutVerificationFailedReason, clusterv1.ConditionSeverityError, "%s", err.Error())
+
+			return ctrl.Result{}, err
+		}
+
+		if failed != "" {
+			log.Info("Rollout verification failed; not proceeding to delete the next batch of orphaned HelmReleaseProxies", "name", helmChartProxy.Name, "failed", failed)
+			conditions.MarkFalse(helmChartProxy, addonsv1alpha1.HelmReleaseProxiesRolloutCompletedCondition, addonsv1alpha1.RolloutVerificationFailedReason, clusterv1.ConditionSeverityWarning, "Uninstall rollout verification failed: %s", failed)
+
+			return ctrl.Result{RequeueAfter: rolloutVerificationRequeueInterval}, nil
+		}
+	}
+
+	var count int
+	if status != nil {
+		count = ptr.Deref(status.Count, 0)
+	}
+	stepSize, err := uninstallRolloutStepSize(helmChartProxy.Spec.Rollout.Uninstall, status, count+len(pending))
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	slices.SortStableFunc(pending, func(a, b addonsv1alpha1.HelmReleaseProxy) int {
+		return strings.Compare(
+			getNamespacedNameStringFor(a.Spec.ClusterRef.Namespace, a.Spec.ClusterRef.Name),
+			getNamespacedNameStringFor(b.Spec.ClusterRef.Namespace, b.Spec.ClusterRef.Name),
+		)
+	})
+
+	deleted := 0
+	defer func() {
+		if deleted > 0 {
+			helmChartProxy.Status.UninstallRollout = &addonsv1alpha1.RolloutStatus{Count: ptr.To(count + deleted), StepSize: ptr.To(stepSize), LastProgressTime: ptr.To(metav1.Now())}
+		}
+	}()
+
+	for i := range pending {
+		if deleted >= stepSize {
+			break
+		}
+		release := pending[i]
+
+		log.V(2).Info("Deleting release", "release", release.Name, "cluster", release.Spec.ClusterRef.Name)
+		if err := r.deleteHelmReleaseProxy(ctx, &release); err != nil {
+			conditions.MarkFalse(helmChartProxy, addonsv1alpha1.HelmReleaseProxySpecsUpToDateCondition, addonsv1alpha1.HelmReleaseProxyDeletionFailedReason, clusterv1.ConditionSeverityError, "%s", err.Error())
+
+			return ctrl.Result{}, err
+		}
+		deleted++
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// uninstallRolloutStepSize returns the number of orphaned HelmReleaseProxies to delete in the next batch of an uninstall
+// rollout of the given total size. The first batch is sized by StepInit, and each later batch grows by StepIncrement up to
+// StepLimit, as for install and upgrade rollouts.
+func uninstallRolloutStepSize(rolloutOptions *addonsv1alpha1.RolloutOptions, status *addonsv1alpha1.RolloutStatus, total int) (int, error) {
+	stepInit, err := intstr.GetScaledValueFromIntOrPercent(rolloutOptions.StepInit, total, true)
+	if err != nil {
+		return 0, err
+	}
+	if status == nil || status.StepSize == nil {
+		return max(stepInit, 1), nil
+	}
+
+	var stepIncrement, stepLimit int
+	if rolloutOptions.StepIncrement != nil {
+		if stepIncrement, err = intstr.GetScaledValueFromIntOrPercent(rolloutOptions.StepIncrement, total, true); err != nil {
+			return 0, err
+		}
+	}
+	if rolloutOptions.StepLimit != nil {
+		if stepLimit, err = intstr.GetScaledValueFromIntOrPercent(rolloutOptions.StepLimit, total, true); err != nil {
+			return 0, err
+		}
+	}
+
+	stepSize := *status.StepSize + stepIncrement
+	if stepLimit > stepInit && stepSize > stepLimit {
+		stepSize = stepLimit
+	}
+
+	return max(stepSize, 1), nil
 }
 
 // reconcileForCluster will create or update a HelmReleaseProxy for the given cluster.
@@ -70,6 +294,18 @@ func (r *HelmChartProxyReconciler) reconcileForCluster(ctx context.Context, helm
 		return nil
 	}
 
+	desiredHelmChartProxy, environment, promoted := helmChartProxyForCluster(helmChartProxy, &cluster)
+	if !promoted {
+		log.V(2).Info("Environment of Cluster has not been promoted a version yet, skipping reconciliation", "cluster", cluster.Name, "environment", environment.Name)
+		return nil
+	}
+	desiredHelmChartProxy, err := r.withChartSourceDefaults(ctx, desiredHelmChartProxy)
+	if err != nil {
+		conditions.MarkFalse(helmChartProxy, addonsv1alpha1.HelmReleaseProxySpecsUpToDateCondition, addonsv1alpha1.HelmReleaseProxyCreationFailedReason, clusterv1.ConditionSeverityError, "%s", err.Error())
+
+		return err
+	}
+
 	existingHelmReleaseProxy, err := r.getExistingHelmReleaseProxy(ctx, helmChartProxy, &cluster)
 	if err != nil {
 		// TODO: Should we set a condition here?
@@ -78,6 +314,17 @@ func (r *HelmChartProxyReconciler) reconcileForCluster(ctx context.Context, helm
 
 	if helmChartProxy.Spec.ReconcileStrategy == string(addonsv1alpha1.ReconcileStrategyInstallOnce) {
 		if internal.HasHelmReleaseBeenSuccessfullyInstalled(existingHelmReleaseProxy) {
+			// The deletion policy is still propagated so that it is honored when the HelmReleaseProxy is eventually deleted.
+			if existingHelmReleaseProxy.Spec.DeletionPolicy != helmChartProxy.Spec.DeletionPolicy {
+				log.V(2).Info("Updating deletion policy of HelmReleaseProxy installed on InstallOnce mode", "helmReleaseProxy", existingHelmReleaseProxy.Name, "deletionPolicy", helmChartProxy.Spec.DeletionPolicy)
+				existingHelmReleaseProxy.Spec.DeletionPolicy = helmChartProxy.Spec.DeletionPolicy
+				if err := r.Update(ctx, existingHelmReleaseProxy); err != nil {
+					return errors.Wrapf(err, "failed to update deletion policy of HelmReleaseProxy %s", existingHelmReleaseProxy.Name)
+				}
+
+				return nil
+			}
+
 			log.V(2).Info("HelmReleaseProxy has been installed on InstallOnce mode, nothing to do", "helmReleaseProxy", existingHelmReleaseProxy.Name, "cluster", cluster.Name)
 
 			return nil
@@ -100,9 +347,32 @@ func (r *HelmChartProxyReconciler) reconcileForCluster(ctx context.Context, helm
 		}
 	}
 
-	values, err := internal.ParseValues(ctx, r.Client, helmChartProxy.Spec, &cluster)
+	if existingHelmReleaseProxy == nil {
+		conflictingHelmReleaseProxy, err := r.getConflictingHelmReleaseProxy(ctx, helmChartProxy, &cluster)
+		if err != nil {
+			return errors.Wrapf(err, "failed to check for release name conflicts on cluster %s", cluster.Name)
+		}
+
+		if conflictingHelmReleaseProxy != nil {
+			err := errors.Errorf("release '%s' in namespace '%s' on cluster '%s' is already managed by HelmReleaseProxy '%s' of HelmChartProxy '%s'",
+				helmChartProxy.Spec.ReleaseName, helmChartProxy.Spec.ReleaseNamespace, cluster.Name,
+				conflictingHelmReleaseProxy.Name, conflictingHelmReleaseProxy.Labels[addonsv1alpha1.HelmChartProxyLabelName])
+			conditions.MarkFalse(helmChartProxy, addonsv1alpha1.HelmReleaseProxySpecsUpToDateCondition, addonsv1alpha1.ReleaseNameConflictReason, clusterv1.ConditionSeverityError, "%s", err.Error())
+
+			return err
+		}
+	}
+
+	var values string
+	if desiredHelmChartProxy.Spec.Manifests != "" {
+		// The rendered manifests take the place of the values of the Helm release.
+		values, err = internal.RenderManifests(ctx, r.Client, desiredHelmChartProxy.Spec, &cluster, r.TemplateOptions)
+	} else {
+		values, err = r.parseValuesForCluster(ctx, desiredHelmChartProxy, environment, &cluster)
+	}
 	if err != nil {
 		conditions.MarkFalse(helmChartProxy, addonsv1alpha1.HelmReleaseProxySpecsUpToDateCondition, addonsv1alpha1.ValueParsingFailedReason, clusterv1.ConditionSeverityError, "%s", err.Error())
+		r.Recorder.Eventf(helmChartProxy, corev1.EventTypeWarning, addonsv1alpha1.ValueParsingFailedReason, "Failed to parse values on cluster %s: %s", cluster.Name, err.Error())
 
 		return errors.Wrapf(err, "failed to parse values on cluster %s", cluster.Name)
 	}
@@ -110,7 +380,7 @@ func (r *HelmChartProxyReconciler) reconcileForCluster(ctx context.Context, helm
 	// If the cluster is not being deleted, create or update the HelmReleaseProxy
 	if cluster.DeletionTimestamp.IsZero() {
 		log.V(2).Info("Values for cluster", "cluster", cluster.Name, "values", values)
-		if err := r.createOrUpdateHelmReleaseProxy(ctx, existingHelmReleaseProxy, helmChartProxy, &cluster, values); err != nil {
+		if err := r.createOrUpdateHelmReleaseProxy(ctx, existingHelmReleaseProxy, desiredHelmChartProxy, &cluster, values); err != nil {
 			conditions.MarkFalse(helmChartProxy, addonsv1alpha1.HelmReleaseProxySpecsUpToDateCondition, addonsv1alpha1.HelmReleaseProxyCreationFailedReason, clusterv1.ConditionSeverityError, "%s", err.Error())
 
 			return errors.Wrapf(err, "failed to create or update HelmReleaseProxy on cluster %s", cluster.Name)
@@ -157,6 +427,42 @@ func (r *HelmChartProxyReconciler) getExistingHelmReleaseProxy(ctx context.Conte
 	return &helmReleaseProxyList.Items[0], nil
 }
 
+// getConflictingHelmReleaseProxy returns a HelmReleaseProxy from a different HelmChartProxy that targets the given cluster with the
+// same release name and release namespace, if one exists. Generated release names never conflict.
+func (r *HelmChartProxyReconciler) getConflictingHelmReleaseProxy(ctx context.Context, helmChartProxy *addonsv1alpha1.HelmChartProxy, cluster *clusterv1.Cluster) (*addonsv1alpha1.HelmReleaseProxy, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	if helmChartProxy.Spec.ReleaseName == "" {
+		return nil, nil
+	}
+
+	helmReleaseProxyList := &addonsv1alpha1.HelmReleaseProxyList{}
+	listOpts := []client.ListOption{
+		client.InNamespace(helmChartProxy.Namespace),
+		client.MatchingLabels{
+			clusterv1.ClusterNameLabel: cluster.Name,
+		},
+	}
+
+	if err := r.List(ctx, helmReleaseProxyList, listOpts...); err != nil {
+		return nil, err
+	}
+
+	for i := range helmReleaseProxyList.Items {
+		helmReleaseProxy := &helmReleaseProxyList.Items[i]
+		if helmReleaseProxy.Labels[addonsv1alpha1.HelmChartProxyLabelName] == helmChartProxy.Name {
+			continue
+		}
+
+		if helmReleaseProxy.Spec.ReleaseName == helmChartProxy.Spec.ReleaseName && helmReleaseProxy.Spec.ReleaseNamespace == helmChartProxy.Spec.ReleaseNamespace {
+			log.V(2).Info("Found HelmReleaseProxy with conflicting release name", "helmReleaseProxy", helmReleaseProxy.Name, "cluster", cluster.Name)
+			return helmReleaseProxy, nil
+		}
+	}
+
+	return nil, nil
+}
+
 // createOrUpdateHelmReleaseProxy creates or updates the HelmReleaseProxy for the given cluster.
 func (r *HelmChartProxyReconciler) createOrUpdateHelmReleaseProxy(ctx context.Context, existing *addonsv1alpha1.HelmReleaseProxy, helmChartProxy *addonsv1alpha1.HelmChartProxy, cluster *clusterv1.Cluster, parsedValues string) error {
 	log := ctrl.LoggerFrom(ctx)
@@ -165,6 +471,12 @@ func (r *HelmChartProxyReconciler) createOrUpdateHelmReleaseProxy(ctx context.Co
 		log.V(2).Info("HelmReleaseProxy is up to date, nothing to do", "helmReleaseProxy", existing.Name, "cluster", cluster.Name)
 		return nil
 	}
+	// The referenced values are stored before the HelmReleaseProxy references them.
+	_, blocks := splitReleaseValues(helmChartProxy, parsedValues)
+	if err := r.createValuesConfigMaps(ctx, helmChartProxy, blocks); err != nil {
+		return err
+	}
+
 	if existing == nil {
 		if err := r.Create(ctx, helmReleaseProxy); err != nil {
 			return errors.Wrapf(err, "failed to create HelmReleaseProxy '%s' for cluster: %s/%s", helmReleaseProxy.Name, cluster.Namespace, cluster.Name)
@@ -224,47 +536,411 @@ func constructHelmReleaseProxy(existing *addonsv1alpha1.HelmReleaseProxy, helmCh
 		// helmChartProxy.ObjectMeta.SetAnnotations(helmReleaseProxy.Annotations)
 	} else {
 		helmReleaseProxy = existing
-		changed := false
-		if existing.Spec.Version != helmChartProxy.Spec.Version {
-			changed = true
-		}
-		if !cmp.Equal(existing.Spec.Values, parsedValues) {
-			changed = true
-		}
-
-		if !changed {
+		if !hasHelmReleaseProxySpecChanged(existing, helmChartProxy, parsedValues, cluster) {
 			return nil
 		}
 	}
 
+	// The labels previously propagated from the Cluster are replaced, as the Cluster labels or the keys may have changed.
+	releaseLabels := releaseLabelsFor(helmChartProxy, cluster)
+	for key := range helmReleaseProxy.Spec.ReleaseLabels {
+		delete(helmReleaseProxy.Labels, key)
+	}
+	if helmReleaseProxy.Labels == nil && len(releaseLabels) > 0 {
+		helmReleaseProxy.Labels = map[string]string{}
+	}
+	for key, value := range releaseLabels {
+		helmReleaseProxy.Labels[key] = value
+	}
+	helmReleaseProxy.Spec.ReleaseLabels = releaseLabels
+
 	helmReleaseProxy.Spec.ReconcileStrategy = helmChartProxy.Spec.ReconcileStrategy
+	helmReleaseProxy.Spec.DeletionPolicy = helmChartProxy.Spec.DeletionPolicy
 	helmReleaseProxy.Spec.Version = helmChartProxy.Spec.Version
-	helmReleaseProxy.Spec.Values = parsedValues
+	helmReleaseProxy.Spec.Digest = helmChartProxy.Spec.Digest
+	values, blocks := splitReleaseValues(helmChartProxy, parsedValues)
+	helmReleaseProxy.Spec.Values = values
+	helmReleaseProxy.Spec.ValuesRefs = valuesRefsFor(helmChartProxy, blocks)
+	helmReleaseProxy.Spec.Manifests = manifestsFor(helmChartProxy, parsedValues)
 	helmReleaseProxy.Spec.Options = helmChartProxy.Spec.Options
-	helmReleaseProxy.Spec.Credentials = helmChartProxy.Spec.Credentials
+	helmReleaseProxy.Spec.Credentials = credentialsFor(helmChartProxy)
+	helmReleaseProxy.Spec.ClusterReadiness = helmChartProxy.Spec.ClusterReadiness
+	helmReleaseProxy.Spec.ResyncPeriod = resyncPeriodFor(helmChartProxy)
+	helmReleaseProxy.Spec.HoldDuringClusterUpgrade = helmChartProxy.Spec.HoldDuringClusterUpgrade
+	helmReleaseProxy.Spec.KubeVersion = helmChartProxy.Spec.KubeVersion
+	helmReleaseProxy.Spec.CopySBOMs = helmChartProxy.Spec.CopySBOMs
+	helmReleaseProxy.Spec.RecordValuesOverrides = helmChartProxy.Spec.RecordValuesOverrides
+	helmReleaseProxy.Spec.Failover = helmChartProxy.Spec.Failover
+	helmReleaseProxy.Spec.DriftPolicy = helmChartProxy.Spec.DriftPolicy
+	helmReleaseProxy.Spec.DriftIgnoreRules = helmChartProxy.Spec.DriftIgnoreRules
+	helmReleaseProxy.Spec.DriftDetection = helmChartProxy.Spec.DriftDetection
+	helmReleaseProxy.Spec.Impersonation = helmChartProxy.Spec.Impersonation
+	helmReleaseProxy.Spec.ChartBundleRef = chartBundleRefFor(helmChartProxy)
+	helmReleaseProxy.Spec.RepositoryRef = repositoryRefFor(helmChartProxy)
+	helmReleaseProxy.Spec.Git = gitChartSourceFor(helmChartProxy)
+
+	helmReleaseProxy.Spec.RepositoryHeaders = secretReferenceFor(helmChartProxy, helmChartProxy.Spec.RepositoryHeaders)
+	helmReleaseProxy.Spec.RepositoryCredentials = secretReferenceFor(helmChartProxy, helmChartProxy.Spec.RepositoryCredentials)
+	helmReleaseProxy.Spec.TLSConfig = tlsConfigFor(helmChartProxy)
+	helmReleaseProxy.Spec.ProxyURL = helmChartProxy.Spec.ProxyURL
+	helmReleaseProxy.Spec.Verify = chartVerificationFor(helmChartProxy)
+	helmReleaseProxy.Spec.PostRenderer = helmChartProxy.Spec.PostRenderer
+
+	return helmReleaseProxy
+}
 
-	if helmReleaseProxy.Spec.Credentials != nil {
-		// If the namespace is not set, set it to the namespace of the HelmChartProxy
-		if helmReleaseProxy.Spec.Credentials.Secret.Namespace == "" {
-			helmReleaseProxy.Spec.Credentials.Secret.Namespace = helmChartProxy.Namespace
+// releaseLabelsFor returns the labels of the Cluster the HelmChartProxy propagates to the HelmReleaseProxy and the Helm
+// release, or nil if it propagates none.
+func releaseLabelsFor(helmChartProxy *addonsv1alpha1.HelmChartProxy, cluster *clusterv1.Cluster) map[string]string {
+	var labels map[string]string
+	for _, key := range helmChartProxy.Spec.PropagateClusterLabels {
+		value, ok := cluster.Labels[key]
+		if !ok {
+			continue
 		}
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[key] = value
+	}
+
+	return labels
+}
 
-		// If the key is not set, set it to the default key
-		if helmReleaseProxy.Spec.Credentials.Key == "" {
-			helmReleaseProxy.Spec.Credentials.Key = addonsv1alpha1.DefaultOCIKey
+// credentialsFor returns a copy of the Credentials of the HelmChartProxy with the namespace of the Secret defaulted to the
+// namespace of the HelmChartProxy and the key defaulted to DefaultOCIKey, or nil if no Credentials are specified.
+func credentialsFor(helmChartProxy *addonsv1alpha1.HelmChartProxy) *addonsv1alpha1.Credentials {
+	if helmChartProxy.Spec.Credentials == nil {
+		return nil
+	}
+
+	credentials := *helmChartProxy.Spec.Credentials
+	if credentials.Secret.Namespace == "" {
+		credentials.Secret.Namespace = helmChartProxy.Namespace
+	}
+	if credentials.Key == "" {
+		credentials.Key = addonsv1alpha1.DefaultOCIKey
+	}
+
+	return &credentials
+}
+
+// tlsConfigFor returns a copy of the TLSConfig of the HelmChartProxy with the namespaces of its references defaulted to
+// the namespace of the HelmChartProxy, or nil if no TLSConfig is specified.
+func tlsConfigFor(helmChartProxy *addonsv1alpha1.HelmChartProxy) *addonsv1alpha1.TLSConfig {
+	if helmChartProxy.Spec.TLSConfig == nil {
+		return nil
+	}
+
+	tlsConfig := helmChartProxy.Spec.TLSConfig.DeepCopy()
+	if tlsConfig.CASecretRef != nil && tlsConfig.CASecretRef.Namespace == "" {
+		tlsConfig.CASecretRef.Namespace = helmChartProxy.Namespace
+	}
+	if tlsConfig.CertManagerRef != nil && tlsConfig.CertManagerRef.Namespace == "" {
+		tlsConfig.CertManagerRef.Namespace = helmChartProxy.Namespace
+	}
+
+	return tlsConfig
+}
+
+// chartBundleRefFor returns the ChartBundleRef of the HelmChartProxy with the namespace defaulted to the namespace of the
+// HelmChartProxy, or nil if the HelmChartProxy does not use a ChartBundle.
+func chartBundleRefFor(helmChartProxy *addonsv1alpha1.HelmChartProxy) *addonsv1alpha1.ChartBundleReference {
+	if helmChartProxy.Spec.ChartBundleRef == nil {
+		return nil
+	}
+
+	ref := *helmChartProxy.Spec.ChartBundleRef
+	if ref.Namespace == "" {
+		ref.Namespace = helmChartProxy.Namespace
+	}
+
+	return &ref
+}
+
+// repositoryRefFor returns the RepositoryRef of the HelmChartProxy with the namespace defaulted to the namespace of the
+// HelmChartProxy, or nil if the HelmChartProxy does not use a HelmRepository.
+func repositoryRefFor(helmChartProxy *addonsv1alpha1.HelmChartProxy) *addonsv1alpha1.HelmRepositoryReference {
+	if helmChartProxy.Spec.RepositoryRef == nil {
+		return nil
+	}
+
+	ref := *helmChartProxy.Spec.RepositoryRef
+	if ref.Namespace == "" {
+		ref.Namespace = helmChartProxy.Namespace
+	}
+
+	return &ref
+}
+
+// secretReferenceFor returns a copy of the Secret reference of the HelmChartProxy with the namespace defaulted to the
+// namespace of the HelmChartProxy.
+func secretReferenceFor(helmChartProxy *addonsv1alpha1.HelmChartProxy, secretRef *corev1.SecretReference) *corev1.SecretReference {
+	if secretRef == nil {
+		return nil
+	}
+
+	ref := *secretRef
+	if ref.Namespace == "" {
+		ref.Namespace = helmChartProxy.Namespace
+	}
+
+	return &ref
+}
+
+// gitChartSourceFor returns a copy of the Git source of the HelmChartProxy with the namespace of its AuthSecretRef defaulted
+// to the namespace of the HelmChartProxy.
+func gitChartSourceFor(helmChartProxy *addonsv1alpha1.HelmChartProxy) *addonsv1alpha1.GitChartSource {
+	if helmChartProxy.Spec.Git == nil {
+		return nil
+	}
+
+	source := helmChartProxy.Spec.Git.DeepCopy()
+	source.AuthSecretRef = secretReferenceFor(helmChartProxy, source.AuthSecretRef)
+
+	return source
+}
+
+// chartVerificationFor returns a copy of the chart verification of the HelmChartProxy with the namespaces of its Secret
+// references defaulted to the namespace of the HelmChartProxy.
+func chartVerificationFor(helmChartProxy *addonsv1alpha1.HelmChartProxy) *addonsv1alpha1.ChartVerification {
+	if helmChartProxy.Spec.Verify == nil {
+		return nil
+	}
+
+	verify := helmChartProxy.Spec.Verify.DeepCopy()
+	if verify.Provenance != nil {
+		verify.Provenance.KeyringSecretRef = *secretReferenceFor(helmChartProxy, &verify.Provenance.KeyringSecretRef)
+	}
+	if verify.Cosign != nil {
+		verify.Cosign.PublicKeySecretRef = secretReferenceFor(helmChartProxy, verify.Cosign.PublicKeySecretRef)
+		if verify.Cosign.Keyless != nil {
+			verify.Cosign.Keyless.TrustedRootSecretRef = *secretReferenceFor(helmChartProxy, &verify.Cosign.Keyless.TrustedRootSecretRef)
 		}
 	}
 
-	helmReleaseProxy.Spec.TLSConfig = helmChartProxy.Spec.TLSConfig
+	return verify
+}
+
+// resyncPeriodFor returns the ResyncPeriod of the HelmChartProxy, which defaults to its ReconcileInterval.
+func resyncPeriodFor(helmChartProxy *addonsv1alpha1.HelmChartProxy) *metav1.Duration {
+	if helmChartProxy.Spec.ResyncPeriod != nil {
+		return helmChartProxy.Spec.ResyncPeriod
+	}
+
+	return helmChartProxy.Spec.ReconcileInterval
+}
 
-	if helmReleaseProxy.Spec.TLSConfig != nil && helmReleaseProxy.Spec.TLSConfig.CASecretRef != nil {
-		// If the namespace is not set, set it to the namespace of the HelmChartProxy
-		if helmReleaseProxy.Spec.TLSConfig.CASecretRef.Namespace == "" {
-			helmReleaseProxy.Spec.TLSConfig.CASecretRef.Namespace = helmChartProxy.Namespace
+// hasHelmReleaseProxySpecChanged returns true if the mutable fields of the existing HelmReleaseProxy spec differ from the
+// ones the HelmChartProxy would set for the Cluster with the given parsed values.
+func hasHelmReleaseProxySpecChanged(existing *addonsv1alpha1.HelmReleaseProxy, helmChartProxy *addonsv1alpha1.HelmChartProxy, parsedValues string, cluster *clusterv1.Cluster) bool {
+	values, blocks := splitReleaseValues(helmChartProxy, parsedValues)
+
+	return existing.Spec.Version != helmChartProxy.Spec.Version ||
+		existing.Spec.Digest != helmChartProxy.Spec.Digest ||
+		existing.Spec.DeletionPolicy != helmChartProxy.Spec.DeletionPolicy ||
+		!cmp.Equal(existing.Spec.ClusterReadiness, helmChartProxy.Spec.ClusterReadiness) ||
+		!cmp.Equal(existing.Spec.ResyncPeriod, resyncPeriodFor(helmChartProxy)) ||
+		existing.Spec.HoldDuringClusterUpgrade != helmChartProxy.Spec.HoldDuringClusterUpgrade ||
+		existing.Spec.KubeVersion != helmChartProxy.Spec.KubeVersion ||
+		existing.Spec.CopySBOMs != helmChartProxy.Spec.CopySBOMs ||
+		existing.Spec.RecordValuesOverrides != helmChartProxy.Spec.RecordValuesOverrides ||
+		existing.Spec.Options.Protect != helmChartProxy.Spec.Options.Protect ||
+		!cmp.Equal(existing.Spec.Options.PendingRecovery, helmChartProxy.Spec.Options.PendingRecovery) ||
+		!cmp.Equal(existing.Spec.Options.Test, helmChartProxy.Spec.Options.Test) ||
+		!cmp.Equal(existing.Spec.Failover, helmChartProxy.Spec.Failover) ||
+		existing.Spec.DriftPolicy != helmChartProxy.Spec.DriftPolicy ||
+		!cmp.Equal(existing.Spec.DriftIgnoreRules, helmChartProxy.Spec.DriftIgnoreRules) ||
+		!cmp.Equal(existing.Spec.DriftDetection, helmChartProxy.Spec.DriftDetection) ||
+		!cmp.Equal(existing.Spec.Impersonation, helmChartProxy.Spec.Impersonation) ||
+		!cmp.Equal(existing.Spec.ChartBundleRef, chartBundleRefFor(helmChartProxy)) ||
+		!cmp.Equal(existing.Spec.RepositoryRef, repositoryRefFor(helmChartProxy)) ||
+		!cmp.Equal(existing.Spec.Git, gitChartSourceFor(helmChartProxy)) ||
+		!cmp.Equal(existing.Spec.RepositoryHeaders, secretReferenceFor(helmChartProxy, helmChartProxy.Spec.RepositoryHeaders)) ||
+		!cmp.Equal(existing.Spec.RepositoryCredentials, secretReferenceFor(helmChartProxy, helmChartProxy.Spec.RepositoryCredentials)) ||
+		!cmp.Equal(existing.Spec.Credentials, credentialsFor(helmChartProxy)) ||
+		!cmp.Equal(existing.Spec.TLSConfig, tlsConfigFor(helmChartProxy)) ||
+		existing.Spec.ProxyURL != helmChartProxy.Spec.ProxyURL ||
+		!cmp.Equal(existing.Spec.Verify, chartVerificationFor(helmChartProxy)) ||
+		!cmp.Equal(existing.Spec.PostRenderer, helmChartProxy.Spec.PostRenderer) ||
+		!cmp.Equal(existing.Spec.ReleaseLabels, releaseLabelsFor(helmChartProxy, cluster)) ||
+		!cmp.Equal(existing.Spec.Values, values) ||
+		existing.Spec.Manifests != manifestsFor(helmChartProxy, parsedValues) ||
+		!cmp.Equal(existing.Spec.ValuesRefs, valuesRefsFor(helmChartProxy, blocks))
+}
+
+// splitReleaseValues returns the values inlined in the HelmReleaseProxies of the HelmChartProxy and the blocks of values they
+// reference, which are only split out of the parsed values if the HelmChartProxy stores values by reference. Values that
+// cannot be split, e.g. because they are not a map, are inlined in full and fail on install instead. There are no values if
+// the HelmChartProxy applies plain manifests.
+func splitReleaseValues(helmChartProxy *addonsv1alpha1.HelmChartProxy, parsedValues string) (string, []internal.ValuesBlock) {
+	if helmChartProxy.Spec.Manifests != "" {
+		return "", nil
+	}
+	if helmChartProxy.Spec.ValuesByReference == nil {
+		return parsedValues, nil
+	}
+
+	minSize := internal.DefaultValuesByReferenceMinSize
+	if helmChartProxy.Spec.ValuesByReference.MinSize != nil {
+		minSize = int(*helmChartProxy.Spec.ValuesByReference.MinSize)
+	}
+	values, blocks, err := internal.SplitValues(parsedValues, minSize)
+	if err != nil {
+		return parsedValues, nil
+	}
+
+	return values, blocks
+}
+
+// manifestsFor returns the rendered manifests of a HelmReleaseProxy of the HelmChartProxy, which are parsed in place of the
+// values if the HelmChartProxy applies plain manifests instead of a Helm chart.
+func manifestsFor(helmChartProxy *addonsv1alpha1.HelmChartProxy, parsedValues string) string {
+	if helmChartProxy.Spec.Manifests == "" {
+		return ""
+	}
+
+	return parsedValues
+}
+
+// valuesRefsFor returns the references of a HelmReleaseProxy to the blocks of values of the HelmChartProxy.
+func valuesRefsFor(helmChartProxy *addonsv1alpha1.HelmChartProxy, blocks []internal.ValuesBlock) []addonsv1alpha1.ValuesReference {
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	refs := make([]addonsv1alpha1.ValuesReference, 0, len(blocks))
+	for _, block := range blocks {
+		refs = append(refs, addonsv1alpha1.ValuesReference{
+			Key:           block.Key,
+			ConfigMapName: internal.ValuesConfigMapName(helmChartProxy.Name, block.Hash),
+			Hash:          block.Hash,
+		})
+	}
+
+	return refs
+}
+
+// createValuesConfigMaps creates the ConfigMaps storing the blocks of values of the HelmChartProxy that do not exist yet.
+// The ConfigMaps are named after the hash of their value, so existing ones never need to be updated.
+func (r *HelmChartProxyReconciler) createValuesConfigMaps(ctx context.Context, helmChartProxy *addonsv1alpha1.HelmChartProxy, blocks []internal.ValuesBlock) error {
+	for _, block := range blocks {
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      internal.ValuesConfigMapName(helmChartProxy.Name, block.Hash),
+				Namespace: helmChartProxy.Namespace,
+				Labels: map[string]string{
+					addonsv1alpha1.HelmChartProxyLabelName: helmChartProxy.Name,
+					addonsv1alpha1.ValuesBlockLabelName:    "true",
+				},
+				OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(helmChartProxy, helmChartProxy.GroupVersionKind())},
+			},
+			Immutable: ptr.To(true),
+			Data:      map[string]string{addonsv1alpha1.ValuesBlockKey: block.Data},
+		}
+		if err := r.Create(ctx, configMap); err != nil && !apierrors.IsAlreadyExists(err) {
+			return errors.Wrapf(err, "failed to create ConfigMap %s of value %s", configMap.Name, block.Key)
 		}
 	}
 
-	return helmReleaseProxy
+	return nil
+}
+
+// deleteUnreferencedValuesConfigMaps deletes the ConfigMaps storing blocks of values of the HelmChartProxy that none of its
+// HelmReleaseProxies reference anymore. A ConfigMap deleted while the cache misses a new reference to it is recreated by
+// the next reconcile.
+func (r *HelmChartProxyReconciler) deleteUnreferencedValuesConfigMaps(ctx context.Context, helmChartProxy *addonsv1alpha1.HelmChartProxy) error {
+	label := map[string]string{
+		addonsv1alpha1.HelmChartProxyLabelName: helmChartProxy.Name,
+	}
+	releaseList, err := r.listInstalledReleases(ctx, helmChartProxy.Namespace, label)
+	if err != nil {
+		return err
+	}
+
+	referenced := map[string]struct{}{}
+	for _, helmReleaseProxy := range releaseList.Items {
+		for _, ref := range helmReleaseProxy.Spec.ValuesRefs {
+			referenced[ref.ConfigMapName] = struct{}{}
+		}
+	}
+
+	configMapList := &corev1.ConfigMapList{}
+	if err := r.List(ctx, configMapList, client.InNamespace(helmChartProxy.Namespace), client.MatchingLabels{
+		addonsv1alpha1.HelmChartProxyLabelName: helmChartProxy.Name,
+		addonsv1alpha1.ValuesBlockLabelName:    "true",
+	}); err != nil {
+		return errors.Wrap(err, "failed to list values ConfigMaps")
+	}
+
+	for i := range configMapList.Items {
+		configMap := &configMapList.Items[i]
+		if _, ok := referenced[configMap.Name]; ok {
+			continue
+		}
+		if err := r.Delete(ctx, configMap); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete values ConfigMap %s", configMap.Name)
+		}
+	}
+
+	return nil
+}
+
+// setOutOfDateReleases sets the HelmReleaseProxies whose spec does not match the desired state rendered from the
+// HelmChartProxy for their Cluster on the HelmChartProxy status. HelmReleaseProxies of Clusters whose values cannot be
+// parsed are left out, as the parsing failure is already reported by the HelmReleaseProxySpecsUpToDate condition.
+func (r *HelmChartProxyReconciler) setOutOfDateReleases(ctx context.Context, helmChartProxy *addonsv1alpha1.HelmChartProxy, clusters []clusterv1.Cluster) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	label := map[string]string{
+		addonsv1alpha1.HelmChartProxyLabelName: helmChartProxy.Name,
+	}
+	releaseList, err := r.listInstalledReleases(ctx, helmChartProxy.Namespace, label)
+	if err != nil {
+		return err
+	}
+
+	clustersByName := map[string]*clusterv1.Cluster{}
+	for i := range clusters {
+		clustersByName[clusters[i].Name] = &clusters[i]
+	}
+
+	outOfDate := []corev1.ObjectReference{}
+	for i := range releaseList.Items {
+		helmReleaseProxy := &releaseList.Items[i]
+		cluster, ok := clustersByName[helmReleaseProxy.Spec.ClusterRef.Name]
+		if !ok {
+			continue
+		}
+
+		desiredHelmChartProxy, environment, promoted := helmChartProxyForCluster(helmChartProxy, cluster)
+		if !promoted {
+			continue
+		}
+		desiredHelmChartProxy, err := r.withChartSourceDefaults(ctx, desiredHelmChartProxy)
+		if err != nil {
+			return err
+		}
+
+		values, err := r.parseValuesForCluster(ctx, desiredHelmChartProxy, environment, cluster)
+		if err != nil {
+			log.V(2).Info("Skipping out of date check of HelmReleaseProxy as values cannot be parsed", "helmReleaseProxy", helmReleaseProxy.Name, "cluster", cluster.Name)
+			continue
+		}
+
+		if shouldReinstallHelmRelease(ctx, helmReleaseProxy, helmChartProxy) || hasHelmReleaseProxySpecChanged(helmReleaseProxy, desiredHelmChartProxy, values, cluster) {
+			outOfDate = append(outOfDate, corev1.ObjectReference{
+				APIVersion: addonsv1alpha1.GroupVersion.String(),
+				Kind:       "HelmReleaseProxy",
+				Name:       helmReleaseProxy.Name,
+				Namespace:  helmReleaseProxy.Namespace,
+			})
+		}
+	}
+
+	helmChartProxy.Status.OutOfDateReleases = outOfDate
+
+	return nil
 }
 
 // shouldReinstallHelmRelease returns true if the HelmReleaseProxy needs to be reinstalled. This is the case if any of the immutable fields changed.
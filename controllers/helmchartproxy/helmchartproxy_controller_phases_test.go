@@ -25,8 +25,11 @@ import (
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/ptr"
 	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/cluster-api-addon-provider-helm/internal"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -368,6 +371,29 @@ var (
 		},
 	}
 
+	fakeConflictingHelmReleaseProxy = &addonsv1alpha1.HelmReleaseProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "other-generated-name",
+			Namespace: "test-namespace",
+			Labels: map[string]string{
+				clusterv1.ClusterNameLabel:             "test-cluster",
+				addonsv1alpha1.HelmChartProxyLabelName: "other-hcp",
+			},
+		},
+		Spec: addonsv1alpha1.HelmReleaseProxySpec{
+			ClusterRef: corev1.ObjectReference{
+				APIVersion: clusterv1.GroupVersion.String(),
+				Kind:       "Cluster",
+				Name:       "test-cluster",
+				Namespace:  "test-namespace",
+			},
+			ReleaseName:      "test-release-name",
+			ChartName:        "other-chart-name",
+			RepoURL:          "https://other-repo-url",
+			ReleaseNamespace: "test-release-namespace",
+		},
+	}
+
 	fakeReadyHelmReleaseProxy = &addonsv1alpha1.HelmReleaseProxy{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "test-generated-name",
@@ -467,9 +493,9 @@ func TestReconcileForCluster(t *testing.T) {
 				g.Expect(specsReady.Status).To(Equal(corev1.ConditionFalse))
 				g.Expect(specsReady.Reason).To(Equal(addonsv1alpha1.ValueParsingFailedReason))
 				g.Expect(specsReady.Severity).To(Equal(clusterv1.ConditionSeverityError))
-				g.Expect(specsReady.Message).To(Equal("failed to parse values on cluster test-cluster: template: test-chart-name-test-cluster:1: bad character U+002D '-'"))
+				g.Expect(specsReady.Message).To(Equal("failed to parse values on cluster test-cluster: valuesTemplate error at line 1: bad character U+002D '-'\n> 1 | apiServerPort: {{ .Cluster.invalid-path }}"))
 			},
-			expectedError: "failed to parse values on cluster test-cluster: template: test-chart-name-test-cluster:1: bad character U+002D '-'",
+			expectedError: "failed to parse values on cluster test-cluster: valuesTemplate error at line 1: bad character U+002D '-'\n> 1 | apiServerPort: {{ .Cluster.invalid-path }}",
 		},
 		{
 			name:                          "set condition for reinstalling when requeueing after a deletion",
@@ -579,6 +605,20 @@ func TestReconcileForCluster(t *testing.T) {
 			},
 			expectedError: "",
 		},
+		{
+			name:                          "set condition when release name conflicts with another HelmChartProxy",
+			helmChartProxy:                fakeHelmChartProxy1,
+			existingHelmReleaseProxy:      fakeConflictingHelmReleaseProxy,
+			cluster:                       fakeCluster1,
+			expectHelmReleaseProxyToExist: false,
+			expect: func(g *WithT, hcp *addonsv1alpha1.HelmChartProxy, hrp *addonsv1alpha1.HelmReleaseProxy) {
+				specsReady := conditions.Get(hcp, addonsv1alpha1.HelmReleaseProxySpecsUpToDateCondition)
+				g.Expect(specsReady.Status).To(Equal(corev1.ConditionFalse))
+				g.Expect(specsReady.Reason).To(Equal(addonsv1alpha1.ReleaseNameConflictReason))
+				g.Expect(specsReady.Severity).To(Equal(clusterv1.ConditionSeverityError))
+			},
+			expectedError: "release 'test-release-name' in namespace 'test-release-namespace' on cluster 'test-cluster' is already managed by HelmReleaseProxy 'other-generated-name' of HelmChartProxy 'other-hcp'",
+		},
 		{
 			name:                          "do not reconcile for a paused cluster",
 			helmChartProxy:                fakeHelmChartProxy1,
@@ -608,6 +648,7 @@ func TestReconcileForCluster(t *testing.T) {
 					WithStatusSubresource(&addonsv1alpha1.HelmChartProxy{}).
 					WithStatusSubresource(&addonsv1alpha1.HelmReleaseProxy{}).
 					Build(),
+				Recorder: record.NewFakeRecorder(10),
 			}
 			err := r.reconcileForCluster(ctx, tc.helmChartProxy, *tc.cluster)
 
@@ -905,6 +946,246 @@ func TestConstructHelmReleaseProxy(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "resync period changed",
+			existing: &addonsv1alpha1.HelmReleaseProxy{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-generated-name",
+					Namespace: "test-namespace",
+					OwnerReferences: []metav1.OwnerReference{
+						{
+							APIVersion:         addonsv1alpha1.GroupVersion.String(),
+							Kind:               "HelmChartProxy",
+							Name:               "test-hcp",
+							Controller:         ptr.To(true),
+							BlockOwnerDeletion: ptr.To(true),
+						},
+					},
+					Labels: map[string]string{
+						clusterv1.ClusterNameLabel:             "test-cluster",
+						addonsv1alpha1.HelmChartProxyLabelName: "test-hcp",
+					},
+				},
+				Spec: addonsv1alpha1.HelmReleaseProxySpec{
+					ClusterRef: corev1.ObjectReference{
+						APIVersion: clusterv1.GroupVersion.String(),
+						Kind:       "Cluster",
+						Name:       "test-cluster",
+						Namespace:  "test-namespace",
+					},
+					ReleaseName:      "test-release-name",
+					ChartName:        "test-chart-name",
+					RepoURL:          "https://test-repo-url",
+					ReleaseNamespace: "test-release-namespace",
+					Values:           "test-parsed-values",
+					Version:          "test-version",
+					Options: addonsv1alpha1.HelmOptions{
+						EnableClientCache: true,
+						Timeout: &metav1.Duration{
+							Duration: 10 * time.Minute,
+						},
+					},
+				},
+			},
+			helmChartProxy: &addonsv1alpha1.HelmChartProxy{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: addonsv1alpha1.GroupVersion.String(),
+					Kind:       "HelmChartProxy",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-hcp",
+					Namespace: "test-namespace",
+				},
+				Spec: addonsv1alpha1.HelmChartProxySpec{
+					ReleaseName:      "test-release-name",
+					ChartName:        "test-chart-name",
+					RepoURL:          "https://test-repo-url",
+					ReleaseNamespace: "test-release-namespace",
+					Version:          "test-version",
+					Options: addonsv1alpha1.HelmOptions{
+						EnableClientCache: true,
+						Timeout: &metav1.Duration{
+							Duration: 10 * time.Minute,
+						},
+					},
+					ResyncPeriod: &metav1.Duration{
+						Duration: time.Minute,
+					},
+				},
+			},
+			parsedValues: "test-parsed-values",
+			cluster: &clusterv1.Cluster{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: clusterv1.GroupVersion.String(),
+					Kind:       "Cluster",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-cluster",
+					Namespace: "test-namespace",
+				},
+			},
+			expected: &addonsv1alpha1.HelmReleaseProxy{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-generated-name",
+					Namespace: "test-namespace",
+					OwnerReferences: []metav1.OwnerReference{
+						{
+							APIVersion:         addonsv1alpha1.GroupVersion.String(),
+							Kind:               "HelmChartProxy",
+							Name:               "test-hcp",
+							Controller:         ptr.To(true),
+							BlockOwnerDeletion: ptr.To(true),
+						},
+					},
+					Labels: map[string]string{
+						clusterv1.ClusterNameLabel:             "test-cluster",
+						addonsv1alpha1.HelmChartProxyLabelName: "test-hcp",
+					},
+				},
+				Spec: addonsv1alpha1.HelmReleaseProxySpec{
+					ClusterRef: corev1.ObjectReference{
+						APIVersion: clusterv1.GroupVersion.String(),
+						Kind:       "Cluster",
+						Name:       "test-cluster",
+						Namespace:  "test-namespace",
+					},
+					ReleaseName:      "test-release-name",
+					ChartName:        "test-chart-name",
+					RepoURL:          "https://test-repo-url",
+					ReleaseNamespace: "test-release-namespace",
+					Values:           "test-parsed-values",
+					Version:          "test-version",
+					Options: addonsv1alpha1.HelmOptions{
+						EnableClientCache: true,
+						Timeout: &metav1.Duration{
+							Duration: 10 * time.Minute,
+						},
+					},
+					ResyncPeriod: &metav1.Duration{
+						Duration: time.Minute,
+					},
+				},
+			},
+		},
+		{
+			name: "chart bundle ref changed",
+			existing: &addonsv1alpha1.HelmReleaseProxy{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-generated-name",
+					Namespace: "test-namespace",
+					OwnerReferences: []metav1.OwnerReference{
+						{
+							APIVersion:         addonsv1alpha1.GroupVersion.String(),
+							Kind:               "HelmChartProxy",
+							Name:               "test-hcp",
+							Controller:         ptr.To(true),
+							BlockOwnerDeletion: ptr.To(true),
+						},
+					},
+					Labels: map[string]string{
+						clusterv1.ClusterNameLabel:             "test-cluster",
+						addonsv1alpha1.HelmChartProxyLabelName: "test-hcp",
+					},
+				},
+				Spec: addonsv1alpha1.HelmReleaseProxySpec{
+					ClusterRef: corev1.ObjectReference{
+						APIVersion: clusterv1.GroupVersion.String(),
+						Kind:       "Cluster",
+						Name:       "test-cluster",
+						Namespace:  "test-namespace",
+					},
+					ReleaseName:      "test-release-name",
+					ChartName:        "test-chart-name",
+					ReleaseNamespace: "test-release-namespace",
+					Values:           "test-parsed-values",
+					Version:          "test-version",
+					Options: addonsv1alpha1.HelmOptions{
+						EnableClientCache: true,
+						Timeout: &metav1.Duration{
+							Duration: 10 * time.Minute,
+						},
+					},
+				},
+			},
+			helmChartProxy: &addonsv1alpha1.HelmChartProxy{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: addonsv1alpha1.GroupVersion.String(),
+					Kind:       "HelmChartProxy",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-hcp",
+					Namespace: "test-namespace",
+				},
+				Spec: addonsv1alpha1.HelmChartProxySpec{
+					ReleaseName:      "test-release-name",
+					ChartName:        "test-chart-name",
+					ReleaseNamespace: "test-release-namespace",
+					Version:          "test-version",
+					Options: addonsv1alpha1.HelmOptions{
+						EnableClientCache: true,
+						Timeout: &metav1.Duration{
+							Duration: 10 * time.Minute,
+						},
+					},
+					ChartBundleRef: &addonsv1alpha1.ChartBundleReference{
+						Name: "test-bundle",
+					},
+				},
+			},
+			parsedValues: "test-parsed-values",
+			cluster: &clusterv1.Cluster{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: clusterv1.GroupVersion.String(),
+					Kind:       "Cluster",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-cluster",
+					Namespace: "test-namespace",
+				},
+			},
+			expected: &addonsv1alpha1.HelmReleaseProxy{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-generated-name",
+					Namespace: "test-namespace",
+					OwnerReferences: []metav1.OwnerReference{
+						{
+							APIVersion:         addonsv1alpha1.GroupVersion.String(),
+							Kind:               "HelmChartProxy",
+							Name:               "test-hcp",
+							Controller:         ptr.To(true),
+							BlockOwnerDeletion: ptr.To(true),
+						},
+					},
+					Labels: map[string]string{
+						clusterv1.ClusterNameLabel:             "test-cluster",
+						addonsv1alpha1.HelmChartProxyLabelName: "test-hcp",
+					},
+				},
+				Spec: addonsv1alpha1.HelmReleaseProxySpec{
+					ClusterRef: corev1.ObjectReference{
+						APIVersion: clusterv1.GroupVersion.String(),
+						Kind:       "Cluster",
+						Name:       "test-cluster",
+						Namespace:  "test-namespace",
+					},
+					ReleaseName:      "test-release-name",
+					ChartName:        "test-chart-name",
+					ReleaseNamespace: "test-release-namespace",
+					Values:           "test-parsed-values",
+					Version:          "test-version",
+					Options: addonsv1alpha1.HelmOptions{
+						EnableClientCache: true,
+						Timeout: &metav1.Duration{
+							Duration: 10 * time.Minute,
+						},
+					},
+					ChartBundleRef: &addonsv1alpha1.ChartBundleReference{
+						Name:      "test-bundle",
+						Namespace: "test-namespace",
+					},
+				},
+			},
+		},
 		{
 			name: "parsed values changed",
 			existing: &addonsv1alpha1.HelmReleaseProxy{
@@ -1675,3 +1956,632 @@ func TestGetOrphanedHelmReleaseProxies(t *testing.T) {
 		})
 	}
 }
+
+func TestRolloutDeleteOrphanedHelmReleaseProxies(t *testing.T) {
+	t.Parallel()
+
+	orphan := func(clusterName string) *addonsv1alpha1.HelmReleaseProxy {
+		return &addonsv1alpha1.HelmReleaseProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-hrp-" + clusterName,
+				Namespace: "test-namespace",
+			},
+			Spec: addonsv1alpha1.HelmReleaseProxySpec{
+				ClusterRef: corev1.ObjectReference{
+					Name:      clusterName,
+					Namespace: "test-namespace",
+				},
+			},
+		}
+	}
+	deleting := orphan("test-cluster-1")
+	deleting.Finalizers = []string{addonsv1alpha1.HelmReleaseProxyFinalizer}
+	deleting.DeletionTimestamp = ptr.To(metav1.Now())
+
+	uninstallRollout := func(stepIncrement *intstr.IntOrString, status *addonsv1alpha1.RolloutStatus) *addonsv1alpha1.HelmChartProxy {
+		helmChartProxy := fakeHelmChartProxy1.DeepCopy()
+		helmChartProxy.Spec.Rollout = &addonsv1alpha1.Rollout{
+			Uninstall: &addonsv1alpha1.RolloutOptions{
+				StepInit:      ptr.To(intstr.FromString("40%")),
+				StepIncrement: stepIncrement,
+			},
+		}
+		helmChartProxy.Status.UninstallRollout = status
+
+		return helmChartProxy
+	}
+
+	testcases := []struct {
+		name                   string
+		helmChartProxy         *addonsv1alpha1.HelmChartProxy
+		orphans                []*addonsv1alpha1.HelmReleaseProxy
+		clusters               []*clusterv1.Cluster
+		expectedRemaining      []string
+		expectedRolloutStatus  *addonsv1alpha1.RolloutStatus
+		expectRolloutStatusNil bool
+	}{
+		{
+			name:           "deletes the first batch in cluster order",
+			helmChartProxy: uninstallRollout(nil, nil),
+			orphans: []*addonsv1alpha1.HelmReleaseProxy{
+				orphan("test-cluster-5"), orphan("test-cluster-4"), orphan("test-cluster-3"), orphan("test-cluster-2"), orphan("test-cluster-1"),
+			},
+			expectedRemaining:     []string{"test-hrp-test-cluster-3", "test-hrp-test-cluster-4", "test-hrp-test-cluster-5"},
+			expectedRolloutStatus: &addonsv1alpha1.RolloutStatus{Count: ptr.To(2), StepSize: ptr.To(2)},
+		},
+		{
+			name:           "waits for the previous batch to be deleted",
+			helmChartProxy: uninstallRollout(nil, &addonsv1alpha1.RolloutStatus{Count: ptr.To(1), StepSize: ptr.To(1)}),
+			orphans: []*addonsv1alpha1.HelmReleaseProxy{
+				deleting, orphan("test-cluster-2"), orphan("test-cluster-3"),
+			},
+			expectedRemaining:     []string{"test-hrp-test-cluster-1", "test-hrp-test-cluster-2", "test-hrp-test-cluster-3"},
+			expectedRolloutStatus: &addonsv1alpha1.RolloutStatus{Count: ptr.To(1), StepSize: ptr.To(1)},
+		},
+		{
+			name:           "increments the step size for the next batch",
+			helmChartProxy: uninstallRollout(ptr.To(intstr.FromInt32(1)), &addonsv1alpha1.RolloutStatus{Count: ptr.To(2), StepSize: ptr.To(2)}),
+			orphans: []*addonsv1alpha1.HelmReleaseProxy{
+				orphan("test-cluster-3"), orphan("test-cluster-4"), orphan("test-cluster-5"), orphan("test-cluster-6"),
+			},
+			expectedRemaining:     []string{"test-hrp-test-cluster-6"},
+			expectedRolloutStatus: &addonsv1alpha1.RolloutStatus{Count: ptr.To(5), StepSize: ptr.To(3)},
+		},
+		{
+			name:           "deletes orphans of Clusters being deleted outside of the batches",
+			helmChartProxy: uninstallRollout(nil, nil),
+			orphans: []*addonsv1alpha1.HelmReleaseProxy{
+				orphan("test-cluster-1"), orphan("test-cluster-2"), orphan("test-cluster-3"), orphan("test-cluster-4"), orphan("test-cluster-5"),
+			},
+			clusters: []*clusterv1.Cluster{{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "test-cluster-5",
+					Namespace:         "test-namespace",
+					Finalizers:        []string{clusterv1.ClusterFinalizer},
+					DeletionTimestamp: ptr.To(metav1.Now()),
+				},
+			}},
+			expectedRemaining:     []string{"test-hrp-test-cluster-3", "test-hrp-test-cluster-4"},
+			expectedRolloutStatus: &addonsv1alpha1.RolloutStatus{Count: ptr.To(2), StepSize: ptr.To(2)},
+		},
+		{
+			name:                   "clears the rollout status once all orphans are deleted",
+			helmChartProxy:         uninstallRollout(nil, &addonsv1alpha1.RolloutStatus{Count: ptr.To(5), StepSize: ptr.To(2)}),
+			expectRolloutStatusNil: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			t.Parallel()
+
+			objects := []client.Object{}
+			releases := []addonsv1alpha1.HelmReleaseProxy{}
+			for _, hrp := range tc.orphans {
+				objects = append(objects, hrp.DeepCopy())
+				releases = append(releases, *hrp.DeepCopy())
+			}
+			for _, cluster := range tc.clusters {
+				objects = append(objects, cluster.DeepCopy())
+			}
+			r := &HelmChartProxyReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(fakeScheme).
+					WithObjects(objects...).
+					Build(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+
+			result, err := r.deleteOrphanedHelmReleaseProxies(ctx, tc.helmChartProxy, nil, releases)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(result.IsZero()).To(BeTrue())
+
+			remaining := &addonsv1alpha1.HelmReleaseProxyList{}
+			g.Expect(r.List(ctx, remaining)).To(Succeed())
+			names := []string{}
+			for _, hrp := range remaining.Items {
+				names = append(names, hrp.Name)
+			}
+			g.Expect(names).To(ConsistOf(tc.expectedRemaining))
+
+			if tc.expectRolloutStatusNil {
+				g.Expect(tc.helmChartProxy.Status.UninstallRollout).To(BeNil())
+			} else {
+				g.Expect(tc.helmChartProxy.Status.UninstallRollout.Count).To(Equal(tc.expectedRolloutStatus.Count))
+				g.Expect(tc.helmChartProxy.Status.UninstallRollout.StepSize).To(Equal(tc.expectedRolloutStatus.StepSize))
+			}
+		})
+	}
+}
+
+func TestHoldUninstalls(t *testing.T) {
+	t.Parallel()
+
+	orphans := []addonsv1alpha1.HelmReleaseProxy{}
+	for _, name := range []string{"test-cluster-3", "test-cluster-1", "test-cluster-2"} {
+		orphans = append(orphans, addonsv1alpha1.HelmReleaseProxy{
+			Spec: addonsv1alpha1.HelmReleaseProxySpec{
+				ClusterRef: corev1.ObjectReference{Name: name, Namespace: "test-namespace"},
+			},
+		})
+	}
+
+	testcases := []struct {
+		name             string
+		annotations      map[string]string
+		threshold        *int32
+		releasesToDelete []addonsv1alpha1.HelmReleaseProxy
+		expectHeld       bool
+		expectedReason   string
+		expectConfirmed  bool
+	}{
+		{
+			name:             "no threshold",
+			releasesToDelete: orphans,
+		},
+		{
+			name:             "within the threshold",
+			threshold:        ptr.To[int32](3),
+			releasesToDelete: orphans,
+		},
+		{
+			name:             "dry run",
+			annotations:      map[string]string{addonsv1alpha1.UninstallDryRunAnnotation: "true"},
+			releasesToDelete: orphans,
+			expectHeld:       true,
+			expectedReason:   addonsv1alpha1.UninstallDryRunReason,
+		},
+		{
+			name:             "exceeds the threshold",
+			threshold:        ptr.To[int32](2),
+			releasesToDelete: orphans,
+			expectHeld:       true,
+			expectedReason:   addonsv1alpha1.UninstallConfirmationRequiredReason,
+		},
+		{
+			name:             "confirmation of fewer Clusters",
+			annotations:      map[string]string{addonsv1alpha1.ConfirmUninstallAnnotation: "2"},
+			threshold:        ptr.To[int32](1),
+			releasesToDelete: orphans,
+			expectHeld:       true,
+			expectedReason:   addonsv1alpha1.UninstallConfirmationRequiredReason,
+			expectConfirmed:  true,
+		},
+		{
+			name:             "confirmed",
+			annotations:      map[string]string{addonsv1alpha1.ConfirmUninstallAnnotation: "3"},
+			threshold:        ptr.To[int32](1),
+			releasesToDelete: orphans,
+			expectConfirmed:  true,
+		},
+		{
+			name:        "removes the confirmation once nothing is pending",
+			annotations: map[string]string{addonsv1alpha1.ConfirmUninstallAnnotation: "3"},
+			threshold:   ptr.To[int32](1),
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			t.Parallel()
+
+			helmChartProxy := fakeHelmChartProxy1.DeepCopy()
+			helmChartProxy.Annotations = tc.annotations
+			helmChartProxy.Spec.UninstallConfirmationThreshold = tc.threshold
+
+			g.Expect(holdUninstalls(ctx, helmChartProxy, tc.releasesToDelete)).To(Equal(tc.expectHeld))
+			if tc.expectConfirmed {
+				g.Expect(helmChartProxy.Annotations).To(HaveKey(addonsv1alpha1.ConfirmUninstallAnnotation))
+			} else {
+				g.Expect(helmChartProxy.Annotations).NotTo(HaveKey(addonsv1alpha1.ConfirmUninstallAnnotation))
+			}
+
+			if !tc.expectHeld {
+				g.Expect(helmChartProxy.Status.PendingUninstalls).To(BeEmpty())
+				g.Expect(conditions.Has(helmChartProxy, addonsv1alpha1.UninstallsConfirmedCondition)).To(BeFalse())
+
+				return
+			}
+			names := []string{}
+			for _, ref := range helmChartProxy.Status.PendingUninstalls {
+				names = append(names, ref.Name)
+			}
+			g.Expect(names).To(Equal([]string{"test-cluster-1", "test-cluster-2", "test-cluster-3"}))
+			g.Expect(conditions.GetReason(helmChartProxy, addonsv1alpha1.UninstallsConfirmedCondition)).To(Equal(tc.expectedReason))
+		})
+	}
+}
+
+func TestValuesByReference(t *testing.T) {
+	g := NewWithT(t)
+
+	helmChartProxy := &addonsv1alpha1.HelmChartProxy{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: addonsv1alpha1.GroupVersion.String(),
+			Kind:       "HelmChartProxy",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-hcp",
+			Namespace: "test-namespace",
+		},
+		Spec: addonsv1alpha1.HelmChartProxySpec{
+			ChartName: "test-chart-name",
+			ValuesByReference: &addonsv1alpha1.ValuesByReference{
+				MinSize: ptr.To(int32(32)),
+			},
+		},
+	}
+	cluster := &clusterv1.Cluster{
+		TypeMeta:   metav1.TypeMeta{APIVersion: clusterv1.GroupVersion.String(), Kind: "Cluster"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", Namespace: "test-namespace"},
+	}
+	parsedValues := "replicas: 2\ndashboards:\n  overview: a long and stable dashboard definition\n"
+
+	helmReleaseProxy := constructHelmReleaseProxy(nil, helmChartProxy, parsedValues, cluster)
+	g.Expect(helmReleaseProxy.Spec.Values).To(Equal("replicas: 2\n"))
+	g.Expect(helmReleaseProxy.Spec.ValuesRefs).To(HaveLen(1))
+	ref := helmReleaseProxy.Spec.ValuesRefs[0]
+	g.Expect(ref.Key).To(Equal("dashboards"))
+	g.Expect(ref.ConfigMapName).To(Equal(internal.ValuesConfigMapName("test-hcp", ref.Hash)))
+	g.Expect(hasHelmReleaseProxySpecChanged(helmReleaseProxy, helmChartProxy, parsedValues, cluster)).To(BeFalse())
+	g.Expect(hasHelmReleaseProxySpecChanged(helmReleaseProxy, helmChartProxy, "replicas: 2\n", cluster)).To(BeTrue())
+
+	staleConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-hcp-values-stale",
+			Namespace: "test-namespace",
+			Labels: map[string]string{
+				addonsv1alpha1.HelmChartProxyLabelName: "test-hcp",
+				addonsv1alpha1.ValuesBlockLabelName:    "true",
+			},
+		},
+	}
+	helmReleaseProxy.Name = "test-hrp"
+	helmReleaseProxy.GenerateName = ""
+	c := fake.NewClientBuilder().WithScheme(fakeScheme).WithObjects(helmChartProxy, helmReleaseProxy, staleConfigMap).Build()
+	r := &HelmChartProxyReconciler{Client: c}
+
+	_, blocks := splitReleaseValues(helmChartProxy, parsedValues)
+	g.Expect(r.createValuesConfigMaps(ctx, helmChartProxy, blocks)).To(Succeed())
+	g.Expect(r.createValuesConfigMaps(ctx, helmChartProxy, blocks)).To(Succeed(), "existing ConfigMaps are kept")
+	g.Expect(r.deleteUnreferencedValuesConfigMaps(ctx, helmChartProxy)).To(Succeed())
+
+	configMapList := &corev1.ConfigMapList{}
+	g.Expect(c.List(ctx, configMapList, client.InNamespace("test-namespace"))).To(Succeed())
+	g.Expect(configMapList.Items).To(HaveLen(1))
+	g.Expect(configMapList.Items[0].Name).To(Equal(ref.ConfigMapName))
+
+	resolved, err := internal.ResolveValues(ctx, c, "test-namespace", helmReleaseProxy.Spec)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(resolved).To(MatchYAML(parsedValues))
+}
+
+func TestPropagateClusterLabels(t *testing.T) {
+	g := NewWithT(t)
+
+	helmChartProxy := &addonsv1alpha1.HelmChartProxy{
+		TypeMeta:   metav1.TypeMeta{APIVersion: addonsv1alpha1.GroupVersion.String(), Kind: "HelmChartProxy"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-hcp", Namespace: "test-namespace"},
+		Spec: addonsv1alpha1.HelmChartProxySpec{
+			ChartName:              "test-chart-name",
+			PropagateClusterLabels: []string{"team", "env", "region"},
+		},
+	}
+	cluster := &clusterv1.Cluster{
+		TypeMeta: metav1.TypeMeta{APIVersion: clusterv1.GroupVersion.String(), Kind: "Cluster"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-cluster",
+			Namespace: "test-namespace",
+			Labels:    map[string]string{"team": "a", "env": "prod", "tier": "gold"},
+		},
+	}
+
+	helmReleaseProxy := constructHelmReleaseProxy(nil, helmChartProxy, "", cluster)
+	g.Expect(helmReleaseProxy.Spec.ReleaseLabels).To(Equal(map[string]string{"team": "a", "env": "prod"}))
+	g.Expect(helmReleaseProxy.Labels).To(Equal(map[string]string{
+		clusterv1.ClusterNameLabel:             "test-cluster",
+		addonsv1alpha1.HelmChartProxyLabelName: "test-hcp",
+		"team":                                 "a",
+		"env":                                  "prod",
+	}))
+	g.Expect(hasHelmReleaseProxySpecChanged(helmReleaseProxy, helmChartProxy, "", cluster)).To(BeFalse())
+	g.Expect(constructHelmReleaseProxy(helmReleaseProxy, helmChartProxy, "", cluster)).To(BeNil())
+
+	cluster.Labels = map[string]string{"team": "b", "region": "eu"}
+	g.Expect(hasHelmReleaseProxySpecChanged(helmReleaseProxy, helmChartProxy, "", cluster)).To(BeTrue())
+	helmReleaseProxy = constructHelmReleaseProxy(helmReleaseProxy, helmChartProxy, "", cluster)
+	g.Expect(helmReleaseProxy.Spec.ReleaseLabels).To(Equal(map[string]string{"team": "b", "region": "eu"}))
+	g.Expect(helmReleaseProxy.Labels).To(Equal(map[string]string{
+		clusterv1.ClusterNameLabel:             "test-cluster",
+		addonsv1alpha1.HelmChartProxyLabelName: "test-hcp",
+		"team":                                 "b",
+		"region":                               "eu",
+	}), "labels no longer propagated are removed")
+
+	helmChartProxy.Spec.PropagateClusterLabels = nil
+	helmReleaseProxy = constructHelmReleaseProxy(helmReleaseProxy, helmChartProxy, "", cluster)
+	g.Expect(helmReleaseProxy.Spec.ReleaseLabels).To(BeNil())
+	g.Expect(helmReleaseProxy.Labels).To(HaveLen(2))
+}
+
+func TestRepositoryRef(t *testing.T) {
+	g := NewWithT(t)
+
+	helmChartProxy := &addonsv1alpha1.HelmChartProxy{
+		TypeMeta:   metav1.TypeMeta{APIVersion: addonsv1alpha1.GroupVersion.String(), Kind: "HelmChartProxy"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-hcp", Namespace: "test-namespace"},
+		Spec: addonsv1alpha1.HelmChartProxySpec{
+			ChartName:     "test-chart-name",
+			RepositoryRef: &addonsv1alpha1.HelmRepositoryReference{Name: "corp"},
+		},
+	}
+	cluster := &clusterv1.Cluster{
+		TypeMeta:   metav1.TypeMeta{APIVersion: clusterv1.GroupVersion.String(), Kind: "Cluster"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", Namespace: "test-namespace"},
+	}
+
+	helmReleaseProxy := constructHelmReleaseProxy(nil, helmChartProxy, "", cluster)
+	g.Expect(helmReleaseProxy.Spec.RepoURL).To(BeEmpty())
+	g.Expect(helmReleaseProxy.Spec.RepositoryRef).To(Equal(&addonsv1alpha1.HelmRepositoryReference{Name: "corp", Namespace: "test-namespace"}))
+	g.Expect(hasHelmReleaseProxySpecChanged(helmReleaseProxy, helmChartProxy, "", cluster)).To(BeFalse())
+
+	helmChartProxy.Spec.RepositoryRef = &addonsv1alpha1.HelmRepositoryReference{Name: "mirror"}
+	g.Expect(hasHelmReleaseProxySpecChanged(helmReleaseProxy, helmChartProxy, "", cluster)).To(BeTrue())
+	g.Expect(shouldReinstallHelmRelease(ctx, helmReleaseProxy, helmChartProxy)).To(BeFalse(), "switching the repository does not reinstall the release")
+}
+
+func TestDriftPolicy(t *testing.T) {
+	g := NewWithT(t)
+
+	helmChartProxy := &addonsv1alpha1.HelmChartProxy{
+		TypeMeta:   metav1.TypeMeta{APIVersion: addonsv1alpha1.GroupVersion.String(), Kind: "HelmChartProxy"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-hcp", Namespace: "test-namespace"},
+		Spec: addonsv1alpha1.HelmChartProxySpec{
+			ChartName:   "test-chart-name",
+			RepoURL:     "https://test-repo-url",
+			DriftPolicy: string(addonsv1alpha1.DriftPolicyWarn),
+			DriftIgnoreRules: []addonsv1alpha1.DriftIgnoreRule{
+				{Kind: "Deployment", Paths: []string{"/spec/replicas"}},
+			},
+		},
+	}
+	cluster := &clusterv1.Cluster{
+		TypeMeta:   metav1.TypeMeta{APIVersion: clusterv1.GroupVersion.String(), Kind: "Cluster"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", Namespace: "test-namespace"},
+	}
+
+	helmReleaseProxy := constructHelmReleaseProxy(nil, helmChartProxy, "", cluster)
+	g.Expect(helmReleaseProxy.Spec.DriftPolicy).To(Equal(string(addonsv1alpha1.DriftPolicyWarn)))
+	g.Expect(helmReleaseProxy.Spec.DriftIgnoreRules).To(Equal(helmChartProxy.Spec.DriftIgnoreRules))
+	g.Expect(hasHelmReleaseProxySpecChanged(helmReleaseProxy, helmChartProxy, "", cluster)).To(BeFalse())
+
+	helmChartProxy.Spec.DriftIgnoreRules = []addonsv1alpha1.DriftIgnoreRule{
+		{Kind: "Deployment", Paths: []string{"/spec/replicas", "/spec/template/metadata/annotations"}},
+	}
+	g.Expect(hasHelmReleaseProxySpecChanged(helmReleaseProxy, helmChartProxy, "", cluster)).To(BeTrue())
+}
+
+func TestDriftDetection(t *testing.T) {
+	g := NewWithT(t)
+
+	helmChartProxy := &addonsv1alpha1.HelmChartProxy{
+		TypeMeta:   metav1.TypeMeta{APIVersion: addonsv1alpha1.GroupVersion.String(), Kind: "HelmChartProxy"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-hcp", Namespace: "test-namespace"},
+		Spec: addonsv1alpha1.HelmChartProxySpec{
+			ChartName: "test-chart-name",
+			RepoURL:   "https://test-repo-url",
+			DriftDetection: &addonsv1alpha1.DriftDetection{
+				Enabled:  true,
+				Interval: &metav1.Duration{Duration: 10 * time.Minute},
+			},
+		},
+	}
+	cluster := &clusterv1.Cluster{
+		TypeMeta:   metav1.TypeMeta{APIVersion: clusterv1.GroupVersion.String(), Kind: "Cluster"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", Namespace: "test-namespace"},
+	}
+
+	helmReleaseProxy := constructHelmReleaseProxy(nil, helmChartProxy, "", cluster)
+	g.Expect(helmReleaseProxy.Spec.DriftDetection).To(Equal(helmChartProxy.Spec.DriftDetection))
+	g.Expect(hasHelmReleaseProxySpecChanged(helmReleaseProxy, helmChartProxy, "", cluster)).To(BeFalse())
+
+	helmChartProxy.Spec.DriftDetection = &addonsv1alpha1.DriftDetection{Enabled: false}
+	g.Expect(hasHelmReleaseProxySpecChanged(helmReleaseProxy, helmChartProxy, "", cluster)).To(BeTrue())
+}
+
+func TestReconcileIntervalDefaultsResyncPeriod(t *testing.T) {
+	g := NewWithT(t)
+
+	helmChartProxy := &addonsv1alpha1.HelmChartProxy{
+		TypeMeta:   metav1.TypeMeta{APIVersion: addonsv1alpha1.GroupVersion.String(), Kind: "HelmChartProxy"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-hcp", Namespace: "test-namespace"},
+		Spec: addonsv1alpha1.HelmChartProxySpec{
+			ChartName:         "test-chart-name",
+			RepoURL:           "https://test-repo-url",
+			ReconcileInterval: &metav1.Duration{Duration: 15 * time.Minute},
+		},
+	}
+	cluster := &clusterv1.Cluster{
+		TypeMeta:   metav1.TypeMeta{APIVersion: clusterv1.GroupVersion.String(), Kind: "Cluster"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", Namespace: "test-namespace"},
+	}
+
+	helmReleaseProxy := constructHelmReleaseProxy(nil, helmChartProxy, "", cluster)
+	g.Expect(helmReleaseProxy.Spec.ResyncPeriod).To(Equal(&metav1.Duration{Duration: 15 * time.Minute}))
+	g.Expect(hasHelmReleaseProxySpecChanged(helmReleaseProxy, helmChartProxy, "", cluster)).To(BeFalse())
+
+	// An explicit ResyncPeriod takes precedence.
+	helmChartProxy.Spec.ResyncPeriod = &metav1.Duration{Duration: time.Minute}
+	g.Expect(hasHelmReleaseProxySpecChanged(helmReleaseProxy, helmChartProxy, "", cluster)).To(BeTrue())
+	g.Expect(constructHelmReleaseProxy(nil, helmChartProxy, "", cluster).Spec.ResyncPeriod).To(Equal(&metav1.Duration{Duration: time.Minute}))
+}
+
+func TestImpersonation(t *testing.T) {
+	g := NewWithT(t)
+
+	helmChartProxy := &addonsv1alpha1.HelmChartProxy{
+		TypeMeta:   metav1.TypeMeta{APIVersion: addonsv1alpha1.GroupVersion.String(), Kind: "HelmChartProxy"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-hcp", Namespace: "test-namespace"},
+		Spec: addonsv1alpha1.HelmChartProxySpec{
+			ChartName:     "test-chart-name",
+			RepoURL:       "https://test-repo-url",
+			Impersonation: &addonsv1alpha1.Impersonation{User: "caaph:platform-team"},
+		},
+	}
+	cluster := &clusterv1.Cluster{
+		TypeMeta:   metav1.TypeMeta{APIVersion: clusterv1.GroupVersion.String(), Kind: "Cluster"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", Namespace: "test-namespace"},
+	}
+
+	helmReleaseProxy := constructHelmReleaseProxy(nil, helmChartProxy, "", cluster)
+	g.Expect(helmReleaseProxy.Spec.Impersonation).To(Equal(helmChartProxy.Spec.Impersonation))
+	g.Expect(hasHelmReleaseProxySpecChanged(helmReleaseProxy, helmChartProxy, "", cluster)).To(BeFalse())
+
+	helmChartProxy.Spec.Impersonation = &addonsv1alpha1.Impersonation{User: "caaph:platform-team", Groups: []string{"platform"}}
+	g.Expect(hasHelmReleaseProxySpecChanged(helmReleaseProxy, helmChartProxy, "", cluster)).To(BeTrue())
+}
+
+func TestChartVerification(t *testing.T) {
+	g := NewWithT(t)
+
+	helmChartProxy := &addonsv1alpha1.HelmChartProxy{
+		TypeMeta:   metav1.TypeMeta{APIVersion: addonsv1alpha1.GroupVersion.String(), Kind: "HelmChartProxy"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-hcp", Namespace: "test-namespace"},
+		Spec: addonsv1alpha1.HelmChartProxySpec{
+			ChartName: "test-chart-name",
+			RepoURL:   "oci://test-registry/charts",
+			Verify: &addonsv1alpha1.ChartVerification{
+				Cosign: &addonsv1alpha1.CosignVerification{PublicKeySecretRef: &corev1.SecretReference{Name: "cosign"}},
+			},
+		},
+	}
+	cluster := &clusterv1.Cluster{
+		TypeMeta:   metav1.TypeMeta{APIVersion: clusterv1.GroupVersion.String(), Kind: "Cluster"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", Namespace: "test-namespace"},
+	}
+
+	// The Secret references default to the namespace of the HelmChartProxy, which is not changed.
+	helmReleaseProxy := constructHelmReleaseProxy(nil, helmChartProxy, "", cluster)
+	g.Expect(helmReleaseProxy.Spec.Verify.Cosign.PublicKeySecretRef).To(Equal(&corev1.SecretReference{Name: "cosign", Namespace: "test-namespace"}))
+	g.Expect(helmChartProxy.Spec.Verify.Cosign.PublicKeySecretRef.Namespace).To(BeEmpty())
+	g.Expect(hasHelmReleaseProxySpecChanged(helmReleaseProxy, helmChartProxy, "", cluster)).To(BeFalse())
+
+	helmChartProxy.Spec.Verify.Cosign = &addonsv1alpha1.CosignVerification{
+		Keyless: &addonsv1alpha1.CosignKeylessVerification{
+			Issuer:               "https://token.actions.githubusercontent.com",
+			Subject:              "https://github.com/org/repo/.github/workflows/release.yaml@refs/heads/main",
+			TrustedRootSecretRef: corev1.SecretReference{Name: "sigstore"},
+		},
+	}
+	g.Expect(hasHelmReleaseProxySpecChanged(helmReleaseProxy, helmChartProxy, "", cluster)).To(BeTrue())
+	helmReleaseProxy = constructHelmReleaseProxy(helmReleaseProxy, helmChartProxy, "", cluster)
+	g.Expect(helmReleaseProxy.Spec.Verify.Cosign.Keyless.TrustedRootSecretRef.Namespace).To(Equal("test-namespace"))
+}
+
+func TestPostRenderer(t *testing.T) {
+	g := NewWithT(t)
+
+	helmChartProxy := &addonsv1alpha1.HelmChartProxy{
+		TypeMeta:   metav1.TypeMeta{APIVersion: addonsv1alpha1.GroupVersion.String(), Kind: "HelmChartProxy"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-hcp", Namespace: "test-namespace"},
+		Spec: addonsv1alpha1.HelmChartProxySpec{
+			ChartName: "test-chart-name",
+			RepoURL:   "https://test-repo-url",
+			PostRenderer: &addonsv1alpha1.PostRenderer{
+				Patches: []addonsv1alpha1.KustomizePatch{{
+					Patch:  "spec:\n  template:\n    spec:\n      nodeSelector:\n        role: infra\n",
+					Target: &addonsv1alpha1.PatchSelector{Kind: "Deployment"},
+				}},
+			},
+		},
+	}
+	cluster := &clusterv1.Cluster{
+		TypeMeta:   metav1.TypeMeta{APIVersion: clusterv1.GroupVersion.String(), Kind: "Cluster"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", Namespace: "test-namespace"},
+	}
+
+	helmReleaseProxy := constructHelmReleaseProxy(nil, helmChartProxy, "", cluster)
+	g.Expect(helmReleaseProxy.Spec.PostRenderer).To(Equal(helmChartProxy.Spec.PostRenderer))
+	g.Expect(hasHelmReleaseProxySpecChanged(helmReleaseProxy, helmChartProxy, "", cluster)).To(BeFalse())
+
+	helmChartProxy.Spec.PostRenderer = &addonsv1alpha1.PostRenderer{
+		Patches: []addonsv1alpha1.KustomizePatch{{
+			Patch:  "- op: add\n  path: /spec/template/spec/tolerations\n  value: []\n",
+			Target: &addonsv1alpha1.PatchSelector{Kind: "Deployment"},
+		}},
+	}
+	g.Expect(hasHelmReleaseProxySpecChanged(helmReleaseProxy, helmChartProxy, "", cluster)).To(BeTrue())
+
+	helmChartProxy.Spec.PostRenderer = nil
+	g.Expect(hasHelmReleaseProxySpecChanged(helmReleaseProxy, helmChartProxy, "", cluster)).To(BeTrue())
+	helmReleaseProxy = constructHelmReleaseProxy(helmReleaseProxy, helmChartProxy, "", cluster)
+	g.Expect(helmReleaseProxy.Spec.PostRenderer).To(BeNil())
+}
+
+func TestGitChartSource(t *testing.T) {
+	g := NewWithT(t)
+
+	helmChartProxy := &addonsv1alpha1.HelmChartProxy{
+		TypeMeta:   metav1.TypeMeta{APIVersion: addonsv1alpha1.GroupVersion.String(), Kind: "HelmChartProxy"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-hcp", Namespace: "test-namespace"},
+		Spec: addonsv1alpha1.HelmChartProxySpec{
+			ChartName: "test-chart-name",
+			Git: &addonsv1alpha1.GitChartSource{
+				URL:           "https://git.example.com/charts.git",
+				Ref:           "main",
+				Path:          "charts/test-chart-name",
+				AuthSecretRef: &corev1.SecretReference{Name: "git-credentials"},
+			},
+		},
+	}
+	cluster := &clusterv1.Cluster{
+		TypeMeta:   metav1.TypeMeta{APIVersion: clusterv1.GroupVersion.String(), Kind: "Cluster"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", Namespace: "test-namespace"},
+	}
+
+	helmReleaseProxy := constructHelmReleaseProxy(nil, helmChartProxy, "", cluster)
+	g.Expect(helmReleaseProxy.Spec.RepoURL).To(BeEmpty())
+	g.Expect(helmReleaseProxy.Spec.Git).To(Equal(&addonsv1alpha1.GitChartSource{
+		URL:           "https://git.example.com/charts.git",
+		Ref:           "main",
+		Path:          "charts/test-chart-name",
+		AuthSecretRef: &corev1.SecretReference{Name: "git-credentials", Namespace: "test-namespace"},
+	}))
+	g.Expect(helmChartProxy.Spec.Git.AuthSecretRef.Namespace).To(BeEmpty(), "the HelmChartProxy is not modified")
+	g.Expect(hasHelmReleaseProxySpecChanged(helmReleaseProxy, helmChartProxy, "", cluster)).To(BeFalse())
+
+	helmChartProxy.Spec.Git.Ref = "v1.0.0"
+	g.Expect(hasHelmReleaseProxySpecChanged(helmReleaseProxy, helmChartProxy, "", cluster)).To(BeTrue())
+	helmReleaseProxy = constructHelmReleaseProxy(helmReleaseProxy, helmChartProxy, "", cluster)
+	g.Expect(helmReleaseProxy.Spec.Git.Ref).To(Equal("v1.0.0"))
+}
+
+func TestManifests(t *testing.T) {
+	g := NewWithT(t)
+
+	manifests := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test-config\n"
+	helmChartProxy := &addonsv1alpha1.HelmChartProxy{
+		TypeMeta:   metav1.TypeMeta{APIVersion: addonsv1alpha1.GroupVersion.String(), Kind: "HelmChartProxy"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-hcp", Namespace: "test-namespace"},
+		Spec: addonsv1alpha1.HelmChartProxySpec{
+			ChartName: "test-addon",
+			Manifests: manifests,
+		},
+	}
+	cluster := &clusterv1.Cluster{
+		TypeMeta:   metav1.TypeMeta{APIVersion: clusterv1.GroupVersion.String(), Kind: "Cluster"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", Namespace: "test-namespace"},
+	}
+
+	helmReleaseProxy := constructHelmReleaseProxy(nil, helmChartProxy, manifests, cluster)
+	g.Expect(helmReleaseProxy.Spec.Manifests).To(Equal(manifests))
+	g.Expect(helmReleaseProxy.Spec.Values).To(BeEmpty())
+	g.Expect(hasHelmReleaseProxySpecChanged(helmReleaseProxy, helmChartProxy, manifests, cluster)).To(BeFalse())
+
+	changed := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test-config-2\n"
+	g.Expect(hasHelmReleaseProxySpecChanged(helmReleaseProxy, helmChartProxy, changed, cluster)).To(BeTrue())
+	helmReleaseProxy = constructHelmReleaseProxy(helmReleaseProxy, helmChartProxy, changed, cluster)
+	g.Expect(helmReleaseProxy.Spec.Manifests).To(Equal(changed))
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helmchartproxy
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+	helmRelease "helm.sh/helm/v3/pkg/release"
+	"k8s.io/apimachinery/pkg/types"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/cluster-api/controllers/remote"
+)
+
+// checkLastBatchReleases re-verifies the Helm releases of the Clusters of the previous rollout batch directly on the
+// workload Clusters, as the Ready conditions of their HelmReleaseProxies may lag behind the releases. It returns a
+// description of the releases that did not pass the check, or an empty string if all of them passed.
+func (r *HelmChartProxyReconciler) checkLastBatchReleases(ctx context.Context, helmChartProxy *addonsv1alpha1.HelmChartProxy, helmReleaseProxies []addonsv1alpha1.HelmReleaseProxy) string {
+	if helmChartProxy.Status.Rollout == nil {
+		return ""
+	}
+
+	failed := []string{}
+	for _, clusterName := range helmChartProxy.Status.Rollout.LastBatch {
+		idx := slices.IndexFunc(helmReleaseProxies, func(h addonsv1alpha1.HelmReleaseProxy) bool { return h.Spec.ClusterRef.Name == clusterName })
+		if idx < 0 {
+			// The Cluster is no longer selected, so its release does not hold back the rollout.
+			continue
+		}
+
+		if err := r.checkRelease(ctx, &helmReleaseProxies[idx], helmChartProxy.Spec.Rollout.ReleaseCheck); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", clusterName, err))
+		}
+	}
+
+	return strings.Join(failed, "; ")
+}
+
+// checkRelease returns an error if the Helm release of the HelmReleaseProxy is not deployed on the workload Cluster with
+// the chart version of the HelmReleaseProxy, or if its resources are not ready when the check requires them to be.
+func (r *HelmChartProxyReconciler) checkRelease(ctx context.Context, helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy, check *addonsv1alpha1.RolloutReleaseCheck) error {
+	clusterKey := types.NamespacedName{Namespace: helmReleaseProxy.Spec.ClusterRef.Namespace, Name: helmReleaseProxy.Spec.ClusterRef.Name}
+	if clusterKey.Namespace == "" {
+		clusterKey.Namespace = helmReleaseProxy.Namespace
+	}
+
+	restConfig, err := remote.RESTConfig(ctx, "caaph", r.Client, clusterKey)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get kubeconfig")
+	}
+
+	release, err := r.HelmClient.GetHelmRelease(ctx, restConfig, helmReleaseProxy.Spec)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get Helm release")
+	}
+	if release.Info == nil || release.Info.Status != helmRelease.StatusDeployed {
+		status := helmRelease.StatusUnknown
+		if release.Info != nil {
+			status = release.Info.Status
+		}
+
+		return errors.Errorf("Helm release %s is %s", release.Name, status)
+	}
+
+	// A version constraint is resolved on install, so only an exact version can be compared with the release.
+	if expected, err := semver.StrictNewVersion(strings.TrimPrefix(helmReleaseProxy.Spec.Version, "v")); err == nil && release.Chart != nil && release.Chart.Metadata != nil {
+		if actual, err := semver.NewVersion(release.Chart.Metadata.Version); err != nil || !actual.Equal(expected) {
+			return errors.Errorf("Helm release %s has chart version %s instead of %s", release.Name, release.Chart.Metadata.Version, helmReleaseProxy.Spec.Version)
+		}
+	}
+
+	if check != nil && check.CheckResources {
+		progress, err := r.HelmClient.GetHelmReleaseProgress(ctx, restConfig, helmReleaseProxy.Spec)
+		if err != nil {
+			return errors.Wrapf(err, "failed to check resources of Helm release %s", release.Name)
+		}
+		if progress.Pending > 0 {
+			return errors.Errorf("%d of %d resources of Helm release %s are not ready", progress.Pending, progress.Ready+progress.Pending, release.Name)
+		}
+	}
+
+	return nil
+}
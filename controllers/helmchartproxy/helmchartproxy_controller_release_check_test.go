@@ -0,0 +1,162 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helmchartproxy
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	helmChart "helm.sh/helm/v3/pkg/chart"
+	helmRelease "helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/cluster-api-addon-provider-helm/internal/mocks"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCheckLastBatchReleases(t *testing.T) {
+	t.Parallel()
+
+	kubeconfigSecret := func(clusterName string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      clusterName + "-kubeconfig",
+				Namespace: "test-namespace",
+			},
+			Data: map[string][]byte{"value": []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: ` + clusterName + `
+  cluster:
+    server: https://` + clusterName + `:6443
+contexts:
+- name: ` + clusterName + `
+  context:
+    cluster: ` + clusterName + `
+    user: admin
+current-context: ` + clusterName + `
+users:
+- name: admin
+  user:
+    token: test-token
+`)},
+		}
+	}
+	helmReleaseProxy := func(clusterName string) addonsv1alpha1.HelmReleaseProxy {
+		return addonsv1alpha1.HelmReleaseProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-hrp-" + clusterName,
+				Namespace: "test-namespace",
+			},
+			Spec: addonsv1alpha1.HelmReleaseProxySpec{
+				ClusterRef:  corev1.ObjectReference{Namespace: "test-namespace", Name: clusterName},
+				ReleaseName: "test-release",
+				Version:     "v1.2.0",
+			},
+		}
+	}
+	release := func(status helmRelease.Status, version string) *helmRelease.Release {
+		return &helmRelease.Release{
+			Name:  "test-release",
+			Info:  &helmRelease.Info{Status: status},
+			Chart: &helmChart.Chart{Metadata: &helmChart.Metadata{Version: version}},
+		}
+	}
+
+	testcases := []struct {
+		name           string
+		lastBatch      []string
+		check          addonsv1alpha1.RolloutReleaseCheck
+		expect         func(m *mocks.MockClientMockRecorder)
+		expectedFailed string
+	}{
+		{
+			name:      "passes when the releases of the last batch are deployed",
+			lastBatch: []string{"test-cluster-1"},
+			expect: func(m *mocks.MockClientMockRecorder) {
+				m.GetHelmRelease(gomock.Any(), gomock.Any(), gomock.Any()).Return(release(helmRelease.StatusDeployed, "1.2.0"), nil).Times(1)
+			},
+		},
+		{
+			name:      "fails when a release is still pending",
+			lastBatch: []string{"test-cluster-1"},
+			expect: func(m *mocks.MockClientMockRecorder) {
+				m.GetHelmRelease(gomock.Any(), gomock.Any(), gomock.Any()).Return(release(helmRelease.StatusPendingUpgrade, "1.2.0"), nil).Times(1)
+			},
+			expectedFailed: "test-cluster-1: Helm release test-release is pending-upgrade",
+		},
+		{
+			name:      "fails when a release has another chart version",
+			lastBatch: []string{"test-cluster-1"},
+			expect: func(m *mocks.MockClientMockRecorder) {
+				m.GetHelmRelease(gomock.Any(), gomock.Any(), gomock.Any()).Return(release(helmRelease.StatusDeployed, "1.1.0"), nil).Times(1)
+			},
+			expectedFailed: "test-cluster-1: Helm release test-release has chart version 1.1.0 instead of v1.2.0",
+		},
+		{
+			name:      "fails when resources are not ready",
+			lastBatch: []string{"test-cluster-1"},
+			check:     addonsv1alpha1.RolloutReleaseCheck{CheckResources: true},
+			expect: func(m *mocks.MockClientMockRecorder) {
+				m.GetHelmRelease(gomock.Any(), gomock.Any(), gomock.Any()).Return(release(helmRelease.StatusDeployed, "1.2.0"), nil).Times(1)
+				m.GetHelmReleaseProgress(gomock.Any(), gomock.Any(), gomock.Any()).Return(&addonsv1alpha1.ReleaseProgress{Ready: 3, Pending: 1}, nil).Times(1)
+			},
+			expectedFailed: "test-cluster-1: 1 of 4 resources of Helm release test-release are not ready",
+		},
+		{
+			name:      "reports clusters whose kubeconfig cannot be read and skips unselected clusters",
+			lastBatch: []string{"test-cluster-2", "test-cluster-3"},
+			expect:    func(m *mocks.MockClientMockRecorder) {},
+			expectedFailed: "test-cluster-2: failed to get kubeconfig: failed to retrieve kubeconfig secret for Cluster test-namespace/test-cluster-2: " +
+				`secrets "test-cluster-2-kubeconfig" not found`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			t.Parallel()
+
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			clientMock := mocks.NewMockClient(mockCtrl)
+			tc.expect(clientMock.EXPECT())
+
+			r := &HelmChartProxyReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(fakeScheme).
+					WithObjects(kubeconfigSecret("test-cluster-1")).
+					Build(),
+				HelmClient: clientMock,
+			}
+
+			helmChartProxy := &addonsv1alpha1.HelmChartProxy{
+				Spec: addonsv1alpha1.HelmChartProxySpec{
+					Rollout: &addonsv1alpha1.Rollout{ReleaseCheck: &tc.check},
+				},
+				Status: addonsv1alpha1.HelmChartProxyStatus{
+					Rollout: &addonsv1alpha1.RolloutStatus{LastBatch: tc.lastBatch},
+				},
+			}
+			helmReleaseProxies := []addonsv1alpha1.HelmReleaseProxy{helmReleaseProxy("test-cluster-1"), helmReleaseProxy("test-cluster-2")}
+
+			g.Expect(r.checkLastBatchReleases(ctx, helmChartProxy, helmReleaseProxies)).To(Equal(tc.expectedFailed))
+		})
+	}
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helmchartproxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// revisionHashLength is the number of hex characters of the hash of the spec appended to the name of a revision.
+const revisionHashLength = 10
+
+// rollbackToRevision restores the spec of the HelmChartProxy from the HelmChartProxyRevision named by the
+// RollbackToRevisionAnnotation and returns true if it did. The annotation is removed even if the revision cannot be
+// restored, in which case a warning event is emitted instead.
+func (r *HelmChartProxyReconciler) rollbackToRevision(ctx context.Context, helmChartProxy *addonsv1alpha1.HelmChartProxy) (bool, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	value, ok := helmChartProxy.GetAnnotations()[addonsv1alpha1.RollbackToRevisionAnnotation]
+	if !ok {
+		return false, nil
+	}
+
+	annotations := helmChartProxy.GetAnnotations()
+	delete(annotations, addonsv1alpha1.RollbackToRevisionAnnotation)
+	helmChartProxy.SetAnnotations(annotations)
+
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		r.Recorder.Eventf(helmChartProxy, corev1.EventTypeWarning, addonsv1alpha1.RevisionNotFoundReason, "Revision %q to roll back to is not a number", value)
+
+		return false, nil
+	}
+
+	revisions, err := r.listRevisions(ctx, helmChartProxy)
+	if err != nil {
+		return false, err
+	}
+
+	idx := slices.IndexFunc(revisions, func(revision addonsv1alpha1.HelmChartProxyRevision) bool { return revision.Spec.Revision == number })
+	if idx < 0 {
+		r.Recorder.Eventf(helmChartProxy, corev1.EventTypeWarning, addonsv1alpha1.RevisionNotFoundReason, "Revision %d to roll back to does not exist", number)
+
+		return false, nil
+	}
+
+	spec := addonsv1alpha1.HelmChartProxySpec{}
+	if err := json.Unmarshal(revisions[idx].Spec.Data.Raw, &spec); err != nil {
+		r.Recorder.Eventf(helmChartProxy, corev1.EventTypeWarning, addonsv1alpha1.RevisionNotFoundReason, "Revision %d to roll back to cannot be restored: %v", number, err)
+
+		return false, nil
+	}
+
+	log.Info("Rolling back HelmChartProxy to revision", "name", helmChartProxy.Name, "revision", number)
+	helmChartProxy.Spec = spec
+	r.Recorder.Eventf(helmChartProxy, corev1.EventTypeNormal, addonsv1alpha1.RolledBackToRevisionReason, "Rolled back to revision %d", number)
+
+	return true, nil
+}
+
+// reconcileRevisions records the current spec of the HelmChartProxy in a HelmChartProxyRevision owned by it, like a
+// ControllerRevision. A spec matching a previously recorded one moves that revision to the latest number instead of
+// creating a new one. The oldest revisions beyond the RevisionHistoryLimit are deleted.
+func (r *HelmChartProxyReconciler) reconcileRevisions(ctx context.Context, helmChartProxy *addonsv1alpha1.HelmChartProxy) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	data, err := json.Marshal(helmChartProxy.Spec)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal spec of HelmChartProxy %s", helmChartProxy.Name)
+	}
+
+	revisions, err := r.listRevisions(ctx, helmChartProxy)
+	if err != nil {
+		return err
+	}
+
+	var latest int64
+	if len(revisions) > 0 {
+		latest = revisions[len(revisions)-1].Spec.Revision
+	}
+
+	name := revisionName(helmChartProxy.Name, data)
+	idx := slices.IndexFunc(revisions, func(revision addonsv1alpha1.HelmChartProxyRevision) bool { return revision.Name == name })
+
+	switch {
+	case idx < 0:
+		revision := addonsv1alpha1.HelmChartProxyRevision{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: helmChartProxy.Namespace,
+				Labels: map[string]string{
+					addonsv1alpha1.HelmChartProxyLabelName: helmChartProxy.Name,
+				},
+			},
+			Spec: addonsv1alpha1.HelmChartProxyRevisionSpec{
+				HelmChartProxyName: helmChartProxy.Name,
+				Revision:           latest + 1,
+				Data:               runtime.RawExtension{Raw: data},
+			},
+		}
+		if err := controllerutil.SetControllerReference(helmChartProxy, &revision, r.Client.Scheme()); err != nil {
+			return errors.Wrapf(err, "failed to set owner of HelmChartProxyRevision %s", revision.Name)
+		}
+		if err := r.Create(ctx, &revision); err != nil {
+			return errors.Wrapf(err, "failed to create HelmChartProxyRevision %s", revision.Name)
+		}
+		log.V(2).Info("Created HelmChartProxyRevision", "helmChartProxy", helmChartProxy.Name, "revision", revision.Spec.Revision)
+		revisions = append(revisions, revision)
+	case revisions[idx].Spec.Revision != latest:
+		revision := revisions[idx]
+		before := revision.DeepCopy()
+		revision.Spec.Revision = latest + 1
+		if err := r.Patch(ctx, &revision, client.MergeFrom(before)); err != nil {
+			return errors.Wrapf(err, "failed to patch HelmChartProxyRevision %s", revision.Name)
+		}
+		log.V(2).Info("Moved HelmChartProxyRevision to latest revision", "helmChartProxy", helmChartProxy.Name, "revision", revision.Spec.Revision)
+		revisions = append(slices.Delete(revisions, idx, idx+1), revision)
+	}
+	helmChartProxy.Status.Revision = revisions[len(revisions)-1].Spec.Revision
+
+	limit := int(ptr.Deref(helmChartProxy.Spec.RevisionHistoryLimit, addonsv1alpha1.DefaultRevisionHistoryLimit))
+	for i := 0; i < len(revisions)-limit; i++ {
+		if err := r.Delete(ctx, &revisions[i]); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete HelmChartProxyRevision %s", revisions[i].Name)
+		}
+	}
+
+	return nil
+}
+
+// listRevisions returns the HelmChartProxyRevisions of the HelmChartProxy by ascending revision.
+func (r *HelmChartProxyReconciler) listRevisions(ctx context.Context, helmChartProxy *addonsv1alpha1.HelmChartProxy) ([]addonsv1alpha1.HelmChartProxyRevision, error) {
+	revisionList := &addonsv1alpha1.HelmChartProxyRevisionList{}
+	if err := r.List(ctx, revisionList, client.InNamespace(helmChartProxy.Namespace), client.MatchingLabels{addonsv1alpha1.HelmChartProxyLabelName: helmChartProxy.Name}); err != nil {
+		return nil, errors.Wrapf(err, "failed to list HelmChartProxyRevisions of HelmChartProxy %s", helmChartProxy.Name)
+	}
+
+	revisions := []addonsv1alpha1.HelmChartProxyRevision{}
+	for _, revision := range revisionList.Items {
+		if revision.Spec.HelmChartProxyName == helmChartProxy.Name {
+			revisions = append(revisions, revision)
+		}
+	}
+	sort.SliceStable(revisions, func(i, j int) bool {
+		return revisions[i].Spec.Revision < revisions[j].Spec.Revision
+	})
+
+	return revisions, nil
+}
+
+// revisionName returns the name of the revision of the HelmChartProxy with the spec, suffixed by the hash of the spec so
+// that identical specs map to the same revision.
+func revisionName(helmChartProxyName string, data []byte) string {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])[:revisionHashLength]
+
+	// Leave room for the hash in the maximum length of a name.
+	if maxLength := 253 - revisionHashLength - 1; len(helmChartProxyName) > maxLength {
+		helmChartProxyName = helmChartProxyName[:maxLength]
+	}
+
+	return fmt.Sprintf("%s-%s", helmChartProxyName, hash)
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helmchartproxy
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// revisionVersions returns the chart versions recorded by the revisions of the HelmChartProxy by revision number.
+func revisionVersions(g *WithT, r *HelmChartProxyReconciler, helmChartProxy *addonsv1alpha1.HelmChartProxy) map[int64]string {
+	revisions, err := r.listRevisions(ctx, helmChartProxy)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	versions := map[int64]string{}
+	for _, revision := range revisions {
+		g.Expect(revision.OwnerReferences).To(HaveLen(1))
+		g.Expect(revision.OwnerReferences[0].Name).To(Equal(helmChartProxy.Name))
+		spec := addonsv1alpha1.HelmChartProxySpec{}
+		g.Expect(json.Unmarshal(revision.Spec.Data.Raw, &spec)).To(Succeed())
+		versions[revision.Spec.Revision] = spec.Version
+	}
+
+	return versions
+}
+
+func TestReconcileRevisions(t *testing.T) {
+	g := NewWithT(t)
+
+	helmChartProxy := environmentHelmChartProxy("1.0.0")
+	r := &HelmChartProxyReconciler{
+		Client: fake.NewClientBuilder().WithScheme(fakeScheme).WithObjects(helmChartProxy).Build(),
+	}
+
+	g.Expect(r.reconcileRevisions(ctx, helmChartProxy)).To(Succeed())
+	g.Expect(helmChartProxy.Status.Revision).To(Equal(int64(1)))
+	g.Expect(r.reconcileRevisions(ctx, helmChartProxy)).To(Succeed())
+	g.Expect(revisionVersions(g, r, helmChartProxy)).To(Equal(map[int64]string{1: "1.0.0"}), "an unchanged spec is not recorded again")
+
+	helmChartProxy.Spec.Version = "2.0.0"
+	g.Expect(r.reconcileRevisions(ctx, helmChartProxy)).To(Succeed())
+	g.Expect(helmChartProxy.Status.Revision).To(Equal(int64(2)))
+	g.Expect(revisionVersions(g, r, helmChartProxy)).To(Equal(map[int64]string{1: "1.0.0", 2: "2.0.0"}))
+
+	helmChartProxy.Spec.Version = "1.0.0"
+	g.Expect(r.reconcileRevisions(ctx, helmChartProxy)).To(Succeed())
+	g.Expect(helmChartProxy.Status.Revision).To(Equal(int64(3)))
+	g.Expect(revisionVersions(g, r, helmChartProxy)).To(Equal(map[int64]string{2: "2.0.0", 3: "1.0.0"}), "a previous spec moves to the latest revision")
+
+	helmChartProxy.Spec.Version = "3.0.0"
+	helmChartProxy.Spec.RevisionHistoryLimit = ptr.To(int32(2))
+	g.Expect(r.reconcileRevisions(ctx, helmChartProxy)).To(Succeed())
+	g.Expect(helmChartProxy.Status.Revision).To(Equal(int64(4)))
+	g.Expect(revisionVersions(g, r, helmChartProxy)).To(Equal(map[int64]string{3: "1.0.0", 4: "3.0.0"}), "the oldest revisions beyond the limit are deleted")
+
+	other := environmentHelmChartProxy("1.0.0")
+	other.Name = "other-hcp"
+	g.Expect(r.reconcileRevisions(ctx, other)).To(Succeed())
+	g.Expect(other.Status.Revision).To(Equal(int64(1)), "revisions are numbered per HelmChartProxy")
+}
+
+func TestRollbackToRevision(t *testing.T) {
+	testcases := []struct {
+		name               string
+		annotation         *string
+		expectedRolledBack bool
+		expectedVersion    string
+		expectedEvent      string
+	}{
+		{
+			name:            "does nothing without the annotation",
+			expectedVersion: "2.0.0",
+		},
+		{
+			name:               "restores the spec of the revision",
+			annotation:         ptr.To("1"),
+			expectedRolledBack: true,
+			expectedVersion:    "1.0.0",
+			expectedEvent:      "Normal RolledBackToRevision Rolled back to revision 1",
+		},
+		{
+			name:            "warns about a revision that does not exist",
+			annotation:      ptr.To("5"),
+			expectedVersion: "2.0.0",
+			expectedEvent:   "Warning RevisionNotFound Revision 5 to roll back to does not exist",
+		},
+		{
+			name:            "warns about a revision that is not a number",
+			annotation:      ptr.To("latest"),
+			expectedVersion: "2.0.0",
+			expectedEvent:   `Warning RevisionNotFound Revision "latest" to roll back to is not a number`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			helmChartProxy := environmentHelmChartProxy("1.0.0")
+			recorder := record.NewFakeRecorder(10)
+			r := &HelmChartProxyReconciler{
+				Client:   fake.NewClientBuilder().WithScheme(fakeScheme).WithObjects(helmChartProxy).Build(),
+				Recorder: recorder,
+			}
+			g.Expect(r.reconcileRevisions(ctx, helmChartProxy)).To(Succeed())
+			helmChartProxy.Spec.Version = "2.0.0"
+			g.Expect(r.reconcileRevisions(ctx, helmChartProxy)).To(Succeed())
+
+			if tc.annotation != nil {
+				helmChartProxy.Annotations = map[string]string{addonsv1alpha1.RollbackToRevisionAnnotation: *tc.annotation}
+			}
+			rolledBack, err := r.rollbackToRevision(ctx, helmChartProxy)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(rolledBack).To(Equal(tc.expectedRolledBack))
+			g.Expect(helmChartProxy.Spec.Version).To(Equal(tc.expectedVersion))
+			g.Expect(helmChartProxy.Annotations).NotTo(HaveKey(addonsv1alpha1.RollbackToRevisionAnnotation))
+
+			if tc.expectedEvent == "" {
+				g.Expect(recorder.Events).To(BeEmpty())
+			} else {
+				g.Expect(recorder.Events).To(Receive(Equal(tc.expectedEvent)))
+			}
+
+			// The restored spec moves its revision to the latest number once it is reconciled.
+			if tc.expectedRolledBack {
+				g.Expect(r.reconcileRevisions(ctx, helmChartProxy)).To(Succeed())
+				g.Expect(helmChartProxy.Status.Revision).To(Equal(int64(3)))
+				revisions, err := r.listRevisions(ctx, helmChartProxy)
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(revisions).To(HaveLen(2))
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helmchartproxy
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/cluster-api-addon-provider-helm/internal"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// maxJobNameLength is the maximum length of the name of a Job, which is also set as the value of a label of its Pods.
+const maxJobNameLength = 63
+
+// rolloutHooksFor returns the hooks of the HelmChartProxy run in the phase.
+func rolloutHooksFor(helmChartProxy *addonsv1alpha1.HelmChartProxy, phase addonsv1alpha1.RolloutHookPhase) []addonsv1alpha1.RolloutHook {
+	if helmChartProxy.Spec.Rollout == nil || helmChartProxy.Spec.Rollout.Hooks == nil {
+		return nil
+	}
+
+	if phase == addonsv1alpha1.RolloutHookPhasePreRollout {
+		return helmChartProxy.Spec.Rollout.Hooks.PreRollout
+	}
+
+	return helmChartProxy.Spec.Rollout.Hooks.PostRollout
+}
+
+// reconcileRolloutHooks runs the hooks of the phase for the current generation of the HelmChartProxy one after the other,
+// each once the previous one succeeded, and records their state in the status. It returns true once all of them
+// succeeded. Until then, the HelmReleaseProxiesRolloutCompleted condition is marked false with the pending or failed hook.
+func (r *HelmChartProxyReconciler) reconcileRolloutHooks(ctx context.Context, helmChartProxy *addonsv1alpha1.HelmChartProxy, phase addonsv1alpha1.RolloutHookPhase) (bool, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	// The state of the hooks of previous generations is dropped, so that each generation runs the hooks again.
+	helmChartProxy.Status.RolloutHooks = slices.DeleteFunc(helmChartProxy.Status.RolloutHooks, func(s addonsv1alpha1.RolloutHookStatus) bool {
+		return s.Generation != helmChartProxy.Generation
+	})
+	if len(helmChartProxy.Status.RolloutHooks) == 0 {
+		helmChartProxy.Status.RolloutHooks = nil
+	}
+
+	for _, hook := range rolloutHooksFor(helmChartProxy, phase) {
+		status := rolloutHookStatusFor(helmChartProxy, phase, hook.Name)
+		if status.State == addonsv1alpha1.RolloutHookStateSucceeded {
+			continue
+		}
+
+		previousState := status.State
+		var err error
+		if hook.Job != nil {
+			err = r.reconcileRolloutHookJob(ctx, helmChartProxy, phase, hook, status)
+		} else {
+			r.callRolloutHookURL(ctx, helmChartProxy, phase, hook, status)
+		}
+		if err != nil {
+			return false, err
+		}
+
+		switch status.State {
+		case addonsv1alpha1.RolloutHookStateSucceeded:
+			log.Info("Rollout hook succeeded", "name", helmChartProxy.Name, "phase", phase, "hook", hook.Name, "generation", helmChartProxy.Generation)
+
+			continue
+		case addonsv1alpha1.RolloutHookStateFailed:
+			if previousState != addonsv1alpha1.RolloutHookStateFailed {
+				log.Info("Rollout hook failed", "name", helmChartProxy.Name, "phase", phase, "hook", hook.Name, "generation", helmChartProxy.Generation, "message", status.Message)
+				r.Recorder.Eventf(helmChartProxy, corev1.EventTypeWarning, addonsv1alpha1.RolloutHookFailedReason, "%s hook %s failed: %s", phase, hook.Name, status.Message)
+			}
+			conditions.MarkFalse(helmChartProxy, addonsv1alpha1.HelmReleaseProxiesRolloutCompletedCondition, addonsv1alpha1.RolloutHookFailedReason, clusterv1.ConditionSeverityError, "%s hook %s failed: %s", phase, hook.Name, status.Message)
+		default:
+			conditions.MarkFalse(helmChartProxy, addonsv1alpha1.HelmReleaseProxiesRolloutCompletedCondition, addonsv1alpha1.RolloutHookPendingReason, clusterv1.ConditionSeverityInfo, "Waiting for %s hook %s to succeed", phase, hook.Name)
+		}
+
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// rolloutHookStatusFor returns the status of the hook of the phase for the current generation of the HelmChartProxy,
+// adding it if it does not exist yet.
+func rolloutHookStatusFor(helmChartProxy *addonsv1alpha1.HelmChartProxy, phase addonsv1alpha1.RolloutHookPhase, name string) *addonsv1alpha1.RolloutHookStatus {
+	for i := range helmChartProxy.Status.RolloutHooks {
+		status := &helmChartProxy.Status.RolloutHooks[i]
+		if status.Phase == phase && status.Name == name {
+			return status
+		}
+	}
+
+	helmChartProxy.Status.RolloutHooks = append(helmChartProxy.Status.RolloutHooks, addonsv1alpha1.RolloutHookStatus{
+		Name:       name,
+		Phase:      phase,
+		Generation: helmChartProxy.Generation,
+		State:      addonsv1alpha1.RolloutHookStateRunning,
+	})
+
+	return &helmChartProxy.Status.RolloutHooks[len(helmChartProxy.Status.RolloutHooks)-1]
+}
+
+// reconcileRolloutHookJob creates the Job of the hook if it does not exist and sets the state of the hook from the Job. A
+// failed Job is not retried, but is created again once it is deleted.
+func (r *HelmChartProxyReconciler) reconcileRolloutHookJob(ctx context.Context, helmChartProxy *addonsv1alpha1.HelmChartProxy, phase addonsv1alpha1.RolloutHookPhase, hook addonsv1alpha1.RolloutHook, status *addonsv1alpha1.RolloutHookStatus) error {
+	job := &batchv1.Job{}
+	key := types.NamespacedName{Namespace: helmChartProxy.Namespace, Name: rolloutHookJobName(helmChartProxy, phase, hook.Name)}
+	err := r.Get(ctx, key, job)
+	if apierrors.IsNotFound(err) {
+		job = constructRolloutHookJob(helmChartProxy, phase, hook)
+		if err := r.Create(ctx, job); err != nil && !apierrors.IsAlreadyExists(err) {
+			return errors.Wrapf(err, "failed to create Job %s of %s hook %s", key.Name, phase, hook.Name)
+		}
+		status.State = addonsv1alpha1.RolloutHookStateRunning
+		status.Message = ""
+		status.CompletionTime = nil
+
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to get Job %s of %s hook %s", key.Name, phase, hook.Name)
+	}
+
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+
+		switch condition.Type {
+		case batchv1.JobComplete:
+			status.State = addonsv1alpha1.RolloutHookStateSucceeded
+			status.Message = ""
+			status.CompletionTime = ptr.To(condition.LastTransitionTime)
+
+			return nil
+		case batchv1.JobFailed:
+			status.State = addonsv1alpha1.RolloutHookStateFailed
+			status.Message = fmt.Sprintf("Job %s failed: %s", job.Name, condition.Message)
+			status.CompletionTime = ptr.To(condition.LastTransitionTime)
+
+			return nil
+		}
+	}
+	status.State = addonsv1alpha1.RolloutHookStateRunning
+
+	return nil
+}
+
+// callRolloutHookURL calls the URL of the hook and sets the state of the hook from the result. A failed call is retried
+// on the next reconcile.
+func (r *HelmChartProxyReconciler) callRolloutHookURL(ctx context.Context, helmChartProxy *addonsv1alpha1.HelmChartProxy, phase addonsv1alpha1.RolloutHookPhase, hook addonsv1alpha1.RolloutHook, status *addonsv1alpha1.RolloutHookStatus) {
+	err := internal.CallRolloutHookURL(ctx, hook, internal.RolloutHookRequest{
+		Name:       helmChartProxy.Name,
+		Namespace:  helmChartProxy.Namespace,
+		Generation: helmChartProxy.Generation,
+		Phase:      phase,
+		Hook:       hook.Name,
+	})
+
+	now := metav1.Now()
+	status.CompletionTime = &now
+	if err != nil {
+		status.State = addonsv1alpha1.RolloutHookStateFailed
+		status.Message = err.Error()
+
+		return
+	}
+	status.State = addonsv1alpha1.RolloutHookStateSucceeded
+	status.Message = ""
+}
+
+// rolloutHookJobName returns the name of the Job of the hook of the phase for the current generation of the
+// HelmChartProxy. The name of the HelmChartProxy is truncated if the name would be too long.
+func rolloutHookJobName(helmChartProxy *addonsv1alpha1.HelmChartProxy, phase addonsv1alpha1.RolloutHookPhase, hookName string) string {
+	suffix := fmt.Sprintf("-%s-%s-%d", strings.ToLower(strings.TrimSuffix(string(phase), "Rollout")), hookName, helmChartProxy.Generation)
+	prefix := helmChartProxy.Name
+	if len(prefix)+len(suffix) > maxJobNameLength {
+		prefix = strings.TrimRight(prefix[:maxJobNameLength-len(suffix)], "-.")
+	}
+
+	return prefix + suffix
+}
+
+// constructRolloutHookJob returns the Job of the hook of the phase for the current generation of the HelmChartProxy,
+// which is owned by the HelmChartProxy.
+func constructRolloutHookJob(helmChartProxy *addonsv1alpha1.HelmChartProxy, phase addonsv1alpha1.RolloutHookPhase, hook addonsv1alpha1.RolloutHook) *batchv1.Job {
+	env := []corev1.EnvVar{
+		{Name: "HELM_CHART_PROXY_NAME", Value: helmChartProxy.Name},
+		{Name: "HELM_CHART_PROXY_NAMESPACE", Value: helmChartProxy.Namespace},
+		{Name: "HELM_CHART_PROXY_GENERATION", Value: strconv.FormatInt(helmChartProxy.Generation, 10)},
+		{Name: "ROLLOUT_HOOK_PHASE", Value: string(phase)},
+	}
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      rolloutHookJobName(helmChartProxy, phase, hook.Name),
+			Namespace: helmChartProxy.Namespace,
+			Labels: map[string]string{
+				addonsv1alpha1.HelmChartProxyLabelName: helmChartProxy.Name,
+			},
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(helmChartProxy, addonsv1alpha1.GroupVersion.WithKind("HelmChartProxy"))},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: hook.Job.BackoffLimit,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy:      corev1.RestartPolicyNever,
+					ServiceAccountName: hook.Job.ServiceAccountName,
+					Containers: []corev1.Container{
+						{
+							Name:    "hook",
+							Image:   hook.Job.Image,
+							Command: hook.Job.Command,
+							Args:    hook.Job.Args,
+							Env:     append(env, hook.Job.Env...),
+						},
+					},
+				},
+			},
+		},
+	}
+	if hook.Timeout != nil && hook.Timeout.Duration > 0 {
+		job.Spec.ActiveDeadlineSeconds = ptr.To(int64(hook.Timeout.Duration.Seconds()))
+	}
+
+	return job
+}
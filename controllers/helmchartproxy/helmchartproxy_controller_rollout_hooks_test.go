@@ -0,0 +1,138 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helmchartproxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcileRolloutHooks(t *testing.T) {
+	g := NewWithT(t)
+
+	failing := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			http.Error(w, "change freeze in effect", http.StatusConflict)
+		}
+	}))
+	defer server.Close()
+
+	helmChartProxy := continuousProxy.DeepCopy()
+	helmChartProxy.Generation = 2
+	helmChartProxy.Spec.Rollout = &addonsv1alpha1.Rollout{
+		Hooks: &addonsv1alpha1.RolloutHooks{
+			PreRollout: []addonsv1alpha1.RolloutHook{
+				{Name: "ticket", URL: server.URL},
+				{Name: "snapshot", Job: &addonsv1alpha1.RolloutHookJob{Image: "snapshot:v1", Args: []string{"--dashboards"}}},
+			},
+		},
+	}
+	// The state of hooks of previous generations is dropped.
+	helmChartProxy.Status.RolloutHooks = []addonsv1alpha1.RolloutHookStatus{
+		{Name: "ticket", Phase: addonsv1alpha1.RolloutHookPhasePreRollout, Generation: 1, State: addonsv1alpha1.RolloutHookStateSucceeded},
+	}
+
+	r := &HelmChartProxyReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(fakeScheme).WithObjects(helmChartProxy).Build(),
+		Recorder: record.NewFakeRecorder(10),
+	}
+
+	// A failed call to the URL holds the rollout.
+	succeeded, err := r.reconcileRolloutHooks(ctx, helmChartProxy, addonsv1alpha1.RolloutHookPhasePreRollout)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(succeeded).To(BeFalse())
+	g.Expect(helmChartProxy.Status.RolloutHooks).To(HaveLen(1))
+	g.Expect(helmChartProxy.Status.RolloutHooks[0].Generation).To(Equal(int64(2)))
+	g.Expect(helmChartProxy.Status.RolloutHooks[0].State).To(Equal(addonsv1alpha1.RolloutHookStateFailed))
+	g.Expect(conditions.GetReason(helmChartProxy, addonsv1alpha1.HelmReleaseProxiesRolloutCompletedCondition)).To(Equal(addonsv1alpha1.RolloutHookFailedReason))
+	g.Expect(conditions.GetMessage(helmChartProxy, addonsv1alpha1.HelmReleaseProxiesRolloutCompletedCondition)).To(ContainSubstring("change freeze in effect"))
+
+	// Once the call succeeds, the Job of the next hook is created.
+	failing = false
+	succeeded, err = r.reconcileRolloutHooks(ctx, helmChartProxy, addonsv1alpha1.RolloutHookPhasePreRollout)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(succeeded).To(BeFalse())
+	g.Expect(helmChartProxy.Status.RolloutHooks[0].State).To(Equal(addonsv1alpha1.RolloutHookStateSucceeded))
+	g.Expect(helmChartProxy.Status.RolloutHooks[1].State).To(Equal(addonsv1alpha1.RolloutHookStateRunning))
+	g.Expect(conditions.GetReason(helmChartProxy, addonsv1alpha1.HelmReleaseProxiesRolloutCompletedCondition)).To(Equal(addonsv1alpha1.RolloutHookPendingReason))
+
+	job := &batchv1.Job{}
+	g.Expect(r.Get(ctx, types.NamespacedName{Namespace: "test-namespace", Name: "test-hcp-pre-snapshot-2"}, job)).To(Succeed())
+	g.Expect(job.Labels).To(HaveKeyWithValue(addonsv1alpha1.HelmChartProxyLabelName, "test-hcp"))
+	g.Expect(job.OwnerReferences).To(HaveLen(1))
+	g.Expect(job.Spec.Template.Spec.Containers[0].Image).To(Equal("snapshot:v1"))
+	g.Expect(job.Spec.Template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "ROLLOUT_HOOK_PHASE", Value: "PreRollout"}))
+
+	// The rollout proceeds once the Job completes.
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue, LastTransitionTime: metav1.Now()}}
+	g.Expect(r.Status().Update(ctx, job)).To(Succeed())
+	succeeded, err = r.reconcileRolloutHooks(ctx, helmChartProxy, addonsv1alpha1.RolloutHookPhasePreRollout)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(succeeded).To(BeTrue())
+	g.Expect(helmChartProxy.Status.RolloutHooks[1].State).To(Equal(addonsv1alpha1.RolloutHookStateSucceeded))
+	g.Expect(helmChartProxy.Status.RolloutHooks[1].CompletionTime).NotTo(BeNil())
+
+	// Without post-rollout hooks, there is nothing to wait for.
+	succeeded, err = r.reconcileRolloutHooks(ctx, helmChartProxy, addonsv1alpha1.RolloutHookPhasePostRollout)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(succeeded).To(BeTrue())
+}
+
+func TestReconcileRolloutHookJobFailed(t *testing.T) {
+	g := NewWithT(t)
+
+	helmChartProxy := continuousProxy.DeepCopy()
+	helmChartProxy.Generation = 1
+	hook := addonsv1alpha1.RolloutHook{Name: "warm", Job: &addonsv1alpha1.RolloutHookJob{Image: "warm:v1"}}
+	job := constructRolloutHookJob(helmChartProxy, addonsv1alpha1.RolloutHookPhasePostRollout, hook)
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "Job has reached the specified backoff limit"}}
+
+	r := &HelmChartProxyReconciler{
+		Client: fake.NewClientBuilder().WithScheme(fakeScheme).WithObjects(job).Build(),
+	}
+
+	status := &addonsv1alpha1.RolloutHookStatus{}
+	g.Expect(r.reconcileRolloutHookJob(ctx, helmChartProxy, addonsv1alpha1.RolloutHookPhasePostRollout, hook, status)).To(Succeed())
+	g.Expect(status.State).To(Equal(addonsv1alpha1.RolloutHookStateFailed))
+	g.Expect(status.Message).To(Equal("Job test-hcp-post-warm-1 failed: Job has reached the specified backoff limit"))
+}
+
+func TestRolloutHookJobName(t *testing.T) {
+	g := NewWithT(t)
+
+	helmChartProxy := continuousProxy.DeepCopy()
+	helmChartProxy.Generation = 12
+	g.Expect(rolloutHookJobName(helmChartProxy, addonsv1alpha1.RolloutHookPhasePostRollout, "warm")).To(Equal("test-hcp-post-warm-12"))
+
+	helmChartProxy.Name = strings.Repeat("a", 60) + "-chart"
+	name := rolloutHookJobName(helmChartProxy, addonsv1alpha1.RolloutHookPhasePreRollout, "ticket")
+	g.Expect(name).To(HaveLen(maxJobNameLength))
+	g.Expect(name).To(HaveSuffix("-pre-ticket-12"))
+}
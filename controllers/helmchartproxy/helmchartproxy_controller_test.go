@@ -17,7 +17,9 @@ limitations under the License.
 package helmchartproxy
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	. "github.com/onsi/gomega"
@@ -26,9 +28,13 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/ptr"
 	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/cluster-api-addon-provider-helm/internal"
+	addonsv1 "sigs.k8s.io/cluster-api/api/addons/v1beta1"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	expv1 "sigs.k8s.io/cluster-api/exp/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util"
 	"sigs.k8s.io/cluster-api/util/conditions"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -1052,6 +1058,7 @@ func TestReconcileNormal(t *testing.T) {
 					WithStatusSubresource(&addonsv1alpha1.HelmChartProxy{}).
 					WithStatusSubresource(&addonsv1alpha1.HelmReleaseProxy{}).
 					Build(),
+				Recorder: record.NewFakeRecorder(10),
 			}
 			result, err := r.Reconcile(ctx, request)
 
@@ -1351,6 +1358,261 @@ func TestRolloutReconcile(t *testing.T) {
 			expectedError:   "",
 			reconcileResult: reconcile.Result{Requeue: true},
 		},
+		{
+			name: "when HelmReleaseProxiesReadyCondition is false past the progress deadline, marks the rollout as stalled",
+			helmChartProxy: newRolloutProxy(
+				withRollout(&addonsv1alpha1.Rollout{
+					Install:          &addonsv1alpha1.RolloutOptions{StepInit: &intstr.IntOrString{Type: intstr.String, StrVal: "25%"}},
+					ProgressDeadline: &metav1.Duration{Duration: time.Minute},
+				}),
+				withRolloutStatus(&addonsv1alpha1.RolloutStatus{StepSize: ptr.To(1), Count: ptr.To(1), LastProgressTime: ptr.To(metav1.NewTime(time.Now().Add(-time.Hour)))}),
+				withConditions(
+					[]clusterv1.Condition{
+						{
+							Type:     addonsv1alpha1.HelmReleaseProxiesReadyCondition,
+							Status:   corev1.ConditionFalse,
+							Severity: clusterv1.ConditionSeverityInfo,
+						},
+					},
+				),
+			),
+			objects: []client.Object{cluster5, cluster6, cluster7, cluster8, hrpNotReady5},
+			expect: func(g *WithT, c client.Client, hcp *addonsv1alpha1.HelmChartProxy) {
+				g.Expect(conditions.IsFalse(hcp, addonsv1alpha1.HelmReleaseProxiesRolloutCompletedCondition)).To(BeTrue())
+				g.Expect(conditions.GetReason(hcp, addonsv1alpha1.HelmReleaseProxiesRolloutCompletedCondition)).To(Equal(addonsv1alpha1.RolloutProgressDeadlineExceededReason))
+				g.Expect((hcp.Status.Rollout.Count)).To(Equal(ptr.To(1)))
+			},
+			expectedError:   "",
+			reconcileResult: reconcile.Result{Requeue: true},
+		},
+		{
+			name: "when maxFailures hrp failed, halts the rollout",
+			helmChartProxy: newRolloutProxy(
+				withRollout(&addonsv1alpha1.Rollout{Install: &addonsv1alpha1.RolloutOptions{
+					StepInit:    &intstr.IntOrString{Type: intstr.String, StrVal: "25%"},
+					MaxFailures: &intstr.IntOrString{Type: intstr.Int, IntVal: 1},
+				}}),
+				withRolloutStatus(&addonsv1alpha1.RolloutStatus{StepSize: ptr.To(1), Count: ptr.To(1)}),
+				withConditions(
+					[]clusterv1.Condition{
+						{
+							Type:     addonsv1alpha1.HelmReleaseProxiesReadyCondition,
+							Status:   corev1.ConditionTrue,
+							Severity: clusterv1.ConditionSeverityInfo,
+						},
+					},
+				),
+			),
+			objects: []client.Object{cluster5, cluster6, cluster7, cluster8, func() client.Object {
+				hrp := hrpNotReady5.DeepCopy()
+				hrp.Status.Conditions[0].Severity = clusterv1.ConditionSeverityError
+
+				return hrp
+			}()},
+			expect: func(g *WithT, c client.Client, hcp *addonsv1alpha1.HelmChartProxy) {
+				g.Expect(conditions.GetReason(hcp, addonsv1alpha1.HelmReleaseProxiesRolloutCompletedCondition)).To(Equal(addonsv1alpha1.RolloutHaltedReason))
+
+				hrpList := &addonsv1alpha1.HelmReleaseProxyList{}
+				g.Expect(c.List(ctx, hrpList, client.InNamespace("test-namespace"))).To(Succeed())
+				g.Expect(hrpList.Items).To(HaveLen(1))
+				g.Expect((hcp.Status.Rollout.Count)).To(Equal(ptr.To(1)))
+			},
+			expectedError:   "",
+			reconcileResult: reconcile.Result{},
+		},
+		{
+			name: "when a halted rollout is resumed, removes the annotation and proceeds",
+			helmChartProxy: newRolloutProxy(
+				withRollout(&addonsv1alpha1.Rollout{Install: &addonsv1alpha1.RolloutOptions{
+					StepInit:    &intstr.IntOrString{Type: intstr.String, StrVal: "25%"},
+					MaxFailures: &intstr.IntOrString{Type: intstr.Int, IntVal: 1},
+				}}),
+				withRolloutStatus(&addonsv1alpha1.RolloutStatus{StepSize: ptr.To(1), Count: ptr.To(1)}),
+				withConditions(
+					[]clusterv1.Condition{
+						{
+							Type:     addonsv1alpha1.HelmReleaseProxiesReadyCondition,
+							Status:   corev1.ConditionFalse,
+							Severity: clusterv1.ConditionSeverityInfo,
+						},
+						{
+							Type:     addonsv1alpha1.HelmReleaseProxiesRolloutCompletedCondition,
+							Status:   corev1.ConditionFalse,
+							Severity: clusterv1.ConditionSeverityError,
+							Reason:   addonsv1alpha1.RolloutHaltedReason,
+						},
+					},
+				),
+				func(h *addonsv1alpha1.HelmChartProxy) {
+					h.Annotations = map[string]string{addonsv1alpha1.ResumeRolloutAnnotation: ""}
+				},
+			),
+			objects: []client.Object{cluster5, cluster6, cluster7, cluster8, hrpNotReady5},
+			expect: func(g *WithT, c client.Client, hcp *addonsv1alpha1.HelmChartProxy) {
+				g.Expect(conditions.GetReason(hcp, addonsv1alpha1.HelmReleaseProxiesRolloutCompletedCondition)).To(Equal(addonsv1alpha1.HelmReleaseProxiesRolloutNotCompleteReason))
+				g.Expect(hcp.Annotations).NotTo(HaveKey(addonsv1alpha1.ResumeRolloutAnnotation))
+			},
+			expectedError:   "",
+			reconcileResult: reconcile.Result{Requeue: true},
+		},
+		{
+			name: "when approval is given before the first batch, rolls out the first batch and keeps the annotation",
+			helmChartProxy: newRolloutProxy(
+				withRollout(&addonsv1alpha1.Rollout{Install: &addonsv1alpha1.RolloutOptions{
+					StepInit:         &intstr.IntOrString{Type: intstr.Int, IntVal: 1},
+					ApprovalRequired: true,
+				}}),
+				func(h *addonsv1alpha1.HelmChartProxy) {
+					h.Annotations = map[string]string{addonsv1alpha1.ApproveRolloutAnnotation: ""}
+				},
+			),
+			objects: []client.Object{cluster5, cluster6, cluster7, cluster8},
+			expect: func(g *WithT, c client.Client, hcp *addonsv1alpha1.HelmChartProxy) {
+				g.Expect(hcp.Annotations).To(HaveKey(addonsv1alpha1.ApproveRolloutAnnotation))
+
+				hrpList := &addonsv1alpha1.HelmReleaseProxyList{}
+				g.Expect(c.List(ctx, hrpList, client.InNamespace("test-namespace"))).To(Succeed())
+				g.Expect(hrpList.Items).To(HaveLen(1))
+				g.Expect((hcp.Status.Rollout.Count)).To(Equal(ptr.To(1)))
+				g.Expect((hcp.Status.Rollout.ApprovedCount)).To(BeNil())
+			},
+			expectedError:   "",
+			reconcileResult: reconcile.Result{Requeue: true},
+		},
+		{
+			name: "when approval is required and the next batch is not approved, pauses the rollout",
+			helmChartProxy: newRolloutProxy(
+				withRollout(&addonsv1alpha1.Rollout{Install: &addonsv1alpha1.RolloutOptions{
+					StepInit:         &intstr.IntOrString{Type: intstr.Int, IntVal: 1},
+					ApprovalRequired: true,
+				}}),
+				withRolloutStatus(&addonsv1alpha1.RolloutStatus{StepSize: ptr.To(1), Count: ptr.To(1)}),
+				withConditions(
+					[]clusterv1.Condition{
+						{
+							Type:   addonsv1alpha1.HelmReleaseProxiesReadyCondition,
+							Status: corev1.ConditionTrue,
+						},
+					},
+				),
+			),
+			objects: []client.Object{cluster5, cluster6, cluster7, cluster8, hrpReady5},
+			expect: func(g *WithT, c client.Client, hcp *addonsv1alpha1.HelmChartProxy) {
+				g.Expect(conditions.GetReason(hcp, addonsv1alpha1.HelmReleaseProxiesRolloutCompletedCondition)).To(Equal(addonsv1alpha1.RolloutAwaitingApprovalReason))
+
+				hrpList := &addonsv1alpha1.HelmReleaseProxyList{}
+				g.Expect(c.List(ctx, hrpList, client.InNamespace("test-namespace"))).To(Succeed())
+				g.Expect(hrpList.Items).To(HaveLen(1))
+				g.Expect((hcp.Status.Rollout.Count)).To(Equal(ptr.To(1)))
+			},
+			expectedError:   "",
+			reconcileResult: reconcile.Result{},
+		},
+		{
+			name: "when approval is required and the next batch is approved, removes the annotation and rolls out the next batch",
+			helmChartProxy: newRolloutProxy(
+				withRollout(&addonsv1alpha1.Rollout{Install: &addonsv1alpha1.RolloutOptions{
+					StepInit:         &intstr.IntOrString{Type: intstr.Int, IntVal: 1},
+					ApprovalRequired: true,
+				}}),
+				withRolloutStatus(&addonsv1alpha1.RolloutStatus{StepSize: ptr.To(1), Count: ptr.To(1)}),
+				withConditions(
+					[]clusterv1.Condition{
+						{
+							Type:   addonsv1alpha1.HelmReleaseProxiesReadyCondition,
+							Status: corev1.ConditionTrue,
+						},
+						{
+							Type:     addonsv1alpha1.HelmReleaseProxiesRolloutCompletedCondition,
+							Status:   corev1.ConditionFalse,
+							Severity: clusterv1.ConditionSeverityInfo,
+							Reason:   addonsv1alpha1.RolloutAwaitingApprovalReason,
+						},
+					},
+				),
+				func(h *addonsv1alpha1.HelmChartProxy) {
+					h.Annotations = map[string]string{addonsv1alpha1.ApproveRolloutAnnotation: ""}
+				},
+			),
+			objects: []client.Object{cluster5, cluster6, cluster7, cluster8, hrpReady5},
+			expect: func(g *WithT, c client.Client, hcp *addonsv1alpha1.HelmChartProxy) {
+				g.Expect(hcp.Annotations).NotTo(HaveKey(addonsv1alpha1.ApproveRolloutAnnotation))
+				g.Expect(conditions.GetReason(hcp, addonsv1alpha1.HelmReleaseProxiesRolloutCompletedCondition)).To(Equal(addonsv1alpha1.HelmReleaseProxiesRolloutNotCompleteReason))
+
+				hrpList := &addonsv1alpha1.HelmReleaseProxyList{}
+				g.Expect(c.List(ctx, hrpList, client.InNamespace("test-namespace"))).To(Succeed())
+				g.Expect(hrpList.Items).To(HaveLen(2))
+				g.Expect((hcp.Status.Rollout.Count)).To(Equal(ptr.To(2)))
+				// The approval does not carry over to the batch after the one it approved.
+				g.Expect((hcp.Status.Rollout.ApprovedCount)).To(Equal(ptr.To(1)))
+			},
+			expectedError:   "",
+			reconcileResult: reconcile.Result{Requeue: true},
+		},
+		{
+			name: "when a cluster is requested for reconciliation, creates its hrp outside of the rollout ordering and removes the annotation",
+			helmChartProxy: newRolloutProxy(
+				withRollout(&addonsv1alpha1.Rollout{Install: &addonsv1alpha1.RolloutOptions{StepInit: &intstr.IntOrString{Type: intstr.String, StrVal: "25%"}}}),
+				withRolloutStatus(&addonsv1alpha1.RolloutStatus{StepSize: ptr.To(1), Count: ptr.To(1)}),
+				withConditions(
+					[]clusterv1.Condition{
+						{
+							Type:     addonsv1alpha1.HelmReleaseProxiesReadyCondition,
+							Status:   corev1.ConditionFalse,
+							Severity: clusterv1.ConditionSeverityInfo,
+						},
+					},
+				),
+				func(h *addonsv1alpha1.HelmChartProxy) {
+					h.Annotations = map[string]string{addonsv1alpha1.ReconcileClusterAnnotation: "test-cluster-7"}
+				},
+			),
+			objects: []client.Object{cluster5, cluster6, cluster7, cluster8, hrpNotReady5},
+			expect: func(g *WithT, c client.Client, hcp *addonsv1alpha1.HelmChartProxy) {
+				hrpList := &addonsv1alpha1.HelmReleaseProxyList{}
+				g.Expect(c.List(ctx, hrpList, client.InNamespace("test-namespace"))).To(Succeed())
+				clusterNames := []string{}
+				for _, hrp := range hrpList.Items {
+					clusterNames = append(clusterNames, hrp.Spec.ClusterRef.Name)
+				}
+				g.Expect(clusterNames).To(ConsistOf("test-cluster-5", "test-cluster-7"))
+				g.Expect(hcp.Annotations).NotTo(HaveKey(addonsv1alpha1.ReconcileClusterAnnotation))
+				g.Expect((hcp.Status.Rollout.Count)).To(Equal(ptr.To(2)))
+			},
+			expectedError:   "",
+			reconcileResult: reconcile.Result{Requeue: true},
+		},
+		{
+			name: "when an existing hrp is not updated by the rollout batch, reports it as out of date",
+			helmChartProxy: newRolloutProxy(
+				withRollout(&addonsv1alpha1.Rollout{Install: &addonsv1alpha1.RolloutOptions{StepInit: &intstr.IntOrString{Type: intstr.Int, IntVal: 1}}}),
+				withRolloutStatus(&addonsv1alpha1.RolloutStatus{StepSize: ptr.To(1), Count: ptr.To(1)}),
+				withConditions(
+					[]clusterv1.Condition{
+						{
+							Type:   addonsv1alpha1.HelmReleaseProxiesReadyCondition,
+							Status: corev1.ConditionTrue,
+						},
+					},
+				),
+				func(h *addonsv1alpha1.HelmChartProxy) {
+					h.Spec.Version = "another-version"
+				},
+			),
+			objects: []client.Object{cluster5, cluster6, cluster7, cluster8, hrpReady5},
+			expect: func(g *WithT, c client.Client, hcp *addonsv1alpha1.HelmChartProxy) {
+				g.Expect(hcp.Status.OutOfDateReleases).To(BeEquivalentTo([]corev1.ObjectReference{
+					{
+						APIVersion: addonsv1alpha1.GroupVersion.String(),
+						Kind:       "HelmReleaseProxy",
+						Name:       hrpReady5.Name,
+						Namespace:  "test-namespace",
+					},
+				}))
+			},
+			expectedError:   "",
+			reconcileResult: reconcile.Result{Requeue: true},
+		},
 		{
 			name: "when HelmReleaseProxiesReadyCondition is true and 1 out of 4 hrp is rolled out and ready, it rolls out 2 more, sets count to 3 and step size to 2",
 			helmChartProxy: newRolloutProxy(
@@ -1599,6 +1861,7 @@ func TestRolloutReconcile(t *testing.T) {
 					WithStatusSubresource(&addonsv1alpha1.HelmChartProxy{}).
 					WithStatusSubresource(&addonsv1alpha1.HelmReleaseProxy{}).
 					Build(),
+				Recorder: record.NewFakeRecorder(10),
 			}
 			result, err := r.Reconcile(ctx, request)
 
@@ -1633,7 +1896,8 @@ func TestReconcileAfterMatchingClusterUnpaused(t *testing.T) {
 		Build()
 
 	r := &HelmChartProxyReconciler{
-		Client: c,
+		Client:   c,
+		Recorder: record.NewFakeRecorder(10),
 	}
 	result, err := r.Reconcile(ctx, request)
 	g.Expect(err).NotTo(HaveOccurred())
@@ -1659,8 +1923,570 @@ func TestReconcileAfterMatchingClusterUnpaused(t *testing.T) {
 	g.Expect(hrpList.Items).To(HaveLen(3))
 }
 
+func TestReconcileGloballyPaused(t *testing.T) {
+	g := NewWithT(t)
+
+	request := reconcile.Request{
+		NamespacedName: util.ObjectKey(continuousProxy),
+	}
+	pauseConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "caaph-pause",
+			Namespace: "caaph-system",
+		},
+		Data: map[string]string{
+			addonsv1alpha1.GlobalPausePausedKey: "true",
+			addonsv1alpha1.GlobalPauseReasonKey: "change freeze",
+		},
+	}
+
+	c := fake.NewClientBuilder().
+		WithScheme(fakeScheme).
+		WithObjects(cluster1, cluster2, continuousProxy, pauseConfigMap).
+		WithStatusSubresource(&addonsv1alpha1.HelmChartProxy{}).
+		WithStatusSubresource(&addonsv1alpha1.HelmReleaseProxy{}).
+		Build()
+
+	r := &HelmChartProxyReconciler{
+		Client:      c,
+		Recorder:    record.NewFakeRecorder(10),
+		GlobalPause: &internal.GlobalPause{Reader: c, ConfigMap: util.ObjectKey(pauseConfigMap)},
+	}
+	result, err := r.Reconcile(ctx, request)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(Equal(reconcile.Result{}))
+
+	// The status is updated, but no HelmReleaseProxies are created while the provider is paused.
+	hrpList := &addonsv1alpha1.HelmReleaseProxyList{}
+	g.Expect(c.List(ctx, hrpList, &client.ListOptions{Namespace: request.Namespace})).To(Succeed())
+	g.Expect(hrpList.Items).To(BeEmpty())
+	helmChartProxy := &addonsv1alpha1.HelmChartProxy{}
+	g.Expect(c.Get(ctx, request.NamespacedName, helmChartProxy)).To(Succeed())
+	g.Expect(helmChartProxy.Status.MatchingClusters).To(HaveLen(2))
+	condition := conditions.Get(helmChartProxy, addonsv1alpha1.HelmReleaseProxySpecsUpToDateCondition)
+	g.Expect(condition).NotTo(BeNil())
+	g.Expect(condition.Reason).To(Equal(addonsv1alpha1.GloballyPausedReason))
+	g.Expect(condition.Message).To(Equal("provider is paused by ConfigMap caaph-system/caaph-pause: change freeze"))
+
+	// Lifting the pause enqueues the HelmChartProxy, which then creates its HelmReleaseProxies.
+	g.Expect(r.GlobalPauseToHelmChartProxiesMapper(ctx, pauseConfigMap)).To(ConsistOf(request))
+	pauseConfigMap.Data[addonsv1alpha1.GlobalPausePausedKey] = "false"
+	g.Expect(c.Update(ctx, pauseConfigMap)).To(Succeed())
+
+	result, err = r.Reconcile(ctx, request)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(Equal(reconcile.Result{}))
+
+	hrpList = &addonsv1alpha1.HelmReleaseProxyList{}
+	g.Expect(c.List(ctx, hrpList, &client.ListOptions{Namespace: request.Namespace})).To(Succeed())
+	g.Expect(hrpList.Items).To(HaveLen(2))
+}
+
+func TestReconcileLightweightStatus(t *testing.T) {
+	g := NewWithT(t)
+
+	request := reconcile.Request{
+		NamespacedName: util.ObjectKey(continuousProxy),
+	}
+
+	c := fake.NewClientBuilder().
+		WithScheme(fakeScheme).
+		WithObjects(cluster1, cluster2, continuousProxy).
+		WithStatusSubresource(&addonsv1alpha1.HelmChartProxy{}).
+		WithStatusSubresource(&addonsv1alpha1.HelmReleaseProxy{}).
+		Build()
+
+	r := &HelmChartProxyReconciler{
+		Client:            c,
+		Recorder:          record.NewFakeRecorder(10),
+		LightweightStatus: true,
+	}
+	_, err := r.Reconcile(ctx, request)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// The Clusters are only counted, and only the Ready condition and the conditions that are not true are kept.
+	helmChartProxy := &addonsv1alpha1.HelmChartProxy{}
+	g.Expect(c.Get(ctx, request.NamespacedName, helmChartProxy)).To(Succeed())
+	g.Expect(helmChartProxy.Status.MatchingClusters).To(BeEmpty())
+	g.Expect(helmChartProxy.Status.MatchingClusterCount).To(Equal(int32(2)))
+	g.Expect(conditions.Has(helmChartProxy, clusterv1.ReadyCondition)).To(BeTrue())
+	for _, condition := range helmChartProxy.GetConditions() {
+		if condition.Type != clusterv1.ReadyCondition {
+			g.Expect(condition.Status != corev1.ConditionTrue || condition.Severity != clusterv1.ConditionSeverityNone).To(BeTrue(), string(condition.Type))
+		}
+	}
+}
+
+func TestClusterClassToHelmChartProxiesMapper(t *testing.T) {
+	g := NewWithT(t)
+
+	topologyCluster := func(cluster *clusterv1.Cluster, class string) *clusterv1.Cluster {
+		cluster = cluster.DeepCopy()
+		cluster.Spec.Topology = &clusterv1.Topology{Class: class, Version: "v1.30.0"}
+
+		return cluster
+	}
+	clusterClass := &clusterv1.ClusterClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-class",
+			Namespace: "test-namespace",
+		},
+	}
+
+	c := fake.NewClientBuilder().
+		WithScheme(fakeScheme).
+		WithObjects(topologyCluster(cluster1, "test-class"), topologyCluster(cluster2, "test-class"), topologyCluster(cluster3, "other-class"), continuousProxy).
+		Build()
+
+	r := &HelmChartProxyReconciler{
+		Client: c,
+	}
+
+	// Both Clusters using the ClusterClass are selected by the same HelmChartProxy, which is only enqueued once.
+	g.Expect(r.ClusterClassToHelmChartProxiesMapper(ctx, clusterClass)).To(ConsistOf(ctrl.Request{
+		NamespacedName: util.ObjectKey(continuousProxy),
+	}))
+
+	unusedClass := clusterClass.DeepCopy()
+	unusedClass.Name = "unused-class"
+	g.Expect(r.ClusterClassToHelmChartProxiesMapper(ctx, unusedClass)).To(BeEmpty())
+}
+
+func TestClusterToHelmChartProxiesMapperWatchFilter(t *testing.T) {
+	t.Parallel()
+
+	withWatchLabel := func(obj client.Object, value string) client.Object {
+		labels := map[string]string{clusterv1.WatchLabel: value}
+		for k, v := range obj.GetLabels() {
+			labels[k] = v
+		}
+		obj.SetLabels(labels)
+
+		return obj
+	}
+	withClusterWatchFilterValue := func(hcp *addonsv1alpha1.HelmChartProxy, value string) *addonsv1alpha1.HelmChartProxy {
+		hcp = hcp.DeepCopy()
+		hcp.Spec.ClusterWatchFilterValue = value
+
+		return hcp
+	}
+
+	testcases := []struct {
+		name             string
+		watchFilterValue string
+		helmChartProxy   client.Object
+		cluster          client.Object
+		expectMapped     bool
+	}{
+		{
+			name:           "cluster is mapped without watch filters",
+			helmChartProxy: continuousProxy.DeepCopy(),
+			cluster:        cluster1.DeepCopy(),
+			expectMapped:   true,
+		},
+		{
+			name:             "cluster without the global watch filter label is not mapped",
+			watchFilterValue: "platform",
+			helmChartProxy:   withWatchLabel(continuousProxy.DeepCopy(), "platform"),
+			cluster:          cluster1.DeepCopy(),
+		},
+		{
+			name:             "cluster with the watch filter label of the HelmChartProxy is mapped",
+			watchFilterValue: "platform",
+			helmChartProxy:   withWatchLabel(withClusterWatchFilterValue(continuousProxy, "team-a"), "platform"),
+			cluster:          withWatchLabel(cluster1.DeepCopy(), "team-a"),
+			expectMapped:     true,
+		},
+		{
+			name:             "cluster with another watch filter label than the HelmChartProxy is not mapped",
+			watchFilterValue: "platform",
+			helmChartProxy:   withWatchLabel(withClusterWatchFilterValue(continuousProxy, "team-a"), "platform"),
+			cluster:          withWatchLabel(cluster1.DeepCopy(), "team-b"),
+		},
+		{
+			name:             "HelmChartProxy without the global watch filter label is not mapped",
+			watchFilterValue: "platform",
+			helmChartProxy:   withClusterWatchFilterValue(continuousProxy, "team-a"),
+			cluster:          withWatchLabel(cluster1.DeepCopy(), "team-a"),
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			t.Parallel()
+
+			r := &HelmChartProxyReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(fakeScheme).
+					WithObjects(tc.helmChartProxy).
+					Build(),
+				WatchFilterValue: tc.watchFilterValue,
+			}
+
+			requests := r.ClusterToHelmChartProxiesMapper(ctx, tc.cluster)
+			if tc.expectMapped {
+				g.Expect(requests).To(ConsistOf(ctrl.Request{NamespacedName: util.ObjectKey(continuousProxy)}))
+			} else {
+				g.Expect(requests).To(BeEmpty())
+			}
+		})
+	}
+}
+
+func TestAggregateHelmReleaseProxyReadyCondition(t *testing.T) {
+	t.Parallel()
+
+	helmReleaseProxy := func(name string, ready bool) client.Object {
+		hrp := &addonsv1alpha1.HelmReleaseProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "test-namespace",
+				Labels:    map[string]string{addonsv1alpha1.HelmChartProxyLabelName: "test-hcp"},
+			},
+		}
+		if ready {
+			conditions.MarkTrue(hrp, clusterv1.ReadyCondition)
+		} else {
+			conditions.MarkFalse(hrp, clusterv1.ReadyCondition, addonsv1alpha1.HelmInstallOrUpgradeFailedReason, clusterv1.ConditionSeverityError, "install failed")
+		}
+
+		return hrp
+	}
+	objects := []client.Object{
+		helmReleaseProxy("hrp-1", true),
+		helmReleaseProxy("hrp-2", true),
+		helmReleaseProxy("hrp-3", true),
+		helmReleaseProxy("hrp-4", false),
+	}
+
+	testcases := []struct {
+		name              string
+		threshold         *intstr.IntOrString
+		expectedStatus    corev1.ConditionStatus
+		expectedReason    string
+		expectedMessageTo string
+	}{
+		{
+			name:           "all HelmReleaseProxies must be ready without a threshold",
+			expectedStatus: corev1.ConditionFalse,
+			expectedReason: addonsv1alpha1.HelmInstallOrUpgradeFailedReason,
+		},
+		{
+			name:              "percentage threshold is met",
+			threshold:         ptr.To(intstr.FromString("75%")),
+			expectedStatus:    corev1.ConditionTrue,
+			expectedReason:    addonsv1alpha1.ReadinessThresholdMetReason,
+			expectedMessageTo: "3 of 4 HelmReleaseProxies are ready, meeting the readiness threshold of 75%",
+		},
+		{
+			name:              "integer threshold is not met",
+			threshold:         ptr.To(intstr.FromInt32(4)),
+			expectedStatus:    corev1.ConditionFalse,
+			expectedReason:    addonsv1alpha1.HelmInstallOrUpgradeFailedReason,
+			expectedMessageTo: "3 of 4 completed",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			t.Parallel()
+
+			hcp := continuousProxy.DeepCopy()
+			hcp.Spec.ReadinessThreshold = tc.threshold
+
+			r := &HelmChartProxyReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(fakeScheme).
+					WithObjects(objects...).
+					Build(),
+			}
+
+			g.Expect(r.aggregateHelmReleaseProxyReadyCondition(ctx, hcp)).To(Succeed())
+
+			condition := conditions.Get(hcp, addonsv1alpha1.HelmReleaseProxiesReadyCondition)
+			g.Expect(condition).NotTo(BeNil())
+			g.Expect(condition.Status).To(Equal(tc.expectedStatus))
+			g.Expect(condition.Reason).To(HavePrefix(tc.expectedReason))
+			g.Expect(condition.Message).To(ContainSubstring(tc.expectedMessageTo))
+		})
+	}
+}
+
+func TestFailureDomainQuota(t *testing.T) {
+	g := NewWithT(t)
+
+	clusterIn := func(name, region string) clusterv1.Cluster {
+		cluster := clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{}}}
+		if region != "" {
+			cluster.Labels["region"] = region
+		}
+
+		return cluster
+	}
+
+	quota := newFailureDomainQuota(&addonsv1alpha1.RolloutOptions{FailureDomainLabel: "region"})
+	east1, east2, west, unlabeled := clusterIn("east-1", "east"), clusterIn("east-2", "east"), clusterIn("west", "west"), clusterIn("unlabeled", "")
+
+	g.Expect(quota.allows(east1)).To(BeTrue())
+	quota.add(east1)
+	g.Expect(quota.allows(east2)).To(BeFalse(), "at most one cluster per region by default")
+	g.Expect(quota.allows(west)).To(BeTrue())
+	quota.add(unlabeled)
+	g.Expect(quota.allows(unlabeled)).To(BeTrue(), "clusters without the label are not limited")
+
+	quota = newFailureDomainQuota(&addonsv1alpha1.RolloutOptions{FailureDomainLabel: "region", MaxPerFailureDomain: ptr.To[int32](2)})
+	quota.add(east1)
+	g.Expect(quota.allows(east2)).To(BeTrue())
+
+	quota = newFailureDomainQuota(&addonsv1alpha1.RolloutOptions{})
+	quota.add(east1)
+	g.Expect(quota.allows(east2)).To(BeTrue(), "quota allows every cluster without a failure domain label")
+}
+
+func TestSetDeployedCharts(t *testing.T) {
+	g := NewWithT(t)
+
+	hrpWith := func(name, version, digest string) addonsv1alpha1.HelmReleaseProxy {
+		return addonsv1alpha1.HelmReleaseProxy{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{addonsv1alpha1.ChartDigestLabelName: digest}},
+			Spec:       addonsv1alpha1.HelmReleaseProxySpec{RepoURL: "https://test-repo", ChartName: "test-chart"},
+			Status:     addonsv1alpha1.HelmReleaseProxyStatus{ChartVersion: version},
+		}
+	}
+	monday := metav1.NewTime(time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC))
+	tuesday := metav1.NewTime(monday.Add(24 * time.Hour))
+	wednesday := metav1.NewTime(tuesday.Add(24 * time.Hour))
+
+	hcp := &addonsv1alpha1.HelmChartProxy{}
+	setDeployedCharts(hcp, []addonsv1alpha1.HelmReleaseProxy{hrpWith("hrp-1", "1.0.0", "digest-1"), hrpWith("hrp-2", "1.0.0", "digest-1"), {}}, monday)
+	g.Expect(hcp.Status.DeployedCharts).To(Equal([]addonsv1alpha1.DeployedChart{
+		{RepoURL: "https://test-repo", ChartName: "test-chart", Version: "1.0.0", ChartDigest: "digest-1", DeployedTime: monday},
+	}))
+
+	// The upgrade rolls out to one Cluster on Tuesday and to the other on Wednesday.
+	setDeployedCharts(hcp, []addonsv1alpha1.HelmReleaseProxy{hrpWith("hrp-1", "1.1.0", "digest-2"), hrpWith("hrp-2", "1.0.0", "digest-1")}, tuesday)
+	setDeployedCharts(hcp, []addonsv1alpha1.HelmReleaseProxy{hrpWith("hrp-1", "1.1.0", "digest-2"), hrpWith("hrp-2", "1.1.0", "digest-2")}, wednesday)
+	g.Expect(hcp.Status.DeployedCharts).To(Equal([]addonsv1alpha1.DeployedChart{
+		{RepoURL: "https://test-repo", ChartName: "test-chart", Version: "1.0.0", ChartDigest: "digest-1", DeployedTime: monday, RetiredTime: &wednesday},
+		{RepoURL: "https://test-repo", ChartName: "test-chart", Version: "1.1.0", ChartDigest: "digest-2", DeployedTime: tuesday},
+	}))
+
+	for i := range maxDeployedCharts {
+		setDeployedCharts(hcp, []addonsv1alpha1.HelmReleaseProxy{hrpWith("hrp-1", fmt.Sprintf("2.%d.0", i), fmt.Sprintf("digest-2-%d", i))}, wednesday)
+	}
+	g.Expect(hcp.Status.DeployedCharts).To(HaveLen(maxDeployedCharts))
+	g.Expect(hcp.Status.DeployedCharts[0].Version).To(Equal("2.0.0"), "the oldest entries are dropped")
+}
+
 func init() {
 	_ = scheme.AddToScheme(fakeScheme)
 	_ = clusterv1.AddToScheme(fakeScheme)
+	_ = expv1.AddToScheme(fakeScheme)
+	_ = addonsv1.AddToScheme(fakeScheme)
 	_ = addonsv1alpha1.AddToScheme(fakeScheme)
 }
+
+func TestAggregateHelmReleaseProxyConditions(t *testing.T) {
+	t.Parallel()
+
+	helmReleaseProxy := func(name string, stale bool) client.Object {
+		hrp := &addonsv1alpha1.HelmReleaseProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "test-namespace",
+				Labels:    map[string]string{addonsv1alpha1.HelmChartProxyLabelName: "test-hcp"},
+			},
+		}
+		conditions.MarkTrue(hrp, addonsv1alpha1.HelmReleaseReadyCondition)
+		if stale {
+			conditions.MarkFalse(hrp, addonsv1alpha1.ReconciledRecentlyCondition, addonsv1alpha1.ReconcileStaleReason, clusterv1.ConditionSeverityWarning, "not reconciled for 2h")
+			conditions.MarkFalse(hrp, clusterv1.ReadyCondition, addonsv1alpha1.ReconcileStaleReason, clusterv1.ConditionSeverityWarning, "not reconciled for 2h")
+		} else {
+			conditions.MarkTrue(hrp, clusterv1.ReadyCondition)
+		}
+
+		return hrp
+	}
+	objects := []client.Object{
+		helmReleaseProxy("hrp-1", false),
+		helmReleaseProxy("hrp-2", true),
+	}
+
+	testcases := []struct {
+		name                  string
+		aggregation           *addonsv1alpha1.ConditionAggregation
+		expectedReadyStatus   corev1.ConditionStatus
+		expectedHealthy       bool
+		expectedHealthyStatus corev1.ConditionStatus
+		expectedSeverity      clusterv1.ConditionSeverity
+	}{
+		{
+			name:                "aggregates the Ready condition without a condition aggregation",
+			expectedReadyStatus: corev1.ConditionFalse,
+		},
+		{
+			name: "a condition with the Warning severity does not block readiness",
+			aggregation: &addonsv1alpha1.ConditionAggregation{Conditions: []addonsv1alpha1.AggregatedCondition{
+				{Type: addonsv1alpha1.HelmReleaseReadyCondition},
+				{Type: addonsv1alpha1.ReconciledRecentlyCondition, Severity: clusterv1.ConditionSeverityWarning},
+			}},
+			expectedReadyStatus:   corev1.ConditionTrue,
+			expectedHealthy:       true,
+			expectedHealthyStatus: corev1.ConditionFalse,
+			expectedSeverity:      clusterv1.ConditionSeverityWarning,
+		},
+		{
+			name: "a condition with the Error severity blocks readiness",
+			aggregation: &addonsv1alpha1.ConditionAggregation{Conditions: []addonsv1alpha1.AggregatedCondition{
+				{Type: addonsv1alpha1.ReconciledRecentlyCondition},
+			}},
+			expectedReadyStatus: corev1.ConditionFalse,
+		},
+		{
+			name: "a negative condition signals a problem when it is true",
+			aggregation: &addonsv1alpha1.ConditionAggregation{Conditions: []addonsv1alpha1.AggregatedCondition{
+				{Type: addonsv1alpha1.HelmReleaseReadyCondition, Polarity: string(addonsv1alpha1.ConditionPolarityNegative), Severity: clusterv1.ConditionSeverityInfo},
+			}},
+			expectedReadyStatus:   corev1.ConditionTrue,
+			expectedHealthy:       true,
+			expectedHealthyStatus: corev1.ConditionFalse,
+			expectedSeverity:      clusterv1.ConditionSeverityInfo,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			t.Parallel()
+
+			hcp := continuousProxy.DeepCopy()
+			hcp.Spec.ConditionAggregation = tc.aggregation
+
+			r := &HelmChartProxyReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(fakeScheme).
+					WithObjects(objects...).
+					Build(),
+			}
+
+			g.Expect(r.aggregateHelmReleaseProxyReadyCondition(ctx, hcp)).To(Succeed())
+			g.Expect(conditions.Get(hcp, addonsv1alpha1.HelmReleaseProxiesReadyCondition).Status).To(Equal(tc.expectedReadyStatus))
+
+			healthy := conditions.Get(hcp, addonsv1alpha1.HelmReleaseProxiesHealthyCondition)
+			if !tc.expectedHealthy {
+				g.Expect(healthy).To(BeNil())
+				return
+			}
+			g.Expect(healthy).NotTo(BeNil())
+			g.Expect(healthy.Status).To(Equal(tc.expectedHealthyStatus))
+			g.Expect(healthy.Severity).To(Equal(tc.expectedSeverity))
+		})
+	}
+}
+
+func TestSetRolloutStatusBatchDuration(t *testing.T) {
+	g := NewWithT(t)
+
+	helmChartProxy := &addonsv1alpha1.HelmChartProxy{}
+	setRolloutStatus(helmChartProxy, 2, 2)
+	g.Expect(helmChartProxy.Status.Rollout.AverageBatchDuration).To(BeNil(), "the first batch has no previous progress")
+
+	helmChartProxy.Status.Rollout.LastProgressTime = &metav1.Time{Time: time.Now().Add(-10 * time.Minute)}
+	setRolloutStatus(helmChartProxy, 2, 2)
+	g.Expect(helmChartProxy.Status.Rollout.AverageBatchDuration).To(BeNil(), "no batch was rolled out")
+
+	setRolloutStatus(helmChartProxy, 4, 2)
+	g.Expect(helmChartProxy.Status.Rollout.ObservedBatches).To(Equal(int32(1)))
+	g.Expect(helmChartProxy.Status.Rollout.AverageBatchDuration.Duration).To(Equal(10 * time.Minute))
+
+	helmChartProxy.Status.Rollout.LastProgressTime = &metav1.Time{Time: time.Now().Add(-20 * time.Minute)}
+	setRolloutStatus(helmChartProxy, 6, 2)
+	g.Expect(helmChartProxy.Status.Rollout.ObservedBatches).To(Equal(int32(2)))
+	g.Expect(helmChartProxy.Status.Rollout.AverageBatchDuration.Duration).To(Equal(15 * time.Minute))
+
+	status := &addonsv1alpha1.RolloutStatus{ObservedBatches: maxObservedBatches, AverageBatchDuration: &metav1.Duration{Duration: 10 * time.Minute}}
+	observeBatchDuration(status, 20*time.Minute)
+	g.Expect(status.ObservedBatches).To(Equal(int32(maxObservedBatches)))
+	g.Expect(status.AverageBatchDuration.Duration).To(Equal(12 * time.Minute))
+}
+
+func TestSetRolloutLastBatch(t *testing.T) {
+	g := NewWithT(t)
+
+	helmChartProxy := &addonsv1alpha1.HelmChartProxy{}
+	setRolloutStatus(helmChartProxy, 2, 2)
+	setRolloutLastBatch(helmChartProxy, []string{"test-cluster-1", "test-cluster-2"})
+	g.Expect(helmChartProxy.Status.Rollout.LastBatch).To(Equal([]string{"test-cluster-1", "test-cluster-2"}))
+
+	setRolloutStatus(helmChartProxy, 2, 2)
+	setRolloutLastBatch(helmChartProxy, []string{})
+	g.Expect(helmChartProxy.Status.Rollout.LastBatch).To(Equal([]string{"test-cluster-1", "test-cluster-2"}), "the previous batch is kept if no Cluster was rolled out")
+
+	setRolloutStatus(helmChartProxy, 3, 2)
+	setRolloutLastBatch(helmChartProxy, []string{"test-cluster-3"})
+	g.Expect(helmChartProxy.Status.Rollout.LastBatch).To(Equal([]string{"test-cluster-3"}))
+}
+
+func TestSetRolloutEstimatedCompletion(t *testing.T) {
+	lastProgressTime := metav1.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name           string
+		status         *addonsv1alpha1.RolloutStatus
+		rolloutOptions *addonsv1alpha1.RolloutOptions
+		clusters       int
+		expected       *metav1.Time
+	}{
+		{
+			name: "no batch duration observed",
+			status: &addonsv1alpha1.RolloutStatus{
+				Count:            ptr.To(2),
+				StepSize:         ptr.To(2),
+				LastProgressTime: &lastProgressTime,
+			},
+			rolloutOptions: &addonsv1alpha1.RolloutOptions{StepInit: ptr.To(intstr.FromInt32(2))},
+			clusters:       10,
+		},
+		{
+			name: "constant step size",
+			status: &addonsv1alpha1.RolloutStatus{
+				Count:                ptr.To(2),
+				StepSize:             ptr.To(2),
+				LastProgressTime:     &lastProgressTime,
+				AverageBatchDuration: &metav1.Duration{Duration: 10 * time.Minute},
+			},
+			rolloutOptions: &addonsv1alpha1.RolloutOptions{StepInit: ptr.To(intstr.FromInt32(2))},
+			clusters:       10,
+			expected:       &metav1.Time{Time: lastProgressTime.Add(40 * time.Minute)},
+		},
+		{
+			name: "increasing step size up to the limit",
+			status: &addonsv1alpha1.RolloutStatus{
+				Count:                ptr.To(1),
+				StepSize:             ptr.To(1),
+				LastProgressTime:     &lastProgressTime,
+				AverageBatchDuration: &metav1.Duration{Duration: 10 * time.Minute},
+			},
+			rolloutOptions: &addonsv1alpha1.RolloutOptions{
+				StepInit:      ptr.To(intstr.FromInt32(1)),
+				StepIncrement: ptr.To(intstr.FromInt32(2)),
+				StepLimit:     ptr.To(intstr.FromString("40%")),
+			},
+			// The remaining batches are of 3, 4, 4 and 4 Clusters.
+			clusters: 10,
+			expected: &metav1.Time{Time: lastProgressTime.Add(30 * time.Minute)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			helmChartProxy := &addonsv1alpha1.HelmChartProxy{Status: addonsv1alpha1.HelmChartProxyStatus{Rollout: tc.status}}
+			setRolloutEstimatedCompletion(helmChartProxy, tc.rolloutOptions, tc.clusters)
+			g.Expect(helmChartProxy.Status.Rollout.EstimatedCompletionTime).To(Equal(tc.expected))
+		})
+	}
+}
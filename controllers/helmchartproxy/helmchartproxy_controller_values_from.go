@@ -0,0 +1,88 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helmchartproxy
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/cluster-api-addon-provider-helm/internal"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// reconcileValuesFrom resolves the ValuesFrom sources of the HelmChartProxy and reports the result in the ValuesReady
+// condition, so that a missing or invalid source is distinguishable from a failed install or upgrade. The values are
+// resolved again for each Cluster, which fails the same way, so an error does not stop the reconcile.
+func (r *HelmChartProxyReconciler) reconcileValuesFrom(ctx context.Context, helmChartProxy *addonsv1alpha1.HelmChartProxy) {
+	if len(helmChartProxy.Spec.ValuesFrom) == 0 {
+		conditions.Delete(helmChartProxy, addonsv1alpha1.ValuesReadyCondition)
+		return
+	}
+
+	_, err := internal.GetValuesFrom(ctx, r.Client, helmChartProxy.Namespace, helmChartProxy.Spec.ValuesFrom)
+	if err == nil {
+		conditions.MarkTrue(helmChartProxy, addonsv1alpha1.ValuesReadyCondition)
+		return
+	}
+
+	reason := addonsv1alpha1.ValuesSourceGetFailedReason
+	var sourceErr *internal.ValuesSourceError
+	if errors.As(err, &sourceErr) {
+		reason = sourceErr.Reason
+	}
+	conditions.MarkFalse(helmChartProxy, addonsv1alpha1.ValuesReadyCondition, reason, clusterv1.ConditionSeverityError, "%s", err.Error())
+}
+
+// ValuesFromToHelmChartProxiesMapper is a mapper function that maps a ConfigMap or Secret to the HelmChartProxies in its
+// namespace referring to it in their ValuesFrom. This is used to re-render the values of the HelmChartProxies when the
+// values of a source change.
+func (r *HelmChartProxyReconciler) ValuesFromToHelmChartProxiesMapper(ctx context.Context, o client.Object) []ctrl.Request {
+	var kind addonsv1alpha1.ValuesFromKind
+	switch o.(type) {
+	case *corev1.ConfigMap:
+		kind = addonsv1alpha1.ValuesFromKindConfigMap
+	case *corev1.Secret:
+		kind = addonsv1alpha1.ValuesFromKindSecret
+	default:
+		return nil
+	}
+
+	helmChartProxies := &addonsv1alpha1.HelmChartProxyList{}
+	if err := r.List(ctx, helmChartProxies, client.InNamespace(o.GetNamespace())); err != nil {
+		return nil
+	}
+
+	results := []ctrl.Request{}
+	for _, helmChartProxy := range helmChartProxies.Items {
+		for _, source := range helmChartProxy.Spec.ValuesFrom {
+			if addonsv1alpha1.ValuesFromKind(source.Kind) == kind && source.Name == o.GetName() {
+				results = append(results, ctrl.Request{
+					NamespacedName: client.ObjectKey{Namespace: helmChartProxy.Namespace, Name: helmChartProxy.Name},
+				})
+
+				break
+			}
+		}
+	}
+
+	return results
+}
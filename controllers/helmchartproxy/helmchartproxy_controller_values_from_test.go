@@ -0,0 +1,144 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helmchartproxy
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestParseValuesForClusterWithValuesFrom(t *testing.T) {
+	g := NewWithT(t)
+
+	cluster := environmentCluster("test-cluster", "prod")
+	helmChartProxy := environmentHelmChartProxy("1.0.0", environment("prod", "1.0.0"))
+	helmChartProxy.Spec.ValuesTemplate = "replicas: 2\nname: {{ .Cluster.metadata.name }}\n"
+	helmChartProxy.Spec.ValuesFrom = []addonsv1alpha1.ValuesFromSource{
+		{Kind: string(addonsv1alpha1.ValuesFromKindConfigMap), Name: "defaults"},
+		{Kind: string(addonsv1alpha1.ValuesFromKindSecret), Name: "credentials", ValuesKey: "credentials.yaml"},
+	}
+	helmChartProxy.Spec.Environments[0].ValuesTemplate = "replicas: 3\n"
+
+	objects := []client.Object{
+		&cluster,
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "defaults", Namespace: "test-namespace"},
+			Data:       map[string]string{addonsv1alpha1.DefaultValuesFromKey: "replicas: 1\nlogLevel: info\n"},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "credentials", Namespace: "test-namespace"},
+			Data:       map[string][]byte{"credentials.yaml": []byte("password: secret\n")},
+		},
+	}
+	r := &HelmChartProxyReconciler{
+		Client: fake.NewClientBuilder().WithScheme(fakeScheme).WithObjects(objects...).Build(),
+	}
+
+	values, err := r.parseValuesForCluster(ctx, helmChartProxy, nil, &cluster)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(values).To(Equal("logLevel: info\nname: test-cluster\npassword: secret\nreplicas: 2\n"), "the values template is merged over the sources")
+
+	values, err = r.parseValuesForCluster(ctx, helmChartProxy, &helmChartProxy.Spec.Environments[0], &cluster)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(values).To(Equal("logLevel: info\nname: test-cluster\npassword: secret\nreplicas: 3\n"), "the environment overlay is merged over the values template")
+
+	helmChartProxy.Spec.ValuesFrom = append(helmChartProxy.Spec.ValuesFrom, addonsv1alpha1.ValuesFromSource{Kind: string(addonsv1alpha1.ValuesFromKindConfigMap), Name: "missing"})
+	_, err = r.parseValuesForCluster(ctx, helmChartProxy, nil, &cluster)
+	g.Expect(err).To(MatchError(ContainSubstring("ConfigMap test-namespace/missing not found")))
+}
+
+func TestReconcileValuesFrom(t *testing.T) {
+	g := NewWithT(t)
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "defaults", Namespace: "test-namespace"},
+		Data:       map[string]string{addonsv1alpha1.DefaultValuesFromKey: "replicas: 1\n"},
+	}
+	r := &HelmChartProxyReconciler{
+		Client: fake.NewClientBuilder().WithScheme(fakeScheme).WithObjects(configMap).Build(),
+	}
+
+	helmChartProxy := environmentHelmChartProxy("1.0.0")
+	r.reconcileValuesFrom(ctx, helmChartProxy)
+	g.Expect(conditions.Has(helmChartProxy, addonsv1alpha1.ValuesReadyCondition)).To(BeFalse(), "the condition is not set without values sources")
+
+	helmChartProxy.Spec.ValuesFrom = []addonsv1alpha1.ValuesFromSource{
+		{Kind: string(addonsv1alpha1.ValuesFromKindConfigMap), Name: "defaults"},
+	}
+	r.reconcileValuesFrom(ctx, helmChartProxy)
+	g.Expect(conditions.IsTrue(helmChartProxy, addonsv1alpha1.ValuesReadyCondition)).To(BeTrue())
+
+	helmChartProxy.Spec.ValuesFrom[0].ValuesKey = "missing.yaml"
+	r.reconcileValuesFrom(ctx, helmChartProxy)
+	g.Expect(conditions.IsFalse(helmChartProxy, addonsv1alpha1.ValuesReadyCondition)).To(BeTrue())
+	g.Expect(conditions.GetReason(helmChartProxy, addonsv1alpha1.ValuesReadyCondition)).To(Equal(addonsv1alpha1.ValuesKeyNotFoundReason))
+
+	helmChartProxy.Spec.ValuesFrom[0] = addonsv1alpha1.ValuesFromSource{Kind: string(addonsv1alpha1.ValuesFromKindSecret), Name: "missing"}
+	r.reconcileValuesFrom(ctx, helmChartProxy)
+	g.Expect(conditions.GetReason(helmChartProxy, addonsv1alpha1.ValuesReadyCondition)).To(Equal(addonsv1alpha1.ValuesSourceNotFoundReason))
+	g.Expect(conditions.GetMessage(helmChartProxy, addonsv1alpha1.ValuesReadyCondition)).To(ContainSubstring("Secret test-namespace/missing not found"))
+
+	helmChartProxy.Spec.ValuesFrom[0].Optional = true
+	r.reconcileValuesFrom(ctx, helmChartProxy)
+	g.Expect(conditions.IsTrue(helmChartProxy, addonsv1alpha1.ValuesReadyCondition)).To(BeTrue(), "missing optional sources are skipped")
+}
+
+func TestValuesFromToHelmChartProxiesMapper(t *testing.T) {
+	g := NewWithT(t)
+
+	helmChartProxy := func(name, namespace string, sources ...addonsv1alpha1.ValuesFromSource) *addonsv1alpha1.HelmChartProxy {
+		return &addonsv1alpha1.HelmChartProxy{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       addonsv1alpha1.HelmChartProxySpec{ValuesFrom: sources},
+		}
+	}
+	configMapSource := addonsv1alpha1.ValuesFromSource{Kind: string(addonsv1alpha1.ValuesFromKindConfigMap), Name: "values"}
+	secretSource := addonsv1alpha1.ValuesFromSource{Kind: string(addonsv1alpha1.ValuesFromKindSecret), Name: "values"}
+
+	objects := []client.Object{
+		helmChartProxy("hcp-configmap", "test-namespace", configMapSource),
+		helmChartProxy("hcp-secret", "test-namespace", secretSource),
+		helmChartProxy("hcp-both", "test-namespace", configMapSource, secretSource),
+		helmChartProxy("hcp-none", "test-namespace"),
+		helmChartProxy("hcp-other-namespace", "other-namespace", configMapSource),
+	}
+	r := &HelmChartProxyReconciler{
+		Client: fake.NewClientBuilder().WithScheme(fakeScheme).WithObjects(objects...).Build(),
+	}
+
+	names := func(requests []ctrl.Request) []string {
+		names := []string{}
+		for _, request := range requests {
+			names = append(names, request.Name)
+		}
+
+		return names
+	}
+
+	objectMeta := metav1.ObjectMeta{Name: "values", Namespace: "test-namespace"}
+	g.Expect(names(r.ValuesFromToHelmChartProxiesMapper(ctx, &corev1.ConfigMap{ObjectMeta: objectMeta}))).To(ConsistOf("hcp-configmap", "hcp-both"))
+	g.Expect(names(r.ValuesFromToHelmChartProxiesMapper(ctx, &corev1.Secret{ObjectMeta: objectMeta}))).To(ConsistOf("hcp-secret", "hcp-both"))
+	g.Expect(r.ValuesFromToHelmChartProxiesMapper(ctx, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "test-namespace"}})).To(BeEmpty())
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helmchartproxy
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/cluster-api-addon-provider-helm/internal"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// valuesOverridesFor returns the HelmValuesOverrides of the HelmChartProxy selecting the Cluster in the order they are
+// merged, i.e. by ascending priority and by name for equal priorities.
+func (r *HelmChartProxyReconciler) valuesOverridesFor(ctx context.Context, helmChartProxy *addonsv1alpha1.HelmChartProxy, cluster *clusterv1.Cluster) ([]addonsv1alpha1.HelmValuesOverride, error) {
+	overrideList := &addonsv1alpha1.HelmValuesOverrideList{}
+	if err := r.List(ctx, overrideList, client.InNamespace(helmChartProxy.Namespace)); err != nil {
+		return nil, errors.Wrapf(err, "failed to list HelmValuesOverrides in namespace %s", helmChartProxy.Namespace)
+	}
+
+	overrides := []addonsv1alpha1.HelmValuesOverride{}
+	for _, override := range overrideList.Items {
+		if override.Spec.HelmChartProxyName != helmChartProxy.Name {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(&override.Spec.ClusterSelector)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse cluster selector of HelmValuesOverride %s", override.Name)
+		}
+		if selector.Matches(labels.Set(cluster.Labels)) {
+			overrides = append(overrides, override)
+		}
+	}
+
+	sort.SliceStable(overrides, func(i, j int) bool {
+		if overrides[i].Spec.Priority != overrides[j].Spec.Priority {
+			return overrides[i].Spec.Priority < overrides[j].Spec.Priority
+		}
+
+		return overrides[i].Name < overrides[j].Name
+	})
+
+	return overrides, nil
+}
+
+// mergeValuesOverrides renders the values of the HelmValuesOverrides of the HelmChartProxy selecting the Cluster and merges
+// them over the values in order.
+func (r *HelmChartProxyReconciler) mergeValuesOverrides(ctx context.Context, helmChartProxy *addonsv1alpha1.HelmChartProxy, cluster *clusterv1.Cluster, values string) (string, error) {
+	overrides, err := r.valuesOverridesFor(ctx, helmChartProxy, cluster)
+	if err != nil {
+		return "", err
+	}
+
+	for _, override := range overrides {
+		// The proxy settings are already injected into the base values.
+		overrideSpec := helmChartProxy.Spec
+		overrideSpec.ValuesTemplate = override.Spec.ValuesTemplate
+		overrideSpec.ProxyValues = nil
+		overrideValues, err := internal.ParseValues(ctx, r.Client, overrideSpec, cluster, r.TemplateOptions)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to parse values of HelmValuesOverride %s", override.Name)
+		}
+
+		values, err = internal.MergeValues(values, overrideValues)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to merge values of HelmValuesOverride %s", override.Name)
+		}
+	}
+
+	return values, nil
+}
+
+// HelmValuesOverrideToHelmChartProxyMapper is a mapper function that maps a HelmValuesOverride to the HelmChartProxy whose
+// values it overrides. This is used to re-render the values of the HelmChartProxy when an override changes.
+func (r *HelmChartProxyReconciler) HelmValuesOverrideToHelmChartProxyMapper(ctx context.Context, o client.Object) []ctrl.Request {
+	override, ok := o.(*addonsv1alpha1.HelmValuesOverride)
+	if !ok {
+		return nil
+	}
+
+	return []ctrl.Request{
+		{
+			NamespacedName: client.ObjectKey{Namespace: override.Namespace, Name: override.Spec.HelmChartProxyName},
+		},
+	}
+}
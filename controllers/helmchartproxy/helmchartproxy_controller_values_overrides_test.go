@@ -0,0 +1,96 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helmchartproxy
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func valuesOverride(name, helmChartProxyName string, priority int32, matchLabels map[string]string, valuesTemplate string) *addonsv1alpha1.HelmValuesOverride {
+	return &addonsv1alpha1.HelmValuesOverride{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test-namespace"},
+		Spec: addonsv1alpha1.HelmValuesOverrideSpec{
+			HelmChartProxyName: helmChartProxyName,
+			ClusterSelector:    metav1.LabelSelector{MatchLabels: matchLabels},
+			ValuesTemplate:     valuesTemplate,
+			Priority:           priority,
+		},
+	}
+}
+
+func TestParseValuesForClusterWithValuesOverrides(t *testing.T) {
+	g := NewWithT(t)
+
+	cluster := environmentCluster("test-cluster", "prod")
+	cluster.Labels["tier"] = "large"
+	helmChartProxy := environmentHelmChartProxy("1.0.0", environment("prod", "1.0.0"))
+	helmChartProxy.Spec.ValuesTemplate = "replicas: 1\nresources:\n  cpu: 100m\n  memory: 128Mi\n"
+	helmChartProxy.Spec.Environments[0].ValuesTemplate = "replicas: 2\n"
+
+	objects := []client.Object{
+		&cluster,
+		valuesOverride("b-large", "test-hcp", 0, map[string]string{"tier": "large"}, "replicas: 5\nresources:\n  memory: 1Gi\n"),
+		valuesOverride("a-large", "test-hcp", 0, map[string]string{"tier": "large"}, "replicas: 4\n"),
+		valuesOverride("pinned", "test-hcp", 10, nil, "name: {{ .Cluster.metadata.name }}\nresources:\n  memory: 2Gi\n"),
+		valuesOverride("small", "test-hcp", 20, map[string]string{"tier": "small"}, "replicas: 0\n"),
+		valuesOverride("other-hcp", "other-hcp", 30, nil, "replicas: 0\n"),
+	}
+	r := &HelmChartProxyReconciler{
+		Client: fake.NewClientBuilder().WithScheme(fakeScheme).WithObjects(objects...).Build(),
+	}
+
+	overrides, err := r.valuesOverridesFor(ctx, helmChartProxy, &cluster)
+	g.Expect(err).NotTo(HaveOccurred())
+	names := []string{}
+	for _, override := range overrides {
+		names = append(names, override.Name)
+	}
+	g.Expect(names).To(Equal([]string{"a-large", "b-large", "pinned"}), "overrides are ordered by priority and name")
+
+	values, err := r.parseValuesForCluster(ctx, helmChartProxy, &helmChartProxy.Spec.Environments[0], &cluster)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(values).To(Equal("name: test-cluster\nreplicas: 5\nresources:\n  cpu: 100m\n  memory: 2Gi\n"))
+
+	values, err = r.parseValuesForCluster(ctx, helmChartProxy, nil, &cluster)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(values).To(Equal("name: test-cluster\nreplicas: 5\nresources:\n  cpu: 100m\n  memory: 2Gi\n"), "overrides are merged without an environment")
+
+	r.Client = fake.NewClientBuilder().WithScheme(fakeScheme).WithObjects(
+		&cluster,
+		valuesOverride("invalid", "test-hcp", 0, nil, "replicas: {{ .Cluster.missing.field }\n"),
+	).Build()
+	_, err = r.parseValuesForCluster(ctx, helmChartProxy, nil, &cluster)
+	g.Expect(err).To(MatchError(ContainSubstring("failed to parse values of HelmValuesOverride invalid")))
+}
+
+func TestHelmValuesOverrideToHelmChartProxyMapper(t *testing.T) {
+	g := NewWithT(t)
+
+	r := &HelmChartProxyReconciler{}
+	requests := r.HelmValuesOverrideToHelmChartProxyMapper(ctx, valuesOverride("override", "test-hcp", 0, nil, ""))
+	g.Expect(requests).To(HaveLen(1))
+	g.Expect(requests[0].Namespace).To(Equal("test-namespace"))
+	g.Expect(requests[0].Name).To(Equal("test-hcp"))
+
+	g.Expect(r.HelmValuesOverrideToHelmChartProxyMapper(ctx, &addonsv1alpha1.HelmChartProxy{})).To(BeEmpty())
+}
@@ -18,17 +18,28 @@ package helmreleaseproxy
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart"
 	helmRelease "helm.sh/helm/v3/pkg/release"
 	helmDriver "helm.sh/helm/v3/pkg/storage/driver"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
 	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
 	"sigs.k8s.io/cluster-api-addon-provider-helm/internal"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
@@ -37,14 +48,46 @@ import (
 	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/cluster-api/util/patch"
 	"sigs.k8s.io/cluster-api/util/predicates"
+	"sigs.k8s.io/cluster-api/util/secret"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
+// releaseProgressInterval is the interval at which the progress of a Helm release is patched into the HelmReleaseProxy status
+// while an install or upgrade waits for resources to become ready. It is a variable so that tests can shorten it.
+var releaseProgressInterval = 10 * time.Second
+
+// clusterCapacityRequeueInterval is the interval at which a HelmReleaseProxy is requeued while waiting for the Cluster to
+// reach the capacity required by ClusterReadiness.
+const clusterCapacityRequeueInterval = 30 * time.Second
+
+// clusterUpgradeRequeueInterval is the interval at which a HelmReleaseProxy is requeued while its upgrade is held until the
+// Kubernetes version upgrade of the Cluster completes.
+const clusterUpgradeRequeueInterval = time.Minute
+
+// kubeconfigRequeueInterval is the interval at which a HelmReleaseProxy is requeued while the kubeconfig Secret of its
+// Cluster does not exist yet. The Secret is watched, so this only covers Secrets whose creation is filtered out by the
+// watch filter.
+const kubeconfigRequeueInterval = time.Minute
+
+// protectedUninstallRequeueInterval is the interval at which a deleted HelmReleaseProxy with a protected Helm release is
+// requeued to check whether the allow-uninstall annotation was set on its HelmChartProxy, which is not watched.
+const protectedUninstallRequeueInterval = time.Minute
+
+// maxPendingChangeLength is the maximum length of the pending change reported in the HelmReleaseProxy status, so that
+// large values diffs do not bloat the object.
+const maxPendingChangeLength = 8 * 1024
+
+// truncatedHashLength is the length of the hashes in the values hash and chart digest labels.
+const truncatedHashLength = 32
+
 // HelmReleaseProxyReconciler reconciles a HelmReleaseProxy object.
 type HelmReleaseProxyReconciler struct {
 	client.Client
@@ -53,11 +96,51 @@ type HelmReleaseProxyReconciler struct {
 
 	// WatchFilterValue is the label value used to filter events prior to reconciliation.
 	WatchFilterValue string
+
+	// FailoverIdentity identifies this management cluster in the ownership leases of HelmReleaseProxies with Failover.
+	FailoverIdentity string
+
+	// ObserveOnly computes the changes to the Helm releases and reports them in the HelmReleaseProxy status without
+	// installing, upgrading or uninstalling anything on the workload Clusters.
+	ObserveOnly bool
+
+	// GlobalPause pauses all changes to the Helm releases while it is set, in which case the HelmReleaseProxies are
+	// reconciled as in observe-only mode. If it is nil, the controller is never paused.
+	GlobalPause *internal.GlobalPause
+
+	// TemplateObjectKinds are the kinds of control planes read to hold upgrades while their Cluster is upgrading. If it is
+	// nil, control planes of any kind are read.
+	TemplateObjectKinds internal.TemplateObjectKinds
+
+	// ClusterOperations limits the number of Helm operations running at once on each workload Cluster and tracks them. If
+	// it is nil, the operations are neither limited nor tracked.
+	ClusterOperations *internal.ClusterOperationQueue
+
+	// Recorder is used to emit events for the HelmReleaseProxy.
+	Recorder record.EventRecorder
+
+	// StalenessThreshold is the duration without a reconcile without error after which the ReconciledRecentlyCondition of
+	// a HelmReleaseProxy is marked false. If it is 0, the condition is never set.
+	StalenessThreshold time.Duration
+
+	// StartupWarmupWindow is the duration after the start of the controller over which the reconciles of ready
+	// HelmReleaseProxies are spread, so that a restarted controller does not reach out to all workload Clusters at once.
+	// If it is 0, all HelmReleaseProxies are reconciled right away.
+	StartupWarmupWindow time.Duration
+
+	// startTime is the time the controller was set up, which the StartupWarmupWindow starts at.
+	startTime time.Time
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *HelmReleaseProxyReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager, options controller.Options) error {
 	log := ctrl.LoggerFrom(ctx)
+	r.startTime = time.Now()
+	// Events are also emitted from the goroutines streaming the progress of Helm releases, where a nil Recorder would crash
+	// the controller.
+	if r.Recorder == nil {
+		r.Recorder = mgr.GetEventRecorderFor("helmreleaseproxy-controller")
+	}
 
 	clusterToHelmReleaseProxies, err := util.ClusterToTypedObjectsMapper(mgr.GetClient(), &addonsv1alpha1.HelmReleaseProxyList{}, mgr.GetScheme())
 	if err != nil {
@@ -80,21 +163,52 @@ func (r *HelmReleaseProxyReconciler) SetupWithManager(ctx context.Context, mgr c
 					predicates.ResourceHasFilterLabel(mgr.GetScheme(), ctrl.LoggerFrom(ctx), r.WatchFilterValue),
 				),
 			)).
+		Watches(
+			&corev1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.clientCertificateSecretToHelmReleaseProxies),
+			builder.WithPredicates(clientCertificateSecretPredicate),
+		).
+		Watches(
+			&addonsv1alpha1.HelmRepository{},
+			handler.EnqueueRequestsFromMapFunc(r.helmRepositoryToHelmReleaseProxies),
+		).
+		Watches(
+			&corev1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.kubeconfigSecretToHelmReleaseProxies),
+			builder.WithPredicates(predicate.Funcs{
+				// Only the creation of a kubeconfig Secret unblocks HelmReleaseProxies waiting for it.
+				UpdateFunc:  func(event.UpdateEvent) bool { return false },
+				DeleteFunc:  func(event.DeleteEvent) bool { return false },
+				GenericFunc: func(event.GenericEvent) bool { return false },
+			}),
+		).
+		Watches(
+			&corev1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(r.globalPauseToHelmReleaseProxies),
+			builder.WithPredicates(predicate.NewPredicateFuncs(r.GlobalPause.IsConfigMap)),
+		).
 		Complete(r)
 }
 
 //+kubebuilder:rbac:groups=addons.cluster.x-k8s.io,resources=helmreleaseproxies,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=addons.cluster.x-k8s.io,resources=helmreleaseproxies/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=addons.cluster.x-k8s.io,resources=helmreleaseproxies/finalizers,verbs=update
+//+kubebuilder:rbac:groups=addons.cluster.x-k8s.io,resources=helmchartproxies,verbs=get
 //+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters,verbs=get;watch
+//+kubebuilder:rbac:groups=addons.cluster.x-k8s.io,resources=chartbundles,verbs=get;list;watch
+//+kubebuilder:rbac:groups=addons.cluster.x-k8s.io,resources=helmrepositories,verbs=get;list;watch
+//+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machinedeployments,verbs=get;list;watch
+//+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machinepools,verbs=get;list;watch
 //+kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;watch
 //+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=secrets,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
-//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io;bootstrap.cluster.x-k8s.io;controlplane.cluster.x-k8s.io;clusterctl.cluster.x-k8s.io,resources=*,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=get
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
-func (r *HelmReleaseProxyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
+func (r *HelmReleaseProxyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, reterr error) {
 	log := ctrl.LoggerFrom(ctx)
 
 	log.V(2).Info("Beginning reconciliation for HelmReleaseProxy", "requestNamespace", req.Namespace, "requestName", req.Name)
@@ -109,6 +223,22 @@ func (r *HelmReleaseProxyReconciler) Reconcile(ctx context.Context, req ctrl.Req
 
 		return ctrl.Result{}, err
 	}
+	ctx = internal.WithAuditSubject(ctx, helmReleaseProxy)
+
+	// While the provider is paused globally, the changes to the Helm release are only reported as in observe-only mode.
+	paused, pauseMessage, err := r.GlobalPause.IsPaused(ctx)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if paused {
+		log.V(2).Info("Not changing Helm release while the provider is paused", "helmReleaseProxy", helmReleaseProxy.Name, "message", pauseMessage)
+	}
+	observeOnly := r.ObserveOnly || paused
+
+	if delay := r.startupDelay(helmReleaseProxy, time.Now()); delay > 0 {
+		log.V(2).Info("Delaying reconcile of ready HelmReleaseProxy during startup warmup", "helmReleaseProxy", helmReleaseProxy.Name, "delay", delay)
+		return ctrl.Result{RequeueAfter: delay}, nil
+	}
 
 	// TODO: should patch helper return an error when the object has been deleted?
 	patchHelper, err := patch.NewHelper(helmReleaseProxy, r.Client)
@@ -119,6 +249,11 @@ func (r *HelmReleaseProxyReconciler) Reconcile(ctx context.Context, req ctrl.Req
 	initializeConditions(ctx, patchHelper, helmReleaseProxy)
 
 	defer func() {
+		// The metrics of a HelmReleaseProxy whose finalizer was removed are deleted along with it.
+		if helmReleaseProxy.DeletionTimestamp.IsZero() || controllerutil.ContainsFinalizer(helmReleaseProxy, addonsv1alpha1.HelmReleaseProxyFinalizer) {
+			internal.RecordReconcileResult(helmReleaseProxy, "HelmReleaseProxy", reterr, r.StalenessThreshold, time.Now())
+		}
+
 		log.V(2).Info("Preparing to patch HelmReleaseProxy with return error", "helmReleaseProxy", helmReleaseProxy.Name, "reterr", reterr)
 		if err := patchHelmReleaseProxy(ctx, patchHelper, helmReleaseProxy); err != nil && reterr == nil {
 			reterr = err
@@ -149,9 +284,37 @@ func (r *HelmReleaseProxyReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		}
 	} else {
 		// The object is being deleted
+		if observeOnly && controllerutil.ContainsFinalizer(helmReleaseProxy, addonsv1alpha1.HelmReleaseProxyFinalizer) {
+			// Keep the finalizer so that the Helm release is uninstalled once the controller enforces changes again.
+			log.Info("Not uninstalling Helm release in observe-only mode", "cluster", clusterKey.Name)
+			helmReleaseProxy.Status.PendingChange = fmt.Sprintf("uninstall release %s", helmReleaseProxy.Spec.ReleaseName)
+			conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.HelmReleaseReadyCondition, addonsv1alpha1.HelmReleaseChangePendingReason, clusterv1.ConditionSeverityInfo, "%s", helmReleaseProxy.Status.PendingChange)
+
+			return ctrl.Result{}, nil
+		}
+
 		if controllerutil.ContainsFinalizer(helmReleaseProxy, addonsv1alpha1.HelmReleaseProxyFinalizer) {
 			// our finalizer is present, so lets handle any external dependency
-			if err := r.Get(ctx, clusterKey, cluster); err == nil {
+			err := r.Get(ctx, clusterKey, cluster)
+			switch {
+			case err == nil && !cluster.DeletionTimestamp.IsZero():
+				// The Helm release goes away with the Cluster, whose API server may already be unreachable, so it is not
+				// uninstalled.
+				log.Info("Cluster is being deleted, not uninstalling Helm release", "cluster", cluster.Name, "release", helmReleaseProxy.Spec.ReleaseName)
+				conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.ClusterAvailableCondition, addonsv1alpha1.ClusterDeletingReason, clusterv1.ConditionSeverityInfo, "Cluster %s is being deleted", cluster.Name)
+			case err == nil:
+				protected, err := r.isProtectedFromUninstall(ctx, helmReleaseProxy)
+				if err != nil {
+					return ctrl.Result{}, err
+				}
+				if protected {
+					log.Info("Not uninstalling protected Helm release", "cluster", cluster.Name, "release", helmReleaseProxy.Spec.ReleaseName)
+					conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.HelmReleaseReadyCondition, addonsv1alpha1.UninstallProtectedReason, clusterv1.ConditionSeverityWarning,
+						"release is protected, set the %s annotation to \"true\" to uninstall it", addonsv1alpha1.AllowUninstallAnnotation)
+
+					return ctrl.Result{RequeueAfter: protectedUninstallRequeueInterval}, nil
+				}
+
 				log.V(2).Info("Getting kubeconfig for cluster", "cluster", cluster.Name)
 				restConfig, err := remote.RESTConfig(ctx, "caaph", r.Client, clusterKey)
 				if err != nil {
@@ -160,18 +323,44 @@ func (r *HelmReleaseProxyReconciler) Reconcile(ctx context.Context, req ctrl.Req
 
 					return ctrl.Result{}, wrappedErr
 				}
+				impersonate(restConfig, helmReleaseProxy.Spec.Impersonation)
 				conditions.MarkTrue(helmReleaseProxy, addonsv1alpha1.ClusterAvailableCondition)
 
-				if err := r.reconcileDelete(ctx, helmReleaseProxy, r.HelmClient, restConfig); err != nil {
-					// if fail to delete the external dependency here, return with error
-					// so that it can be retried
-					return ctrl.Result{}, err
+				acquired := true
+				if helmReleaseProxy.Spec.Failover != nil {
+					acquired, _, err = r.acquireFailoverLease(ctx, helmReleaseProxy, restConfig)
+					if err != nil {
+						return ctrl.Result{}, err
+					}
+				}
+
+				if acquired {
+					releaseOperation, err := r.ClusterOperations.Acquire(ctx, util.ObjectKey(cluster))
+					if err != nil {
+						return ctrl.Result{}, err
+					}
+					defer releaseOperation()
+
+					if err := r.reconcileDelete(ctx, helmReleaseProxy, r.HelmClient, restConfig); err != nil {
+						// if fail to delete the external dependency here, return with error
+						// so that it can be retried
+						return ctrl.Result{}, err
+					}
+
+					if helmReleaseProxy.Spec.Failover != nil {
+						if err := r.releaseFailoverLease(ctx, helmReleaseProxy, restConfig); err != nil {
+							return ctrl.Result{}, err
+						}
+					}
+				} else {
+					// Another management cluster owns the Helm release, so it is left in place for it.
+					log.Info("Not deleting Helm release owned by another management cluster", "cluster", cluster.Name)
 				}
-			} else if apierrors.IsNotFound(err) {
+			case apierrors.IsNotFound(err):
 				// Cluster is gone, so we should remove our finalizer from the list and delete
-				log.V(2).Info("Cluster not found, no need to delete external dependency", "cluster", cluster.Name)
-				// TODO: should we set a condition here?
-			} else {
+				log.V(2).Info("Cluster not found, no need to delete external dependency", "cluster", clusterKey.Name)
+				conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.ClusterAvailableCondition, addonsv1alpha1.ClusterDeletingReason, clusterv1.ConditionSeverityInfo, "Cluster %s was deleted", clusterKey.Name)
+			default:
 				wrappedErr := errors.Wrapf(err, "failed to get cluster %s/%s", clusterKey.Namespace, clusterKey.Name)
 				conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.ClusterAvailableCondition, addonsv1alpha1.GetClusterFailedReason, clusterv1.ConditionSeverityError, "%s", wrappedErr.Error())
 
@@ -180,6 +369,7 @@ func (r *HelmReleaseProxyReconciler) Reconcile(ctx context.Context, req ctrl.Req
 
 			// remove our finalizer from the list and update it.
 			controllerutil.RemoveFinalizer(helmReleaseProxy, addonsv1alpha1.HelmReleaseProxyFinalizer)
+			internal.DeleteReconcileMetrics("HelmReleaseProxy", helmReleaseProxy.Namespace, helmReleaseProxy.Name)
 			if err := patchHelmReleaseProxy(ctx, patchHelper, helmReleaseProxy); err != nil {
 				// TODO: Should we try to set the error here? If we can't remove the finalizer we likely can't update the status either.
 				return ctrl.Result{}, err
@@ -191,13 +381,28 @@ func (r *HelmReleaseProxyReconciler) Reconcile(ctx context.Context, req ctrl.Req
 	}
 
 	if err := r.Get(ctx, clusterKey, cluster); err != nil {
-		// TODO: add check to tell if Cluster is deleted so we can remove the HelmReleaseProxy.
+		if apierrors.IsNotFound(err) {
+			// The HelmChartProxy deletes the HelmReleaseProxy of a deleted Cluster, so it is not an error.
+			log.V(2).Info("Cluster not found, waiting for the HelmReleaseProxy to be deleted", "cluster", clusterKey.Name)
+			conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.ClusterAvailableCondition, addonsv1alpha1.ClusterDeletingReason, clusterv1.ConditionSeverityInfo, "Cluster %s was deleted", clusterKey.Name)
+
+			return ctrl.Result{}, nil
+		}
+
 		wrappedErr := errors.Wrapf(err, "failed to get cluster %s/%s", clusterKey.Namespace, clusterKey.Name)
 		conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.ClusterAvailableCondition, addonsv1alpha1.GetClusterFailedReason, clusterv1.ConditionSeverityError, "%s", wrappedErr.Error())
 
 		return ctrl.Result{}, wrappedErr
 	}
 
+	if !cluster.DeletionTimestamp.IsZero() {
+		// The HelmChartProxy deletes the HelmReleaseProxy of a Cluster being deleted, so it is not reconciled meanwhile.
+		log.V(2).Info("Cluster is being deleted, waiting for the HelmReleaseProxy to be deleted", "cluster", cluster.Name)
+		conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.ClusterAvailableCondition, addonsv1alpha1.ClusterDeletingReason, clusterv1.ConditionSeverityInfo, "Cluster %s is being deleted", cluster.Name)
+
+		return ctrl.Result{}, nil
+	}
+
 	if !conditions.IsTrue(cluster, clusterv1.ControlPlaneInitializedCondition) {
 		log.Info("Waiting for the control plane to be initialized")
 		conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.ClusterAvailableCondition, clusterv1.WaitingForControlPlaneAvailableReason, clusterv1.ConditionSeverityInfo, "")
@@ -208,16 +413,115 @@ func (r *HelmReleaseProxyReconciler) Reconcile(ctx context.Context, req ctrl.Req
 	}
 
 	log.V(2).Info("Getting REST config for cluster", "cluster", cluster.Name)
-	restConfig, err := remote.RESTConfig(ctx, "caaph", r.Client, clusterKey)
+	restConfig, err := r.getRESTConfig(ctx, helmReleaseProxy, cluster)
 	if err != nil {
-		wrappedErr := errors.Wrapf(err, "failed to get kubeconfig for cluster")
-		conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.ClusterAvailableCondition, addonsv1alpha1.GetKubeconfigFailedReason, clusterv1.ConditionSeverityError, "%s", wrappedErr.Error())
+		return ctrl.Result{}, err
+	}
+	if restConfig == nil {
+		log.Info("Waiting for the kubeconfig Secret of the cluster to be created", "cluster", cluster.Name)
 
-		return ctrl.Result{}, wrappedErr
+		return ctrl.Result{RequeueAfter: kubeconfigRequeueInterval}, nil
+	}
+
+	// The lease is written to the workload Cluster, so it is not acquired in observe-only mode.
+	if helmReleaseProxy.Spec.Failover != nil && !observeOnly {
+		acquired, lease, err := r.acquireFailoverLease(ctx, helmReleaseProxy, restConfig)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if !acquired {
+			expiresIn := time.Until(lease.RenewTime.Add(lease.Duration))
+			log.Info("Standing by while another management cluster holds the lease", "cluster", cluster.Name, "holder", lease.Holder, "expiresIn", expiresIn)
+			conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.ClusterAvailableCondition, addonsv1alpha1.WaitingForFailoverLeaseReason, clusterv1.ConditionSeverityInfo,
+				"lease is held by %s until %s", lease.Holder, lease.RenewTime.Add(lease.Duration).Format(time.RFC3339))
+
+			// The lease is not watched, so requeue to take it over once it expires.
+			return ctrl.Result{RequeueAfter: expiresIn + time.Second}, nil
+		}
+
+		// Requeue to renew the lease in time, whatever the outcome of the rest of the reconciliation.
+		renewInterval := failoverLeaseDuration(helmReleaseProxy) / 2
+		defer func() {
+			if reterr == nil && (result.RequeueAfter == 0 || result.RequeueAfter > renewInterval) {
+				result.RequeueAfter = renewInterval
+			}
+		}()
+	}
+
+	if helmReleaseProxy.Spec.ClusterReadiness != nil && !internal.HasHelmReleaseBeenSuccessfullyInstalled(helmReleaseProxy) {
+		workloadClient, err := client.New(restConfig, client.Options{})
+		if err != nil {
+			wrappedErr := errors.Wrapf(err, "failed to create client for cluster")
+			conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.ClusterAvailableCondition, addonsv1alpha1.ClusterCapacityCheckFailedReason, clusterv1.ConditionSeverityError, "%s", wrappedErr.Error())
+
+			return ctrl.Result{}, wrappedErr
+		}
+
+		ready, message, err := internal.CheckClusterCapacity(ctx, r.Client, workloadClient, cluster.Namespace, helmReleaseProxy.Spec.ClusterReadiness)
+		if err != nil {
+			wrappedErr := errors.Wrapf(err, "failed to check capacity of cluster")
+			conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.ClusterAvailableCondition, addonsv1alpha1.ClusterCapacityCheckFailedReason, clusterv1.ConditionSeverityError, "%s", wrappedErr.Error())
+
+			return ctrl.Result{}, wrappedErr
+		}
+
+		if !ready {
+			log.Info("Waiting for the cluster to reach the required capacity", "cluster", cluster.Name, "reason", message)
+			conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.ClusterAvailableCondition, addonsv1alpha1.WaitingForClusterCapacityReason, clusterv1.ConditionSeverityInfo, "%s", message)
+
+			// Node and MachineDeployment changes are not watched, so requeue to check the capacity again.
+			return ctrl.Result{RequeueAfter: clusterCapacityRequeueInterval}, nil
+		}
+	}
+
+	if helmReleaseProxy.Spec.HoldDuringClusterUpgrade && internal.HasHelmReleaseBeenSuccessfullyInstalled(helmReleaseProxy) {
+		upgrading, message, err := internal.IsClusterUpgrading(ctx, r.Client, cluster, r.TemplateObjectKinds)
+		if err != nil {
+			wrappedErr := errors.Wrapf(err, "failed to check whether cluster is upgrading")
+			conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.ClusterAvailableCondition, addonsv1alpha1.ClusterUpgradeCheckFailedReason, clusterv1.ConditionSeverityError, "%s", wrappedErr.Error())
+
+			return ctrl.Result{}, wrappedErr
+		}
+
+		if upgrading {
+			log.Info("Holding Helm release upgrade until the cluster upgrade completes", "cluster", cluster.Name, "reason", message)
+			conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.ClusterAvailableCondition, addonsv1alpha1.WaitingForClusterUpgradeReason, clusterv1.ConditionSeverityInfo, "%s", message)
+
+			// Control plane changes are not watched, so requeue to check whether the upgrade has completed.
+			return ctrl.Result{RequeueAfter: clusterUpgradeRequeueInterval}, nil
+		}
 	}
 	conditions.MarkTrue(helmReleaseProxy, addonsv1alpha1.ClusterAvailableCondition)
 
-	credentialsPath, err := r.getCredentials(ctx, helmReleaseProxy)
+	// The Helm operations of a Cluster are queued once it runs the maximum concurrent operations per Cluster.
+	releaseOperation, err := r.ClusterOperations.Acquire(ctx, util.ObjectKey(cluster))
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	defer releaseOperation()
+
+	// Plain manifests are applied without Helm, so none of the chart sources, rollbacks or drift checks apply to them.
+	if helmReleaseProxy.Spec.Manifests != "" {
+		if err := r.reconcileManifests(ctx, helmReleaseProxy, restConfig, observeOnly); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{RequeueAfter: resyncPeriodFor(helmReleaseProxy)}, nil
+	}
+
+	// The rollback is not performed in observe-only mode, so RollbackTo is left in place until changes are enforced again.
+	if helmReleaseProxy.Spec.RollbackTo != nil && !observeOnly {
+		return ctrl.Result{}, r.reconcileRollback(ctx, helmReleaseProxy, r.HelmClient, restConfig)
+	}
+
+	// The HelmRepository is resolved into a copy of the HelmReleaseProxy, which is only used to fetch the chart.
+	source, repository, err := r.withHelmRepository(ctx, helmReleaseProxy)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	credentialsPath, err := r.getCredentials(ctx, source)
 	if err != nil {
 		wrappedErr := errors.Wrapf(err, "failed to get credentials for cluster")
 		conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.ClusterAvailableCondition, addonsv1alpha1.GetCredentialsFailedReason, clusterv1.ConditionSeverityError, "%s", wrappedErr.Error())
@@ -233,7 +537,7 @@ func (r *HelmReleaseProxyReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		}()
 	}
 
-	caFilePath, err := r.getCAFile(ctx, helmReleaseProxy)
+	caFilePath, err := r.getCAFile(ctx, source)
 	if err != nil {
 		wrappedErr := errors.Wrapf(err, "failed to get CA certificate file for cluster")
 		conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.ClusterAvailableCondition, addonsv1alpha1.GetCACertificateFailedReason, clusterv1.ConditionSeverityError, "%s", wrappedErr.Error())
@@ -249,15 +553,82 @@ func (r *HelmReleaseProxyReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		}()
 	}
 
+	repositoryAuth, err := r.getRepositoryAuth(ctx, source)
+	if err != nil {
+		wrappedErr := errors.Wrapf(err, "failed to get repository headers and credentials for cluster")
+		conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.ClusterAvailableCondition, addonsv1alpha1.GetCredentialsFailedReason, clusterv1.ConditionSeverityError, "%s", wrappedErr.Error())
+
+		return ctrl.Result{}, wrappedErr
+	}
+
+	certFile, keyFile, err := r.getClientCertificateFiles(ctx, source)
+	if err != nil {
+		wrappedErr := errors.Wrapf(err, "failed to get client certificate for cluster")
+		conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.ClusterAvailableCondition, addonsv1alpha1.GetClientCertificateFailedReason, clusterv1.ConditionSeverityError, "%s", wrappedErr.Error())
+
+		return ctrl.Result{}, wrappedErr
+	}
+
+	if certFile != "" {
+		defer func() {
+			for _, path := range []string{certFile, keyFile} {
+				if err := os.Remove(path); err != nil {
+					log.Error(err, "failed to remove client certificate file in path", "path", path)
+				}
+			}
+		}()
+	}
+	repositoryAuth.CertFile = certFile
+	repositoryAuth.KeyFile = keyFile
+	repositoryAuth.PlainHTTP = repository != nil && repository.Spec.PlainHTTP
+
+	verification, err := r.getChartVerification(ctx, source)
+	if err != nil {
+		wrappedErr := errors.Wrapf(err, "failed to get chart verification keys")
+		setChartVerificationFailed(helmReleaseProxy, addonsv1alpha1.ChartVerificationFailedReason, wrappedErr.Error())
+
+		return ctrl.Result{}, wrappedErr
+	}
+	if verification != nil && verification.KeyringFile != "" {
+		defer func() {
+			if err := os.Remove(verification.KeyringFile); err != nil {
+				log.Error(err, "failed to remove keyring file in path", "path", verification.KeyringFile)
+			}
+		}()
+	}
+	repositoryAuth.Verification = verification
+
+	if helmReleaseProxy.Spec.ChartBundleRef != nil {
+		if err := internal.ExtractChartBundleChart(ctx, r.Client, helmReleaseProxy.Spec); err != nil {
+			conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.HelmReleaseReadyCondition, addonsv1alpha1.ChartBundleUnavailableReason, clusterv1.ConditionSeverityError, "%s", err.Error())
+
+			return ctrl.Result{}, err
+		}
+	}
+
 	log.V(2).Info("Reconciling HelmReleaseProxy", "releaseProxyName", helmReleaseProxy.Name)
-	err = r.reconcileNormal(ctx, helmReleaseProxy, r.HelmClient, credentialsPath, caFilePath, restConfig)
+	err = r.reconcileNormal(ctx, helmReleaseProxy, r.HelmClient, credentialsPath, caFilePath, repositoryAuth, restConfig, observeOnly)
+
+	// The values are only shown for troubleshooting, so a failure to copy them does not fail the reconcile.
+	if valuesErr := r.reconcileValuesConfigMap(ctx, helmReleaseProxy, r.HelmClient, restConfig); valuesErr != nil {
+		log.Error(valuesErr, "Failed to copy values of release to ConfigMap", "release", helmReleaseProxy.Spec.ReleaseName, "cluster", cluster.Name)
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
 
-	return ctrl.Result{}, err
+	requeueAfter := r.reconcileDrift(ctx, helmReleaseProxy, r.HelmClient, restConfig, observeOnly, time.Now())
+	if resyncPeriod := resyncPeriodFor(helmReleaseProxy); resyncPeriod > 0 && (requeueAfter == 0 || resyncPeriod < requeueAfter) {
+		requeueAfter = resyncPeriod
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
 }
 
 // reconcileNormal handles HelmReleaseProxy reconciliation when it is not being deleted. This will install or upgrade the HelmReleaseProxy on the Cluster.
 // It will set the ReleaseName on the HelmReleaseProxy if the name is generated and also set the release status and release revision.
-func (r *HelmReleaseProxyReconciler) reconcileNormal(ctx context.Context, helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy, client internal.Client, credentialsPath, caFilePath string, restConfig *rest.Config) error {
+// If observeOnly is set, the install or upgrade is only reported in the status.
+func (r *HelmReleaseProxyReconciler) reconcileNormal(ctx context.Context, helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy, client internal.Client, credentialsPath, caFilePath string, repositoryAuth internal.RepositoryAuth, restConfig *rest.Config, observeOnly bool) error {
 	log := ctrl.LoggerFrom(ctx)
 
 	log.V(2).Info("Reconciling HelmReleaseProxy on cluster", "HelmReleaseProxy", helmReleaseProxy.Name, "cluster", helmReleaseProxy.Spec.ClusterRef.Name)
@@ -270,6 +641,13 @@ func (r *HelmReleaseProxyReconciler) reconcileNormal(ctx context.Context, helmRe
 		}
 	}
 
+	if isHeldForRollback(helmReleaseProxy) {
+		log.Info("Holding install or upgrade of rolled back Helm release", "helmReleaseProxy", helmReleaseProxy.Name, "revision", helmReleaseProxy.Status.Rollback.Revision, "cluster", helmReleaseProxy.Spec.ClusterRef.Name)
+		markRolledBack(helmReleaseProxy)
+
+		return nil
+	}
+
 	annotations := helmReleaseProxy.GetAnnotations()
 	if annotations == nil {
 		annotations = make(map[string]string)
@@ -281,10 +659,87 @@ func (r *HelmReleaseProxyReconciler) reconcileNormal(ctx context.Context, helmRe
 		helmReleaseProxy.SetAnnotations(annotations)
 	}
 
-	release, err := client.InstallOrUpgradeHelmRelease(ctx, restConfig, credentialsPath, caFilePath, helmReleaseProxy.Spec)
+	spec, err := r.resolvedSpec(ctx, helmReleaseProxy)
+	if err != nil {
+		return err
+	}
+
+	if observeOnly {
+		return r.reconcileObserveOnly(ctx, helmReleaseProxy, spec, client, credentialsPath, caFilePath, repositoryAuth, restConfig)
+	}
+	helmReleaseProxy.Status.PendingChange = ""
+
+	if isReleaseUpToDate(helmReleaseProxy, spec, time.Now()) {
+		log.V(2).Info("Helm release was deployed from the current spec, skipping release check on cluster", "release", helmReleaseProxy.Spec.ReleaseName, "cluster", helmReleaseProxy.Spec.ClusterRef.Name)
+		return nil
+	}
+
+	installSpec := skipWait(ctx, helmReleaseProxy, spec)
+	var stopReleaseProgress func() *addonsv1alpha1.ReleaseProgress
+	if installSpec.Options.Wait {
+		stopReleaseProgress = r.streamReleaseProgress(ctx, helmReleaseProxy, client, restConfig)
+	}
+
+	release, err := client.InstallOrUpgradeHelmRelease(ctx, restConfig, credentialsPath, caFilePath, repositoryAuth, installSpec)
+	var progress *addonsv1alpha1.ReleaseProgress
+	if stopReleaseProgress != nil {
+		if progress = stopReleaseProgress(); progress != nil {
+			if release != nil && release.Info.Status == helmRelease.StatusDeployed {
+				progress.Ready += progress.Pending
+				progress.Pending = 0
+				progress.Events = nil
+			}
+			helmReleaseProxy.Status.Progress = progress
+		}
+	}
 	if err != nil {
 		log.Error(err, fmt.Sprintf("Failed to install or upgrade release '%s' on cluster %s", helmReleaseProxy.Spec.ReleaseName, helmReleaseProxy.Spec.ClusterRef.Name))
-		conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.HelmReleaseReadyCondition, addonsv1alpha1.HelmInstallOrUpgradeFailedReason, clusterv1.ConditionSeverityError, "%s", err.Error())
+		reason := addonsv1alpha1.HelmInstallOrUpgradeFailedReason
+		severity := clusterv1.ConditionSeverityError
+		var missingAPIsErr *internal.MissingAPIsError
+		var kubeVersionErr *internal.KubeVersionIncompatibleError
+		var registryErr *internal.RegistryUnavailableError
+		var quotaErr *internal.QuotaExceededError
+		var rolledBackErr *internal.UpgradeRolledBackError
+		var verificationErr *internal.ChartVerificationError
+		var pendingErr *internal.PendingReleaseError
+		var chartLockErr *internal.ChartLockMismatchError
+		switch {
+		case errors.As(err, &missingAPIsErr):
+			reason = addonsv1alpha1.MissingRequiredAPIsReason
+		case errors.As(err, &kubeVersionErr):
+			reason = addonsv1alpha1.KubeVersionIncompatibleReason
+		case errors.As(err, &registryErr):
+			reason = addonsv1alpha1.RegistryUnavailableReason
+		case errors.As(err, &quotaErr):
+			reason = addonsv1alpha1.QuotaExceededReason
+		case errors.As(err, &rolledBackErr):
+			reason = addonsv1alpha1.UpgradeRolledBackReason
+			r.recordUpgradeRollback(helmReleaseProxy, rolledBackErr, time.Now())
+		case errors.As(err, &verificationErr):
+			reason = addonsv1alpha1.ChartVerificationFailedReason
+			setChartVerificationFailed(helmReleaseProxy, addonsv1alpha1.ChartVerificationFailedReason, verificationErr.Error())
+			r.Recorder.Eventf(helmReleaseProxy, corev1.EventTypeWarning, addonsv1alpha1.ChartVerificationFailedReason, "Chart %s was not installed on cluster %s: %s",
+				helmReleaseProxy.Spec.ChartName, helmReleaseProxy.Spec.ClusterRef.Name, verificationErr.Error())
+		case errors.As(err, &chartLockErr):
+			reason = addonsv1alpha1.ChartLockMismatchReason
+			setChartVerificationFailed(helmReleaseProxy, addonsv1alpha1.ChartLockMismatchReason, chartLockErr.Error())
+			r.Recorder.Eventf(helmReleaseProxy, corev1.EventTypeWarning, addonsv1alpha1.ChartLockMismatchReason, "Chart %s was not installed on cluster %s: %s",
+				helmReleaseProxy.Spec.ChartName, helmReleaseProxy.Spec.ClusterRef.Name, chartLockErr.Error())
+		case errors.As(err, &pendingErr):
+			reason = addonsv1alpha1.HelmReleasePendingReason
+			severity = clusterv1.ConditionSeverityInfo
+			if pendingErr.Stuck {
+				severity = clusterv1.ConditionSeverityWarning
+			}
+		}
+		message := err.Error()
+		// The most recent event of the resources that did not become ready usually explains why the wait failed.
+		if progress != nil && len(progress.Events) > 0 {
+			event := progress.Events[0]
+			message = fmt.Sprintf("%s; last event of %s %s: %s: %s", message, event.Kind, event.Name, event.Reason, event.Message)
+		}
+		conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.HelmReleaseReadyCondition, reason, severity, "%s", message)
 	}
 	if release != nil {
 		log.V(2).Info(fmt.Sprintf("Release '%s' exists on cluster %s, revision = %d", release.Name, helmReleaseProxy.Spec.ClusterRef.Name, release.Version))
@@ -297,8 +752,39 @@ func (r *HelmReleaseProxyReconciler) reconcileNormal(ctx context.Context, helmRe
 		switch {
 		case status == helmRelease.StatusDeployed:
 			conditions.MarkTrue(helmReleaseProxy, addonsv1alpha1.HelmReleaseReadyCondition)
+			conditions.Delete(helmReleaseProxy, addonsv1alpha1.UpgradeRolledBackCondition)
+			conditions.Delete(helmReleaseProxy, addonsv1alpha1.ChartVerificationFailedCondition)
 			annotations[addonsv1alpha1.ReleaseSuccessfullyInstalledAnnotation] = "true"
 			helmReleaseProxy.SetAnnotations(annotations)
+			setDeployedConfigLabels(helmReleaseProxy, release)
+			setAppliedDigests(helmReleaseProxy, spec, release)
+
+			// Labeling only helps tracing the release from the workload Cluster, so a failure does not fail the reconcile.
+			if err := client.LabelReleaseResources(ctx, restConfig, helmReleaseProxy.Spec, release.Version, ownerLabelsFor(helmReleaseProxy)); err != nil {
+				log.Error(err, "Failed to label resources of release with owner", "release", release.Name, "cluster", helmReleaseProxy.Spec.ClusterRef.Name)
+			}
+
+			// SBOMs are only reported for compliance, so a failure to get them does not fail the reconcile either.
+			sboms, err := client.GetChartSBOMs(ctx, spec, credentialsPath, caFilePath)
+			if err != nil {
+				log.Error(err, "Failed to get SBOMs of chart", "chart", helmReleaseProxy.Spec.ChartName, "version", helmReleaseProxy.Spec.Version)
+			} else {
+				helmReleaseProxy.Status.SBOMs = sboms
+			}
+			// The digest is only reported for traceability, so a failure to resolve it does not fail the reconcile either.
+			resolvedDigest, err := client.ResolveChartDigest(ctx, spec, credentialsPath, caFilePath)
+			if err != nil {
+				log.Error(err, "Failed to resolve digest of chart", "chart", helmReleaseProxy.Spec.ChartName, "version", helmReleaseProxy.Spec.Version)
+			} else {
+				helmReleaseProxy.Status.ResolvedDigest = resolvedDigest
+			}
+			if err := r.reconcileSBOMConfigMap(ctx, helmReleaseProxy, spec, client, credentialsPath, caFilePath); err != nil {
+				log.Error(err, "Failed to copy SBOMs of chart to ConfigMap", "chart", helmReleaseProxy.Spec.ChartName, "version", helmReleaseProxy.Spec.Version)
+			}
+			if err := r.reconcileValuesOverridesConfigMap(ctx, helmReleaseProxy, release); err != nil {
+				log.Error(err, "Failed to record overridden chart default values in ConfigMap", "chart", helmReleaseProxy.Spec.ChartName, "version", helmReleaseProxy.Spec.Version)
+			}
+			r.reconcileReleaseTest(ctx, helmReleaseProxy, client, restConfig, spec, release, time.Now())
 		case status.IsPending():
 			conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.HelmReleaseReadyCondition, addonsv1alpha1.HelmReleasePendingReason, clusterv1.ConditionSeverityInfo, "Helm release is in a pending state: %s", status)
 		case status == helmRelease.StatusFailed && err == nil:
@@ -311,18 +797,335 @@ func (r *HelmReleaseProxyReconciler) reconcileNormal(ctx context.Context, helmRe
 	return err
 }
 
+// recordUpgradeRollback records the rollback of a failed atomic upgrade in the HelmReleaseProxy status and the
+// UpgradeRolledBack condition. The status of the Helm release is the one of the revision it was rolled back to.
+func (r *HelmReleaseProxyReconciler) recordUpgradeRollback(helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy, rolledBackErr *internal.UpgradeRolledBackError, now time.Time) {
+	release := rolledBackErr.Release
+	helmReleaseProxy.SetReleaseStatus(release.Info.Status.String())
+	helmReleaseProxy.SetReleaseRevision(release.Version)
+	helmReleaseProxy.Status.UpgradeRollback = &addonsv1alpha1.UpgradeRollbackStatus{
+		FailedRevision: rolledBackErr.FailedRevision,
+		Revision:       release.Version,
+		Time:           metav1.NewTime(now),
+		Message:        rolledBackErr.Err.Error(),
+	}
+	conditions.Set(helmReleaseProxy, &clusterv1.Condition{
+		Type:     addonsv1alpha1.UpgradeRolledBackCondition,
+		Status:   corev1.ConditionTrue,
+		Reason:   addonsv1alpha1.UpgradeRolledBackReason,
+		Severity: clusterv1.ConditionSeverityWarning,
+		Message:  fmt.Sprintf("Upgrade to revision %d failed and was rolled back to revision %d", rolledBackErr.FailedRevision, release.Version),
+	})
+	r.Recorder.Eventf(helmReleaseProxy, corev1.EventTypeWarning, addonsv1alpha1.UpgradeRolledBackReason, "Upgrade of release %s on cluster %s failed and was rolled back to revision %d: %s",
+		helmReleaseProxy.Spec.ReleaseName, helmReleaseProxy.Spec.ClusterRef.Name, release.Version, rolledBackErr.Err.Error())
+}
+
+// setChartVerificationFailed marks the ChartVerificationFailed condition True with the reason and message.
+func setChartVerificationFailed(helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy, reason, message string) {
+	conditions.Set(helmReleaseProxy, &clusterv1.Condition{
+		Type:     addonsv1alpha1.ChartVerificationFailedCondition,
+		Status:   corev1.ConditionTrue,
+		Reason:   reason,
+		Severity: clusterv1.ConditionSeverityError,
+		Message:  message,
+	})
+}
+
+// reconcileObserveOnly reports the install or upgrade of the Helm release that reconcileNormal would perform in the
+// HelmReleaseProxy status and conditions, without changing anything on the Cluster. The spec is the one of the
+// HelmReleaseProxy with its referenced values resolved.
+func (r *HelmReleaseProxyReconciler) reconcileObserveOnly(ctx context.Context, helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy, spec addonsv1alpha1.HelmReleaseProxySpec, client internal.Client, credentialsPath, caFilePath string, repositoryAuth internal.RepositoryAuth, restConfig *rest.Config) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	release, change, err := client.DiffHelmRelease(ctx, restConfig, credentialsPath, caFilePath, repositoryAuth, spec)
+	if err != nil {
+		conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.HelmReleaseReadyCondition, addonsv1alpha1.HelmReleaseDiffFailedReason, clusterv1.ConditionSeverityError, "%s", err.Error())
+
+		return errors.Wrapf(err, "failed to compute change to release on cluster %s", helmReleaseProxy.Spec.ClusterRef.Name)
+	}
+
+	if release != nil {
+		helmReleaseProxy.SetReleaseStatus(release.Info.Status.String())
+		helmReleaseProxy.SetReleaseRevision(release.Version)
+		helmReleaseProxy.SetReleaseName(release.Name)
+	}
+
+	if len(change) > maxPendingChangeLength {
+		change = change[:maxPendingChangeLength] + "\n... (truncated)"
+	}
+	helmReleaseProxy.Status.PendingChange = change
+
+	switch {
+	case change != "":
+		log.Info("Helm release has a pending change in observe-only mode", "cluster", helmReleaseProxy.Spec.ClusterRef.Name, "change", change)
+		summary, _, _ := strings.Cut(change, "\n")
+		conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.HelmReleaseReadyCondition, addonsv1alpha1.HelmReleaseChangePendingReason, clusterv1.ConditionSeverityInfo, "would %s", summary)
+	case release != nil && release.Info.Status == helmRelease.StatusDeployed:
+		conditions.MarkTrue(helmReleaseProxy, addonsv1alpha1.HelmReleaseReadyCondition)
+	case release != nil:
+		conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.HelmReleaseReadyCondition, addonsv1alpha1.HelmReleasePendingReason, clusterv1.ConditionSeverityInfo, "Helm release is in a pending state: %s", release.Info.Status)
+	}
+
+	return nil
+}
+
+// isProtectedFromUninstall returns true if the Helm release of the HelmReleaseProxy would be uninstalled but is protected
+// by Options.Protect, and neither the HelmReleaseProxy nor its HelmChartProxy has the allow-uninstall annotation.
+func (r *HelmReleaseProxyReconciler) isProtectedFromUninstall(ctx context.Context, helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy) (bool, error) {
+	if !helmReleaseProxy.Spec.Options.Protect {
+		return false, nil
+	}
+
+	// The release is orphaned rather than uninstalled, so there is nothing to protect.
+	if helmReleaseProxy.Spec.ReconcileStrategy == string(addonsv1alpha1.ReconcileStrategyInstallOnce) &&
+		helmReleaseProxy.Spec.DeletionPolicy != string(addonsv1alpha1.DeletionPolicyUninstall) {
+		return false, nil
+	}
+
+	if helmReleaseProxy.GetAnnotations()[addonsv1alpha1.AllowUninstallAnnotation] == "true" {
+		return false, nil
+	}
+
+	name, ok := helmReleaseProxy.Labels[addonsv1alpha1.HelmChartProxyLabelName]
+	if !ok {
+		return true, nil
+	}
+	helmChartProxy := &addonsv1alpha1.HelmChartProxy{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: helmReleaseProxy.Namespace, Name: name}, helmChartProxy); err != nil {
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+
+		return false, errors.Wrapf(err, "failed to get HelmChartProxy %s", name)
+	}
+
+	return helmChartProxy.GetAnnotations()[addonsv1alpha1.AllowUninstallAnnotation] != "true", nil
+}
+
+// ownerLabelsFor returns the labels identifying the HelmChartProxy and HelmReleaseProxy managing a release on the workload
+// Cluster. Values that are not valid label values, such as names longer than 63 characters, are omitted.
+func ownerLabelsFor(helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy) map[string]string {
+	labels := map[string]string{}
+	for key, value := range map[string]string{
+		addonsv1alpha1.OwnerNamespaceLabelName:        helmReleaseProxy.Namespace,
+		addonsv1alpha1.OwnerHelmChartProxyLabelName:   helmReleaseProxy.Labels[addonsv1alpha1.HelmChartProxyLabelName],
+		addonsv1alpha1.OwnerHelmReleaseProxyLabelName: helmReleaseProxy.Name,
+	} {
+		if value != "" && len(validation.IsValidLabelValue(value)) == 0 {
+			labels[key] = value
+		}
+	}
+
+	return labels
+}
+
+// setDeployedConfigLabels sets the values hash and chart digest labels of the HelmReleaseProxy to those of the deployed Helm
+// release. Both are truncated SHA-256 hashes, as label values are limited to 63 characters. The chart version, which is not
+// always a valid label value, and the full digest of the default values of the chart are set in the status instead.
+func setDeployedConfigLabels(helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy, release *helmRelease.Release) {
+	labels := helmReleaseProxy.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+
+	// Maps are marshaled with sorted keys, so equal values always have the same hash.
+	values, err := json.Marshal(release.Config)
+	if err == nil {
+		labels[addonsv1alpha1.ValuesHashLabelName] = truncatedHash(values)
+	}
+	if release.Chart != nil {
+		labels[addonsv1alpha1.ChartDigestLabelName] = chartDigest(release.Chart)
+	}
+	if release.Chart != nil && release.Chart.Metadata != nil {
+		helmReleaseProxy.Status.ChartVersion = release.Chart.Metadata.Version
+	}
+	if release.Chart != nil {
+		if defaults, err := json.Marshal(release.Chart.Values); err == nil {
+			sum := sha256.Sum256(defaults)
+			helmReleaseProxy.Status.DefaultValuesDigest = "sha256:" + hex.EncodeToString(sum[:])
+		}
+	}
+
+	helmReleaseProxy.SetLabels(labels)
+}
+
+// setAppliedDigests sets the digests of the manifest of the deployed Helm release and of the spec it was deployed from.
+func setAppliedDigests(helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy, spec addonsv1alpha1.HelmReleaseProxySpec, release *helmRelease.Release) {
+	sum := sha256.Sum256([]byte(release.Manifest))
+	helmReleaseProxy.Status.ManifestDigest = "sha256:" + hex.EncodeToString(sum[:])
+	helmReleaseProxy.Status.AppliedSpecDigest = specDigest(spec)
+}
+
+// specDigest returns the digest of the spec, or an empty string if it cannot be marshaled.
+func specDigest(spec addonsv1alpha1.HelmReleaseProxySpec) string {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// skipWait returns the spec to install or upgrade the Helm release with. If the HelmReleaseProxy has the
+// SkipWaitAnnotation, the options to wait for the resources of the release are cleared. The spec the release is deployed
+// from is not changed, so that setting or removing the annotation does not upgrade the release.
+func skipWait(ctx context.Context, helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy, spec addonsv1alpha1.HelmReleaseProxySpec) addonsv1alpha1.HelmReleaseProxySpec {
+	if helmReleaseProxy.GetAnnotations()[addonsv1alpha1.SkipWaitAnnotation] != "true" {
+		return spec
+	}
+
+	ctrl.LoggerFrom(ctx).Info("Not waiting for resources of release to become ready", "release", helmReleaseProxy.Spec.ReleaseName, "annotation", addonsv1alpha1.SkipWaitAnnotation, "cluster", helmReleaseProxy.Spec.ClusterRef.Name)
+	spec.Options.Wait = false
+	spec.Options.WaitForJobs = false
+	spec.Options.Atomic = false
+
+	return spec
+}
+
+// resyncPeriodFor returns the ResyncPeriod of the HelmReleaseProxy, which defaults to the DefaultVersionResolutionInterval
+// if its Version is a constraint, so that newly published matching versions are rolled out. It returns zero if the
+// HelmReleaseProxy is not resynced periodically.
+func resyncPeriodFor(helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy) time.Duration {
+	if helmReleaseProxy.Spec.ResyncPeriod != nil && helmReleaseProxy.Spec.ResyncPeriod.Duration > 0 {
+		return helmReleaseProxy.Spec.ResyncPeriod.Duration
+	}
+	if addonsv1alpha1.IsVersionConstraint(helmReleaseProxy.Spec.Version) {
+		return addonsv1alpha1.DefaultVersionResolutionInterval
+	}
+
+	return 0
+}
+
+// isReleaseUpToDate returns true if the Helm release was deployed from the spec and is ready, and the resync period of the
+// HelmReleaseProxy, if any, has not elapsed since its last successful reconcile. The Helm release then does not need to be
+// fetched from the workload Cluster to determine that no upgrade is needed.
+func isReleaseUpToDate(helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy, spec addonsv1alpha1.HelmReleaseProxySpec, now time.Time) bool {
+	if helmReleaseProxy.Status.AppliedSpecDigest == "" || helmReleaseProxy.Status.AppliedSpecDigest != specDigest(spec) {
+		return false
+	}
+	if helmReleaseProxy.Status.Status != helmRelease.StatusDeployed.String() || !conditions.IsTrue(helmReleaseProxy, addonsv1alpha1.HelmReleaseReadyCondition) {
+		return false
+	}
+	resyncPeriod := resyncPeriodFor(helmReleaseProxy)
+	if resyncPeriod == 0 {
+		return true
+	}
+	last := helmReleaseProxy.Status.LastSuccessfulReconcileTime
+
+	return last != nil && now.Sub(last.Time) < resyncPeriod
+}
+
+// chartDigest returns the truncated hash of the metadata, templates, files and default values of the chart. The archive of
+// the chart is not kept in the Helm release storage, so the digest is computed from its contents instead.
+func chartDigest(c *chart.Chart) string {
+	hash := sha256.New()
+	if c.Metadata != nil {
+		fmt.Fprintf(hash, "%s\x00%s\x00", c.Metadata.Name, c.Metadata.Version)
+	}
+	if c.Lock != nil {
+		fmt.Fprintf(hash, "%s\x00", c.Lock.Digest)
+	}
+
+	files := append(append([]*chart.File{}, c.Templates...), c.Files...)
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+	for _, file := range files {
+		fmt.Fprintf(hash, "%s\x00", file.Name)
+		hash.Write(file.Data)
+		hash.Write([]byte{0})
+	}
+	hash.Write(c.Schema)
+	if values, err := json.Marshal(c.Values); err == nil {
+		hash.Write(values)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))[:truncatedHashLength]
+}
+
+// truncatedHash returns the truncated hex-encoded SHA-256 hash of the data.
+func truncatedHash(data []byte) string {
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])[:truncatedHashLength]
+}
+
+// streamReleaseProgress periodically patches the progress of the Helm release into the HelmReleaseProxy status while an install
+// or upgrade waits for resources to become ready, and emits each notable event of its resources on the workload Cluster as an
+// event of the HelmReleaseProxy once. The returned function stops streaming and returns the last observed progress.
+func (r *HelmReleaseProxyReconciler) streamReleaseProgress(ctx context.Context, helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy, helmClient internal.Client, restConfig *rest.Config) func() *addonsv1alpha1.ReleaseProgress {
+	log := ctrl.LoggerFrom(ctx)
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	obj := helmReleaseProxy.DeepCopy()
+	var lastProgress *addonsv1alpha1.ReleaseProgress
+	recorded := map[string]struct{}{}
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(releaseProgressInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			progress, err := helmClient.GetHelmReleaseProgress(ctx, restConfig, obj.Spec)
+			if err != nil {
+				log.V(4).Info("Failed to get Helm release progress", "helmReleaseProxy", obj.Name, "error", err.Error())
+				continue
+			}
+			progress.LastUpdated = ptr.To(metav1.Now())
+			lastProgress = progress
+
+			for _, event := range progress.Events {
+				key := event.Kind + "/" + event.Namespace + "/" + event.Name + "/" + event.Reason
+				if _, ok := recorded[key]; ok {
+					continue
+				}
+				recorded[key] = struct{}{}
+				r.Recorder.Eventf(obj, corev1.EventTypeWarning, event.Reason, "%s %s/%s on cluster %s: %s", event.Kind, event.Namespace, event.Name, obj.Spec.ClusterRef.Name, event.Message)
+			}
+
+			before := obj.DeepCopy()
+			obj.Status.Progress = progress
+			if err := r.Status().Patch(ctx, obj, client.MergeFrom(before)); err != nil {
+				log.V(2).Info("Failed to patch Helm release progress", "helmReleaseProxy", obj.Name, "error", err.Error())
+			}
+		}
+	}()
+
+	return func() *addonsv1alpha1.ReleaseProgress {
+		cancel()
+		<-done
+
+		return lastProgress
+	}
+}
+
 // reconcileDelete handles HelmReleaseProxy deletion. This will uninstall the HelmReleaseProxy on the Cluster or return nil if the HelmReleaseProxy is not found.
 func (r *HelmReleaseProxyReconciler) reconcileDelete(ctx context.Context, helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy, client internal.Client, restConfig *rest.Config) error {
 	log := ctrl.LoggerFrom(ctx)
 
-	if helmReleaseProxy.Spec.ReconcileStrategy == string(addonsv1alpha1.ReconcileStrategyInstallOnce) {
-		log.V(2).Info("HelmReleaseProxy is in InstallOnce mode, nothing to do for uninstall", "HelmReleaseProxy", helmReleaseProxy.Name, "cluster", helmReleaseProxy.Spec.ClusterRef.Name)
+	if helmReleaseProxy.Spec.ReconcileStrategy == string(addonsv1alpha1.ReconcileStrategyInstallOnce) &&
+		helmReleaseProxy.Spec.DeletionPolicy != string(addonsv1alpha1.DeletionPolicyUninstall) {
+		log.V(2).Info("HelmReleaseProxy is in InstallOnce mode with Orphan deletion policy, nothing to do for uninstall", "HelmReleaseProxy", helmReleaseProxy.Name, "cluster", helmReleaseProxy.Spec.ClusterRef.Name)
 
 		return nil
 	}
 
 	log.V(2).Info("Deleting HelmReleaseProxy on cluster", "HelmReleaseProxy", helmReleaseProxy.Name, "cluster", helmReleaseProxy.Spec.ClusterRef.Name)
 
+	if helmReleaseProxy.Spec.Manifests != "" {
+		return r.deleteManifests(ctx, helmReleaseProxy, restConfig)
+	}
+
 	_, err := client.GetHelmRelease(ctx, restConfig, helmReleaseProxy.Spec)
 	if err != nil {
 		log.V(2).Error(err, "error getting release from cluster", "cluster", helmReleaseProxy.Spec.ClusterRef.Name)
@@ -386,6 +1189,11 @@ func patchHelmReleaseProxy(ctx context.Context, patchHelper *patch.Helper, helmR
 			clusterv1.ReadyCondition,
 			addonsv1alpha1.ClusterAvailableCondition,
 			addonsv1alpha1.HelmReleaseReadyCondition,
+			addonsv1alpha1.ReconciledRecentlyCondition,
+			addonsv1alpha1.DriftDetectedCondition,
+			addonsv1alpha1.UpgradeRolledBackCondition,
+			addonsv1alpha1.ReleaseTestedCondition,
+			addonsv1alpha1.ChartVerificationFailedCondition,
 		}},
 		patch.WithStatusObservedGeneration{},
 	)
@@ -416,6 +1224,154 @@ func (r *HelmReleaseProxyReconciler) getCredentials(ctx context.Context, helmRel
 	return credentialsPath, nil
 }
 
+// failoverLeaseDuration returns the duration of the ownership lease of the HelmReleaseProxy.
+func failoverLeaseDuration(helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy) time.Duration {
+	if helmReleaseProxy.Spec.Failover == nil || helmReleaseProxy.Spec.Failover.LeaseDuration == nil {
+		return addonsv1alpha1.DefaultFailoverLeaseDuration
+	}
+
+	return helmReleaseProxy.Spec.Failover.LeaseDuration.Duration
+}
+
+// failoverLeaseOwner returns the name the ownership lease of the HelmReleaseProxy is keyed by, which is the name of its
+// HelmChartProxy, or of the HelmReleaseProxy itself if it was not created by a HelmChartProxy.
+func failoverLeaseOwner(helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy) string {
+	if name := helmReleaseProxy.Labels[addonsv1alpha1.HelmChartProxyLabelName]; name != "" {
+		return name
+	}
+
+	return helmReleaseProxy.Name
+}
+
+// acquireFailoverLease acquires or renews the ownership lease of the HelmChartProxy of the HelmReleaseProxy on the
+// workload Cluster. If the lease is held by another management cluster, the lease of its holder is returned.
+func (r *HelmReleaseProxyReconciler) acquireFailoverLease(ctx context.Context, helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy, restConfig *rest.Config) (bool, internal.FailoverLease, error) {
+	workloadClient, err := r.failoverLeaseClient(helmReleaseProxy, restConfig)
+	if err != nil {
+		return false, internal.FailoverLease{}, err
+	}
+
+	acquired, lease, err := internal.AcquireFailoverLease(ctx, workloadClient, failoverLeaseOwner(helmReleaseProxy), r.FailoverIdentity, failoverLeaseDuration(helmReleaseProxy), time.Now())
+	if err != nil {
+		wrappedErr := errors.Wrapf(err, "failed to acquire lease on cluster")
+		conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.ClusterAvailableCondition, addonsv1alpha1.FailoverLeaseFailedReason, clusterv1.ConditionSeverityError, "%s", wrappedErr.Error())
+
+		return false, internal.FailoverLease{}, wrappedErr
+	}
+
+	return acquired, lease, nil
+}
+
+// releaseFailoverLease releases the ownership lease of the HelmChartProxy of the HelmReleaseProxy on the workload Cluster.
+func (r *HelmReleaseProxyReconciler) releaseFailoverLease(ctx context.Context, helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy, restConfig *rest.Config) error {
+	workloadClient, err := r.failoverLeaseClient(helmReleaseProxy, restConfig)
+	if err != nil {
+		return err
+	}
+
+	if err := internal.ReleaseFailoverLease(ctx, workloadClient, failoverLeaseOwner(helmReleaseProxy), r.FailoverIdentity); err != nil {
+		wrappedErr := errors.Wrapf(err, "failed to release lease on cluster")
+		conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.ClusterAvailableCondition, addonsv1alpha1.FailoverLeaseFailedReason, clusterv1.ConditionSeverityError, "%s", wrappedErr.Error())
+
+		return wrappedErr
+	}
+
+	return nil
+}
+
+// failoverLeaseClient returns a client of the workload Cluster for the ownership lease of the HelmReleaseProxy, or an error
+// if the controller was started without a failover identity.
+func (r *HelmReleaseProxyReconciler) failoverLeaseClient(helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy, restConfig *rest.Config) (client.Client, error) {
+	if r.FailoverIdentity == "" {
+		err := errors.New("failover requires the controller to be started with --failover-identity")
+		conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.ClusterAvailableCondition, addonsv1alpha1.FailoverLeaseFailedReason, clusterv1.ConditionSeverityError, "%s", err.Error())
+
+		return nil, err
+	}
+
+	workloadClient, err := client.New(restConfig, client.Options{})
+	if err != nil {
+		wrappedErr := errors.Wrapf(err, "failed to create client for cluster")
+		conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.ClusterAvailableCondition, addonsv1alpha1.FailoverLeaseFailedReason, clusterv1.ConditionSeverityError, "%s", wrappedErr.Error())
+
+		return nil, wrappedErr
+	}
+
+	return workloadClient, nil
+}
+
+// getRepositoryAuth fetches the headers and credentials of the HTTP chart repository from the RepositoryHeaders and
+// RepositoryCredentials Secrets, or the credentials of the Git repository from its AuthSecretRef.
+func (r *HelmReleaseProxyReconciler) getRepositoryAuth(ctx context.Context, helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy) (internal.RepositoryAuth, error) {
+	repositoryAuth := internal.RepositoryAuth{
+		ProxyURL: helmReleaseProxy.Spec.ProxyURL,
+	}
+
+	headers, err := r.getRepositorySecretData(ctx, helmReleaseProxy, helmReleaseProxy.Spec.RepositoryHeaders)
+	if err != nil {
+		return repositoryAuth, err
+	}
+	if headers != nil {
+		repositoryAuth.Headers = make(map[string]string, len(headers))
+		for name, value := range headers {
+			repositoryAuth.Headers[name] = string(value)
+		}
+	}
+
+	credentialsRef := helmReleaseProxy.Spec.RepositoryCredentials
+	if helmReleaseProxy.Spec.Git != nil {
+		credentialsRef = helmReleaseProxy.Spec.Git.AuthSecretRef
+	}
+	credentials, err := r.getRepositorySecretData(ctx, helmReleaseProxy, credentialsRef)
+	if err != nil {
+		return repositoryAuth, err
+	}
+	if credentials == nil {
+		return repositoryAuth, nil
+	}
+
+	token, hasToken := credentials[addonsv1alpha1.RepositoryTokenKey]
+	username, hasUsername := credentials[addonsv1alpha1.RepositoryUsernameKey]
+	password, hasPassword := credentials[addonsv1alpha1.RepositoryPasswordKey]
+	switch {
+	case hasToken && (hasUsername || hasPassword):
+		return repositoryAuth, errors.Errorf("repository credentials Secret %s must contain either the key %s or the keys %s and %s, not both",
+			credentialsRef.Name, addonsv1alpha1.RepositoryTokenKey, addonsv1alpha1.RepositoryUsernameKey, addonsv1alpha1.RepositoryPasswordKey)
+	case hasToken:
+		repositoryAuth.BearerToken = string(token)
+	case hasUsername && hasPassword:
+		repositoryAuth.Username = string(username)
+		repositoryAuth.Password = string(password)
+	default:
+		return repositoryAuth, errors.Errorf("repository credentials Secret %s must contain either the key %s or the keys %s and %s",
+			credentialsRef.Name, addonsv1alpha1.RepositoryTokenKey, addonsv1alpha1.RepositoryUsernameKey, addonsv1alpha1.RepositoryPasswordKey)
+	}
+
+	return repositoryAuth, nil
+}
+
+// getRepositorySecretData returns the data of the referenced Secret, or nil if no Secret is referenced.
+func (r *HelmReleaseProxyReconciler) getRepositorySecretData(ctx context.Context, helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy, secretRef *corev1.SecretReference) (map[string][]byte, error) {
+	if secretRef == nil || secretRef.Name == "" {
+		return nil, nil
+	}
+
+	// By default, the secret is in the same namespace as the HelmReleaseProxy
+	namespace := secretRef.Namespace
+	if namespace == "" {
+		namespace = helmReleaseProxy.Namespace
+	}
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: secretRef.Name, Namespace: namespace}, secret); err != nil {
+		return nil, err
+	}
+	if secret.Data == nil {
+		return map[string][]byte{}, nil
+	}
+
+	return secret.Data, nil
+}
+
 // getCAFile fetches the CA certificate from a Secret and writes it to a temporary file, returning the path to the temporary file.
 func (r *HelmReleaseProxyReconciler) getCAFile(ctx context.Context, helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy) (string, error) {
 	caFilePath := ""
@@ -505,3 +1461,85 @@ func writeCACertificateToFile(ctx context.Context, caCertificate []byte) (string
 
 	return caCertFile.Name(), nil
 }
+
+// getRESTConfig returns the REST config of the Cluster from its kubeconfig Secret. If the Secret does not exist yet, which
+// is expected while the Cluster is being provisioned, the ClusterAvailableCondition is marked as waiting for it and nil
+// is returned without an error.
+func (r *HelmReleaseProxyReconciler) getRESTConfig(ctx context.Context, helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy, cluster *clusterv1.Cluster) (*rest.Config, error) {
+	restConfig, err := remote.RESTConfig(ctx, "caaph", r.Client, util.ObjectKey(cluster))
+	if apierrors.IsNotFound(err) {
+		conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.ClusterAvailableCondition, addonsv1alpha1.WaitingForKubeconfigReason, clusterv1.ConditionSeverityInfo,
+			"kubeconfig Secret %s does not exist yet", secret.Name(cluster.Name, secret.Kubeconfig))
+
+		return nil, nil
+	}
+	if err != nil {
+		wrappedErr := errors.Wrapf(err, "failed to get kubeconfig for cluster")
+		conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.ClusterAvailableCondition, addonsv1alpha1.GetKubeconfigFailedReason, clusterv1.ConditionSeverityError, "%s", wrappedErr.Error())
+
+		return nil, wrappedErr
+	}
+	impersonate(restConfig, helmReleaseProxy.Spec.Impersonation)
+
+	return restConfig, nil
+}
+
+// impersonate sets the impersonation of the REST config of a Cluster, if any, so that the audit logs of the Cluster
+// attribute the changes to the Helm release to the impersonated identity.
+func impersonate(restConfig *rest.Config, impersonation *addonsv1alpha1.Impersonation) {
+	if impersonation == nil {
+		return
+	}
+
+	restConfig.Impersonate = rest.ImpersonationConfig{
+		UserName: impersonation.User,
+		Groups:   impersonation.Groups,
+	}
+}
+
+// kubeconfigSecretToHelmReleaseProxies is a mapper function that maps the kubeconfig Secret of a Cluster to the
+// HelmReleaseProxies of the Cluster, so that those waiting for the Secret are reconciled as soon as it is created.
+func (r *HelmReleaseProxyReconciler) kubeconfigSecretToHelmReleaseProxies(ctx context.Context, o client.Object) []reconcile.Request {
+	log := ctrl.LoggerFrom(ctx)
+
+	clusterName := o.GetLabels()[clusterv1.ClusterNameLabel]
+	if clusterName == "" || o.GetName() != secret.Name(clusterName, secret.Kubeconfig) {
+		return nil
+	}
+
+	helmReleaseProxies := &addonsv1alpha1.HelmReleaseProxyList{}
+	if err := r.List(ctx, helmReleaseProxies, client.InNamespace(o.GetNamespace()), client.MatchingLabels{clusterv1.ClusterNameLabel: clusterName}); err != nil {
+		log.Error(err, "failed to list HelmReleaseProxies")
+		return nil
+	}
+
+	results := []reconcile.Request{}
+	for i := range helmReleaseProxies.Items {
+		results = append(results, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&helmReleaseProxies.Items[i])})
+	}
+
+	return results
+}
+
+// globalPauseToHelmReleaseProxies is a mapper function that maps the global pause ConfigMap to all HelmReleaseProxies, so
+// that the changes held back during the pause are made as soon as it is lifted.
+func (r *HelmReleaseProxyReconciler) globalPauseToHelmReleaseProxies(ctx context.Context, o client.Object) []reconcile.Request {
+	log := ctrl.LoggerFrom(ctx)
+
+	if !r.GlobalPause.IsConfigMap(o) {
+		return nil
+	}
+
+	helmReleaseProxies := &addonsv1alpha1.HelmReleaseProxyList{}
+	if err := r.List(ctx, helmReleaseProxies); err != nil {
+		log.Error(err, "failed to list HelmReleaseProxies")
+		return nil
+	}
+
+	results := []reconcile.Request{}
+	for i := range helmReleaseProxies.Items {
+		results = append(results, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&helmReleaseProxies.Items[i])})
+	}
+
+	return results
+}
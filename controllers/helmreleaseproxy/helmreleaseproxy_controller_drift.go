@@ -0,0 +1,120 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helmreleaseproxy
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	helmRelease "helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/cluster-api-addon-provider-helm/internal"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// reconcileDrift compares the objects of the deployed Helm release against its manifest once the drift detection interval
+// elapsed, and re-applies the drifted objects unless the DriftPolicy is Warn or observeOnly is set.
+// It returns the duration after which the next drift check is due, or zero if drift is not detected. A failed drift check
+// is reported in the DriftDetected condition and does not fail the reconcile.
+func (r *HelmReleaseProxyReconciler) reconcileDrift(ctx context.Context, helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy, helmClient internal.Client, restConfig *rest.Config, observeOnly bool, now time.Time) time.Duration {
+	log := ctrl.LoggerFrom(ctx)
+
+	driftDetection := helmReleaseProxy.Spec.DriftDetection
+	if driftDetection == nil || !driftDetection.Enabled || helmReleaseProxy.Spec.DriftPolicy == string(addonsv1alpha1.DriftPolicyIgnore) {
+		conditions.Delete(helmReleaseProxy, addonsv1alpha1.DriftDetectedCondition)
+		helmReleaseProxy.Status.LastDriftCheckTime = nil
+		helmReleaseProxy.Status.DriftedResources = nil
+
+		return 0
+	}
+
+	// Releases installed in InstallOnce mode are not managed after the install, so their drift is not checked either.
+	if helmReleaseProxy.Spec.ReconcileStrategy == string(addonsv1alpha1.ReconcileStrategyInstallOnce) {
+		return 0
+	}
+
+	// Drift checks are skipped while the release is being troubleshot on the Cluster; the last result is kept.
+	if helmReleaseProxy.GetAnnotations()[addonsv1alpha1.SkipDriftCheckAnnotation] == "true" {
+		log.V(2).Info("Skipping drift check of release", "release", helmReleaseProxy.Spec.ReleaseName, "annotation", addonsv1alpha1.SkipDriftCheckAnnotation, "cluster", helmReleaseProxy.Spec.ClusterRef.Name)
+
+		return 0
+	}
+
+	interval := addonsv1alpha1.DefaultDriftDetectionInterval
+	if driftDetection.Interval != nil && driftDetection.Interval.Duration > 0 {
+		interval = driftDetection.Interval.Duration
+	}
+
+	if helmReleaseProxy.Status.Status != helmRelease.StatusDeployed.String() || !conditions.IsTrue(helmReleaseProxy, addonsv1alpha1.HelmReleaseReadyCondition) {
+		return interval
+	}
+
+	if last := helmReleaseProxy.Status.LastDriftCheckTime; last != nil && now.Sub(last.Time) < interval {
+		return interval - now.Sub(last.Time)
+	}
+
+	correct := helmReleaseProxy.Spec.DriftPolicy != string(addonsv1alpha1.DriftPolicyWarn) && !observeOnly
+	drifted, err := helmClient.ReconcileHelmReleaseDrift(ctx, restConfig, helmReleaseProxy.Spec, correct)
+	helmReleaseProxy.Status.LastDriftCheckTime = &metav1.Time{Time: now}
+	if err != nil {
+		log.Error(err, "Failed to check release for drift", "release", helmReleaseProxy.Spec.ReleaseName, "cluster", helmReleaseProxy.Spec.ClusterRef.Name)
+		conditions.Set(helmReleaseProxy, &clusterv1.Condition{
+			Type:     addonsv1alpha1.DriftDetectedCondition,
+			Status:   corev1.ConditionUnknown,
+			Reason:   addonsv1alpha1.DriftCheckFailedReason,
+			Severity: clusterv1.ConditionSeverityWarning,
+			Message:  err.Error(),
+		})
+
+		return interval
+	}
+
+	helmReleaseProxy.Status.DriftedResources = drifted
+	switch {
+	case len(drifted) == 0:
+		conditions.Set(helmReleaseProxy, &clusterv1.Condition{
+			Type:   addonsv1alpha1.DriftDetectedCondition,
+			Status: corev1.ConditionFalse,
+		})
+	case correct:
+		log.Info("Corrected drift of release", "release", helmReleaseProxy.Spec.ReleaseName, "resources", drifted, "cluster", helmReleaseProxy.Spec.ClusterRef.Name)
+		conditions.Set(helmReleaseProxy, &clusterv1.Condition{
+			Type:     addonsv1alpha1.DriftDetectedCondition,
+			Status:   corev1.ConditionTrue,
+			Reason:   addonsv1alpha1.DriftCorrectedReason,
+			Severity: clusterv1.ConditionSeverityInfo,
+			Message:  "Re-applied drifted resources: " + strings.Join(drifted, ", "),
+		})
+	default:
+		log.Info("Detected drift of release", "release", helmReleaseProxy.Spec.ReleaseName, "resources", drifted, "cluster", helmReleaseProxy.Spec.ClusterRef.Name)
+		conditions.Set(helmReleaseProxy, &clusterv1.Condition{
+			Type:     addonsv1alpha1.DriftDetectedCondition,
+			Status:   corev1.ConditionTrue,
+			Reason:   addonsv1alpha1.DriftDetectedReason,
+			Severity: clusterv1.ConditionSeverityWarning,
+			Message:  "Drifted resources: " + strings.Join(drifted, ", "),
+		})
+	}
+
+	return interval
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helmreleaseproxy
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/rest"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/cluster-api-addon-provider-helm/internal"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// reconcileManifests applies the Manifests of the HelmReleaseProxy to the Cluster instead of installing a Helm chart, and
+// records the applied resources in the ManifestInventory so that the resources removed from the Manifests are deleted.
+func (r *HelmReleaseProxyReconciler) reconcileManifests(ctx context.Context, helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy, restConfig *rest.Config, observeOnly bool) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	if observeOnly {
+		log.Info("Not applying manifests in observe-only mode", "cluster", helmReleaseProxy.Spec.ClusterRef.Name)
+
+		return nil
+	}
+
+	workloadClient, err := client.New(restConfig, client.Options{})
+	if err != nil {
+		wrappedErr := errors.Wrapf(err, "failed to create client for cluster")
+		conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.HelmReleaseReadyCondition, addonsv1alpha1.ManifestsApplyFailedReason, clusterv1.ConditionSeverityError, "%s", wrappedErr.Error())
+
+		return wrappedErr
+	}
+
+	log.V(2).Info("Applying manifests", "cluster", helmReleaseProxy.Spec.ClusterRef.Name)
+	inventory, err := internal.ApplyManifests(ctx, workloadClient, helmReleaseProxy.Spec, helmReleaseProxy.Status.ManifestInventory)
+	helmReleaseProxy.Status.ManifestInventory = inventory
+	if err != nil {
+		conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.HelmReleaseReadyCondition, addonsv1alpha1.ManifestsApplyFailedReason, clusterv1.ConditionSeverityError, "%s", err.Error())
+
+		return errors.Wrapf(err, "failed to apply manifests on cluster %s", helmReleaseProxy.Spec.ClusterRef.Name)
+	}
+
+	conditions.MarkTrue(helmReleaseProxy, addonsv1alpha1.HelmReleaseReadyCondition)
+	annotations := helmReleaseProxy.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[addonsv1alpha1.ReleaseSuccessfullyInstalledAnnotation] = "true"
+	helmReleaseProxy.SetAnnotations(annotations)
+
+	return nil
+}
+
+// deleteManifests deletes the resources of the ManifestInventory of the HelmReleaseProxy from the Cluster.
+func (r *HelmReleaseProxyReconciler) deleteManifests(ctx context.Context, helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy, restConfig *rest.Config) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	workloadClient, err := client.New(restConfig, client.Options{})
+	if err != nil {
+		wrappedErr := errors.Wrapf(err, "failed to create client for cluster")
+		conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.HelmReleaseReadyCondition, addonsv1alpha1.HelmReleaseDeletionFailedReason, clusterv1.ConditionSeverityError, "%s", wrappedErr.Error())
+
+		return wrappedErr
+	}
+
+	log.V(2).Info("Deleting manifests", "cluster", helmReleaseProxy.Spec.ClusterRef.Name, "resources", len(helmReleaseProxy.Status.ManifestInventory))
+	if err := internal.DeleteManifests(ctx, workloadClient, helmReleaseProxy.Status.ManifestInventory); err != nil {
+		conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.HelmReleaseReadyCondition, addonsv1alpha1.HelmReleaseDeletionFailedReason, clusterv1.ConditionSeverityError, "%s", err.Error())
+
+		return errors.Wrapf(err, "failed to delete manifests on cluster %s", helmReleaseProxy.Spec.ClusterRef.Name)
+	}
+
+	helmReleaseProxy.Status.ManifestInventory = nil
+	conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.HelmReleaseReadyCondition, addonsv1alpha1.HelmReleaseDeletedReason, clusterv1.ConditionSeverityInfo, "")
+
+	return nil
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helmreleaseproxy
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// helmRepositoryKey returns the key of the HelmRepository referenced by the HelmReleaseProxy, defaulting its namespace to
+// the namespace of the HelmReleaseProxy.
+func helmRepositoryKey(helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy) types.NamespacedName {
+	key := types.NamespacedName{
+		Namespace: helmReleaseProxy.Spec.RepositoryRef.Namespace,
+		Name:      helmReleaseProxy.Spec.RepositoryRef.Name,
+	}
+	if key.Namespace == "" {
+		key.Namespace = helmReleaseProxy.Namespace
+	}
+
+	return key
+}
+
+// getHelmRepository returns the HelmRepository referenced by the HelmReleaseProxy, or nil if it references none, marking
+// the HelmReleaseReady condition false if it cannot be fetched.
+func (r *HelmReleaseProxyReconciler) getHelmRepository(ctx context.Context, helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy) (*addonsv1alpha1.HelmRepository, error) {
+	if helmReleaseProxy.Spec.RepositoryRef == nil {
+		return nil, nil
+	}
+
+	key := helmRepositoryKey(helmReleaseProxy)
+	repository := &addonsv1alpha1.HelmRepository{}
+	if err := r.Get(ctx, key, repository); err != nil {
+		wrappedErr := errors.Wrapf(err, "failed to get HelmRepository %s", key)
+		conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.HelmReleaseReadyCondition, addonsv1alpha1.HelmRepositoryUnavailableReason, clusterv1.ConditionSeverityError, "%s", wrappedErr.Error())
+
+		return nil, wrappedErr
+	}
+
+	return repository, nil
+}
+
+// applyHelmRepository sets the URL, credentials, TLS configuration and proxy of the spec to the ones of the HelmRepository,
+// defaulting the namespaces of its Secret references to the namespace of the HelmRepository.
+func applyHelmRepository(spec *addonsv1alpha1.HelmReleaseProxySpec, repository *addonsv1alpha1.HelmRepository) {
+	repositorySpec := repository.Spec.DeepCopy()
+
+	spec.RepoURL = repositorySpec.URL
+	spec.Credentials = repositorySpec.Credentials
+	if spec.Credentials != nil {
+		if spec.Credentials.Secret.Namespace == "" {
+			spec.Credentials.Secret.Namespace = repository.Namespace
+		}
+		if spec.Credentials.Key == "" {
+			spec.Credentials.Key = addonsv1alpha1.DefaultOCIKey
+		}
+	}
+	spec.RepositoryHeaders = repositorySpec.RepositoryHeaders
+	if spec.RepositoryHeaders != nil && spec.RepositoryHeaders.Namespace == "" {
+		spec.RepositoryHeaders.Namespace = repository.Namespace
+	}
+	spec.RepositoryCredentials = repositorySpec.RepositoryCredentials
+	if spec.RepositoryCredentials != nil && spec.RepositoryCredentials.Namespace == "" {
+		spec.RepositoryCredentials.Namespace = repository.Namespace
+	}
+	spec.TLSConfig = repositorySpec.TLSConfig
+	if spec.TLSConfig != nil {
+		if spec.TLSConfig.CASecretRef != nil && spec.TLSConfig.CASecretRef.Namespace == "" {
+			spec.TLSConfig.CASecretRef.Namespace = repository.Namespace
+		}
+		if spec.TLSConfig.CertManagerRef != nil && spec.TLSConfig.CertManagerRef.Namespace == "" {
+			spec.TLSConfig.CertManagerRef.Namespace = repository.Namespace
+		}
+	}
+	spec.ProxyURL = repositorySpec.ProxyURL
+}
+
+// withHelmRepository returns the HelmReleaseProxy with the HelmRepository it references applied to a copy of its spec, so
+// that the resolved repository is never persisted, and the HelmRepository. The HelmReleaseProxy is returned unchanged if it
+// references no HelmRepository.
+func (r *HelmReleaseProxyReconciler) withHelmRepository(ctx context.Context, helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy) (*addonsv1alpha1.HelmReleaseProxy, *addonsv1alpha1.HelmRepository, error) {
+	repository, err := r.getHelmRepository(ctx, helmReleaseProxy)
+	if err != nil || repository == nil {
+		return helmReleaseProxy, nil, err
+	}
+
+	resolved := helmReleaseProxy.DeepCopy()
+	applyHelmRepository(&resolved.Spec, repository)
+
+	return resolved, repository, nil
+}
+
+// helmRepositoryToHelmReleaseProxies is a mapper function that maps a HelmRepository to the HelmReleaseProxies referencing
+// it, so that changes to the repository are picked up right away.
+func (r *HelmReleaseProxyReconciler) helmRepositoryToHelmReleaseProxies(ctx context.Context, o client.Object) []reconcile.Request {
+	log := ctrl.LoggerFrom(ctx)
+
+	helmReleaseProxies := &addonsv1alpha1.HelmReleaseProxyList{}
+	if err := r.List(ctx, helmReleaseProxies); err != nil {
+		log.Error(err, "failed to list HelmReleaseProxies")
+		return nil
+	}
+
+	results := []reconcile.Request{}
+	for i := range helmReleaseProxies.Items {
+		helmReleaseProxy := &helmReleaseProxies.Items[i]
+		if helmReleaseProxy.Spec.RepositoryRef == nil {
+			continue
+		}
+		if helmRepositoryKey(helmReleaseProxy) == client.ObjectKeyFromObject(o) {
+			results = append(results, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(helmReleaseProxy)})
+		}
+	}
+
+	return results
+}
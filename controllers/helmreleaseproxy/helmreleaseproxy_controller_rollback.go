@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helmreleaseproxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/cluster-api-addon-provider-helm/internal"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// reconcileRollback rolls back the Helm release on the Cluster to the revision of RollbackTo and clears it. The rollback
+// is recorded in the status, so that reconcileNormal holds installs and upgrades until the chart or values change.
+func (r *HelmReleaseProxyReconciler) reconcileRollback(ctx context.Context, helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy, helmClient internal.Client, restConfig *rest.Config) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	// RollbackTo is a one-off request, so it is cleared whether or not the rollback succeeds.
+	revision := helmReleaseProxy.Spec.RollbackTo.Revision
+	helmReleaseProxy.Spec.RollbackTo = nil
+
+	log.Info("Rolling back Helm release", "release", helmReleaseProxy.Spec.ReleaseName, "revision", revision, "cluster", helmReleaseProxy.Spec.ClusterRef.Name)
+	release, err := helmClient.RollbackHelmRelease(ctx, restConfig, helmReleaseProxy.Spec, revision)
+	if err != nil {
+		conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.HelmReleaseReadyCondition, addonsv1alpha1.HelmReleaseRollbackFailedReason, clusterv1.ConditionSeverityError, "%s", err.Error())
+
+		return errors.Wrapf(err, "failed to roll back release %s to revision %d on cluster %s", helmReleaseProxy.Spec.ReleaseName, revision, helmReleaseProxy.Spec.ClusterRef.Name)
+	}
+
+	helmReleaseProxy.SetReleaseStatus(release.Info.Status.String())
+	helmReleaseProxy.SetReleaseRevision(release.Version)
+	helmReleaseProxy.SetReleaseName(release.Name)
+	setDeployedConfigLabels(helmReleaseProxy, release)
+	helmReleaseProxy.Status.Rollback = &addonsv1alpha1.RollbackStatus{
+		Revision: revision,
+		Time:     metav1.Now(),
+		SpecHash: rollbackSpecHash(helmReleaseProxy.Spec),
+	}
+	markRolledBack(helmReleaseProxy)
+
+	return nil
+}
+
+// isHeldForRollback returns true if the Helm release was rolled back and the chart and values of the HelmReleaseProxy have
+// not changed since, so that installing or upgrading it would undo the rollback. Once they change, the rollback status is
+// cleared.
+func isHeldForRollback(helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy) bool {
+	rollback := helmReleaseProxy.Status.Rollback
+	if rollback == nil {
+		return false
+	}
+
+	if rollback.SpecHash != rollbackSpecHash(helmReleaseProxy.Spec) {
+		helmReleaseProxy.Status.Rollback = nil
+
+		return false
+	}
+
+	return true
+}
+
+// markRolledBack marks the Helm release of the HelmReleaseProxy as rolled back.
+func markRolledBack(helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy) {
+	conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.HelmReleaseReadyCondition, addonsv1alpha1.HelmReleaseRolledBackReason, clusterv1.ConditionSeverityInfo,
+		"Helm release was rolled back to revision %d, upgrades are held until the chart or values change", helmReleaseProxy.Status.Rollback.Revision)
+}
+
+// rollbackSpecHash returns the hash of the chart and values of the HelmReleaseProxy spec.
+func rollbackSpecHash(spec addonsv1alpha1.HelmReleaseProxySpec) string {
+	hash := sha256.New()
+	for _, field := range []string{spec.RepoURL, spec.ChartName, spec.Version, spec.Values} {
+		hash.Write([]byte(field))
+		hash.Write([]byte{0})
+	}
+	for _, ref := range spec.ValuesRefs {
+		fmt.Fprintf(hash, "%s\x00%s\x00", ref.Key, ref.Hash)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
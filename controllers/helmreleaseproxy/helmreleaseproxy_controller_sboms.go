@@ -0,0 +1,109 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helmreleaseproxy
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/cluster-api-addon-provider-helm/internal"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// maxSBOMConfigMapSize is the maximum total size of the SBOMs copied to a ConfigMap, as ConfigMaps cannot hold more than
+// 1 MiB of data.
+const maxSBOMConfigMapSize = 1024 * 1024
+
+// reconcileSBOMConfigMap copies the SBOMs in the HelmReleaseProxy status to a ConfigMap owned by the HelmReleaseProxy if
+// CopySBOMs is set, and deletes a previously created ConfigMap otherwise. SBOMs already in the ConfigMap are not fetched
+// again, as the content of a digest never changes.
+func (r *HelmReleaseProxyReconciler) reconcileSBOMConfigMap(ctx context.Context, helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy, spec addonsv1alpha1.HelmReleaseProxySpec, helmClient internal.Client, credentialsPath, caFilePath string) error {
+	if !helmReleaseProxy.Spec.CopySBOMs || len(helmReleaseProxy.Status.SBOMs) == 0 {
+		if helmReleaseProxy.Status.SBOMConfigMapName == "" {
+			return nil
+		}
+
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      helmReleaseProxy.Status.SBOMConfigMapName,
+				Namespace: helmReleaseProxy.Namespace,
+			},
+		}
+		if err := r.Client.Delete(ctx, configMap); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete SBOM ConfigMap %s", configMap.Name)
+		}
+		helmReleaseProxy.Status.SBOMConfigMapName = ""
+
+		return nil
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      helmReleaseProxy.Name + "-sboms",
+			Namespace: helmReleaseProxy.Namespace,
+		},
+	}
+	if err := r.Client.Get(ctx, client.ObjectKeyFromObject(configMap), configMap); err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to get SBOM ConfigMap %s", configMap.Name)
+	}
+
+	data := make(map[string]string, len(helmReleaseProxy.Status.SBOMs))
+	size := 0
+	for _, sbom := range helmReleaseProxy.Status.SBOMs {
+		key := strings.Replace(sbom.Digest, ":", "-", 1)
+		content, ok := configMap.Data[key]
+		if !ok {
+			// The SBOM is read up to the space left in the ConfigMap, so that large SBOMs are not buffered in full.
+			b, err := helmClient.GetChartSBOM(ctx, spec, credentialsPath, caFilePath, sbom, int64(maxSBOMConfigMapSize-size))
+			if err != nil {
+				return errors.Wrapf(err, "failed to get SBOM %s of chart %s within the maximum ConfigMap size of %d bytes", sbom.Location, helmReleaseProxy.Spec.ChartName, maxSBOMConfigMapSize)
+			}
+			content = string(b)
+		}
+
+		size += len(content)
+		if size > maxSBOMConfigMapSize {
+			return errors.Errorf("SBOMs of chart %s exceed the maximum ConfigMap size of %d bytes", helmReleaseProxy.Spec.ChartName, maxSBOMConfigMapSize)
+		}
+		data[key] = content
+	}
+
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, configMap, func() error {
+		if configMap.Labels == nil {
+			configMap.Labels = map[string]string{}
+		}
+		configMap.Labels[clusterv1.ClusterNameLabel] = helmReleaseProxy.Spec.ClusterRef.Name
+		if helmChartProxyName, ok := helmReleaseProxy.Labels[addonsv1alpha1.HelmChartProxyLabelName]; ok {
+			configMap.Labels[addonsv1alpha1.HelmChartProxyLabelName] = helmChartProxyName
+		}
+		configMap.Data = data
+
+		return controllerutil.SetControllerReference(helmReleaseProxy, configMap, r.Client.Scheme())
+	}); err != nil {
+		return errors.Wrapf(err, "failed to create or update SBOM ConfigMap %s", configMap.Name)
+	}
+	helmReleaseProxy.Status.SBOMConfigMapName = configMap.Name
+
+	return nil
+}
@@ -17,23 +17,34 @@ limitations under the License.
 package helmreleaseproxy
 
 import (
+	"crypto/sha256"
 	"fmt"
+	"os"
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
 	"go.uber.org/mock/gomock"
+	"helm.sh/helm/v3/pkg/chart"
 	helmRelease "helm.sh/helm/v3/pkg/release"
 	helmDriver "helm.sh/helm/v3/pkg/storage/driver"
+	helmTime "helm.sh/helm/v3/pkg/time"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
 	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/cluster-api-addon-provider-helm/internal"
 	"sigs.k8s.io/cluster-api-addon-provider-helm/internal/mocks"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util/conditions"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
@@ -280,19 +291,26 @@ func TestReconcileNormal(t *testing.T) {
 			name:             "successfully install a Helm release",
 			helmReleaseProxy: defaultProxy.DeepCopy(),
 			clientExpect: func(g *WithT, c *mocks.MockClientMockRecorder) {
-				c.InstallOrUpgradeHelmRelease(ctx, restConfig, "", "", defaultProxy.DeepCopy().Spec).Return(&helmRelease.Release{
+				c.InstallOrUpgradeHelmRelease(ctx, restConfig, "", "", internal.RepositoryAuth{}, defaultProxy.DeepCopy().Spec).Return(&helmRelease.Release{
 					Name:    "test-release",
 					Version: 1,
 					Info: &helmRelease.Info{
 						Status: helmRelease.StatusDeployed,
 					},
 				}, nil).Times(1)
+				c.LabelReleaseResources(ctx, restConfig, defaultProxy.DeepCopy().Spec, 1, map[string]string{
+					addonsv1alpha1.OwnerNamespaceLabelName:        defaultProxy.Namespace,
+					addonsv1alpha1.OwnerHelmReleaseProxyLabelName: defaultProxy.Name,
+				}).Return(nil).Times(1)
+				c.GetChartSBOMs(ctx, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).Times(1)
+				c.ResolveChartDigest(ctx, gomock.Any(), gomock.Any(), gomock.Any()).Return("sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", nil).Times(1)
 			},
 			expect: func(g *WithT, hrp *addonsv1alpha1.HelmReleaseProxy) {
 				_, ok := hrp.Annotations[addonsv1alpha1.IsReleaseNameGeneratedAnnotation]
 				g.Expect(ok).To(BeFalse())
 				g.Expect(hrp.Spec.ReleaseName).To(Equal("test-release"))
 				g.Expect(hrp.Status.Revision).To(Equal(1))
+				g.Expect(hrp.Status.ResolvedDigest).To(Equal("sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"))
 				g.Expect(hrp.Status.Status).To(BeEquivalentTo(helmRelease.StatusDeployed))
 
 				g.Expect(conditions.Has(hrp, addonsv1alpha1.HelmReleaseReadyCondition)).To(BeTrue())
@@ -304,13 +322,16 @@ func TestReconcileNormal(t *testing.T) {
 			name:             "successfully install a Helm release with a generated name",
 			helmReleaseProxy: generateNameProxy,
 			clientExpect: func(g *WithT, c *mocks.MockClientMockRecorder) {
-				c.InstallOrUpgradeHelmRelease(ctx, restConfig, "", "", generateNameProxy.Spec).Return(&helmRelease.Release{
+				c.InstallOrUpgradeHelmRelease(ctx, restConfig, "", "", internal.RepositoryAuth{}, generateNameProxy.Spec).Return(&helmRelease.Release{
 					Name:    "test-release",
 					Version: 1,
 					Info: &helmRelease.Info{
 						Status: helmRelease.StatusDeployed,
 					},
 				}, nil).Times(1)
+				c.LabelReleaseResources(ctx, restConfig, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+				c.GetChartSBOMs(ctx, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).Times(1)
+				c.ResolveChartDigest(ctx, gomock.Any(), gomock.Any(), gomock.Any()).Return("", nil).Times(1)
 			},
 			expect: func(g *WithT, hrp *addonsv1alpha1.HelmReleaseProxy) {
 				_, ok := hrp.Annotations[addonsv1alpha1.IsReleaseNameGeneratedAnnotation]
@@ -328,7 +349,7 @@ func TestReconcileNormal(t *testing.T) {
 			name:             "Helm release pending",
 			helmReleaseProxy: defaultProxy.DeepCopy(),
 			clientExpect: func(g *WithT, c *mocks.MockClientMockRecorder) {
-				c.InstallOrUpgradeHelmRelease(ctx, restConfig, "", "", defaultProxy.Spec).Return(&helmRelease.Release{
+				c.InstallOrUpgradeHelmRelease(ctx, restConfig, "", "", internal.RepositoryAuth{}, defaultProxy.Spec).Return(&helmRelease.Release{
 					Name:    "test-release",
 					Version: 1,
 					Info: &helmRelease.Info{
@@ -355,7 +376,7 @@ func TestReconcileNormal(t *testing.T) {
 			name:             "Helm client returns error",
 			helmReleaseProxy: defaultProxy.DeepCopy(),
 			clientExpect: func(g *WithT, c *mocks.MockClientMockRecorder) {
-				c.InstallOrUpgradeHelmRelease(ctx, restConfig, "", "", defaultProxy.Spec).Return(nil, errInternal).Times(1)
+				c.InstallOrUpgradeHelmRelease(ctx, restConfig, "", "", internal.RepositoryAuth{}, defaultProxy.Spec).Return(nil, errInternal).Times(1)
 			},
 			expect: func(g *WithT, hrp *addonsv1alpha1.HelmReleaseProxy) {
 				_, ok := hrp.Annotations[addonsv1alpha1.IsReleaseNameGeneratedAnnotation]
@@ -373,7 +394,7 @@ func TestReconcileNormal(t *testing.T) {
 			name:             "Helm release in a failed state, no client error",
 			helmReleaseProxy: defaultProxy.DeepCopy(),
 			clientExpect: func(g *WithT, c *mocks.MockClientMockRecorder) {
-				c.InstallOrUpgradeHelmRelease(ctx, restConfig, "", "", defaultProxy.Spec).Return(&helmRelease.Release{
+				c.InstallOrUpgradeHelmRelease(ctx, restConfig, "", "", internal.RepositoryAuth{}, defaultProxy.Spec).Return(&helmRelease.Release{
 					Name:    "test-release",
 					Version: 1,
 					Info: &helmRelease.Info{
@@ -415,13 +436,16 @@ func TestReconcileNormal(t *testing.T) {
 			name:             "successfully install a Helm release when strategy is InstallOnce",
 			helmReleaseProxy: installOnceProxyNotInstalled.DeepCopy(),
 			clientExpect: func(g *WithT, c *mocks.MockClientMockRecorder) {
-				c.InstallOrUpgradeHelmRelease(ctx, restConfig, "", "", installOnceProxyNotInstalled.DeepCopy().Spec).Return(&helmRelease.Release{
+				c.InstallOrUpgradeHelmRelease(ctx, restConfig, "", "", internal.RepositoryAuth{}, installOnceProxyNotInstalled.DeepCopy().Spec).Return(&helmRelease.Release{
 					Name:    "test-release",
 					Version: 1,
 					Info: &helmRelease.Info{
 						Status: helmRelease.StatusDeployed,
 					},
 				}, nil).Times(1)
+				c.LabelReleaseResources(ctx, restConfig, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+				c.GetChartSBOMs(ctx, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).Times(1)
+				c.ResolveChartDigest(ctx, gomock.Any(), gomock.Any(), gomock.Any()).Return("", nil).Times(1)
 			},
 			expect: func(g *WithT, hrp *addonsv1alpha1.HelmReleaseProxy) {
 				_, ok := hrp.Annotations[addonsv1alpha1.IsReleaseNameGeneratedAnnotation]
@@ -458,7 +482,7 @@ func TestReconcileNormal(t *testing.T) {
 					Build(),
 			}
 
-			err := r.reconcileNormal(ctx, tc.helmReleaseProxy, clientMock, "", "", restConfig)
+			err := r.reconcileNormal(ctx, tc.helmReleaseProxy, clientMock, "", "", internal.RepositoryAuth{}, restConfig, false)
 			if tc.expectedError != "" {
 				g.Expect(err).To(HaveOccurred())
 				g.Expect(err).To(MatchError(tc.expectedError), err.Error())
@@ -470,6 +494,113 @@ func TestReconcileNormal(t *testing.T) {
 	}
 }
 
+func TestReconcileNormalObserveOnly(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name             string
+		helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy
+		clientExpect     func(g *WithT, c *mocks.MockClientMockRecorder)
+		expect           func(g *WithT, hrp *addonsv1alpha1.HelmReleaseProxy)
+		expectedError    string
+	}{
+		{
+			name:             "reports a pending install",
+			helmReleaseProxy: defaultProxy.DeepCopy(),
+			clientExpect: func(g *WithT, c *mocks.MockClientMockRecorder) {
+				c.DiffHelmRelease(ctx, restConfig, "", "", internal.RepositoryAuth{}, defaultProxy.DeepCopy().Spec).Return(nil, "install chart test-chart version 1.0.0", nil).Times(1)
+			},
+			expect: func(g *WithT, hrp *addonsv1alpha1.HelmReleaseProxy) {
+				g.Expect(hrp.Status.PendingChange).To(Equal("install chart test-chart version 1.0.0"))
+				g.Expect(conditions.IsFalse(hrp, addonsv1alpha1.HelmReleaseReadyCondition)).To(BeTrue())
+				g.Expect(conditions.GetReason(hrp, addonsv1alpha1.HelmReleaseReadyCondition)).To(Equal(addonsv1alpha1.HelmReleaseChangePendingReason))
+				g.Expect(conditions.GetMessage(hrp, addonsv1alpha1.HelmReleaseReadyCondition)).To(Equal("would install chart test-chart version 1.0.0"))
+				_, ok := hrp.Annotations[addonsv1alpha1.ReleaseSuccessfullyInstalledAnnotation]
+				g.Expect(ok).To(BeFalse())
+			},
+		},
+		{
+			name:             "reports a pending upgrade with the first line of the change in the condition",
+			helmReleaseProxy: defaultProxy.DeepCopy(),
+			clientExpect: func(g *WithT, c *mocks.MockClientMockRecorder) {
+				c.DiffHelmRelease(ctx, restConfig, "", "", internal.RepositoryAuth{}, defaultProxy.DeepCopy().Spec).Return(&helmRelease.Release{
+					Name:    "test-release",
+					Version: 3,
+					Info: &helmRelease.Info{
+						Status: helmRelease.StatusDeployed,
+					},
+				}, "upgrade values of release test-release, values diff (-existing +desired):\n-a: 1\n+a: 2", nil).Times(1)
+			},
+			expect: func(g *WithT, hrp *addonsv1alpha1.HelmReleaseProxy) {
+				g.Expect(hrp.Status.Revision).To(Equal(3))
+				g.Expect(hrp.Status.PendingChange).To(ContainSubstring("+a: 2"))
+				g.Expect(conditions.GetReason(hrp, addonsv1alpha1.HelmReleaseReadyCondition)).To(Equal(addonsv1alpha1.HelmReleaseChangePendingReason))
+				g.Expect(conditions.GetMessage(hrp, addonsv1alpha1.HelmReleaseReadyCondition)).To(Equal("would upgrade values of release test-release, values diff (-existing +desired):"))
+			},
+		},
+		{
+			name: "reports an up to date release as ready",
+			helmReleaseProxy: func() *addonsv1alpha1.HelmReleaseProxy {
+				hrp := defaultProxy.DeepCopy()
+				hrp.Status.PendingChange = "install chart test-chart version 1.0.0"
+
+				return hrp
+			}(),
+			clientExpect: func(g *WithT, c *mocks.MockClientMockRecorder) {
+				c.DiffHelmRelease(ctx, restConfig, "", "", internal.RepositoryAuth{}, defaultProxy.DeepCopy().Spec).Return(&helmRelease.Release{
+					Name:    "test-release",
+					Version: 1,
+					Info: &helmRelease.Info{
+						Status: helmRelease.StatusDeployed,
+					},
+				}, "", nil).Times(1)
+			},
+			expect: func(g *WithT, hrp *addonsv1alpha1.HelmReleaseProxy) {
+				g.Expect(hrp.Status.PendingChange).To(BeEmpty())
+				g.Expect(conditions.IsTrue(hrp, addonsv1alpha1.HelmReleaseReadyCondition)).To(BeTrue())
+			},
+		},
+		{
+			name:             "fails to compute the change",
+			helmReleaseProxy: defaultProxy.DeepCopy(),
+			clientExpect: func(g *WithT, c *mocks.MockClientMockRecorder) {
+				c.DiffHelmRelease(ctx, restConfig, "", "", internal.RepositoryAuth{}, defaultProxy.DeepCopy().Spec).Return(nil, "", errInternal).Times(1)
+			},
+			expect: func(g *WithT, hrp *addonsv1alpha1.HelmReleaseProxy) {
+				g.Expect(conditions.GetReason(hrp, addonsv1alpha1.HelmReleaseReadyCondition)).To(Equal(addonsv1alpha1.HelmReleaseDiffFailedReason))
+			},
+			expectedError: "failed to compute change to release on cluster test-cluster: internal error",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			t.Parallel()
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			clientMock := mocks.NewMockClient(mockCtrl)
+			tc.clientExpect(g, clientMock.EXPECT())
+
+			r := &HelmReleaseProxyReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(fakeScheme).
+					WithStatusSubresource(&addonsv1alpha1.HelmReleaseProxy{}).
+					Build(),
+			}
+
+			err := r.reconcileNormal(ctx, tc.helmReleaseProxy, clientMock, "", "", internal.RepositoryAuth{}, restConfig, true)
+			if tc.expectedError != "" {
+				g.Expect(err).To(MatchError(tc.expectedError))
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+			tc.expect(g, tc.helmReleaseProxy)
+		})
+	}
+}
+
 func TestReconcileNormalWithCredentialRef(t *testing.T) {
 	t.Parallel()
 
@@ -484,13 +615,16 @@ func TestReconcileNormalWithCredentialRef(t *testing.T) {
 			name:             "successfully install a Helm release",
 			helmReleaseProxy: defaultProxyWithCredentialRef.DeepCopy(),
 			clientExpect: func(g *WithT, c *mocks.MockClientMockRecorder) {
-				c.InstallOrUpgradeHelmRelease(ctx, restConfig, "/tmp/oci-credentials-xyz.json", "", defaultProxyWithCredentialRef.Spec).Return(&helmRelease.Release{
+				c.InstallOrUpgradeHelmRelease(ctx, restConfig, "/tmp/oci-credentials-xyz.json", "", internal.RepositoryAuth{}, defaultProxyWithCredentialRef.Spec).Return(&helmRelease.Release{
 					Name:    "test-release",
 					Version: 1,
 					Info: &helmRelease.Info{
 						Status: helmRelease.StatusDeployed,
 					},
 				}, nil).Times(1)
+				c.LabelReleaseResources(ctx, restConfig, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+				c.GetChartSBOMs(ctx, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).Times(1)
+				c.ResolveChartDigest(ctx, gomock.Any(), gomock.Any(), gomock.Any()).Return("", nil).Times(1)
 			},
 			expect: func(g *WithT, hrp *addonsv1alpha1.HelmReleaseProxy) {
 				_, ok := hrp.Annotations[addonsv1alpha1.IsReleaseNameGeneratedAnnotation]
@@ -523,7 +657,7 @@ func TestReconcileNormalWithCredentialRef(t *testing.T) {
 					Build(),
 			}
 
-			err := r.reconcileNormal(ctx, tc.helmReleaseProxy, clientMock, "/tmp/oci-credentials-xyz.json", "", restConfig)
+			err := r.reconcileNormal(ctx, tc.helmReleaseProxy, clientMock, "/tmp/oci-credentials-xyz.json", "", internal.RepositoryAuth{}, restConfig, false)
 			if tc.expectedError != "" {
 				g.Expect(err).To(HaveOccurred())
 				g.Expect(err).To(MatchError(tc.expectedError), err.Error())
@@ -549,13 +683,16 @@ func TestReconcileNormalWithACertificateRef(t *testing.T) {
 			name:             "successfully install a Helm release",
 			helmReleaseProxy: defaultProxyWithCACertRef.DeepCopy(),
 			clientExpect: func(g *WithT, c *mocks.MockClientMockRecorder) {
-				c.InstallOrUpgradeHelmRelease(ctx, restConfig, "", "/tmp/ca-xyz.crt", defaultProxyWithCACertRef.Spec).Return(&helmRelease.Release{
+				c.InstallOrUpgradeHelmRelease(ctx, restConfig, "", "/tmp/ca-xyz.crt", internal.RepositoryAuth{}, defaultProxyWithCACertRef.Spec).Return(&helmRelease.Release{
 					Name:    "test-release",
 					Version: 1,
 					Info: &helmRelease.Info{
 						Status: helmRelease.StatusDeployed,
 					},
 				}, nil).Times(1)
+				c.LabelReleaseResources(ctx, restConfig, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+				c.GetChartSBOMs(ctx, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).Times(1)
+				c.ResolveChartDigest(ctx, gomock.Any(), gomock.Any(), gomock.Any()).Return("", nil).Times(1)
 			},
 			expect: func(g *WithT, hrp *addonsv1alpha1.HelmReleaseProxy) {
 				_, ok := hrp.Annotations[addonsv1alpha1.IsReleaseNameGeneratedAnnotation]
@@ -588,7 +725,7 @@ func TestReconcileNormalWithACertificateRef(t *testing.T) {
 					Build(),
 			}
 
-			err := r.reconcileNormal(ctx, tc.helmReleaseProxy, clientMock, "", "/tmp/ca-xyz.crt", restConfig)
+			err := r.reconcileNormal(ctx, tc.helmReleaseProxy, clientMock, "", "/tmp/ca-xyz.crt", internal.RepositoryAuth{}, restConfig, false)
 			if tc.expectedError != "" {
 				g.Expect(err).To(HaveOccurred())
 				g.Expect(err).To(MatchError(tc.expectedError), err.Error())
@@ -662,6 +799,34 @@ func TestReconcileDelete(t *testing.T) {
 			},
 			expectedError: errInternal.Error(),
 		},
+		{
+			name: "uninstall when strategy is InstallOnce and deletion policy is Uninstall",
+			helmReleaseProxy: func() *addonsv1alpha1.HelmReleaseProxy {
+				hrp := installOnceProxyAlreadyInstalled.DeepCopy()
+				hrp.Spec.DeletionPolicy = string(addonsv1alpha1.DeletionPolicyUninstall)
+
+				return hrp
+			}(),
+			clientExpect: func(g *WithT, c *mocks.MockClientMockRecorder) {
+				spec := installOnceProxyAlreadyInstalled.DeepCopy().Spec
+				spec.DeletionPolicy = string(addonsv1alpha1.DeletionPolicyUninstall)
+				c.GetHelmRelease(ctx, restConfig, spec).Return(&helmRelease.Release{
+					Name:    "test-release",
+					Version: 1,
+					Info: &helmRelease.Info{
+						Status: helmRelease.StatusDeployed,
+					},
+				}, nil).Times(1)
+				c.UninstallHelmRelease(ctx, restConfig, spec).Return(&helmRelease.UninstallReleaseResponse{}, nil).Times(1)
+			},
+			expect: func(g *WithT, hrp *addonsv1alpha1.HelmReleaseProxy) {
+				g.Expect(conditions.Has(hrp, addonsv1alpha1.HelmReleaseReadyCondition)).To(BeTrue())
+				releaseReady := conditions.Get(hrp, addonsv1alpha1.HelmReleaseReadyCondition)
+				g.Expect(releaseReady.Status).To(Equal(corev1.ConditionFalse))
+				g.Expect(releaseReady.Reason).To(Equal(addonsv1alpha1.HelmReleaseDeletedReason))
+			},
+			expectedError: "",
+		},
 		{
 			name:             "do nothing when strategy is InstallOnce",
 			helmReleaseProxy: installOnceProxyAlreadyInstalled.DeepCopy(),
@@ -706,6 +871,165 @@ func TestReconcileDelete(t *testing.T) {
 	}
 }
 
+func TestReconcileClusterDeleting(t *testing.T) {
+	t.Parallel()
+
+	deletingCluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "test-cluster",
+			Namespace:         "default",
+			Finalizers:        []string{clusterv1.ClusterFinalizer},
+			DeletionTimestamp: ptr.To(metav1.Now()),
+		},
+	}
+
+	testcases := []struct {
+		name             string
+		helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy
+		cluster          *clusterv1.Cluster
+		expectDeleted    bool
+	}{
+		{
+			name:             "Helm release is not reconciled on a Cluster being deleted",
+			helmReleaseProxy: defaultProxy.DeepCopy(),
+			cluster:          deletingCluster,
+		},
+		{
+			name:             "Helm release is not reconciled on a deleted Cluster",
+			helmReleaseProxy: defaultProxy.DeepCopy(),
+		},
+		{
+			name: "Helm release is not uninstalled from a Cluster being deleted",
+			helmReleaseProxy: func() *addonsv1alpha1.HelmReleaseProxy {
+				hrp := defaultProxy.DeepCopy()
+				hrp.Finalizers = []string{addonsv1alpha1.HelmReleaseProxyFinalizer}
+				hrp.DeletionTimestamp = ptr.To(metav1.Now())
+
+				return hrp
+			}(),
+			cluster:       deletingCluster,
+			expectDeleted: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			t.Parallel()
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			objects := []client.Object{tc.helmReleaseProxy}
+			if tc.cluster != nil {
+				objects = append(objects, tc.cluster.DeepCopy())
+			}
+			c := fake.NewClientBuilder().
+				WithScheme(fakeScheme).
+				WithObjects(objects...).
+				WithStatusSubresource(&addonsv1alpha1.HelmReleaseProxy{}).
+				Build()
+			r := &HelmReleaseProxyReconciler{
+				Client: c,
+				// No Helm operation is expected.
+				HelmClient: mocks.NewMockClient(mockCtrl),
+			}
+
+			// The deferred patch of a HelmReleaseProxy whose finalizer was removed fails as it is gone, so the error is only
+			// checked if it is kept.
+			_, reconcileErr := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(tc.helmReleaseProxy)})
+
+			hrp := &addonsv1alpha1.HelmReleaseProxy{}
+			err := c.Get(ctx, client.ObjectKeyFromObject(tc.helmReleaseProxy), hrp)
+			if tc.expectDeleted {
+				g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+				return
+			}
+			g.Expect(reconcileErr).NotTo(HaveOccurred())
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(conditions.GetReason(hrp, addonsv1alpha1.ClusterAvailableCondition)).To(Equal(addonsv1alpha1.ClusterDeletingReason))
+		})
+	}
+}
+
+func TestIsProtectedFromUninstall(t *testing.T) {
+	t.Parallel()
+
+	protectedProxy := defaultProxy.DeepCopy()
+	protectedProxy.Labels = map[string]string{addonsv1alpha1.HelmChartProxyLabelName: "test-hcp"}
+	protectedProxy.Spec.Options.Protect = true
+
+	allowedProxy := protectedProxy.DeepCopy()
+	allowedProxy.Annotations = map[string]string{addonsv1alpha1.AllowUninstallAnnotation: "true"}
+
+	orphanedProxy := protectedProxy.DeepCopy()
+	orphanedProxy.Spec.ReconcileStrategy = string(addonsv1alpha1.ReconcileStrategyInstallOnce)
+
+	helmChartProxy := &addonsv1alpha1.HelmChartProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-hcp",
+			Namespace: "default",
+		},
+	}
+	allowingHelmChartProxy := helmChartProxy.DeepCopy()
+	allowingHelmChartProxy.Annotations = map[string]string{addonsv1alpha1.AllowUninstallAnnotation: "true"}
+
+	testcases := []struct {
+		name             string
+		helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy
+		objects          []client.Object
+		expectProtected  bool
+	}{
+		{
+			name:             "release is not protected",
+			helmReleaseProxy: defaultProxy.DeepCopy(),
+		},
+		{
+			name:             "protected release",
+			helmReleaseProxy: protectedProxy.DeepCopy(),
+			objects:          []client.Object{helmChartProxy.DeepCopy()},
+			expectProtected:  true,
+		},
+		{
+			name:             "protected release without HelmChartProxy",
+			helmReleaseProxy: protectedProxy.DeepCopy(),
+			expectProtected:  true,
+		},
+		{
+			name:             "HelmReleaseProxy allows uninstall",
+			helmReleaseProxy: allowedProxy.DeepCopy(),
+			objects:          []client.Object{helmChartProxy.DeepCopy()},
+		},
+		{
+			name:             "HelmChartProxy allows uninstall",
+			helmReleaseProxy: protectedProxy.DeepCopy(),
+			objects:          []client.Object{allowingHelmChartProxy.DeepCopy()},
+		},
+		{
+			name:             "orphaned release is not uninstalled",
+			helmReleaseProxy: orphanedProxy.DeepCopy(),
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			t.Parallel()
+
+			r := &HelmReleaseProxyReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(fakeScheme).
+					WithObjects(tc.objects...).
+					Build(),
+			}
+
+			protected, err := r.isProtectedFromUninstall(ctx, tc.helmReleaseProxy)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(protected).To(Equal(tc.expectProtected))
+		})
+	}
+}
+
 func TestTLSSettings(t *testing.T) {
 	t.Parallel()
 
@@ -720,13 +1044,16 @@ func TestTLSSettings(t *testing.T) {
 			name:             "test",
 			helmReleaseProxy: defaultProxyWithSkipTLS.DeepCopy(),
 			clientExpect: func(g *WithT, c *mocks.MockClientMockRecorder) {
-				c.InstallOrUpgradeHelmRelease(ctx, restConfig, "", "", defaultProxyWithSkipTLS.Spec).Return(&helmRelease.Release{
+				c.InstallOrUpgradeHelmRelease(ctx, restConfig, "", "", internal.RepositoryAuth{}, defaultProxyWithSkipTLS.Spec).Return(&helmRelease.Release{
 					Name:    "test-release",
 					Version: 1,
 					Info: &helmRelease.Info{
 						Status: helmRelease.StatusDeployed,
 					},
 				}, nil).Times(1)
+				c.LabelReleaseResources(ctx, restConfig, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+				c.GetChartSBOMs(ctx, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).Times(1)
+				c.ResolveChartDigest(ctx, gomock.Any(), gomock.Any(), gomock.Any()).Return("", nil).Times(1)
 			},
 			expect: func(g *WithT, hrp *addonsv1alpha1.HelmReleaseProxy) {
 				_, ok := hrp.Annotations[addonsv1alpha1.IsReleaseNameGeneratedAnnotation]
@@ -759,7 +1086,7 @@ func TestTLSSettings(t *testing.T) {
 			}
 			caFilePath, err := r.getCAFile(ctx, tc.helmReleaseProxy)
 			g.Expect(err).ToNot(HaveOccurred(), "did not expect error to get CA file")
-			err = r.reconcileNormal(ctx, tc.helmReleaseProxy, clientMock, "", caFilePath, restConfig)
+			err = r.reconcileNormal(ctx, tc.helmReleaseProxy, clientMock, "", caFilePath, internal.RepositoryAuth{}, restConfig, false)
 			if tc.expectedError != "" {
 				g.Expect(err).To(HaveOccurred())
 				g.Expect(err).To(MatchError(tc.expectedError), err.Error())
@@ -771,8 +1098,1341 @@ func TestTLSSettings(t *testing.T) {
 	}
 }
 
-func init() {
-	_ = scheme.AddToScheme(fakeScheme)
-	_ = clusterv1.AddToScheme(fakeScheme)
-	_ = addonsv1alpha1.AddToScheme(fakeScheme)
+func TestReconcileSBOMConfigMap(t *testing.T) {
+	t.Parallel()
+
+	sboms := []addonsv1alpha1.SBOMReference{
+		{MediaType: "application/spdx+json", Digest: "sha256:1111", Location: "registry.example.com/charts/test-chart@sha256:1111"},
+		{MediaType: "application/vnd.cyclonedx+json", Digest: "sha256:2222", Location: "registry.example.com/charts/test-chart@sha256:2222"},
+	}
+
+	copyProxy := defaultProxy.DeepCopy()
+	copyProxy.Spec.CopySBOMs = true
+	copyProxy.Status.SBOMs = sboms
+
+	disabledProxy := defaultProxy.DeepCopy()
+	disabledProxy.Status.SBOMs = sboms
+	disabledProxy.Status.SBOMConfigMapName = "test-proxy-sboms"
+
+	existingConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-proxy-sboms",
+			Namespace: "default",
+		},
+		Data: map[string]string{
+			"sha256-1111": "spdx",
+		},
+	}
+
+	testcases := []struct {
+		name             string
+		helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy
+		objects          []client.Object
+		clientExpect     func(g *WithT, c *mocks.MockClientMockRecorder)
+		expect           func(g *WithT, c client.Client, hrp *addonsv1alpha1.HelmReleaseProxy)
+	}{
+		{
+			name:             "copies SBOMs to a new ConfigMap",
+			helmReleaseProxy: copyProxy.DeepCopy(),
+			clientExpect: func(g *WithT, c *mocks.MockClientMockRecorder) {
+				c.GetChartSBOM(ctx, gomock.Any(), "", "", sboms[0], int64(maxSBOMConfigMapSize)).Return([]byte("spdx"), nil).Times(1)
+				c.GetChartSBOM(ctx, gomock.Any(), "", "", sboms[1], int64(maxSBOMConfigMapSize-len("spdx"))).Return([]byte("cyclonedx"), nil).Times(1)
+			},
+			expect: func(g *WithT, c client.Client, hrp *addonsv1alpha1.HelmReleaseProxy) {
+				g.Expect(hrp.Status.SBOMConfigMapName).To(Equal("test-proxy-sboms"))
+
+				configMap := &corev1.ConfigMap{}
+				g.Expect(c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "test-proxy-sboms"}, configMap)).To(Succeed())
+				g.Expect(configMap.Data).To(Equal(map[string]string{
+					"sha256-1111": "spdx",
+					"sha256-2222": "cyclonedx",
+				}))
+				g.Expect(configMap.Labels).To(HaveKeyWithValue(clusterv1.ClusterNameLabel, "test-cluster"))
+				g.Expect(configMap.OwnerReferences).To(HaveLen(1))
+				g.Expect(configMap.OwnerReferences[0].Name).To(Equal("test-proxy"))
+			},
+		},
+		{
+			name:             "does not fetch SBOMs already in the ConfigMap",
+			helmReleaseProxy: copyProxy.DeepCopy(),
+			objects:          []client.Object{existingConfigMap.DeepCopy()},
+			clientExpect: func(g *WithT, c *mocks.MockClientMockRecorder) {
+				c.GetChartSBOM(ctx, gomock.Any(), "", "", sboms[1], int64(maxSBOMConfigMapSize-len("spdx"))).Return([]byte("cyclonedx"), nil).Times(1)
+			},
+			expect: func(g *WithT, c client.Client, hrp *addonsv1alpha1.HelmReleaseProxy) {
+				configMap := &corev1.ConfigMap{}
+				g.Expect(c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "test-proxy-sboms"}, configMap)).To(Succeed())
+				g.Expect(configMap.Data).To(HaveLen(2))
+			},
+		},
+		{
+			name:             "deletes the ConfigMap if SBOMs are no longer copied",
+			helmReleaseProxy: disabledProxy.DeepCopy(),
+			objects:          []client.Object{existingConfigMap.DeepCopy()},
+			clientExpect:     func(g *WithT, c *mocks.MockClientMockRecorder) {},
+			expect: func(g *WithT, c client.Client, hrp *addonsv1alpha1.HelmReleaseProxy) {
+				g.Expect(hrp.Status.SBOMConfigMapName).To(BeEmpty())
+
+				err := c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "test-proxy-sboms"}, &corev1.ConfigMap{})
+				g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			t.Parallel()
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			clientMock := mocks.NewMockClient(mockCtrl)
+			tc.clientExpect(g, clientMock.EXPECT())
+
+			r := &HelmReleaseProxyReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(fakeScheme).
+					WithObjects(tc.objects...).
+					Build(),
+			}
+
+			g.Expect(r.reconcileSBOMConfigMap(ctx, tc.helmReleaseProxy, tc.helmReleaseProxy.Spec, clientMock, "", "")).To(Succeed())
+			tc.expect(g, r.Client, tc.helmReleaseProxy)
+		})
+	}
+}
+
+func TestReconcileValuesConfigMap(t *testing.T) {
+	t.Parallel()
+
+	showProxy := defaultProxy.DeepCopy()
+	showProxy.Annotations = map[string]string{addonsv1alpha1.ShowValuesAnnotation: "true"}
+
+	hiddenProxy := defaultProxy.DeepCopy()
+	hiddenProxy.Status.ValuesConfigMapName = "test-proxy-values"
+
+	existingConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-proxy-values",
+			Namespace: "default",
+		},
+	}
+
+	testcases := []struct {
+		name             string
+		helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy
+		objects          []client.Object
+		clientExpect     func(g *WithT, c *mocks.MockClientMockRecorder)
+		expect           func(g *WithT, c client.Client, hrp *addonsv1alpha1.HelmReleaseProxy)
+	}{
+		{
+			name:             "copies redacted values to a ConfigMap",
+			helmReleaseProxy: showProxy.DeepCopy(),
+			clientExpect: func(g *WithT, c *mocks.MockClientMockRecorder) {
+				c.GetHelmRelease(ctx, gomock.Any(), gomock.Any()).Return(&helmRelease.Release{
+					Name:    "test-release",
+					Version: 3,
+					Config: map[string]interface{}{
+						"replicaCount": 2,
+						"auth": map[string]interface{}{
+							"adminPassword": "hunter2",
+							"users": []interface{}{
+								map[string]interface{}{"name": "admin", "apiKey": "abc123"},
+							},
+						},
+						"existingSecret": nil,
+					},
+				}, nil).Times(1)
+			},
+			expect: func(g *WithT, c client.Client, hrp *addonsv1alpha1.HelmReleaseProxy) {
+				g.Expect(hrp.Status.ValuesConfigMapName).To(Equal("test-proxy-values"))
+
+				configMap := &corev1.ConfigMap{}
+				g.Expect(c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "test-proxy-values"}, configMap)).To(Succeed())
+				g.Expect(configMap.Data).To(Equal(map[string]string{
+					"revision": "3",
+					"values.yaml": `auth:
+  adminPassword: <redacted>
+  users:
+  - apiKey: <redacted>
+    name: admin
+existingSecret: null
+replicaCount: 2
+`,
+				}))
+				g.Expect(configMap.Labels).To(HaveKeyWithValue(clusterv1.ClusterNameLabel, "test-cluster"))
+				g.Expect(configMap.OwnerReferences).To(HaveLen(1))
+				g.Expect(configMap.OwnerReferences[0].Name).To(Equal("test-proxy"))
+			},
+		},
+		{
+			name:             "deletes the ConfigMap once the annotation is removed",
+			helmReleaseProxy: hiddenProxy.DeepCopy(),
+			objects:          []client.Object{existingConfigMap.DeepCopy()},
+			clientExpect:     func(g *WithT, c *mocks.MockClientMockRecorder) {},
+			expect: func(g *WithT, c client.Client, hrp *addonsv1alpha1.HelmReleaseProxy) {
+				g.Expect(hrp.Status.ValuesConfigMapName).To(BeEmpty())
+
+				err := c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "test-proxy-values"}, &corev1.ConfigMap{})
+				g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			t.Parallel()
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			clientMock := mocks.NewMockClient(mockCtrl)
+			tc.clientExpect(g, clientMock.EXPECT())
+
+			r := &HelmReleaseProxyReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(fakeScheme).
+					WithObjects(tc.objects...).
+					Build(),
+			}
+
+			g.Expect(r.reconcileValuesConfigMap(ctx, tc.helmReleaseProxy, clientMock, nil)).To(Succeed())
+			tc.expect(g, r.Client, tc.helmReleaseProxy)
+		})
+	}
+}
+
+func TestReconcileValuesOverridesConfigMap(t *testing.T) {
+	t.Parallel()
+
+	recordProxy := defaultProxy.DeepCopy()
+	recordProxy.Spec.RecordValuesOverrides = true
+
+	unrecordedProxy := defaultProxy.DeepCopy()
+	unrecordedProxy.Status.ValuesOverridesConfigMapName = "test-proxy-values-overrides"
+
+	existingConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-proxy-values-overrides",
+			Namespace: "default",
+		},
+	}
+
+	release := &helmRelease.Release{
+		Name:    "test-release",
+		Version: 2,
+		Chart: &chart.Chart{
+			Values: map[string]interface{}{
+				"replicaCount": 1,
+				"image":        map[string]interface{}{"repository": "nginx", "tag": "1.25"},
+				"auth":         map[string]interface{}{"password": ""},
+				"args":         []interface{}{"--verbose"},
+			},
+		},
+		Config: map[string]interface{}{
+			"replicaCount": 1,
+			"image":        map[string]interface{}{"tag": "1.27"},
+			"auth":         map[string]interface{}{"password": "hunter2"},
+			"args":         []interface{}{"--quiet"},
+			"extraEnv":     map[string]interface{}{"LOG_LEVEL": "debug"},
+		},
+	}
+
+	testcases := []struct {
+		name             string
+		helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy
+		objects          []client.Object
+		expect           func(g *WithT, c client.Client, hrp *addonsv1alpha1.HelmReleaseProxy)
+	}{
+		{
+			name:             "records the overridden chart defaults in a ConfigMap",
+			helmReleaseProxy: recordProxy.DeepCopy(),
+			expect: func(g *WithT, c client.Client, hrp *addonsv1alpha1.HelmReleaseProxy) {
+				g.Expect(hrp.Status.ValuesOverridesConfigMapName).To(Equal("test-proxy-values-overrides"))
+
+				configMap := &corev1.ConfigMap{}
+				g.Expect(c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "test-proxy-values-overrides"}, configMap)).To(Succeed())
+				g.Expect(configMap.Data).To(Equal(map[string]string{
+					"revision": "2",
+					"overrides.yaml": `- default:
+  - --verbose
+  path: args
+  value:
+  - --quiet
+- default: null
+  path: extraEnv
+  value:
+    LOG_LEVEL: debug
+- default: "1.25"
+  path: image.tag
+  value: "1.27"
+`,
+				}))
+				g.Expect(configMap.OwnerReferences).To(HaveLen(1))
+				g.Expect(configMap.OwnerReferences[0].Name).To(Equal("test-proxy"))
+			},
+		},
+		{
+			name:             "deletes the ConfigMap once overrides are no longer recorded",
+			helmReleaseProxy: unrecordedProxy.DeepCopy(),
+			objects:          []client.Object{existingConfigMap.DeepCopy()},
+			expect: func(g *WithT, c client.Client, hrp *addonsv1alpha1.HelmReleaseProxy) {
+				g.Expect(hrp.Status.ValuesOverridesConfigMapName).To(BeEmpty())
+
+				err := c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "test-proxy-values-overrides"}, &corev1.ConfigMap{})
+				g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			t.Parallel()
+
+			r := &HelmReleaseProxyReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(fakeScheme).
+					WithObjects(tc.objects...).
+					Build(),
+			}
+
+			g.Expect(r.reconcileValuesOverridesConfigMap(ctx, tc.helmReleaseProxy, release)).To(Succeed())
+			tc.expect(g, r.Client, tc.helmReleaseProxy)
+		})
+	}
+}
+
+func TestReconcileRollback(t *testing.T) {
+	t.Parallel()
+
+	rollbackProxy := defaultProxy.DeepCopy()
+	rollbackProxy.Spec.RollbackTo = &addonsv1alpha1.RollbackTo{Revision: 2}
+
+	testcases := []struct {
+		name          string
+		clientExpect  func(g *WithT, c *mocks.MockClientMockRecorder)
+		expect        func(g *WithT, hrp *addonsv1alpha1.HelmReleaseProxy)
+		expectedError string
+	}{
+		{
+			name: "rolls back the release and clears rollbackTo",
+			clientExpect: func(g *WithT, c *mocks.MockClientMockRecorder) {
+				c.RollbackHelmRelease(ctx, restConfig, defaultProxy.Spec, 2).Return(&helmRelease.Release{
+					Name:    "test-release",
+					Version: 4,
+					Info: &helmRelease.Info{
+						Status: helmRelease.StatusDeployed,
+					},
+				}, nil).Times(1)
+			},
+			expect: func(g *WithT, hrp *addonsv1alpha1.HelmReleaseProxy) {
+				g.Expect(hrp.Spec.RollbackTo).To(BeNil())
+				g.Expect(hrp.Status.Revision).To(Equal(4))
+				g.Expect(hrp.Status.Status).To(BeEquivalentTo(helmRelease.StatusDeployed))
+				g.Expect(hrp.Status.Rollback).NotTo(BeNil())
+				g.Expect(hrp.Status.Rollback.Revision).To(Equal(2))
+				g.Expect(hrp.Status.Rollback.SpecHash).To(Equal(rollbackSpecHash(defaultProxy.Spec)))
+				g.Expect(conditions.GetReason(hrp, addonsv1alpha1.HelmReleaseReadyCondition)).To(Equal(addonsv1alpha1.HelmReleaseRolledBackReason))
+
+				// Upgrades are held until the chart or values change.
+				g.Expect(isHeldForRollback(hrp)).To(BeTrue())
+				hrp.Spec.Version = "new-version"
+				g.Expect(isHeldForRollback(hrp)).To(BeFalse())
+				g.Expect(hrp.Status.Rollback).To(BeNil())
+			},
+		},
+		{
+			name: "clears rollbackTo when the rollback fails",
+			clientExpect: func(g *WithT, c *mocks.MockClientMockRecorder) {
+				c.RollbackHelmRelease(ctx, restConfig, defaultProxy.Spec, 2).Return(nil, fmt.Errorf("release: not found")).Times(1)
+			},
+			expect: func(g *WithT, hrp *addonsv1alpha1.HelmReleaseProxy) {
+				g.Expect(hrp.Spec.RollbackTo).To(BeNil())
+				g.Expect(hrp.Status.Rollback).To(BeNil())
+				g.Expect(conditions.GetReason(hrp, addonsv1alpha1.HelmReleaseReadyCondition)).To(Equal(addonsv1alpha1.HelmReleaseRollbackFailedReason))
+			},
+			expectedError: "failed to roll back release test-release to revision 2 on cluster test-cluster: release: not found",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			t.Parallel()
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			clientMock := mocks.NewMockClient(mockCtrl)
+			tc.clientExpect(g, clientMock.EXPECT())
+
+			r := &HelmReleaseProxyReconciler{}
+
+			hrp := rollbackProxy.DeepCopy()
+			err := r.reconcileRollback(ctx, hrp, clientMock, restConfig)
+			if tc.expectedError != "" {
+				g.Expect(err).To(MatchError(tc.expectedError))
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+			tc.expect(g, hrp)
+		})
+	}
+}
+
+func TestReconcileNormalUpgradeRolledBack(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	helmReleaseProxy := defaultProxy.DeepCopy()
+	helmReleaseProxy.Spec.Options.Atomic = true
+	rolledBackErr := &internal.UpgradeRolledBackError{
+		Release:        &helmRelease.Release{Name: "test-release", Version: 4, Info: &helmRelease.Info{Status: helmRelease.StatusDeployed}},
+		FailedRevision: 3,
+		Err:            errInternal,
+	}
+
+	clientMock := mocks.NewMockClient(mockCtrl)
+	clientMock.EXPECT().InstallOrUpgradeHelmRelease(ctx, restConfig, "", "", internal.RepositoryAuth{}, helmReleaseProxy.Spec).Return(nil, rolledBackErr).Times(1)
+
+	recorder := record.NewFakeRecorder(10)
+	r := &HelmReleaseProxyReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(fakeScheme).Build(),
+		Recorder: recorder,
+	}
+
+	err := r.reconcileNormal(ctx, helmReleaseProxy, clientMock, "", "", internal.RepositoryAuth{}, restConfig, false)
+	g.Expect(err).To(MatchError(rolledBackErr))
+
+	// The release is reported at the revision it was rolled back to, but is not ready as the upgrade did not succeed.
+	g.Expect(helmReleaseProxy.Status.Revision).To(Equal(4))
+	g.Expect(helmReleaseProxy.Status.Status).To(BeEquivalentTo(helmRelease.StatusDeployed))
+	g.Expect(helmReleaseProxy.Status.UpgradeRollback).NotTo(BeNil())
+	g.Expect(helmReleaseProxy.Status.UpgradeRollback.FailedRevision).To(Equal(3))
+	g.Expect(helmReleaseProxy.Status.UpgradeRollback.Revision).To(Equal(4))
+	g.Expect(helmReleaseProxy.Status.UpgradeRollback.Message).To(Equal(errInternal.Error()))
+	g.Expect(conditions.IsTrue(helmReleaseProxy, addonsv1alpha1.UpgradeRolledBackCondition)).To(BeTrue())
+	g.Expect(conditions.GetReason(helmReleaseProxy, addonsv1alpha1.HelmReleaseReadyCondition)).To(Equal(addonsv1alpha1.UpgradeRolledBackReason))
+	g.Expect(conditions.IsFalse(helmReleaseProxy, addonsv1alpha1.HelmReleaseReadyCondition)).To(BeTrue())
+	g.Expect(recorder.Events).To(Receive(ContainSubstring("rolled back to revision 4")))
+
+	// The condition is removed once an upgrade succeeds.
+	clientMock.EXPECT().InstallOrUpgradeHelmRelease(ctx, restConfig, "", "", internal.RepositoryAuth{}, helmReleaseProxy.Spec).Return(&helmRelease.Release{
+		Name:    "test-release",
+		Version: 5,
+		Info:    &helmRelease.Info{Status: helmRelease.StatusDeployed},
+	}, nil).Times(1)
+	clientMock.EXPECT().LabelReleaseResources(ctx, restConfig, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	clientMock.EXPECT().GetChartSBOMs(ctx, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).Times(1)
+	clientMock.EXPECT().ResolveChartDigest(ctx, gomock.Any(), gomock.Any(), gomock.Any()).Return("", nil).Times(1)
+
+	g.Expect(r.reconcileNormal(ctx, helmReleaseProxy, clientMock, "", "", internal.RepositoryAuth{}, restConfig, false)).To(Succeed())
+	g.Expect(conditions.Has(helmReleaseProxy, addonsv1alpha1.UpgradeRolledBackCondition)).To(BeFalse())
+	g.Expect(helmReleaseProxy.Status.UpgradeRollback).NotTo(BeNil())
+}
+
+func TestReconcileNormalChartVerificationFailed(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	helmReleaseProxy := defaultProxy.DeepCopy()
+	verificationErr := &internal.ChartVerificationError{Chart: helmReleaseProxy.Spec.ChartName, Err: errInternal}
+
+	clientMock := mocks.NewMockClient(mockCtrl)
+	clientMock.EXPECT().InstallOrUpgradeHelmRelease(ctx, restConfig, "", "", internal.RepositoryAuth{}, helmReleaseProxy.Spec).Return(nil, verificationErr).Times(1)
+
+	recorder := record.NewFakeRecorder(10)
+	r := &HelmReleaseProxyReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(fakeScheme).Build(),
+		Recorder: recorder,
+	}
+
+	err := r.reconcileNormal(ctx, helmReleaseProxy, clientMock, "", "", internal.RepositoryAuth{}, restConfig, false)
+	g.Expect(err).To(MatchError(verificationErr))
+	g.Expect(conditions.IsTrue(helmReleaseProxy, addonsv1alpha1.ChartVerificationFailedCondition)).To(BeTrue())
+	g.Expect(conditions.GetMessage(helmReleaseProxy, addonsv1alpha1.ChartVerificationFailedCondition)).To(Equal(verificationErr.Error()))
+	g.Expect(conditions.GetReason(helmReleaseProxy, addonsv1alpha1.HelmReleaseReadyCondition)).To(Equal(addonsv1alpha1.ChartVerificationFailedReason))
+	g.Expect(recorder.Events).To(Receive(ContainSubstring("was not installed")))
+
+	// The condition is removed once an install succeeds.
+	clientMock.EXPECT().InstallOrUpgradeHelmRelease(ctx, restConfig, "", "", internal.RepositoryAuth{}, helmReleaseProxy.Spec).Return(&helmRelease.Release{
+		Name:    "test-release",
+		Version: 1,
+		Info:    &helmRelease.Info{Status: helmRelease.StatusDeployed},
+	}, nil).Times(1)
+	clientMock.EXPECT().LabelReleaseResources(ctx, restConfig, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	clientMock.EXPECT().GetChartSBOMs(ctx, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).Times(1)
+	clientMock.EXPECT().ResolveChartDigest(ctx, gomock.Any(), gomock.Any(), gomock.Any()).Return("", nil).Times(1)
+
+	g.Expect(r.reconcileNormal(ctx, helmReleaseProxy, clientMock, "", "", internal.RepositoryAuth{}, restConfig, false)).To(Succeed())
+	g.Expect(conditions.Has(helmReleaseProxy, addonsv1alpha1.ChartVerificationFailedCondition)).To(BeFalse())
+}
+
+func TestReconcileNormalChartLockMismatch(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	helmReleaseProxy := defaultProxy.DeepCopy()
+	chartLockErr := &internal.ChartLockMismatchError{Chart: helmReleaseProxy.Spec.ChartName, Mismatches: []string{"locked dependency common of test-chart is not vendored"}}
+
+	clientMock := mocks.NewMockClient(mockCtrl)
+	clientMock.EXPECT().InstallOrUpgradeHelmRelease(ctx, restConfig, "", "", internal.RepositoryAuth{}, helmReleaseProxy.Spec).Return(nil, chartLockErr).Times(1)
+
+	recorder := record.NewFakeRecorder(10)
+	r := &HelmReleaseProxyReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(fakeScheme).Build(),
+		Recorder: recorder,
+	}
+
+	err := r.reconcileNormal(ctx, helmReleaseProxy, clientMock, "", "", internal.RepositoryAuth{}, restConfig, false)
+	g.Expect(err).To(MatchError(chartLockErr))
+	g.Expect(conditions.IsTrue(helmReleaseProxy, addonsv1alpha1.ChartVerificationFailedCondition)).To(BeTrue())
+	g.Expect(conditions.GetReason(helmReleaseProxy, addonsv1alpha1.ChartVerificationFailedCondition)).To(Equal(addonsv1alpha1.ChartLockMismatchReason))
+	g.Expect(conditions.GetReason(helmReleaseProxy, addonsv1alpha1.HelmReleaseReadyCondition)).To(Equal(addonsv1alpha1.ChartLockMismatchReason))
+	g.Expect(recorder.Events).To(Receive(ContainSubstring("do not match its Chart.lock")))
+}
+
+func TestGetChartVerification(t *testing.T) {
+	g := NewWithT(t)
+
+	helmReleaseProxy := defaultProxy.DeepCopy()
+	helmReleaseProxy.Spec.Verify = &addonsv1alpha1.ChartVerification{
+		Provenance: &addonsv1alpha1.ProvenanceVerification{KeyringSecretRef: corev1.SecretReference{Name: "keyring"}},
+		Cosign: &addonsv1alpha1.CosignVerification{
+			Keyless: &addonsv1alpha1.CosignKeylessVerification{
+				Issuer:               "https://token.actions.githubusercontent.com",
+				Subject:              "https://github.com/org/repo/.github/workflows/release.yaml@refs/heads/main",
+				TrustedRootSecretRef: corev1.SecretReference{Name: "sigstore"},
+			},
+		},
+	}
+	newSecret := func(name string, data map[string][]byte) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: helmReleaseProxy.Namespace}, Data: data}
+	}
+
+	r := &HelmReleaseProxyReconciler{
+		Client: fake.NewClientBuilder().WithScheme(fakeScheme).WithObjects(
+			newSecret("keyring", map[string][]byte{addonsv1alpha1.ProvenanceKeyringKey: []byte("keyring")}),
+			newSecret("sigstore", map[string][]byte{addonsv1alpha1.FulcioCertificatesKey: []byte("fulcio")}),
+		).Build(),
+	}
+
+	// A missing key fails without leaving the keyring file behind.
+	_, err := r.getChartVerification(ctx, helmReleaseProxy)
+	g.Expect(err).To(MatchError(ContainSubstring("does not contain the key rekor.pub")))
+
+	g.Expect(r.Update(ctx, newSecret("sigstore", map[string][]byte{
+		addonsv1alpha1.FulcioCertificatesKey: []byte("fulcio"),
+		addonsv1alpha1.RekorPublicKeyKey:     []byte("rekor"),
+	}))).To(Succeed())
+	verification, err := r.getChartVerification(ctx, helmReleaseProxy)
+	g.Expect(err).NotTo(HaveOccurred())
+	defer os.Remove(verification.KeyringFile)
+
+	g.Expect(verification.CosignKeyless).To(Equal(&internal.CosignKeylessVerification{
+		Issuer:             "https://token.actions.githubusercontent.com",
+		Subject:            "https://github.com/org/repo/.github/workflows/release.yaml@refs/heads/main",
+		FulcioCertificates: []byte("fulcio"),
+		RekorPublicKey:     []byte("rekor"),
+	}))
+	keyring, err := os.ReadFile(verification.KeyringFile)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(keyring)).To(Equal("keyring"))
+
+	helmReleaseProxy.Spec.Verify = nil
+	g.Expect(r.getChartVerification(ctx, helmReleaseProxy)).To(BeNil())
+}
+
+func TestReconcileReleaseTest(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	now := time.Now()
+	release := &helmRelease.Release{Name: "test-release", Version: 2, Info: &helmRelease.Info{Status: helmRelease.StatusDeployed}}
+	helmReleaseProxy := defaultProxy.DeepCopy()
+	helmReleaseProxy.Spec.Options.Test = &addonsv1alpha1.HelmTestOptions{Enable: true}
+
+	clientMock := mocks.NewMockClient(mockCtrl)
+	recorder := record.NewFakeRecorder(10)
+	r := &HelmReleaseProxyReconciler{Recorder: recorder}
+
+	// Tests are run once per revision of the release.
+	clientMock.EXPECT().TestHelmRelease(ctx, restConfig, helmReleaseProxy.Spec).Return(&helmRelease.Release{
+		Hooks: []*helmRelease.Hook{{
+			Name:    "test-connection",
+			Events:  []helmRelease.HookEvent{helmRelease.HookTest},
+			LastRun: helmRelease.HookExecution{StartedAt: helmTime.Time{Time: now}, Phase: helmRelease.HookPhaseSucceeded},
+		}},
+	}, nil).Times(1)
+	r.reconcileReleaseTest(ctx, helmReleaseProxy, clientMock, restConfig, helmReleaseProxy.Spec, release, now)
+	r.reconcileReleaseTest(ctx, helmReleaseProxy, clientMock, restConfig, helmReleaseProxy.Spec, release, now)
+	g.Expect(conditions.IsTrue(helmReleaseProxy, addonsv1alpha1.ReleaseTestedCondition)).To(BeTrue())
+	g.Expect(helmReleaseProxy.Status.Test.Revision).To(Equal(2))
+	g.Expect(helmReleaseProxy.Status.Test.Results).To(HaveLen(1))
+	g.Expect(helmReleaseProxy.Status.Test.Results[0].Phase).To(Equal("Succeeded"))
+
+	// Failed tests are reported without failing the reconcile.
+	release.Version = 3
+	clientMock.EXPECT().TestHelmRelease(ctx, restConfig, helmReleaseProxy.Spec).Return(nil, errInternal).Times(1)
+	r.reconcileReleaseTest(ctx, helmReleaseProxy, clientMock, restConfig, helmReleaseProxy.Spec, release, now)
+	g.Expect(conditions.IsFalse(helmReleaseProxy, addonsv1alpha1.ReleaseTestedCondition)).To(BeTrue())
+	g.Expect(conditions.GetReason(helmReleaseProxy, addonsv1alpha1.ReleaseTestedCondition)).To(Equal(addonsv1alpha1.ReleaseTestFailedReason))
+	g.Expect(helmReleaseProxy.Status.Test.Revision).To(Equal(3))
+	g.Expect(helmReleaseProxy.Status.Test.Results).To(BeEmpty())
+	g.Expect(recorder.Events).To(Receive(ContainSubstring("Tests of release test-release revision 3")))
+
+	// Tests are not run with the skip-tests annotation.
+	release.Version = 4
+	helmReleaseProxy.Annotations = map[string]string{addonsv1alpha1.SkipTestsAnnotation: "true"}
+	r.reconcileReleaseTest(ctx, helmReleaseProxy, clientMock, restConfig, helmReleaseProxy.Spec, release, now)
+	g.Expect(helmReleaseProxy.Status.Test.Revision).To(Equal(3))
+
+	// The test status is cleared once tests are disabled.
+	helmReleaseProxy.Spec.Options.Test = nil
+	r.reconcileReleaseTest(ctx, helmReleaseProxy, clientMock, restConfig, helmReleaseProxy.Spec, release, now)
+	g.Expect(conditions.Has(helmReleaseProxy, addonsv1alpha1.ReleaseTestedCondition)).To(BeFalse())
+	g.Expect(helmReleaseProxy.Status.Test).To(BeNil())
+}
+
+func TestReconcileDrift(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	driftProxy := defaultProxy.DeepCopy()
+	driftProxy.Spec.DriftDetection = &addonsv1alpha1.DriftDetection{
+		Enabled:  true,
+		Interval: &metav1.Duration{Duration: 10 * time.Minute},
+	}
+	driftProxy.SetReleaseStatus(helmRelease.StatusDeployed.String())
+	conditions.MarkTrue(driftProxy, addonsv1alpha1.HelmReleaseReadyCondition)
+
+	testcases := []struct {
+		name                 string
+		helmReleaseProxy     func() *addonsv1alpha1.HelmReleaseProxy
+		observeOnly          bool
+		clientExpect         func(g *WithT, c *mocks.MockClientMockRecorder)
+		expect               func(g *WithT, hrp *addonsv1alpha1.HelmReleaseProxy)
+		expectedRequeueAfter time.Duration
+	}{
+		{
+			name: "corrects drift by default",
+			helmReleaseProxy: func() *addonsv1alpha1.HelmReleaseProxy {
+				return driftProxy.DeepCopy()
+			},
+			clientExpect: func(g *WithT, c *mocks.MockClientMockRecorder) {
+				c.ReconcileHelmReleaseDrift(ctx, restConfig, driftProxy.Spec, true).Return([]string{"Deployment default/controller"}, nil).Times(1)
+			},
+			expect: func(g *WithT, hrp *addonsv1alpha1.HelmReleaseProxy) {
+				g.Expect(conditions.IsTrue(hrp, addonsv1alpha1.DriftDetectedCondition)).To(BeTrue())
+				g.Expect(conditions.GetReason(hrp, addonsv1alpha1.DriftDetectedCondition)).To(Equal(addonsv1alpha1.DriftCorrectedReason))
+				g.Expect(hrp.Status.DriftedResources).To(ConsistOf("Deployment default/controller"))
+				g.Expect(hrp.Status.LastDriftCheckTime.Time).To(Equal(now))
+			},
+			expectedRequeueAfter: 10 * time.Minute,
+		},
+		{
+			name: "only reports drift with the Warn policy",
+			helmReleaseProxy: func() *addonsv1alpha1.HelmReleaseProxy {
+				hrp := driftProxy.DeepCopy()
+				hrp.Spec.DriftPolicy = string(addonsv1alpha1.DriftPolicyWarn)
+				return hrp
+			},
+			clientExpect: func(g *WithT, c *mocks.MockClientMockRecorder) {
+				c.ReconcileHelmReleaseDrift(ctx, restConfig, gomock.Any(), false).Return([]string{"Deployment default/controller"}, nil).Times(1)
+			},
+			expect: func(g *WithT, hrp *addonsv1alpha1.HelmReleaseProxy) {
+				g.Expect(conditions.GetReason(hrp, addonsv1alpha1.DriftDetectedCondition)).To(Equal(addonsv1alpha1.DriftDetectedReason))
+				g.Expect(conditions.GetSeverity(hrp, addonsv1alpha1.DriftDetectedCondition)).To(HaveValue(Equal(clusterv1.ConditionSeverityWarning)))
+			},
+			expectedRequeueAfter: 10 * time.Minute,
+		},
+		{
+			name: "only reports drift in observe-only mode",
+			helmReleaseProxy: func() *addonsv1alpha1.HelmReleaseProxy {
+				return driftProxy.DeepCopy()
+			},
+			observeOnly: true,
+			clientExpect: func(g *WithT, c *mocks.MockClientMockRecorder) {
+				c.ReconcileHelmReleaseDrift(ctx, restConfig, driftProxy.Spec, false).Return(nil, nil).Times(1)
+			},
+			expect: func(g *WithT, hrp *addonsv1alpha1.HelmReleaseProxy) {
+				g.Expect(conditions.IsFalse(hrp, addonsv1alpha1.DriftDetectedCondition)).To(BeTrue())
+				g.Expect(hrp.Status.DriftedResources).To(BeEmpty())
+			},
+			expectedRequeueAfter: 10 * time.Minute,
+		},
+		{
+			name: "reports failed drift checks without failing",
+			helmReleaseProxy: func() *addonsv1alpha1.HelmReleaseProxy {
+				return driftProxy.DeepCopy()
+			},
+			clientExpect: func(g *WithT, c *mocks.MockClientMockRecorder) {
+				c.ReconcileHelmReleaseDrift(ctx, restConfig, driftProxy.Spec, true).Return(nil, fmt.Errorf("connection refused")).Times(1)
+			},
+			expect: func(g *WithT, hrp *addonsv1alpha1.HelmReleaseProxy) {
+				g.Expect(conditions.IsUnknown(hrp, addonsv1alpha1.DriftDetectedCondition)).To(BeTrue())
+				g.Expect(conditions.GetReason(hrp, addonsv1alpha1.DriftDetectedCondition)).To(Equal(addonsv1alpha1.DriftCheckFailedReason))
+			},
+			expectedRequeueAfter: 10 * time.Minute,
+		},
+		{
+			name: "waits for the interval to elapse",
+			helmReleaseProxy: func() *addonsv1alpha1.HelmReleaseProxy {
+				hrp := driftProxy.DeepCopy()
+				hrp.Status.LastDriftCheckTime = &metav1.Time{Time: now.Add(-4 * time.Minute)}
+				return hrp
+			},
+			clientExpect: func(g *WithT, c *mocks.MockClientMockRecorder) {},
+			expect: func(g *WithT, hrp *addonsv1alpha1.HelmReleaseProxy) {
+				g.Expect(conditions.Has(hrp, addonsv1alpha1.DriftDetectedCondition)).To(BeFalse())
+			},
+			expectedRequeueAfter: 6 * time.Minute,
+		},
+		{
+			name: "skips drift checks with the skip-drift-check annotation",
+			helmReleaseProxy: func() *addonsv1alpha1.HelmReleaseProxy {
+				hrp := driftProxy.DeepCopy()
+				hrp.Annotations = map[string]string{addonsv1alpha1.SkipDriftCheckAnnotation: "true"}
+				hrp.Status.DriftedResources = []string{"Deployment default/controller"}
+				conditions.MarkTrue(hrp, addonsv1alpha1.DriftDetectedCondition)
+				return hrp
+			},
+			clientExpect: func(g *WithT, c *mocks.MockClientMockRecorder) {},
+			expect: func(g *WithT, hrp *addonsv1alpha1.HelmReleaseProxy) {
+				g.Expect(conditions.IsTrue(hrp, addonsv1alpha1.DriftDetectedCondition)).To(BeTrue())
+				g.Expect(hrp.Status.DriftedResources).To(ConsistOf("Deployment default/controller"))
+				g.Expect(hrp.Status.LastDriftCheckTime).To(BeNil())
+			},
+		},
+		{
+			name: "clears the drift status with the Ignore policy",
+			helmReleaseProxy: func() *addonsv1alpha1.HelmReleaseProxy {
+				hrp := driftProxy.DeepCopy()
+				hrp.Spec.DriftPolicy = string(addonsv1alpha1.DriftPolicyIgnore)
+				hrp.Status.DriftedResources = []string{"Deployment default/controller"}
+				conditions.MarkTrue(hrp, addonsv1alpha1.DriftDetectedCondition)
+				return hrp
+			},
+			clientExpect: func(g *WithT, c *mocks.MockClientMockRecorder) {},
+			expect: func(g *WithT, hrp *addonsv1alpha1.HelmReleaseProxy) {
+				g.Expect(conditions.Has(hrp, addonsv1alpha1.DriftDetectedCondition)).To(BeFalse())
+				g.Expect(hrp.Status.DriftedResources).To(BeNil())
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			t.Parallel()
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			clientMock := mocks.NewMockClient(mockCtrl)
+			tc.clientExpect(g, clientMock.EXPECT())
+
+			r := &HelmReleaseProxyReconciler{}
+
+			hrp := tc.helmReleaseProxy()
+			g.Expect(r.reconcileDrift(ctx, hrp, clientMock, restConfig, tc.observeOnly, now)).To(Equal(tc.expectedRequeueAfter))
+			tc.expect(g, hrp)
+		})
+	}
+}
+
+func TestStartupDelay(t *testing.T) {
+	g := NewWithT(t)
+
+	start := time.Now()
+	window := 10 * time.Minute
+	r := &HelmReleaseProxyReconciler{StartupWarmupWindow: window, startTime: start}
+
+	ready := defaultProxy.DeepCopy()
+	conditions.MarkTrue(ready, addonsv1alpha1.HelmReleaseReadyCondition)
+	offset := warmupOffset(ready, window)
+	g.Expect(offset).To(BeNumerically(">=", 0))
+	g.Expect(offset).To(BeNumerically("<", window))
+	g.Expect(warmupOffset(ready.DeepCopy(), window)).To(Equal(offset), "offsets are stable across requeues")
+
+	g.Expect(r.startupDelay(ready, start)).To(Equal(offset))
+	g.Expect(r.startupDelay(ready, start.Add(offset))).To(BeNumerically("<=", 0), "ready HelmReleaseProxies are reconciled at their offset")
+	g.Expect(r.startupDelay(ready, start.Add(window))).To(BeNumerically("<=", 0))
+
+	notReady := defaultProxy.DeepCopy()
+	conditions.MarkFalse(notReady, addonsv1alpha1.HelmReleaseReadyCondition, addonsv1alpha1.HelmInstallOrUpgradeFailedReason, clusterv1.ConditionSeverityError, "")
+	g.Expect(r.startupDelay(notReady, start)).To(BeZero(), "HelmReleaseProxies that are not ready are prioritized")
+
+	changed := ready.DeepCopy()
+	changed.Generation = ready.Status.ObservedGeneration + 1
+	g.Expect(r.startupDelay(changed, start)).To(BeZero(), "spec changes are reconciled right away")
+
+	disabled := &HelmReleaseProxyReconciler{startTime: start}
+	g.Expect(disabled.startupDelay(ready, start)).To(BeZero())
+}
+
+func TestIsReleaseUpToDate(t *testing.T) {
+	g := NewWithT(t)
+
+	now := time.Now()
+	deployed := func() *addonsv1alpha1.HelmReleaseProxy {
+		hrp := defaultProxy.DeepCopy()
+		setAppliedDigests(hrp, hrp.Spec, &helmRelease.Release{Manifest: "kind: Deployment"})
+		hrp.SetReleaseStatus(helmRelease.StatusDeployed.String())
+		conditions.MarkTrue(hrp, addonsv1alpha1.HelmReleaseReadyCondition)
+
+		return hrp
+	}
+
+	hrp := deployed()
+	g.Expect(hrp.Status.ManifestDigest).To(Equal(fmt.Sprintf("sha256:%x", sha256.Sum256([]byte("kind: Deployment")))))
+	g.Expect(hrp.Status.AppliedSpecDigest).To(HavePrefix("sha256:"))
+	g.Expect(isReleaseUpToDate(hrp, hrp.Spec, now)).To(BeTrue())
+
+	changed := hrp.Spec
+	changed.Values = "replicaCount: 3"
+	g.Expect(isReleaseUpToDate(hrp, changed, now)).To(BeFalse(), "spec changes require an upgrade check")
+
+	hrp = deployed()
+	hrp.Status.AppliedSpecDigest = ""
+	g.Expect(isReleaseUpToDate(hrp, hrp.Spec, now)).To(BeFalse(), "releases deployed before the digest was recorded are checked")
+
+	hrp = deployed()
+	conditions.MarkFalse(hrp, addonsv1alpha1.HelmReleaseReadyCondition, addonsv1alpha1.HelmInstallOrUpgradeFailedReason, clusterv1.ConditionSeverityError, "")
+	g.Expect(isReleaseUpToDate(hrp, hrp.Spec, now)).To(BeFalse())
+
+	hrp = deployed()
+	hrp.Spec.ResyncPeriod = &metav1.Duration{Duration: 10 * time.Minute}
+	setAppliedDigests(hrp, hrp.Spec, &helmRelease.Release{Manifest: "kind: Deployment"})
+	hrp.Status.LastSuccessfulReconcileTime = &metav1.Time{Time: now.Add(-5 * time.Minute)}
+	g.Expect(isReleaseUpToDate(hrp, hrp.Spec, now)).To(BeTrue())
+	hrp.Status.LastSuccessfulReconcileTime = &metav1.Time{Time: now.Add(-15 * time.Minute)}
+	g.Expect(isReleaseUpToDate(hrp, hrp.Spec, now)).To(BeFalse(), "releases are checked once the resync period elapsed")
+
+	hrp = deployed()
+	hrp.Spec.Version = ">=1.2.0 <2.0.0"
+	setAppliedDigests(hrp, hrp.Spec, &helmRelease.Release{Manifest: "kind: Deployment"})
+	hrp.Status.LastSuccessfulReconcileTime = &metav1.Time{Time: now.Add(-5 * time.Minute)}
+	g.Expect(isReleaseUpToDate(hrp, hrp.Spec, now)).To(BeTrue())
+	hrp.Status.LastSuccessfulReconcileTime = &metav1.Time{Time: now.Add(-15 * time.Minute)}
+	g.Expect(isReleaseUpToDate(hrp, hrp.Spec, now)).To(BeFalse(), "version constraints are resolved again periodically")
+
+	// No Helm operation is expected on the workload Cluster for an up to date release.
+	hrp = deployed()
+	r := &HelmReleaseProxyReconciler{
+		Client: fake.NewClientBuilder().WithScheme(fakeScheme).Build(),
+	}
+	g.Expect(r.reconcileNormal(ctx, hrp, mocks.NewMockClient(gomock.NewController(t)), "", "", internal.RepositoryAuth{}, restConfig, false)).To(Succeed())
+}
+
+func TestSkipWait(t *testing.T) {
+	g := NewWithT(t)
+
+	hrp := defaultProxy.DeepCopy()
+	hrp.Spec.Options.Wait = true
+	hrp.Spec.Options.WaitForJobs = true
+	hrp.Spec.Options.Atomic = true
+
+	g.Expect(skipWait(ctx, hrp, hrp.Spec)).To(Equal(hrp.Spec))
+
+	hrp.Annotations = map[string]string{addonsv1alpha1.SkipWaitAnnotation: "true"}
+	spec := skipWait(ctx, hrp, hrp.Spec)
+	g.Expect(spec.Options.Wait).To(BeFalse())
+	g.Expect(spec.Options.WaitForJobs).To(BeFalse())
+	g.Expect(spec.Options.Atomic).To(BeFalse())
+	g.Expect(hrp.Spec.Options.Wait).To(BeTrue(), "the spec of the HelmReleaseProxy is not changed")
+}
+
+func TestSetDeployedConfigLabels(t *testing.T) {
+	g := NewWithT(t)
+
+	release := func(values map[string]interface{}, template string) *helmRelease.Release {
+		return &helmRelease.Release{
+			Config: values,
+			Chart: &chart.Chart{
+				Metadata:  &chart.Metadata{Name: "test-chart", Version: "1.0.0"},
+				Templates: []*chart.File{{Name: "templates/deployment.yaml", Data: []byte(template)}},
+			},
+		}
+	}
+	labelsFor := func(release *helmRelease.Release) map[string]string {
+		hrp := defaultProxy.DeepCopy()
+		setDeployedConfigLabels(hrp, release)
+
+		return hrp.Labels
+	}
+
+	labels := labelsFor(release(map[string]interface{}{"replicaCount": 2, "image": map[string]interface{}{"tag": "v1"}}, "kind: Deployment"))
+	g.Expect(labels).To(HaveKey(addonsv1alpha1.ValuesHashLabelName))
+	g.Expect(labels).To(HaveKey(addonsv1alpha1.ChartDigestLabelName))
+	for _, value := range labels {
+		g.Expect(validation.IsValidLabelValue(value)).To(BeEmpty())
+	}
+
+	// The labels only change with the deployed values and chart.
+	g.Expect(labelsFor(release(map[string]interface{}{"image": map[string]interface{}{"tag": "v1"}, "replicaCount": 2}, "kind: Deployment"))).To(Equal(labels))
+
+	changedValues := labelsFor(release(map[string]interface{}{"replicaCount": 3, "image": map[string]interface{}{"tag": "v1"}}, "kind: Deployment"))
+	g.Expect(changedValues[addonsv1alpha1.ValuesHashLabelName]).NotTo(Equal(labels[addonsv1alpha1.ValuesHashLabelName]))
+	g.Expect(changedValues[addonsv1alpha1.ChartDigestLabelName]).To(Equal(labels[addonsv1alpha1.ChartDigestLabelName]))
+
+	changedChart := labelsFor(release(map[string]interface{}{"replicaCount": 2, "image": map[string]interface{}{"tag": "v1"}}, "kind: StatefulSet"))
+	g.Expect(changedChart[addonsv1alpha1.ValuesHashLabelName]).To(Equal(labels[addonsv1alpha1.ValuesHashLabelName]))
+	g.Expect(changedChart[addonsv1alpha1.ChartDigestLabelName]).NotTo(Equal(labels[addonsv1alpha1.ChartDigestLabelName]))
+
+	hrp := defaultProxy.DeepCopy()
+	withDefaults := release(nil, "kind: Deployment")
+	withDefaults.Chart.Values = map[string]interface{}{"replicaCount": 1}
+	setDeployedConfigLabels(hrp, withDefaults)
+	g.Expect(hrp.Status.DefaultValuesDigest).To(Equal("sha256:" + fmt.Sprintf("%x", sha256.Sum256([]byte(`{"replicaCount":1}`)))))
+}
+
+func TestGetRepositoryAuth(t *testing.T) {
+	t.Parallel()
+
+	newSecret := func(name string, data map[string][]byte) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+			Data: data,
+		}
+	}
+
+	testcases := []struct {
+		name                  string
+		repositoryHeaders     *corev1.SecretReference
+		repositoryCredentials *corev1.SecretReference
+		proxyURL              string
+		objects               []client.Object
+		expectedAuth          internal.RepositoryAuth
+		expectedError         string
+	}{
+		{
+			name:         "no Secrets referenced",
+			expectedAuth: internal.RepositoryAuth{},
+		},
+		{
+			name:              "headers and basic authentication",
+			repositoryHeaders: &corev1.SecretReference{Name: "headers"},
+			repositoryCredentials: &corev1.SecretReference{
+				Name: "credentials",
+			},
+			objects: []client.Object{
+				newSecret("headers", map[string][]byte{"X-Artifact-Token": []byte("secret")}),
+				newSecret("credentials", map[string][]byte{"username": []byte("user"), "password": []byte("pass")}),
+			},
+			expectedAuth: internal.RepositoryAuth{
+				Headers:  map[string]string{"X-Artifact-Token": "secret"},
+				Username: "user",
+				Password: "pass",
+			},
+		},
+		{
+			name:                  "bearer token authentication",
+			repositoryCredentials: &corev1.SecretReference{Name: "credentials", Namespace: "default"},
+			objects: []client.Object{
+				newSecret("credentials", map[string][]byte{"token": []byte("abc")}),
+			},
+			expectedAuth: internal.RepositoryAuth{BearerToken: "abc"},
+		},
+		{
+			name:                  "proxy",
+			repositoryCredentials: &corev1.SecretReference{Name: "credentials"},
+			proxyURL:              "http://proxy.corp.local:3128",
+			objects: []client.Object{
+				newSecret("credentials", map[string][]byte{"token": []byte("abc")}),
+			},
+			expectedAuth: internal.RepositoryAuth{BearerToken: "abc", ProxyURL: "http://proxy.corp.local:3128"},
+		},
+		{
+			name:                  "token together with username",
+			repositoryCredentials: &corev1.SecretReference{Name: "credentials"},
+			objects: []client.Object{
+				newSecret("credentials", map[string][]byte{"token": []byte("abc"), "username": []byte("user")}),
+			},
+			expectedError: "not both",
+		},
+		{
+			name:                  "username without password",
+			repositoryCredentials: &corev1.SecretReference{Name: "credentials"},
+			objects: []client.Object{
+				newSecret("credentials", map[string][]byte{"username": []byte("user")}),
+			},
+			expectedError: "must contain either the key token or the keys username and password",
+		},
+		{
+			name:                  "missing Secret",
+			repositoryCredentials: &corev1.SecretReference{Name: "credentials"},
+			expectedError:         "not found",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			t.Parallel()
+
+			helmReleaseProxy := defaultProxy.DeepCopy()
+			helmReleaseProxy.Spec.RepositoryHeaders = tc.repositoryHeaders
+			helmReleaseProxy.Spec.RepositoryCredentials = tc.repositoryCredentials
+			helmReleaseProxy.Spec.ProxyURL = tc.proxyURL
+
+			r := &HelmReleaseProxyReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(fakeScheme).
+					WithObjects(tc.objects...).
+					Build(),
+			}
+
+			repositoryAuth, err := r.getRepositoryAuth(ctx, helmReleaseProxy)
+			if tc.expectedError != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tc.expectedError)))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(repositoryAuth).To(Equal(tc.expectedAuth))
+		})
+	}
+}
+
+func init() {
+	_ = scheme.AddToScheme(fakeScheme)
+	_ = clusterv1.AddToScheme(fakeScheme)
+	_ = addonsv1alpha1.AddToScheme(fakeScheme)
+}
+
+func TestClientCertificateSecretToHelmReleaseProxies(t *testing.T) {
+	g := NewWithT(t)
+
+	withCertManagerRef := func(name string, ref *addonsv1alpha1.CertManagerReference) *addonsv1alpha1.HelmReleaseProxy {
+		helmReleaseProxy := defaultProxy.DeepCopy()
+		helmReleaseProxy.Name = name
+		helmReleaseProxy.Spec.TLSConfig = &addonsv1alpha1.TLSConfig{CertManagerRef: ref}
+
+		return helmReleaseProxy
+	}
+
+	r := &HelmReleaseProxyReconciler{
+		Client: fake.NewClientBuilder().
+			WithScheme(fakeScheme).
+			WithObjects(
+				withCertManagerRef("defaulted-namespace", &addonsv1alpha1.CertManagerReference{Name: "client"}),
+				withCertManagerRef("explicit-namespace", &addonsv1alpha1.CertManagerReference{Name: "client", Namespace: "default"}),
+				withCertManagerRef("other-certificate", &addonsv1alpha1.CertManagerReference{Name: "other"}),
+				withCertManagerRef("other-namespace", &addonsv1alpha1.CertManagerReference{Name: "client", Namespace: "other"}),
+				withCertManagerRef("no-certificate", nil),
+			).
+			Build(),
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "client-tls",
+			Namespace:   "default",
+			Annotations: map[string]string{certManagerCertificateNameAnnotation: "client"},
+		},
+	}
+	names := []string{}
+	for _, request := range r.clientCertificateSecretToHelmReleaseProxies(ctx, secret) {
+		names = append(names, request.Name)
+	}
+	g.Expect(names).To(ConsistOf("defaulted-namespace", "explicit-namespace"))
+
+	secret.Annotations = nil
+	g.Expect(r.clientCertificateSecretToHelmReleaseProxies(ctx, secret)).To(BeEmpty(), "Secrets not issued by cert-manager are ignored")
+}
+
+func TestIsClientCertificateSecret(t *testing.T) {
+	g := NewWithT(t)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "client-tls",
+			Namespace:   "default",
+			Annotations: map[string]string{certManagerCertificateNameAnnotation: "client"},
+		},
+		Type: corev1.SecretTypeTLS,
+	}
+	g.Expect(isClientCertificateSecret(secret)).To(BeTrue())
+
+	opaque := secret.DeepCopy()
+	opaque.Type = corev1.SecretTypeOpaque
+	g.Expect(isClientCertificateSecret(opaque)).To(BeFalse(), "only TLS Secrets are issued by cert-manager")
+
+	unannotated := secret.DeepCopy()
+	unannotated.Annotations = nil
+	g.Expect(isClientCertificateSecret(unannotated)).To(BeFalse(), "TLS Secrets not issued by cert-manager are ignored")
+
+	g.Expect(isClientCertificateSecret(&corev1.ConfigMap{})).To(BeFalse())
+}
+
+func TestWithHelmRepository(t *testing.T) {
+	g := NewWithT(t)
+
+	repository := &addonsv1alpha1.HelmRepository{
+		ObjectMeta: metav1.ObjectMeta{Name: "corp", Namespace: "repositories"},
+		Spec: addonsv1alpha1.HelmRepositorySpec{
+			URL:                   "https://charts.corp.local",
+			RepositoryCredentials: &corev1.SecretReference{Name: "corp-credentials"},
+			TLSConfig:             &addonsv1alpha1.TLSConfig{CASecretRef: &corev1.SecretReference{Name: "corp-ca"}},
+			ProxyURL:              "http://proxy.corp.local:3128",
+		},
+	}
+	r := &HelmReleaseProxyReconciler{
+		Client: fake.NewClientBuilder().WithScheme(fakeScheme).WithObjects(repository).Build(),
+	}
+
+	helmReleaseProxy := defaultProxy.DeepCopy()
+	resolved, resolvedRepository, err := r.withHelmRepository(ctx, helmReleaseProxy)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(resolved).To(BeIdenticalTo(helmReleaseProxy), "HelmReleaseProxies without a RepositoryRef are unchanged")
+	g.Expect(resolvedRepository).To(BeNil())
+
+	helmReleaseProxy.Spec.RepositoryRef = &addonsv1alpha1.HelmRepositoryReference{Name: "corp", Namespace: "repositories"}
+	resolved, resolvedRepository, err = r.withHelmRepository(ctx, helmReleaseProxy)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(resolvedRepository.Name).To(Equal("corp"))
+	g.Expect(resolved.Spec.RepoURL).To(Equal("https://charts.corp.local"))
+	g.Expect(resolved.Spec.RepositoryCredentials).To(Equal(&corev1.SecretReference{Name: "corp-credentials", Namespace: "repositories"}))
+	g.Expect(resolved.Spec.TLSConfig.CASecretRef).To(Equal(&corev1.SecretReference{Name: "corp-ca", Namespace: "repositories"}))
+	g.Expect(resolved.Spec.ProxyURL).To(Equal("http://proxy.corp.local:3128"))
+	g.Expect(helmReleaseProxy.Spec.RepoURL).To(Equal(defaultProxy.Spec.RepoURL), "the resolved repository is not persisted")
+	g.Expect(repository.Spec.RepositoryCredentials.Namespace).To(BeEmpty(), "the HelmRepository is not modified")
+
+	spec, err := r.resolvedSpec(ctx, helmReleaseProxy)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(spec.RepoURL).To(Equal("https://charts.corp.local"))
+
+	helmReleaseProxy.Spec.RepositoryRef = &addonsv1alpha1.HelmRepositoryReference{Name: "missing"}
+	_, _, err = r.withHelmRepository(ctx, helmReleaseProxy)
+	g.Expect(err).To(MatchError(ContainSubstring("failed to get HelmRepository default/missing")))
+	g.Expect(conditions.GetReason(helmReleaseProxy, addonsv1alpha1.HelmReleaseReadyCondition)).To(Equal(addonsv1alpha1.HelmRepositoryUnavailableReason))
+}
+
+func TestHelmRepositoryToHelmReleaseProxies(t *testing.T) {
+	g := NewWithT(t)
+
+	withRepositoryRef := func(name string, ref *addonsv1alpha1.HelmRepositoryReference) *addonsv1alpha1.HelmReleaseProxy {
+		helmReleaseProxy := defaultProxy.DeepCopy()
+		helmReleaseProxy.Name = name
+		helmReleaseProxy.Spec.RepositoryRef = ref
+
+		return helmReleaseProxy
+	}
+
+	r := &HelmReleaseProxyReconciler{
+		Client: fake.NewClientBuilder().
+			WithScheme(fakeScheme).
+			WithObjects(
+				withRepositoryRef("defaulted-namespace", &addonsv1alpha1.HelmRepositoryReference{Name: "corp"}),
+				withRepositoryRef("explicit-namespace", &addonsv1alpha1.HelmRepositoryReference{Name: "corp", Namespace: "default"}),
+				withRepositoryRef("other-repository", &addonsv1alpha1.HelmRepositoryReference{Name: "other"}),
+				withRepositoryRef("other-namespace", &addonsv1alpha1.HelmRepositoryReference{Name: "corp", Namespace: "other"}),
+				withRepositoryRef("no-repository", nil),
+			).
+			Build(),
+	}
+
+	names := []string{}
+	for _, request := range r.helmRepositoryToHelmReleaseProxies(ctx, &addonsv1alpha1.HelmRepository{ObjectMeta: metav1.ObjectMeta{Name: "corp", Namespace: "default"}}) {
+		names = append(names, request.Name)
+	}
+	g.Expect(names).To(ConsistOf("defaulted-namespace", "explicit-namespace"))
+}
+
+func TestGetRESTConfig(t *testing.T) {
+	t.Parallel()
+
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-cluster",
+			Namespace: "default",
+		},
+	}
+	kubeconfigSecret := func(data []byte) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-cluster-kubeconfig",
+				Namespace: "default",
+			},
+			Data: map[string][]byte{"value": data},
+		}
+	}
+	kubeconfig := []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: test-cluster
+  cluster:
+    server: https://test-cluster:6443
+contexts:
+- name: test-cluster
+  context:
+    cluster: test-cluster
+    user: admin
+current-context: test-cluster
+users:
+- name: admin
+  user:
+    token: test-token
+`)
+
+	testcases := []struct {
+		name                string
+		objects             []client.Object
+		impersonation       *addonsv1alpha1.Impersonation
+		expectConfig        bool
+		expectError         bool
+		expectedReason      string
+		expectedImpersonate rest.ImpersonationConfig
+	}{
+		{
+			name:           "waits for a kubeconfig Secret that does not exist yet",
+			expectedReason: addonsv1alpha1.WaitingForKubeconfigReason,
+		},
+		{
+			name:           "fails on an invalid kubeconfig",
+			objects:        []client.Object{kubeconfigSecret([]byte("not a kubeconfig"))},
+			expectError:    true,
+			expectedReason: addonsv1alpha1.GetKubeconfigFailedReason,
+		},
+		{
+			name:         "returns the REST config of the kubeconfig Secret",
+			objects:      []client.Object{kubeconfigSecret(kubeconfig)},
+			expectConfig: true,
+		},
+		{
+			name:                "impersonates the identity of the HelmReleaseProxy",
+			objects:             []client.Object{kubeconfigSecret(kubeconfig)},
+			impersonation:       &addonsv1alpha1.Impersonation{User: "caaph:platform-team", Groups: []string{"platform"}},
+			expectConfig:        true,
+			expectedImpersonate: rest.ImpersonationConfig{UserName: "caaph:platform-team", Groups: []string{"platform"}},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			t.Parallel()
+
+			r := &HelmReleaseProxyReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(fakeScheme).
+					WithObjects(tc.objects...).
+					Build(),
+			}
+
+			helmReleaseProxy := defaultProxy.DeepCopy()
+			helmReleaseProxy.Spec.Impersonation = tc.impersonation
+			restConfig, err := r.getRESTConfig(ctx, helmReleaseProxy, cluster)
+			if tc.expectError {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+			if tc.expectConfig {
+				g.Expect(restConfig).NotTo(BeNil())
+				g.Expect(restConfig.Host).To(Equal("https://test-cluster:6443"))
+				g.Expect(restConfig.Impersonate).To(Equal(tc.expectedImpersonate))
+			} else {
+				g.Expect(restConfig).To(BeNil())
+			}
+			if tc.expectedReason != "" {
+				g.Expect(conditions.GetReason(helmReleaseProxy, addonsv1alpha1.ClusterAvailableCondition)).To(Equal(tc.expectedReason))
+			}
+		})
+	}
+}
+
+func TestKubeconfigSecretToHelmReleaseProxies(t *testing.T) {
+	g := NewWithT(t)
+
+	forCluster := func(name, clusterName string) *addonsv1alpha1.HelmReleaseProxy {
+		helmReleaseProxy := defaultProxy.DeepCopy()
+		helmReleaseProxy.Name = name
+		helmReleaseProxy.Labels = map[string]string{clusterv1.ClusterNameLabel: clusterName}
+
+		return helmReleaseProxy
+	}
+
+	r := &HelmReleaseProxyReconciler{
+		Client: fake.NewClientBuilder().
+			WithScheme(fakeScheme).
+			WithObjects(
+				forCluster("first-chart", "test-cluster"),
+				forCluster("second-chart", "test-cluster"),
+				forCluster("other-cluster", "other-cluster"),
+			).
+			Build(),
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-cluster-kubeconfig",
+			Namespace: "default",
+			Labels:    map[string]string{clusterv1.ClusterNameLabel: "test-cluster"},
+		},
+	}
+	names := []string{}
+	for _, request := range r.kubeconfigSecretToHelmReleaseProxies(ctx, secret) {
+		names = append(names, request.Name)
+	}
+	g.Expect(names).To(ConsistOf("first-chart", "second-chart"))
+
+	secret.Name = "test-cluster-ca"
+	g.Expect(r.kubeconfigSecretToHelmReleaseProxies(ctx, secret)).To(BeEmpty(), "other Secrets of the Cluster are ignored")
+}
+
+func TestStreamReleaseProgress(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	defer func(interval time.Duration) { releaseProgressInterval = interval }(releaseProgressInterval)
+	releaseProgressInterval = 10 * time.Millisecond
+
+	helmReleaseProxy := defaultProxy.DeepCopy()
+	event := addonsv1alpha1.ReleaseEvent{Kind: "Pod", Namespace: "default", Name: "test-pod", Reason: "BackOff", Message: "Back-off restarting failed container"}
+
+	clientMock := mocks.NewMockClient(mockCtrl)
+	clientMock.EXPECT().GetHelmReleaseProgress(gomock.Any(), restConfig, helmReleaseProxy.Spec).DoAndReturn(
+		func(_, _, _ any) (*addonsv1alpha1.ReleaseProgress, error) {
+			return &addonsv1alpha1.ReleaseProgress{Ready: 1, Pending: 1, Events: []addonsv1alpha1.ReleaseEvent{event}}, nil
+		},
+	).MinTimes(1)
+
+	c := fake.NewClientBuilder().
+		WithScheme(fakeScheme).
+		WithObjects(helmReleaseProxy).
+		WithStatusSubresource(&addonsv1alpha1.HelmReleaseProxy{}).
+		Build()
+	recorder := record.NewFakeRecorder(10)
+	r := &HelmReleaseProxyReconciler{
+		Client:   c,
+		Recorder: recorder,
+	}
+
+	stop := r.streamReleaseProgress(ctx, helmReleaseProxy, clientMock, restConfig)
+
+	g.Eventually(func(g Gomega) {
+		patched := &addonsv1alpha1.HelmReleaseProxy{}
+		g.Expect(c.Get(ctx, client.ObjectKeyFromObject(helmReleaseProxy), patched)).To(Succeed())
+		g.Expect(patched.Status.Progress).NotTo(BeNil())
+		g.Expect(patched.Status.Progress.Ready).To(Equal(1))
+		g.Expect(patched.Status.Progress.Pending).To(Equal(1))
+		g.Expect(patched.Status.Progress.LastUpdated).NotTo(BeNil())
+	}, time.Second, 10*time.Millisecond).Should(Succeed())
+
+	progress := stop()
+	g.Expect(progress).NotTo(BeNil())
+	g.Expect(progress.Ready).To(Equal(1))
+	g.Expect(progress.Events).To(Equal([]addonsv1alpha1.ReleaseEvent{event}))
+
+	// Each event is only recorded once, however often it is observed.
+	g.Expect(recorder.Events).To(HaveLen(1))
+	g.Expect(<-recorder.Events).To(ContainSubstring("BackOff"))
 }
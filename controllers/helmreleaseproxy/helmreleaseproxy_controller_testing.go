@@ -0,0 +1,74 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helmreleaseproxy
+
+import (
+	"context"
+	"time"
+
+	helmRelease "helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/cluster-api-addon-provider-helm/internal"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// reconcileReleaseTest runs the tests of the deployed Helm release once per revision if Options.Test is enabled, and
+// records the results in the Test status and the ReleaseTested condition. Failed tests do not fail the reconcile, as the
+// Helm release is deployed either way.
+func (r *HelmReleaseProxyReconciler) reconcileReleaseTest(ctx context.Context, helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy, helmClient internal.Client, restConfig *rest.Config, spec addonsv1alpha1.HelmReleaseProxySpec, release *helmRelease.Release, now time.Time) {
+	log := ctrl.LoggerFrom(ctx)
+
+	if test := helmReleaseProxy.Spec.Options.Test; test == nil || !test.Enable {
+		conditions.Delete(helmReleaseProxy, addonsv1alpha1.ReleaseTestedCondition)
+		helmReleaseProxy.Status.Test = nil
+
+		return
+	}
+
+	if helmReleaseProxy.GetAnnotations()[addonsv1alpha1.SkipTestsAnnotation] == "true" {
+		log.V(2).Info("Skipping tests of release", "release", release.Name, "annotation", addonsv1alpha1.SkipTestsAnnotation, "cluster", helmReleaseProxy.Spec.ClusterRef.Name)
+		return
+	}
+
+	if status := helmReleaseProxy.Status.Test; status != nil && status.Revision == release.Version {
+		return
+	}
+
+	log.Info("Testing release", "release", release.Name, "revision", release.Version, "cluster", helmReleaseProxy.Spec.ClusterRef.Name)
+	tested, err := helmClient.TestHelmRelease(ctx, restConfig, spec)
+	helmReleaseProxy.Status.Test = &addonsv1alpha1.ReleaseTestStatus{
+		Revision: release.Version,
+		Time:     metav1.NewTime(now),
+		Results:  internal.ReleaseTestResults(tested, now),
+	}
+	if err != nil {
+		log.Error(err, "Tests of release failed", "release", release.Name, "revision", release.Version, "cluster", helmReleaseProxy.Spec.ClusterRef.Name)
+		conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.ReleaseTestedCondition, addonsv1alpha1.ReleaseTestFailedReason, clusterv1.ConditionSeverityError, "%s", err.Error())
+		r.Recorder.Eventf(helmReleaseProxy, corev1.EventTypeWarning, addonsv1alpha1.ReleaseTestFailedReason, "Tests of release %s revision %d on cluster %s failed: %s",
+			release.Name, release.Version, helmReleaseProxy.Spec.ClusterRef.Name, err.Error())
+
+		return
+	}
+
+	conditions.MarkTrue(helmReleaseProxy, addonsv1alpha1.ReleaseTestedCondition)
+	log.V(2).Info("Tests of release passed", "release", release.Name, "tests", len(helmReleaseProxy.Status.Test.Results), "cluster", helmReleaseProxy.Spec.ClusterRef.Name)
+}
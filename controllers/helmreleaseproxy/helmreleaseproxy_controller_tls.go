@@ -0,0 +1,135 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helmreleaseproxy
+
+import (
+	"context"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/cluster-api-addon-provider-helm/internal"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// certManagerCertificateNameAnnotation is the annotation cert-manager sets on the Secrets it issues to the name of their
+// Certificate.
+const certManagerCertificateNameAnnotation = "cert-manager.io/certificate-name"
+
+// getClientCertificateFiles fetches the client certificate and key issued for the cert-manager Certificate referenced by
+// the HelmReleaseProxy and writes them to temporary files, returning their paths. Empty paths are returned if no
+// Certificate is referenced.
+func (r *HelmReleaseProxyReconciler) getClientCertificateFiles(ctx context.Context, helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy) (certFile, keyFile string, err error) {
+	if helmReleaseProxy.Spec.TLSConfig == nil || helmReleaseProxy.Spec.TLSConfig.CertManagerRef == nil {
+		return "", "", nil
+	}
+
+	certPEM, keyPEM, err := internal.GetCertManagerClientCertificate(ctx, r.Client, certManagerRefKey(helmReleaseProxy))
+	if err != nil {
+		return "", "", err
+	}
+
+	certFile, err = writeTemporaryFile(ctx, "client-*.crt", certPEM)
+	if err != nil {
+		return "", "", err
+	}
+	keyFile, err = writeTemporaryFile(ctx, "client-*.key", keyPEM)
+	if err != nil {
+		_ = os.Remove(certFile)
+		return "", "", err
+	}
+
+	return certFile, keyFile, nil
+}
+
+// certManagerRefKey returns the key of the cert-manager Certificate referenced by the HelmReleaseProxy, which defaults to
+// the namespace of the HelmReleaseProxy.
+func certManagerRefKey(helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy) types.NamespacedName {
+	ref := helmReleaseProxy.Spec.TLSConfig.CertManagerRef
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = helmReleaseProxy.Namespace
+	}
+
+	return types.NamespacedName{Namespace: namespace, Name: ref.Name}
+}
+
+// writeTemporaryFile writes the data to a temporary file only readable by the controller and returns its path.
+func writeTemporaryFile(ctx context.Context, pattern string, data []byte) (string, error) {
+	log := ctrl.LoggerFrom(ctx)
+	log.V(2).Info("Writing temporary file", "pattern", pattern)
+	file, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if _, err := file.Write(data); err != nil {
+		_ = os.Remove(file.Name())
+		return "", err
+	}
+
+	return file.Name(), nil
+}
+
+// isClientCertificateSecret returns true if the object is a TLS Secret issued by cert-manager for a Certificate.
+func isClientCertificateSecret(o client.Object) bool {
+	secret, ok := o.(*corev1.Secret)
+	if !ok {
+		return false
+	}
+
+	return secret.Type == corev1.SecretTypeTLS && secret.Annotations[certManagerCertificateNameAnnotation] != ""
+}
+
+// clientCertificateSecretPredicate filters the Secret events to those of Secrets issued by cert-manager, so that the
+// mapper does not list all HelmReleaseProxies on every Secret change in the management cluster.
+var clientCertificateSecretPredicate = predicate.NewPredicateFuncs(isClientCertificateSecret)
+
+// clientCertificateSecretToHelmReleaseProxies is a mapper function that maps a Secret issued by cert-manager to the
+// HelmReleaseProxies referencing its Certificate, so that renewed client certificates are used right away.
+func (r *HelmReleaseProxyReconciler) clientCertificateSecretToHelmReleaseProxies(ctx context.Context, o client.Object) []reconcile.Request {
+	log := ctrl.LoggerFrom(ctx)
+
+	certificateName := o.GetAnnotations()[certManagerCertificateNameAnnotation]
+	if certificateName == "" {
+		return nil
+	}
+
+	helmReleaseProxies := &addonsv1alpha1.HelmReleaseProxyList{}
+	if err := r.List(ctx, helmReleaseProxies); err != nil {
+		log.Error(err, "failed to list HelmReleaseProxies")
+		return nil
+	}
+
+	results := []reconcile.Request{}
+	for i := range helmReleaseProxies.Items {
+		helmReleaseProxy := &helmReleaseProxies.Items[i]
+		if helmReleaseProxy.Spec.TLSConfig == nil || helmReleaseProxy.Spec.TLSConfig.CertManagerRef == nil {
+			continue
+		}
+		if certManagerRefKey(helmReleaseProxy) == (types.NamespacedName{Namespace: o.GetNamespace(), Name: certificateName}) {
+			results = append(results, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(helmReleaseProxy)})
+		}
+	}
+
+	return results
+}
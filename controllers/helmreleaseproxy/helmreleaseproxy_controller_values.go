@@ -0,0 +1,289 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helmreleaseproxy
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	helmRelease "helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/cluster-api-addon-provider-helm/internal"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// valuesConfigMapValuesKey is the key of the redacted values in the values ConfigMap.
+	valuesConfigMapValuesKey = "values.yaml"
+
+	// valuesConfigMapRevisionKey is the key of the revision of the Helm release the values were read from.
+	valuesConfigMapRevisionKey = "revision"
+
+	// redactedValue replaces sensitive values in the values ConfigMap.
+	redactedValue = "<redacted>"
+
+	// valuesOverridesConfigMapKey is the key of the overridden chart defaults in the values overrides ConfigMap.
+	valuesOverridesConfigMapKey = "overrides.yaml"
+)
+
+// valuesOverride is a default value of a chart overridden by the values of a Helm release.
+type valuesOverride struct {
+	// Path is the dot-separated path of the value.
+	Path string `json:"path"`
+
+	// Default is the default value of the chart, or null if the chart has none.
+	Default interface{} `json:"default"`
+
+	// Value is the value of the Helm release.
+	Value interface{} `json:"value"`
+}
+
+// sensitiveValueKeys are the substrings of the keys, in lower case, whose values are redacted in the values ConfigMap.
+var sensitiveValueKeys = []string{"password", "passwd", "secret", "token", "credential", "apikey", "api_key", "privatekey", "private_key", "accesskey", "access_key"}
+
+// reconcileValuesConfigMap copies the values of the Helm release deployed on the Cluster, with sensitive values redacted,
+// to a ConfigMap owned by the HelmReleaseProxy if it has the show-values annotation, and deletes a previously created
+// ConfigMap otherwise. This answers what is actually deployed without access to the workload Cluster.
+func (r *HelmReleaseProxyReconciler) reconcileValuesConfigMap(ctx context.Context, helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy, helmClient internal.Client, restConfig *rest.Config) error {
+	if helmReleaseProxy.GetAnnotations()[addonsv1alpha1.ShowValuesAnnotation] != "true" {
+		if helmReleaseProxy.Status.ValuesConfigMapName == "" {
+			return nil
+		}
+
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      helmReleaseProxy.Status.ValuesConfigMapName,
+				Namespace: helmReleaseProxy.Namespace,
+			},
+		}
+		if err := r.Client.Delete(ctx, configMap); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete values ConfigMap %s", configMap.Name)
+		}
+		helmReleaseProxy.Status.ValuesConfigMapName = ""
+
+		return nil
+	}
+
+	release, err := helmClient.GetHelmRelease(ctx, restConfig, helmReleaseProxy.Spec)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get release %s", helmReleaseProxy.Spec.ReleaseName)
+	}
+
+	values, err := yaml.Marshal(redactValues(release.Config))
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal values of release %s", release.Name)
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      helmReleaseProxy.Name + "-values",
+			Namespace: helmReleaseProxy.Namespace,
+		},
+	}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, configMap, func() error {
+		if configMap.Labels == nil {
+			configMap.Labels = map[string]string{}
+		}
+		configMap.Labels[clusterv1.ClusterNameLabel] = helmReleaseProxy.Spec.ClusterRef.Name
+		if helmChartProxyName, ok := helmReleaseProxy.Labels[addonsv1alpha1.HelmChartProxyLabelName]; ok {
+			configMap.Labels[addonsv1alpha1.HelmChartProxyLabelName] = helmChartProxyName
+		}
+		configMap.Data = map[string]string{
+			valuesConfigMapValuesKey:   string(values),
+			valuesConfigMapRevisionKey: strconv.Itoa(release.Version),
+		}
+
+		return controllerutil.SetControllerReference(helmReleaseProxy, configMap, r.Client.Scheme())
+	}); err != nil {
+		return errors.Wrapf(err, "failed to create or update values ConfigMap %s", configMap.Name)
+	}
+	helmReleaseProxy.Status.ValuesConfigMapName = configMap.Name
+
+	return nil
+}
+
+// reconcileValuesOverridesConfigMap records the default values of the chart overridden by the values of the deployed Helm
+// release, with sensitive values redacted, in a ConfigMap owned by the HelmReleaseProxy if RecordValuesOverrides is set,
+// and deletes a previously created ConfigMap otherwise.
+func (r *HelmReleaseProxyReconciler) reconcileValuesOverridesConfigMap(ctx context.Context, helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy, release *helmRelease.Release) error {
+	if !helmReleaseProxy.Spec.RecordValuesOverrides || release.Chart == nil {
+		if helmReleaseProxy.Status.ValuesOverridesConfigMapName == "" {
+			return nil
+		}
+
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      helmReleaseProxy.Status.ValuesOverridesConfigMapName,
+				Namespace: helmReleaseProxy.Namespace,
+			},
+		}
+		if err := r.Client.Delete(ctx, configMap); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete values overrides ConfigMap %s", configMap.Name)
+		}
+		helmReleaseProxy.Status.ValuesOverridesConfigMapName = ""
+
+		return nil
+	}
+
+	overrides, err := yaml.Marshal(valuesOverrides("", redactValues(release.Chart.Values), redactValues(release.Config)))
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal values overrides of release %s", release.Name)
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      helmReleaseProxy.Name + "-values-overrides",
+			Namespace: helmReleaseProxy.Namespace,
+		},
+	}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, configMap, func() error {
+		if configMap.Labels == nil {
+			configMap.Labels = map[string]string{}
+		}
+		configMap.Labels[clusterv1.ClusterNameLabel] = helmReleaseProxy.Spec.ClusterRef.Name
+		if helmChartProxyName, ok := helmReleaseProxy.Labels[addonsv1alpha1.HelmChartProxyLabelName]; ok {
+			configMap.Labels[addonsv1alpha1.HelmChartProxyLabelName] = helmChartProxyName
+		}
+		configMap.Data = map[string]string{
+			valuesOverridesConfigMapKey: string(overrides),
+			valuesConfigMapRevisionKey:  strconv.Itoa(release.Version),
+		}
+
+		return controllerutil.SetControllerReference(helmReleaseProxy, configMap, r.Client.Scheme())
+	}); err != nil {
+		return errors.Wrapf(err, "failed to create or update values overrides ConfigMap %s", configMap.Name)
+	}
+	helmReleaseProxy.Status.ValuesOverridesConfigMapName = configMap.Name
+
+	return nil
+}
+
+// valuesOverrides returns the values that differ from the defaults of the chart, sorted by path. Maps are compared
+// recursively, so only the leaves that are overridden are returned, while any other value, including lists, is compared
+// as a whole.
+func valuesOverrides(prefix string, defaults, values map[string]interface{}) []valuesOverride {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	overrides := []valuesOverride{}
+	for _, key := range keys {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		value, defaultValue := values[key], defaults[key]
+		valueMap, isMap := value.(map[string]interface{})
+		defaultMap, isDefaultMap := defaultValue.(map[string]interface{})
+		if isMap && isDefaultMap {
+			overrides = append(overrides, valuesOverrides(path, defaultMap, valueMap)...)
+			continue
+		}
+		if !reflect.DeepEqual(value, defaultValue) {
+			overrides = append(overrides, valuesOverride{Path: path, Default: defaultValue, Value: value})
+		}
+	}
+
+	return overrides
+}
+
+// redactValues returns a copy of the Helm values with the values of sensitive keys, such as passwords and tokens, replaced
+// by a placeholder.
+func redactValues(values map[string]interface{}) map[string]interface{} {
+	if values == nil {
+		return map[string]interface{}{}
+	}
+
+	redacted := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		if isSensitiveValueKey(key) && value != nil {
+			redacted[key] = redactedValue
+			continue
+		}
+		redacted[key] = redactValue(value)
+	}
+
+	return redacted
+}
+
+// redactValue redacts the sensitive keys of the maps nested in a Helm value.
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return redactValues(v)
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, item := range v {
+			redacted[i] = redactValue(item)
+		}
+
+		return redacted
+	default:
+		return value
+	}
+}
+
+// isSensitiveValueKey returns true if the values of the key should be redacted.
+func isSensitiveValueKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitive := range sensitiveValueKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolvedSpec returns the spec of the HelmReleaseProxy with the values it references from ConfigMaps merged into its
+// inline values and the HelmRepository it references applied, marking the HelmReleaseReady condition false if they
+// cannot be resolved.
+func (r *HelmReleaseProxyReconciler) resolvedSpec(ctx context.Context, helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy) (addonsv1alpha1.HelmReleaseProxySpec, error) {
+	spec := *helmReleaseProxy.Spec.DeepCopy()
+	repository, err := r.getHelmRepository(ctx, helmReleaseProxy)
+	if err != nil {
+		return spec, err
+	}
+	if repository != nil {
+		applyHelmRepository(&spec, repository)
+	}
+
+	values, err := internal.ResolveValues(ctx, r.Client, helmReleaseProxy.Namespace, spec)
+	if err != nil {
+		conditions.MarkFalse(helmReleaseProxy, addonsv1alpha1.HelmReleaseReadyCondition, addonsv1alpha1.ValuesUnavailableReason, clusterv1.ConditionSeverityError, "%s", err.Error())
+
+		return spec, errors.Wrap(err, "failed to resolve referenced values")
+	}
+	spec.Values = values
+	spec.ValuesRefs = nil
+
+	return spec, nil
+}
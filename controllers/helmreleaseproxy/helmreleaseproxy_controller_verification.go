@@ -0,0 +1,93 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helmreleaseproxy
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/cluster-api-addon-provider-helm/internal"
+)
+
+// getChartVerification fetches the keys the chart of the HelmReleaseProxy is verified with from the Secrets referenced by
+// Verify. The keyring of provenance verification is written to a temporary file, whose path is returned in the
+// ChartVerification. Nil is returned if the chart is not verified.
+func (r *HelmReleaseProxyReconciler) getChartVerification(ctx context.Context, helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy) (*internal.ChartVerification, error) {
+	verify := helmReleaseProxy.Spec.Verify
+	if verify == nil || (verify.Provenance == nil && verify.Cosign == nil) {
+		return nil, nil
+	}
+
+	verification := &internal.ChartVerification{}
+	if verify.Cosign != nil && verify.Cosign.PublicKeySecretRef != nil {
+		publicKey, err := r.getVerificationKey(ctx, helmReleaseProxy, verify.Cosign.PublicKeySecretRef, addonsv1alpha1.CosignPublicKeyKey)
+		if err != nil {
+			return nil, err
+		}
+		verification.CosignPublicKey = publicKey
+	}
+
+	if verify.Cosign != nil && verify.Cosign.Keyless != nil {
+		keyless := verify.Cosign.Keyless
+		fulcioCertificates, err := r.getVerificationKey(ctx, helmReleaseProxy, &keyless.TrustedRootSecretRef, addonsv1alpha1.FulcioCertificatesKey)
+		if err != nil {
+			return nil, err
+		}
+		rekorPublicKey, err := r.getVerificationKey(ctx, helmReleaseProxy, &keyless.TrustedRootSecretRef, addonsv1alpha1.RekorPublicKeyKey)
+		if err != nil {
+			return nil, err
+		}
+		verification.CosignKeyless = &internal.CosignKeylessVerification{
+			Issuer:             keyless.Issuer,
+			Subject:            keyless.Subject,
+			FulcioCertificates: fulcioCertificates,
+			RekorPublicKey:     rekorPublicKey,
+		}
+	}
+
+	// The keyring is read last, so that its temporary file is not left behind when fetching the other keys fails.
+	if verify.Provenance != nil {
+		keyring, err := r.getVerificationKey(ctx, helmReleaseProxy, &verify.Provenance.KeyringSecretRef, addonsv1alpha1.ProvenanceKeyringKey)
+		if err != nil {
+			return nil, err
+		}
+		keyringFile, err := writeTemporaryFile(ctx, "keyring-*.gpg", keyring)
+		if err != nil {
+			return nil, err
+		}
+		verification.KeyringFile = keyringFile
+	}
+
+	return verification, nil
+}
+
+// getVerificationKey returns the value of the key in the referenced Secret, which defaults to the namespace of the
+// HelmReleaseProxy.
+func (r *HelmReleaseProxyReconciler) getVerificationKey(ctx context.Context, helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy, secretRef *corev1.SecretReference, key string) ([]byte, error) {
+	data, err := r.getRepositorySecretData(ctx, helmReleaseProxy, secretRef)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := data[key]
+	if !ok || len(value) == 0 {
+		return nil, errors.Errorf("verification Secret %s does not contain the key %s", secretRef.Name, key)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helmreleaseproxy
+
+import (
+	"hash/fnv"
+	"time"
+
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+)
+
+// startupDelay returns how long the reconcile of the HelmReleaseProxy is delayed to spread the reconciles after a
+// controller restart over the StartupWarmupWindow. Each ready HelmReleaseProxy is assigned a stable offset within the
+// window, while HelmReleaseProxies that are not ready, have a changed spec or are being deleted are reconciled right away.
+func (r *HelmReleaseProxyReconciler) startupDelay(helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy, now time.Time) time.Duration {
+	if r.StartupWarmupWindow <= 0 || r.startTime.IsZero() {
+		return 0
+	}
+	if !helmReleaseProxy.DeletionTimestamp.IsZero() || helmReleaseProxy.Status.ObservedGeneration != helmReleaseProxy.Generation {
+		return 0
+	}
+	if !conditions.IsTrue(helmReleaseProxy, addonsv1alpha1.HelmReleaseReadyCondition) {
+		return 0
+	}
+
+	return warmupOffset(helmReleaseProxy, r.StartupWarmupWindow) - now.Sub(r.startTime)
+}
+
+// warmupOffset returns the offset of the HelmReleaseProxy within the warmup window, derived from a hash of its namespace and
+// name so that the offsets are evenly spread and do not change between requeues.
+func warmupOffset(helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy, window time.Duration) time.Duration {
+	hash := fnv.New64a()
+	hash.Write([]byte(helmReleaseProxy.Namespace + "/" + helmReleaseProxy.Name))
+
+	return time.Duration(hash.Sum64() % uint64(window))
+}
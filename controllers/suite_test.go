@@ -131,8 +131,11 @@ var _ = BeforeSuite(func() {
 
 	helmClient = mocks.NewMockClient(gomock.NewController(&TestReporter{}))
 
-	helmClient.EXPECT().InstallOrUpgradeHelmRelease(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(helmReleaseDeployed, nil).AnyTimes()
+	helmClient.EXPECT().InstallOrUpgradeHelmRelease(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(helmReleaseDeployed, nil).AnyTimes()
 	helmClient.EXPECT().GetHelmRelease(gomock.Any(), gomock.Any(), gomock.Any()).Return(&helmRelease.Release{}, nil).AnyTimes()
+	helmClient.EXPECT().LabelReleaseResources(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	helmClient.EXPECT().GetChartSBOMs(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	helmClient.EXPECT().ResolveChartDigest(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return("", nil).AnyTimes()
 	helmClient.EXPECT().UninstallHelmRelease(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_, _, _ any) (*helmRelease.UninstallReleaseResponse, error) {
 		if failedHelmUninstall {
 			return nil, errors.New(releaseFailedMessage)
@@ -143,8 +146,9 @@ var _ = BeforeSuite(func() {
 	).AnyTimes()
 
 	err = (&helmchartproxy.HelmChartProxyReconciler{
-		Client: k8sManager.GetClient(),
-		Scheme: k8sManager.GetScheme(),
+		Client:   k8sManager.GetClient(),
+		Scheme:   k8sManager.GetScheme(),
+		Recorder: k8sManager.GetEventRecorderFor("helmchartproxy-controller"),
 	}).SetupWithManager(ctx, k8sManager, controller.Options{})
 	Expect(err).ToNot(HaveOccurred())
 
@@ -152,6 +156,7 @@ var _ = BeforeSuite(func() {
 		Client:     k8sManager.GetClient(),
 		Scheme:     k8sManager.GetScheme(),
 		HelmClient: helmClient,
+		Recorder:   k8sManager.GetEventRecorderFor("helmreleaseproxy-controller"),
 	}).SetupWithManager(ctx, k8sManager, controller.Options{})
 	Expect(err).ToNot(HaveOccurred())
 
@@ -0,0 +1,165 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	helmRelease "helm.sh/helm/v3/pkg/release"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// AuditOperationInstall is the operation of an audit record of a Helm install.
+	AuditOperationInstall = "install"
+
+	// AuditOperationUpgrade is the operation of an audit record of a Helm upgrade.
+	AuditOperationUpgrade = "upgrade"
+
+	// AuditOperationUninstall is the operation of an audit record of a Helm uninstall.
+	AuditOperationUninstall = "uninstall"
+
+	// AuditOperationRollback is the operation of an audit record of a Helm rollback.
+	AuditOperationRollback = "rollback"
+
+	// AuditOperationDriftCorrection is the operation of an audit record of the re-apply of the drifted resources of a Helm
+	// release.
+	AuditOperationDriftCorrection = "drift-correction"
+
+	// AuditResultSucceeded is the result of an audit record of a Helm operation that succeeded.
+	AuditResultSucceeded = "succeeded"
+
+	// AuditResultFailed is the result of an audit record of a Helm operation that failed.
+	AuditResultFailed = "failed"
+)
+
+// AuditRecord is a structured record of a mutation of a Helm release on a workload Cluster.
+type AuditRecord struct {
+	// Time is the time the operation completed.
+	Time time.Time `json:"time"`
+
+	// Actor is the identity of the controller that performed the operation.
+	Actor string `json:"actor"`
+
+	// HelmChartProxy and HelmReleaseProxy are the namespaced names of the objects the operation was performed for.
+	HelmChartProxy   string `json:"helmChartProxy,omitempty"`
+	HelmReleaseProxy string `json:"helmReleaseProxy,omitempty"`
+
+	// Operation is the Helm operation, e.g. install or upgrade.
+	Operation string `json:"operation"`
+
+	// Cluster is the namespaced name of the workload Cluster.
+	Cluster string `json:"cluster"`
+
+	ReleaseName      string `json:"releaseName"`
+	ReleaseNamespace string `json:"releaseNamespace"`
+	Chart            string `json:"chart"`
+	RepoURL          string `json:"repoURL,omitempty"`
+	Version          string `json:"version,omitempty"`
+
+	// Revision is the revision of the Helm release after the operation, if any.
+	Revision int `json:"revision,omitempty"`
+
+	// Result is whether the operation succeeded or failed, and Error the error of a failed operation.
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// AuditLog writes an AuditRecord for every mutation of a Helm release as a line of JSON, e.g. to be shipped to a SIEM. A
+// nil AuditLog discards the records.
+type AuditLog struct {
+	mu    sync.Mutex
+	w     io.Writer
+	actor string
+	now   func() time.Time
+}
+
+// NewAuditLog returns an AuditLog writing to w with the given actor identity.
+func NewAuditLog(w io.Writer, actor string) *AuditLog {
+	return &AuditLog{w: w, actor: actor, now: time.Now}
+}
+
+// auditSubjectKey is the context key of the HelmReleaseProxy that Helm operations are performed for.
+type auditSubjectKey struct{}
+
+// WithAuditSubject returns a context recording the HelmReleaseProxy in the audit records of the Helm operations performed
+// with it.
+func WithAuditSubject(ctx context.Context, helmReleaseProxy *addonsv1alpha1.HelmReleaseProxy) context.Context {
+	return context.WithValue(ctx, auditSubjectKey{}, helmReleaseProxy)
+}
+
+// record writes the audit record of the Helm operation on the release of the spec. Failures to write are logged, as they
+// must not fail the operation that already happened.
+func (a *AuditLog) record(ctx context.Context, operation string, spec addonsv1alpha1.HelmReleaseProxySpec, release *helmRelease.Release, err error) {
+	if a == nil {
+		return
+	}
+
+	clusterNamespace := spec.ClusterRef.Namespace
+	record := AuditRecord{
+		Time:             a.now().UTC(),
+		Actor:            a.actor,
+		Operation:        operation,
+		ReleaseName:      spec.ReleaseName,
+		ReleaseNamespace: spec.ReleaseNamespace,
+		Chart:            spec.ChartName,
+		RepoURL:          spec.RepoURL,
+		Version:          spec.Version,
+		Result:           AuditResultSucceeded,
+	}
+	if helmReleaseProxy, ok := ctx.Value(auditSubjectKey{}).(*addonsv1alpha1.HelmReleaseProxy); ok {
+		record.HelmReleaseProxy = helmReleaseProxy.Namespace + "/" + helmReleaseProxy.Name
+		if name := helmReleaseProxy.Labels[addonsv1alpha1.HelmChartProxyLabelName]; name != "" {
+			record.HelmChartProxy = helmReleaseProxy.Namespace + "/" + name
+		}
+		if clusterNamespace == "" {
+			clusterNamespace = helmReleaseProxy.Namespace
+		}
+	}
+	record.Cluster = spec.ClusterRef.Name
+	if clusterNamespace != "" {
+		record.Cluster = clusterNamespace + "/" + spec.ClusterRef.Name
+	}
+	if release != nil {
+		record.ReleaseName = release.Name
+		record.Revision = release.Version
+		if release.Chart != nil && release.Chart.Metadata != nil {
+			record.Version = release.Chart.Metadata.Version
+		}
+	}
+	if err != nil {
+		record.Result = AuditResultFailed
+		record.Error = err.Error()
+	}
+
+	line, marshalErr := json.Marshal(record)
+	if marshalErr != nil {
+		ctrl.LoggerFrom(ctx).Error(marshalErr, "Failed to marshal audit record", "operation", operation, "release", record.ReleaseName)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, writeErr := a.w.Write(append(line, '\n')); writeErr != nil {
+		ctrl.LoggerFrom(ctx).Error(writeErr, "Failed to write audit record", "operation", operation, "release", record.ReleaseName)
+	}
+}
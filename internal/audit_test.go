@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart"
+	helmRelease "helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+)
+
+func TestAuditLogRecord(t *testing.T) {
+	g := NewWithT(t)
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	buf := &bytes.Buffer{}
+	auditLog := NewAuditLog(buf, "caaph-controller-manager-abc")
+	auditLog.now = func() time.Time { return now }
+
+	spec := addonsv1alpha1.HelmReleaseProxySpec{
+		ClusterRef:       corev1.ObjectReference{Name: "test-cluster"},
+		ReleaseNamespace: "test-namespace",
+		ChartName:        "test-chart",
+		RepoURL:          "https://charts.example.com",
+		Version:          "1.0.0",
+	}
+	ctx := WithAuditSubject(context.TODO(), &addonsv1alpha1.HelmReleaseProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "test-hrp",
+			Labels:    map[string]string{addonsv1alpha1.HelmChartProxyLabelName: "test-hcp"},
+		},
+	})
+
+	auditLog.record(ctx, AuditOperationInstall, spec, &helmRelease.Release{
+		Name:    "test-chart-12345",
+		Version: 1,
+		Chart:   &chart.Chart{Metadata: &chart.Metadata{Version: "1.0.0"}},
+	}, nil)
+	auditLog.record(context.TODO(), AuditOperationUpgrade, spec, nil, errors.New("timed out waiting for the condition"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	g.Expect(lines).To(HaveLen(2))
+
+	var installed, upgraded AuditRecord
+	g.Expect(json.Unmarshal([]byte(lines[0]), &installed)).To(Succeed())
+	g.Expect(installed).To(Equal(AuditRecord{
+		Time:             now,
+		Actor:            "caaph-controller-manager-abc",
+		HelmChartProxy:   "default/test-hcp",
+		HelmReleaseProxy: "default/test-hrp",
+		Operation:        AuditOperationInstall,
+		Cluster:          "default/test-cluster",
+		ReleaseName:      "test-chart-12345",
+		ReleaseNamespace: "test-namespace",
+		Chart:            "test-chart",
+		RepoURL:          "https://charts.example.com",
+		Version:          "1.0.0",
+		Revision:         1,
+		Result:           AuditResultSucceeded,
+	}))
+
+	g.Expect(json.Unmarshal([]byte(lines[1]), &upgraded)).To(Succeed())
+	g.Expect(upgraded.Operation).To(Equal(AuditOperationUpgrade))
+	g.Expect(upgraded.HelmReleaseProxy).To(BeEmpty())
+	g.Expect(upgraded.Cluster).To(Equal("test-cluster"))
+	g.Expect(upgraded.Result).To(Equal(AuditResultFailed))
+	g.Expect(upgraded.Error).To(Equal("timed out waiting for the condition"))
+
+	// A nil audit log discards the records.
+	var discarded *AuditLog
+	discarded.record(ctx, AuditOperationUninstall, spec, nil, nil)
+}
@@ -0,0 +1,336 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	orasregistry "oras.land/oras-go/pkg/registry"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// maxChartBundleSize is the maximum size of a bundle tarball, before and after it is decompressed.
+	maxChartBundleSize = 1 << 30
+
+	// maxChartBundleArchiveSize is the maximum size of a chart archive in a bundle tarball.
+	maxChartBundleArchiveSize = 64 << 20
+)
+
+// chartBundleDir is the directory the chart archives extracted from ChartBundles are written to, named by their digest.
+var chartBundleDir = filepath.Join(os.TempDir(), "chart-bundles")
+
+// chartBundleCacheKey returns the chart cache key of a chart version of a ChartBundle.
+func chartBundleCacheKey(ref addonsv1alpha1.ChartBundleReference, chartName, version string) string {
+	return chartCacheKey("chartbundle://"+ref.Namespace+"/"+ref.Name, chartName, version)
+}
+
+// ReadChartBundle returns the chart versions contained in the bundle tarball of the ChartBundle.
+func ReadChartBundle(ctx context.Context, c ctrlClient.Client, bundle *addonsv1alpha1.ChartBundle) ([]addonsv1alpha1.ChartBundleEntry, error) {
+	r, err := openChartBundle(ctx, c, bundle)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	entries := []addonsv1alpha1.ChartBundleEntry{}
+	err = walkChartBundle(r, func(name string, archive []byte) error {
+		chart, err := loader.LoadArchive(bytes.NewReader(archive))
+		if err != nil {
+			return errors.Wrapf(err, "failed to load chart archive %s", name)
+		}
+		entries = append(entries, addonsv1alpha1.ChartBundleEntry{
+			Name:    chart.Metadata.Name,
+			Version: chart.Metadata.Version,
+			Digest:  digest.FromBytes(archive).String(),
+		})
+
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read ChartBundle %s", bundle.Name)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Name != entries[j].Name {
+			return entries[i].Name < entries[j].Name
+		}
+		return entries[i].Version < entries[j].Version
+	})
+
+	return entries, nil
+}
+
+// ExtractChartBundleChart writes the chart archive of the HelmReleaseProxy spec from its ChartBundle to disk, so that it is
+// found by installs and upgrades. Archives already extracted are not extracted again, as they are named by their digest.
+func ExtractChartBundleChart(ctx context.Context, c ctrlClient.Client, spec addonsv1alpha1.HelmReleaseProxySpec) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	ref := ptr.Deref(spec.ChartBundleRef, addonsv1alpha1.ChartBundleReference{})
+	bundle := &addonsv1alpha1.ChartBundle{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, bundle); err != nil {
+		return errors.Wrapf(err, "failed to get ChartBundle %s/%s", ref.Namespace, ref.Name)
+	}
+
+	entry, ok := bundle.Entry(spec.ChartName, spec.Version)
+	if !ok {
+		return errors.Errorf("ChartBundle %s/%s does not contain chart %s version %s", ref.Namespace, ref.Name, spec.ChartName, spec.Version)
+	}
+	dgst, err := digest.Parse(entry.Digest)
+	if err != nil {
+		return errors.Wrapf(err, "invalid digest of chart %s version %s in ChartBundle %s/%s", spec.ChartName, spec.Version, ref.Namespace, ref.Name)
+	}
+
+	path := filepath.Join(chartBundleDir, dgst.Encoded()+".tgz")
+	if _, err := os.Stat(path); err != nil {
+		log.V(2).Info("Extracting chart from ChartBundle", "chartBundle", ref.Name, "chart", spec.ChartName, "version", spec.Version)
+		if err := extractChartBundleArchive(ctx, c, bundle, dgst, path); err != nil {
+			return err
+		}
+	}
+	defaultChartCache.set(chartBundleCacheKey(ref, spec.ChartName, spec.Version), path)
+
+	return nil
+}
+
+// extractChartBundleArchive writes the chart archive with the digest in the bundle tarball of the ChartBundle to the path.
+func extractChartBundleArchive(ctx context.Context, c ctrlClient.Client, bundle *addonsv1alpha1.ChartBundle, dgst digest.Digest, path string) error {
+	r, err := openChartBundle(ctx, c, bundle)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if err := os.MkdirAll(chartBundleDir, 0o755); err != nil {
+		return err
+	}
+
+	found := false
+	err = walkChartBundle(r, func(_ string, archive []byte) error {
+		if found || digest.FromBytes(archive) != dgst {
+			return nil
+		}
+		found = true
+
+		// Write to a temporary file first so that a concurrent reconcile never sees a partially written archive.
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, archive, 0o600); err != nil {
+			return err
+		}
+
+		return os.Rename(tmp, path)
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to extract chart archive %s from ChartBundle %s", dgst, bundle.Name)
+	}
+	if !found {
+		return errors.Errorf("ChartBundle %s no longer contains chart archive %s", bundle.Name, dgst)
+	}
+
+	return nil
+}
+
+// openChartBundle returns a reader of the bundle tarball of the ChartBundle from its source.
+func openChartBundle(ctx context.Context, c ctrlClient.Client, bundle *addonsv1alpha1.ChartBundle) (io.ReadCloser, error) {
+	source := bundle.Spec.Source
+	switch {
+	case source.Secret != nil:
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: bundle.Namespace, Name: source.Secret.Name}, secret); err != nil {
+			return nil, errors.Wrapf(err, "failed to get Secret %s of ChartBundle %s", source.Secret.Name, bundle.Name)
+		}
+		data, ok := secret.Data[source.Secret.Key]
+		if !ok {
+			return nil, errors.Errorf("Secret %s of ChartBundle %s has no key %s", source.Secret.Name, bundle.Name, source.Secret.Key)
+		}
+
+		return io.NopCloser(bytes.NewReader(data)), nil
+	case source.OCI != nil:
+		return openOCIChartBundle(ctx, c, bundle.Namespace, source.OCI)
+	default:
+		return nil, errors.Errorf("ChartBundle %s has no source", bundle.Name)
+	}
+}
+
+// openOCIChartBundle downloads the only layer of the OCI artifact of the source to a temporary file and returns a reader
+// of it, removing the file when closed.
+func openOCIChartBundle(ctx context.Context, c ctrlClient.Client, namespace string, source *addonsv1alpha1.ChartBundleOCISource) (io.ReadCloser, error) {
+	ref, err := parseOCIReference(source.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	var config []byte
+	if source.Credentials != nil {
+		secretNamespace := source.Credentials.Secret.Namespace
+		if secretNamespace == "" {
+			secretNamespace = namespace
+		}
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: secretNamespace, Name: source.Credentials.Secret.Name}, secret); err != nil {
+			return nil, errors.Wrapf(err, "failed to get credentials Secret %s/%s", secretNamespace, source.Credentials.Secret.Name)
+		}
+		key := source.Credentials.Key
+		if key == "" {
+			key = addonsv1alpha1.DefaultOCIKey
+		}
+		config = secret.Data[key]
+	}
+	creds, err := registryCredentialsFromConfig(config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse credentials of %s", source.URL)
+	}
+
+	client, err := newHTTPClient("", RepositoryAuth{}, source.InsecureSkipTLSVerify)
+	if err != nil {
+		return nil, err
+	}
+	repo := newOCIRepositoryWithClient(ref.Registry, ref.Repository, client, creds)
+
+	manifest, _, err := repo.manifest(ctx, ref.Reference)
+	if err != nil {
+		return nil, err
+	}
+	if len(manifest.Layers) != 1 {
+		return nil, errors.Errorf("OCI artifact %s must have exactly one layer, found %d", source.URL, len(manifest.Layers))
+	}
+	if manifest.Layers[0].Size > maxChartBundleSize {
+		return nil, errors.Errorf("layer of OCI artifact %s exceeds %d bytes", source.URL, maxChartBundleSize)
+	}
+
+	f, err := os.CreateTemp("", "chart-bundle-*.tar")
+	if err != nil {
+		return nil, err
+	}
+	if err := repo.fetchBlob(ctx, manifest.Layers[0], f); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+
+	return &tempFileReader{File: f}, nil
+}
+
+// tempFileReader is a temporary file that is removed when closed.
+type tempFileReader struct {
+	*os.File
+}
+
+// Close closes and removes the file.
+func (f *tempFileReader) Close() error {
+	err := f.File.Close()
+	if removeErr := os.Remove(f.Name()); removeErr != nil && err == nil {
+		err = removeErr
+	}
+
+	return err
+}
+
+// parseOCIReference parses an oci:// URL into its registry host, repository name and tag or digest. The tag defaults to
+// latest.
+func parseOCIReference(url string) (orasregistry.Reference, error) {
+	ref, err := orasregistry.ParseReference(strings.TrimPrefix(url, "oci://"))
+	if err != nil {
+		return orasregistry.Reference{}, errors.Wrapf(err, "invalid OCI reference %s", url)
+	}
+	if ref.Reference == "" {
+		ref.Reference = "latest"
+	}
+
+	return ref, nil
+}
+
+// walkChartBundle calls fn with the content of each chart archive in the bundle tarball. The tarball may be gzipped. It
+// fails once more than maxChartBundleSize bytes are decompressed or if a chart archive exceeds maxChartBundleArchiveSize,
+// so that a small compressed bundle cannot exhaust the memory of the controller.
+func walkChartBundle(r io.Reader, fn func(name string, archive []byte) error) error {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gzr, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer gzr.Close()
+		r = gzr
+	} else {
+		r = br
+	}
+
+	tr := tar.NewReader(&maxSizeReader{r: r, remaining: maxChartBundleSize})
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg || !strings.HasSuffix(header.Name, ".tgz") {
+			continue
+		}
+		if header.Size > maxChartBundleArchiveSize {
+			return errors.Errorf("chart archive %s exceeds %d bytes", header.Name, maxChartBundleArchiveSize)
+		}
+
+		// The tar reader returns no more than the size in the header of the entry.
+		archive, err := io.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		if err := fn(header.Name, archive); err != nil {
+			return err
+		}
+	}
+}
+
+// maxSizeReader reads from r, failing once more than remaining bytes have been read.
+type maxSizeReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (m *maxSizeReader) Read(p []byte) (int, error) {
+	n, err := m.r.Read(p)
+	m.remaining -= int64(n)
+	if m.remaining < 0 {
+		return n, errors.New("bundle tarball exceeds the maximum size")
+	}
+
+	return n, err
+}
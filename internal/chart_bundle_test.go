@@ -0,0 +1,179 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/opencontainers/go-digest"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// newChartBundleTarball returns a gzipped bundle tarball of chart archives of the given chart versions, keyed by name, and
+// the digests of the chart archives.
+func newChartBundleTarball(t *testing.T, charts map[string]string) ([]byte, map[string]string) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+	digests := map[string]string{}
+	for name, version := range charts {
+		path, err := chartutil.Save(&chart.Chart{Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: name, Version: version}}, t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		archive, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		digests[name] = digest.FromBytes(archive).String()
+
+		if err := tw.WriteHeader(&tar.Header{Name: "charts/" + filepath.Base(path), Mode: 0o644, Size: int64(len(archive)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(archive); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes(), digests
+}
+
+func TestChartBundle(t *testing.T) {
+	g := NewWithT(t)
+
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = addonsv1alpha1.AddToScheme(scheme)
+
+	tarball, digests := newChartBundleTarball(t, map[string]string{"nginx": "1.2.3", "cert-manager": "1.14.4"})
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "bundle", Namespace: "default"},
+		Data:       map[string][]byte{"bundle.tar.gz": tarball},
+	}
+	bundle := &addonsv1alpha1.ChartBundle{
+		ObjectMeta: metav1.ObjectMeta{Name: "addons", Namespace: "default"},
+		Spec: addonsv1alpha1.ChartBundleSpec{
+			Source: addonsv1alpha1.ChartBundleSource{
+				Secret: &addonsv1alpha1.ChartBundleSecretSource{Name: "bundle", Key: "bundle.tar.gz"},
+			},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret, bundle).WithStatusSubresource(bundle).Build()
+
+	entries, err := ReadChartBundle(context.TODO(), c, bundle)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(entries).To(Equal([]addonsv1alpha1.ChartBundleEntry{
+		{Name: "cert-manager", Version: "1.14.4", Digest: digests["cert-manager"]},
+		{Name: "nginx", Version: "1.2.3", Digest: digests["nginx"]},
+	}))
+
+	bundle.Status.Charts = entries
+	g.Expect(c.Status().Update(context.TODO(), bundle)).To(Succeed())
+
+	chartBundleDir = t.TempDir()
+	spec := addonsv1alpha1.HelmReleaseProxySpec{
+		ChartBundleRef: &addonsv1alpha1.ChartBundleReference{Name: "addons", Namespace: "default"},
+		ChartName:      "nginx",
+		Version:        "1.2.3",
+	}
+	g.Expect(ExtractChartBundleChart(context.TODO(), c, spec)).To(Succeed())
+
+	path, err := locateChart(context.TODO(), nil, spec.ChartName, nil, spec, "", "", RepositoryAuth{}, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	archive, err := os.ReadFile(path)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(digest.FromBytes(archive).String()).To(Equal(digests["nginx"]))
+
+	spec.Version = "9.9.9"
+	g.Expect(ExtractChartBundleChart(context.TODO(), c, spec)).To(MatchError(ContainSubstring("does not contain chart nginx version 9.9.9")))
+}
+
+func TestWalkChartBundleLimits(t *testing.T) {
+	g := NewWithT(t)
+
+	// The size in the header of a chart archive is checked before its content is read.
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	g.Expect(tw.WriteHeader(&tar.Header{Name: "charts/large-1.0.0.tgz", Mode: 0o644, Size: maxChartBundleArchiveSize + 1, Typeflag: tar.TypeReg})).To(Succeed())
+	g.Expect(tw.Flush()).NotTo(Succeed())
+	err := walkChartBundle(&buf, func(string, []byte) error { return nil })
+	g.Expect(err).To(MatchError(ContainSubstring("chart archive charts/large-1.0.0.tgz exceeds")))
+
+	r := &maxSizeReader{r: bytes.NewReader(make([]byte, 10)), remaining: 5}
+	_, err = io.ReadAll(r)
+	g.Expect(err).To(MatchError(ContainSubstring("exceeds the maximum size")))
+
+	r = &maxSizeReader{r: bytes.NewReader(make([]byte, 10)), remaining: 10}
+	b, err := io.ReadAll(r)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(b).To(HaveLen(10))
+}
+
+func TestParseOCIReference(t *testing.T) {
+	testcases := []struct {
+		url            string
+		wantHost       string
+		wantRepository string
+		wantReference  string
+		wantErr        bool
+	}{
+		{url: "oci://registry.local:5000/bundles/addons:v1", wantHost: "registry.local:5000", wantRepository: "bundles/addons", wantReference: "v1"},
+		{url: "oci://registry.local/bundles/addons@" + digest.FromString("bundle").String(), wantHost: "registry.local", wantRepository: "bundles/addons", wantReference: digest.FromString("bundle").String()},
+		{url: "oci://registry.local/addons", wantHost: "registry.local", wantRepository: "addons", wantReference: "latest"},
+		{url: "oci://registry.local", wantErr: true},
+		{url: "oci://registry.local/bundles/addons@sha256:abc", wantErr: true},
+		{url: "oci://registry.local/Bundles/addons:v1", wantErr: true},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.url, func(t *testing.T) {
+			g := NewWithT(t)
+
+			ref, err := parseOCIReference(tc.url)
+			if tc.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(ref.Registry).To(Equal(tc.wantHost))
+			g.Expect(ref.Repository).To(Equal(tc.wantRepository))
+			g.Expect(ref.Reference).To(Equal(tc.wantReference))
+		})
+	}
+}
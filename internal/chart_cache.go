@@ -0,0 +1,312 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	helmAction "helm.sh/helm/v3/pkg/action"
+	helmCli "helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/registry"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// chartCache remembers where charts with a pinned version were downloaded, so that later installs and upgrades of the same
+// chart do not fetch the repository index and chart archive again.
+type chartCache struct {
+	mu      sync.Mutex
+	paths   map[string]string
+	warming map[string]struct{}
+}
+
+var defaultChartCache = &chartCache{
+	paths:   map[string]string{},
+	warming: map[string]struct{}{},
+}
+
+// chartCacheKey returns the cache key of a chart version in a repository.
+func chartCacheKey(repoURL, chartName, version string) string {
+	return repoURL + "/" + chartName + "@" + version
+}
+
+// pinnedChartVersion returns the version a pinned chart is cached by, i.e. its version followed by the digest it is pinned
+// to, if any.
+func pinnedChartVersion(version, dgst string) string {
+	if dgst == "" {
+		return version
+	}
+
+	return version + "@" + dgst
+}
+
+// isChartPinned returns true if the chart of the spec is pinned to an exact version or to a digest.
+func isChartPinned(version, dgst string) bool {
+	return dgst != "" || (version != "" && !addonsv1alpha1.IsVersionConstraint(version))
+}
+
+// get returns the cached path of a chart if it is still present on disk.
+func (c *chartCache) get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	path, ok := c.paths[key]
+	if !ok {
+		return "", false
+	}
+	if _, err := os.Stat(path); err != nil {
+		delete(c.paths, key)
+		return "", false
+	}
+
+	return path, true
+}
+
+// set caches the path of a chart.
+func (c *chartCache) set(key, path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.paths[key] = path
+}
+
+// startWarming returns true if the chart is neither cached nor already being warmed, and marks it as being warmed.
+func (c *chartCache) startWarming(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.paths[key]; ok {
+		return false
+	}
+	if _, ok := c.warming[key]; ok {
+		return false
+	}
+	c.warming[key] = struct{}{}
+
+	return true
+}
+
+// stopWarming unmarks the chart as being warmed.
+func (c *chartCache) stopWarming(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.warming, key)
+}
+
+// locateChart returns the path of a chart, verifying its signature with the keys of the RepositoryAuth if it has any. A
+// chart failing verification returns a ChartVerificationError. Charts are fetched through the circuit breaker.
+func locateChart(ctx context.Context, pathOptions *helmAction.ChartPathOptions, chartName string, settings *helmCli.EnvSettings, spec addonsv1alpha1.HelmReleaseProxySpec, credentialsPath, caFilePath string, repositoryAuth RepositoryAuth, breaker *RegistryBreaker) (string, error) {
+	path, err := locateChartArchive(ctx, pathOptions, chartName, settings, spec, credentialsPath, caFilePath, repositoryAuth, breaker)
+	if err != nil {
+		return "", err
+	}
+	if err := verifyChart(ctx, path, spec, credentialsPath, caFilePath, repositoryAuth); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// locateChartArchive returns the path of a chart, using the cached download if the chart version is pinned and was located
+// before. Charts are not fetched while the circuit of their registry is open, in which case the last located chart is used
+// for charts without a pinned version. A version constraint is not pinned, as the latest matching version may change.
+// Charts of Git repositories are cached by the commit their ref resolves to.
+// Pinned OCI charts whose manifest Helm cannot pull are downloaded by selecting their chart layer directly, and so are OCI
+// charts pinned by digest, which are pulled by their digest. Charts of HTTP
+// repositories are located with the RepositoryAuth if it is not empty or their provenance is verified.
+func locateChartArchive(ctx context.Context, pathOptions *helmAction.ChartPathOptions, chartName string, settings *helmCli.EnvSettings, spec addonsv1alpha1.HelmReleaseProxySpec, credentialsPath, caFilePath string, repositoryAuth RepositoryAuth, breaker *RegistryBreaker) (string, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	if spec.ChartBundleRef != nil {
+		path, ok := defaultChartCache.get(chartBundleCacheKey(*spec.ChartBundleRef, spec.ChartName, spec.Version))
+		if !ok {
+			return "", errors.Errorf("chart %s version %s has not been extracted from ChartBundle %s", spec.ChartName, spec.Version, spec.ChartBundleRef.Name)
+		}
+
+		return path, nil
+	}
+
+	if spec.Git != nil {
+		host := registryHost(spec.Git.URL)
+		if err := breaker.allow(host); err != nil {
+			return "", err
+		}
+		path, err := fetchWithTimeout(spec.Options.FetchTimeout, chartName, func() (string, error) {
+			return locateGitChart(ctx, spec, caFilePath, repositoryAuth)
+		})
+		breaker.record(host, err)
+
+		return path, err
+	}
+
+	if spec.Digest != "" && !registry.IsOCI(spec.RepoURL) {
+		return "", errors.Errorf("chart %s cannot be pinned by digest, as it is not pulled from an OCI registry", spec.ChartName)
+	}
+
+	// Helm presents the client certificate to HTTP chart repositories, the registry client to OCI registries.
+	pathOptions.CertFile = repositoryAuth.CertFile
+	pathOptions.KeyFile = repositoryAuth.KeyFile
+	locate := func() (string, error) {
+		if (!repositoryAuth.isEmpty() || repositoryAuth.verifiesProvenance()) && !registry.IsOCI(spec.RepoURL) {
+			return locateHTTPChart(ctx, pathOptions, chartName, settings, repositoryAuth, caFilePath, ptr.Deref(spec.TLSConfig, addonsv1alpha1.TLSConfig{}).InsecureSkipTLSVerify)
+		}
+
+		return pathOptions.LocateChart(chartName, settings)
+	}
+
+	// Fetches go through the circuit breaker of the registry, so that they fail fast while it is failing.
+	host := registryHost(spec.RepoURL)
+	fetch := func(locate func() (string, error)) (string, error) {
+		if err := breaker.allow(host); err != nil {
+			return "", err
+		}
+		path, err := fetchWithTimeout(spec.Options.FetchTimeout, chartName, locate)
+		breaker.record(host, err)
+
+		return path, err
+	}
+
+	if !isChartPinned(spec.Version, spec.Digest) {
+		// The last located chart is only used while the circuit of the registry is open, as the latest version may have
+		// changed since.
+		key := chartCacheKey(spec.RepoURL, spec.ChartName, spec.Version)
+		path, err := fetch(locate)
+		var unavailableErr *RegistryUnavailableError
+		if errors.As(err, &unavailableErr) {
+			if cached, ok := defaultChartCache.get(key); ok {
+				log.Info("Registry is unavailable, using the last located chart", "chart", spec.ChartName, "registry", host, "path", cached)
+				return cached, nil
+			}
+		}
+		if err != nil {
+			return "", err
+		}
+		defaultChartCache.set(key, path)
+
+		return path, nil
+	}
+
+	// Charts located before their provenance was verified are located again to download their provenance file.
+	key := chartCacheKey(spec.RepoURL, spec.ChartName, pinnedChartVersion(spec.Version, spec.Digest))
+	if path, ok := defaultChartCache.get(key); ok && (!repositoryAuth.verifiesProvenance() || hasProvenanceFile(path)) {
+		return path, nil
+	}
+
+	path, err := fetch(func() (string, error) {
+		// Helm pulls the provenance along with the chart and buffers both in memory, so pinned OCI charts are streamed to disk
+		// by pulling only their chart layer, unless the provenance is needed to verify the chart. This also pulls charts
+		// pushed as custom artifact types, e.g. with ORAS, whose media types Helm does not accept.
+		if registry.IsOCI(spec.RepoURL) && !pathOptions.Verify {
+			log.V(2).Info("Pulling chart layer of OCI chart", "chart", spec.ChartName, "version", spec.Version)
+			if err := os.MkdirAll(settings.RepositoryCache, 0o755); err != nil {
+				return "", err
+			}
+
+			return pullOCIChartLayer(ctx, spec, credentialsPath, caFilePath, repositoryAuth, settings.RepositoryCache)
+		}
+
+		return locate()
+	})
+	if err != nil {
+		return "", err
+	}
+	defaultChartCache.set(key, path)
+
+	return path, nil
+}
+
+// fetchWithTimeout returns the result of locate, or an error wrapping context.DeadlineExceeded if it does not return within
+// the fetch timeout. Helm does not allow cancelling the download of a chart, so it keeps running in the background after
+// the timeout and its result is discarded.
+func fetchWithTimeout(fetchTimeout *metav1.Duration, chartName string, locate func() (string, error)) (string, error) {
+	if fetchTimeout == nil || fetchTimeout.Duration <= 0 {
+		return locate()
+	}
+
+	type result struct {
+		path string
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		path, err := locate()
+		done <- result{path: path, err: err}
+	}()
+
+	timer := time.NewTimer(fetchTimeout.Duration)
+	defer timer.Stop()
+
+	select {
+	case r := <-done:
+		return r.path, r.err
+	case <-timer.C:
+		return "", errors.Wrapf(context.DeadlineExceeded, "timed out after %s fetching chart %s", fetchTimeout.Duration, chartName)
+	}
+}
+
+// WarmupChart downloads the chart of a HelmChartProxy in the background so that the first install on a Cluster does not wait
+// for it. Only charts of chart repositories with a pinned version or digest that do not require credentials, a custom CA
+// certificate or a client certificate are warmed up. The chart is fetched through the circuit breaker.
+func WarmupChart(ctx context.Context, spec addonsv1alpha1.HelmChartProxySpec, breaker *RegistryBreaker) {
+	log := ctrl.LoggerFrom(ctx)
+
+	tlsConfig := ptr.Deref(spec.TLSConfig, addonsv1alpha1.TLSConfig{})
+	if !isChartPinned(spec.Version, spec.Digest) || spec.ChartBundleRef != nil || spec.Git != nil || spec.Credentials != nil || spec.RepositoryHeaders != nil || spec.RepositoryCredentials != nil || tlsConfig.CASecretRef != nil || tlsConfig.CertManagerRef != nil {
+		return
+	}
+
+	key := chartCacheKey(spec.RepoURL, spec.ChartName, pinnedChartVersion(spec.Version, spec.Digest))
+	if !defaultChartCache.startWarming(key) {
+		return
+	}
+
+	go func() {
+		defer defaultChartCache.stopWarming(key)
+
+		registryClient, err := newDefaultRegistryClient("", false, "", RepositoryAuth{}, ptr.Deref(spec.TLSConfig, addonsv1alpha1.TLSConfig{}).InsecureSkipTLSVerify)
+		if err != nil {
+			log.Error(err, "Failed to create registry client for chart warmup", "chart", spec.ChartName)
+			return
+		}
+
+		chartName, repoURL, err := getHelmChartAndRepoName(spec.ChartName, spec.RepoURL)
+		if err != nil {
+			log.Error(err, "Failed to get chart and repo name for chart warmup", "chart", spec.ChartName)
+			return
+		}
+
+		installClient := helmAction.NewInstall(&helmAction.Configuration{})
+		installClient.SetRegistryClient(registryClient)
+		installClient.RepoURL = repoURL
+		installClient.Version = spec.Version
+
+		releaseSpec := addonsv1alpha1.HelmReleaseProxySpec{RepoURL: spec.RepoURL, ChartName: spec.ChartName, Version: spec.Version, Digest: spec.Digest, TLSConfig: spec.TLSConfig}
+		path, err := locateChart(ctx, &installClient.ChartPathOptions, chartName, helmCli.New(), releaseSpec, "", "", RepositoryAuth{}, breaker)
+		if err != nil {
+			log.Error(err, "Failed to warm up chart", "chart", spec.ChartName, "version", spec.Version)
+			return
+		}
+		log.V(2).Info("Warmed up chart", "chart", spec.ChartName, "version", spec.Version, "path", path)
+	}()
+}
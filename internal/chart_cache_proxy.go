@@ -0,0 +1,478 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/singleflight"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// chartCacheProxyManifestTTL is the duration for which the manifest a tag resolves to is served from the cache before the
+// upstream registry is asked again, as tags can be moved. Manifests referenced by digest never change and are cached for
+// the lifetime of the proxy.
+const chartCacheProxyManifestTTL = 5 * time.Minute
+
+// chartCacheProxyFetchTimeout is the timeout of fetching a manifest or blob from an upstream registry.
+const chartCacheProxyFetchTimeout = 5 * time.Minute
+
+// maxChartCacheProxyManifestSize is the maximum size of a manifest fetched from an upstream registry.
+const maxChartCacheProxyManifestSize = 4 << 20
+
+// maxChartCacheProxyManifestsSize is the maximum total size of the manifests cached in memory. The manifests fetched the
+// longest time ago are evicted first.
+const maxChartCacheProxyManifestsSize = 64 << 20
+
+// chartCacheProxyManifestMediaTypes are the media types of the manifests served by the chart cache proxy.
+var chartCacheProxyManifestMediaTypes = []string{
+	ocispec.MediaTypeImageManifest,
+	ocispec.MediaTypeImageIndex,
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+}
+
+// cachedManifest is a manifest served by the chart cache proxy.
+type cachedManifest struct {
+	mediaType string
+	body      []byte
+	digest    digest.Digest
+	fetched   time.Time
+}
+
+// ChartCacheProxy is a read-only, pull-through cache of the OCI distribution API for chart artifacts. Chart manifests and
+// blobs of upstream registries are served at /v2/<registry host>/<repository>/, so a chart at
+// oci://registry.example.com/charts/nginx is pulled from oci://<proxy address>/registry.example.com/charts/nginx over
+// plain HTTP. Blobs are stored on disk by digest, so each blob is fetched from the upstream registry once however many
+// components pull it. Only registries allowing anonymous pulls are proxied, and only if they are allowed, so that the
+// proxy cannot be used to reach other hosts.
+type ChartCacheProxy struct {
+	// Addr is the address the proxy binds to.
+	Addr string
+
+	// Dir is the directory the blobs are stored in.
+	Dir string
+
+	// AllowedRegistries are the hosts, with their port if they have one, of the upstream registries the proxy serves charts
+	// of. Requests for other registries are rejected.
+	AllowedRegistries []string
+
+	// MaxSize is the maximum total size in bytes of the blobs stored in Dir. The blobs served the longest time ago are
+	// removed once it is exceeded, and blobs larger than it are not served.
+	MaxSize int64
+
+	// upstreamClient is the HTTP client used to connect to upstream registries.
+	upstreamClient *http.Client
+
+	// upstreamPlainHTTP connects to upstream registries over HTTP instead of HTTPS.
+	upstreamPlainHTTP bool
+
+	// maxManifestsSize is the maximum total size of the manifests cached in memory.
+	maxManifestsSize int
+
+	log           logr.Logger
+	fetches       singleflight.Group
+	mu            sync.Mutex
+	manifests     map[string]*cachedManifest
+	manifestsSize int
+	blobsMu       sync.Mutex
+}
+
+// NewChartCacheProxy returns a ChartCacheProxy binding to the address and storing at most maxSize bytes of blobs in the
+// directory, for the allowed upstream registries.
+func NewChartCacheProxy(addr, dir string, allowedRegistries []string, maxSize int64) (*ChartCacheProxy, error) {
+	if len(allowedRegistries) == 0 {
+		return nil, errors.New("the chart cache proxy requires at least one allowed registry")
+	}
+	if maxSize <= 0 {
+		return nil, errors.Errorf("the maximum size of the chart cache must be positive, got %d", maxSize)
+	}
+	client, err := newHTTPClient("", RepositoryAuth{}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChartCacheProxy{
+		Addr:              addr,
+		Dir:               dir,
+		AllowedRegistries: allowedRegistries,
+		MaxSize:           maxSize,
+		upstreamClient:    client,
+		maxManifestsSize:  maxChartCacheProxyManifestsSize,
+		log:               logr.Discard(),
+		manifests:         map[string]*cachedManifest{},
+	}, nil
+}
+
+// NeedLeaderElection returns false, so that every replica of the controller serves its co-located components.
+func (p *ChartCacheProxy) NeedLeaderElection() bool {
+	return false
+}
+
+// Start serves the proxy until the context is done.
+func (p *ChartCacheProxy) Start(ctx context.Context) error {
+	p.log = ctrl.LoggerFrom(ctx).WithName("chart-cache-proxy")
+
+	if err := os.MkdirAll(filepath.Join(p.Dir, "blobs"), 0o755); err != nil {
+		return errors.Wrapf(err, "failed to create chart cache directory %s", p.Dir)
+	}
+
+	listener, err := net.Listen("tcp", p.Addr)
+	if err != nil {
+		return errors.Wrapf(err, "failed to listen on %s", p.Addr)
+	}
+
+	server := &http.Server{
+		Handler:           p,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	p.log.Info("Serving chart cache proxy", "address", listener.Addr().String(), "dir", p.Dir)
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// ServeHTTP serves the manifests and blobs of the OCI distribution API.
+func (p *ChartCacheProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "the chart cache proxy is read-only", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Docker-Distribution-API-Version", "registry/2.0")
+	if r.URL.Path == "/v2/" || r.URL.Path == "/v2" {
+		return
+	}
+
+	host, name, kind, reference, ok := parseChartCacheProxyPath(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if !p.isAllowedRegistry(host) {
+		http.Error(w, fmt.Sprintf("registry %s is not allowed by the chart cache proxy", host), http.StatusForbidden)
+		return
+	}
+
+	repo := newOCIRepositoryWithClient(host, name, p.upstreamClient, func(string) (string, string, error) { return "", "", nil })
+	repo.plainHTTP = p.upstreamPlainHTTP
+
+	switch kind {
+	case "manifests":
+		p.serveManifest(w, r, repo, reference)
+	default:
+		p.serveBlob(w, r, repo, reference)
+	}
+}
+
+// isAllowedRegistry returns true if the upstream registry host is one of the AllowedRegistries. Hosts are compared case
+// insensitively, as registry hosts are DNS names.
+func (p *ChartCacheProxy) isAllowedRegistry(host string) bool {
+	return slices.ContainsFunc(p.AllowedRegistries, func(allowed string) bool {
+		return strings.EqualFold(allowed, host)
+	})
+}
+
+// fetchContext returns the context of a fetch from an upstream registry started by a request. The fetch is shared with
+// concurrent requests for the same object, so it is not cancelled with the request that started it.
+func fetchContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), chartCacheProxyFetchTimeout)
+}
+
+// parseChartCacheProxyPath returns the upstream registry host, repository name, kind of object, i.e. manifests or blobs,
+// and reference of a request path of the form /v2/<host>/<name>/<kind>/<reference>.
+func parseChartCacheProxyPath(urlPath string) (host, name, kind, reference string, ok bool) {
+	rest, ok := strings.CutPrefix(urlPath, "/v2/")
+	if !ok {
+		return "", "", "", "", false
+	}
+
+	for _, kind := range []string{"manifests", "blobs"} {
+		i := strings.LastIndex(rest, "/"+kind+"/")
+		if i < 0 {
+			continue
+		}
+		repository, reference := rest[:i], rest[i+len(kind)+2:]
+		host, name, ok := strings.Cut(repository, "/")
+		if !ok || host == "" || name == "" || reference == "" || strings.Contains(reference, "/") {
+			return "", "", "", "", false
+		}
+
+		return host, name, kind, reference, true
+	}
+
+	return "", "", "", "", false
+}
+
+// serveManifest serves the manifest of the reference, fetching it from the upstream registry unless it is cached. A tag is
+// served from the cache for the manifest TTL, and for longer if the upstream registry is unavailable.
+func (p *ChartCacheProxy) serveManifest(w http.ResponseWriter, r *http.Request, repo *ociRepository, reference string) {
+	_, err := digest.Parse(reference)
+	byDigest := err == nil
+	key := repo.host + "/" + repo.name + ":" + reference
+	if byDigest {
+		key = repo.host + "/" + repo.name + "@" + reference
+	}
+
+	p.mu.Lock()
+	manifest, cached := p.manifests[key]
+	p.mu.Unlock()
+
+	result := "hit"
+	if !cached || (!byDigest && time.Since(manifest.fetched) > chartCacheProxyManifestTTL) {
+		result = "miss"
+		fetched, err, _ := p.fetches.Do("manifest "+key, func() (interface{}, error) {
+			ctx, cancel := fetchContext(r.Context())
+			defer cancel()
+
+			return p.fetchManifest(ctx, repo, reference)
+		})
+		switch {
+		case err == nil && fetched.(*cachedManifest) == nil:
+			recordChartCacheProxyRequest("manifest", "miss")
+			http.NotFound(w, r)
+			return
+		case err == nil:
+			manifest = fetched.(*cachedManifest)
+			p.storeManifest(key, manifest)
+		case cached:
+			p.log.Info("Upstream registry is unavailable, serving cached manifest", "reference", key, "error", err.Error())
+			result = "stale"
+		default:
+			p.log.Error(err, "Failed to fetch manifest", "reference", key)
+			recordChartCacheProxyRequest("manifest", "error")
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+	}
+	recordChartCacheProxyRequest("manifest", result)
+
+	w.Header().Set("Content-Type", manifest.mediaType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(manifest.body)))
+	w.Header().Set("Docker-Content-Digest", manifest.digest.String())
+	if r.Method == http.MethodGet {
+		_, _ = w.Write(manifest.body)
+	}
+}
+
+// storeManifest caches the manifest under the key, evicting the manifests fetched the longest time ago until the cached
+// manifests fit in the maximum size again.
+func (p *ChartCacheProxy) storeManifest(key string, manifest *cachedManifest) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if previous, ok := p.manifests[key]; ok {
+		p.manifestsSize -= len(previous.body)
+	}
+	p.manifests[key] = manifest
+	p.manifestsSize += len(manifest.body)
+
+	for p.manifestsSize > p.maxManifestsSize && len(p.manifests) > 1 {
+		oldestKey := ""
+		for k, m := range p.manifests {
+			if k != key && (oldestKey == "" || m.fetched.Before(p.manifests[oldestKey].fetched)) {
+				oldestKey = k
+			}
+		}
+		p.manifestsSize -= len(p.manifests[oldestKey].body)
+		delete(p.manifests, oldestKey)
+	}
+}
+
+// fetchManifest fetches the manifest of the reference from the upstream registry. It returns nil if the manifest does
+// not exist, and an error if the content of a manifest referenced by digest does not match it.
+func (p *ChartCacheProxy) fetchManifest(ctx context.Context, repo *ociRepository, reference string) (*cachedManifest, error) {
+	resp, err := repo.get(ctx, "manifests/"+reference, chartCacheProxyManifestMediaTypes...)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status %s getting %s/manifests/%s", resp.Status, repo.name, reference)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxChartCacheProxyManifestSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxChartCacheProxyManifestSize {
+		return nil, errors.Errorf("manifest %s:%s exceeds %d bytes", repo.name, reference, maxChartCacheProxyManifestSize)
+	}
+
+	manifest := &cachedManifest{
+		mediaType: resp.Header.Get("Content-Type"),
+		body:      body,
+		digest:    digest.FromBytes(body),
+		fetched:   time.Now(),
+	}
+	if dgst, err := digest.Parse(reference); err == nil && dgst != manifest.digest {
+		return nil, errors.Errorf("content of manifest %s@%s does not match its digest", repo.name, dgst)
+	}
+
+	return manifest, nil
+}
+
+// serveBlob serves the blob with the digest, downloading it from the upstream registry unless it is stored on disk.
+func (p *ChartCacheProxy) serveBlob(w http.ResponseWriter, r *http.Request, repo *ociRepository, reference string) {
+	dgst, err := digest.Parse(reference)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid digest %s", reference), http.StatusBadRequest)
+		return
+	}
+
+	filename := filepath.Join(p.Dir, "blobs", dgst.Algorithm().String(), dgst.Encoded())
+	result := "hit"
+	f, err := os.Open(filename)
+	if err != nil {
+		result = "miss"
+		_, fetchErr, _ := p.fetches.Do("blob "+dgst.String(), func() (interface{}, error) {
+			if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+				return nil, err
+			}
+
+			ctx, cancel := fetchContext(r.Context())
+			defer cancel()
+
+			if err := p.downloadBlob(ctx, repo, dgst, filename); err != nil {
+				return nil, err
+			}
+
+			return nil, p.evictBlobs(filename)
+		})
+		if fetchErr != nil {
+			p.log.Error(fetchErr, "Failed to fetch blob", "repository", repo.host+"/"+repo.name, "digest", dgst.String())
+			recordChartCacheProxyRequest("blob", "error")
+			http.Error(w, fetchErr.Error(), http.StatusBadGateway)
+			return
+		}
+		f, err = os.Open(filename)
+	} else {
+		// The modification time of blobs records when they were last served, to evict the least recently served first.
+		now := time.Now()
+		_ = os.Chtimes(filename, now, now)
+	}
+	if err != nil {
+		recordChartCacheProxyRequest("blob", "error")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	recordChartCacheProxyRequest("blob", result)
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Docker-Content-Digest", dgst.String())
+	http.ServeContent(w, r, "", time.Time{}, f)
+}
+
+// downloadBlob downloads the blob with the digest from the upstream registry to the file, failing if it is larger than
+// the maximum size of the blobs stored on disk.
+func (p *ChartCacheProxy) downloadBlob(ctx context.Context, repo *ociRepository, dgst digest.Digest, filename string) error {
+	f, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := repo.fetchBlobLimited(ctx, dgst, p.MaxSize, f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), filename)
+}
+
+// evictBlobs removes the blobs served the longest time ago until the blobs stored on disk fit in the maximum size. The
+// blob stored in the file is kept, as it was just fetched to be served.
+func (p *ChartCacheProxy) evictBlobs(keep string) error {
+	p.blobsMu.Lock()
+	defer p.blobsMu.Unlock()
+
+	type blob struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var blobs []blob
+	var total int64
+	err := filepath.WalkDir(filepath.Join(p.Dir, "blobs"), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasSuffix(path, ".tmp") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		total += info.Size()
+		if path != keep {
+			blobs = append(blobs, blob{path: path, size: info.Size(), modTime: info.ModTime()})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	slices.SortFunc(blobs, func(a, b blob) int { return a.modTime.Compare(b.modTime) })
+	for _, b := range blobs {
+		if total <= p.MaxSize {
+			break
+		}
+		if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		total -= b.size
+	}
+
+	return nil
+}
@@ -0,0 +1,248 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func TestChartCacheProxy(t *testing.T) {
+	g := NewWithT(t)
+
+	chart := []byte("chart archive")
+	chartDigest := digest.FromBytes(chart)
+	manifest := []byte(`{"schemaVersion":2,"layers":[{"digest":"` + chartDigest.String() + `"}]}`)
+	manifestDigest := digest.FromBytes(manifest)
+
+	var upstreamRequests atomic.Int32
+	var available atomic.Bool
+	available.Store(true)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamRequests.Add(1)
+		if !available.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/charts/nginx/manifests/1.0.0", "/v2/charts/nginx/manifests/" + manifestDigest.String():
+			w.Header().Set("Content-Type", ocispec.MediaTypeImageManifest)
+			_, _ = w.Write(manifest)
+		case "/v2/charts/nginx/blobs/" + chartDigest.String():
+			_, _ = w.Write(chart)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer upstream.Close()
+	upstreamHost := strings.TrimPrefix(upstream.URL, "http://")
+
+	_, err := NewChartCacheProxy("", t.TempDir(), nil, 1<<20)
+	g.Expect(err).To(MatchError(ContainSubstring("requires at least one allowed registry")))
+
+	p, err := NewChartCacheProxy("", t.TempDir(), []string{upstreamHost}, 1<<20)
+	g.Expect(err).NotTo(HaveOccurred())
+	p.upstreamPlainHTTP = true
+	proxy := httptest.NewServer(p)
+	defer proxy.Close()
+
+	get := func(urlPath string) (*http.Response, []byte) {
+		resp, err := http.Get(proxy.URL + urlPath)
+		g.Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		g.Expect(err).NotTo(HaveOccurred())
+
+		return resp, body
+	}
+
+	resp, _ := get("/v2/")
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(resp.Header.Get("Docker-Distribution-API-Version")).To(Equal("registry/2.0"))
+
+	// Manifests and blobs are fetched from the upstream registry once.
+	for range 2 {
+		resp, body := get("/v2/" + upstreamHost + "/charts/nginx/manifests/1.0.0")
+		g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		g.Expect(resp.Header.Get("Content-Type")).To(Equal(ocispec.MediaTypeImageManifest))
+		g.Expect(resp.Header.Get("Docker-Content-Digest")).To(Equal(manifestDigest.String()))
+		g.Expect(body).To(Equal(manifest))
+
+		resp, body = get("/v2/" + upstreamHost + "/charts/nginx/blobs/" + chartDigest.String())
+		g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		g.Expect(body).To(Equal(chart))
+	}
+	g.Expect(upstreamRequests.Load()).To(Equal(int32(2)))
+
+	// Registries that are not allowed are never reached.
+	resp, _ = get("/v2/169.254.169.254/latest/meta-data/manifests/1.0.0")
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	resp, _ = get("/v2/registry.example.com/charts/nginx/blobs/" + chartDigest.String())
+	g.Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	g.Expect(upstreamRequests.Load()).To(Equal(int32(2)))
+
+	// Cached manifests are served while the upstream registry is unavailable, once the tag expired.
+	available.Store(false)
+	p.manifests[upstreamHost+"/charts/nginx:1.0.0"].fetched = p.manifests[upstreamHost+"/charts/nginx:1.0.0"].fetched.Add(-2 * chartCacheProxyManifestTTL)
+	resp, body := get("/v2/" + upstreamHost + "/charts/nginx/manifests/1.0.0")
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(body).To(Equal(manifest))
+
+	resp, _ = get("/v2/" + upstreamHost + "/charts/nginx/manifests/" + manifestDigest.String())
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadGateway))
+
+	available.Store(true)
+	resp, _ = get("/v2/" + upstreamHost + "/charts/nginx/manifests/2.0.0")
+	g.Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+
+	resp, _ = get("/v2/" + upstreamHost + "/charts/nginx/blobs/not-a-digest")
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+	req, err := http.NewRequest(http.MethodPut, proxy.URL+"/v2/"+upstreamHost+"/charts/nginx/manifests/1.0.0", http.NoBody)
+	g.Expect(err).NotTo(HaveOccurred())
+	resp, err = http.DefaultClient.Do(req)
+	g.Expect(err).NotTo(HaveOccurred())
+	resp.Body.Close()
+	g.Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+}
+
+func TestChartCacheProxyEviction(t *testing.T) {
+	g := NewWithT(t)
+
+	blobs := map[digest.Digest][]byte{}
+	for _, content := range []string{"first chart", "second chart", "third chart", strings.Repeat("large chart", 10)} {
+		blobs[digest.FromString(content)] = []byte(content)
+	}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dgst, ok := strings.CutPrefix(r.URL.Path, "/v2/charts/nginx/blobs/")
+		if !ok || blobs[digest.Digest(dgst)] == nil {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(blobs[digest.Digest(dgst)])
+	}))
+	defer upstream.Close()
+	upstreamHost := strings.TrimPrefix(upstream.URL, "http://")
+
+	dir := t.TempDir()
+	p, err := NewChartCacheProxy("", dir, []string{upstreamHost}, 30)
+	g.Expect(err).NotTo(HaveOccurred())
+	p.upstreamPlainHTTP = true
+	proxy := httptest.NewServer(p)
+	defer proxy.Close()
+
+	getBlob := func(content string) int {
+		resp, err := http.Get(proxy.URL + "/v2/" + upstreamHost + "/charts/nginx/blobs/" + digest.FromString(content).String())
+		g.Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+
+		return resp.StatusCode
+	}
+	blobFile := func(content string) string {
+		return filepath.Join(dir, "blobs", "sha256", digest.FromString(content).Encoded())
+	}
+
+	// The blob served the longest time ago is removed once the blobs exceed the maximum size.
+	g.Expect(getBlob("first chart")).To(Equal(http.StatusOK))
+	g.Expect(getBlob("second chart")).To(Equal(http.StatusOK))
+	old := time.Now().Add(-time.Hour)
+	g.Expect(os.Chtimes(blobFile("first chart"), old, old)).To(Succeed())
+	g.Expect(os.Chtimes(blobFile("second chart"), old.Add(-time.Hour), old.Add(-time.Hour))).To(Succeed())
+	g.Expect(getBlob("first chart")).To(Equal(http.StatusOK))
+	g.Expect(getBlob("third chart")).To(Equal(http.StatusOK))
+	g.Expect(blobFile("first chart")).To(BeAnExistingFile())
+	g.Expect(blobFile("second chart")).NotTo(BeAnExistingFile())
+	g.Expect(blobFile("third chart")).To(BeAnExistingFile())
+
+	// Blobs larger than the maximum size are not stored.
+	g.Expect(getBlob(strings.Repeat("large chart", 10))).To(Equal(http.StatusBadGateway))
+	g.Expect(blobFile(strings.Repeat("large chart", 10))).NotTo(BeAnExistingFile())
+
+	// The manifests fetched the longest time ago are evicted once the cached manifests exceed their maximum size.
+	p.maxManifestsSize = 10
+	now := time.Now()
+	p.storeManifest("first", &cachedManifest{body: []byte("12345"), fetched: now.Add(-time.Minute)})
+	p.storeManifest("second", &cachedManifest{body: []byte("12345"), fetched: now})
+	p.storeManifest("second", &cachedManifest{body: []byte("1234"), fetched: now})
+	g.Expect(p.manifests).To(HaveLen(2))
+	p.storeManifest("third", &cachedManifest{body: []byte("12"), fetched: now})
+	g.Expect(p.manifests).To(HaveKey("second"))
+	g.Expect(p.manifests).To(HaveKey("third"))
+	g.Expect(p.manifests).NotTo(HaveKey("first"))
+	g.Expect(p.manifestsSize).To(Equal(6))
+}
+
+func TestParseChartCacheProxyPath(t *testing.T) {
+	testcases := []struct {
+		path              string
+		expectedHost      string
+		expectedName      string
+		expectedKind      string
+		expectedReference string
+		expectedOK        bool
+	}{
+		{
+			path:              "/v2/registry.example.com/charts/nginx/manifests/1.0.0",
+			expectedHost:      "registry.example.com",
+			expectedName:      "charts/nginx",
+			expectedKind:      "manifests",
+			expectedReference: "1.0.0",
+			expectedOK:        true,
+		},
+		{
+			path:              "/v2/localhost:5000/nginx/blobs/sha256:abc",
+			expectedHost:      "localhost:5000",
+			expectedName:      "nginx",
+			expectedKind:      "blobs",
+			expectedReference: "sha256:abc",
+			expectedOK:        true,
+		},
+		{
+			path: "/v2/nginx/manifests/1.0.0",
+		},
+		{
+			path: "/v2/registry.example.com/nginx/tags/list",
+		},
+		{
+			path: "/v1/registry.example.com/nginx/manifests/1.0.0",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.path, func(t *testing.T) {
+			g := NewWithT(t)
+
+			host, name, kind, reference, ok := parseChartCacheProxyPath(tc.path)
+			g.Expect(ok).To(Equal(tc.expectedOK))
+			g.Expect(host).To(Equal(tc.expectedHost))
+			g.Expect(name).To(Equal(tc.expectedName))
+			g.Expect(kind).To(Equal(tc.expectedKind))
+			g.Expect(reference).To(Equal(tc.expectedReference))
+		})
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	helmAction "helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	helmCli "helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/repo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestChartCache(t *testing.T) {
+	g := NewWithT(t)
+
+	cache := &chartCache{paths: map[string]string{}, warming: map[string]struct{}{}}
+	key := chartCacheKey("https://test-repo", "test-chart", "1.0.0")
+	path := filepath.Join(t.TempDir(), "test-chart-1.0.0.tgz")
+	g.Expect(os.WriteFile(path, []byte("chart"), 0o600)).To(Succeed())
+
+	_, ok := cache.get(key)
+	g.Expect(ok).To(BeFalse())
+
+	g.Expect(cache.startWarming(key)).To(BeTrue())
+	g.Expect(cache.startWarming(key)).To(BeFalse(), "chart is already being warmed")
+	cache.set(key, path)
+	cache.stopWarming(key)
+	g.Expect(cache.startWarming(key)).To(BeFalse(), "chart is already cached")
+
+	cached, ok := cache.get(key)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(cached).To(Equal(path))
+
+	g.Expect(os.Remove(path)).To(Succeed())
+	_, ok = cache.get(key)
+	g.Expect(ok).To(BeFalse(), "removed charts are evicted")
+	g.Expect(cache.startWarming(key)).To(BeTrue())
+}
+
+func TestFetchWithTimeout(t *testing.T) {
+	g := NewWithT(t)
+
+	release := make(chan struct{})
+	defer close(release)
+	slowFetch := func() (string, error) {
+		<-release
+		return "slow-chart.tgz", nil
+	}
+	fastFetch := func() (string, error) {
+		return "fast-chart.tgz", nil
+	}
+
+	path, err := fetchWithTimeout(&metav1.Duration{Duration: time.Minute}, "test-chart", fastFetch)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(path).To(Equal("fast-chart.tgz"))
+
+	_, err = fetchWithTimeout(&metav1.Duration{Duration: 10 * time.Millisecond}, "test-chart", slowFetch)
+	g.Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+	g.Expect(isRegistryOutageError(err)).To(BeTrue(), "timed out fetches count towards the registry circuit breaker")
+
+	path, err = fetchWithTimeout(nil, "test-chart", fastFetch)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(path).To(Equal("fast-chart.tgz"))
+}
+
+func TestLocateChartArchiveVersionConstraint(t *testing.T) {
+	g := NewWithT(t)
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// publish adds a version of the chart to the index of the repository.
+	index := repo.NewIndexFile()
+	publish := func(version string) {
+		metadata := &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "test-chart", Version: version}
+		archive, err := chartutil.Save(&chart.Chart{Metadata: metadata}, t.TempDir())
+		g.Expect(err).NotTo(HaveOccurred())
+		content, err := os.ReadFile(archive)
+		g.Expect(err).NotTo(HaveOccurred())
+		mux.HandleFunc("/"+filepath.Base(archive), func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write(content)
+		})
+		g.Expect(index.MustAdd(metadata, filepath.Base(archive), server.URL, "")).To(Succeed())
+	}
+	mux.HandleFunc("/index.yaml", func(w http.ResponseWriter, _ *http.Request) {
+		content, _ := yaml.Marshal(index)
+		_, _ = w.Write(content)
+	})
+	for _, version := range []string{"1.2.0", "1.3.0", "2.0.0"} {
+		publish(version)
+	}
+
+	spec := addonsv1alpha1.HelmReleaseProxySpec{RepoURL: server.URL, ChartName: "test-chart", Version: ">=1.2.0 <2.0.0"}
+	locate := func() string {
+		settings := helmCli.New()
+		settings.RepositoryCache = t.TempDir()
+		settings.RepositoryConfig = filepath.Join(t.TempDir(), "repositories.yaml")
+		pathOptions := &helmAction.ChartPathOptions{RepoURL: spec.RepoURL, Version: spec.Version}
+
+		path, err := locateChartArchive(context.Background(), pathOptions, spec.ChartName, settings, spec, "", "", RepositoryAuth{}, nil)
+		g.Expect(err).NotTo(HaveOccurred())
+		located, err := loader.Load(path)
+		g.Expect(err).NotTo(HaveOccurred())
+
+		return located.Metadata.Version
+	}
+
+	g.Expect(locate()).To(Equal("1.3.0"))
+
+	// Charts located for a constraint are not cached, so a newly published matching version is located.
+	publish("1.4.0")
+	g.Expect(locate()).To(Equal("1.4.0"))
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/provenance"
+)
+
+// ChartLockMismatchError is returned when the vendored dependencies of a Helm chart do not match its Chart.lock, so that
+// the chart is not installed or upgraded.
+type ChartLockMismatchError struct {
+	// Chart is the name of the chart.
+	Chart string
+
+	// Mismatches describes the differences between the Chart.lock and the dependencies of the chart.
+	Mismatches []string
+}
+
+func (e *ChartLockMismatchError) Error() string {
+	return fmt.Sprintf("dependencies of chart %s do not match its Chart.lock: %s", e.Chart, strings.Join(e.Mismatches, "; "))
+}
+
+// verifyChartLock returns a ChartLockMismatchError if the chart or one of its subcharts has a Chart.lock that does not
+// match its dependencies, i.e. the digest of the Chart.lock is not the one of the dependencies of the Chart.yaml and the
+// locked dependencies, or a locked dependency is missing from the charts directory or vendored at another version.
+// Charts without a Chart.lock are not verified.
+func verifyChartLock(chartRequested *chart.Chart) error {
+	mismatches, err := chartLockMismatches(chartRequested, "")
+	if err != nil {
+		return err
+	}
+	if len(mismatches) > 0 {
+		return &ChartLockMismatchError{Chart: chartRequested.Name(), Mismatches: mismatches}
+	}
+
+	return nil
+}
+
+// chartLockMismatches returns the mismatches between the Chart.lock and the dependencies of the chart and of its
+// subcharts. The prefix is the path of the chart in the chart it is vendored in.
+func chartLockMismatches(c *chart.Chart, prefix string) ([]string, error) {
+	path := prefix + c.Name()
+	var mismatches []string
+
+	if c.Lock != nil {
+		// The digest of the requirements.lock of apiVersion v1 charts may have been computed by Helm v2 in another way.
+		if c.Metadata.APIVersion != chart.APIVersionV1 {
+			digest, err := chartLockDigest(c.Metadata.Dependencies, c.Lock.Dependencies)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to compute digest of dependencies of chart %s", path)
+			}
+			if digest != c.Lock.Digest {
+				mismatches = append(mismatches, fmt.Sprintf("digest %s of Chart.lock of %s is not the digest %s of its dependencies", c.Lock.Digest, path, digest))
+			}
+		}
+
+		for _, locked := range c.Lock.Dependencies {
+			if locked == nil {
+				continue
+			}
+			vendored := vendoredDependency(c, locked.Name)
+			switch {
+			case vendored == nil:
+				mismatches = append(mismatches, fmt.Sprintf("locked dependency %s of %s is not vendored", locked.Name, path))
+			case vendored.Metadata.Version != locked.Version:
+				mismatches = append(mismatches, fmt.Sprintf("dependency %s of %s is vendored at version %s instead of locked version %s", locked.Name, path, vendored.Metadata.Version, locked.Version))
+			}
+		}
+	}
+
+	for _, dependency := range c.Dependencies() {
+		dependencyMismatches, err := chartLockMismatches(dependency, path+"/")
+		if err != nil {
+			return nil, err
+		}
+		mismatches = append(mismatches, dependencyMismatches...)
+	}
+
+	return mismatches, nil
+}
+
+// vendoredDependency returns the subchart with the name in the charts directory of the chart, or nil if there is none.
+func vendoredDependency(c *chart.Chart, name string) *chart.Chart {
+	for _, dependency := range c.Dependencies() {
+		if dependency.Name() == name {
+			return dependency
+		}
+	}
+
+	return nil
+}
+
+// chartLockDigest returns the digest of the dependencies of a Chart.yaml and of its Chart.lock, computed the same way
+// as Helm does when it writes the Chart.lock.
+func chartLockDigest(dependencies, locked []*chart.Dependency) (string, error) {
+	data, err := json.Marshal([2][]*chart.Dependency{dependencies, locked})
+	if err != nil {
+		return "", err
+	}
+	digest, err := provenance.Digest(bytes.NewBuffer(data))
+	if err != nil {
+		return "", err
+	}
+
+	return "sha256:" + digest, nil
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+func TestVerifyChartLock(t *testing.T) {
+	newChart := func(name, version string, dependencies []*chart.Dependency, lock []*chart.Dependency, vendored ...*chart.Chart) *chart.Chart {
+		c := &chart.Chart{Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: name, Version: version, Dependencies: dependencies}}
+		if lock != nil {
+			digest, err := chartLockDigest(dependencies, lock)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.Lock = &chart.Lock{Dependencies: lock, Digest: digest}
+		}
+		c.SetDependencies(vendored...)
+
+		return c
+	}
+	dependencies := []*chart.Dependency{{Name: "common", Version: "^2.0.0", Repository: "https://charts.example.com"}}
+	lock := []*chart.Dependency{{Name: "common", Version: "2.1.0", Repository: "https://charts.example.com"}}
+
+	testcases := []struct {
+		name          string
+		chart         *chart.Chart
+		expectedError string
+	}{
+		{
+			name:  "chart without Chart.lock is not verified",
+			chart: newChart("test-chart", "1.0.0", dependencies, nil),
+		},
+		{
+			name:  "vendored dependencies match the Chart.lock",
+			chart: newChart("test-chart", "1.0.0", dependencies, lock, newChart("common", "2.1.0", nil, nil)),
+		},
+		{
+			name: "digest of the Chart.lock does not match the dependencies",
+			chart: func() *chart.Chart {
+				c := newChart("test-chart", "1.0.0", dependencies, lock, newChart("common", "2.1.0", nil, nil))
+				c.Lock.Digest = "sha256:0000"
+				return c
+			}(),
+			expectedError: "dependencies of chart test-chart do not match its Chart.lock: digest sha256:0000 of Chart.lock of test-chart is not the digest",
+		},
+		{
+			name:          "dependency vendored at another version",
+			chart:         newChart("test-chart", "1.0.0", dependencies, lock, newChart("common", "2.0.0", nil, nil)),
+			expectedError: "dependency common of test-chart is vendored at version 2.0.0 instead of locked version 2.1.0",
+		},
+		{
+			name:          "locked dependency is not vendored",
+			chart:         newChart("test-chart", "1.0.0", dependencies, lock),
+			expectedError: "locked dependency common of test-chart is not vendored",
+		},
+		{
+			name: "Chart.lock of a subchart is verified",
+			chart: newChart("test-chart", "1.0.0", nil, nil,
+				newChart("common", "2.1.0", dependencies, lock, newChart("common", "1.0.0", nil, nil))),
+			expectedError: "dependency common of test-chart/common is vendored at version 1.0.0 instead of locked version 2.1.0",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			err := verifyChartLock(tc.chart)
+			if tc.expectedError == "" {
+				g.Expect(err).NotTo(HaveOccurred())
+				return
+			}
+			var chartLockErr *ChartLockMismatchError
+			g.Expect(err).To(BeAssignableToTypeOf(chartLockErr))
+			g.Expect(err.Error()).To(ContainSubstring(tc.expectedError))
+		})
+	}
+}
@@ -0,0 +1,325 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/downloader"
+	"helm.sh/helm/v3/pkg/registry"
+	"k8s.io/utils/ptr"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// cosignTimeout is the maximum duration of a run of cosign verifying the signature of a chart.
+	cosignTimeout = 2 * time.Minute
+
+	// maxCommandOutputSize is the maximum size of the output of a command run by the controller that is kept.
+	maxCommandOutputSize = 1 << 20
+)
+
+// ChartVerification holds the keys the signature of a chart is verified with before it is installed or upgraded.
+type ChartVerification struct {
+	// KeyringFile is the path of the GPG keyring verifying the provenance files of charts from HTTP chart repositories.
+	KeyringFile string
+
+	// CosignPublicKey is the PEM encoded public key verifying key-based cosign signatures of charts from OCI registries.
+	CosignPublicKey []byte
+
+	// CosignKeyless verifies keyless cosign signatures of charts from OCI registries.
+	CosignKeyless *CosignKeylessVerification
+}
+
+// CosignKeylessVerification holds the identity keyless cosign signatures must be made by and the roots they are trusted
+// with.
+type CosignKeylessVerification struct {
+	// Issuer and Subject are the OIDC issuer and the email address or URI of the identity in the signing certificate.
+	Issuer  string
+	Subject string
+
+	// FulcioCertificates are the PEM encoded Fulcio root and intermediate certificates the signing certificate must chain
+	// to.
+	FulcioCertificates []byte
+
+	// RekorPublicKey is the PEM encoded public key of the Rekor transparency log the signature must be recorded in.
+	RekorPublicKey []byte
+}
+
+// ChartVerificationError is returned when the signature of a chart cannot be verified, so that the chart is not installed.
+type ChartVerificationError struct {
+	// Chart is the name of the chart.
+	Chart string
+
+	// Err is the reason the verification failed.
+	Err error
+}
+
+func (e *ChartVerificationError) Error() string {
+	return fmt.Sprintf("failed to verify signature of chart %s: %v", e.Chart, e.Err)
+}
+
+func (e *ChartVerificationError) Unwrap() error {
+	return e.Err
+}
+
+// verifiesProvenance returns true if the provenance files of charts from HTTP chart repositories are verified.
+func (a RepositoryAuth) verifiesProvenance() bool {
+	return a.Verification != nil && a.Verification.KeyringFile != ""
+}
+
+// hasProvenanceFile returns true if the provenance file of the chart archive at the path was downloaded.
+func hasProvenanceFile(path string) bool {
+	_, err := os.Stat(path + ".prov")
+	return err == nil
+}
+
+// verifyChart verifies the chart archive at the path with the keys of the RepositoryAuth, i.e. the provenance file of
+// charts from HTTP chart repositories or the cosign signature of charts from OCI registries. It returns a
+// ChartVerificationError if the chart fails verification.
+func verifyChart(ctx context.Context, path string, spec addonsv1alpha1.HelmReleaseProxySpec, credentialsPath, caFilePath string, repositoryAuth RepositoryAuth) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	verification := repositoryAuth.Verification
+	if verification == nil {
+		return nil
+	}
+
+	var err error
+	switch {
+	case spec.ChartBundleRef != nil:
+		err = errors.New("charts of ChartBundles have no signature to verify")
+	case spec.Git != nil:
+		err = errors.New("charts of Git repositories have no signature to verify")
+	case registry.IsOCI(spec.RepoURL):
+		if verification.CosignPublicKey == nil && verification.CosignKeyless == nil {
+			return nil
+		}
+		log.V(2).Info("Verifying cosign signature of chart", "chart", spec.ChartName, "path", path)
+		err = verifyOCIChartSignature(ctx, path, spec, credentialsPath, caFilePath, repositoryAuth)
+	case verification.KeyringFile != "":
+		log.V(2).Info("Verifying provenance of chart", "chart", spec.ChartName, "path", path)
+		_, err = downloader.VerifyChart(path, verification.KeyringFile)
+	}
+	if err != nil {
+		return &ChartVerificationError{Chart: spec.ChartName, Err: err}
+	}
+
+	return nil
+}
+
+// verifyOCIChartSignature verifies that the chart archive at the path is the chart layer of the OCI chart of the spec and
+// that the manifest of the chart has a valid cosign signature. Charts pinned by digest are verified against the manifest
+// of their digest, and the version of charts without a pinned version is read from the archive.
+func verifyOCIChartSignature(ctx context.Context, path string, spec addonsv1alpha1.HelmReleaseProxySpec, credentialsPath, caFilePath string, repositoryAuth RepositoryAuth) error {
+	version := spec.Version
+	if spec.Digest != "" {
+		version = spec.Digest
+	} else if version == "" || addonsv1alpha1.IsVersionConstraint(version) {
+		chart, err := loader.Load(path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to load chart %s", path)
+		}
+		version = chart.Metadata.Version
+	}
+
+	repo, err := newOCIRepository(spec, credentialsPath, caFilePath, repositoryAuth)
+	if err != nil {
+		return err
+	}
+
+	opts := cosignOptions{
+		credentialsPath:       credentialsPath,
+		caFilePath:            caFilePath,
+		repositoryAuth:        repositoryAuth,
+		insecureSkipTLSVerify: ptr.Deref(spec.TLSConfig, addonsv1alpha1.TLSConfig{}).InsecureSkipTLSVerify,
+	}
+
+	return repo.verifyCosignSignature(ctx, path, version, repositoryAuth.Verification, opts)
+}
+
+// verifyCosignSignature verifies that the chart archive at the path is the chart layer of the chart version, or of the
+// manifest with the digest, and that cosign verifies a signature of its manifest.
+func (r *ociRepository) verifyCosignSignature(ctx context.Context, path, version string, verification *ChartVerification, opts cosignOptions) error {
+	manifest, manifestDigest, err := r.manifest(ctx, ociTag(version))
+	if err != nil {
+		return err
+	}
+	chartLayer := chartLayerOf(manifest)
+	if chartLayer == nil {
+		return errors.Errorf("manifest of chart %s:%s does not contain a chart layer", r.name, version)
+	}
+	if err := verifyFileDigest(path, chartLayer.Digest); err != nil {
+		return err
+	}
+
+	return cosignVerify(ctx, r.host+"/"+r.name+"@"+manifestDigest.String(), verification, opts)
+}
+
+// verifyFileDigest returns an error if the content of the file does not match the digest.
+func verifyFileDigest(path string, dgst digest.Digest) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	verifier := dgst.Verifier()
+	if _, err := io.Copy(verifier, f); err != nil {
+		return err
+	}
+	if !verifier.Verified() {
+		return errors.Errorf("chart %s does not match the signed chart layer %s", path, dgst)
+	}
+
+	return nil
+}
+
+// cosignOptions are the options of cosign reaching the registry of a chart, mirroring the HTTP client of its
+// ociRepository.
+type cosignOptions struct {
+	// credentialsPath is the path of the Docker config file holding the credentials of the registry.
+	credentialsPath string
+
+	// caFilePath is the path of the CA certificate the registry is trusted with.
+	caFilePath string
+
+	// repositoryAuth holds the client certificate, the proxy and the plain HTTP setting of the registry.
+	repositoryAuth RepositoryAuth
+
+	// insecureSkipTLSVerify skips the verification of the certificate of the registry.
+	insecureSkipTLSVerify bool
+}
+
+// cosignVerify runs cosign verify for the image, which must be referenced by digest, with the public key or the keyless
+// identity and trusted root of the verification. Keyless signatures are verified offline with the Rekor bundle stored
+// with them. Signatures made with a public key are trusted by the key alone, as they are not required to be recorded in
+// a transparency log.
+func cosignVerify(ctx context.Context, image string, verification *ChartVerification, opts cosignOptions) error {
+	ctx, cancel := context.WithTimeout(ctx, cosignTimeout)
+	defer cancel()
+
+	dir, err := os.MkdirTemp("", "cosign-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	// cosign reads the credentials from the config.json of DOCKER_CONFIG and caches the sigstore TUF metadata in
+	// TUF_ROOT, which must not be the read-only home directory of the controller.
+	args := []string{"verify"}
+	env := append(os.Environ(), "DOCKER_CONFIG="+dir, "TUF_ROOT="+filepath.Join(dir, "tuf"))
+	if keyless := verification.CosignKeyless; keyless != nil {
+		fulcioFile, err := writeCosignFile(dir, "fulcio.crt", keyless.FulcioCertificates)
+		if err != nil {
+			return err
+		}
+		rekorFile, err := writeCosignFile(dir, "rekor.pub", keyless.RekorPublicKey)
+		if err != nil {
+			return err
+		}
+		// The signing certificate is not checked against certificate transparency logs, whose keys are not part of the
+		// trusted root.
+		env = append(env, "SIGSTORE_ROOT_FILE="+fulcioFile, "SIGSTORE_REKOR_PUBLIC_KEY="+rekorFile)
+		args = append(args, "--certificate-identity="+keyless.Subject, "--certificate-oidc-issuer="+keyless.Issuer,
+			"--offline", "--insecure-ignore-sct")
+	} else {
+		keyFile, err := writeCosignFile(dir, "cosign.pub", verification.CosignPublicKey)
+		if err != nil {
+			return err
+		}
+		args = append(args, "--key="+keyFile, "--insecure-ignore-tlog")
+	}
+
+	if opts.credentialsPath != "" {
+		config, err := os.ReadFile(opts.credentialsPath)
+		if err != nil {
+			return errors.Wrap(err, "failed to read registry credentials")
+		}
+		if _, err := writeCosignFile(dir, "config.json", config); err != nil {
+			return err
+		}
+	}
+	if opts.caFilePath != "" {
+		args = append(args, "--registry-cacert="+opts.caFilePath)
+	}
+	if auth := opts.repositoryAuth; auth.CertFile != "" {
+		args = append(args, "--registry-client-cert="+auth.CertFile, "--registry-client-key="+auth.KeyFile)
+	}
+	if opts.insecureSkipTLSVerify || opts.repositoryAuth.PlainHTTP {
+		args = append(args, "--allow-insecure-registry")
+	}
+	if opts.repositoryAuth.PlainHTTP {
+		args = append(args, "--allow-http-registry")
+	}
+	if proxyURL := opts.repositoryAuth.ProxyURL; proxyURL != "" {
+		env = append(env, "HTTPS_PROXY="+proxyURL, "HTTP_PROXY="+proxyURL)
+	}
+	args = append(args, "--", image)
+
+	cmd := exec.CommandContext(ctx, "cosign", args...)
+	cmd.Env = env
+	stderr := &limitedBuffer{limit: maxCommandOutputSize}
+	cmd.Stdout = io.Discard
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return errors.Errorf("cosign verify: %s", message)
+		}
+
+		return errors.Wrap(err, "cosign verify")
+	}
+
+	return nil
+}
+
+// writeCosignFile writes the data to the file with the name in the directory and returns its path.
+func writeCosignFile(dir, name string, data []byte) (string, error) {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return "", errors.Wrapf(err, "failed to write %s for cosign", name)
+	}
+
+	return path, nil
+}
+
+// limitedBuffer is a buffer for the output of a command, whose writes fail once more than limit bytes were written to
+// it, so that a misbehaving command cannot exhaust the memory of the controller.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		return 0, errors.Errorf("output exceeds %d bytes", b.limit)
+	}
+
+	return b.Buffer.Write(p)
+}
@@ -0,0 +1,250 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+)
+
+// newChartTestRegistry returns a registry serving the manifest of a chart, and the chart archive and manifest digest.
+func newChartTestRegistry(t *testing.T) (*httptest.Server, []byte, digest.Digest) {
+	t.Helper()
+
+	chartArchive := []byte("signed chart archive")
+	manifest, err := json.Marshal(ocispec.Manifest{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    ocispec.DescriptorEmptyJSON,
+		Layers: []ocispec.Descriptor{
+			{MediaType: "application/vnd.cncf.helm.chart.content.v1.tar+gzip", Digest: digest.FromBytes(chartArchive), Size: int64(len(chartArchive))},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/charts/test-chart/manifests/1.0.0", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(manifest)
+	})
+
+	return httptest.NewTLSServer(mux), chartArchive, digest.FromBytes(manifest)
+}
+
+// fakeCosign puts a cosign script on the PATH that records its arguments, the files it is given and its environment in
+// the returned directory, and fails with the message if it is not empty.
+func fakeCosign(t *testing.T, message string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	script := `#!/bin/sh
+dir=$(dirname "$0")
+printf '%s\n' "$@" > "$dir/args"
+env > "$dir/env"
+for arg in "$@"; do
+	case "$arg" in
+	--key=*) cp "${arg#--key=}" "$dir/cosign.pub" ;;
+	esac
+done
+[ -n "$SIGSTORE_ROOT_FILE" ] && cp "$SIGSTORE_ROOT_FILE" "$dir/fulcio.crt"
+[ -n "$SIGSTORE_REKOR_PUBLIC_KEY" ] && cp "$SIGSTORE_REKOR_PUBLIC_KEY" "$dir/rekor.pub"
+[ -f "$DOCKER_CONFIG/config.json" ] && cp "$DOCKER_CONFIG/config.json" "$dir/config.json"
+if [ -n "` + message + `" ]; then
+	echo "` + message + `" >&2
+	exit 1
+fi
+exit 0
+`
+	if err := os.WriteFile(filepath.Join(dir, "cosign"), []byte(script), 0o700); err != nil { //nolint:gosec // The script must be executable.
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	return dir
+}
+
+// readCosignFile returns the content of the file recorded by the fake cosign in the directory.
+func readCosignFile(t *testing.T, dir, name string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+// verifyTestChart verifies the chart of the test registry, or the given chart archive, with the verification.
+func verifyTestChart(t *testing.T, server *httptest.Server, chartArchive []byte, verification *ChartVerification) error {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "test-chart-1.0.0.tgz")
+	if err := os.WriteFile(path, chartArchive, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	creds, err := registryCredentials("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := newOCIRepositoryWithClient(strings.TrimPrefix(server.URL, "https://"), "charts/test-chart", server.Client(), creds)
+
+	return repo.verifyCosignSignature(context.TODO(), path, "1.0.0", verification, cosignOptions{})
+}
+
+func TestVerifyCosignSignatureWithPublicKey(t *testing.T) {
+	publicKey := []byte("-----BEGIN PUBLIC KEY-----\ncosign\n-----END PUBLIC KEY-----\n")
+
+	t.Run("valid signature", func(t *testing.T) {
+		g := NewWithT(t)
+
+		dir := fakeCosign(t, "")
+		server, chartArchive, manifestDigest := newChartTestRegistry(t)
+		defer server.Close()
+
+		g.Expect(verifyTestChart(t, server, chartArchive, &ChartVerification{CosignPublicKey: publicKey})).To(Succeed())
+		args := strings.Fields(readCosignFile(t, dir, "args"))
+		g.Expect(args[0]).To(Equal("verify"))
+		g.Expect(args).To(ContainElement("--insecure-ignore-tlog"))
+		g.Expect(args[len(args)-2:]).To(Equal([]string{"--", strings.TrimPrefix(server.URL, "https://") + "/charts/test-chart@" + manifestDigest.String()}))
+		g.Expect(readCosignFile(t, dir, "cosign.pub")).To(Equal(string(publicKey)))
+	})
+
+	t.Run("invalid signature", func(t *testing.T) {
+		g := NewWithT(t)
+
+		fakeCosign(t, "Error: no matching signatures")
+		server, chartArchive, _ := newChartTestRegistry(t)
+		defer server.Close()
+
+		err := verifyTestChart(t, server, chartArchive, &ChartVerification{CosignPublicKey: publicKey})
+		g.Expect(err).To(MatchError("cosign verify: Error: no matching signatures"))
+	})
+
+	t.Run("chart not matching the signed manifest", func(t *testing.T) {
+		g := NewWithT(t)
+
+		dir := fakeCosign(t, "")
+		server, _, _ := newChartTestRegistry(t)
+		defer server.Close()
+
+		err := verifyTestChart(t, server, []byte("tampered chart archive"), &ChartVerification{CosignPublicKey: publicKey})
+		g.Expect(err).To(MatchError(ContainSubstring("does not match the signed chart layer")))
+		g.Expect(filepath.Join(dir, "args")).NotTo(BeAnExistingFile(), "cosign is not run for charts not matching the manifest")
+	})
+}
+
+func TestVerifyCosignSignatureKeyless(t *testing.T) {
+	g := NewWithT(t)
+
+	dir := fakeCosign(t, "")
+	server, chartArchive, _ := newChartTestRegistry(t)
+	defer server.Close()
+
+	verification := &ChartVerification{
+		CosignKeyless: &CosignKeylessVerification{
+			Issuer:             "https://token.actions.githubusercontent.com",
+			Subject:            "https://github.com/org/repo/.github/workflows/release.yaml@refs/heads/main",
+			FulcioCertificates: []byte("fulcio"),
+			RekorPublicKey:     []byte("rekor"),
+		},
+	}
+	g.Expect(verifyTestChart(t, server, chartArchive, verification)).To(Succeed())
+
+	args := strings.Fields(readCosignFile(t, dir, "args"))
+	g.Expect(args).To(ContainElements(
+		"--certificate-identity=https://github.com/org/repo/.github/workflows/release.yaml@refs/heads/main",
+		"--certificate-oidc-issuer=https://token.actions.githubusercontent.com",
+		"--offline",
+	))
+	g.Expect(args).NotTo(ContainElement(HavePrefix("--key")))
+	g.Expect(readCosignFile(t, dir, "fulcio.crt")).To(Equal("fulcio"))
+	g.Expect(readCosignFile(t, dir, "rekor.pub")).To(Equal("rekor"))
+}
+
+func TestCosignVerifyRegistryOptions(t *testing.T) {
+	g := NewWithT(t)
+
+	dir := fakeCosign(t, "")
+	credentialsPath := filepath.Join(t.TempDir(), "config.json")
+	g.Expect(os.WriteFile(credentialsPath, []byte(`{"auths":{}}`), 0o600)).To(Succeed())
+
+	opts := cosignOptions{
+		credentialsPath: credentialsPath,
+		caFilePath:      "/tmp/ca.crt",
+		repositoryAuth: RepositoryAuth{
+			CertFile:  "/tmp/tls.crt",
+			KeyFile:   "/tmp/tls.key",
+			ProxyURL:  "http://proxy.example.com:3128",
+			PlainHTTP: true,
+		},
+	}
+	g.Expect(cosignVerify(context.TODO(), "registry.example.com/charts/test-chart@sha256:abc", &ChartVerification{CosignPublicKey: []byte("key")}, opts)).To(Succeed())
+
+	g.Expect(strings.Fields(readCosignFile(t, dir, "args"))).To(ContainElements(
+		"--registry-cacert=/tmp/ca.crt",
+		"--registry-client-cert=/tmp/tls.crt",
+		"--registry-client-key=/tmp/tls.key",
+		"--allow-insecure-registry",
+		"--allow-http-registry",
+	))
+	g.Expect(strings.Split(readCosignFile(t, dir, "env"), "\n")).To(ContainElement("HTTPS_PROXY=http://proxy.example.com:3128"))
+	g.Expect(readCosignFile(t, dir, "config.json")).To(Equal(`{"auths":{}}`))
+}
+
+func TestLimitedBuffer(t *testing.T) {
+	g := NewWithT(t)
+
+	b := &limitedBuffer{limit: 4}
+	g.Expect(b.Write([]byte("abc"))).To(Equal(3))
+	_, err := b.Write([]byte("de"))
+	g.Expect(err).To(MatchError("output exceeds 4 bytes"))
+	g.Expect(b.String()).To(Equal("abc"))
+}
+
+func TestVerifyChartProvenance(t *testing.T) {
+	g := NewWithT(t)
+
+	spec := addonsv1alpha1.HelmReleaseProxySpec{ChartName: "signtest", RepoURL: "https://charts.example.com"}
+	repositoryAuth := RepositoryAuth{Verification: &ChartVerification{KeyringFile: "testdata/helm-test-key.pub"}}
+	g.Expect(verifyChart(context.TODO(), "testdata/signtest-0.1.0.tgz", spec, "", "", repositoryAuth)).To(Succeed())
+
+	// A chart without a provenance file fails verification.
+	dir := t.TempDir()
+	chart, err := os.ReadFile("testdata/signtest-0.1.0.tgz")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(os.WriteFile(filepath.Join(dir, "signtest-0.1.0.tgz"), chart, 0o600)).To(Succeed())
+	err = verifyChart(context.TODO(), filepath.Join(dir, "signtest-0.1.0.tgz"), spec, "", "", repositoryAuth)
+	var verificationErr *ChartVerificationError
+	g.Expect(errors.As(err, &verificationErr)).To(BeTrue())
+	g.Expect(verificationErr.Chart).To(Equal("signtest"))
+
+	// Charts are not verified without verification keys.
+	g.Expect(verifyChart(context.TODO(), filepath.Join(dir, "signtest-0.1.0.tgz"), spec, "", "", RepositoryAuth{})).To(Succeed())
+}
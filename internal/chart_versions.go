@@ -0,0 +1,242 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/registry"
+	"k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/utils/ptr"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+)
+
+const (
+	// chartVersionsCacheTTL is the duration for which the versions of a chart listed from its repository are reused.
+	chartVersionsCacheTTL = 10 * time.Minute
+
+	// chartVersionsCacheSize is the maximum number of charts whose versions are cached. The charts used least recently
+	// are evicted first.
+	chartVersionsCacheSize = 1024
+
+	// maxTagPages is the maximum number of pages of tags listed from a repository.
+	maxTagPages = 100
+
+	// maxTagListSize is the maximum size of all pages of tags listed from a repository.
+	maxTagListSize = 4 << 20
+
+	// maxChartUpgrades is the number of most recent versions a chart can be upgraded to that are reported.
+	maxChartUpgrades = 10
+)
+
+// chartVersionsCache caches the versions of the charts listed from their repositories, as every reconcile of a
+// HelmChartProxy lists them.
+var chartVersionsCache = cache.NewLRUExpireCache(chartVersionsCacheSize)
+
+// ListChartVersions lists the versions of the OCI chart of the spec from the tags of its repository, newest first. Tags
+// that are not semantic versions are left out. Charts from other repositories, from ChartBundles and from repositories
+// requiring credentials or custom certificates are not listed and have no versions.
+func ListChartVersions(ctx context.Context, spec addonsv1alpha1.HelmChartProxySpec) ([]string, error) {
+	tlsConfig := ptr.Deref(spec.TLSConfig, addonsv1alpha1.TLSConfig{})
+	if !registry.IsOCI(spec.RepoURL) || spec.ChartBundleRef != nil || spec.Credentials != nil || spec.RepositoryHeaders != nil || spec.RepositoryCredentials != nil || tlsConfig.CASecretRef != nil || tlsConfig.CertManagerRef != nil {
+		return nil, nil
+	}
+
+	key := chartCacheKey(spec.RepoURL, spec.ChartName, "")
+	if versions, ok := chartVersionsCache.Get(key); ok {
+		return versions.([]string), nil
+	}
+
+	repo, err := newOCIRepository(addonsv1alpha1.HelmReleaseProxySpec{RepoURL: spec.RepoURL, ChartName: spec.ChartName, TLSConfig: spec.TLSConfig}, "", "", RepositoryAuth{})
+	if err != nil {
+		return nil, err
+	}
+	tags, err := repo.tags(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list tags of chart %s", spec.ChartName)
+	}
+	versions := chartVersionsFromTags(tags)
+
+	chartVersionsCache.Add(key, versions, chartVersionsCacheTTL)
+
+	return versions, nil
+}
+
+// tags lists the tags of the repository, following the pagination links of the registry up to maxTagPages pages of at
+// most maxTagListSize bytes in total.
+func (r *ociRepository) tags(ctx context.Context) ([]string, error) {
+	tags := []string{}
+	remaining := int64(maxTagListSize)
+	urlPath := "tags/list"
+	for page := 0; urlPath != ""; page++ {
+		if page == maxTagPages {
+			return nil, errors.Errorf("tags of %s exceed %d pages", r.name, maxTagPages)
+		}
+		resp, err := r.get(ctx, urlPath)
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := io.ReadAll(io.LimitReader(resp.Body, remaining+1))
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(body)) > remaining {
+			return nil, errors.Errorf("tags of %s exceed %d bytes", r.name, maxTagListSize)
+		}
+		remaining -= int64(len(body))
+		if resp.StatusCode != http.StatusOK {
+			return nil, errors.Errorf("unexpected status %s listing tags of %s", resp.Status, r.name)
+		}
+
+		list := struct {
+			Tags []string `json:"tags"`
+		}{}
+		if err := json.Unmarshal(body, &list); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode tags of %s", r.name)
+		}
+		tags = append(tags, list.Tags...)
+
+		urlPath, err = r.nextPagePath(resp.Header.Get("Link"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tags, nil
+}
+
+// nextPagePath returns the path of the next page of a paginated response relative to the repository from its Link
+// header, or an empty path if it is the last page.
+func (r *ociRepository) nextPagePath(link string) (string, error) {
+	if link == "" {
+		return "", nil
+	}
+
+	target, _, _ := strings.Cut(link, ";")
+	next, err := url.Parse(strings.Trim(strings.TrimSpace(target), "<>"))
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid pagination link %s", link)
+	}
+	prefix := "/v2/" + r.name + "/"
+	if !strings.HasPrefix(next.Path, prefix) {
+		return "", errors.Errorf("pagination link %s is outside of repository %s", link, r.name)
+	}
+
+	urlPath := strings.TrimPrefix(next.Path, prefix)
+	if next.RawQuery != "" {
+		urlPath += "?" + next.RawQuery
+	}
+
+	return urlPath, nil
+}
+
+// chartVersionsFromTags returns the chart versions of the tags, newest first. Tags that are not semantic versions are
+// left out, and the "_" Helm replaces "+" with in tags is turned back.
+func chartVersionsFromTags(tags []string) []string {
+	parsed := []*semver.Version{}
+	for _, tag := range tags {
+		version, err := semver.StrictNewVersion(strings.ReplaceAll(tag, "_", "+"))
+		if err != nil {
+			continue
+		}
+		parsed = append(parsed, version)
+	}
+	sort.Sort(sort.Reverse(semver.Collection(parsed)))
+
+	versions := make([]string, 0, len(parsed))
+	for _, version := range parsed {
+		versions = append(versions, version.Original())
+	}
+
+	return versions
+}
+
+// ChartVersionsFor returns the latest stable version of the listed chart versions, the newest version satisfying the
+// version constraint, which resolves to the latest stable version if it is empty, and the stable versions newer than
+// the resolved version, newest first. The versions are expected newest first, as returned by ListChartVersions.
+func ChartVersionsFor(versions []string, constraint string) (*addonsv1alpha1.ChartVersions, error) {
+	if constraint == "" {
+		constraint = "*"
+	}
+	constraints, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid version constraint %s", constraint)
+	}
+
+	chartVersions := &addonsv1alpha1.ChartVersions{}
+	parsed := make([]*semver.Version, 0, len(versions))
+	for _, v := range versions {
+		version, err := semver.NewVersion(v)
+		if err != nil {
+			continue
+		}
+		parsed = append(parsed, version)
+		if chartVersions.Latest == "" && version.Prerelease() == "" {
+			chartVersions.Latest = v
+		}
+	}
+
+	upgrades := []string{}
+	for _, version := range parsed {
+		v := version.Original()
+		if constraints.Check(version) {
+			chartVersions.Resolved = v
+			break
+		}
+		if version.Prerelease() == "" {
+			upgrades = append(upgrades, v)
+		}
+	}
+	if chartVersions.Resolved == "" {
+		// Nothing satisfies the constraint, so there is no version to upgrade from.
+		return chartVersions, nil
+	}
+
+	if len(upgrades) > maxChartUpgrades {
+		upgrades = upgrades[:maxChartUpgrades]
+	}
+	if len(upgrades) > 0 {
+		chartVersions.Upgrades = upgrades
+	}
+
+	return chartVersions, nil
+}
+
+// matchesVersionConstraint returns true if the chart version satisfies the semver constraint.
+func matchesVersionConstraint(version, constraint string) bool {
+	constraints, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return false
+	}
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false
+	}
+
+	return constraints.Check(v)
+}
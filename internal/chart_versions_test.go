@@ -0,0 +1,152 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+)
+
+func TestOCIRepositoryTags(t *testing.T) {
+	g := NewWithT(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/charts/test-chart/tags/list", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("last") == "" {
+			w.Header().Set("Link", `</v2/charts/test-chart/tags/list?last=1.1.0&n=2>; rel="next"`)
+			_, _ = w.Write([]byte(`{"name":"charts/test-chart","tags":["1.0.0","1.1.0"]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"name":"charts/test-chart","tags":["2.0.0","latest"]}`))
+	})
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+
+	creds, err := registryCredentials("")
+	g.Expect(err).NotTo(HaveOccurred())
+	repo := newOCIRepositoryWithClient(strings.TrimPrefix(server.URL, "https://"), "charts/test-chart", server.Client(), creds)
+
+	tags, err := repo.tags(context.TODO())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(tags).To(Equal([]string{"1.0.0", "1.1.0", "2.0.0", "latest"}))
+
+	_, err = repo.nextPagePath(`</v2/charts/other-chart/tags/list?last=1.1.0>; rel="next"`)
+	g.Expect(err).To(MatchError(ContainSubstring("outside of repository charts/test-chart")))
+}
+
+func TestOCIRepositoryTagsLimits(t *testing.T) {
+	g := NewWithT(t)
+
+	mux := http.NewServeMux()
+	// The registry links every page to another one.
+	mux.HandleFunc("/v2/charts/endless-chart/tags/list", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Link", `</v2/charts/endless-chart/tags/list?last=1.0.0&n=1>; rel="next"`)
+		_, _ = w.Write([]byte(`{"name":"charts/endless-chart","tags":["1.0.0"]}`))
+	})
+	mux.HandleFunc("/v2/charts/large-chart/tags/list", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"name":"charts/large-chart","tags":[`))
+		_, _ = w.Write([]byte(strings.Repeat(" ", maxTagListSize)))
+		_, _ = w.Write([]byte(`]}`))
+	})
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+
+	creds, err := registryCredentials("")
+	g.Expect(err).NotTo(HaveOccurred())
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	_, err = newOCIRepositoryWithClient(host, "charts/endless-chart", server.Client(), creds).tags(context.TODO())
+	g.Expect(err).To(MatchError(ContainSubstring("exceed 100 pages")))
+
+	_, err = newOCIRepositoryWithClient(host, "charts/large-chart", server.Client(), creds).tags(context.TODO())
+	g.Expect(err).To(MatchError(ContainSubstring("exceed 4194304 bytes")))
+}
+
+func TestChartVersionsFromTags(t *testing.T) {
+	g := NewWithT(t)
+
+	versions := chartVersionsFromTags([]string{"1.0.0", "latest", "2.0.0-rc.1", "1.10.0", "v1.2.0", "1.2.0_build.1", "sha256-abc"})
+	g.Expect(versions).To(Equal([]string{"2.0.0-rc.1", "1.10.0", "1.2.0+build.1", "1.0.0"}))
+}
+
+func TestChartVersionsFor(t *testing.T) {
+	versions := []string{"3.0.0-rc.1", "2.1.0", "2.0.0", "1.2.0", "1.1.0", "1.0.0"}
+
+	testCases := []struct {
+		name          string
+		versions      []string
+		constraint    string
+		expected      *addonsv1alpha1.ChartVersions
+		expectedError bool
+	}{
+		{
+			name:     "unpinned version resolves to the latest stable version",
+			versions: versions,
+			expected: &addonsv1alpha1.ChartVersions{Latest: "2.1.0", Resolved: "2.1.0"},
+		},
+		{
+			name:       "pinned version",
+			versions:   versions,
+			constraint: "1.1.0",
+			expected:   &addonsv1alpha1.ChartVersions{Latest: "2.1.0", Resolved: "1.1.0", Upgrades: []string{"2.1.0", "2.0.0", "1.2.0"}},
+		},
+		{
+			name:       "version constraint",
+			versions:   versions,
+			constraint: "~1.1",
+			expected:   &addonsv1alpha1.ChartVersions{Latest: "2.1.0", Resolved: "1.1.0", Upgrades: []string{"2.1.0", "2.0.0", "1.2.0"}},
+		},
+		{
+			name:       "prerelease version",
+			versions:   versions,
+			constraint: ">=3.0.0-0",
+			expected:   &addonsv1alpha1.ChartVersions{Latest: "2.1.0", Resolved: "3.0.0-rc.1"},
+		},
+		{
+			name:       "no version satisfies the constraint",
+			versions:   versions,
+			constraint: "4.0.0",
+			expected:   &addonsv1alpha1.ChartVersions{Latest: "2.1.0"},
+		},
+		{
+			name:          "invalid constraint",
+			versions:      versions,
+			constraint:    "not a version",
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			chartVersions, err := ChartVersionsFor(tc.versions, tc.constraint)
+			if tc.expectedError {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(chartVersions).To(Equal(tc.expected))
+		})
+	}
+}
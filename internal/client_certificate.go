@@ -0,0 +1,78 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"crypto/tls"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CertManagerCertificateGVK is the kind of cert-manager Certificates. It is read as unstructured, so that cert-manager is
+// only required when a client certificate is referenced.
+var CertManagerCertificateGVK = schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"}
+
+// CertManagerCertificateSecretName returns the name of the Secret cert-manager stores the certificate of the Certificate in.
+func CertManagerCertificateSecretName(ctx context.Context, c ctrlClient.Client, key ctrlClient.ObjectKey) (string, error) {
+	certificate := &unstructured.Unstructured{}
+	certificate.SetGroupVersionKind(CertManagerCertificateGVK)
+	if err := c.Get(ctx, key, certificate); err != nil {
+		if meta.IsNoMatchError(err) {
+			return "", errors.Wrapf(err, "failed to get Certificate %s, is cert-manager installed?", key)
+		}
+
+		return "", errors.Wrapf(err, "failed to get Certificate %s", key)
+	}
+
+	secretName, _, err := unstructured.NestedString(certificate.Object, "spec", "secretName")
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get Secret name of Certificate %s", key)
+	}
+	if secretName == "" {
+		return "", errors.Errorf("Certificate %s has no Secret name", key)
+	}
+
+	return secretName, nil
+}
+
+// GetCertManagerClientCertificate returns the PEM-encoded client certificate and key issued for the cert-manager
+// Certificate. An error is returned until the Certificate has been issued.
+func GetCertManagerClientCertificate(ctx context.Context, c ctrlClient.Client, key ctrlClient.ObjectKey) (certPEM, keyPEM []byte, err error) {
+	secretName, err := CertManagerCertificateSecretName(ctx, c, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	secret := &corev1.Secret{}
+	secretKey := ctrlClient.ObjectKey{Namespace: key.Namespace, Name: secretName}
+	if err := c.Get(ctx, secretKey, secret); err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to get Secret %s of Certificate %s", secretKey, key)
+	}
+
+	certPEM, keyPEM = secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey]
+	if _, err := tls.X509KeyPair(certPEM, keyPEM); err != nil {
+		return nil, nil, errors.Wrapf(err, "Secret %s of Certificate %s does not hold a valid client certificate", secretKey, key)
+	}
+
+	return certPEM, keyPEM, nil
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGetCertManagerClientCertificate(t *testing.T) {
+	g := NewWithT(t)
+
+	certPEM, err := os.ReadFile("testdata/client-cert.pem")
+	g.Expect(err).NotTo(HaveOccurred())
+	keyPEM, err := os.ReadFile("testdata/client-key.pem")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	certificate := func(name, secretName string) *unstructured.Unstructured {
+		certificate := &unstructured.Unstructured{}
+		certificate.SetGroupVersionKind(CertManagerCertificateGVK)
+		certificate.SetNamespace("default")
+		certificate.SetName(name)
+		if secretName != "" {
+			g.Expect(unstructured.SetNestedField(certificate.Object, secretName, "spec", "secretName")).To(Succeed())
+		}
+
+		return certificate
+	}
+
+	testCases := []struct {
+		name          string
+		objects       []ctrlClient.Object
+		certificate   string
+		expectedError string
+	}{
+		{
+			name: "issued certificate",
+			objects: []ctrlClient.Object{
+				certificate("client", "client-tls"),
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "client-tls"},
+					Data:       map[string][]byte{corev1.TLSCertKey: certPEM, corev1.TLSPrivateKeyKey: keyPEM},
+				},
+			},
+			certificate: "client",
+		},
+		{
+			name:          "missing certificate",
+			certificate:   "client",
+			expectedError: "failed to get Certificate default/client",
+		},
+		{
+			name:          "certificate without secret name",
+			objects:       []ctrlClient.Object{certificate("client", "")},
+			certificate:   "client",
+			expectedError: "has no Secret name",
+		},
+		{
+			name:          "certificate not issued yet",
+			objects:       []ctrlClient.Object{certificate("client", "client-tls")},
+			certificate:   "client",
+			expectedError: "failed to get Secret default/client-tls",
+		},
+		{
+			name: "secret without key",
+			objects: []ctrlClient.Object{
+				certificate("client", "client-tls"),
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "client-tls"},
+					Data:       map[string][]byte{corev1.TLSCertKey: certPEM},
+				},
+			},
+			certificate:   "client",
+			expectedError: "does not hold a valid client certificate",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			scheme := runtime.NewScheme()
+			_ = corev1.AddToScheme(scheme)
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tc.objects...).Build()
+
+			cert, key, err := GetCertManagerClientCertificate(context.TODO(), c, ctrlClient.ObjectKey{Namespace: "default", Name: tc.certificate})
+			if tc.expectedError != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tc.expectedError)))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(cert).To(Equal(certPEM))
+			g.Expect(key).To(Equal(keyPEM))
+		})
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// clusterOperations is the queue of the Helm operations of a single workload Cluster.
+type clusterOperations struct {
+	queued   int
+	inFlight int
+	slots    chan struct{}
+}
+
+// ClusterOperationQueue limits the number of Helm operations running at once on each workload Cluster, queueing the others,
+// and tracks the depth of the queue and the operations in flight per Cluster. A nil ClusterOperationQueue neither limits
+// nor tracks the operations.
+type ClusterOperationQueue struct {
+	mu          sync.Mutex
+	maxInFlight int
+	clusters    map[types.NamespacedName]*clusterOperations
+}
+
+// NewClusterOperationQueue returns a ClusterOperationQueue running at most maxInFlight Helm operations at once per Cluster.
+// Setting it to 1 serializes the Helm operations of each Cluster. A maxInFlight of 0 does not limit the operations, which
+// are then only tracked.
+func NewClusterOperationQueue(maxInFlight int) *ClusterOperationQueue {
+	return &ClusterOperationQueue{
+		maxInFlight: maxInFlight,
+		clusters:    map[types.NamespacedName]*clusterOperations{},
+	}
+}
+
+// Acquire waits until a Helm operation can run on the Cluster and returns the function to call once it is done. An error is
+// returned if the context is done while the operation is queued.
+func (q *ClusterOperationQueue) Acquire(ctx context.Context, cluster types.NamespacedName) (func(), error) {
+	if q == nil {
+		return func() {}, nil
+	}
+
+	q.mu.Lock()
+	ops, ok := q.clusters[cluster]
+	if !ok {
+		ops = &clusterOperations{}
+		if q.maxInFlight > 0 {
+			ops.slots = make(chan struct{}, q.maxInFlight)
+		}
+		q.clusters[cluster] = ops
+	}
+	ops.queued++
+	q.record(cluster, ops)
+	q.mu.Unlock()
+
+	var err error
+	if ops.slots != nil {
+		select {
+		case ops.slots <- struct{}{}:
+		case <-ctx.Done():
+			err = errors.Wrapf(ctx.Err(), "Helm operation on cluster %s was cancelled while queued", cluster)
+		}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	ops.queued--
+	if err != nil {
+		q.record(cluster, ops)
+		return nil, err
+	}
+	ops.inFlight++
+	q.record(cluster, ops)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			ops.inFlight--
+			if ops.slots != nil {
+				<-ops.slots
+			}
+			q.record(cluster, ops)
+		})
+	}, nil
+}
+
+// Counts returns the number of queued and in-flight Helm operations of the Cluster.
+func (q *ClusterOperationQueue) Counts(cluster types.NamespacedName) (queued, inFlight int) {
+	if q == nil {
+		return 0, 0
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	ops, ok := q.clusters[cluster]
+	if !ok {
+		return 0, 0
+	}
+
+	return ops.queued, ops.inFlight
+}
+
+// record updates the metrics of the Cluster and forgets it once it has no queued or in-flight operations. It must be called
+// with the lock held.
+func (q *ClusterOperationQueue) record(cluster types.NamespacedName, ops *clusterOperations) {
+	if ops.queued == 0 && ops.inFlight == 0 {
+		delete(q.clusters, cluster)
+		clusterOperationsQueuedGauge.DeleteLabelValues(cluster.Namespace, cluster.Name)
+		clusterOperationsInFlightGauge.DeleteLabelValues(cluster.Namespace, cluster.Name)
+
+		return
+	}
+
+	clusterOperationsQueuedGauge.WithLabelValues(cluster.Namespace, cluster.Name).Set(float64(ops.queued))
+	clusterOperationsInFlightGauge.WithLabelValues(cluster.Namespace, cluster.Name).Set(float64(ops.inFlight))
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestClusterOperationQueue(t *testing.T) {
+	g := NewWithT(t)
+
+	queue := NewClusterOperationQueue(1)
+	cluster := types.NamespacedName{Namespace: "default", Name: "test-cluster"}
+
+	release, err := queue.Acquire(context.Background(), cluster)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	acquired := make(chan func())
+	go func() {
+		release, err := queue.Acquire(context.Background(), cluster)
+		if err == nil {
+			acquired <- release
+		}
+	}()
+	g.Eventually(func() int {
+		queued, _ := queue.Counts(cluster)
+		return queued
+	}).Should(Equal(1))
+	queued, inFlight := queue.Counts(cluster)
+	g.Expect(queued).To(Equal(1))
+	g.Expect(inFlight).To(Equal(1))
+
+	// A queued operation is cancelled with its context.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = queue.Acquire(ctx, cluster)
+	g.Expect(err).To(HaveOccurred())
+
+	other := types.NamespacedName{Namespace: "default", Name: "other-cluster"}
+	releaseOther, err := queue.Acquire(context.Background(), other)
+	g.Expect(err).NotTo(HaveOccurred(), "operations are queued per Cluster")
+	releaseOther()
+
+	release()
+	release()
+	var releaseQueued func()
+	g.Eventually(acquired).Should(Receive(&releaseQueued))
+	queued, inFlight = queue.Counts(cluster)
+	g.Expect(queued).To(Equal(0))
+	g.Expect(inFlight).To(Equal(1))
+
+	releaseQueued()
+	queued, inFlight = queue.Counts(cluster)
+	g.Expect(queued).To(Equal(0))
+	g.Expect(inFlight).To(Equal(0))
+	g.Expect(queue.clusters).To(BeEmpty())
+
+	var unset *ClusterOperationQueue
+	release, err = unset.Acquire(context.Background(), cluster)
+	g.Expect(err).NotTo(HaveOccurred())
+	release()
+	queued, inFlight = unset.Counts(cluster)
+	g.Expect(queued).To(Equal(0))
+	g.Expect(inFlight).To(Equal(0))
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	expv1 "sigs.k8s.io/cluster-api/exp/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// controlPlaneNodeRoleLabel is the label kubeadm and most bootstrap providers set on control plane nodes.
+const controlPlaneNodeRoleLabel = "node-role.kubernetes.io/control-plane"
+
+// CheckClusterCapacity checks whether a Cluster has reached the capacity required by the ClusterReadinessOptions. Worker
+// nodes are listed with the workload Cluster client, while MachineDeployments and MachinePools are read with the management
+// Cluster client from the given namespace. If the capacity is not reached, a message describing what is still missing is
+// returned.
+func CheckClusterCapacity(ctx context.Context, c client.Client, workloadClient client.Client, namespace string, opts *addonsv1alpha1.ClusterReadinessOptions) (bool, string, error) {
+	if opts == nil {
+		return true, "", nil
+	}
+
+	if opts.ReadyWorkerNodes != nil {
+		nodes := &corev1.NodeList{}
+		if err := workloadClient.List(ctx, nodes); err != nil {
+			return false, "", errors.Wrapf(err, "failed to list nodes")
+		}
+
+		ready := countReadyWorkerNodes(nodes.Items)
+		if ready < int(*opts.ReadyWorkerNodes) {
+			return false, fmt.Sprintf("%d of %d required worker nodes are ready", ready, *opts.ReadyWorkerNodes), nil
+		}
+	}
+
+	if opts.MachineDeployment != nil {
+		machineDeployment := &clusterv1.MachineDeployment{}
+		key := types.NamespacedName{Namespace: namespace, Name: opts.MachineDeployment.Name}
+		if err := c.Get(ctx, key, machineDeployment); err != nil {
+			return false, "", errors.Wrapf(err, "failed to get MachineDeployment %s", key)
+		}
+
+		var minReady int32
+		switch {
+		case opts.MachineDeployment.MinReadyReplicas != nil:
+			minReady = *opts.MachineDeployment.MinReadyReplicas
+		case machineDeployment.Spec.Replicas != nil:
+			minReady = *machineDeployment.Spec.Replicas
+		}
+
+		if machineDeployment.Status.ReadyReplicas < minReady {
+			return false, fmt.Sprintf("%d of %d required replicas of MachineDeployment %s are ready", machineDeployment.Status.ReadyReplicas, minReady, key.Name), nil
+		}
+	}
+
+	if opts.MachinePool != nil {
+		machinePool := &expv1.MachinePool{}
+		key := types.NamespacedName{Namespace: namespace, Name: opts.MachinePool.Name}
+		if err := c.Get(ctx, key, machinePool); err != nil {
+			return false, "", errors.Wrapf(err, "failed to get MachinePool %s", key)
+		}
+
+		var minReady int32
+		switch {
+		case opts.MachinePool.MinReadyReplicas != nil:
+			minReady = *opts.MachinePool.MinReadyReplicas
+		case machinePool.Spec.Replicas != nil:
+			minReady = *machinePool.Spec.Replicas
+		}
+
+		if machinePool.Status.ReadyReplicas < minReady {
+			return false, fmt.Sprintf("%d of %d required replicas of MachinePool %s are ready", machinePool.Status.ReadyReplicas, minReady, key.Name), nil
+		}
+	}
+
+	return true, "", nil
+}
+
+// countReadyWorkerNodes returns the number of nodes that have a true Ready condition and are not control plane nodes.
+func countReadyWorkerNodes(nodes []corev1.Node) int {
+	ready := 0
+	for _, node := range nodes {
+		if _, ok := node.Labels[controlPlaneNodeRoleLabel]; ok {
+			continue
+		}
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == corev1.NodeReady && condition.Status == corev1.ConditionTrue {
+				ready++
+				break
+			}
+		}
+	}
+
+	return ready
+}
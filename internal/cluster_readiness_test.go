@@ -0,0 +1,156 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	expv1 "sigs.k8s.io/cluster-api/exp/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCheckClusterCapacity(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = clusterv1.AddToScheme(scheme)
+	_ = expv1.AddToScheme(scheme)
+
+	node := func(name string, controlPlane, ready bool) *corev1.Node {
+		n := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{}}}
+		if controlPlane {
+			n.Labels[controlPlaneNodeRoleLabel] = ""
+		}
+		status := corev1.ConditionFalse
+		if ready {
+			status = corev1.ConditionTrue
+		}
+		n.Status.Conditions = []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}}
+
+		return n
+	}
+
+	workloadObjects := []client.Object{
+		node("control-plane", true, true),
+		node("worker-1", false, true),
+		node("worker-2", false, false),
+	}
+
+	machineDeployment := &clusterv1.MachineDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "md-0", Namespace: "default"},
+		Spec:       clusterv1.MachineDeploymentSpec{Replicas: ptr.To[int32](3)},
+		Status:     clusterv1.MachineDeploymentStatus{ReadyReplicas: 2},
+	}
+
+	machinePool := &expv1.MachinePool{
+		ObjectMeta: metav1.ObjectMeta{Name: "pool-0", Namespace: "default"},
+		Spec:       expv1.MachinePoolSpec{Replicas: ptr.To[int32](3)},
+		Status:     expv1.MachinePoolStatus{ReadyReplicas: 1},
+	}
+
+	testCases := []struct {
+		name          string
+		opts          *addonsv1alpha1.ClusterReadinessOptions
+		expectReady   bool
+		expectMessage string
+		expectErr     bool
+	}{
+		{
+			name:        "ready when options are unset",
+			opts:        nil,
+			expectReady: true,
+		},
+		{
+			name:        "ready when enough worker nodes are ready",
+			opts:        &addonsv1alpha1.ClusterReadinessOptions{ReadyWorkerNodes: ptr.To[int32](1)},
+			expectReady: true,
+		},
+		{
+			name:          "not ready when worker nodes are not ready",
+			opts:          &addonsv1alpha1.ClusterReadinessOptions{ReadyWorkerNodes: ptr.To[int32](2)},
+			expectMessage: "1 of 2 required worker nodes are ready",
+		},
+		{
+			name: "not ready when MachineDeployment has not reached its desired replicas",
+			opts: &addonsv1alpha1.ClusterReadinessOptions{
+				MachineDeployment: &addonsv1alpha1.MachineDeploymentReadiness{Name: "md-0"},
+			},
+			expectMessage: "2 of 3 required replicas of MachineDeployment md-0 are ready",
+		},
+		{
+			name: "ready when MachineDeployment has reached its minimum ready replicas",
+			opts: &addonsv1alpha1.ClusterReadinessOptions{
+				MachineDeployment: &addonsv1alpha1.MachineDeploymentReadiness{Name: "md-0", MinReadyReplicas: ptr.To[int32](2)},
+			},
+			expectReady: true,
+		},
+		{
+			name: "error when MachineDeployment does not exist",
+			opts: &addonsv1alpha1.ClusterReadinessOptions{
+				MachineDeployment: &addonsv1alpha1.MachineDeploymentReadiness{Name: "md-missing"},
+			},
+			expectErr: true,
+		},
+		{
+			name: "not ready when MachinePool has not reached its desired replicas",
+			opts: &addonsv1alpha1.ClusterReadinessOptions{
+				MachinePool: &addonsv1alpha1.MachinePoolReadiness{Name: "pool-0"},
+			},
+			expectMessage: "1 of 3 required replicas of MachinePool pool-0 are ready",
+		},
+		{
+			name: "ready when MachinePool has reached its minimum ready replicas",
+			opts: &addonsv1alpha1.ClusterReadinessOptions{
+				MachinePool: &addonsv1alpha1.MachinePoolReadiness{Name: "pool-0", MinReadyReplicas: ptr.To[int32](1)},
+			},
+			expectReady: true,
+		},
+		{
+			name: "error when MachinePool does not exist",
+			opts: &addonsv1alpha1.ClusterReadinessOptions{
+				MachinePool: &addonsv1alpha1.MachinePoolReadiness{Name: "pool-missing"},
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(machineDeployment, machinePool).Build()
+			workloadClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(workloadObjects...).Build()
+
+			ready, message, err := CheckClusterCapacity(context.TODO(), c, workloadClient, "default", tc.opts)
+			if tc.expectErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(ready).To(Equal(tc.expectReady))
+			g.Expect(message).To(Equal(tc.expectMessage))
+		})
+	}
+}
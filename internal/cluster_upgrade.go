@@ -0,0 +1,71 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/controllers/external"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// IsClusterUpgrading checks whether the Kubernetes version of a Cluster's control plane is being upgraded. A control plane
+// is upgrading when the version in its spec differs from the version reported in its status, or, for a Cluster with a
+// managed topology, when the topology version has not yet been rolled out to the control plane. If the Cluster is
+// upgrading, a message describing the upgrade is returned. Control planes whose kind is not one of the template object kinds
+// are not read.
+func IsClusterUpgrading(ctx context.Context, c client.Client, cluster *clusterv1.Cluster, kinds TemplateObjectKinds) (bool, string, error) {
+	if cluster.Spec.ControlPlaneRef == nil {
+		return false, "", nil
+	}
+
+	ref := *cluster.Spec.ControlPlaneRef
+	if ref.Namespace == "" {
+		ref.Namespace = cluster.Namespace
+	}
+	if gk := ref.GroupVersionKind().GroupKind(); !kinds.allows(gk) {
+		return false, "", errors.Errorf("kind %s of control plane %s is not one of the template object kinds", gk, ref.Name)
+	}
+	controlPlane, err := external.Get(ctx, c, &ref)
+	if err != nil {
+		return false, "", errors.Wrapf(err, "failed to get control plane %s", ref.Name)
+	}
+
+	desired, _, err := unstructured.NestedString(controlPlane.Object, "spec", "version")
+	if err != nil {
+		return false, "", errors.Wrapf(err, "failed to get spec.version of control plane %s", ref.Name)
+	}
+	current, _, err := unstructured.NestedString(controlPlane.Object, "status", "version")
+	if err != nil {
+		return false, "", errors.Wrapf(err, "failed to get status.version of control plane %s", ref.Name)
+	}
+
+	if cluster.Spec.Topology != nil && cluster.Spec.Topology.Version != "" && cluster.Spec.Topology.Version != desired {
+		return true, fmt.Sprintf("control plane %s is pending upgrade to %s", ref.Name, cluster.Spec.Topology.Version), nil
+	}
+
+	// The status version is only reported once the control plane has machines, so an empty version is not an upgrade.
+	if desired != "" && current != "" && desired != current {
+		return true, fmt.Sprintf("control plane %s is upgrading from %s to %s", ref.Name, current, desired), nil
+	}
+
+	return false, "", nil
+}
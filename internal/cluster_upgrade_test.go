@@ -0,0 +1,107 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestIsClusterUpgrading(t *testing.T) {
+	controlPlane := func(desired, current string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{
+			"spec":   map[string]interface{}{"version": desired},
+			"status": map[string]interface{}{"version": current},
+		}}
+		obj.SetAPIVersion("controlplane.cluster.x-k8s.io/v1beta1")
+		obj.SetKind("KubeadmControlPlane")
+		obj.SetNamespace("test-namespace")
+		obj.SetName("test-control-plane")
+
+		return obj
+	}
+	cluster := func(topologyVersion string) *clusterv1.Cluster {
+		c := &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test-namespace", Name: "test-cluster"},
+			Spec: clusterv1.ClusterSpec{
+				ControlPlaneRef: &corev1.ObjectReference{
+					APIVersion: "controlplane.cluster.x-k8s.io/v1beta1",
+					Kind:       "KubeadmControlPlane",
+					Name:       "test-control-plane",
+				},
+			},
+		}
+		if topologyVersion != "" {
+			c.Spec.Topology = &clusterv1.Topology{Version: topologyVersion}
+		}
+
+		return c
+	}
+
+	testcases := []struct {
+		name         string
+		cluster      *clusterv1.Cluster
+		controlPlane *unstructured.Unstructured
+		expected     bool
+	}{
+		{
+			name:         "control plane is up to date",
+			cluster:      cluster(""),
+			controlPlane: controlPlane("v1.30.0", "v1.30.0"),
+			expected:     false,
+		},
+		{
+			name:         "control plane is rolling to a new version",
+			cluster:      cluster(""),
+			controlPlane: controlPlane("v1.30.0", "v1.29.3"),
+			expected:     true,
+		},
+		{
+			name:         "control plane has not reported a version yet",
+			cluster:      cluster(""),
+			controlPlane: controlPlane("v1.30.0", ""),
+			expected:     false,
+		},
+		{
+			name:         "topology version is pending on the control plane",
+			cluster:      cluster("v1.31.0"),
+			controlPlane: controlPlane("v1.30.0", "v1.30.0"),
+			expected:     true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			c := fake.NewClientBuilder().WithObjects(tc.controlPlane).Build()
+			upgrading, message, err := IsClusterUpgrading(context.TODO(), c, tc.cluster, nil)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(upgrading).To(Equal(tc.expected))
+			if tc.expected {
+				g.Expect(message).NotTo(BeEmpty())
+			}
+		})
+	}
+}
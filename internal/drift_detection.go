@@ -0,0 +1,178 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/pkg/errors"
+	helmAction "helm.sh/helm/v3/pkg/action"
+	helmKube "helm.sh/helm/v3/pkg/kube"
+	helmDriver "helm.sh/helm/v3/pkg/storage/driver"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/rest"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// DriftIgnoreRulesFor returns the DriftIgnoreRules of the spec together with the IgnoreRules of its DriftDetection.
+func DriftIgnoreRulesFor(spec addonsv1alpha1.HelmReleaseProxySpec) []addonsv1alpha1.DriftIgnoreRule {
+	if spec.DriftDetection == nil || len(spec.DriftDetection.IgnoreRules) == 0 {
+		return spec.DriftIgnoreRules
+	}
+
+	return append(append([]addonsv1alpha1.DriftIgnoreRule{}, spec.DriftIgnoreRules...), spec.DriftDetection.IgnoreRules...)
+}
+
+// ReconcileHelmReleaseDrift compares the objects of the manifest of the latest revision of a Helm release against the live
+// objects on the workload Cluster, ignoring the fields selected by the ignore rules of the spec, and returns the objects
+// that drifted, e.g. `Deployment ingress-nginx/controller`. If correct is set, the drifted objects are re-applied from
+// the manifest, recreating the ones that were deleted.
+func (c *HelmClient) ReconcileHelmReleaseDrift(ctx context.Context, restConfig *rest.Config, spec addonsv1alpha1.HelmReleaseProxySpec, correct bool) ([]string, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	if spec.ReleaseName == "" {
+		return nil, helmDriver.ErrReleaseNotFound
+	}
+
+	_, actionConfig, err := HelmInit(ctx, spec.ReleaseNamespace, restConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := helmAction.NewGet(actionConfig).Run(spec.ReleaseName)
+	if err != nil {
+		return nil, err
+	}
+
+	resources, err := actionConfig.KubeClient.Build(bytes.NewBufferString(release.Manifest), false)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build resources of release %s", release.Name)
+	}
+
+	rules := DriftIgnoreRulesFor(spec)
+	drifted := []string{}
+	var targets helmKube.ResourceList
+	for _, info := range resources {
+		desired, err := runtime.DefaultUnstructuredConverter.ToUnstructured(info.Object)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to convert resource %s/%s", info.Namespace, info.Name)
+		}
+		if err := RemoveIgnoredFields(desired, rules); err != nil {
+			return nil, err
+		}
+
+		var live map[string]interface{}
+		obj, err := resource.NewHelper(info.Client, info.Mapping).Get(info.Namespace, info.Name)
+		switch {
+		case apierrors.IsNotFound(err):
+		case err != nil:
+			return nil, errors.Wrapf(err, "failed to get resource %s/%s", info.Namespace, info.Name)
+		default:
+			if live, err = runtime.DefaultUnstructuredConverter.ToUnstructured(obj); err != nil {
+				return nil, errors.Wrapf(err, "failed to convert resource %s/%s", info.Namespace, info.Name)
+			}
+			if err := RemoveIgnoredFields(live, rules); err != nil {
+				return nil, err
+			}
+		}
+
+		if live != nil && !hasDrifted(desired, live) {
+			continue
+		}
+		kind := info.Object.GetObjectKind().GroupVersionKind().Kind
+		if info.Namespace != "" {
+			drifted = append(drifted, fmt.Sprintf("%s %s/%s", kind, info.Namespace, info.Name))
+		} else {
+			drifted = append(drifted, fmt.Sprintf("%s %s", kind, info.Name))
+		}
+		// The ignored fields are not re-applied, so that they keep their in-cluster values.
+		info.Object = &unstructured.Unstructured{Object: desired}
+		targets = append(targets, info)
+	}
+
+	if !correct || len(targets) == 0 {
+		return drifted, nil
+	}
+
+	log.V(2).Info("Re-applying drifted resources of release", "release", release.Name, "resources", drifted)
+	// The drifted resources are both the original and the target, so that only their changed fields are patched and no
+	// resource is deleted.
+	_, err = actionConfig.KubeClient.Update(targets, targets, false)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to re-apply drifted resources of release %s", release.Name)
+	}
+	c.AuditLog.record(ctx, AuditOperationDriftCorrection, spec, release, err)
+
+	return drifted, err
+}
+
+// hasDrifted returns true if a field of the desired object is not set to the same value in the live object. Fields only
+// set in the live object, e.g. defaulted by the API server or added by controllers, are not considered drift.
+func hasDrifted(desired, live interface{}) bool {
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+		if !ok {
+			return true
+		}
+		for key, value := range d {
+			if hasDrifted(value, l[key]) {
+				return true
+			}
+		}
+
+		return false
+	case []interface{}:
+		l, ok := live.([]interface{})
+		if !ok || len(l) != len(d) {
+			return true
+		}
+		for i := range d {
+			if hasDrifted(d[i], l[i]) {
+				return true
+			}
+		}
+
+		return false
+	case nil:
+		return false
+	default:
+		return !reflect.DeepEqual(normalizeNumber(desired), normalizeNumber(live))
+	}
+}
+
+// normalizeNumber returns the number as a float64, as numbers of the manifest and of the live object may be decoded as
+// integers or floats, and any other value unchanged.
+func normalizeNumber(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int64:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int:
+		return float64(v)
+	default:
+		return value
+	}
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+)
+
+func TestHasDrifted(t *testing.T) {
+	desired := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"replicas": int64(2),
+			"selector": map[string]interface{}{"app": "controller"},
+			"ports":    []interface{}{map[string]interface{}{"port": int64(80)}},
+		},
+	}
+
+	testcases := []struct {
+		name     string
+		live     map[string]interface{}
+		expected bool
+	}{
+		{
+			name: "fields only set in the live object are not drift",
+			live: map[string]interface{}{
+				"metadata": map[string]interface{}{"resourceVersion": "42"},
+				"spec": map[string]interface{}{
+					"replicas":                float64(2),
+					"selector":                map[string]interface{}{"app": "controller"},
+					"ports":                   []interface{}{map[string]interface{}{"port": int64(80), "protocol": "TCP"}},
+					"progressDeadlineSeconds": int64(600),
+				},
+			},
+		},
+		{
+			name: "changed field",
+			live: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"replicas": int64(3),
+					"selector": map[string]interface{}{"app": "controller"},
+					"ports":    []interface{}{map[string]interface{}{"port": int64(80)}},
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "removed list item",
+			live: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"replicas": int64(2),
+					"selector": map[string]interface{}{"app": "controller"},
+					"ports":    []interface{}{},
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "removed field",
+			live: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"replicas": int64(2),
+					"ports":    []interface{}{map[string]interface{}{"port": int64(80)}},
+				},
+			},
+			expected: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			g.Expect(hasDrifted(desired, tc.live)).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestDriftIgnoreRulesFor(t *testing.T) {
+	g := NewWithT(t)
+
+	spec := addonsv1alpha1.HelmReleaseProxySpec{
+		DriftIgnoreRules: []addonsv1alpha1.DriftIgnoreRule{{Paths: []string{"/spec/replicas"}}},
+	}
+	g.Expect(DriftIgnoreRulesFor(spec)).To(Equal(spec.DriftIgnoreRules))
+
+	spec.DriftDetection = &addonsv1alpha1.DriftDetection{
+		Enabled:     true,
+		IgnoreRules: []addonsv1alpha1.DriftIgnoreRule{{Kind: "Secret", Paths: []string{"/data"}}},
+	}
+	g.Expect(DriftIgnoreRulesFor(spec)).To(Equal([]addonsv1alpha1.DriftIgnoreRule{
+		{Paths: []string{"/spec/replicas"}},
+		{Kind: "Secret", Paths: []string{"/data"}},
+	}))
+	g.Expect(spec.DriftIgnoreRules).To(HaveLen(1))
+}
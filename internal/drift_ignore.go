@@ -0,0 +1,169 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+// RemoveIgnoredFields removes the fields selected by the DriftIgnoreRules applying to the object from it, so that known
+// benign mutations, e.g. of the replicas of a Deployment scaled by a HorizontalPodAutoscaler, are not compared.
+func RemoveIgnoredFields(obj map[string]interface{}, rules []addonsv1alpha1.DriftIgnoreRule) error {
+	kind, _, _ := unstructured.NestedString(obj, "kind")
+	name, _, _ := unstructured.NestedString(obj, "metadata", "name")
+	for _, rule := range rules {
+		if (rule.Kind != "" && rule.Kind != kind) || (rule.Name != "" && rule.Name != name) {
+			continue
+		}
+		for _, path := range rule.Paths {
+			segments, err := ignorePathSegments(path)
+			if err != nil {
+				return err
+			}
+			removeField(obj, segments)
+		}
+	}
+
+	return nil
+}
+
+// removeIgnoredManifestFields returns the manifest of a Helm release with the fields selected by the DriftIgnoreRules
+// removed from its objects. The manifest is returned unchanged if there are no rules.
+func removeIgnoredManifestFields(manifest string, rules []addonsv1alpha1.DriftIgnoreRule) (string, error) {
+	if len(rules) == 0 {
+		return manifest, nil
+	}
+
+	docs := releaseutil.SplitManifests(manifest)
+	keys := make([]string, 0, len(docs))
+	for key := range docs {
+		keys = append(keys, key)
+	}
+	sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+
+	var b strings.Builder
+	for _, key := range keys {
+		obj := map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(docs[key]), &obj); err != nil {
+			return "", errors.Wrap(err, "failed to parse manifest")
+		}
+		if err := RemoveIgnoredFields(obj, rules); err != nil {
+			return "", err
+		}
+		doc, err := yaml.Marshal(obj)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to marshal manifest")
+		}
+		b.WriteString("---\n")
+		b.Write(doc)
+	}
+
+	return b.String(), nil
+}
+
+// ignorePathSegments parses a JSON pointer, e.g. `/spec/replicas`, or a JSONPath, e.g. `.spec.replicas`,
+// `$.spec.template.spec.containers[*].image` or `.metadata.annotations['deployment.kubernetes.io/revision']`, into the
+// keys and indices along it. A `*` segment matches every key or index.
+func ignorePathSegments(path string) ([]string, error) {
+	if strings.HasPrefix(path, "/") {
+		segments := strings.Split(path[1:], "/")
+		for i, segment := range segments {
+			segments[i] = strings.ReplaceAll(strings.ReplaceAll(segment, "~1", "/"), "~0", "~")
+		}
+
+		return segments, nil
+	}
+
+	jsonPath := strings.TrimPrefix(path, "$")
+	if jsonPath != "" && jsonPath[0] != '.' && jsonPath[0] != '[' {
+		jsonPath = "." + jsonPath
+	}
+
+	segments := []string{}
+	for i := 0; i < len(jsonPath); {
+		var segment string
+		switch jsonPath[i] {
+		case '.':
+			end := strings.IndexAny(jsonPath[i+1:], ".[")
+			if end < 0 {
+				end = len(jsonPath) - i - 1
+			}
+			segment = jsonPath[i+1 : i+1+end]
+			i += 1 + end
+		case '[':
+			end := strings.IndexByte(jsonPath[i:], ']')
+			if end < 0 {
+				return nil, errors.Errorf("invalid ignore path %q: unterminated [", path)
+			}
+			segment = strings.Trim(jsonPath[i+1:i+end], `'"`)
+			i += end + 1
+		default:
+			return nil, errors.Errorf("invalid ignore path %q: unexpected %q", path, jsonPath[i])
+		}
+		if segment == "" {
+			return nil, errors.Errorf("invalid ignore path %q: empty segment", path)
+		}
+		segments = append(segments, segment)
+	}
+	if len(segments) == 0 {
+		return nil, errors.Errorf("invalid ignore path %q: no field selected", path)
+	}
+
+	return segments, nil
+}
+
+// removeField returns the value with the fields at the segments removed from it. Maps are modified in place, while lists
+// are copied when an item is removed.
+func removeField(value interface{}, segments []string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, next := range v {
+			if segments[0] != "*" && segments[0] != key {
+				continue
+			}
+			if len(segments) == 1 {
+				delete(v, key)
+			} else {
+				v[key] = removeField(next, segments[1:])
+			}
+		}
+
+		return v
+	case []interface{}:
+		kept := make([]interface{}, 0, len(v))
+		for i, next := range v {
+			switch {
+			case segments[0] != "*" && segments[0] != strconv.Itoa(i):
+				kept = append(kept, next)
+			case len(segments) > 1:
+				kept = append(kept, removeField(next, segments[1:]))
+			}
+		}
+
+		return kept
+	default:
+		return value
+	}
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestIgnorePathSegments(t *testing.T) {
+	testcases := []struct {
+		path     string
+		expected []string
+		err      bool
+	}{
+		{path: "/spec/replicas", expected: []string{"spec", "replicas"}},
+		{path: "/metadata/annotations/sidecar.istio.io~1status", expected: []string{"metadata", "annotations", "sidecar.istio.io/status"}},
+		{path: "spec.replicas", expected: []string{"spec", "replicas"}},
+		{path: ".spec.replicas", expected: []string{"spec", "replicas"}},
+		{path: "$.spec.template.spec.containers[*].image", expected: []string{"spec", "template", "spec", "containers", "*", "image"}},
+		{path: ".metadata.annotations['deployment.kubernetes.io/revision']", expected: []string{"metadata", "annotations", "deployment.kubernetes.io/revision"}},
+		{path: "spec.containers[0", err: true},
+		{path: "spec..replicas", err: true},
+		{path: "$", err: true},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.path, func(t *testing.T) {
+			g := NewWithT(t)
+
+			segments, err := ignorePathSegments(tc.path)
+			if tc.err {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(segments).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestRemoveIgnoredFields(t *testing.T) {
+	g := NewWithT(t)
+
+	obj := map[string]interface{}{}
+	g.Expect(yaml.Unmarshal([]byte(`
+kind: Deployment
+metadata:
+  name: ingress-nginx
+  annotations:
+    deployment.kubernetes.io/revision: "3"
+    owner: platform
+spec:
+  replicas: 5
+  template:
+    spec:
+      containers:
+      - name: controller
+        image: controller:v1
+        resources: {}
+      - name: sidecar
+        image: sidecar:v1
+`), &obj)).To(Succeed())
+
+	rules := []addonsv1alpha1.DriftIgnoreRule{
+		{Kind: "Deployment", Paths: []string{"/spec/replicas", ".spec.template.spec.containers[*].resources"}},
+		{Name: "ingress-nginx", Paths: []string{".metadata.annotations['deployment.kubernetes.io/revision']"}},
+		{Kind: "StatefulSet", Paths: []string{"/metadata"}},
+		{Paths: []string{"/spec/template/spec/containers/1"}},
+	}
+	g.Expect(RemoveIgnoredFields(obj, rules)).To(Succeed())
+
+	expected := map[string]interface{}{}
+	g.Expect(yaml.Unmarshal([]byte(`
+kind: Deployment
+metadata:
+  name: ingress-nginx
+  annotations:
+    owner: platform
+spec:
+  template:
+    spec:
+      containers:
+      - name: controller
+        image: controller:v1
+`), &expected)).To(Succeed())
+	g.Expect(obj).To(Equal(expected))
+
+	g.Expect(RemoveIgnoredFields(obj, []addonsv1alpha1.DriftIgnoreRule{{Paths: []string{"spec[0"}}})).NotTo(Succeed())
+}
+
+func TestRemoveIgnoredManifestFields(t *testing.T) {
+	g := NewWithT(t)
+
+	manifest := "---\nkind: ConfigMap\n---\nkind: Deployment\nspec:\n  replicas: 1\n"
+	g.Expect(removeIgnoredManifestFields(manifest, nil)).To(Equal(manifest))
+
+	filtered, err := removeIgnoredManifestFields(manifest, []addonsv1alpha1.DriftIgnoreRule{{Kind: "Deployment", Paths: []string{"/spec/replicas"}}})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(filtered).To(Equal("---\nkind: ConfigMap\n---\nkind: Deployment\nspec: {}\n"))
+}
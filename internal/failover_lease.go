@@ -0,0 +1,148 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// failoverLeaseNamespace is the namespace of the ownership lease ConfigMaps on the workload Cluster. It exists on every
+// Cluster, so the lease can be acquired before the release namespace is created.
+const failoverLeaseNamespace = metav1.NamespaceSystem
+
+// failoverLeaseName returns the name of the ownership lease ConfigMap of a HelmChartProxy on the workload Cluster.
+// Identical HelmChartProxies in different management clusters share the same name and therefore the same lease.
+func failoverLeaseName(helmChartProxyName string) string {
+	return "caaph-lease-" + helmChartProxyName
+}
+
+// FailoverLease is the state of an ownership lease on a workload Cluster.
+type FailoverLease struct {
+	// Holder is the failover identity of the management cluster holding the lease.
+	Holder string
+
+	// RenewTime is the time the lease was last renewed by its holder.
+	RenewTime time.Time
+
+	// Duration is the duration after the RenewTime that the lease expires.
+	Duration time.Duration
+}
+
+// expired returns true if the lease is not held by anyone at the given time.
+func (l FailoverLease) expired(now time.Time) bool {
+	return l.Holder == "" || !now.Before(l.RenewTime.Add(l.Duration))
+}
+
+// AcquireFailoverLease acquires or renews the ownership lease of the HelmChartProxy on the workload Cluster for the
+// identity. The lease is a ConfigMap annotated with its holder, renew time and duration. A lease held by another identity
+// is only taken over once it has expired. As the ConfigMap is updated with its resource version, only one of several
+// management clusters racing for an expired lease acquires it. If the lease is not acquired, the lease of its current
+// holder is returned.
+func AcquireFailoverLease(ctx context.Context, workloadClient client.Client, helmChartProxyName, identity string, duration time.Duration, now time.Time) (bool, FailoverLease, error) {
+	key := types.NamespacedName{Namespace: failoverLeaseNamespace, Name: failoverLeaseName(helmChartProxyName)}
+	configMap := &corev1.ConfigMap{}
+	if err := workloadClient.Get(ctx, key, configMap); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return false, FailoverLease{}, errors.Wrapf(err, "failed to get lease ConfigMap %s", key)
+		}
+
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: key.Namespace,
+				Name:      key.Name,
+			},
+		}
+		setFailoverLease(configMap, FailoverLease{Holder: identity, RenewTime: now, Duration: duration})
+		if err := workloadClient.Create(ctx, configMap); err != nil {
+			return false, FailoverLease{}, errors.Wrapf(err, "failed to create lease ConfigMap %s", key)
+		}
+
+		return true, FailoverLease{}, nil
+	}
+
+	lease := getFailoverLease(configMap)
+	if lease.Holder != identity && !lease.expired(now) {
+		return false, lease, nil
+	}
+
+	setFailoverLease(configMap, FailoverLease{Holder: identity, RenewTime: now, Duration: duration})
+	if err := workloadClient.Update(ctx, configMap); err != nil {
+		return false, FailoverLease{}, errors.Wrapf(err, "failed to update lease ConfigMap %s", key)
+	}
+
+	return true, FailoverLease{}, nil
+}
+
+// ReleaseFailoverLease deletes the ownership lease of the HelmChartProxy on the workload Cluster if it is held by the
+// identity, so that another management cluster does not have to wait for it to expire.
+func ReleaseFailoverLease(ctx context.Context, workloadClient client.Client, helmChartProxyName, identity string) error {
+	key := types.NamespacedName{Namespace: failoverLeaseNamespace, Name: failoverLeaseName(helmChartProxyName)}
+	configMap := &corev1.ConfigMap{}
+	if err := workloadClient.Get(ctx, key, configMap); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if getFailoverLease(configMap).Holder != identity {
+		return nil
+	}
+
+	if err := workloadClient.Delete(ctx, configMap, client.Preconditions{ResourceVersion: &configMap.ResourceVersion}); err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete lease ConfigMap %s", key)
+	}
+
+	return nil
+}
+
+// getFailoverLease returns the lease recorded in the annotations of the ConfigMap. A lease with an invalid renew time or
+// duration is treated as expired.
+func getFailoverLease(configMap *corev1.ConfigMap) FailoverLease {
+	annotations := configMap.GetAnnotations()
+	lease := FailoverLease{Holder: annotations[addonsv1alpha1.FailoverLeaseHolderAnnotation]}
+
+	renewTime, err := time.Parse(time.RFC3339, annotations[addonsv1alpha1.FailoverLeaseRenewTimeAnnotation])
+	if err != nil {
+		return FailoverLease{}
+	}
+	duration, err := time.ParseDuration(annotations[addonsv1alpha1.FailoverLeaseDurationAnnotation])
+	if err != nil {
+		return FailoverLease{}
+	}
+	lease.RenewTime = renewTime
+	lease.Duration = duration
+
+	return lease
+}
+
+// setFailoverLease records the lease in the annotations of the ConfigMap.
+func setFailoverLease(configMap *corev1.ConfigMap, lease FailoverLease) {
+	annotations := configMap.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[addonsv1alpha1.FailoverLeaseHolderAnnotation] = lease.Holder
+	annotations[addonsv1alpha1.FailoverLeaseRenewTimeAnnotation] = lease.RenewTime.UTC().Format(time.RFC3339)
+	annotations[addonsv1alpha1.FailoverLeaseDurationAnnotation] = lease.Duration.String()
+	configMap.SetAnnotations(annotations)
+}
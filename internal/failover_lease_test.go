@@ -0,0 +1,139 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestAcquireFailoverLease(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	leaseConfigMap := func(holder string, renewTime time.Time) *corev1.ConfigMap {
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: metav1.NamespaceSystem,
+				Name:      "caaph-lease-test-hcp",
+			},
+		}
+		setFailoverLease(configMap, FailoverLease{Holder: holder, RenewTime: renewTime, Duration: time.Minute})
+
+		return configMap
+	}
+
+	testcases := []struct {
+		name           string
+		objects        []client.Object
+		expectAcquired bool
+		expectedHolder string
+	}{
+		{
+			name:           "creates the lease",
+			expectAcquired: true,
+			expectedHolder: "primary",
+		},
+		{
+			name:           "renews its own lease",
+			objects:        []client.Object{leaseConfigMap("primary", now.Add(-30*time.Second))},
+			expectAcquired: true,
+			expectedHolder: "primary",
+		},
+		{
+			name:           "stands by while another holder's lease is valid",
+			objects:        []client.Object{leaseConfigMap("standby", now.Add(-30*time.Second))},
+			expectAcquired: false,
+			expectedHolder: "standby",
+		},
+		{
+			name:           "takes over an expired lease",
+			objects:        []client.Object{leaseConfigMap("standby", now.Add(-2*time.Minute))},
+			expectAcquired: true,
+			expectedHolder: "primary",
+		},
+		{
+			name: "takes over a lease with an invalid renew time",
+			objects: []client.Object{&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: metav1.NamespaceSystem,
+					Name:      "caaph-lease-test-hcp",
+					Annotations: map[string]string{
+						addonsv1alpha1.FailoverLeaseHolderAnnotation:    "standby",
+						addonsv1alpha1.FailoverLeaseRenewTimeAnnotation: "yesterday",
+					},
+				},
+			}},
+			expectAcquired: true,
+			expectedHolder: "primary",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			c := fake.NewClientBuilder().WithObjects(tc.objects...).Build()
+
+			acquired, lease, err := AcquireFailoverLease(context.TODO(), c, "test-hcp", "primary", time.Minute, now)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(acquired).To(Equal(tc.expectAcquired))
+			if !tc.expectAcquired {
+				g.Expect(lease.Holder).To(Equal(tc.expectedHolder))
+			}
+
+			configMap := &corev1.ConfigMap{}
+			g.Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: metav1.NamespaceSystem, Name: "caaph-lease-test-hcp"}, configMap)).To(Succeed())
+			stored := getFailoverLease(configMap)
+			g.Expect(stored.Holder).To(Equal(tc.expectedHolder))
+			if tc.expectAcquired {
+				g.Expect(stored.RenewTime).To(Equal(now))
+				g.Expect(stored.Duration).To(Equal(time.Minute))
+			}
+		})
+	}
+}
+
+func TestReleaseFailoverLease(t *testing.T) {
+	g := NewWithT(t)
+
+	c := fake.NewClientBuilder().Build()
+	key := types.NamespacedName{Namespace: metav1.NamespaceSystem, Name: "caaph-lease-test-hcp"}
+
+	acquired, _, err := AcquireFailoverLease(context.TODO(), c, "test-hcp", "standby", time.Minute, time.Now())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(acquired).To(BeTrue())
+
+	// A lease held by another identity is left in place.
+	g.Expect(ReleaseFailoverLease(context.TODO(), c, "test-hcp", "primary")).To(Succeed())
+	g.Expect(c.Get(context.TODO(), key, &corev1.ConfigMap{})).To(Succeed())
+
+	g.Expect(ReleaseFailoverLease(context.TODO(), c, "test-hcp", "standby")).To(Succeed())
+	err = c.Get(context.TODO(), key, &corev1.ConfigMap{})
+	g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+	// Releasing a lease that does not exist succeeds.
+	g.Expect(ReleaseFailoverLease(context.TODO(), c, "test-hcp", "standby")).To(Succeed())
+}
@@ -0,0 +1,369 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"k8s.io/utils/ptr"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// gitTimeout is the maximum duration of a run of git, e.g. of the fetch of a commit.
+	gitTimeout = 5 * time.Minute
+
+	// maxGitChartSize is the maximum size of all files of the chart directory of a commit, the same as the maximum size
+	// of a chart archive in a ChartBundle.
+	maxGitChartSize = maxChartBundleArchiveSize
+)
+
+// gitChartDir is the directory the chart archives packaged from Git repositories are written to, named by the hash of
+// their cache key.
+var gitChartDir = filepath.Join(os.TempDir(), "git-charts")
+
+// gitCommitPattern matches full SHA-1 commit names, which are fetched without resolving them with ls-remote.
+var gitCommitPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// gitChartCacheKey returns the cache key of the chart packaged from the path of a commit of a Git repository.
+func gitChartCacheKey(source addonsv1alpha1.GitChartSource, commit, chartName, version string) string {
+	return chartCacheKey("git+"+source.URL+"//"+source.Path+"@"+commit, chartName, version)
+}
+
+// locateGitChart resolves the Ref of the Git source of the spec to a commit and returns the path of the chart archive
+// packaged from the chart directory of the commit. The repository is only fetched if the chart of the commit was not
+// packaged before, with a shallow fetch of just the commit.
+func locateGitChart(ctx context.Context, spec addonsv1alpha1.HelmReleaseProxySpec, caFilePath string, repositoryAuth RepositoryAuth) (string, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	source := ptr.Deref(spec.Git, addonsv1alpha1.GitChartSource{})
+	repo := newGitRepository(strings.TrimSuffix(source.URL, "/"), caFilePath, repositoryAuth, ptr.Deref(spec.TLSConfig, addonsv1alpha1.TLSConfig{}).InsecureSkipTLSVerify)
+
+	commit, err := repo.resolveRef(ctx, source.Ref)
+	if err != nil {
+		return "", err
+	}
+	key := gitChartCacheKey(source, commit, spec.ChartName, spec.Version)
+	if path, ok := defaultChartCache.get(key); ok {
+		return path, nil
+	}
+
+	log.V(2).Info("Fetching chart from Git repository", "chart", spec.ChartName, "url", source.URL, "ref", source.Ref, "commit", commit)
+	path, err := packageGitChart(ctx, repo, commit, key, spec)
+	if err != nil {
+		return "", err
+	}
+	defaultChartCache.set(key, path)
+
+	return path, nil
+}
+
+// packageGitChart fetches the commit, writes its chart directory to disk, checks that it is the chart of the spec and
+// packages it into an archive named by the hash of the cache key. The commit is recorded in the annotations of the chart,
+// so that releases are upgraded when it changes.
+func packageGitChart(ctx context.Context, repo *gitRepository, commit, key string, spec addonsv1alpha1.HelmReleaseProxySpec) (string, error) {
+	source := ptr.Deref(spec.Git, addonsv1alpha1.GitChartSource{})
+
+	if err := os.MkdirAll(gitChartDir, 0o755); err != nil {
+		return "", err
+	}
+	dir, err := os.MkdirTemp(gitChartDir, "checkout-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	chartDir := filepath.Join(dir, "chart")
+	if err := repo.checkout(ctx, commit, source.Path, dir, chartDir); err != nil {
+		return "", err
+	}
+	chart, err := loader.LoadDir(chartDir)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to load chart at path %q of commit %s of Git repository %s", source.Path, commit, source.URL)
+	}
+	if chart.Metadata.Name != spec.ChartName {
+		return "", errors.Errorf("chart at path %q of commit %s of Git repository %s is named %s instead of %s", source.Path, commit, source.URL, chart.Metadata.Name, spec.ChartName)
+	}
+	if addonsv1alpha1.IsVersionConstraint(spec.Version) {
+		if !matchesVersionConstraint(chart.Metadata.Version, spec.Version) {
+			return "", errors.Errorf("chart %s at commit %s of Git repository %s has version %s not matching %s", spec.ChartName, commit, source.URL, chart.Metadata.Version, spec.Version)
+		}
+	} else if spec.Version != "" && chart.Metadata.Version != spec.Version {
+		return "", errors.Errorf("chart %s at commit %s of Git repository %s has version %s instead of %s", spec.ChartName, commit, source.URL, chart.Metadata.Version, spec.Version)
+	}
+	if chart.Metadata.Annotations == nil {
+		chart.Metadata.Annotations = map[string]string{}
+	}
+	chart.Metadata.Annotations[addonsv1alpha1.GitCommitAnnotation] = commit
+
+	archive, err := chartutil.Save(chart, dir)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to package chart %s", spec.ChartName)
+	}
+	// The archive is renamed into place, so that concurrent fetches of the same commit do not read partial archives.
+	sum := sha256.Sum256([]byte(key))
+	path := filepath.Join(gitChartDir, hex.EncodeToString(sum[:])+".tgz")
+	if err := os.Rename(archive, path); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// gitRepository fetches commits of a Git repository with the git binary, which must be in the PATH of the controller.
+// Only the HTTP and HTTPS transports are allowed, and git is configured through its environment alone, so that neither
+// the system nor the user configuration of git applies.
+type gitRepository struct {
+	url string
+	env []string
+}
+
+// newGitRepository returns a gitRepository sending the headers and credentials of the RepositoryAuth with every request,
+// through its proxy and with its client certificate if it has ones. The credentials are passed in the environment of
+// git instead of its arguments, so that they are not visible in the process list.
+func newGitRepository(url, caFilePath string, repositoryAuth RepositoryAuth, insecureSkipTLSVerify bool) *gitRepository {
+	var config [][2]string
+	for _, name := range slices.Sorted(maps.Keys(repositoryAuth.Headers)) {
+		config = append(config, [2]string{"http.extraHeader", name + ": " + repositoryAuth.Headers[name]})
+	}
+	switch {
+	case repositoryAuth.BearerToken != "":
+		config = append(config, [2]string{"http.extraHeader", "Authorization: Bearer " + repositoryAuth.BearerToken})
+	case repositoryAuth.Username != "" || repositoryAuth.Password != "":
+		credentials := base64.StdEncoding.EncodeToString([]byte(repositoryAuth.Username + ":" + repositoryAuth.Password))
+		config = append(config, [2]string{"http.extraHeader", "Authorization: Basic " + credentials})
+	}
+	if caFilePath != "" {
+		config = append(config, [2]string{"http.sslCAInfo", caFilePath})
+	}
+	if repositoryAuth.CertFile != "" {
+		config = append(config, [2]string{"http.sslCert", repositoryAuth.CertFile}, [2]string{"http.sslKey", repositoryAuth.KeyFile})
+	}
+	if insecureSkipTLSVerify {
+		config = append(config, [2]string{"http.sslVerify", "false"})
+	}
+	if repositoryAuth.ProxyURL != "" {
+		config = append(config, [2]string{"http.proxy", repositoryAuth.ProxyURL})
+	}
+	config = append(config, [2]string{"protocol.version", "2"})
+
+	env := append(os.Environ(),
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_CONFIG_GLOBAL="+os.DevNull,
+		"GIT_ALLOW_PROTOCOL=http:https",
+		"GIT_CONFIG_COUNT="+strconv.Itoa(len(config)),
+	)
+	for i, entry := range config {
+		env = append(env, "GIT_CONFIG_KEY_"+strconv.Itoa(i)+"="+entry[0], "GIT_CONFIG_VALUE_"+strconv.Itoa(i)+"="+entry[1])
+	}
+
+	return &gitRepository{url: url, env: env}
+}
+
+// resolveRef returns the commit the branch, tag or ref points at, or the commit of HEAD if the ref is empty. Full commit
+// names are returned as is.
+func (r *gitRepository) resolveRef(ctx context.Context, ref string) (string, error) {
+	if gitCommitPattern.MatchString(ref) {
+		return ref, nil
+	}
+
+	candidates := []string{"HEAD"}
+	if ref != "" {
+		candidates = []string{"refs/heads/" + ref, "refs/tags/" + ref}
+		if strings.HasPrefix(ref, "refs/") {
+			candidates = []string{ref}
+		}
+	}
+	// Annotated tags are only peeled to the commit they point at if the peeled ref is listed as well.
+	args := []string{"ls-remote", "--", r.url}
+	for _, candidate := range candidates {
+		args = append(args, candidate, candidate+"^{}")
+	}
+	out, err := r.git(ctx, "", args...)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to list refs of Git repository %s", r.url)
+	}
+
+	refs := map[string]string{}
+	peeled := map[string]string{}
+	for _, line := range strings.Split(string(out), "\n") {
+		oid, name, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		if tag, ok := strings.CutSuffix(name, "^{}"); ok {
+			peeled[tag] = oid
+			continue
+		}
+		refs[name] = oid
+	}
+	for _, candidate := range candidates {
+		if oid, ok := peeled[candidate]; ok {
+			return oid, nil
+		}
+		if oid, ok := refs[candidate]; ok {
+			return oid, nil
+		}
+	}
+	if ref == "" {
+		return "", errors.Errorf("Git repository %s has no default branch", r.url)
+	}
+
+	return "", errors.Errorf("ref %s not found in Git repository %s", ref, r.url)
+}
+
+// checkout fetches the commit with a depth of 1 into a bare repository in the work directory and writes the directory at
+// the path of the commit to the chart directory.
+func (r *gitRepository) checkout(ctx context.Context, commit, treePath, workDir, chartDir string) error {
+	gitDir := filepath.Join(workDir, "repository.git")
+	if _, err := r.git(ctx, "", "init", "--quiet", "--bare", gitDir); err != nil {
+		return err
+	}
+	if _, err := r.git(ctx, gitDir, "fetch", "--quiet", "--no-tags", "--depth=1", "--", r.url, commit); err != nil {
+		return errors.Wrapf(err, "failed to fetch commit %s of Git repository %s", commit, r.url)
+	}
+
+	treePath = strings.TrimPrefix(path.Clean("/"+treePath), "/")
+	tree := commit + ":" + treePath
+	out, err := r.git(ctx, gitDir, "cat-file", "-t", tree)
+	if err != nil || strings.TrimSpace(string(out)) != "tree" {
+		return errors.Errorf("directory %s not found in Git repository", treePath)
+	}
+
+	archive := filepath.Join(workDir, "chart.tar")
+	if _, err := r.git(ctx, gitDir, "archive", "--format=tar", "--output="+archive, tree); err != nil {
+		return errors.Wrapf(err, "failed to archive directory %s of commit %s of Git repository %s", treePath, commit, r.url)
+	}
+
+	return extractGitArchive(archive, chartDir)
+}
+
+// git runs git with the arguments in the directory, or in the working directory of the controller if it is empty, and
+// returns its standard output. Errors include the standard error of git. git is killed after gitTimeout, and fails if
+// its standard output or error exceed maxCommandOutputSize.
+func (r *gitRepository) git(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, gitTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = r.env
+	// The helpers git spawns for the HTTP transport inherit its output, so the output is not waited for once git is
+	// killed.
+	cmd.WaitDelay = time.Second
+	stdout := &limitedBuffer{limit: maxCommandOutputSize}
+	stderr := &limitedBuffer{limit: maxCommandOutputSize}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, errors.Errorf("git %s: %s", args[0], message)
+		}
+
+		return nil, errors.Wrapf(err, "git %s", args[0])
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// extractGitArchive writes the directories and regular files of the tar archive written by git archive to the directory.
+// Symbolic links are skipped, as they may point outside of the chart directory. It fails once more than maxGitChartSize
+// bytes are written.
+func extractGitArchive(archive, dir string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	reader := tar.NewReader(f)
+	remaining := int64(maxGitChartSize)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to read archive of Git repository")
+		}
+		if header.Typeflag != tar.TypeDir && header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if !filepath.IsLocal(header.Name) {
+			return errors.Errorf("invalid file name %q in archive of Git repository", header.Name)
+		}
+
+		target := filepath.Join(dir, header.Name)
+		if header.Typeflag == tar.TypeDir {
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+			continue
+		}
+		n, err := writeGitArchiveFile(reader, target, remaining)
+		if err != nil {
+			return err
+		}
+		remaining -= n
+	}
+}
+
+// writeGitArchiveFile writes the current file of the tar archive to the path and returns its size. It fails if the file
+// exceeds the limit.
+func writeGitArchiveFile(reader io.Reader, target string, limit int64) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return 0, err
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(f, io.LimitReader(reader, limit+1))
+	if err != nil {
+		f.Close()
+		return 0, err
+	}
+	if n > limit {
+		f.Close()
+		return 0, errors.Errorf("chart directory of Git repository exceeds %d bytes", maxGitChartSize)
+	}
+
+	return n, f.Close()
+}
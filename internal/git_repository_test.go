@@ -0,0 +1,263 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/cgi"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+)
+
+// gitCommand runs git in the directory and returns its trimmed output.
+func gitCommand(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+		"GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1",
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+
+	return strings.TrimSpace(string(out))
+}
+
+// writeChartFiles writes a chart with the name and version to the directory.
+func writeChartFiles(t *testing.T, dir, name, version string) {
+	t.Helper()
+
+	files := map[string]string{
+		"Chart.yaml":               "apiVersion: v2\nname: " + name + "\nversion: " + version + "\n",
+		"values.yaml":              "replicas: 1\n",
+		"templates/configmap.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Release.Name }}\ndata:\n  replicas: {{ .Values.replicas | quote }}\n",
+	}
+	for file, content := range files {
+		path := filepath.Join(dir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// newGitServer serves the bare Git repository charts.git, with two commits of the chart nginx at charts/nginx on the
+// branch main and an annotated tag v1 of the first commit, over the smart HTTP transport of git http-backend. Requests
+// without the basic authentication credentials user:secret are rejected. It returns the URL of the repository and the two
+// commits.
+func newGitServer(t *testing.T) (string, string, string) {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	root := t.TempDir()
+	work := filepath.Join(root, "work")
+	gitCommand(t, root, "init", "--quiet", "--initial-branch=main", work)
+	writeChartFiles(t, filepath.Join(work, "charts", "nginx"), "nginx", "1.0.0")
+	gitCommand(t, work, "add", ".")
+	gitCommand(t, work, "commit", "--quiet", "-m", "Add nginx chart")
+	gitCommand(t, work, "tag", "-a", "v1", "-m", "v1")
+	first := gitCommand(t, work, "rev-parse", "HEAD")
+	if err := os.WriteFile(filepath.Join(work, "charts", "nginx", "values.yaml"), []byte("replicas: 2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	gitCommand(t, work, "commit", "--quiet", "-am", "Scale nginx")
+	second := gitCommand(t, work, "rev-parse", "HEAD")
+	gitCommand(t, root, "clone", "--quiet", "--bare", work, filepath.Join(root, "charts.git"))
+
+	backend := &cgi.Handler{
+		Path: filepath.Join(gitCommand(t, root, "--exec-path"), "git-http-backend"),
+		Env:  []string{"GIT_PROJECT_ROOT=" + root, "GIT_HTTP_EXPORT_ALL=1"},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "user" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		backend.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return server.URL + "/charts.git", first, second
+}
+
+func TestLocateGitChart(t *testing.T) {
+	g := NewWithT(t)
+
+	repoURL, first, second := newGitServer(t)
+	gitChartDir = t.TempDir()
+	auth := RepositoryAuth{Username: "user", Password: "secret"}
+
+	for _, tc := range []struct {
+		ref            string
+		expectedCommit string
+		expectedValues string
+	}{
+		{ref: "", expectedCommit: second, expectedValues: "replicas: 2\n"},
+		{ref: "main", expectedCommit: second, expectedValues: "replicas: 2\n"},
+		{ref: "refs/heads/main", expectedCommit: second, expectedValues: "replicas: 2\n"},
+		{ref: "v1", expectedCommit: first, expectedValues: "replicas: 1\n"},
+		{ref: first, expectedCommit: first, expectedValues: "replicas: 1\n"},
+	} {
+		spec := addonsv1alpha1.HelmReleaseProxySpec{
+			ChartName: "nginx",
+			Git:       &addonsv1alpha1.GitChartSource{URL: repoURL, Ref: tc.ref, Path: "charts/nginx"},
+		}
+		path, err := locateGitChart(context.Background(), spec, "", auth)
+		g.Expect(err).NotTo(HaveOccurred(), tc.ref)
+
+		chart, err := loader.Load(path)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(chart.Metadata.Name).To(Equal("nginx"))
+		g.Expect(chart.Metadata.Annotations).To(HaveKeyWithValue(addonsv1alpha1.GitCommitAnnotation, tc.expectedCommit), tc.ref)
+		g.Expect(chart.Templates).To(HaveLen(1))
+		var values []byte
+		for _, file := range chart.Raw {
+			if file.Name == "values.yaml" {
+				values = file.Data
+			}
+		}
+		g.Expect(string(values)).To(Equal(tc.expectedValues), tc.ref)
+
+		cached, err := locateGitChart(context.Background(), spec, "", auth)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(cached).To(Equal(path), "charts of a commit are only packaged once")
+	}
+}
+
+func TestLocateGitChartErrors(t *testing.T) {
+	g := NewWithT(t)
+
+	repoURL, _, _ := newGitServer(t)
+	gitChartDir = t.TempDir()
+	auth := RepositoryAuth{Username: "user", Password: "secret"}
+	spec := func(chartName, version, ref, path string) addonsv1alpha1.HelmReleaseProxySpec {
+		return addonsv1alpha1.HelmReleaseProxySpec{
+			ChartName: chartName,
+			Version:   version,
+			Git:       &addonsv1alpha1.GitChartSource{URL: repoURL, Ref: ref, Path: path},
+		}
+	}
+
+	_, err := locateGitChart(context.Background(), spec("nginx", "", "main", "charts/nginx"), "", RepositoryAuth{})
+	g.Expect(err).To(MatchError(ContainSubstring("failed to list refs of Git repository")))
+
+	_, err = locateGitChart(context.Background(), spec("nginx", "", "release-1", "charts/nginx"), "", auth)
+	g.Expect(err).To(MatchError(ContainSubstring("ref release-1 not found")))
+
+	_, err = locateGitChart(context.Background(), spec("nginx", "", "main", "charts/redis"), "", auth)
+	g.Expect(err).To(MatchError(ContainSubstring("directory charts/redis not found")))
+
+	_, err = locateGitChart(context.Background(), spec("redis", "", "main", "charts/nginx"), "", auth)
+	g.Expect(err).To(MatchError(ContainSubstring("is named nginx instead of redis")))
+
+	_, err = locateGitChart(context.Background(), spec("nginx", "2.0.0", "main", "charts/nginx"), "", auth)
+	g.Expect(err).To(MatchError(ContainSubstring("has version 1.0.0 instead of 2.0.0")))
+
+	_, err = locateGitChart(context.Background(), spec("nginx", ">=2.0.0", "main", "charts/nginx"), "", auth)
+	g.Expect(err).To(MatchError(ContainSubstring("has version 1.0.0 not matching >=2.0.0")))
+
+	for _, version := range []string{"1.0.0", "~1.0"} {
+		path, err := locateGitChart(context.Background(), spec("nginx", version, "main", "charts/nginx"), "", auth)
+		g.Expect(err).NotTo(HaveOccurred(), version)
+		g.Expect(path).To(BeAnExistingFile())
+	}
+}
+
+func TestExtractGitArchive(t *testing.T) {
+	g := NewWithT(t)
+
+	writeArchive := func(headers ...*tar.Header) string {
+		var buf bytes.Buffer
+		w := tar.NewWriter(&buf)
+		for _, header := range headers {
+			g.Expect(w.WriteHeader(header)).To(Succeed())
+			if header.Typeflag == tar.TypeReg {
+				_, err := w.Write([]byte(header.Name))
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+		}
+		g.Expect(w.Close()).To(Succeed())
+		path := filepath.Join(t.TempDir(), "chart.tar")
+		g.Expect(os.WriteFile(path, buf.Bytes(), 0o644)).To(Succeed())
+
+		return path
+	}
+	file := func(name string) *tar.Header {
+		return &tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0o644, Size: int64(len(name))}
+	}
+
+	// Symbolic links are skipped, as they may point outside of the chart directory.
+	dir := filepath.Join(t.TempDir(), "chart")
+	archive := writeArchive(
+		&tar.Header{Typeflag: tar.TypeDir, Name: "templates/", Mode: 0o755},
+		file("Chart.yaml"),
+		file("templates/configmap.yaml"),
+		&tar.Header{Typeflag: tar.TypeSymlink, Name: "values.yaml", Linkname: "/etc/passwd"},
+	)
+	g.Expect(extractGitArchive(archive, dir)).To(Succeed())
+	content, err := os.ReadFile(filepath.Join(dir, "templates", "configmap.yaml"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(content)).To(Equal("templates/configmap.yaml"))
+	g.Expect(filepath.Join(dir, "Chart.yaml")).To(BeARegularFile())
+	g.Expect(filepath.Join(dir, "values.yaml")).NotTo(BeAnExistingFile())
+
+	for _, name := range []string{"../Chart.yaml", "/etc/Chart.yaml"} {
+		err := extractGitArchive(writeArchive(file(name)), filepath.Join(t.TempDir(), "chart"))
+		g.Expect(err).To(MatchError(ContainSubstring("invalid file name")), name)
+	}
+
+	err = extractGitArchive(filepath.Join(t.TempDir(), "missing.tar"), filepath.Join(t.TempDir(), "chart"))
+	g.Expect(err).To(HaveOccurred())
+
+	// The files of the chart directory are limited in total.
+	large := func(name string) *tar.Header {
+		return &tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0o644, Size: maxGitChartSize / 2}
+	}
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	for _, header := range []*tar.Header{large("a.yaml"), large("b.yaml"), file("c.yaml")} {
+		g.Expect(w.WriteHeader(header)).To(Succeed())
+		_, err := w.Write(bytes.Repeat([]byte("#"), int(header.Size)))
+		g.Expect(err).NotTo(HaveOccurred())
+	}
+	g.Expect(w.Close()).To(Succeed())
+	archive = filepath.Join(t.TempDir(), "chart.tar")
+	g.Expect(os.WriteFile(archive, buf.Bytes(), 0o644)).To(Succeed())
+	err = extractGitArchive(archive, filepath.Join(t.TempDir(), "chart"))
+	g.Expect(err).To(MatchError(ContainSubstring("exceeds")))
+}
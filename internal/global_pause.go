@@ -0,0 +1,84 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// GlobalPause is the switch that pauses all changes made by the provider, e.g. during an emergency change freeze. The
+// provider is paused while its ConfigMap has the GlobalPausePausedKey set to "true".
+type GlobalPause struct {
+	// Reader reads the ConfigMap, usually from the cache of the manager.
+	Reader client.Reader
+
+	// ConfigMap is the namespace and name of the ConfigMap.
+	ConfigMap types.NamespacedName
+}
+
+// ParseGlobalPauseConfigMap parses the namespace and name of the global pause ConfigMap in the form namespace/name.
+func ParseGlobalPauseConfigMap(value string) (types.NamespacedName, error) {
+	namespace, name, ok := strings.Cut(value, "/")
+	if !ok || namespace == "" || name == "" || strings.Contains(name, "/") {
+		return types.NamespacedName{}, errors.Errorf("global pause ConfigMap %q is not of the form namespace/name", value)
+	}
+
+	return types.NamespacedName{Namespace: namespace, Name: name}, nil
+}
+
+// IsPaused returns true and a message describing the pause if the provider is paused. A nil GlobalPause is never paused,
+// and neither is a provider whose ConfigMap does not exist. Any other failure to read the ConfigMap is returned, so that
+// callers do not make changes while the state of the pause is unknown.
+func (p *GlobalPause) IsPaused(ctx context.Context) (bool, string, error) {
+	if p == nil {
+		return false, "", nil
+	}
+
+	configMap := &corev1.ConfigMap{}
+	if err := p.Reader.Get(ctx, p.ConfigMap, configMap); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, "", nil
+		}
+
+		return false, "", errors.Wrapf(err, "failed to get global pause ConfigMap %s", p.ConfigMap)
+	}
+
+	if configMap.Data[addonsv1alpha1.GlobalPausePausedKey] != "true" {
+		return false, "", nil
+	}
+
+	message := fmt.Sprintf("provider is paused by ConfigMap %s", p.ConfigMap)
+	if reason := configMap.Data[addonsv1alpha1.GlobalPauseReasonKey]; reason != "" {
+		message += ": " + reason
+	}
+
+	return true, message, nil
+}
+
+// IsConfigMap returns true if the object is the global pause ConfigMap. A nil GlobalPause has no ConfigMap.
+func (p *GlobalPause) IsConfigMap(o client.Object) bool {
+	return p != nil && o.GetNamespace() == p.ConfigMap.Namespace && o.GetName() == p.ConfigMap.Name
+}
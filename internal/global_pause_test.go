@@ -0,0 +1,106 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestParseGlobalPauseConfigMap(t *testing.T) {
+	g := NewWithT(t)
+
+	key, err := ParseGlobalPauseConfigMap("caaph-system/caaph-pause")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(key).To(Equal(types.NamespacedName{Namespace: "caaph-system", Name: "caaph-pause"}))
+
+	for _, value := range []string{"caaph-pause", "/caaph-pause", "caaph-system/", "caaph-system/caaph/pause"} {
+		_, err := ParseGlobalPauseConfigMap(value)
+		g.Expect(err).To(HaveOccurred(), value)
+	}
+}
+
+func TestGlobalPauseIsPaused(t *testing.T) {
+	key := types.NamespacedName{Namespace: "caaph-system", Name: "caaph-pause"}
+	pauseConfigMap := func(data map[string]string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+			Data:       data,
+		}
+	}
+
+	testcases := []struct {
+		name            string
+		objects         []client.Object
+		expectPaused    bool
+		expectedMessage string
+	}{
+		{
+			name: "is not paused without the ConfigMap",
+		},
+		{
+			name:    "is not paused unless paused is true",
+			objects: []client.Object{pauseConfigMap(map[string]string{addonsv1alpha1.GlobalPausePausedKey: "false"})},
+		},
+		{
+			name:            "is paused",
+			objects:         []client.Object{pauseConfigMap(map[string]string{addonsv1alpha1.GlobalPausePausedKey: "true"})},
+			expectPaused:    true,
+			expectedMessage: "provider is paused by ConfigMap caaph-system/caaph-pause",
+		},
+		{
+			name: "reports the reason of the pause",
+			objects: []client.Object{pauseConfigMap(map[string]string{
+				addonsv1alpha1.GlobalPausePausedKey: "true",
+				addonsv1alpha1.GlobalPauseReasonKey: "change freeze INC-1234",
+			})},
+			expectPaused:    true,
+			expectedMessage: "provider is paused by ConfigMap caaph-system/caaph-pause: change freeze INC-1234",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			pause := &GlobalPause{
+				Reader:    fake.NewClientBuilder().WithObjects(tc.objects...).Build(),
+				ConfigMap: key,
+			}
+			paused, message, err := pause.IsPaused(context.Background())
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(paused).To(Equal(tc.expectPaused))
+			g.Expect(message).To(Equal(tc.expectedMessage))
+			g.Expect(pause.IsConfigMap(pauseConfigMap(nil))).To(BeTrue())
+		})
+	}
+
+	g := NewWithT(t)
+	var pause *GlobalPause
+	paused, _, err := pause.IsPaused(context.Background())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(paused).To(BeFalse())
+	g.Expect(pause.IsConfigMap(pauseConfigMap(nil))).To(BeFalse())
+}
@@ -17,10 +17,13 @@ limitations under the License.
 package internal
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"fmt"
+	"maps"
 	"net/http"
 	"net/url"
 	"os"
@@ -33,13 +36,19 @@ import (
 	helmAction "helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chart"
 	helmLoader "helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
 	helmCli "helm.sh/helm/v3/pkg/cli"
 	helmVals "helm.sh/helm/v3/pkg/cli/values"
 	helmGetter "helm.sh/helm/v3/pkg/getter"
+	helmKube "helm.sh/helm/v3/pkg/kube"
 	"helm.sh/helm/v3/pkg/registry"
 	helmRelease "helm.sh/helm/v3/pkg/release"
 	helmDriver "helm.sh/helm/v3/pkg/storage/driver"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/ptr"
@@ -48,12 +57,29 @@ import (
 )
 
 type Client interface {
-	InstallOrUpgradeHelmRelease(ctx context.Context, restConfig *rest.Config, credentialsPath, caFilePath string, spec addonsv1alpha1.HelmReleaseProxySpec) (*helmRelease.Release, error)
+	InstallOrUpgradeHelmRelease(ctx context.Context, restConfig *rest.Config, credentialsPath, caFilePath string, repositoryAuth RepositoryAuth, spec addonsv1alpha1.HelmReleaseProxySpec) (*helmRelease.Release, error)
+	DiffHelmRelease(ctx context.Context, restConfig *rest.Config, credentialsPath, caFilePath string, repositoryAuth RepositoryAuth, spec addonsv1alpha1.HelmReleaseProxySpec) (*helmRelease.Release, string, error)
 	GetHelmRelease(ctx context.Context, restConfig *rest.Config, spec addonsv1alpha1.HelmReleaseProxySpec) (*helmRelease.Release, error)
 	UninstallHelmRelease(ctx context.Context, restConfig *rest.Config, spec addonsv1alpha1.HelmReleaseProxySpec) (*helmRelease.UninstallReleaseResponse, error)
+	RollbackHelmRelease(ctx context.Context, restConfig *rest.Config, spec addonsv1alpha1.HelmReleaseProxySpec, revision int) (*helmRelease.Release, error)
+	TestHelmRelease(ctx context.Context, restConfig *rest.Config, spec addonsv1alpha1.HelmReleaseProxySpec) (*helmRelease.Release, error)
+	GetHelmReleaseProgress(ctx context.Context, restConfig *rest.Config, spec addonsv1alpha1.HelmReleaseProxySpec) (*addonsv1alpha1.ReleaseProgress, error)
+	ReconcileHelmReleaseDrift(ctx context.Context, restConfig *rest.Config, spec addonsv1alpha1.HelmReleaseProxySpec, correct bool) ([]string, error)
+	LabelReleaseResources(ctx context.Context, restConfig *rest.Config, spec addonsv1alpha1.HelmReleaseProxySpec, revision int, labels map[string]string) error
+	ListHelmReleases(ctx context.Context, restConfig *rest.Config, spec addonsv1alpha1.HelmReleaseProxySpec) ([]*helmRelease.Release, error)
+	GetChartSBOMs(ctx context.Context, spec addonsv1alpha1.HelmReleaseProxySpec, credentialsPath, caFilePath string) ([]addonsv1alpha1.SBOMReference, error)
+	GetChartSBOM(ctx context.Context, spec addonsv1alpha1.HelmReleaseProxySpec, credentialsPath, caFilePath string, sbom addonsv1alpha1.SBOMReference, maxSize int64) ([]byte, error)
+	ResolveChartDigest(ctx context.Context, spec addonsv1alpha1.HelmReleaseProxySpec, credentialsPath, caFilePath string) (string, error)
 }
 
-type HelmClient struct{}
+// HelmClient is the Client performing Helm operations on workload Clusters.
+type HelmClient struct {
+	// AuditLog records every install, upgrade, uninstall and rollback of a Helm release. If it is nil, nothing is recorded.
+	AuditLog *AuditLog
+
+	// RegistryBreaker stops fetching charts from registries that keep failing. If it is nil, charts are always fetched.
+	RegistryBreaker *RegistryBreaker
+}
 
 // GetActionConfig returns a new Helm action configuration.
 func GetActionConfig(ctx context.Context, namespace string, config *rest.Config) (*helmAction.Configuration, error) {
@@ -96,7 +122,7 @@ func HelmInit(ctx context.Context, namespace string, restConfig *rest.Config) (*
 
 // InstallOrUpgradeHelmRelease installs a Helm release if it does not exist, or upgrades it if it does and differs from the spec.
 // It returns a boolean indicating whether an install or upgrade was performed.
-func (c *HelmClient) InstallOrUpgradeHelmRelease(ctx context.Context, restConfig *rest.Config, credentialsPath, caFilePath string, spec addonsv1alpha1.HelmReleaseProxySpec) (*helmRelease.Release, error) {
+func (c *HelmClient) InstallOrUpgradeHelmRelease(ctx context.Context, restConfig *rest.Config, credentialsPath, caFilePath string, repositoryAuth RepositoryAuth, spec addonsv1alpha1.HelmReleaseProxySpec) (*helmRelease.Release, error) {
 	log := ctrl.LoggerFrom(ctx)
 
 	log.V(2).Info("Installing or upgrading Helm release")
@@ -105,15 +131,147 @@ func (c *HelmClient) InstallOrUpgradeHelmRelease(ctx context.Context, restConfig
 	// historyClient.Max = 1
 	// if _, err := historyClient.Run(spec.ReleaseName); err == helmDriver.ErrReleaseNotFound {
 	existingRelease, err := c.GetHelmRelease(ctx, restConfig, spec)
+	if err == nil && existingRelease.Info.Status.IsPending() {
+		existingRelease, err = c.recoverPendingRelease(ctx, restConfig, spec, existingRelease, time.Now())
+	}
 	if err != nil {
 		if errors.Is(err, helmDriver.ErrReleaseNotFound) {
-			return c.InstallHelmRelease(ctx, restConfig, credentialsPath, caFilePath, spec)
+			return c.InstallHelmRelease(ctx, restConfig, credentialsPath, caFilePath, repositoryAuth, spec)
 		}
 
 		return nil, err
 	}
 
-	return c.UpgradeHelmReleaseIfChanged(ctx, restConfig, credentialsPath, caFilePath, spec, existingRelease)
+	return c.UpgradeHelmReleaseIfChanged(ctx, restConfig, credentialsPath, caFilePath, repositoryAuth, spec, existingRelease)
+}
+
+// DiffHelmRelease returns the existing Helm release, if any, and a description of the install or upgrade that
+// InstallOrUpgradeHelmRelease would perform for the spec, without changing anything on the workload Cluster. The
+// description is empty if the release is up to date.
+func (c *HelmClient) DiffHelmRelease(ctx context.Context, restConfig *rest.Config, credentialsPath, caFilePath string, repositoryAuth RepositoryAuth, spec addonsv1alpha1.HelmReleaseProxySpec) (*helmRelease.Release, string, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	existingRelease, err := c.GetHelmRelease(ctx, restConfig, spec)
+	if err != nil && !errors.Is(err, helmDriver.ErrReleaseNotFound) {
+		return nil, "", err
+	}
+
+	settings, actionConfig, err := HelmInit(ctx, spec.ReleaseNamespace, restConfig)
+	if err != nil {
+		return nil, "", err
+	}
+	settings.RegistryConfig = credentialsPath
+
+	registryClient, err := newDefaultRegistryClient(credentialsPath, spec.Options.EnableClientCache, caFilePath, repositoryAuth, ptr.Deref(spec.TLSConfig, addonsv1alpha1.TLSConfig{}).InsecureSkipTLSVerify)
+	if err != nil {
+		return nil, "", err
+	}
+	actionConfig.RegistryClient = registryClient
+
+	chartName, repoURL, err := getHelmChartAndRepoName(spec.ChartName, spec.RepoURL)
+	if err != nil {
+		return nil, "", err
+	}
+
+	upgradeClient := generateHelmUpgradeConfig(actionConfig, &spec.Options)
+	upgradeClient.RepoURL = repoURL
+	upgradeClient.Version = spec.Version
+
+	log.V(2).Info("Locating chart...")
+	cp, err := locateChart(ctx, &upgradeClient.ChartPathOptions, chartName, settings, spec, credentialsPath, caFilePath, repositoryAuth, c.RegistryBreaker)
+	if err != nil {
+		return nil, "", err
+	}
+	chartRequested, err := helmLoader.Load(cp)
+	if err != nil {
+		return nil, "", err
+	}
+	vals, err := chartutil.ReadValues([]byte(spec.Values))
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "failed to parse values")
+	}
+
+	if existingRelease == nil {
+		return nil, fmt.Sprintf("install chart %s version %s", chartName, chartRequested.Metadata.Version), nil
+	}
+
+	labels, err := releaseLabels(spec)
+	if err != nil {
+		return nil, "", err
+	}
+	shouldUpgrade, err := shouldUpgradeHelmRelease(ctx, *existingRelease, chartRequested, vals, labels)
+	if err != nil {
+		return nil, "", err
+	}
+	if !shouldUpgrade {
+		return existingRelease, "", nil
+	}
+
+	change, err := describeHelmReleaseUpgrade(*existingRelease, chartRequested, vals)
+	if err != nil {
+		return nil, "", err
+	}
+
+	rendered, _, err := renderChart(ctx, restConfig, spec, chartRequested, vals)
+	if err != nil {
+		log.V(2).Info("Skipping manifest diff, failed to render chart", "error", err.Error())
+		return existingRelease, change, nil
+	}
+
+	manifestChange, err := describeManifestChange(existingRelease.Manifest, rendered.manifest, spec.DriftIgnoreRules)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return existingRelease, change + manifestChange, nil
+}
+
+// describeManifestChange returns a diff of the existing and desired manifests of a release without the fields ignored by
+// the DriftIgnoreRules, or an empty string if they are identical.
+func describeManifestChange(existing, desired string, rules []addonsv1alpha1.DriftIgnoreRule) (string, error) {
+	existing, err := removeIgnoredManifestFields(existing, rules)
+	if err != nil {
+		return "", err
+	}
+	desired, err = removeIgnoredManifestFields(desired, rules)
+	if err != nil {
+		return "", err
+	}
+
+	diff := cmp.Diff(existing, desired)
+	if diff == "" {
+		return "", nil
+	}
+
+	return ", manifest diff (-existing +desired):\n" + diff, nil
+}
+
+// describeHelmReleaseUpgrade describes the upgrade of the existing Helm release to the requested chart and values,
+// including a diff of the values if they changed.
+func describeHelmReleaseUpgrade(existing helmRelease.Release, chartRequested *chart.Chart, values map[string]interface{}) (string, error) {
+	var change string
+	switch {
+	case existing.Chart.Metadata.Version != chartRequested.Metadata.Version:
+		change = fmt.Sprintf("upgrade release %s from chart version %s to %s", existing.Name, existing.Chart.Metadata.Version, chartRequested.Metadata.Version)
+	case existing.Info != nil && existing.Info.Status == helmRelease.StatusFailed:
+		change = fmt.Sprintf("upgrade failed release %s", existing.Name)
+	default:
+		change = fmt.Sprintf("upgrade values of release %s", existing.Name)
+	}
+
+	oldValues, err := yaml.Marshal(existing.Config)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to marshal existing release values")
+	}
+	newValues, err := yaml.Marshal(values)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to marshal new release values")
+	}
+	if diff := cmp.Diff(string(oldValues), string(newValues)); diff != "" {
+		change += ", values diff (-existing +desired):\n" + diff
+	}
+
+	return change, nil
 }
 
 // generateHelmInstallConfig generates default helm install config using helmOptions specified in HCP CR spec.
@@ -154,7 +312,8 @@ func generateHelmUpgradeConfig(actionConfig *helmAction.Configuration, helmOptio
 	}
 
 	upgradeClient.DisableHooks = helmOptions.DisableHooks
-	upgradeClient.Wait = helmOptions.Wait
+	// Failed atomic upgrades are rolled back by the client, see rollbackFailedUpgrade, so that the rollback is reported.
+	upgradeClient.Wait = helmOptions.Wait || helmOptions.Atomic
 	upgradeClient.WaitForJobs = helmOptions.WaitForJobs
 	if helmOptions.Timeout != nil {
 		upgradeClient.Timeout = helmOptions.Timeout.Duration
@@ -162,7 +321,6 @@ func generateHelmUpgradeConfig(actionConfig *helmAction.Configuration, helmOptio
 	upgradeClient.SkipCRDs = helmOptions.SkipCRDs
 	upgradeClient.SubNotes = helmOptions.SubNotes
 	upgradeClient.DisableOpenAPIValidation = helmOptions.DisableOpenAPIValidation
-	upgradeClient.Atomic = helmOptions.Atomic
 	upgradeClient.Force = helmOptions.Upgrade.Force
 	upgradeClient.ResetValues = helmOptions.Upgrade.ResetValues
 	upgradeClient.ReuseValues = helmOptions.Upgrade.ReuseValues
@@ -174,7 +332,7 @@ func generateHelmUpgradeConfig(actionConfig *helmAction.Configuration, helmOptio
 }
 
 // InstallHelmRelease installs a Helm release.
-func (c *HelmClient) InstallHelmRelease(ctx context.Context, restConfig *rest.Config, credentialsPath, caFilePath string, spec addonsv1alpha1.HelmReleaseProxySpec) (*helmRelease.Release, error) {
+func (c *HelmClient) InstallHelmRelease(ctx context.Context, restConfig *rest.Config, credentialsPath, caFilePath string, repositoryAuth RepositoryAuth, spec addonsv1alpha1.HelmReleaseProxySpec) (*helmRelease.Release, error) {
 	log := ctrl.LoggerFrom(ctx)
 
 	settings, actionConfig, err := HelmInit(ctx, spec.ReleaseNamespace, restConfig)
@@ -186,7 +344,7 @@ func (c *HelmClient) InstallHelmRelease(ctx context.Context, restConfig *rest.Co
 	enableClientCache := spec.Options.EnableClientCache
 	log.V(2).Info("Creating install registry client", "enableClientCache", enableClientCache, "credentialsPath", credentialsPath, "cFilePath", caFilePath)
 
-	registryClient, err := newDefaultRegistryClient(credentialsPath, enableClientCache, caFilePath, ptr.Deref(spec.TLSConfig, addonsv1alpha1.TLSConfig{}).InsecureSkipTLSVerify)
+	registryClient, err := newDefaultRegistryClient(credentialsPath, enableClientCache, caFilePath, repositoryAuth, ptr.Deref(spec.TLSConfig, addonsv1alpha1.TLSConfig{}).InsecureSkipTLSVerify)
 	if err != nil {
 		return nil, err
 	}
@@ -211,9 +369,14 @@ func (c *HelmClient) InstallHelmRelease(ctx context.Context, restConfig *rest.Co
 		}
 	}
 	installClient.ReleaseName = spec.ReleaseName
+	installClient.Labels, err = releaseLabels(spec)
+	if err != nil {
+		return nil, err
+	}
+	installClient.PostRenderer = newPostRenderer(spec.PostRenderer)
 
 	log.V(2).Info("Locating chart...")
-	cp, err := installClient.LocateChart(chartName, settings)
+	cp, err := locateChart(ctx, &installClient.ChartPathOptions, chartName, settings, spec, credentialsPath, caFilePath, repositoryAuth, c.RegistryBreaker)
 	if err != nil {
 		return nil, err
 	}
@@ -252,13 +415,52 @@ func (c *HelmClient) InstallHelmRelease(ctx context.Context, restConfig *rest.Co
 	if err != nil {
 		return nil, err
 	}
+
+	clientSet, err := actionConfig.KubernetesClientSet()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkKubeVersion(clientSet.Discovery(), spec, chartRequested); err != nil {
+		return nil, err
+	}
+
+	if err := verifyChartLock(chartRequested); err != nil {
+		return nil, err
+	}
+
+	if spec.Options.Install.CheckResourceQuota {
+		log.V(2).Info("Checking that the chart fits into the ResourceQuotas of the release namespace", "release", spec.ReleaseName, "namespace", spec.ReleaseNamespace)
+		if err := checkResourceQuota(ctx, clientSet, restConfig, spec, chartRequested, vals); err != nil {
+			return nil, err
+		}
+	}
+
+	namespaceCreated := false
+	if installClient.CreateNamespace {
+		exists, err := namespaceExists(ctx, clientSet, spec.ReleaseNamespace)
+		if err != nil {
+			return nil, err
+		}
+		namespaceCreated = !exists
+	}
+
 	log.V(1).Info("Installing with Helm", "chart", spec.ChartName, "repo", spec.RepoURL)
 
-	return installClient.RunWithContext(ctx, chartRequested, vals) // Can return error and a release
+	release, err := installClient.RunWithContext(ctx, chartRequested, vals) // Can return error and a release
+	c.AuditLog.record(ctx, AuditOperationInstall, spec, release, err)
+	if release != nil && namespaceCreated {
+		// Remember that the namespace was created for the release so that it can be garbage collected on uninstall.
+		if markErr := markNamespaceCreated(ctx, clientSet, spec.ReleaseNamespace, release.Name); markErr != nil {
+			log.Error(markErr, "Failed to mark namespace as created for release", "namespace", spec.ReleaseNamespace, "release", release.Name)
+		}
+	}
+
+	return release, err
 }
 
 // newDefaultRegistryClient creates registry client object with default config which can be used to install/upgrade helm charts.
-func newDefaultRegistryClient(credentialsPath string, enableCache bool, caFilePath string, insecureSkipTLSVerify bool) (*registry.Client, error) {
+func newDefaultRegistryClient(credentialsPath string, enableCache bool, caFilePath string, repositoryAuth RepositoryAuth, insecureSkipTLSVerify bool) (*registry.Client, error) {
 	opts := []registry.ClientOption{
 		registry.ClientOptDebug(true),
 		registry.ClientOptEnableCache(enableCache),
@@ -268,16 +470,23 @@ func newDefaultRegistryClient(credentialsPath string, enableCache bool, caFilePa
 		// Create a new registry client with credentials
 		opts = append(opts, registry.ClientOptCredentialsFile(credentialsPath))
 	}
+	if repositoryAuth.PlainHTTP {
+		opts = append(opts, registry.ClientOptPlainHTTP())
+	}
 
-	if caFilePath != "" || insecureSkipTLSVerify {
-		tlsConf, err := newClientTLS(caFilePath, insecureSkipTLSVerify)
+	if caFilePath != "" || repositoryAuth.CertFile != "" || insecureSkipTLSVerify || repositoryAuth.ProxyURL != "" {
+		tlsConf, err := newClientTLS(caFilePath, repositoryAuth.CertFile, repositoryAuth.KeyFile, insecureSkipTLSVerify)
 		if err != nil {
 			return nil, fmt.Errorf("can't create TLS config for client: %w", err)
 		}
+		proxy, err := proxyFunc(repositoryAuth.ProxyURL)
+		if err != nil {
+			return nil, err
+		}
 		opts = append(opts, registry.ClientOptHTTPClient(&http.Client{
 			Transport: &http.Transport{
 				TLSClientConfig: tlsConf,
-				Proxy:           http.ProxyFromEnvironment,
+				Proxy:           proxy,
 				// This registry client is not reused and is discarded after a single reconciliation
 				// loop. Limit how long can be the idle connection open. Otherwise its possible that
 				// a registry server that keeps the connection open for a long time could result in
@@ -292,12 +501,20 @@ func newDefaultRegistryClient(credentialsPath string, enableCache bool, caFilePa
 }
 
 // newClientTLS creates a new TLS config for the registry client based on the provided
-// CA file and insecureSkipTLSverify flag.
-func newClientTLS(caFile string, insecureSkipTLSverify bool) (*tls.Config, error) {
+// CA file, client certificate and key files and insecureSkipTLSverify flag.
+func newClientTLS(caFile, certFile, keyFile string, insecureSkipTLSverify bool) (*tls.Config, error) {
 	config := tls.Config{
 		InsecureSkipVerify: insecureSkipTLSverify,
 	}
 
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "can't load client certificate: %s", certFile)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
 	if caFile != "" {
 		cp, err := certPoolFromFile(caFile)
 		if err != nil {
@@ -341,7 +558,7 @@ func getHelmChartAndRepoName(chartName, repoURL string) (string, string, error)
 }
 
 // UpgradeHelmReleaseIfChanged upgrades a Helm release. The boolean refers to if an upgrade was attempted.
-func (c *HelmClient) UpgradeHelmReleaseIfChanged(ctx context.Context, restConfig *rest.Config, credentialsPath, caFilePath string, spec addonsv1alpha1.HelmReleaseProxySpec, existing *helmRelease.Release) (*helmRelease.Release, error) {
+func (c *HelmClient) UpgradeHelmReleaseIfChanged(ctx context.Context, restConfig *rest.Config, credentialsPath, caFilePath string, repositoryAuth RepositoryAuth, spec addonsv1alpha1.HelmReleaseProxySpec, existing *helmRelease.Release) (*helmRelease.Release, error) {
 	log := ctrl.LoggerFrom(ctx)
 
 	settings, actionConfig, err := HelmInit(ctx, spec.ReleaseNamespace, restConfig)
@@ -353,7 +570,7 @@ func (c *HelmClient) UpgradeHelmReleaseIfChanged(ctx context.Context, restConfig
 	enableClientCache := spec.Options.EnableClientCache
 	log.V(2).Info("Creating upgrade registry client", "enableClientCache", enableClientCache, "credentialsPath", credentialsPath)
 
-	registryClient, err := newDefaultRegistryClient(credentialsPath, enableClientCache, caFilePath, ptr.Deref(spec.TLSConfig, addonsv1alpha1.TLSConfig{}).InsecureSkipTLSVerify)
+	registryClient, err := newDefaultRegistryClient(credentialsPath, enableClientCache, caFilePath, repositoryAuth, ptr.Deref(spec.TLSConfig, addonsv1alpha1.TLSConfig{}).InsecureSkipTLSVerify)
 	if err != nil {
 		return nil, err
 	}
@@ -369,9 +586,15 @@ func (c *HelmClient) UpgradeHelmReleaseIfChanged(ctx context.Context, restConfig
 	upgradeClient.RepoURL = repoURL
 	upgradeClient.Version = spec.Version
 	upgradeClient.Namespace = spec.ReleaseNamespace
+	labels, err := releaseLabels(spec)
+	if err != nil {
+		return nil, err
+	}
+	upgradeClient.Labels = upgradeReleaseLabels(existing.Labels, labels)
+	upgradeClient.PostRenderer = newPostRenderer(spec.PostRenderer)
 
 	log.V(2).Info("Locating chart...")
-	cp, err := upgradeClient.LocateChart(chartName, settings)
+	cp, err := locateChart(ctx, &upgradeClient.ChartPathOptions, chartName, settings, spec, credentialsPath, caFilePath, repositoryAuth, c.RegistryBreaker)
 	if err != nil {
 		return nil, err
 	}
@@ -414,7 +637,7 @@ func (c *HelmClient) UpgradeHelmReleaseIfChanged(ctx context.Context, restConfig
 		return nil, errors.Errorf("failed to load request chart %s", chartName)
 	}
 
-	shouldUpgrade, err := shouldUpgradeHelmRelease(ctx, *existing, chartRequested, vals)
+	shouldUpgrade, err := shouldUpgradeHelmRelease(ctx, *existing, chartRequested, vals, labels)
 	if err != nil {
 		return nil, err
 	}
@@ -423,8 +646,30 @@ func (c *HelmClient) UpgradeHelmReleaseIfChanged(ctx context.Context, restConfig
 		return existing, nil
 	}
 
+	clientSet, err := actionConfig.KubernetesClientSet()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkKubeVersion(clientSet.Discovery(), spec, chartRequested); err != nil {
+		return nil, err
+	}
+
+	if err := verifyChartLock(chartRequested); err != nil {
+		return nil, err
+	}
+
+	log.V(2).Info("Checking that the cluster serves the APIs required by the chart", "release", spec.ReleaseName)
+	if err := checkRequiredAPIs(ctx, restConfig, spec, chartRequested, vals); err != nil {
+		return nil, err
+	}
+
 	log.V(1).Info("Upgrading with Helm", "release", spec.ReleaseName, "repo", spec.RepoURL)
 	release, err := upgradeClient.RunWithContext(ctx, spec.ReleaseName, chartRequested, vals)
+	c.AuditLog.record(ctx, AuditOperationUpgrade, spec, release, err)
+	if err != nil && spec.Options.Atomic {
+		return nil, c.rollbackFailedUpgrade(ctx, restConfig, spec, existing, release, err)
+	}
 
 	return release, err
 	// Should we force upgrade if it failed previously?
@@ -446,8 +691,25 @@ func writeValuesToFile(ctx context.Context, spec addonsv1alpha1.HelmReleaseProxy
 	return valuesFile.Name(), nil
 }
 
+// upgradeReleaseLabels returns the labels to upgrade a Helm release with to replace its existing labels by the desired ones.
+// Helm merges the labels of an upgrade into the existing labels and only removes the ones set to "null".
+func upgradeReleaseLabels(existing, desired map[string]string) map[string]string {
+	labels := maps.Clone(desired)
+	for key := range existing {
+		if _, ok := desired[key]; ok {
+			continue
+		}
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[key] = "null"
+	}
+
+	return labels
+}
+
 // shouldUpgradeHelmRelease determines if a Helm release should be upgraded.
-func shouldUpgradeHelmRelease(ctx context.Context, existing helmRelease.Release, chartRequested *chart.Chart, values map[string]interface{}) (bool, error) {
+func shouldUpgradeHelmRelease(ctx context.Context, existing helmRelease.Release, chartRequested *chart.Chart, values map[string]interface{}, labels map[string]string) (bool, error) {
 	log := ctrl.LoggerFrom(ctx)
 
 	if existing.Chart == nil || existing.Chart.Metadata == nil {
@@ -458,11 +720,21 @@ func shouldUpgradeHelmRelease(ctx context.Context, existing helmRelease.Release,
 		return true, nil
 	}
 
+	if existing.Chart.Metadata.Annotations[addonsv1alpha1.GitCommitAnnotation] != chartRequested.Metadata.Annotations[addonsv1alpha1.GitCommitAnnotation] {
+		log.V(3).Info("Git commits of charts are different, upgrading")
+		return true, nil
+	}
+
 	if existing.Info.Status == helmRelease.StatusFailed {
 		log.Info("Release is in failed state, attempting upgrade to fix it")
 		return true, nil
 	}
 
+	if !maps.Equal(existing.Labels, labels) {
+		log.V(3).Info("Release labels are different, upgrading")
+		return true, nil
+	}
+
 	klog.V(2).Infof("Diff between values is:\n%s", cmp.Diff(existing.Config, values))
 
 	// TODO: Comparing yaml is not ideal, but it's the best we can do since DeepEquals fails. This is because int64 types
@@ -498,7 +770,158 @@ func (c *HelmClient) GetHelmRelease(ctx context.Context, restConfig *rest.Config
 	return release, nil
 }
 
-// ListHelmReleases lists all Helm releases in a namespace.
+// GetHelmReleaseProgress returns the number of ready and pending resources of the latest revision of a Helm release, using
+// the same readiness checks Helm uses when waiting for an install or upgrade, and the notable warning events of its
+// resources while some are pending.
+func (c *HelmClient) GetHelmReleaseProgress(ctx context.Context, restConfig *rest.Config, spec addonsv1alpha1.HelmReleaseProxySpec) (*addonsv1alpha1.ReleaseProgress, error) {
+	if spec.ReleaseName == "" {
+		return nil, helmDriver.ErrReleaseNotFound
+	}
+
+	_, actionConfig, err := HelmInit(ctx, spec.ReleaseNamespace, restConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := helmAction.NewGet(actionConfig).Run(spec.ReleaseName)
+	if err != nil {
+		return nil, err
+	}
+
+	resources, err := actionConfig.KubeClient.Build(bytes.NewBufferString(release.Manifest), false)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build resources of release %s", release.Name)
+	}
+
+	clientSet, err := actionConfig.KubernetesClientSet()
+	if err != nil {
+		return nil, err
+	}
+
+	checker := helmKube.NewReadyChecker(clientSet, klog.V(4).Infof, helmKube.PausedAsReady(true), helmKube.CheckJobs(spec.Options.WaitForJobs))
+	progress, objects, err := countReadyResources(ctx, checker, resources)
+	if err != nil {
+		return nil, err
+	}
+
+	// The events only help diagnosing resources that do not become ready, so a failure to get them is not an error.
+	if progress.Pending > 0 {
+		events, err := getReleaseEvents(ctx, clientSet, objects, release.Info.LastDeployed.Time)
+		if err != nil {
+			ctrl.LoggerFrom(ctx).V(4).Info("Failed to get events of release", "release", release.Name, "error", err.Error())
+		}
+		progress.Events = events
+	}
+
+	return progress, nil
+}
+
+// countReadyResources returns the number of ready and pending resources of a Helm release according to the checker, and
+// its namespaced resources by namespace.
+func countReadyResources(ctx context.Context, checker helmKube.ReadyChecker, resources helmKube.ResourceList) (*addonsv1alpha1.ReleaseProgress, map[string][]releaseObject, error) {
+	progress := &addonsv1alpha1.ReleaseProgress{}
+	objects := map[string][]releaseObject{}
+	for _, info := range resources {
+		ready, err := checker.IsReady(ctx, info)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to check readiness of resource %s/%s", info.Namespace, info.Name)
+		}
+
+		if ready {
+			progress.Ready++
+		} else {
+			progress.Pending++
+		}
+		if info.Namespace != "" && info.Mapping != nil {
+			objects[info.Namespace] = append(objects[info.Namespace], releaseObject{kind: info.Mapping.GroupVersionKind.Kind, name: info.Name})
+		}
+	}
+
+	return progress, objects, nil
+}
+
+// LabelReleaseResources sets the given labels on the Helm storage Secret of the revision of the release on the workload
+// Cluster, and on the release namespace if it was created for the release. Namespaces shared with other releases are not
+// labeled, as they have no single owner. Only missing or differing labels are patched, so that labeling a revision that
+// is already labeled does not write to the workload Cluster.
+func (c *HelmClient) LabelReleaseResources(ctx context.Context, restConfig *rest.Config, spec addonsv1alpha1.HelmReleaseProxySpec, revision int, labels map[string]string) error {
+	if spec.ReleaseName == "" {
+		return helmDriver.ErrReleaseNotFound
+	}
+
+	_, actionConfig, err := HelmInit(ctx, spec.ReleaseNamespace, restConfig)
+	if err != nil {
+		return err
+	}
+
+	clientSet, err := actionConfig.KubernetesClientSet()
+	if err != nil {
+		return err
+	}
+
+	return labelReleaseResources(ctx, clientSet, spec, revision, labels)
+}
+
+// labelReleaseResources sets the labels on the storage Secret of the revision of the release and on the release
+// namespace if it was created for the release, unless they already have them.
+func labelReleaseResources(ctx context.Context, clientSet kubernetes.Interface, spec addonsv1alpha1.HelmReleaseProxySpec, revision int, labels map[string]string) error {
+	namespace, err := clientSet.CoreV1().Namespaces().Get(ctx, spec.ReleaseNamespace, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get namespace %s", spec.ReleaseNamespace)
+	}
+	if namespace.Annotations[addonsv1alpha1.CreatedForReleaseAnnotation] == spec.ReleaseName {
+		if patch := missingLabelsPatch(namespace.Labels, labels); patch != nil {
+			if _, err := clientSet.CoreV1().Namespaces().Patch(ctx, namespace.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+				return errors.Wrapf(err, "failed to label namespace %s", namespace.Name)
+			}
+		}
+	}
+
+	// The Helm Secret storage driver names the Secret of each revision of a release after the release and the revision.
+	name := fmt.Sprintf("sh.helm.release.v1.%s.v%d", spec.ReleaseName, revision)
+	secret, err := clientSet.CoreV1().Secrets(spec.ReleaseNamespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		// Releases stored by another storage driver have no storage Secret.
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+
+		return errors.Wrapf(err, "failed to get storage Secret %s", name)
+	}
+	if patch := missingLabelsPatch(secret.Labels, labels); patch != nil {
+		if _, err := clientSet.CoreV1().Secrets(spec.ReleaseNamespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			return errors.Wrapf(err, "failed to label storage Secret %s", name)
+		}
+	}
+
+	return nil
+}
+
+// missingLabelsPatch returns a merge patch setting the labels that are missing from or differ in the existing labels, or
+// nil if all of them are already set.
+func missingLabelsPatch(existing, labels map[string]string) []byte {
+	missing := map[string]string{}
+	for key, value := range labels {
+		if current, ok := existing[key]; !ok || current != value {
+			missing[key] = value
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	// Marshaling a map of strings cannot fail.
+	patch, _ := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": missing,
+		},
+	})
+
+	return patch
+}
+
+// ListHelmReleases lists the deployed and failed Helm releases in a namespace. If the namespace is empty, the Helm releases of
+// all namespaces are listed.
 func (c *HelmClient) ListHelmReleases(ctx context.Context, restConfig *rest.Config, spec addonsv1alpha1.HelmReleaseProxySpec) ([]*helmRelease.Release, error) {
 	_, actionConfig, err := HelmInit(ctx, spec.ReleaseNamespace, restConfig)
 	if err != nil {
@@ -544,21 +967,71 @@ func (c *HelmClient) UninstallHelmRelease(ctx context.Context, restConfig *rest.
 	uninstallClient := generateHelmUninstallConfig(actionConfig, &spec.Options)
 
 	response, err := uninstallClient.Run(spec.ReleaseName)
+	var uninstalled *helmRelease.Release
+	if response != nil {
+		uninstalled = response.Release
+	}
+	c.AuditLog.record(ctx, AuditOperationUninstall, spec, uninstalled, err)
 	if err != nil {
 		return nil, err
 	}
 
+	if spec.Options.Uninstall != nil && spec.Options.Uninstall.DeleteCreatedNamespace {
+		clientSet, err := actionConfig.KubernetesClientSet()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := deleteCreatedNamespaceIfEmpty(ctx, clientSet, spec.ReleaseNamespace, spec.ReleaseName); err != nil {
+			return nil, err
+		}
+	}
+
 	return response, nil
 }
 
-// RollbackHelmRelease rolls back a Helm release.
-func (c *HelmClient) RollbackHelmRelease(ctx context.Context, restConfig *rest.Config, spec addonsv1alpha1.HelmReleaseProxySpec) error {
+// generateHelmRollbackConfig generates a Helm rollback action to the revision with the HelmOptions.
+func generateHelmRollbackConfig(actionConfig *helmAction.Configuration, helmOptions *addonsv1alpha1.HelmOptions, revision int) *helmAction.Rollback {
+	rollbackClient := helmAction.NewRollback(actionConfig)
+	rollbackClient.Version = revision
+	if helmOptions == nil {
+		return rollbackClient
+	}
+
+	rollbackClient.DisableHooks = helmOptions.DisableHooks
+	rollbackClient.Wait = helmOptions.Wait
+	rollbackClient.WaitForJobs = helmOptions.WaitForJobs
+	if helmOptions.Timeout != nil {
+		rollbackClient.Timeout = helmOptions.Timeout.Duration
+	}
+	rollbackClient.Force = helmOptions.Upgrade.Force
+	rollbackClient.MaxHistory = helmOptions.Upgrade.MaxHistory
+	rollbackClient.CleanupOnFail = helmOptions.Upgrade.CleanupOnFail
+
+	return rollbackClient
+}
+
+// RollbackHelmRelease rolls back a Helm release to the revision and returns the resulting release.
+func (c *HelmClient) RollbackHelmRelease(ctx context.Context, restConfig *rest.Config, spec addonsv1alpha1.HelmReleaseProxySpec, revision int) (*helmRelease.Release, error) {
 	_, actionConfig, err := HelmInit(ctx, spec.ReleaseNamespace, restConfig)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	rollbackClient := helmAction.NewRollback(actionConfig)
+	rollbackClient := generateHelmRollbackConfig(actionConfig, &spec.Options, revision)
+
+	if err := rollbackClient.Run(spec.ReleaseName); err != nil {
+		c.AuditLog.record(ctx, AuditOperationRollback, spec, nil, err)
 
-	return rollbackClient.Run(spec.ReleaseName)
+		return nil, err
+	}
+
+	// The rollback creates a new revision of the release, which the rollback action does not return.
+	release, err := actionConfig.Releases.Last(spec.ReleaseName)
+	c.AuditLog.record(ctx, AuditOperationRollback, spec, release, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get release %s after rollback", spec.ReleaseName)
+	}
+
+	return release, nil
 }
@@ -17,12 +17,23 @@ limitations under the License.
 package internal
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
 
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/types"
+	"helm.sh/helm/v3/pkg/chart"
+	helmKube "helm.sh/helm/v3/pkg/kube"
+	helmRelease "helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes/fake"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
 )
 
 func TestNewDefaultRegistryClient(t *testing.T) {
@@ -36,6 +47,7 @@ func TestNewDefaultRegistryClient(t *testing.T) {
 		credentialsPath       string
 		enableCache           bool
 		caFilePath            string
+		repositoryAuth        RepositoryAuth
 		insecureSkipTLSVerify bool
 		assertErr             types.GomegaMatcher
 		assertClient          types.GomegaMatcher
@@ -85,6 +97,24 @@ func TestNewDefaultRegistryClient(t *testing.T) {
 			assertErr:             Not(HaveOccurred()),
 			assertClient:          Not(BeNil()),
 		},
+		{
+			name:                  "valid client certificate",
+			credentialsPath:       "",
+			enableCache:           true,
+			repositoryAuth:        RepositoryAuth{CertFile: "testdata/client-cert.pem", KeyFile: "testdata/client-key.pem"},
+			insecureSkipTLSVerify: false,
+			assertErr:             Not(HaveOccurred()),
+			assertClient:          Not(BeNil()),
+		},
+		{
+			name:                  "client certificate without key",
+			credentialsPath:       "",
+			enableCache:           true,
+			repositoryAuth:        RepositoryAuth{CertFile: "testdata/client-cert.pem", KeyFile: filepath.Join(t.TempDir(), "non-existing")},
+			insecureSkipTLSVerify: false,
+			assertErr:             MatchError(ContainSubstring("can't load client certificate")),
+			assertClient:          BeNil(),
+		},
 	}
 
 	for _, tc := range testCases {
@@ -95,6 +125,7 @@ func TestNewDefaultRegistryClient(t *testing.T) {
 				tc.credentialsPath,
 				tc.enableCache,
 				tc.caFilePath,
+				tc.repositoryAuth,
 				tc.insecureSkipTLSVerify,
 			)
 			g.Expect(err).To(tc.assertErr)
@@ -102,3 +133,213 @@ func TestNewDefaultRegistryClient(t *testing.T) {
 		})
 	}
 }
+
+func TestDescribeHelmReleaseUpgrade(t *testing.T) {
+	t.Parallel()
+
+	existing := func(version string, status helmRelease.Status) helmRelease.Release {
+		return helmRelease.Release{
+			Name:   "test-release",
+			Chart:  &chart.Chart{Metadata: &chart.Metadata{Name: "test-chart", Version: version}},
+			Config: map[string]interface{}{"replicas": 1},
+			Info:   &helmRelease.Info{Status: status},
+		}
+	}
+	requested := func(version string) *chart.Chart {
+		return &chart.Chart{Metadata: &chart.Metadata{Name: "test-chart", Version: version}}
+	}
+
+	testcases := []struct {
+		name             string
+		existing         helmRelease.Release
+		requested        *chart.Chart
+		values           map[string]interface{}
+		expectedChange   string
+		expectValuesDiff bool
+	}{
+		{
+			name:           "chart version changed",
+			existing:       existing("1.0.0", helmRelease.StatusDeployed),
+			requested:      requested("1.1.0"),
+			values:         map[string]interface{}{"replicas": 1},
+			expectedChange: "upgrade release test-release from chart version 1.0.0 to 1.1.0",
+		},
+		{
+			name:           "failed release",
+			existing:       existing("1.0.0", helmRelease.StatusFailed),
+			requested:      requested("1.0.0"),
+			values:         map[string]interface{}{"replicas": 1},
+			expectedChange: "upgrade failed release test-release",
+		},
+		{
+			name:             "values changed",
+			existing:         existing("1.0.0", helmRelease.StatusDeployed),
+			requested:        requested("1.0.0"),
+			values:           map[string]interface{}{"replicas": 2},
+			expectedChange:   "upgrade values of release test-release, values diff (-existing +desired):",
+			expectValuesDiff: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			t.Parallel()
+
+			change, err := describeHelmReleaseUpgrade(tc.existing, tc.requested, tc.values)
+			g.Expect(err).NotTo(HaveOccurred())
+			if tc.expectValuesDiff {
+				g.Expect(change).To(HavePrefix(tc.expectedChange))
+				g.Expect(change).To(ContainSubstring("replicas: 2"))
+			} else {
+				g.Expect(change).To(Equal(tc.expectedChange))
+			}
+		})
+	}
+}
+
+func TestShouldUpgradeHelmReleaseLabels(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	existing := helmRelease.Release{
+		Name:   "test-release",
+		Chart:  &chart.Chart{Metadata: &chart.Metadata{Name: "test-chart", Version: "1.0.0"}},
+		Config: map[string]interface{}{"replicas": 1},
+		Info:   &helmRelease.Info{Status: helmRelease.StatusDeployed},
+		Labels: map[string]string{"team": "a"},
+	}
+	requested := &chart.Chart{Metadata: &chart.Metadata{Name: "test-chart", Version: "1.0.0"}}
+	values := map[string]interface{}{"replicas": 1}
+
+	g.Expect(shouldUpgradeHelmRelease(context.TODO(), existing, requested, values, map[string]string{"team": "a"})).To(BeFalse())
+	g.Expect(shouldUpgradeHelmRelease(context.TODO(), existing, requested, values, map[string]string{"team": "b"})).To(BeTrue())
+	g.Expect(shouldUpgradeHelmRelease(context.TODO(), existing, requested, values, nil)).To(BeTrue())
+
+	existing.Labels = nil
+	g.Expect(shouldUpgradeHelmRelease(context.TODO(), existing, requested, values, map[string]string{})).To(BeFalse())
+}
+
+func TestShouldUpgradeHelmReleaseGitCommit(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	gitChart := func(commit string) *chart.Chart {
+		return &chart.Chart{Metadata: &chart.Metadata{
+			Name:        "test-chart",
+			Version:     "1.0.0",
+			Annotations: map[string]string{addonsv1alpha1.GitCommitAnnotation: commit},
+		}}
+	}
+	existing := helmRelease.Release{
+		Name:   "test-release",
+		Chart:  gitChart("1111111111111111111111111111111111111111"),
+		Config: map[string]interface{}{"replicas": 1},
+		Info:   &helmRelease.Info{Status: helmRelease.StatusDeployed},
+	}
+	values := map[string]interface{}{"replicas": 1}
+
+	g.Expect(shouldUpgradeHelmRelease(context.TODO(), existing, gitChart("1111111111111111111111111111111111111111"), values, nil)).To(BeFalse())
+	g.Expect(shouldUpgradeHelmRelease(context.TODO(), existing, gitChart("2222222222222222222222222222222222222222"), values, nil)).To(BeTrue(),
+		"charts of a new commit are upgraded to even if their version did not change")
+}
+
+func TestUpgradeReleaseLabels(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	g.Expect(upgradeReleaseLabels(nil, nil)).To(BeNil())
+	g.Expect(upgradeReleaseLabels(nil, map[string]string{"team": "a"})).To(Equal(map[string]string{"team": "a"}))
+	g.Expect(upgradeReleaseLabels(map[string]string{"team": "a", "env": "prod"}, map[string]string{"team": "b"})).
+		To(Equal(map[string]string{"team": "b", "env": "null"}))
+}
+
+func TestCountReadyResources(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	pod := func(name string, ready corev1.ConditionStatus) *corev1.Pod {
+		return &corev1.Pod{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Status:     corev1.PodStatus{Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: ready}}},
+		}
+	}
+	readyPod := pod("ready", corev1.ConditionTrue)
+	pendingPod := pod("pending", corev1.ConditionFalse)
+	configMap := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "default"},
+	}
+	info := func(obj runtime.Object, name, kind string) *resource.Info {
+		return &resource.Info{
+			Name:      name,
+			Namespace: "default",
+			Object:    obj,
+			Mapping:   &meta.RESTMapping{GroupVersionKind: corev1.SchemeGroupVersion.WithKind(kind)},
+		}
+	}
+
+	checker := helmKube.NewReadyChecker(fake.NewSimpleClientset(readyPod, pendingPod), t.Logf, helmKube.PausedAsReady(true))
+	progress, objects, err := countReadyResources(context.TODO(), checker, helmKube.ResourceList{
+		info(readyPod, "ready", "Pod"),
+		info(pendingPod, "pending", "Pod"),
+		info(configMap, "settings", "ConfigMap"),
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(progress).To(Equal(&addonsv1alpha1.ReleaseProgress{Ready: 2, Pending: 1}))
+	g.Expect(objects).To(Equal(map[string][]releaseObject{"default": {
+		{kind: "Pod", name: "ready"},
+		{kind: "Pod", name: "pending"},
+		{kind: "ConfigMap", name: "settings"},
+	}}))
+}
+
+func TestLabelReleaseResources(t *testing.T) {
+	g := NewWithT(t)
+
+	spec := addonsv1alpha1.HelmReleaseProxySpec{ReleaseName: "nginx", ReleaseNamespace: "platform"}
+	labels := map[string]string{addonsv1alpha1.OwnerHelmReleaseProxyLabelName: "nginx-hrp"}
+	storageSecret := func(revision string) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "sh.helm.release.v1.nginx.v" + revision, Namespace: "platform"}}
+	}
+	patches := func(clientSet *fake.Clientset) []string {
+		var patched []string
+		for _, action := range clientSet.Actions() {
+			if action.GetVerb() == "patch" {
+				patched = append(patched, action.GetResource().Resource)
+			}
+		}
+
+		return patched
+	}
+
+	createdNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "platform",
+		Annotations: map[string]string{addonsv1alpha1.CreatedForReleaseAnnotation: "nginx"},
+	}}
+	clientSet := fake.NewSimpleClientset(createdNamespace, storageSecret("1"), storageSecret("2"))
+	g.Expect(labelReleaseResources(context.TODO(), clientSet, spec, 2, labels)).To(Succeed())
+	g.Expect(patches(clientSet)).To(ConsistOf("namespaces", "secrets"))
+
+	namespace, err := clientSet.CoreV1().Namespaces().Get(context.TODO(), "platform", metav1.GetOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(namespace.Labels).To(Equal(labels))
+	secret, err := clientSet.CoreV1().Secrets("platform").Get(context.TODO(), "sh.helm.release.v1.nginx.v2", metav1.GetOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(secret.Labels).To(Equal(labels))
+	secret, err = clientSet.CoreV1().Secrets("platform").Get(context.TODO(), "sh.helm.release.v1.nginx.v1", metav1.GetOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(secret.Labels).To(BeEmpty(), "only the storage Secret of the revision is labeled")
+
+	// Resources that are already labeled are not patched again.
+	clientSet.ClearActions()
+	g.Expect(labelReleaseResources(context.TODO(), clientSet, spec, 2, labels)).To(Succeed())
+	g.Expect(patches(clientSet)).To(BeEmpty())
+
+	// Namespaces that were not created for the release are shared with other releases and are not labeled.
+	sharedNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "platform"}}
+	clientSet = fake.NewSimpleClientset(sharedNamespace, storageSecret("1"))
+	g.Expect(labelReleaseResources(context.TODO(), clientSet, spec, 1, labels)).To(Succeed())
+	g.Expect(patches(clientSet)).To(ConsistOf("secrets"))
+}
@@ -0,0 +1,205 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	helmAction "helm.sh/helm/v3/pkg/action"
+	helmCli "helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/downloader"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/repo"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// RepositoryAuth holds the authentication of requests to an HTTP chart repository that Helm cannot express with its
+// repository options.
+type RepositoryAuth struct {
+	// Headers are extra HTTP headers sent with every request to the chart repository, e.g. tokens required by artifact
+	// proxies.
+	Headers map[string]string
+
+	// Username and Password are the credentials of basic authentication with the chart repository.
+	Username string
+	Password string
+
+	// BearerToken is the token of bearer token authentication with the chart repository. It takes precedence over Username
+	// and Password.
+	BearerToken string
+
+	// CertFile and KeyFile are the paths of the client certificate and key of mutual TLS with the chart repository or
+	// registry.
+	CertFile string
+	KeyFile  string
+
+	// ProxyURL is the URL of the HTTP proxy used to reach the chart repository or registry. If it is empty, the proxy
+	// environment variables are used.
+	ProxyURL string
+
+	// PlainHTTP connects to an OCI registry over HTTP instead of HTTPS.
+	PlainHTTP bool
+
+	// Verification holds the keys the signature of the chart is verified with, if it is verified.
+	Verification *ChartVerification
+}
+
+// isEmpty returns true if the RepositoryAuth does not add headers or credentials to the requests to the chart repository
+// and does not route them through a proxy. The client certificate is set on the TLS configuration of the clients instead.
+func (a RepositoryAuth) isEmpty() bool {
+	return len(a.Headers) == 0 && a.Username == "" && a.Password == "" && a.BearerToken == "" && a.ProxyURL == ""
+}
+
+// proxyFunc returns the proxy function of the HTTP transports reaching chart repositories and registries through the
+// proxy URL, or through the proxy of the environment if it is empty.
+func proxyFunc(proxyURL string) (func(*http.Request) (*url.URL, error), error) {
+	if proxyURL == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+
+	parsed, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid proxy URL %s", proxyURL)
+	}
+
+	return http.ProxyURL(parsed), nil
+}
+
+// headerGetter is a Helm getter for HTTP chart repositories sending the headers and credentials of a RepositoryAuth with
+// every request. Helm only allows configuring its HTTP getter through options that cannot add headers, so the getter
+// replaces it. Like Helm, the headers and credentials are only sent to the host of the repository, so that they are not
+// leaked to chart archives hosted elsewhere.
+type headerGetter struct {
+	client *http.Client
+	auth   RepositoryAuth
+	host   string
+}
+
+// newHeaderGetter returns a headerGetter sending the headers and credentials of the RepositoryAuth to the host with a copy
+// of the client. Redirects to other hosts are followed without the headers and credentials.
+func newHeaderGetter(client *http.Client, auth RepositoryAuth, host string) *headerGetter {
+	redirectClient := *client
+	redirectClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		if req.URL.Host != host {
+			for name := range auth.Headers {
+				req.Header.Del(name)
+			}
+			req.Header.Del("Authorization")
+		}
+
+		return nil
+	}
+
+	return &headerGetter{client: &redirectClient, auth: auth, host: host}
+}
+
+// Get implements getter.Getter. The options passed by Helm are ignored, as the client of the getter is already configured.
+func (g *headerGetter) Get(href string, _ ...getter.Option) (*bytes.Buffer, error) {
+	req, err := http.NewRequest(http.MethodGet, href, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	if req.URL.Host == g.host {
+		for name, value := range g.auth.Headers {
+			req.Header.Set(name, value)
+		}
+		switch {
+		case g.auth.BearerToken != "":
+			req.Header.Set("Authorization", "Bearer "+g.auth.BearerToken)
+		case g.auth.Username != "" || g.auth.Password != "":
+			req.SetBasicAuth(g.auth.Username, g.auth.Password)
+		}
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to fetch %s : %s", href, resp.Status)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if _, err := io.Copy(buf, resp.Body); err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+// locateHTTPChart downloads the chart of an HTTP chart repository into the repository cache using the RepositoryAuth and
+// returns its path. It is used instead of ChartPathOptions.LocateChart when the RepositoryAuth is not empty or the
+// provenance of the chart is verified, in which case the provenance file is downloaded next to the chart.
+func locateHTTPChart(ctx context.Context, pathOptions *helmAction.ChartPathOptions, chartName string, settings *helmCli.EnvSettings, repositoryAuth RepositoryAuth, caFilePath string, insecureSkipTLSVerify bool) (string, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	repoURL, err := url.Parse(pathOptions.RepoURL)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid repository URL %s", pathOptions.RepoURL)
+	}
+	client, err := newHTTPClient(caFilePath, repositoryAuth, insecureSkipTLSVerify)
+	if err != nil {
+		return "", err
+	}
+	providers := getter.Providers{
+		{
+			Schemes: []string{"http", "https"},
+			New: func(...getter.Option) (getter.Getter, error) {
+				return newHeaderGetter(client, repositoryAuth, repoURL.Host), nil
+			},
+		},
+	}
+
+	log.V(2).Info("Locating chart with repository auth", "chart", chartName, "repo", pathOptions.RepoURL)
+	chartURL, err := repo.FindChartInAuthAndTLSAndPassRepoURL(pathOptions.RepoURL, "", "", chartName, pathOptions.Version, "", "", "", false, false, providers)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(settings.RepositoryCache, 0o755); err != nil {
+		return "", err
+	}
+	dl := downloader.ChartDownloader{
+		Out:              io.Discard,
+		Getters:          providers,
+		RepositoryConfig: settings.RepositoryConfig,
+		RepositoryCache:  settings.RepositoryCache,
+	}
+	// The provenance is verified by locateChart, also for charts located before, so a missing provenance file only fails
+	// the verification.
+	if repositoryAuth.verifiesProvenance() {
+		dl.Verify = downloader.VerifyLater
+	}
+	filename, _, err := dl.DownloadTo(chartURL, pathOptions.Version, settings.RepositoryCache)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Abs(filename)
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	helmAction "helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	helmCli "helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/repo"
+	"sigs.k8s.io/yaml"
+)
+
+func TestLocateHTTPChart(t *testing.T) {
+	archive, err := chartutil.Save(&chart.Chart{Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "test-chart", Version: "1.2.3"}}, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	archiveContent, err := os.ReadFile(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, _ := r.BasicAuth()
+		authorized := r.Header.Get("X-Artifact-Token") == "secret" ||
+			r.Header.Get("Authorization") == "Bearer token" ||
+			(username == "user" && password == "pass")
+		if !authorized {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	index := repo.NewIndexFile()
+	if err := index.MustAdd(&chart.Metadata{APIVersion: chart.APIVersionV2, Name: "test-chart", Version: "1.2.3"}, filepath.Base(archive), server.URL, ""); err != nil {
+		t.Fatal(err)
+	}
+	indexContent, err := yaml.Marshal(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux.HandleFunc("/index.yaml", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(indexContent)
+	})
+	mux.HandleFunc("/"+filepath.Base(archive), func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(archiveContent)
+	})
+
+	testcases := []struct {
+		name           string
+		repositoryAuth RepositoryAuth
+		expectedError  string
+	}{
+		{
+			name:           "headers are sent",
+			repositoryAuth: RepositoryAuth{Headers: map[string]string{"X-Artifact-Token": "secret"}},
+		},
+		{
+			name:           "basic authentication",
+			repositoryAuth: RepositoryAuth{Username: "user", Password: "pass"},
+		},
+		{
+			name:           "bearer token authentication",
+			repositoryAuth: RepositoryAuth{BearerToken: "token"},
+		},
+		{
+			name:           "wrong password",
+			repositoryAuth: RepositoryAuth{Username: "user", Password: "wrong"},
+			expectedError:  "401 Unauthorized",
+		},
+		{
+			name:           "wrong header value",
+			repositoryAuth: RepositoryAuth{Headers: map[string]string{"X-Artifact-Token": "wrong"}},
+			expectedError:  "401 Unauthorized",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			settings := helmCli.New()
+			settings.RepositoryCache = t.TempDir()
+			settings.RepositoryConfig = filepath.Join(t.TempDir(), "repositories.yaml")
+			pathOptions := &helmAction.ChartPathOptions{RepoURL: server.URL, Version: "1.2.3"}
+
+			path, err := locateHTTPChart(context.TODO(), pathOptions, "test-chart", settings, tc.repositoryAuth, "", false)
+			if tc.expectedError != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tc.expectedError)))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+
+			content, err := os.ReadFile(path)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(content).To(Equal(archiveContent))
+		})
+	}
+}
+
+func TestHeaderGetter(t *testing.T) {
+	g := NewWithT(t)
+
+	// other records the headers of the requests it receives.
+	var otherHeaders []http.Header
+	other := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		otherHeaders = append(otherHeaders, r.Header.Clone())
+	}))
+	defer other.Close()
+
+	var repositoryHeaders http.Header
+	repository := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		repositoryHeaders = r.Header.Clone()
+		http.Redirect(w, r, other.URL+"/chart.tgz", http.StatusFound)
+	}))
+	defer repository.Close()
+
+	repositoryURL, err := url.Parse(repository.URL)
+	g.Expect(err).NotTo(HaveOccurred())
+	getter := newHeaderGetter(repository.Client(), RepositoryAuth{Headers: map[string]string{"X-Artifact-Token": "secret"}, BearerToken: "token"}, repositoryURL.Host)
+
+	// The headers are sent to the repository, but not to the host it redirects to.
+	_, err = getter.Get(repository.URL + "/chart.tgz")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(repositoryHeaders.Get("X-Artifact-Token")).To(Equal("secret"))
+	g.Expect(repositoryHeaders.Get("Authorization")).To(Equal("Bearer token"))
+	g.Expect(otherHeaders).To(HaveLen(1))
+	g.Expect(otherHeaders[0].Get("X-Artifact-Token")).To(BeEmpty())
+	g.Expect(otherHeaders[0].Get("Authorization")).To(BeEmpty())
+
+	// Nor are they sent to chart archives hosted elsewhere.
+	_, err = getter.Get(other.URL + "/chart.tgz")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(otherHeaders).To(HaveLen(2))
+	g.Expect(otherHeaders[1].Get("X-Artifact-Token")).To(BeEmpty())
+	g.Expect(otherHeaders[1].Get("Authorization")).To(BeEmpty())
+}
+
+func TestProxyFunc(t *testing.T) {
+	g := NewWithT(t)
+
+	req := httptest.NewRequest(http.MethodGet, "https://charts.example.com/index.yaml", http.NoBody)
+
+	proxy, err := proxyFunc("http://proxy.corp.local:3128")
+	g.Expect(err).NotTo(HaveOccurred())
+	proxyURL, err := proxy(req)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(proxyURL.String()).To(Equal("http://proxy.corp.local:3128"))
+
+	_, err = proxyFunc("http://proxy.corp.local:port")
+	g.Expect(err).To(MatchError(ContainSubstring("invalid proxy URL")))
+
+	proxy, err = proxyFunc("")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(proxy).NotTo(BeNil())
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"k8s.io/client-go/discovery"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+)
+
+// KubeVersionIncompatibleError is returned when the Kubernetes version of the workload Cluster is outside the range
+// supported by a Helm chart.
+type KubeVersionIncompatibleError struct {
+	// KubeVersion is the Kubernetes version of the workload Cluster.
+	KubeVersion string
+
+	// Constraint is the supported Kubernetes version range the Cluster does not satisfy.
+	Constraint string
+}
+
+func (e *KubeVersionIncompatibleError) Error() string {
+	return fmt.Sprintf("cluster Kubernetes version %s is incompatible with the supported range %s", e.KubeVersion, e.Constraint)
+}
+
+// checkKubeVersion returns a KubeVersionIncompatibleError if the Kubernetes version of the workload Cluster does not
+// satisfy the KubeVersion of the spec or the kubeVersion declared in the Chart.yaml of the requested chart.
+func checkKubeVersion(client discovery.ServerVersionInterface, spec addonsv1alpha1.HelmReleaseProxySpec, chartRequested *chart.Chart) error {
+	constraints := []string{}
+	if spec.KubeVersion != "" {
+		constraints = append(constraints, spec.KubeVersion)
+	}
+	if chartRequested.Metadata != nil && chartRequested.Metadata.KubeVersion != "" {
+		constraints = append(constraints, chartRequested.Metadata.KubeVersion)
+	}
+	if len(constraints) == 0 {
+		return nil
+	}
+
+	serverVersion, err := client.ServerVersion()
+	if err != nil {
+		return errors.Wrapf(err, "failed to discover Kubernetes version of cluster %s", spec.ClusterRef.Name)
+	}
+
+	for _, constraint := range constraints {
+		if !chartutil.IsCompatibleRange(constraint, serverVersion.GitVersion) {
+			return &KubeVersionIncompatibleError{KubeVersion: serverVersion.GitVersion, Constraint: constraint}
+		}
+	}
+
+	return nil
+}
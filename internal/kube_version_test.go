@@ -0,0 +1,76 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/chart"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+)
+
+func TestCheckKubeVersion(t *testing.T) {
+	discovery := fake.NewSimpleClientset().Discovery().(*fakediscovery.FakeDiscovery)
+	discovery.FakedServerVersion = &version.Info{GitVersion: "v1.30.2"}
+
+	testcases := []struct {
+		name             string
+		specKubeVersion  string
+		chartKubeVersion string
+		expectedError    bool
+	}{
+		{
+			name: "no supported range declared",
+		},
+		{
+			name:            "cluster version within spec range",
+			specKubeVersion: ">=1.27.0-0 <1.31.0-0",
+		},
+		{
+			name:            "cluster version outside spec range",
+			specKubeVersion: "<1.30.0-0",
+			expectedError:   true,
+		},
+		{
+			name:             "cluster version outside Chart.yaml range",
+			specKubeVersion:  ">=1.27.0-0",
+			chartKubeVersion: ">=1.31.0-0",
+			expectedError:    true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			spec := addonsv1alpha1.HelmReleaseProxySpec{KubeVersion: tc.specKubeVersion}
+			chartRequested := &chart.Chart{Metadata: &chart.Metadata{Name: "test-chart", KubeVersion: tc.chartKubeVersion}}
+
+			err := checkKubeVersion(discovery, spec, chartRequested)
+			if tc.expectedError {
+				var kubeVersionErr *KubeVersionIncompatibleError
+				g.Expect(err).To(BeAssignableToTypeOf(kubeVersionErr))
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+		})
+	}
+}
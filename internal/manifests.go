@@ -0,0 +1,189 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"slices"
+	"sort"
+
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// ManifestsFieldOwner is the field manager the resources of the Manifests of HelmReleaseProxies are applied with.
+const ManifestsFieldOwner = "cluster-api-addon-provider-helm"
+
+// RenderManifests renders the Go templating of the Manifests of the HelmChartProxy for the Cluster, with the same
+// templating objects and functions as the ValuesTemplate.
+func RenderManifests(ctx context.Context, c ctrlClient.Client, spec addonsv1alpha1.HelmChartProxySpec, cluster *clusterv1.Cluster, opts TemplateOptions) (string, error) {
+	manifestsSpec := spec
+	manifestsSpec.ValuesTemplate = spec.Manifests
+	// The proxy settings are values of Helm charts, so they are not injected into plain manifests.
+	manifestsSpec.ProxyValues = nil
+
+	return ParseValues(ctx, c, manifestsSpec, cluster, opts)
+}
+
+// DecodeManifests decodes the multi-document YAML manifests into objects sorted in the order Helm installs resources in,
+// e.g. Namespaces and CustomResourceDefinitions first. Empty documents are skipped.
+func DecodeManifests(manifests string) ([]*unstructured.Unstructured, error) {
+	docs := releaseutil.SplitManifests(manifests)
+	keys := make([]string, 0, len(docs))
+	for key := range docs {
+		keys = append(keys, key)
+	}
+	sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+
+	objs := make([]*unstructured.Unstructured, 0, len(keys))
+	for i, key := range keys {
+		obj := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(docs[key]), &obj.Object); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse document %d of manifests", i)
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+		if obj.GetAPIVersion() == "" || obj.GetKind() == "" || obj.GetName() == "" {
+			return nil, errors.Errorf("document %d of manifests must have an apiVersion, a kind and a name", i)
+		}
+		objs = append(objs, obj)
+	}
+
+	slices.SortStableFunc(objs, func(a, b *unstructured.Unstructured) int {
+		return kindOrder(releaseutil.InstallOrder, a.GetKind()) - kindOrder(releaseutil.InstallOrder, b.GetKind())
+	})
+
+	return objs, nil
+}
+
+// kindOrder returns the position of the kind in the order, kinds not in it coming last.
+func kindOrder(order releaseutil.KindSortOrder, kind string) int {
+	if i := slices.Index(order, kind); i >= 0 {
+		return i
+	}
+
+	return len(order)
+}
+
+// ApplyManifests applies the resources of the Manifests of the HelmReleaseProxy to the workload Cluster with server-side
+// apply, and deletes the resources of the inventory that are no longer in the Manifests once all of them are applied. It
+// returns the inventory of the resources on the workload Cluster, which also keeps the resources of the previous inventory
+// if applying failed, so that they are still deleted later.
+func ApplyManifests(ctx context.Context, workloadClient ctrlClient.Client, spec addonsv1alpha1.HelmReleaseProxySpec, inventory []corev1.ObjectReference) ([]corev1.ObjectReference, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	objs, err := DecodeManifests(spec.Manifests)
+	if err != nil {
+		return inventory, err
+	}
+
+	applied := make([]corev1.ObjectReference, 0, len(objs))
+	for _, obj := range objs {
+		namespaced, err := workloadClient.IsObjectNamespaced(obj)
+		if err != nil {
+			return mergeInventory(inventory, applied), errors.Wrapf(err, "failed to get scope of %s %s", obj.GetKind(), obj.GetName())
+		}
+		if namespaced && obj.GetNamespace() == "" {
+			obj.SetNamespace(spec.ReleaseNamespace)
+			if obj.GetNamespace() == "" {
+				obj.SetNamespace(metav1.NamespaceDefault)
+			}
+		}
+
+		log.V(2).Info("Applying manifest", "kind", obj.GetKind(), "namespace", obj.GetNamespace(), "name", obj.GetName())
+		if err := workloadClient.Patch(ctx, obj, ctrlClient.Apply, ctrlClient.FieldOwner(ManifestsFieldOwner), ctrlClient.ForceOwnership); err != nil {
+			return mergeInventory(inventory, applied), errors.Wrapf(err, "failed to apply %s %s", obj.GetKind(), manifestResourceName(obj.GetNamespace(), obj.GetName()))
+		}
+		applied = append(applied, corev1.ObjectReference{
+			APIVersion: obj.GetAPIVersion(),
+			Kind:       obj.GetKind(),
+			Namespace:  obj.GetNamespace(),
+			Name:       obj.GetName(),
+		})
+	}
+
+	pruned := slices.DeleteFunc(slices.Clone(inventory), func(ref corev1.ObjectReference) bool {
+		return slices.ContainsFunc(applied, func(a corev1.ObjectReference) bool { return isSameManifestResource(a, ref) })
+	})
+	if err := DeleteManifests(ctx, workloadClient, pruned); err != nil {
+		return mergeInventory(inventory, applied), err
+	}
+
+	return applied, nil
+}
+
+// DeleteManifests deletes the resources of the inventory from the workload Cluster in the order Helm uninstalls resources
+// in. Resources that are already gone are skipped.
+func DeleteManifests(ctx context.Context, workloadClient ctrlClient.Client, inventory []corev1.ObjectReference) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	refs := slices.Clone(inventory)
+	slices.SortStableFunc(refs, func(a, b corev1.ObjectReference) int {
+		return kindOrder(releaseutil.UninstallOrder, a.Kind) - kindOrder(releaseutil.UninstallOrder, b.Kind)
+	})
+
+	for _, ref := range refs {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion(ref.APIVersion)
+		obj.SetKind(ref.Kind)
+		obj.SetNamespace(ref.Namespace)
+		obj.SetName(ref.Name)
+
+		log.V(2).Info("Deleting manifest", "kind", ref.Kind, "namespace", ref.Namespace, "name", ref.Name)
+		if err := workloadClient.Delete(ctx, obj); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete %s %s", ref.Kind, manifestResourceName(ref.Namespace, ref.Name))
+		}
+	}
+
+	return nil
+}
+
+// mergeInventory returns the inventory with the applied resources it does not contain yet.
+func mergeInventory(inventory, applied []corev1.ObjectReference) []corev1.ObjectReference {
+	merged := slices.Clone(inventory)
+	for _, ref := range applied {
+		if !slices.ContainsFunc(merged, func(m corev1.ObjectReference) bool { return isSameManifestResource(m, ref) }) {
+			merged = append(merged, ref)
+		}
+	}
+
+	return merged
+}
+
+// isSameManifestResource returns true if both references are to the same resource, regardless of the version of its API.
+func isSameManifestResource(a, b corev1.ObjectReference) bool {
+	return a.GroupVersionKind().GroupKind() == b.GroupVersionKind().GroupKind() && a.Namespace == b.Namespace && a.Name == b.Name
+}
+
+// manifestResourceName returns the name of a resource, prefixed with its namespace if it is namespaced.
+func manifestResourceName(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+
+	return namespace + "/" + name
+}
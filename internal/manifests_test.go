@@ -0,0 +1,152 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestDecodeManifests(t *testing.T) {
+	g := NewWithT(t)
+
+	objs, err := DecodeManifests(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  namespace: platform
+---
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: platform
+`)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(objs).To(HaveLen(2))
+	g.Expect(objs[0].GetKind()).To(Equal("Namespace"))
+	g.Expect(objs[1].GetKind()).To(Equal("ConfigMap"))
+
+	_, err = DecodeManifests("apiVersion: v1\nkind: ConfigMap\n")
+	g.Expect(err).To(MatchError("document 0 of manifests must have an apiVersion, a kind and a name"))
+
+	_, err = DecodeManifests("apiVersion: v1\nkind: [ConfigMap\n")
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestApplyManifests(t *testing.T) {
+	g := NewWithT(t)
+
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{corev1.SchemeGroupVersion})
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("Namespace"), meta.RESTScopeRoot)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+	stale := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "stale", Namespace: "platform"}}
+	c := fake.NewClientBuilder().
+		WithRESTMapper(mapper).
+		WithObjects(stale).
+		WithInterceptorFuncs(interceptor.Funcs{
+			// The fake client does not support server-side apply, so applied objects are created or replaced.
+			Patch: func(ctx context.Context, c ctrlClient.WithWatch, obj ctrlClient.Object, patch ctrlClient.Patch, opts ...ctrlClient.PatchOption) error {
+				if patch.Type() != types.ApplyPatchType {
+					return c.Patch(ctx, obj, patch, opts...)
+				}
+				existing := &unstructured.Unstructured{}
+				existing.SetGroupVersionKind(obj.GetObjectKind().GroupVersionKind())
+				if err := c.Get(ctx, ctrlClient.ObjectKeyFromObject(obj), existing); err != nil {
+					if apierrors.IsNotFound(err) {
+						return c.Create(ctx, obj)
+					}
+
+					return err
+				}
+				obj.SetResourceVersion(existing.GetResourceVersion())
+
+				return c.Update(ctx, obj)
+			},
+		}).
+		Build()
+
+	spec := addonsv1alpha1.HelmReleaseProxySpec{
+		ReleaseNamespace: "platform",
+		Manifests: `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  key: value
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: platform
+`,
+	}
+	inventory := []corev1.ObjectReference{{APIVersion: "v1", Kind: "ConfigMap", Namespace: "platform", Name: "stale"}}
+
+	inventory, err := ApplyManifests(context.TODO(), c, spec, inventory)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(inventory).To(Equal([]corev1.ObjectReference{
+		{APIVersion: "v1", Kind: "Namespace", Name: "platform"},
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: "platform", Name: "settings"},
+	}))
+
+	settings := &corev1.ConfigMap{}
+	g.Expect(c.Get(context.TODO(), ctrlClient.ObjectKey{Namespace: "platform", Name: "settings"}, settings)).To(Succeed())
+	g.Expect(settings.Data).To(HaveKeyWithValue("key", "value"))
+
+	err = c.Get(context.TODO(), ctrlClient.ObjectKeyFromObject(stale), &corev1.ConfigMap{})
+	g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+	// Resources are never deleted if the manifests cannot be applied.
+	spec.Manifests = "apiVersion: v1\nkind: ConfigMap\n"
+	failedInventory, err := ApplyManifests(context.TODO(), c, spec, inventory)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(failedInventory).To(Equal(inventory))
+	g.Expect(c.Get(context.TODO(), ctrlClient.ObjectKeyFromObject(settings), &corev1.ConfigMap{})).To(Succeed())
+}
+
+func TestDeleteManifests(t *testing.T) {
+	g := NewWithT(t)
+
+	c := fake.NewClientBuilder().
+		WithObjects(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "platform"}}).
+		Build()
+	inventory := []corev1.ObjectReference{
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: "platform", Name: "settings"},
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: "platform", Name: "already-deleted"},
+	}
+
+	g.Expect(DeleteManifests(context.TODO(), c, inventory)).To(Succeed())
+
+	err := c.Get(context.TODO(), ctrlClient.ObjectKey{Namespace: "platform", Name: "settings"}, &corev1.ConfigMap{})
+	g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+}
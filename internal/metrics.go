@@ -0,0 +1,227 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	metricsNamespaceLabel      = "namespace"
+	metricsHelmChartProxyLabel = "helmchartproxy"
+	metricsClusterLabel        = "cluster"
+	metricsKindLabel           = "kind"
+	metricsNameLabel           = "name"
+	metricsTypeLabel           = "type"
+	metricsStatusLabel         = "status"
+)
+
+var (
+	helmReleaseProxiesGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "caaph_helmreleaseproxies",
+			Help: "Number of HelmReleaseProxies owned by a HelmChartProxy.",
+		},
+		[]string{metricsNamespaceLabel, metricsHelmChartProxyLabel, metricsClusterLabel},
+	)
+
+	helmReleaseProxiesReadyGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "caaph_helmreleaseproxies_ready",
+			Help: "Number of HelmReleaseProxies owned by a HelmChartProxy whose HelmReleaseReady condition is true.",
+		},
+		[]string{metricsNamespaceLabel, metricsHelmChartProxyLabel, metricsClusterLabel},
+	)
+
+	lastSuccessfulReconcileGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "caaph_last_successful_reconcile_timestamp_seconds",
+			Help: "Unix time of the last reconcile of a HelmChartProxy or HelmReleaseProxy without error. It stops advancing if the controller is wedged.",
+		},
+		[]string{metricsKindLabel, metricsNamespaceLabel, metricsNameLabel},
+	)
+
+	reconcileStaleGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "caaph_reconcile_stale",
+			Help: "Whether a HelmChartProxy or HelmReleaseProxy was not reconciled without error within the staleness threshold (1) or was (0).",
+		},
+		[]string{metricsKindLabel, metricsNamespaceLabel, metricsNameLabel},
+	)
+
+	clusterOperationsQueuedGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "caaph_cluster_helm_operations_queued",
+			Help: "Number of Helm operations waiting for a workload Cluster to run fewer than the maximum concurrent operations per Cluster.",
+		},
+		[]string{metricsNamespaceLabel, metricsClusterLabel},
+	)
+
+	clusterOperationsInFlightGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "caaph_cluster_helm_operations_in_flight",
+			Help: "Number of Helm operations running on a workload Cluster.",
+		},
+		[]string{metricsNamespaceLabel, metricsClusterLabel},
+	)
+
+	helmChartProxyMatchingClustersGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "caaph_helmchartproxy_matching_clusters",
+			Help: "Number of Clusters selected by a HelmChartProxy.",
+		},
+		[]string{metricsNamespaceLabel, metricsHelmChartProxyLabel},
+	)
+
+	helmChartProxyOutOfDateReleasesGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "caaph_helmchartproxy_out_of_date_releases",
+			Help: "Number of HelmReleaseProxies of a HelmChartProxy whose spec does not yet match the desired state rendered from it.",
+		},
+		[]string{metricsNamespaceLabel, metricsHelmChartProxyLabel},
+	)
+
+	helmChartProxyConditionGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "caaph_helmchartproxy_status_condition",
+			Help: "Condition of a HelmChartProxy, set to 1 for the current status of each condition type and to 0 for the others.",
+		},
+		[]string{metricsNamespaceLabel, metricsHelmChartProxyLabel, metricsTypeLabel, metricsStatusLabel},
+	)
+
+	chartCacheProxyRequestsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "caaph_chart_cache_proxy_requests_total",
+			Help: "Number of manifest and blob requests served by the chart cache proxy, by whether they were served from the cache (hit), fetched from the upstream registry (miss), served from the cache while the upstream registry is unavailable (stale) or failed (error).",
+		},
+		[]string{"type", "result"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(helmReleaseProxiesGauge, helmReleaseProxiesReadyGauge, lastSuccessfulReconcileGauge, reconcileStaleGauge,
+		clusterOperationsQueuedGauge, clusterOperationsInFlightGauge, helmChartProxyMatchingClustersGauge, helmChartProxyOutOfDateReleasesGauge,
+		helmChartProxyConditionGauge, chartCacheProxyRequestsCounter)
+}
+
+// recordChartCacheProxyRequest counts a request of the type, i.e. manifest or blob, served by the chart cache proxy with the
+// result.
+func recordChartCacheProxyRequest(requestType, result string) {
+	chartCacheProxyRequestsCounter.WithLabelValues(requestType, result).Inc()
+}
+
+// DeleteReconcileMetrics deletes the reconcile metric series recorded for a deleted HelmChartProxy or HelmReleaseProxy.
+func DeleteReconcileMetrics(kind, namespace, name string) {
+	lastSuccessfulReconcileGauge.DeleteLabelValues(kind, namespace, name)
+	reconcileStaleGauge.DeleteLabelValues(kind, namespace, name)
+}
+
+// RecordHelmReleaseProxyMetrics records the HelmReleaseProxy metrics for a HelmChartProxy. Metrics are labeled per Cluster
+// unless the number of matching Clusters exceeds the ClusterLabelThreshold of the HelmChartProxy, in which case the
+// series are aggregated into a single series per HelmChartProxy with an empty cluster label.
+func RecordHelmReleaseProxyMetrics(helmChartProxy *addonsv1alpha1.HelmChartProxy, helmReleaseProxies []addonsv1alpha1.HelmReleaseProxy) {
+	DeleteHelmReleaseProxyMetrics(helmChartProxy)
+
+	switch clusterLabelPolicyFor(helmChartProxy) {
+	case addonsv1alpha1.ClusterLabelPolicyAggregate:
+		ready := 0
+		for i := range helmReleaseProxies {
+			if conditions.IsTrue(&helmReleaseProxies[i], addonsv1alpha1.HelmReleaseReadyCondition) {
+				ready++
+			}
+		}
+		helmReleaseProxiesGauge.WithLabelValues(helmChartProxy.Namespace, helmChartProxy.Name, "").Set(float64(len(helmReleaseProxies)))
+		helmReleaseProxiesReadyGauge.WithLabelValues(helmChartProxy.Namespace, helmChartProxy.Name, "").Set(float64(ready))
+	default:
+		for i := range helmReleaseProxies {
+			helmReleaseProxy := &helmReleaseProxies[i]
+			cluster := helmReleaseProxy.Spec.ClusterRef.Name
+			ready := 0.0
+			if conditions.IsTrue(helmReleaseProxy, addonsv1alpha1.HelmReleaseReadyCondition) {
+				ready = 1
+			}
+			helmReleaseProxiesGauge.WithLabelValues(helmChartProxy.Namespace, helmChartProxy.Name, cluster).Set(1)
+			helmReleaseProxiesReadyGauge.WithLabelValues(helmChartProxy.Namespace, helmChartProxy.Name, cluster).Set(ready)
+		}
+	}
+}
+
+// DeleteHelmReleaseProxyMetrics deletes all HelmReleaseProxy metric series recorded for a HelmChartProxy.
+func DeleteHelmReleaseProxyMetrics(helmChartProxy *addonsv1alpha1.HelmChartProxy) {
+	labels := prometheus.Labels{
+		metricsNamespaceLabel:      helmChartProxy.Namespace,
+		metricsHelmChartProxyLabel: helmChartProxy.Name,
+	}
+	helmReleaseProxiesGauge.DeletePartialMatch(labels)
+	helmReleaseProxiesReadyGauge.DeletePartialMatch(labels)
+}
+
+// RecordHelmChartProxyStatusMetrics records the number of matching Clusters and out of date HelmReleaseProxies and the
+// conditions of a HelmChartProxy, so that they can be observed even if they are omitted from a lightweight status.
+func RecordHelmChartProxyStatusMetrics(helmChartProxy *addonsv1alpha1.HelmChartProxy) {
+	DeleteHelmChartProxyStatusMetrics(helmChartProxy)
+
+	helmChartProxyMatchingClustersGauge.WithLabelValues(helmChartProxy.Namespace, helmChartProxy.Name).Set(float64(matchingClusterCount(helmChartProxy)))
+	helmChartProxyOutOfDateReleasesGauge.WithLabelValues(helmChartProxy.Namespace, helmChartProxy.Name).Set(float64(len(helmChartProxy.Status.OutOfDateReleases)))
+	for _, condition := range helmChartProxy.GetConditions() {
+		for _, status := range []corev1.ConditionStatus{corev1.ConditionTrue, corev1.ConditionFalse, corev1.ConditionUnknown} {
+			value := 0.0
+			if condition.Status == status {
+				value = 1
+			}
+			helmChartProxyConditionGauge.WithLabelValues(helmChartProxy.Namespace, helmChartProxy.Name, string(condition.Type), strings.ToLower(string(status))).Set(value)
+		}
+	}
+}
+
+// DeleteHelmChartProxyStatusMetrics deletes all status metric series recorded for a HelmChartProxy.
+func DeleteHelmChartProxyStatusMetrics(helmChartProxy *addonsv1alpha1.HelmChartProxy) {
+	labels := prometheus.Labels{
+		metricsNamespaceLabel:      helmChartProxy.Namespace,
+		metricsHelmChartProxyLabel: helmChartProxy.Name,
+	}
+	helmChartProxyMatchingClustersGauge.DeletePartialMatch(labels)
+	helmChartProxyOutOfDateReleasesGauge.DeletePartialMatch(labels)
+	helmChartProxyConditionGauge.DeletePartialMatch(labels)
+}
+
+// matchingClusterCount returns the number of Clusters selected by a HelmChartProxy, which is only counted in its status if
+// it was reconciled with a lightweight status.
+func matchingClusterCount(helmChartProxy *addonsv1alpha1.HelmChartProxy) int {
+	return max(len(helmChartProxy.Status.MatchingClusters), int(helmChartProxy.Status.MatchingClusterCount))
+}
+
+// clusterLabelPolicyFor returns the ClusterLabelPolicy in effect for a HelmChartProxy, or an empty policy if metrics should
+// be labeled per Cluster.
+func clusterLabelPolicyFor(helmChartProxy *addonsv1alpha1.HelmChartProxy) addonsv1alpha1.ClusterLabelPolicy {
+	opts := helmChartProxy.Spec.Metrics
+	if opts == nil || opts.ClusterLabelThreshold == nil {
+		return ""
+	}
+
+	if matchingClusterCount(helmChartProxy) <= int(*opts.ClusterLabelThreshold) {
+		return ""
+	}
+
+	return addonsv1alpha1.ClusterLabelPolicyAggregate
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+func TestRecordHelmReleaseProxyMetrics(t *testing.T) {
+	helmReleaseProxies := []addonsv1alpha1.HelmReleaseProxy{
+		{
+			Spec: addonsv1alpha1.HelmReleaseProxySpec{ClusterRef: corev1.ObjectReference{Name: "cluster-1"}},
+			Status: addonsv1alpha1.HelmReleaseProxyStatus{
+				Conditions: clusterv1.Conditions{{Type: addonsv1alpha1.HelmReleaseReadyCondition, Status: corev1.ConditionTrue}},
+			},
+		},
+		{
+			Spec: addonsv1alpha1.HelmReleaseProxySpec{ClusterRef: corev1.ObjectReference{Name: "cluster-2"}},
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		metrics       *addonsv1alpha1.MetricsOptions
+		expectedCount int
+		expectedReady float64
+	}{
+		{
+			name:          "per cluster series when metrics options are unset",
+			metrics:       nil,
+			expectedCount: 2,
+		},
+		{
+			name:          "per cluster series when threshold is not exceeded",
+			metrics:       &addonsv1alpha1.MetricsOptions{ClusterLabelThreshold: ptr.To[int32](2)},
+			expectedCount: 2,
+		},
+		{
+			name:          "aggregated series when threshold is exceeded",
+			metrics:       &addonsv1alpha1.MetricsOptions{ClusterLabelThreshold: ptr.To[int32](1)},
+			expectedCount: 1,
+			expectedReady: 1,
+		},
+		{
+			name: "aggregated series when threshold is exceeded and policy is Aggregate",
+			metrics: &addonsv1alpha1.MetricsOptions{
+				ClusterLabelThreshold: ptr.To[int32](1),
+				ClusterLabelPolicy:    string(addonsv1alpha1.ClusterLabelPolicyAggregate),
+			},
+			expectedCount: 1,
+			expectedReady: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			helmChartProxy := &addonsv1alpha1.HelmChartProxy{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-hcp", Namespace: "test-namespace"},
+				Spec:       addonsv1alpha1.HelmChartProxySpec{Metrics: tc.metrics},
+				Status: addonsv1alpha1.HelmChartProxyStatus{
+					MatchingClusters: []corev1.ObjectReference{{Name: "cluster-1"}, {Name: "cluster-2"}},
+				},
+			}
+			defer DeleteHelmReleaseProxyMetrics(helmChartProxy)
+
+			RecordHelmReleaseProxyMetrics(helmChartProxy, helmReleaseProxies)
+
+			g.Expect(testutil.CollectAndCount(helmReleaseProxiesGauge)).To(Equal(tc.expectedCount))
+			g.Expect(testutil.CollectAndCount(helmReleaseProxiesReadyGauge)).To(Equal(tc.expectedCount))
+			if tc.expectedCount == 1 {
+				g.Expect(testutil.ToFloat64(helmReleaseProxiesGauge.WithLabelValues("test-namespace", "test-hcp", ""))).To(Equal(float64(len(helmReleaseProxies))))
+				g.Expect(testutil.ToFloat64(helmReleaseProxiesReadyGauge.WithLabelValues("test-namespace", "test-hcp", ""))).To(Equal(tc.expectedReady))
+			}
+		})
+	}
+}
+
+func TestRecordHelmChartProxyStatusMetrics(t *testing.T) {
+	g := NewWithT(t)
+
+	helmChartProxy := &addonsv1alpha1.HelmChartProxy{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-hcp", Namespace: "test-namespace"},
+		Status: addonsv1alpha1.HelmChartProxyStatus{
+			MatchingClusterCount: 3,
+			OutOfDateReleases:    []corev1.ObjectReference{{Name: "test-hrp"}},
+			Conditions: clusterv1.Conditions{
+				{Type: clusterv1.ReadyCondition, Status: corev1.ConditionFalse},
+				{Type: addonsv1alpha1.HelmReleaseProxySpecsUpToDateCondition, Status: corev1.ConditionTrue},
+			},
+		},
+	}
+	defer DeleteHelmChartProxyStatusMetrics(helmChartProxy)
+
+	RecordHelmChartProxyStatusMetrics(helmChartProxy)
+
+	g.Expect(testutil.ToFloat64(helmChartProxyMatchingClustersGauge.WithLabelValues("test-namespace", "test-hcp"))).To(Equal(3.0))
+	g.Expect(testutil.ToFloat64(helmChartProxyOutOfDateReleasesGauge.WithLabelValues("test-namespace", "test-hcp"))).To(Equal(1.0))
+	g.Expect(testutil.CollectAndCount(helmChartProxyConditionGauge)).To(Equal(6))
+	g.Expect(testutil.ToFloat64(helmChartProxyConditionGauge.WithLabelValues("test-namespace", "test-hcp", string(clusterv1.ReadyCondition), "false"))).To(Equal(1.0))
+	g.Expect(testutil.ToFloat64(helmChartProxyConditionGauge.WithLabelValues("test-namespace", "test-hcp", string(clusterv1.ReadyCondition), "true"))).To(Equal(0.0))
+
+	// Conditions that were removed from the status are no longer recorded.
+	helmChartProxy.Status.Conditions = helmChartProxy.Status.Conditions[:1]
+	RecordHelmChartProxyStatusMetrics(helmChartProxy)
+	g.Expect(testutil.CollectAndCount(helmChartProxyConditionGauge)).To(Equal(3))
+}
@@ -32,6 +32,7 @@ import (
 	release "helm.sh/helm/v3/pkg/release"
 	rest "k8s.io/client-go/rest"
 	v1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	internal "sigs.k8s.io/cluster-api-addon-provider-helm/internal"
 )
 
 // MockClient is a mock of Client interface.
@@ -58,6 +59,52 @@ func (m *MockClient) EXPECT() *MockClientMockRecorder {
 	return m.recorder
 }
 
+// DiffHelmRelease mocks base method.
+func (m *MockClient) DiffHelmRelease(ctx context.Context, restConfig *rest.Config, credentialsPath, caFilePath string, repositoryAuth internal.RepositoryAuth, spec v1alpha1.HelmReleaseProxySpec) (*release.Release, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DiffHelmRelease", ctx, restConfig, credentialsPath, caFilePath, repositoryAuth, spec)
+	ret0, _ := ret[0].(*release.Release)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// DiffHelmRelease indicates an expected call of DiffHelmRelease.
+func (mr *MockClientMockRecorder) DiffHelmRelease(ctx, restConfig, credentialsPath, caFilePath, repositoryAuth, spec any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DiffHelmRelease", reflect.TypeOf((*MockClient)(nil).DiffHelmRelease), ctx, restConfig, credentialsPath, caFilePath, repositoryAuth, spec)
+}
+
+// GetChartSBOM mocks base method.
+func (m *MockClient) GetChartSBOM(ctx context.Context, spec v1alpha1.HelmReleaseProxySpec, credentialsPath, caFilePath string, sbom v1alpha1.SBOMReference, maxSize int64) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetChartSBOM", ctx, spec, credentialsPath, caFilePath, sbom, maxSize)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetChartSBOM indicates an expected call of GetChartSBOM.
+func (mr *MockClientMockRecorder) GetChartSBOM(ctx, spec, credentialsPath, caFilePath, sbom, maxSize any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChartSBOM", reflect.TypeOf((*MockClient)(nil).GetChartSBOM), ctx, spec, credentialsPath, caFilePath, sbom, maxSize)
+}
+
+// GetChartSBOMs mocks base method.
+func (m *MockClient) GetChartSBOMs(ctx context.Context, spec v1alpha1.HelmReleaseProxySpec, credentialsPath, caFilePath string) ([]v1alpha1.SBOMReference, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetChartSBOMs", ctx, spec, credentialsPath, caFilePath)
+	ret0, _ := ret[0].([]v1alpha1.SBOMReference)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetChartSBOMs indicates an expected call of GetChartSBOMs.
+func (mr *MockClientMockRecorder) GetChartSBOMs(ctx, spec, credentialsPath, caFilePath any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChartSBOMs", reflect.TypeOf((*MockClient)(nil).GetChartSBOMs), ctx, spec, credentialsPath, caFilePath)
+}
+
 // GetHelmRelease mocks base method.
 func (m *MockClient) GetHelmRelease(ctx context.Context, restConfig *rest.Config, spec v1alpha1.HelmReleaseProxySpec) (*release.Release, error) {
 	m.ctrl.T.Helper()
@@ -73,19 +120,123 @@ func (mr *MockClientMockRecorder) GetHelmRelease(ctx, restConfig, spec any) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHelmRelease", reflect.TypeOf((*MockClient)(nil).GetHelmRelease), ctx, restConfig, spec)
 }
 
+// GetHelmReleaseProgress mocks base method.
+func (m *MockClient) GetHelmReleaseProgress(ctx context.Context, restConfig *rest.Config, spec v1alpha1.HelmReleaseProxySpec) (*v1alpha1.ReleaseProgress, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHelmReleaseProgress", ctx, restConfig, spec)
+	ret0, _ := ret[0].(*v1alpha1.ReleaseProgress)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHelmReleaseProgress indicates an expected call of GetHelmReleaseProgress.
+func (mr *MockClientMockRecorder) GetHelmReleaseProgress(ctx, restConfig, spec any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHelmReleaseProgress", reflect.TypeOf((*MockClient)(nil).GetHelmReleaseProgress), ctx, restConfig, spec)
+}
+
 // InstallOrUpgradeHelmRelease mocks base method.
-func (m *MockClient) InstallOrUpgradeHelmRelease(ctx context.Context, restConfig *rest.Config, credentialsPath, caFilePath string, spec v1alpha1.HelmReleaseProxySpec) (*release.Release, error) {
+func (m *MockClient) InstallOrUpgradeHelmRelease(ctx context.Context, restConfig *rest.Config, credentialsPath, caFilePath string, repositoryAuth internal.RepositoryAuth, spec v1alpha1.HelmReleaseProxySpec) (*release.Release, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "InstallOrUpgradeHelmRelease", ctx, restConfig, credentialsPath, caFilePath, spec)
+	ret := m.ctrl.Call(m, "InstallOrUpgradeHelmRelease", ctx, restConfig, credentialsPath, caFilePath, repositoryAuth, spec)
 	ret0, _ := ret[0].(*release.Release)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // InstallOrUpgradeHelmRelease indicates an expected call of InstallOrUpgradeHelmRelease.
-func (mr *MockClientMockRecorder) InstallOrUpgradeHelmRelease(ctx, restConfig, credentialsPath, caFilePath, spec any) *gomock.Call {
+func (mr *MockClientMockRecorder) InstallOrUpgradeHelmRelease(ctx, restConfig, credentialsPath, caFilePath, repositoryAuth, spec any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InstallOrUpgradeHelmRelease", reflect.TypeOf((*MockClient)(nil).InstallOrUpgradeHelmRelease), ctx, restConfig, credentialsPath, caFilePath, repositoryAuth, spec)
+}
+
+// LabelReleaseResources mocks base method.
+func (m *MockClient) LabelReleaseResources(ctx context.Context, restConfig *rest.Config, spec v1alpha1.HelmReleaseProxySpec, revision int, labels map[string]string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LabelReleaseResources", ctx, restConfig, spec, revision, labels)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LabelReleaseResources indicates an expected call of LabelReleaseResources.
+func (mr *MockClientMockRecorder) LabelReleaseResources(ctx, restConfig, spec, revision, labels any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LabelReleaseResources", reflect.TypeOf((*MockClient)(nil).LabelReleaseResources), ctx, restConfig, spec, revision, labels)
+}
+
+// ListHelmReleases mocks base method.
+func (m *MockClient) ListHelmReleases(ctx context.Context, restConfig *rest.Config, spec v1alpha1.HelmReleaseProxySpec) ([]*release.Release, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListHelmReleases", ctx, restConfig, spec)
+	ret0, _ := ret[0].([]*release.Release)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListHelmReleases indicates an expected call of ListHelmReleases.
+func (mr *MockClientMockRecorder) ListHelmReleases(ctx, restConfig, spec any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListHelmReleases", reflect.TypeOf((*MockClient)(nil).ListHelmReleases), ctx, restConfig, spec)
+}
+
+// ReconcileHelmReleaseDrift mocks base method.
+func (m *MockClient) ReconcileHelmReleaseDrift(ctx context.Context, restConfig *rest.Config, spec v1alpha1.HelmReleaseProxySpec, correct bool) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReconcileHelmReleaseDrift", ctx, restConfig, spec, correct)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReconcileHelmReleaseDrift indicates an expected call of ReconcileHelmReleaseDrift.
+func (mr *MockClientMockRecorder) ReconcileHelmReleaseDrift(ctx, restConfig, spec, correct any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReconcileHelmReleaseDrift", reflect.TypeOf((*MockClient)(nil).ReconcileHelmReleaseDrift), ctx, restConfig, spec, correct)
+}
+
+// ResolveChartDigest mocks base method.
+func (m *MockClient) ResolveChartDigest(ctx context.Context, spec v1alpha1.HelmReleaseProxySpec, credentialsPath, caFilePath string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveChartDigest", ctx, spec, credentialsPath, caFilePath)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResolveChartDigest indicates an expected call of ResolveChartDigest.
+func (mr *MockClientMockRecorder) ResolveChartDigest(ctx, spec, credentialsPath, caFilePath any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveChartDigest", reflect.TypeOf((*MockClient)(nil).ResolveChartDigest), ctx, spec, credentialsPath, caFilePath)
+}
+
+// RollbackHelmRelease mocks base method.
+func (m *MockClient) RollbackHelmRelease(ctx context.Context, restConfig *rest.Config, spec v1alpha1.HelmReleaseProxySpec, revision int) (*release.Release, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RollbackHelmRelease", ctx, restConfig, spec, revision)
+	ret0, _ := ret[0].(*release.Release)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RollbackHelmRelease indicates an expected call of RollbackHelmRelease.
+func (mr *MockClientMockRecorder) RollbackHelmRelease(ctx, restConfig, spec, revision any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RollbackHelmRelease", reflect.TypeOf((*MockClient)(nil).RollbackHelmRelease), ctx, restConfig, spec, revision)
+}
+
+// TestHelmRelease mocks base method.
+func (m *MockClient) TestHelmRelease(ctx context.Context, restConfig *rest.Config, spec v1alpha1.HelmReleaseProxySpec) (*release.Release, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TestHelmRelease", ctx, restConfig, spec)
+	ret0, _ := ret[0].(*release.Release)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TestHelmRelease indicates an expected call of TestHelmRelease.
+func (mr *MockClientMockRecorder) TestHelmRelease(ctx, restConfig, spec any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InstallOrUpgradeHelmRelease", reflect.TypeOf((*MockClient)(nil).InstallOrUpgradeHelmRelease), ctx, restConfig, credentialsPath, caFilePath, spec)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TestHelmRelease", reflect.TypeOf((*MockClient)(nil).TestHelmRelease), ctx, restConfig, spec)
 }
 
 // UninstallHelmRelease mocks base method.
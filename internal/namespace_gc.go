@@ -0,0 +1,154 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// rootCAConfigMapName is the ConfigMap Kubernetes publishes into every namespace, which does not make a namespace non-empty.
+const rootCAConfigMapName = "kube-root-ca.crt"
+
+// namespaceExists returns true if the namespace exists on the workload Cluster.
+func namespaceExists(ctx context.Context, clientSet kubernetes.Interface, namespace string) (bool, error) {
+	if _, err := clientSet.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{}); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+
+		return false, errors.Wrapf(err, "failed to get namespace %s", namespace)
+	}
+
+	return true, nil
+}
+
+// markNamespaceCreated annotates the namespace as created for the given release.
+func markNamespaceCreated(ctx context.Context, clientSet kubernetes.Interface, namespace, releaseName string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				addonsv1alpha1.CreatedForReleaseAnnotation: releaseName,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := clientSet.CoreV1().Namespaces().Patch(ctx, namespace, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return errors.Wrapf(err, "failed to annotate namespace %s", namespace)
+	}
+
+	return nil
+}
+
+// deleteCreatedNamespaceIfEmpty deletes the namespace if it was created for the given release and is otherwise empty.
+func deleteCreatedNamespaceIfEmpty(ctx context.Context, clientSet kubernetes.Interface, namespace, releaseName string) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	ns, err := clientSet.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+
+		return errors.Wrapf(err, "failed to get namespace %s", namespace)
+	}
+
+	if ns.Annotations[addonsv1alpha1.CreatedForReleaseAnnotation] != releaseName {
+		log.V(2).Info("Namespace was not created for release, skipping deletion", "namespace", namespace, "release", releaseName)
+		return nil
+	}
+
+	empty, err := isNamespaceEmpty(ctx, clientSet, namespace)
+	if err != nil {
+		return err
+	}
+	if !empty {
+		log.V(2).Info("Namespace is not empty, skipping deletion", "namespace", namespace, "release", releaseName)
+		return nil
+	}
+
+	log.V(2).Info("Deleting namespace created for release", "namespace", namespace, "release", releaseName)
+	if err := clientSet.CoreV1().Namespaces().Delete(ctx, namespace, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete namespace %s", namespace)
+	}
+
+	return nil
+}
+
+// isNamespaceEmpty returns true if no Pods, Services, PersistentVolumeClaims, Secrets or ConfigMaps remain in the namespace,
+// ignoring the ones Kubernetes creates in every namespace.
+func isNamespaceEmpty(ctx context.Context, clientSet kubernetes.Interface, namespace string) (bool, error) {
+	core := clientSet.CoreV1()
+
+	pods, err := core.Pods(namespace).List(ctx, metav1.ListOptions{Limit: 1})
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to list Pods in namespace %s", namespace)
+	}
+	if len(pods.Items) > 0 {
+		return false, nil
+	}
+
+	services, err := core.Services(namespace).List(ctx, metav1.ListOptions{Limit: 1})
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to list Services in namespace %s", namespace)
+	}
+	if len(services.Items) > 0 {
+		return false, nil
+	}
+
+	pvcs, err := core.PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{Limit: 1})
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to list PersistentVolumeClaims in namespace %s", namespace)
+	}
+	if len(pvcs.Items) > 0 {
+		return false, nil
+	}
+
+	secrets, err := core.Secrets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to list Secrets in namespace %s", namespace)
+	}
+	for _, secret := range secrets.Items {
+		if secret.Type != corev1.SecretTypeServiceAccountToken {
+			return false, nil
+		}
+	}
+
+	configMaps, err := core.ConfigMaps(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to list ConfigMaps in namespace %s", namespace)
+	}
+	for _, configMap := range configMaps.Items {
+		if configMap.Name != rootCAConfigMapName {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
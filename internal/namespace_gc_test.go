@@ -0,0 +1,91 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+)
+
+func TestDeleteCreatedNamespaceIfEmpty(t *testing.T) {
+	createdNamespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "addon",
+			Annotations: map[string]string{addonsv1alpha1.CreatedForReleaseAnnotation: "test-release"},
+		},
+	}
+	rootCA := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: rootCAConfigMapName, Namespace: "addon"}}
+
+	testCases := []struct {
+		name          string
+		objects       []runtime.Object
+		expectDeleted bool
+	}{
+		{
+			name:          "deletes an empty namespace created for the release",
+			objects:       []runtime.Object{createdNamespace.DeepCopy(), rootCA.DeepCopy()},
+			expectDeleted: true,
+		},
+		{
+			name: "keeps a namespace that was not created for the release",
+			objects: []runtime.Object{&corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "addon",
+					Annotations: map[string]string{addonsv1alpha1.CreatedForReleaseAnnotation: "other-release"},
+				},
+			}},
+		},
+		{
+			name: "keeps a namespace that is not empty",
+			objects: []runtime.Object{
+				createdNamespace.DeepCopy(),
+				&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "addon"}},
+			},
+		},
+		{
+			name: "keeps a namespace that still has Helm release history",
+			objects: []runtime.Object{
+				createdNamespace.DeepCopy(),
+				&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "sh.helm.release.v1.test-release.v1", Namespace: "addon"}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			clientSet := fake.NewSimpleClientset(tc.objects...)
+			g.Expect(deleteCreatedNamespaceIfEmpty(context.TODO(), clientSet, "addon", "test-release")).To(Succeed())
+
+			_, err := clientSet.CoreV1().Namespaces().Get(context.TODO(), "addon", metav1.GetOptions{})
+			if tc.expectDeleted {
+				g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+		})
+	}
+}
@@ -0,0 +1,621 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/containerd/containerd/remotes/docker"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/registry"
+	"k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/utils/ptr"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+)
+
+// sbomMediaTypes are the media types of SBOMs attached to a chart, either as a layer of the chart manifest or as the
+// artifact type of a referrer of it.
+var sbomMediaTypes = map[string]struct{}{
+	"application/spdx+json":          {},
+	"text/spdx":                      {},
+	"application/vnd.cyclonedx+json": {},
+	"application/vnd.cyclonedx+xml":  {},
+	"application/vnd.syft+json":      {},
+}
+
+// maxManifestSize is the maximum size of a manifest or index fetched from a registry, the limit of containerd and ORAS.
+const maxManifestSize = 4 << 20
+
+const (
+	// ociArtifactsCacheSize is the maximum number of chart versions whose SBOMs or digests are cached. The chart versions
+	// used the longest time ago are evicted first.
+	ociArtifactsCacheSize = 1024
+
+	// ociArtifactsCacheTTL is the duration the SBOMs and digests of a chart version are cached for, so that a tag pushed
+	// again is eventually resolved again.
+	ociArtifactsCacheTTL = time.Hour
+)
+
+// sbomCache remembers the SBOMs of pinned chart versions, as the artifacts of a pushed version do not change.
+var sbomCache = cache.NewLRUExpireCache(ociArtifactsCacheSize)
+
+// digestCache remembers the digests the tags of pinned chart versions resolve to, as a pushed version does not change.
+var digestCache = cache.NewLRUExpireCache(ociArtifactsCacheSize)
+
+// ociRepository is a minimal client of the OCI distribution API for a single repository. It is used for the parts of OCI
+// charts the Helm registry client does not handle, i.e. custom artifact layouts and referrers.
+type ociRepository struct {
+	client     *http.Client
+	authorizer docker.Authorizer
+	host       string
+	name       string
+
+	// plainHTTP connects to the registry over HTTP instead of HTTPS.
+	plainHTTP bool
+}
+
+// newOCIRepository returns an ociRepository for the OCI chart of the spec, presenting the client certificate of the
+// RepositoryAuth if it has one.
+func newOCIRepository(spec addonsv1alpha1.HelmReleaseProxySpec, credentialsPath, caFilePath string, repositoryAuth RepositoryAuth) (*ociRepository, error) {
+	ref := strings.TrimSuffix(strings.TrimPrefix(spec.RepoURL, "oci://"), "/") + "/" + spec.ChartName
+	host, name, ok := strings.Cut(ref, "/")
+	if !ok {
+		return nil, errors.Errorf("invalid OCI chart reference %s", ref)
+	}
+
+	client, err := newHTTPClient(caFilePath, repositoryAuth, ptr.Deref(spec.TLSConfig, addonsv1alpha1.TLSConfig{}).InsecureSkipTLSVerify)
+	if err != nil {
+		return nil, err
+	}
+
+	creds, err := registryCredentials(credentialsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := newOCIRepositoryWithClient(host, name, client, creds)
+	repo.plainHTTP = repositoryAuth.PlainHTTP
+
+	return repo, nil
+}
+
+// newHTTPClient returns an HTTP client for registries and chart repositories with the given CA certificate, client
+// certificate and proxy of the RepositoryAuth and TLS verification settings.
+func newHTTPClient(caFilePath string, repositoryAuth RepositoryAuth, insecureSkipTLSVerify bool) (*http.Client, error) {
+	proxy, err := proxyFunc(repositoryAuth.ProxyURL)
+	if err != nil {
+		return nil, err
+	}
+	transport := &http.Transport{
+		Proxy: proxy,
+		// The client is discarded after a single reconciliation loop, see newDefaultRegistryClient.
+		IdleConnTimeout: 1 * time.Second,
+	}
+	if caFilePath != "" || repositoryAuth.CertFile != "" || insecureSkipTLSVerify {
+		tlsConf, err := newClientTLS(caFilePath, repositoryAuth.CertFile, repositoryAuth.KeyFile, insecureSkipTLSVerify)
+		if err != nil {
+			return nil, fmt.Errorf("can't create TLS config for client: %w", err)
+		}
+		transport.TLSClientConfig = tlsConf
+	}
+
+	return &http.Client{Transport: transport}, nil
+}
+
+// newOCIRepositoryWithClient returns an ociRepository using the given HTTP client and credentials.
+func newOCIRepositoryWithClient(host, name string, client *http.Client, creds func(string) (string, string, error)) *ociRepository {
+	return &ociRepository{
+		client:     client,
+		authorizer: docker.NewDockerAuthorizer(docker.WithAuthClient(client), docker.WithAuthCreds(creds)),
+		host:       host,
+		name:       name,
+	}
+}
+
+// registryCredentials returns a function looking up the credentials of a registry host in a Docker config file, the
+// format of the OCI credentials of a HelmChartProxy.
+func registryCredentials(credentialsPath string) (func(string) (string, string, error), error) {
+	if credentialsPath == "" {
+		return registryCredentialsFromConfig(nil)
+	}
+
+	b, err := os.ReadFile(credentialsPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read credentials file %s", credentialsPath)
+	}
+	creds, err := registryCredentialsFromConfig(b)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse credentials file %s", credentialsPath)
+	}
+
+	return creds, nil
+}
+
+// registryCredentialsFromConfig returns a function looking up the credentials of a registry host in the content of a
+// Docker config file. An empty config has no credentials.
+func registryCredentialsFromConfig(b []byte) (func(string) (string, string, error), error) {
+	type authConfig struct {
+		Auth     string `json:"auth"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	config := struct {
+		Auths map[string]authConfig `json:"auths"`
+	}{}
+
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, &config); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(host string) (string, string, error) {
+		auth, ok := config.Auths[host]
+		if !ok {
+			return "", "", nil
+		}
+		if auth.Auth == "" {
+			return auth.Username, auth.Password, nil
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+		if err != nil {
+			return "", "", errors.Wrapf(err, "failed to decode credentials of registry %s", host)
+		}
+		username, password, _ := strings.Cut(string(decoded), ":")
+
+		return username, password, nil
+	}, nil
+}
+
+// get sends a GET request for the path of the repository, authorizing it again if the registry challenges it.
+func (r *ociRepository) get(ctx context.Context, urlPath string, accept ...string) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		scheme := "https"
+		if r.plainHTTP {
+			scheme = "http"
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s://%s/v2/%s/%s", scheme, r.host, r.name, urlPath), http.NoBody)
+		if err != nil {
+			return nil, err
+		}
+		if len(accept) > 0 {
+			req.Header.Set("Accept", strings.Join(accept, ", "))
+		}
+		if err := r.authorizer.Authorize(ctx, req); err != nil {
+			return nil, errors.Wrapf(err, "failed to authorize request to %s", r.host)
+		}
+
+		resp, err := r.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusUnauthorized || attempt > 0 {
+			return resp, nil
+		}
+
+		err = r.authorizer.AddResponses(ctx, []*http.Response{resp})
+		resp.Body.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to authenticate to %s", r.host)
+		}
+	}
+}
+
+// getJSON gets the path of the repository and decodes the JSON response into obj. It returns the digest of the response
+// body, or false if the registry returned not found.
+func (r *ociRepository) getJSON(ctx context.Context, urlPath string, obj interface{}, accept ...string) (digest.Digest, bool, error) {
+	resp, err := r.get(ctx, urlPath, accept...)
+	if err != nil {
+		return "", false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", false, errors.Errorf("unexpected status %s getting %s/%s", resp.Status, r.name, urlPath)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize+1))
+	if err != nil {
+		return "", false, err
+	}
+	if len(body) > maxManifestSize {
+		return "", false, errors.Errorf("%s/%s exceeds %d bytes", r.name, urlPath, maxManifestSize)
+	}
+	if err := json.Unmarshal(body, obj); err != nil {
+		return "", false, errors.Wrapf(err, "failed to decode %s/%s", r.name, urlPath)
+	}
+
+	return digest.FromBytes(body), true, nil
+}
+
+// manifest returns the image manifest of the reference and its digest.
+func (r *ociRepository) manifest(ctx context.Context, reference string) (*ocispec.Manifest, digest.Digest, error) {
+	manifest := &ocispec.Manifest{}
+	dgst, found, err := r.getJSON(ctx, "manifests/"+reference, manifest, ocispec.MediaTypeImageManifest)
+	if err != nil {
+		return nil, "", err
+	}
+	if !found {
+		return nil, "", errors.Errorf("manifest %s:%s not found", r.name, reference)
+	}
+
+	return manifest, dgst, nil
+}
+
+// referrers returns the descriptors of the manifests referring to the manifest with the digest. Registries without the
+// referrers API are queried with the referrers tag schema instead.
+func (r *ociRepository) referrers(ctx context.Context, dgst digest.Digest) ([]ocispec.Descriptor, error) {
+	index := &ocispec.Index{}
+	_, found, err := r.getJSON(ctx, "referrers/"+dgst.String(), index, ocispec.MediaTypeImageIndex)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return index.Manifests, nil
+	}
+
+	tag := fmt.Sprintf("%s-%s", dgst.Algorithm(), dgst.Encoded())
+	if _, _, err := r.getJSON(ctx, "manifests/"+tag, index, ocispec.MediaTypeImageIndex); err != nil {
+		return nil, err
+	}
+
+	return index.Manifests, nil
+}
+
+// downloadBlob streams the blob of the descriptor to the file, verifying its size and content. The blob is written to a
+// temporary file that replaces the file once complete, so that concurrent downloads of the same chart never read a
+// partial file.
+func (r *ociRepository) downloadBlob(ctx context.Context, desc ocispec.Descriptor, filename string) error {
+	f, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := r.fetchBlob(ctx, desc, f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), filename)
+}
+
+// fetchBlob writes the blob of the descriptor to w, verifying its size and content.
+func (r *ociRepository) fetchBlob(ctx context.Context, desc ocispec.Descriptor, w io.Writer) error {
+	n, err := r.fetchBlobLimited(ctx, desc.Digest, desc.Size, w)
+	if err != nil {
+		return err
+	}
+	if n != desc.Size {
+		return errors.Errorf("blob %s has %d bytes, not %d", desc.Digest, n, desc.Size)
+	}
+
+	return nil
+}
+
+// fetchBlobLimited writes the blob with the digest to w, verifying its content, and returns its size. It fails without
+// reading more than maxSize bytes if the blob is larger.
+func (r *ociRepository) fetchBlobLimited(ctx context.Context, dgst digest.Digest, maxSize int64, w io.Writer) (int64, error) {
+	resp, err := r.get(ctx, "blobs/"+dgst.String())
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, errors.Errorf("unexpected status %s getting blob %s", resp.Status, dgst)
+	}
+	if resp.ContentLength > maxSize {
+		return 0, errors.Errorf("blob %s exceeds %d bytes", dgst, maxSize)
+	}
+
+	verifier := dgst.Verifier()
+	n, err := io.Copy(io.MultiWriter(w, verifier), io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to download blob %s", dgst)
+	}
+	if n > maxSize {
+		return 0, errors.Errorf("blob %s exceeds %d bytes", dgst, maxSize)
+	}
+	if !verifier.Verified() {
+		return 0, errors.Errorf("content of blob %s does not match its digest", dgst)
+	}
+
+	return n, nil
+}
+
+// ociTag returns the tag of a chart version, as Helm replaces the "+" not allowed in tags with "_".
+func ociTag(version string) string {
+	return strings.ReplaceAll(version, "+", "_")
+}
+
+// ociReference returns the reference of the manifest of a chart, i.e. the digest it is pinned to or the tag of its version.
+func ociReference(version, dgst string) string {
+	if dgst != "" {
+		return dgst
+	}
+
+	return ociTag(version)
+}
+
+// pullOCIChartLayer downloads the chart layer of the pinned OCI chart of the spec into the directory and returns the path
+// of the chart archive. The chart layer is the layer with a Helm chart media type or, failing that, the first layer
+// titled as a chart archive. Charts pinned by digest are pulled by their digest rather than the tag of their version.
+func pullOCIChartLayer(ctx context.Context, spec addonsv1alpha1.HelmReleaseProxySpec, credentialsPath, caFilePath string, repositoryAuth RepositoryAuth, dir string) (string, error) {
+	repo, err := newOCIRepository(spec, credentialsPath, caFilePath, repositoryAuth)
+	if err != nil {
+		return "", err
+	}
+
+	if spec.Digest != "" {
+		return repo.pullChartLayerByDigest(ctx, spec.Digest, spec.Version, dir)
+	}
+
+	return repo.pullChartLayer(ctx, spec.Version, dir)
+}
+
+// pullChartLayer downloads the chart layer of the chart version into the directory and returns the path of the chart
+// archive.
+func (r *ociRepository) pullChartLayer(ctx context.Context, version, dir string) (string, error) {
+	manifest, _, err := r.manifest(ctx, ociTag(version))
+	if err != nil {
+		return "", err
+	}
+
+	chartLayer := chartLayerOf(manifest)
+	if chartLayer == nil {
+		return "", errors.Errorf("manifest of chart %s:%s does not contain a chart layer", r.name, version)
+	}
+
+	filename := filepath.Join(dir, fmt.Sprintf("%s-%s.tgz", path.Base(r.name), version))
+	if err := r.downloadBlob(ctx, *chartLayer, filename); err != nil {
+		return "", err
+	}
+
+	return filename, nil
+}
+
+// pullChartLayerByDigest downloads the chart layer of the manifest with the digest into the directory and returns the path
+// of the chart archive. It returns an error if the content of the manifest does not match the digest, or if the chart is
+// not of the version unless the version is empty.
+func (r *ociRepository) pullChartLayerByDigest(ctx context.Context, pinned, version, dir string) (string, error) {
+	dgst, err := digest.Parse(pinned)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid digest %s of chart %s", pinned, r.name)
+	}
+
+	manifest, manifestDigest, err := r.manifest(ctx, dgst.String())
+	if err != nil {
+		return "", err
+	}
+	if manifestDigest != dgst {
+		return "", errors.Errorf("manifest of chart %s@%s does not match its digest, got %s", r.name, dgst, manifestDigest)
+	}
+
+	chartLayer := chartLayerOf(manifest)
+	if chartLayer == nil {
+		return "", errors.Errorf("manifest of chart %s@%s does not contain a chart layer", r.name, dgst)
+	}
+
+	filename := filepath.Join(dir, fmt.Sprintf("%s-%s.tgz", path.Base(r.name), dgst.Encoded()))
+	if err := r.downloadBlob(ctx, *chartLayer, filename); err != nil {
+		return "", err
+	}
+
+	if version != "" {
+		chart, err := loader.Load(filename)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to load chart %s@%s", r.name, dgst)
+		}
+		if chart.Metadata.Version != version {
+			return "", errors.Errorf("chart %s@%s has version %s, not %s", r.name, dgst, chart.Metadata.Version, version)
+		}
+	}
+
+	return filename, nil
+}
+
+// chartLayerOf returns the chart layer of the manifest, or nil if it has none.
+func chartLayerOf(manifest *ocispec.Manifest) *ocispec.Descriptor {
+	var chartLayer *ocispec.Descriptor
+	for i, layer := range manifest.Layers {
+		if layer.MediaType == registry.ChartLayerMediaType || layer.MediaType == registry.LegacyChartLayerMediaType {
+			return &manifest.Layers[i]
+		}
+		if chartLayer == nil && strings.HasSuffix(layer.Annotations[ocispec.AnnotationTitle], ".tgz") {
+			chartLayer = &manifest.Layers[i]
+		}
+	}
+
+	return chartLayer
+}
+
+// GetChartSBOMs returns the SBOMs attached to the OCI chart of the spec, either as layers of the chart manifest or as
+// referrers of it. Charts from other repositories have no SBOMs, and neither do charts without an exact version or digest,
+// whose tag is only known once they are pulled.
+func (c *HelmClient) GetChartSBOMs(ctx context.Context, spec addonsv1alpha1.HelmReleaseProxySpec, credentialsPath, caFilePath string) ([]addonsv1alpha1.SBOMReference, error) {
+	if !registry.IsOCI(spec.RepoURL) || !isChartPinned(spec.Version, spec.Digest) {
+		return nil, nil
+	}
+
+	key := chartCacheKey(spec.RepoURL, spec.ChartName, pinnedChartVersion(spec.Version, spec.Digest))
+	if sboms, ok := sbomCache.Get(key); ok {
+		return sboms.([]addonsv1alpha1.SBOMReference), nil
+	}
+
+	repo, err := newOCIRepository(spec, credentialsPath, caFilePath, RepositoryAuth{})
+	if err != nil {
+		return nil, err
+	}
+
+	sboms, err := repo.sboms(ctx, ociReference(spec.Version, spec.Digest))
+	if err != nil {
+		return nil, err
+	}
+
+	sbomCache.Add(key, sboms, ociArtifactsCacheTTL)
+
+	return sboms, nil
+}
+
+// ResolveChartDigest returns the digest of the manifest of the OCI chart of the spec, i.e. the digest it is pinned to or the
+// digest the tag of its version resolves to. Charts from other repositories and charts without an exact version or digest
+// have no digest, and an empty digest is returned.
+func (c *HelmClient) ResolveChartDigest(ctx context.Context, spec addonsv1alpha1.HelmReleaseProxySpec, credentialsPath, caFilePath string) (string, error) {
+	if spec.Digest != "" {
+		return spec.Digest, nil
+	}
+	if !registry.IsOCI(spec.RepoURL) || !isChartPinned(spec.Version, spec.Digest) {
+		return "", nil
+	}
+
+	key := chartCacheKey(spec.RepoURL, spec.ChartName, spec.Version)
+	if dgst, ok := digestCache.Get(key); ok {
+		return dgst.(string), nil
+	}
+
+	repo, err := newOCIRepository(spec, credentialsPath, caFilePath, RepositoryAuth{})
+	if err != nil {
+		return "", err
+	}
+
+	_, manifestDigest, err := repo.manifest(ctx, ociTag(spec.Version))
+	if err != nil {
+		return "", err
+	}
+
+	digestCache.Add(key, manifestDigest.String(), ociArtifactsCacheTTL)
+
+	return manifestDigest.String(), nil
+}
+
+// sboms returns the SBOMs attached to the manifest of the reference.
+func (r *ociRepository) sboms(ctx context.Context, reference string) ([]addonsv1alpha1.SBOMReference, error) {
+	manifest, dgst, err := r.manifest(ctx, reference)
+	if err != nil {
+		return nil, err
+	}
+
+	sboms := []addonsv1alpha1.SBOMReference{}
+	for _, layer := range manifest.Layers {
+		if _, ok := sbomMediaTypes[layer.MediaType]; ok {
+			sboms = append(sboms, r.sbomReference(layer.MediaType, layer.Digest))
+		}
+	}
+
+	referrers, err := r.referrers(ctx, dgst)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get referrers of chart %s:%s", r.name, reference)
+	}
+	for _, referrer := range referrers {
+		if _, ok := sbomMediaTypes[referrer.ArtifactType]; ok {
+			sboms = append(sboms, r.sbomReference(referrer.ArtifactType, referrer.Digest))
+		}
+	}
+
+	return sboms, nil
+}
+
+// sbomReference returns the reference of the SBOM with the media type and digest in the repository.
+func (r *ociRepository) sbomReference(mediaType string, dgst digest.Digest) addonsv1alpha1.SBOMReference {
+	return addonsv1alpha1.SBOMReference{
+		MediaType: mediaType,
+		Digest:    dgst.String(),
+		Location:  fmt.Sprintf("%s/%s@%s", r.host, r.name, dgst),
+	}
+}
+
+// GetChartSBOM returns the content of an SBOM attached to the OCI chart of the spec, as returned by GetChartSBOMs. It fails
+// without reading more than maxSize bytes if the SBOM is larger.
+func (c *HelmClient) GetChartSBOM(ctx context.Context, spec addonsv1alpha1.HelmReleaseProxySpec, credentialsPath, caFilePath string, sbom addonsv1alpha1.SBOMReference, maxSize int64) ([]byte, error) {
+	repo, err := newOCIRepository(spec, credentialsPath, caFilePath, RepositoryAuth{})
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.sbomContent(ctx, sbom, maxSize)
+}
+
+// sbomContent returns the content of the SBOM. The digest of an SBOM attached as a referrer is the digest of the referrer
+// manifest, whose layer of the SBOM media type holds the SBOM itself. SBOMs larger than maxSize bytes are not read.
+func (r *ociRepository) sbomContent(ctx context.Context, sbom addonsv1alpha1.SBOMReference, maxSize int64) ([]byte, error) {
+	dgst, err := digest.Parse(sbom.Digest)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid digest of SBOM %s", sbom.Location)
+	}
+
+	manifest := &ocispec.Manifest{}
+	_, found, err := r.getJSON(ctx, "manifests/"+dgst.String(), manifest, ocispec.MediaTypeImageManifest)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		var sbomLayer *ocispec.Descriptor
+		for i, layer := range manifest.Layers {
+			if layer.MediaType == sbom.MediaType {
+				sbomLayer = &manifest.Layers[i]
+				break
+			}
+		}
+		// Referrers pushed with a generic layer media type hold the SBOM as their only layer.
+		if sbomLayer == nil && len(manifest.Layers) == 1 {
+			sbomLayer = &manifest.Layers[0]
+		}
+		if sbomLayer == nil {
+			return nil, errors.Errorf("referrer %s does not contain a layer with mediatype %s", sbom.Location, sbom.MediaType)
+		}
+		if sbomLayer.Size > maxSize {
+			return nil, errors.Errorf("SBOM %s exceeds %d bytes", sbom.Location, maxSize)
+		}
+
+		var content bytes.Buffer
+		if err := r.fetchBlob(ctx, *sbomLayer, &content); err != nil {
+			return nil, errors.Wrapf(err, "failed to fetch SBOM %s", sbom.Location)
+		}
+
+		return content.Bytes(), nil
+	}
+
+	// SBOMs attached as a layer of the chart manifest are referenced by the digest of the layer, whose size is not known.
+	var content bytes.Buffer
+	if _, err := r.fetchBlobLimited(ctx, dgst, maxSize, &content); err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch SBOM %s", sbom.Location)
+	}
+
+	return content.Bytes(), nil
+}
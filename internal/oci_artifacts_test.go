@@ -0,0 +1,293 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+)
+
+// newTestRegistry returns a registry serving a chart pushed as a custom ORAS artifact, with an SBOM layer and an SBOM
+// attached as a referrer. If referrersAPI is false, the referrers are only served with the referrers tag schema.
+func newTestRegistry(t *testing.T, referrersAPI bool) (*httptest.Server, []byte, digest.Digest) {
+	t.Helper()
+
+	chartArchive := []byte("chart archive")
+	sbom := []byte(`{"spdxVersion":"SPDX-2.3"}`)
+	manifest, err := json.Marshal(ocispec.Manifest{
+		MediaType:    ocispec.MediaTypeImageManifest,
+		ArtifactType: "application/vnd.example.chart",
+		Config:       ocispec.DescriptorEmptyJSON,
+		Layers: []ocispec.Descriptor{
+			{MediaType: "application/spdx+json", Digest: digest.FromBytes(sbom), Size: int64(len(sbom))},
+			{MediaType: "application/vnd.oci.image.layer.v1.tar+gzip", Digest: digest.FromBytes(chartArchive), Size: int64(len(chartArchive)), Annotations: map[string]string{ocispec.AnnotationTitle: "test-chart-1.0.0.tgz"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifestDigest := digest.FromBytes(manifest)
+	referrerSBOM := []byte(`{"bomFormat":"CycloneDX"}`)
+	referrer, err := json.Marshal(ocispec.Manifest{
+		MediaType:    ocispec.MediaTypeImageManifest,
+		ArtifactType: "application/vnd.cyclonedx+json",
+		Config:       ocispec.DescriptorEmptyJSON,
+		Layers: []ocispec.Descriptor{
+			{MediaType: "application/vnd.cyclonedx+json", Digest: digest.FromBytes(referrerSBOM), Size: int64(len(referrerSBOM))},
+		},
+		Subject: &ocispec.Descriptor{MediaType: ocispec.MediaTypeImageManifest, Digest: manifestDigest, Size: int64(len(manifest))},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	referrerDigest := digest.FromBytes(referrer)
+	referrers, err := json.Marshal(ocispec.Index{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: []ocispec.Descriptor{
+			{MediaType: ocispec.MediaTypeImageManifest, ArtifactType: "application/vnd.cyclonedx+json", Digest: referrerDigest},
+			{MediaType: ocispec.MediaTypeImageManifest, ArtifactType: "application/vnd.dev.cosign.artifact.sig.v1+json", Digest: digest.FromString("signature")},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	for _, reference := range []string{"1.0.0", manifestDigest.String()} {
+		mux.HandleFunc("/v2/charts/test-chart/manifests/"+reference, func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write(manifest)
+		})
+	}
+	for _, blob := range [][]byte{chartArchive, sbom, referrerSBOM} {
+		mux.HandleFunc("/v2/charts/test-chart/blobs/"+digest.FromBytes(blob).String(), func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write(blob)
+		})
+	}
+	mux.HandleFunc("/v2/charts/test-chart/manifests/"+referrerDigest.String(), func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(referrer)
+	})
+	referrersPath := "/v2/charts/test-chart/referrers/" + manifestDigest.String()
+	if !referrersAPI {
+		referrersPath = "/v2/charts/test-chart/manifests/sha256-" + manifestDigest.Encoded()
+	}
+	mux.HandleFunc(referrersPath, func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(referrers)
+	})
+
+	return httptest.NewTLSServer(mux), chartArchive, referrerDigest
+}
+
+func TestOCIRepositorySBOMs(t *testing.T) {
+	for _, referrersAPI := range []bool{true, false} {
+		t.Run("referrers API "+map[bool]string{true: "supported", false: "not supported"}[referrersAPI], func(t *testing.T) {
+			g := NewWithT(t)
+
+			server, _, referrerDigest := newTestRegistry(t, referrersAPI)
+			defer server.Close()
+
+			creds, err := registryCredentials("")
+			g.Expect(err).NotTo(HaveOccurred())
+			repo := newOCIRepositoryWithClient(strings.TrimPrefix(server.URL, "https://"), "charts/test-chart", server.Client(), creds)
+
+			host := strings.TrimPrefix(server.URL, "https://")
+			layerDigest := digest.FromString(`{"spdxVersion":"SPDX-2.3"}`).String()
+
+			sboms, err := repo.sboms(context.TODO(), "1.0.0")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(sboms).To(Equal([]addonsv1alpha1.SBOMReference{
+				{MediaType: "application/spdx+json", Digest: layerDigest, Location: host + "/charts/test-chart@" + layerDigest},
+				{MediaType: "application/vnd.cyclonedx+json", Digest: referrerDigest.String(), Location: host + "/charts/test-chart@" + referrerDigest.String()},
+			}))
+
+			content, err := repo.sbomContent(context.TODO(), sboms[0], 1024)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(string(content)).To(Equal(`{"spdxVersion":"SPDX-2.3"}`))
+
+			content, err = repo.sbomContent(context.TODO(), sboms[1], 1024)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(string(content)).To(Equal(`{"bomFormat":"CycloneDX"}`))
+
+			// SBOMs larger than the maximum size are not read.
+			_, err = repo.sbomContent(context.TODO(), sboms[0], 10)
+			g.Expect(err).To(MatchError(ContainSubstring("exceeds 10 bytes")))
+			_, err = repo.sbomContent(context.TODO(), sboms[1], 10)
+			g.Expect(err).To(MatchError(ContainSubstring("exceeds 10 bytes")))
+		})
+	}
+}
+
+func TestOCIRepositoryPullChartLayer(t *testing.T) {
+	g := NewWithT(t)
+
+	server, chartArchive, _ := newTestRegistry(t, true)
+	defer server.Close()
+
+	creds, err := registryCredentials("")
+	g.Expect(err).NotTo(HaveOccurred())
+	repo := newOCIRepositoryWithClient(strings.TrimPrefix(server.URL, "https://"), "charts/test-chart", server.Client(), creds)
+
+	dir := t.TempDir()
+	path, err := repo.pullChartLayer(context.TODO(), "1.0.0", dir)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(path).To(HaveSuffix("test-chart-1.0.0.tgz"))
+
+	content, err := os.ReadFile(path)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(content).To(Equal(chartArchive))
+
+	// Pulling the chart again replaces the chart archive, and the temporary file it is streamed to is not left behind.
+	_, err = repo.pullChartLayer(context.TODO(), "1.0.0", dir)
+	g.Expect(err).NotTo(HaveOccurred())
+	entries, err := os.ReadDir(dir)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(entries).To(HaveLen(1))
+	g.Expect(entries[0].Name()).To(Equal("test-chart-1.0.0.tgz"))
+}
+
+func TestOCIRepositoryPullChartLayerByDigest(t *testing.T) {
+	g := NewWithT(t)
+
+	server, chartArchive, _ := newTestRegistry(t, true)
+	defer server.Close()
+
+	creds, err := registryCredentials("")
+	g.Expect(err).NotTo(HaveOccurred())
+	repo := newOCIRepositoryWithClient(strings.TrimPrefix(server.URL, "https://"), "charts/test-chart", server.Client(), creds)
+
+	_, manifestDigest, err := repo.manifest(context.TODO(), "1.0.0")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	dir := t.TempDir()
+	path, err := repo.pullChartLayerByDigest(context.TODO(), manifestDigest.String(), "", dir)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(path).To(HaveSuffix("test-chart-" + manifestDigest.Encoded() + ".tgz"))
+
+	content, err := os.ReadFile(path)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(content).To(Equal(chartArchive))
+
+	// A digest the registry does not serve a manifest for is not pulled by the tag of the version instead.
+	_, err = repo.pullChartLayerByDigest(context.TODO(), digest.FromString("other").String(), "", dir)
+	g.Expect(err).To(HaveOccurred())
+
+	_, err = repo.pullChartLayerByDigest(context.TODO(), "sha256:abc", "", dir)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestOCIRepositoryDownloadBlobFailure(t *testing.T) {
+	g := NewWithT(t)
+
+	server, _, _ := newTestRegistry(t, true)
+	defer server.Close()
+
+	creds, err := registryCredentials("")
+	g.Expect(err).NotTo(HaveOccurred())
+	repo := newOCIRepositoryWithClient(strings.TrimPrefix(server.URL, "https://"), "charts/test-chart", server.Client(), creds)
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "test-chart-1.0.0.tgz")
+	chartArchive := []byte("chart archive")
+	err = repo.downloadBlob(context.TODO(), ocispec.Descriptor{Digest: digest.FromBytes(chartArchive), Size: int64(len(chartArchive))}, filename)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// A failed download neither replaces the file nor leaves the temporary file behind.
+	err = repo.downloadBlob(context.TODO(), ocispec.Descriptor{Digest: digest.FromString("missing"), Size: 7}, filename)
+	g.Expect(err).To(HaveOccurred())
+	err = repo.downloadBlob(context.TODO(), ocispec.Descriptor{Digest: digest.FromBytes(chartArchive), Size: 5}, filename)
+	g.Expect(err).To(MatchError(ContainSubstring("exceeds 5 bytes")))
+	content, err := os.ReadFile(filename)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(content).To(Equal([]byte("chart archive")))
+	entries, err := os.ReadDir(dir)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(entries).To(HaveLen(1))
+}
+
+func TestOCIRepositoryLimits(t *testing.T) {
+	g := NewWithT(t)
+
+	blob := []byte("chart archive")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/charts/test-chart/manifests/large", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"layers":[`))
+		_, _ = w.Write([]byte(strings.Repeat(" ", maxManifestSize)))
+		_, _ = w.Write([]byte(`]}`))
+	})
+	mux.HandleFunc("/v2/charts/test-chart/blobs/"+digest.FromBytes(blob).String(), func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(blob)
+	})
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+
+	creds, err := registryCredentials("")
+	g.Expect(err).NotTo(HaveOccurred())
+	repo := newOCIRepositoryWithClient(strings.TrimPrefix(server.URL, "https://"), "charts/test-chart", server.Client(), creds)
+
+	_, _, err = repo.manifest(context.TODO(), "large")
+	g.Expect(err).To(MatchError(ContainSubstring("exceeds")))
+
+	// The blob must have the size of its descriptor.
+	var content bytes.Buffer
+	err = repo.fetchBlob(context.TODO(), ocispec.Descriptor{Digest: digest.FromBytes(blob), Size: int64(len(blob)) + 1}, &content)
+	g.Expect(err).To(MatchError(ContainSubstring("has 13 bytes, not 14")))
+
+	content.Reset()
+	n, err := repo.fetchBlobLimited(context.TODO(), digest.FromBytes(blob), int64(len(blob)), &content)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(n).To(Equal(int64(len(blob))))
+	g.Expect(content.Bytes()).To(Equal(blob))
+}
+
+func TestOCIRepositoryPlainHTTP(t *testing.T) {
+	g := NewWithT(t)
+
+	tlsServer, chartArchive, _ := newTestRegistry(t, true)
+	defer tlsServer.Close()
+	// The handler of the TLS registry is served over plain HTTP.
+	server := httptest.NewServer(tlsServer.Config.Handler)
+	defer server.Close()
+
+	spec := addonsv1alpha1.HelmReleaseProxySpec{
+		RepoURL:   "oci://" + strings.TrimPrefix(server.URL, "http://") + "/charts",
+		ChartName: "test-chart",
+	}
+	repo, err := newOCIRepository(spec, "", "", RepositoryAuth{PlainHTTP: true})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	path, err := repo.pullChartLayer(context.TODO(), "1.0.0", t.TempDir())
+	g.Expect(err).NotTo(HaveOccurred())
+	content, err := os.ReadFile(path)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(content).To(Equal(chartArchive))
+
+	repo, err = newOCIRepository(spec, "", "", RepositoryAuth{})
+	g.Expect(err).NotTo(HaveOccurred())
+	_, err = repo.pullChartLayer(context.TODO(), "1.0.0", t.TempDir())
+	g.Expect(err).To(HaveOccurred(), "the registry is not reached over HTTPS")
+}
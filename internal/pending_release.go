@@ -0,0 +1,170 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	helmAction "helm.sh/helm/v3/pkg/action"
+	helmRelease "helm.sh/helm/v3/pkg/release"
+	helmDriver "helm.sh/helm/v3/pkg/storage/driver"
+	"k8s.io/client-go/rest"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// AuditOperationPendingRecovery is the operation of an audit record of the deletion of a revision of a Helm release that
+	// was stuck in a pending state.
+	AuditOperationPendingRecovery = "pending-recovery"
+
+	// defaultHelmTimeout is the Timeout of the Helm operations if Options.Timeout is not set.
+	defaultHelmTimeout = 10 * time.Minute
+
+	// pendingReleaseGracePeriod is added to the Timeout of the Helm operations to get how long a Helm release must have
+	// been pending before it is considered stuck, if PendingRecovery.StuckAfter is not set.
+	pendingReleaseGracePeriod = time.Minute
+)
+
+// PendingReleaseError is returned when the Helm release is in a pending state, so that it can neither be installed nor
+// upgraded until the operation in progress completes or the Helm release is recovered.
+type PendingReleaseError struct {
+	// Status is the pending status of the Helm release.
+	Status helmRelease.Status
+
+	// Revision is the pending revision of the Helm release.
+	Revision int
+
+	// Stuck is true if the Helm release has been pending for longer than any operation can take, but is not recovered
+	// because PendingRecovery is not set.
+	Stuck bool
+}
+
+func (e *PendingReleaseError) Error() string {
+	if e.Stuck {
+		return fmt.Sprintf("release is stuck in %s at revision %d, set Options.PendingRecovery to recover it", e.Status, e.Revision)
+	}
+
+	return fmt.Sprintf("release is in %s at revision %d, waiting for the operation in progress to complete", e.Status, e.Revision)
+}
+
+// pendingReleaseStuckAfter returns how long a Helm release must have been pending before it is considered stuck.
+func pendingReleaseStuckAfter(spec addonsv1alpha1.HelmReleaseProxySpec) time.Duration {
+	if recovery := spec.Options.PendingRecovery; recovery != nil && recovery.StuckAfter != nil {
+		return recovery.StuckAfter.Duration
+	}
+
+	timeout := defaultHelmTimeout
+	if spec.Options.Timeout != nil {
+		timeout = spec.Options.Timeout.Duration
+	}
+
+	return timeout + pendingReleaseGracePeriod
+}
+
+// isPendingReleaseStuck returns true if the pending Helm release has been pending for longer than it takes to be stuck.
+func isPendingReleaseStuck(spec addonsv1alpha1.HelmReleaseProxySpec, release *helmRelease.Release, now time.Time) bool {
+	return now.Sub(release.Info.LastDeployed.Time) > pendingReleaseStuckAfter(spec)
+}
+
+// pendingRecoveryRevision returns the revision to roll a stuck Helm release back to, i.e. the latest revision before the
+// stuck one that was deployed, or 0 if there is none.
+func pendingRecoveryRevision(history []*helmRelease.Release, stuck *helmRelease.Release) int {
+	revision := 0
+	for _, release := range history {
+		if release.Version >= stuck.Version || release.Version <= revision || release.Info == nil {
+			continue
+		}
+		if release.Info.Status == helmRelease.StatusDeployed || release.Info.Status == helmRelease.StatusSuperseded {
+			revision = release.Version
+		}
+	}
+
+	return revision
+}
+
+// recoverPendingRelease recovers the existing Helm release from a pending state according to Options.PendingRecovery
+// once it is stuck, and returns the Helm release after the recovery. A PendingReleaseError is returned if the Helm release
+// is still pending.
+func (c *HelmClient) recoverPendingRelease(ctx context.Context, restConfig *rest.Config, spec addonsv1alpha1.HelmReleaseProxySpec, existing *helmRelease.Release, now time.Time) (*helmRelease.Release, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	stuck := isPendingReleaseStuck(spec, existing, now)
+	recovery := spec.Options.PendingRecovery
+	if !stuck || recovery == nil {
+		return nil, &PendingReleaseError{Status: existing.Info.Status, Revision: existing.Version, Stuck: stuck}
+	}
+
+	_, actionConfig, err := HelmInit(ctx, spec.ReleaseNamespace, restConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if recovery.Policy != string(addonsv1alpha1.PendingRecoveryPolicyDelete) {
+		history, err := actionConfig.Releases.History(spec.ReleaseName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get history of release %s", spec.ReleaseName)
+		}
+
+		if revision := pendingRecoveryRevision(history, existing); revision > 0 {
+			log.Info("Rolling back release stuck in a pending state", "release", spec.ReleaseName, "status", existing.Info.Status, "stuckRevision", existing.Version, "revision", revision)
+			release, err := c.RollbackHelmRelease(ctx, restConfig, spec, revision)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to roll back release %s stuck in %s to revision %d", spec.ReleaseName, existing.Info.Status, revision)
+			}
+
+			return release, nil
+		}
+	}
+
+	log.Info("Deleting revision of release stuck in a pending state", "release", spec.ReleaseName, "status", existing.Info.Status, "stuckRevision", existing.Version)
+	err = deletePendingRevision(actionConfig, existing)
+	c.AuditLog.record(ctx, AuditOperationPendingRecovery, spec, existing, err)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to delete revision %d of release %s stuck in %s", existing.Version, spec.ReleaseName, existing.Info.Status)
+	}
+
+	return actionConfig.Releases.Last(spec.ReleaseName)
+}
+
+// deletePendingRevision deletes the stuck revision of a Helm release from its history. The revision before it is marked
+// deployed again if the stuck upgrade or rollback superseded it, so that the Helm release is back in the state it was in
+// before the stuck operation.
+func deletePendingRevision(actionConfig *helmAction.Configuration, stuck *helmRelease.Release) error {
+	if _, err := actionConfig.Releases.Delete(stuck.Name, stuck.Version); err != nil {
+		return err
+	}
+
+	previous, err := actionConfig.Releases.Get(stuck.Name, stuck.Version-1)
+	if err != nil {
+		// There is no revision before a stuck install.
+		if errors.Is(err, helmDriver.ErrReleaseNotFound) {
+			return nil
+		}
+
+		return err
+	}
+	if previous.Info == nil || previous.Info.Status != helmRelease.StatusSuperseded {
+		return nil
+	}
+	previous.Info.Status = helmRelease.StatusDeployed
+
+	return actionConfig.Releases.Update(previous)
+}
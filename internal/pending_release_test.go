@@ -0,0 +1,164 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	helmAction "helm.sh/helm/v3/pkg/action"
+	helmRelease "helm.sh/helm/v3/pkg/release"
+	helmStorage "helm.sh/helm/v3/pkg/storage"
+	helmDriver "helm.sh/helm/v3/pkg/storage/driver"
+	helmTime "helm.sh/helm/v3/pkg/time"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+)
+
+func TestIsPendingReleaseStuck(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	release := func(pendingFor time.Duration) *helmRelease.Release {
+		return &helmRelease.Release{Version: 2, Info: &helmRelease.Info{Status: helmRelease.StatusPendingUpgrade, LastDeployed: helmTime.Time{Time: now.Add(-pendingFor)}}}
+	}
+
+	testcases := []struct {
+		name     string
+		options  addonsv1alpha1.HelmOptions
+		release  *helmRelease.Release
+		expected bool
+	}{
+		{
+			name:     "release pending for less than the default timeout is not stuck",
+			release:  release(5 * time.Minute),
+			expected: false,
+		},
+		{
+			name:     "release pending for longer than the default timeout and the grace period is stuck",
+			release:  release(12 * time.Minute),
+			expected: true,
+		},
+		{
+			name:     "release pending for longer than the timeout and the grace period is stuck",
+			options:  addonsv1alpha1.HelmOptions{Timeout: &metav1.Duration{Duration: 2 * time.Minute}},
+			release:  release(5 * time.Minute),
+			expected: true,
+		},
+		{
+			name: "stuckAfter takes precedence over the timeout",
+			options: addonsv1alpha1.HelmOptions{
+				Timeout:         &metav1.Duration{Duration: 2 * time.Minute},
+				PendingRecovery: &addonsv1alpha1.HelmPendingRecoveryOptions{StuckAfter: &metav1.Duration{Duration: time.Hour}},
+			},
+			release:  release(5 * time.Minute),
+			expected: false,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			spec := addonsv1alpha1.HelmReleaseProxySpec{Options: tc.options}
+			g.Expect(isPendingReleaseStuck(spec, tc.release, now)).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestPendingRecoveryRevision(t *testing.T) {
+	release := func(version int, status helmRelease.Status) *helmRelease.Release {
+		return &helmRelease.Release{Version: version, Info: &helmRelease.Info{Status: status}}
+	}
+
+	testcases := []struct {
+		name     string
+		history  []*helmRelease.Release
+		stuck    *helmRelease.Release
+		expected int
+	}{
+		{
+			name:     "stuck upgrade is rolled back to the superseded revision",
+			history:  []*helmRelease.Release{release(1, helmRelease.StatusSuperseded), release(2, helmRelease.StatusSuperseded), release(3, helmRelease.StatusPendingUpgrade)},
+			stuck:    release(3, helmRelease.StatusPendingUpgrade),
+			expected: 2,
+		},
+		{
+			name:     "failed revisions are skipped",
+			history:  []*helmRelease.Release{release(1, helmRelease.StatusSuperseded), release(2, helmRelease.StatusFailed), release(3, helmRelease.StatusPendingUpgrade)},
+			stuck:    release(3, helmRelease.StatusPendingUpgrade),
+			expected: 1,
+		},
+		{
+			name:    "stuck install has no revision to roll back to",
+			history: []*helmRelease.Release{release(1, helmRelease.StatusPendingInstall)},
+			stuck:   release(1, helmRelease.StatusPendingInstall),
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			g.Expect(pendingRecoveryRevision(tc.history, tc.stuck)).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestDeletePendingRevision(t *testing.T) {
+	release := func(version int, status helmRelease.Status) *helmRelease.Release {
+		return &helmRelease.Release{Name: "test-release", Namespace: "default", Version: version, Info: &helmRelease.Info{Status: status}}
+	}
+
+	t.Run("stuck upgrade is deleted and the superseded revision is deployed again", func(t *testing.T) {
+		g := NewWithT(t)
+
+		actionConfig := &helmAction.Configuration{Releases: helmStorage.Init(helmDriver.NewMemory())}
+		g.Expect(actionConfig.Releases.Create(release(1, helmRelease.StatusSuperseded))).To(Succeed())
+		stuck := release(2, helmRelease.StatusPendingUpgrade)
+		g.Expect(actionConfig.Releases.Create(stuck)).To(Succeed())
+
+		g.Expect(deletePendingRevision(actionConfig, stuck)).To(Succeed())
+
+		last, err := actionConfig.Releases.Last("test-release")
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(last.Version).To(Equal(1))
+		g.Expect(last.Info.Status).To(Equal(helmRelease.StatusDeployed))
+	})
+
+	t.Run("stuck install is deleted", func(t *testing.T) {
+		g := NewWithT(t)
+
+		actionConfig := &helmAction.Configuration{Releases: helmStorage.Init(helmDriver.NewMemory())}
+		stuck := release(1, helmRelease.StatusPendingInstall)
+		g.Expect(actionConfig.Releases.Create(stuck)).To(Succeed())
+
+		g.Expect(deletePendingRevision(actionConfig, stuck)).To(Succeed())
+
+		_, err := actionConfig.Releases.Last("test-release")
+		g.Expect(err).To(MatchError(helmDriver.ErrReleaseNotFound))
+	})
+}
+
+func TestPendingReleaseError(t *testing.T) {
+	g := NewWithT(t)
+
+	err := &PendingReleaseError{Status: helmRelease.StatusPendingInstall, Revision: 1}
+	g.Expect(err.Error()).To(Equal("release is in pending-install at revision 1, waiting for the operation in progress to complete"))
+
+	err.Stuck = true
+	g.Expect(err.Error()).To(Equal("release is stuck in pending-install at revision 1, set Options.PendingRecovery to recover it"))
+}
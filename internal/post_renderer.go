@@ -0,0 +1,149 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"maps"
+
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/postrender"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/kustomize/api/konfig"
+	"sigs.k8s.io/kustomize/api/krusty"
+	kustomizetypes "sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/kustomize/kyaml/resid"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// postRenderedManifestsFile is the file the rendered manifests are written to in the in-memory Kustomization.
+	postRenderedManifestsFile = "manifests.yaml"
+
+	// postRendererHashLength is the length of the hash of a PostRenderer in the PostRendererHashLabelName label.
+	postRendererHashLength = 16
+)
+
+// kustomizePostRenderer is a Helm post-renderer applying the Kustomize patches of a PostRenderer to the rendered
+// manifests.
+type kustomizePostRenderer struct {
+	patches []addonsv1alpha1.KustomizePatch
+}
+
+var _ postrender.PostRenderer = &kustomizePostRenderer{}
+
+// newPostRenderer returns the Helm post-renderer of the PostRenderer, or nil if it has no patches.
+func newPostRenderer(postRenderer *addonsv1alpha1.PostRenderer) postrender.PostRenderer {
+	if postRenderer == nil || len(postRenderer.Patches) == 0 {
+		return nil
+	}
+
+	return &kustomizePostRenderer{patches: postRenderer.Patches}
+}
+
+// Run applies the patches to the rendered manifests with an in-memory Kustomization.
+func (r *kustomizePostRenderer) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	kustomization := kustomizetypes.Kustomization{
+		TypeMeta: kustomizetypes.TypeMeta{
+			APIVersion: kustomizetypes.KustomizationVersion,
+			Kind:       kustomizetypes.KustomizationKind,
+		},
+		Resources: []string{postRenderedManifestsFile},
+	}
+	for _, patch := range r.patches {
+		kustomization.Patches = append(kustomization.Patches, kustomizetypes.Patch{
+			Patch:  patch.Patch,
+			Target: patchTarget(patch.Target),
+		})
+	}
+	data, err := yaml.Marshal(kustomization)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal Kustomization of post-renderer")
+	}
+
+	fs := filesys.MakeFsInMemory()
+	if err := fs.WriteFile(postRenderedManifestsFile, renderedManifests.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := fs.WriteFile(konfig.DefaultKustomizationFileName(), data); err != nil {
+		return nil, err
+	}
+
+	resources, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(fs, filesys.SelfDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to apply post-renderer patches")
+	}
+	manifests, err := resources.AsYaml()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal post-rendered manifests")
+	}
+
+	return bytes.NewBuffer(manifests), nil
+}
+
+// patchTarget returns the Kustomize selector of the PatchSelector.
+func patchTarget(target *addonsv1alpha1.PatchSelector) *kustomizetypes.Selector {
+	if target == nil {
+		return nil
+	}
+
+	return &kustomizetypes.Selector{
+		ResId: resid.ResId{
+			Gvk:       resid.Gvk{Group: target.Group, Version: target.Version, Kind: target.Kind},
+			Name:      target.Name,
+			Namespace: target.Namespace,
+		},
+		LabelSelector:      target.LabelSelector,
+		AnnotationSelector: target.AnnotationSelector,
+	}
+}
+
+// postRendererHash returns a hash of the PostRenderer, or an empty string if it has no patches.
+func postRendererHash(postRenderer *addonsv1alpha1.PostRenderer) (string, error) {
+	if postRenderer == nil || len(postRenderer.Patches) == 0 {
+		return "", nil
+	}
+
+	data, err := json.Marshal(postRenderer)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal post-renderer")
+	}
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])[:postRendererHashLength], nil
+}
+
+// releaseLabels returns the labels of the Helm release of the spec, i.e. its ReleaseLabels and the hash of its
+// PostRenderer, so that the release is upgraded when either changes.
+func releaseLabels(spec addonsv1alpha1.HelmReleaseProxySpec) (map[string]string, error) {
+	hash, err := postRendererHash(spec.PostRenderer)
+	if err != nil || hash == "" {
+		return spec.ReleaseLabels, err
+	}
+
+	labels := maps.Clone(spec.ReleaseLabels)
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[addonsv1alpha1.PostRendererHashLabelName] = hash
+
+	return labels, nil
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+const postRendererManifests = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: controller
+  namespace: ingress
+  labels:
+    app: ingress
+spec:
+  template:
+    spec:
+      containers:
+      - name: controller
+        image: controller:v1
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: webhook
+  namespace: ingress
+spec:
+  template:
+    spec:
+      containers:
+      - name: webhook
+        image: webhook:v1
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: controller
+  namespace: ingress
+spec:
+  ports:
+  - port: 80
+`
+
+func TestKustomizePostRenderer(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(newPostRenderer(nil)).To(BeNil())
+	g.Expect(newPostRenderer(&addonsv1alpha1.PostRenderer{})).To(BeNil())
+
+	postRenderer := newPostRenderer(&addonsv1alpha1.PostRenderer{
+		Patches: []addonsv1alpha1.KustomizePatch{
+			{
+				// A strategic merge patch of all Deployments.
+				Patch:  "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: any\nspec:\n  template:\n    spec:\n      nodeSelector:\n        role: infra\n",
+				Target: &addonsv1alpha1.PatchSelector{Kind: "Deployment"},
+			},
+			{
+				// A strategic merge patch of the resource it names.
+				Patch: "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: webhook\n  namespace: ingress\nspec:\n  replicas: 2\n",
+			},
+			{
+				// A JSON 6902 patch of the resources matching the label selector.
+				Patch:  "- op: add\n  path: /spec/template/spec/tolerations\n  value:\n  - key: infra\n    operator: Exists\n",
+				Target: &addonsv1alpha1.PatchSelector{Kind: "Deployment", LabelSelector: "app=ingress"},
+			},
+		},
+	})
+	g.Expect(postRenderer).NotTo(BeNil())
+
+	rendered, err := postRenderer.Run(bytes.NewBufferString(postRendererManifests))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	resources := map[string]map[string]interface{}{}
+	for _, document := range bytes.Split(rendered.Bytes(), []byte("\n---\n")) {
+		resource := map[string]interface{}{}
+		g.Expect(yaml.Unmarshal(document, &resource)).To(Succeed())
+		metadata := resource["metadata"].(map[string]interface{})
+		resources[resource["kind"].(string)+"/"+metadata["name"].(string)] = resource
+	}
+	g.Expect(resources).To(HaveLen(3))
+
+	podSpec := func(name string) map[string]interface{} {
+		spec := resources["Deployment/"+name]["spec"].(map[string]interface{})
+		return spec["template"].(map[string]interface{})["spec"].(map[string]interface{})
+	}
+	g.Expect(podSpec("controller")).To(HaveKeyWithValue("nodeSelector", map[string]interface{}{"role": "infra"}))
+	g.Expect(podSpec("controller")).To(HaveKey("tolerations"))
+	g.Expect(podSpec("controller")["containers"]).To(HaveLen(1))
+	g.Expect(podSpec("webhook")).To(HaveKeyWithValue("nodeSelector", map[string]interface{}{"role": "infra"}))
+	g.Expect(podSpec("webhook")).NotTo(HaveKey("tolerations"))
+	g.Expect(resources["Deployment/webhook"]["spec"]).To(HaveKeyWithValue("replicas", BeEquivalentTo(2)))
+	g.Expect(resources["Service/controller"]["spec"]).NotTo(HaveKey("template"))
+
+	_, err = newPostRenderer(&addonsv1alpha1.PostRenderer{
+		Patches: []addonsv1alpha1.KustomizePatch{{
+			Patch:  "- op: replace\n  path: /spec/missing/field\n  value: 1\n",
+			Target: &addonsv1alpha1.PatchSelector{Kind: "Service"},
+		}},
+	}).Run(bytes.NewBufferString(postRendererManifests))
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestReleaseLabels(t *testing.T) {
+	g := NewWithT(t)
+
+	spec := addonsv1alpha1.HelmReleaseProxySpec{ReleaseLabels: map[string]string{"team": "platform"}}
+	labels, err := releaseLabels(spec)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(labels).To(Equal(map[string]string{"team": "platform"}))
+
+	spec.PostRenderer = &addonsv1alpha1.PostRenderer{
+		Patches: []addonsv1alpha1.KustomizePatch{{Patch: "spec:\n  replicas: 2\n", Target: &addonsv1alpha1.PatchSelector{Kind: "Deployment"}}},
+	}
+	labels, err = releaseLabels(spec)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(labels).To(HaveKeyWithValue("team", "platform"))
+	g.Expect(labels).To(HaveKeyWithValue(addonsv1alpha1.PostRendererHashLabelName, HaveLen(postRendererHashLength)))
+	g.Expect(spec.ReleaseLabels).NotTo(HaveKey(addonsv1alpha1.PostRendererHashLabelName))
+
+	// A changed PostRenderer changes the hash, so that the release is upgraded.
+	hash := labels[addonsv1alpha1.PostRendererHashLabelName]
+	spec.PostRenderer.Patches[0].Patch = "spec:\n  replicas: 3\n"
+	labels, err = releaseLabels(spec)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(labels[addonsv1alpha1.PostRendererHashLabelName]).NotTo(Equal(hash))
+
+	spec.ReleaseLabels = nil
+	labels, err = releaseLabels(spec)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(labels).To(HaveLen(1))
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// injectProxyValues injects the proxy settings and trust bundle of the Cluster into the rendered values at the paths of the
+// ProxyValues. The values are returned unchanged if the Cluster does not have the ProxySettingsLabelName label.
+func injectProxyValues(ctx context.Context, c ctrlClient.Client, proxyValues *addonsv1alpha1.ProxyValues, cluster *clusterv1.Cluster, values string) (string, error) {
+	name := cluster.Labels[addonsv1alpha1.ProxySettingsLabelName]
+	if proxyValues == nil || name == "" {
+		return values, nil
+	}
+
+	configMap := &corev1.ConfigMap{}
+	if err := c.Get(ctx, ctrlClient.ObjectKey{Namespace: cluster.Namespace, Name: name}, configMap); err != nil {
+		return "", errors.Wrapf(err, "failed to get proxy settings ConfigMap %s of cluster %s", name, cluster.Name)
+	}
+
+	parsed := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(values), &parsed); err != nil {
+		return "", errors.Wrap(err, "failed to parse values to inject proxy settings")
+	}
+	if parsed == nil {
+		parsed = map[string]interface{}{}
+	}
+
+	for _, setting := range []struct {
+		path   string
+		key    string
+		append bool
+	}{
+		{path: proxyValues.HTTPProxyPath, key: addonsv1alpha1.ProxySettingsHTTPProxyKey},
+		{path: proxyValues.HTTPSProxyPath, key: addonsv1alpha1.ProxySettingsHTTPSProxyKey},
+		{path: proxyValues.NoProxyPath, key: addonsv1alpha1.ProxySettingsNoProxyKey},
+		{path: proxyValues.TrustBundlePath, key: addonsv1alpha1.ProxySettingsTrustBundleKey, append: true},
+	} {
+		value, ok := configMap.Data[setting.key]
+		if setting.path == "" || !ok {
+			continue
+		}
+		if err := setValue(parsed, setting.path, value, setting.append); err != nil {
+			return "", errors.Wrapf(err, "failed to inject %s of proxy settings ConfigMap %s", setting.key, name)
+		}
+	}
+
+	injected, err := yaml.Marshal(parsed)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal values with proxy settings")
+	}
+
+	return string(injected), nil
+}
+
+// setValue sets the value at the path in dot notation, creating the maps along it. A value that is already set is kept,
+// unless the value is appended to it on a new line.
+func setValue(values map[string]interface{}, path, value string, appendValue bool) error {
+	keys := strings.Split(path, ".")
+	for i, key := range keys[:len(keys)-1] {
+		next, ok := values[key]
+		if !ok || next == nil {
+			next = map[string]interface{}{}
+			values[key] = next
+		}
+		nested, ok := next.(map[string]interface{})
+		if !ok {
+			return errors.Errorf("value at %s is not a map", strings.Join(keys[:i+1], "."))
+		}
+		values = nested
+	}
+
+	key := keys[len(keys)-1]
+	existing, ok := values[key]
+	switch {
+	case !ok || existing == nil || existing == "":
+		values[key] = value
+	case appendValue:
+		s, ok := existing.(string)
+		if !ok {
+			return errors.Errorf("value at %s is not a string", path)
+		}
+		values[key] = strings.TrimRight(s, "\n") + "\n" + value
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestInjectProxyValues(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+
+	proxySettings := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "corp-proxy", Namespace: "default"},
+		Data: map[string]string{
+			addonsv1alpha1.ProxySettingsHTTPProxyKey:   "http://proxy.corp:3128",
+			addonsv1alpha1.ProxySettingsNoProxyKey:     "10.0.0.0/8,.svc",
+			addonsv1alpha1.ProxySettingsTrustBundleKey: "-----BEGIN CERTIFICATE-----\ncorp\n-----END CERTIFICATE-----\n",
+		},
+	}
+	proxyValues := &addonsv1alpha1.ProxyValues{
+		HTTPProxyPath:   "global.proxy.http",
+		HTTPSProxyPath:  "global.proxy.https",
+		NoProxyPath:     "global.proxy.noProxy",
+		TrustBundlePath: "tls.caBundle",
+	}
+	clusterBehindProxy := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-cluster",
+			Namespace: "default",
+			Labels:    map[string]string{addonsv1alpha1.ProxySettingsLabelName: "corp-proxy"},
+		},
+	}
+
+	testCases := []struct {
+		name        string
+		proxyValues *addonsv1alpha1.ProxyValues
+		cluster     *clusterv1.Cluster
+		values      string
+		expected    string
+		expectErr   bool
+	}{
+		{
+			name:        "Cluster without proxy settings is left unchanged",
+			proxyValues: proxyValues,
+			cluster:     &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", Namespace: "default"}},
+			values:      "replicaCount: 2",
+			expected:    "replicaCount: 2",
+		},
+		{
+			name:     "HelmChartProxy without proxy values is left unchanged",
+			cluster:  clusterBehindProxy,
+			values:   "replicaCount: 2",
+			expected: "replicaCount: 2",
+		},
+		{
+			name:        "proxy settings are injected and the trust bundle is appended",
+			proxyValues: proxyValues,
+			cluster:     clusterBehindProxy,
+			values:      "global:\n  proxy:\n    noProxy: localhost\ntls:\n  caBundle: |\n    -----BEGIN CERTIFICATE-----\n    chart\n    -----END CERTIFICATE-----\n",
+			expected: `global:
+  proxy:
+    http: http://proxy.corp:3128
+    noProxy: localhost
+tls:
+  caBundle: |
+    -----BEGIN CERTIFICATE-----
+    chart
+    -----END CERTIFICATE-----
+    -----BEGIN CERTIFICATE-----
+    corp
+    -----END CERTIFICATE-----
+`,
+		},
+		{
+			name:        "value path through a non-map value fails",
+			proxyValues: proxyValues,
+			cluster:     clusterBehindProxy,
+			values:      "global: enabled",
+			expectErr:   true,
+		},
+		{
+			name:        "missing proxy settings ConfigMap fails",
+			proxyValues: proxyValues,
+			cluster: &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-cluster",
+				Namespace: "default",
+				Labels:    map[string]string{addonsv1alpha1.ProxySettingsLabelName: "missing"},
+			}},
+			values:    "replicaCount: 2",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(proxySettings.DeepCopy()).Build()
+
+			values, err := injectProxyValues(context.TODO(), c, tc.proxyValues, tc.cluster, tc.values)
+			if tc.expectErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(values).To(Equal(tc.expected))
+		})
+	}
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+)
+
+// lastSuccessfulReconcileResolution is the minimum interval between updates of the last successful reconcile time in the
+// status. Without it, every reconcile would patch the status and trigger another reconcile of the object.
+const lastSuccessfulReconcileResolution = time.Minute
+
+// ReconcileTracked is an object whose last successful reconcile time is recorded in its status.
+type ReconcileTracked interface {
+	conditions.Setter
+	GetLastSuccessfulReconcileTime() *metav1.Time
+	SetLastSuccessfulReconcileTime(*metav1.Time)
+}
+
+// RecordReconcileResult records the time of a reconcile of the object without error in its status and metrics, and marks
+// the ReconciledRecentlyCondition false once the last successful reconcile, or the creation of the object if it never
+// succeeded, is older than the staleness threshold. A staleness threshold of 0 disables the condition.
+func RecordReconcileResult(obj ReconcileTracked, kind string, reconcileErr error, stalenessThreshold time.Duration, now time.Time) {
+	last := obj.GetLastSuccessfulReconcileTime()
+	if reconcileErr == nil {
+		if last == nil || now.Sub(last.Time) >= lastSuccessfulReconcileResolution {
+			last = &metav1.Time{Time: now}
+			obj.SetLastSuccessfulReconcileTime(last)
+		}
+		lastSuccessfulReconcileGauge.WithLabelValues(kind, obj.GetNamespace(), obj.GetName()).Set(float64(now.Unix()))
+	}
+
+	since := obj.GetCreationTimestamp().Time
+	if last != nil {
+		since = last.Time
+	}
+
+	if stalenessThreshold <= 0 || reconcileErr == nil || now.Sub(since) < stalenessThreshold {
+		conditions.Delete(obj, addonsv1alpha1.ReconciledRecentlyCondition)
+		reconcileStaleGauge.WithLabelValues(kind, obj.GetNamespace(), obj.GetName()).Set(0)
+
+		return
+	}
+
+	conditions.MarkFalse(obj, addonsv1alpha1.ReconciledRecentlyCondition, addonsv1alpha1.ReconcileStaleReason, clusterv1.ConditionSeverityWarning,
+		"Not reconciled without error since %s, which is longer than the staleness threshold of %s", since.UTC().Format(time.RFC3339), stalenessThreshold)
+	reconcileStaleGauge.WithLabelValues(kind, obj.GetNamespace(), obj.GetName()).Set(1)
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+)
+
+func TestRecordReconcileResult(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	reconcileErr := errors.New("failed to get kubeconfig: token expired")
+
+	testCases := []struct {
+		name               string
+		last               *time.Time
+		now                time.Time
+		reconcileErr       error
+		stalenessThreshold time.Duration
+		expectedLast       *time.Time
+		expectStale        bool
+	}{
+		{
+			name:               "successful reconcile records the time",
+			now:                created.Add(time.Second),
+			stalenessThreshold: time.Hour,
+			expectedLast:       ptr.To(created.Add(time.Second)),
+		},
+		{
+			name:               "successful reconcile within the resolution keeps the time",
+			last:               ptr.To(created),
+			now:                created.Add(30 * time.Second),
+			stalenessThreshold: time.Hour,
+			expectedLast:       ptr.To(created),
+		},
+		{
+			name:               "failed reconcile within the staleness threshold is not stale",
+			last:               ptr.To(created),
+			now:                created.Add(30 * time.Minute),
+			reconcileErr:       reconcileErr,
+			stalenessThreshold: time.Hour,
+			expectedLast:       ptr.To(created),
+		},
+		{
+			name:               "failed reconcile past the staleness threshold is stale",
+			last:               ptr.To(created),
+			now:                created.Add(2 * time.Hour),
+			reconcileErr:       reconcileErr,
+			stalenessThreshold: time.Hour,
+			expectedLast:       ptr.To(created),
+			expectStale:        true,
+		},
+		{
+			name:               "never successfully reconciled object is stale past the staleness threshold after its creation",
+			now:                created.Add(2 * time.Hour),
+			reconcileErr:       reconcileErr,
+			stalenessThreshold: time.Hour,
+			expectStale:        true,
+		},
+		{
+			name:         "staleness threshold of 0 disables the condition",
+			last:         ptr.To(created),
+			now:          created.Add(2 * time.Hour),
+			reconcileErr: reconcileErr,
+			expectedLast: ptr.To(created),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			hrp := &addonsv1alpha1.HelmReleaseProxy{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "test-hrp",
+					Namespace:         "default",
+					CreationTimestamp: metav1.NewTime(created),
+				},
+			}
+			if tc.last != nil {
+				hrp.SetLastSuccessfulReconcileTime(&metav1.Time{Time: *tc.last})
+			}
+
+			RecordReconcileResult(hrp, "HelmReleaseProxy", tc.reconcileErr, tc.stalenessThreshold, tc.now)
+
+			if tc.expectedLast == nil {
+				g.Expect(hrp.GetLastSuccessfulReconcileTime()).To(BeNil())
+			} else {
+				g.Expect(hrp.GetLastSuccessfulReconcileTime().Time).To(Equal(*tc.expectedLast))
+			}
+			if tc.expectStale {
+				g.Expect(conditions.IsFalse(hrp, addonsv1alpha1.ReconciledRecentlyCondition)).To(BeTrue())
+				g.Expect(conditions.GetReason(hrp, addonsv1alpha1.ReconciledRecentlyCondition)).To(Equal(addonsv1alpha1.ReconcileStaleReason))
+			} else {
+				g.Expect(conditions.Has(hrp, addonsv1alpha1.ReconciledRecentlyCondition)).To(BeFalse())
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RegistryUnavailableError is returned instead of fetching a chart while the circuit of its registry is open, because
+// recent fetches from the registry kept failing.
+type RegistryUnavailableError struct {
+	// Host is the host of the chart repository or OCI registry.
+	Host string
+
+	// RetryAfter is the time after which a fetch from the registry is attempted again.
+	RetryAfter time.Time
+}
+
+func (e *RegistryUnavailableError) Error() string {
+	return fmt.Sprintf("registry %s is unavailable after repeated failures, not fetching charts from it until %s", e.Host, e.RetryAfter.UTC().Format(time.RFC3339))
+}
+
+// registryOutageErrors are the substrings of the errors of fetches that indicate the registry is failing, as opposed to e.g.
+// a chart version that does not exist. The errors of Helm getters are not typed, so they are matched by their message.
+var registryOutageErrors = []string{
+	"connection refused",
+	"connection reset",
+	"no such host",
+	"i/o timeout",
+	"TLS handshake timeout",
+	"context deadline exceeded",
+	"500 Internal Server Error",
+	"502 Bad Gateway",
+	"503 Service Unavailable",
+	"504 Gateway Timeout",
+}
+
+// registryCircuit is the state of the circuit of a single registry.
+type registryCircuit struct {
+	failures  int
+	openUntil time.Time
+}
+
+// RegistryBreaker tracks the consecutive failed fetches from each registry and opens its circuit once they reach the
+// failure threshold, so that reconciles fail fast during a registry outage instead of each timing out against it. After the
+// open duration, a single fetch is let through; its success closes the circuit and its failure opens it again. A nil
+// RegistryBreaker lets all fetches through.
+type RegistryBreaker struct {
+	mu               sync.Mutex
+	failureThreshold int
+	openDuration     time.Duration
+	circuits         map[string]*registryCircuit
+	now              func() time.Time
+}
+
+// NewRegistryBreaker returns a RegistryBreaker opening the circuit of a registry for the open duration after the given
+// number of consecutive failed chart fetches from it. A failure threshold of 0 disables it.
+func NewRegistryBreaker(failureThreshold int, openDuration time.Duration) *RegistryBreaker {
+	return &RegistryBreaker{
+		failureThreshold: failureThreshold,
+		openDuration:     openDuration,
+		circuits:         map[string]*registryCircuit{},
+		now:              time.Now,
+	}
+}
+
+// registryHost returns the host of the chart repository or OCI registry URL, which identifies its circuit.
+func registryHost(repoURL string) string {
+	u, err := url.Parse(repoURL)
+	if err != nil || u.Host == "" {
+		return repoURL
+	}
+
+	return u.Host
+}
+
+// allow returns a RegistryUnavailableError if the circuit of the registry is open. Once the open duration has passed, the
+// circuit is opened again for the open duration before returning nil, so that only a single fetch is let through.
+func (b *RegistryBreaker) allow(host string) error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	circuit, ok := b.circuits[host]
+	if b.failureThreshold <= 0 || !ok || circuit.failures < b.failureThreshold {
+		return nil
+	}
+
+	now := b.now()
+	if now.Before(circuit.openUntil) {
+		return &RegistryUnavailableError{Host: host, RetryAfter: circuit.openUntil}
+	}
+	circuit.openUntil = now.Add(b.openDuration)
+
+	return nil
+}
+
+// record records the result of a fetch from the registry. Errors that do not indicate a registry outage close the circuit
+// like a success, as the registry did respond.
+func (b *RegistryBreaker) record(host string, err error) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failureThreshold <= 0 {
+		return
+	}
+
+	if err == nil || !isRegistryOutageError(err) {
+		delete(b.circuits, host)
+		return
+	}
+
+	circuit, ok := b.circuits[host]
+	if !ok {
+		circuit = &registryCircuit{}
+		b.circuits[host] = circuit
+	}
+	circuit.failures++
+	if circuit.failures >= b.failureThreshold {
+		circuit.openUntil = b.now().Add(b.openDuration)
+	}
+}
+
+// isRegistryOutageError returns true if the error of a fetch indicates that the registry is unreachable or failing.
+func isRegistryOutageError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	msg := err.Error()
+	for _, outage := range registryOutageErrors {
+		if strings.Contains(msg, outage) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+func TestRegistryBreaker(t *testing.T) {
+	g := NewWithT(t)
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	breaker := NewRegistryBreaker(2, time.Minute)
+	breaker.now = func() time.Time { return now }
+
+	outage := errors.New("failed to fetch https://charts.example.com/index.yaml : 503 Service Unavailable")
+	host := registryHost("https://charts.example.com/stable")
+	g.Expect(host).To(Equal("charts.example.com"))
+
+	// Errors that do not indicate an outage do not count towards the threshold.
+	breaker.record(host, outage)
+	breaker.record(host, errors.New("chart \"test-chart\" version \"9.9.9\" not found"))
+	breaker.record(host, outage)
+	g.Expect(breaker.allow(host)).To(Succeed())
+
+	breaker.record(host, outage)
+	var unavailableErr *RegistryUnavailableError
+	g.Expect(errors.As(breaker.allow(host), &unavailableErr)).To(BeTrue())
+	g.Expect(unavailableErr.RetryAfter).To(Equal(now.Add(time.Minute)))
+	g.Expect(breaker.allow("oci.example.com")).To(Succeed(), "circuits are tracked per registry")
+
+	// A single fetch is let through once the circuit was open for the open duration.
+	now = now.Add(time.Minute)
+	g.Expect(breaker.allow(host)).To(Succeed())
+	g.Expect(breaker.allow(host)).NotTo(Succeed())
+
+	breaker.record(host, outage)
+	g.Expect(breaker.allow(host)).NotTo(Succeed(), "a failed fetch opens the circuit again")
+
+	now = now.Add(time.Minute)
+	g.Expect(breaker.allow(host)).To(Succeed())
+	breaker.record(host, nil)
+	g.Expect(breaker.allow(host)).To(Succeed())
+	g.Expect(breaker.allow(host)).To(Succeed(), "a successful fetch closes the circuit")
+
+	// A failure threshold of 0 disables the circuit breaker.
+	disabled := NewRegistryBreaker(0, time.Minute)
+	for range 3 {
+		disabled.record(host, outage)
+	}
+	g.Expect(disabled.allow(host)).To(Succeed())
+
+	var unset *RegistryBreaker
+	unset.record(host, outage)
+	g.Expect(unset.allow(host)).To(Succeed())
+}
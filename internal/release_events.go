@@ -0,0 +1,133 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+)
+
+// maxReleaseEvents is the maximum number of events reported in the progress of a Helm release.
+const maxReleaseEvents = 10
+
+// notableEventReasons are the reasons of the warning events that usually explain why the resources of a Helm release do not
+// become ready, e.g. BackOff for a container in CrashLoopBackOff.
+var notableEventReasons = map[string]struct{}{
+	"BackOff":          {},
+	"Failed":           {},
+	"FailedCreate":     {},
+	"FailedMount":      {},
+	"FailedScheduling": {},
+	"Unhealthy":        {},
+}
+
+// workloadKinds are the kinds of resources whose pods, or ReplicaSets, are named after them.
+var workloadKinds = map[string]struct{}{
+	"DaemonSet":   {},
+	"Deployment":  {},
+	"Job":         {},
+	"ReplicaSet":  {},
+	"StatefulSet": {},
+}
+
+// releaseObject identifies a resource of a Helm release.
+type releaseObject struct {
+	kind string
+	name string
+}
+
+// getReleaseEvents returns the most recent notable warning events since the given time of the resources of a Helm release,
+// given by namespace, and of the pods and ReplicaSets of its workloads, which are recognized by the name of the workload
+// followed by a dash.
+func getReleaseEvents(ctx context.Context, clientSet kubernetes.Interface, objects map[string][]releaseObject, since time.Time) ([]addonsv1alpha1.ReleaseEvent, error) {
+	releaseEvents := []addonsv1alpha1.ReleaseEvent{}
+	for namespace, namespaceObjects := range objects {
+		events, err := clientSet.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+			FieldSelector: fields.OneTermEqualSelector("type", corev1.EventTypeWarning).String(),
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list events in namespace %s", namespace)
+		}
+
+		for _, event := range events.Items {
+			if _, ok := notableEventReasons[event.Reason]; !ok {
+				continue
+			}
+			lastTimestamp := eventTime(event)
+			if lastTimestamp.Before(since) || !isReleaseObjectEvent(event.InvolvedObject, namespaceObjects) {
+				continue
+			}
+
+			releaseEvents = append(releaseEvents, addonsv1alpha1.ReleaseEvent{
+				Kind:          event.InvolvedObject.Kind,
+				Namespace:     event.InvolvedObject.Namespace,
+				Name:          event.InvolvedObject.Name,
+				Reason:        event.Reason,
+				Message:       event.Message,
+				Count:         event.Count,
+				LastTimestamp: &metav1.Time{Time: lastTimestamp},
+			})
+		}
+	}
+
+	sort.SliceStable(releaseEvents, func(i, j int) bool {
+		return releaseEvents[i].LastTimestamp.After(releaseEvents[j].LastTimestamp.Time)
+	})
+	if len(releaseEvents) > maxReleaseEvents {
+		releaseEvents = releaseEvents[:maxReleaseEvents]
+	}
+
+	return releaseEvents, nil
+}
+
+// eventTime returns the time an event last occurred, which is recorded in different fields by the different event APIs.
+func eventTime(event corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case event.Series != nil && !event.Series.LastObservedTime.IsZero():
+		return event.Series.LastObservedTime.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.FirstTimestamp.Time
+	}
+}
+
+// isReleaseObjectEvent returns true if the object of an event is one of the resources of the release, or a pod or
+// ReplicaSet of one of its workloads.
+func isReleaseObjectEvent(involved corev1.ObjectReference, objects []releaseObject) bool {
+	for _, object := range objects {
+		if involved.Kind == object.kind && involved.Name == object.name {
+			return true
+		}
+		if _, ok := workloadKinds[object.kind]; ok && (involved.Kind == "Pod" || involved.Kind == "ReplicaSet") && strings.HasPrefix(involved.Name, object.name+"-") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetReleaseEvents(t *testing.T) {
+	g := NewWithT(t)
+
+	started := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	event := func(name, kind, objectName, reason string, lastTimestamp time.Time) *corev1.Event {
+		return &corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: "test-namespace"},
+			InvolvedObject: corev1.ObjectReference{Kind: kind, Namespace: "test-namespace", Name: objectName},
+			Type:           corev1.EventTypeWarning,
+			Reason:         reason,
+			Message:        reason + " of " + objectName,
+			Count:          3,
+			LastTimestamp:  metav1.NewTime(lastTimestamp),
+		}
+	}
+
+	clientSet := fake.NewSimpleClientset(
+		event("crashloop", "Pod", "test-app-7d9f8-x2v4p", "BackOff", started.Add(2*time.Minute)),
+		event("scheduling", "Pod", "test-db-0", "FailedScheduling", started.Add(time.Minute)),
+		event("create", "StatefulSet", "test-db", "FailedCreate", started.Add(3*time.Minute)),
+		event("before-upgrade", "Pod", "test-app-5c4b2-q8z7m", "BackOff", started.Add(-time.Minute)),
+		event("other-release", "Pod", "other-app-7d9f8-x2v4p", "BackOff", started.Add(time.Minute)),
+		event("not-notable", "Pod", "test-app-7d9f8-x2v4p", "DNSConfigForming", started.Add(time.Minute)),
+	)
+	objects := map[string][]releaseObject{
+		"test-namespace": {
+			{kind: "Deployment", name: "test-app"},
+			{kind: "StatefulSet", name: "test-db"},
+			{kind: "Service", name: "test-app"},
+		},
+	}
+
+	events, err := getReleaseEvents(context.TODO(), clientSet, objects, started)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(events).To(HaveLen(3))
+	g.Expect(events[0].Name).To(Equal("test-db"), "events are sorted from the most recent")
+	g.Expect(events[0].Reason).To(Equal("FailedCreate"))
+	g.Expect(events[1].Name).To(Equal("test-app-7d9f8-x2v4p"))
+	g.Expect(events[1].Reason).To(Equal("BackOff"))
+	g.Expect(events[1].Count).To(Equal(int32(3)))
+	g.Expect(events[2].Name).To(Equal("test-db-0"))
+	g.Expect(events[2].Reason).To(Equal("FailedScheduling"))
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	helmAction "helm.sh/helm/v3/pkg/action"
+	helmRelease "helm.sh/helm/v3/pkg/release"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// TestHelmRelease runs the tests of the last revision of a Helm release, as `helm test` does. The returned release has
+// the results of the tests in its hooks, also if a test failed and an error is returned.
+func (c *HelmClient) TestHelmRelease(ctx context.Context, restConfig *rest.Config, spec addonsv1alpha1.HelmReleaseProxySpec) (*helmRelease.Release, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	_, actionConfig, err := HelmInit(ctx, spec.ReleaseNamespace, restConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	log.V(2).Info("Testing Helm release", "release", spec.ReleaseName)
+	testClient := generateHelmTestConfig(actionConfig, &spec.Options)
+	testClient.Namespace = spec.ReleaseNamespace
+
+	return testClient.Run(spec.ReleaseName)
+}
+
+// generateHelmTestConfig generates default helm test config using helmOptions specified in HCP CR spec.
+func generateHelmTestConfig(actionConfig *helmAction.Configuration, helmOptions *addonsv1alpha1.HelmOptions) *helmAction.ReleaseTesting {
+	testClient := helmAction.NewReleaseTesting(actionConfig)
+	if helmOptions == nil {
+		return testClient
+	}
+
+	if helmOptions.Timeout != nil {
+		testClient.Timeout = helmOptions.Timeout.Duration
+	}
+	if helmOptions.Test == nil {
+		return testClient
+	}
+
+	if helmOptions.Test.Timeout != nil {
+		testClient.Timeout = helmOptions.Test.Timeout.Duration
+	}
+	for _, filter := range helmOptions.Test.Filters {
+		if name, ok := strings.CutPrefix(filter, "!"); ok {
+			testClient.Filters[helmAction.ExcludeNameFilter] = append(testClient.Filters[helmAction.ExcludeNameFilter], name)
+		} else {
+			testClient.Filters[helmAction.IncludeNameFilter] = append(testClient.Filters[helmAction.IncludeNameFilter], filter)
+		}
+	}
+
+	return testClient
+}
+
+// ReleaseTestResults returns the results of the tests of a Helm release that were started since the given time, in the
+// order of its hooks. Tests not run because of the filters keep the result of their last run, which is not returned.
+func ReleaseTestResults(release *helmRelease.Release, since time.Time) []addonsv1alpha1.ReleaseTestResult {
+	if release == nil {
+		return nil
+	}
+
+	var results []addonsv1alpha1.ReleaseTestResult
+	for _, hook := range release.Hooks {
+		if !isTestHook(hook) || hook.LastRun.StartedAt.Time.Before(since) {
+			continue
+		}
+
+		result := addonsv1alpha1.ReleaseTestResult{
+			Name:      hook.Name,
+			Phase:     hook.LastRun.Phase.String(),
+			StartedAt: &metav1.Time{Time: hook.LastRun.StartedAt.Time},
+		}
+		if !hook.LastRun.CompletedAt.IsZero() {
+			result.CompletedAt = &metav1.Time{Time: hook.LastRun.CompletedAt.Time}
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// isTestHook returns true if the hook is run by `helm test`.
+func isTestHook(hook *helmRelease.Hook) bool {
+	for _, event := range hook.Events {
+		if event == helmRelease.HookTest {
+			return true
+		}
+	}
+
+	return false
+}
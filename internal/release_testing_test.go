@@ -0,0 +1,91 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	helmAction "helm.sh/helm/v3/pkg/action"
+	helmRelease "helm.sh/helm/v3/pkg/release"
+	helmTime "helm.sh/helm/v3/pkg/time"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+)
+
+func TestGenerateHelmTestConfig(t *testing.T) {
+	g := NewWithT(t)
+
+	options := &addonsv1alpha1.HelmOptions{Timeout: &metav1.Duration{Duration: 10 * time.Minute}}
+	testClient := generateHelmTestConfig(&helmAction.Configuration{}, options)
+	g.Expect(testClient.Timeout).To(Equal(10 * time.Minute))
+	g.Expect(testClient.Filters).To(BeEmpty())
+
+	options.Test = &addonsv1alpha1.HelmTestOptions{
+		Enable:  true,
+		Timeout: &metav1.Duration{Duration: 2 * time.Minute},
+		Filters: []string{"test-connection", "!test-slow", "test-api"},
+	}
+	testClient = generateHelmTestConfig(&helmAction.Configuration{}, options)
+	g.Expect(testClient.Timeout).To(Equal(2 * time.Minute))
+	g.Expect(testClient.Filters).To(Equal(map[string][]string{
+		helmAction.IncludeNameFilter: {"test-connection", "test-api"},
+		helmAction.ExcludeNameFilter: {"test-slow"},
+	}))
+}
+
+func TestReleaseTestResults(t *testing.T) {
+	g := NewWithT(t)
+
+	now := time.Now()
+	hook := func(name string, event helmRelease.HookEvent, startedAt time.Time, phase helmRelease.HookPhase) *helmRelease.Hook {
+		return &helmRelease.Hook{
+			Name:   name,
+			Events: []helmRelease.HookEvent{event},
+			LastRun: helmRelease.HookExecution{
+				StartedAt:   helmTime.Time{Time: startedAt},
+				CompletedAt: helmTime.Time{Time: startedAt.Add(time.Second)},
+				Phase:       phase,
+			},
+		}
+	}
+	release := &helmRelease.Release{
+		Hooks: []*helmRelease.Hook{
+			hook("pre-install-job", helmRelease.HookPreInstall, now, helmRelease.HookPhaseSucceeded),
+			hook("test-connection", helmRelease.HookTest, now.Add(time.Second), helmRelease.HookPhaseSucceeded),
+			hook("test-filtered", helmRelease.HookTest, now.Add(-time.Hour), helmRelease.HookPhaseFailed),
+			hook("test-api", helmRelease.HookTest, now.Add(2*time.Second), helmRelease.HookPhaseFailed),
+		},
+	}
+
+	g.Expect(ReleaseTestResults(nil, now)).To(BeEmpty())
+	g.Expect(ReleaseTestResults(release, now)).To(Equal([]addonsv1alpha1.ReleaseTestResult{
+		{
+			Name:        "test-connection",
+			Phase:       "Succeeded",
+			StartedAt:   &metav1.Time{Time: now.Add(time.Second)},
+			CompletedAt: &metav1.Time{Time: now.Add(2 * time.Second)},
+		},
+		{
+			Name:        "test-api",
+			Phase:       "Failed",
+			StartedAt:   &metav1.Time{Time: now.Add(2 * time.Second)},
+			CompletedAt: &metav1.Time{Time: now.Add(3 * time.Second)},
+		},
+	}))
+}
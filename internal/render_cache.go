@@ -0,0 +1,188 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	helmAction "helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"k8s.io/client-go/rest"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// maxRenderCacheEntries is the maximum number of rendered charts kept in the render cache. The oldest entry is evicted
+// first once it is full.
+const maxRenderCacheEntries = 256
+
+// renderedChart is a chart rendered against the capabilities of a workload Cluster.
+type renderedChart struct {
+	// manifest is the rendered manifest of the release, excluding hooks.
+	manifest string
+
+	// hooks are the rendered manifests of the hooks of the release.
+	hooks []string
+}
+
+// manifests returns the manifest and the hook manifests of the rendered chart.
+func (r *renderedChart) manifests() []string {
+	return append([]string{r.manifest}, r.hooks...)
+}
+
+// renderCache caches rendered charts keyed by the chart version, values and the capabilities of the workload Cluster, so
+// that Clusters with identical inputs share the rendering when checking required APIs and diffing releases.
+type renderCache struct {
+	mu      sync.Mutex
+	entries map[string]*renderedChart
+	order   []string
+}
+
+var defaultRenderCache = &renderCache{
+	entries: map[string]*renderedChart{},
+}
+
+// get returns the cached rendered chart.
+func (c *renderCache) get(key string) (*renderedChart, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rendered, ok := c.entries[key]
+
+	return rendered, ok
+}
+
+// set caches the rendered chart, evicting the oldest entry if the cache is full.
+func (c *renderCache) set(key string, rendered *renderedChart) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; ok {
+		return
+	}
+	if len(c.order) >= maxRenderCacheEntries {
+		delete(c.entries, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.entries[key] = rendered
+	c.order = append(c.order, key)
+}
+
+// renderCacheKey returns the cache key of rendering the chart of the spec with the values against the Kubernetes version
+// and served APIs of a workload Cluster.
+func renderCacheKey(spec addonsv1alpha1.HelmReleaseProxySpec, chartRequested *chart.Chart, values map[string]interface{}, kubeVersion *chartutil.KubeVersion, served chartutil.VersionSet) (string, error) {
+	apis := append([]string{}, served...)
+	sort.Strings(apis)
+
+	// Maps are marshaled with sorted keys, so identical values always have the same hash.
+	inputs, err := json.Marshal(struct {
+		Chart            string                       `json:"chart"`
+		ReleaseName      string                       `json:"releaseName"`
+		ReleaseNamespace string                       `json:"releaseNamespace"`
+		Values           map[string]interface{}       `json:"values"`
+		PostRenderer     *addonsv1alpha1.PostRenderer `json:"postRenderer,omitempty"`
+		KubeVersion      string                       `json:"kubeVersion"`
+		APIs             []string                     `json:"apis"`
+	}{
+		Chart:            chartCacheKey(spec.RepoURL, spec.ChartName, chartRequested.Metadata.Version),
+		ReleaseName:      spec.ReleaseName,
+		ReleaseNamespace: spec.ReleaseNamespace,
+		Values:           values,
+		PostRenderer:     spec.PostRenderer,
+		KubeVersion:      kubeVersion.String(),
+		APIs:             apis,
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to marshal render inputs")
+	}
+
+	sum := sha256.Sum256(inputs)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// renderChart renders the requested chart with the values against the capabilities of the workload Cluster, reusing the
+// rendering of another Cluster with identical inputs if it is cached. It also returns the APIs served by the Cluster.
+func renderChart(ctx context.Context, restConfig *rest.Config, spec addonsv1alpha1.HelmReleaseProxySpec, chartRequested *chart.Chart, values map[string]interface{}) (*renderedChart, chartutil.VersionSet, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	// Client-only rendering replaces the Kubernetes client and release storage of the action configuration, so it gets its own.
+	_, actionConfig, err := HelmInit(ctx, spec.ReleaseNamespace, restConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	clientSet, err := actionConfig.KubernetesClientSet()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	served, err := helmAction.GetVersionSet(clientSet.Discovery())
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to discover APIs served by cluster %s", spec.ClusterRef.Name)
+	}
+
+	serverVersion, err := clientSet.Discovery().ServerVersion()
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to discover Kubernetes version of cluster %s", spec.ClusterRef.Name)
+	}
+
+	kubeVersion, err := chartutil.ParseKubeVersion(serverVersion.GitVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, err := renderCacheKey(spec, chartRequested, values, kubeVersion, served)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rendered, ok := defaultRenderCache.get(key); ok {
+		log.V(4).Info("Using cached rendering of chart", "chart", spec.ChartName, "version", chartRequested.Metadata.Version)
+		return rendered, served, nil
+	}
+
+	renderClient := helmAction.NewInstall(actionConfig)
+	renderClient.DryRun = true
+	renderClient.ClientOnly = true
+	renderClient.IsUpgrade = true
+	renderClient.IncludeCRDs = true
+	renderClient.ReleaseName = spec.ReleaseName
+	renderClient.Namespace = spec.ReleaseNamespace
+	renderClient.KubeVersion = kubeVersion
+	renderClient.APIVersions = served
+	renderClient.PostRenderer = newPostRenderer(spec.PostRenderer)
+
+	release, err := renderClient.RunWithContext(ctx, chartRequested, values)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to render chart %s", spec.ChartName)
+	}
+
+	rendered := &renderedChart{manifest: release.Manifest}
+	for _, hook := range release.Hooks {
+		rendered.hooks = append(rendered.hooks, hook.Manifest)
+	}
+	defaultRenderCache.set(key, rendered)
+
+	return rendered, served, nil
+}
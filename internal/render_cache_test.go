@@ -0,0 +1,107 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+)
+
+func TestRenderCacheKey(t *testing.T) {
+	g := NewWithT(t)
+
+	spec := addonsv1alpha1.HelmReleaseProxySpec{
+		RepoURL:          "https://test-repo",
+		ChartName:        "test-chart",
+		ReleaseName:      "test-release",
+		ReleaseNamespace: "default",
+	}
+	chartRequested := &chart.Chart{Metadata: &chart.Metadata{Name: "test-chart", Version: "1.0.0"}}
+	values := map[string]interface{}{"replicas": 1, "image": map[string]interface{}{"tag": "v1"}}
+	kubeVersion, err := chartutil.ParseKubeVersion("v1.29.0")
+	g.Expect(err).NotTo(HaveOccurred())
+	served := chartutil.VersionSet{"v1", "apps/v1"}
+
+	key := func(spec addonsv1alpha1.HelmReleaseProxySpec, chartRequested *chart.Chart, values map[string]interface{}, kubeVersion *chartutil.KubeVersion, served chartutil.VersionSet) string {
+		key, err := renderCacheKey(spec, chartRequested, values, kubeVersion, served)
+		g.Expect(err).NotTo(HaveOccurred())
+		return key
+	}
+	base := key(spec, chartRequested, values, kubeVersion, served)
+
+	g.Expect(key(spec, chartRequested, map[string]interface{}{"image": map[string]interface{}{"tag": "v1"}, "replicas": 1}, kubeVersion, chartutil.VersionSet{"apps/v1", "v1"})).
+		To(Equal(base), "identical inputs share the rendering")
+
+	otherVersion := &chart.Chart{Metadata: &chart.Metadata{Name: "test-chart", Version: "1.1.0"}}
+	g.Expect(key(spec, otherVersion, values, kubeVersion, served)).NotTo(Equal(base))
+	g.Expect(key(spec, chartRequested, map[string]interface{}{"replicas": 2}, kubeVersion, served)).NotTo(Equal(base))
+	otherKubeVersion, err := chartutil.ParseKubeVersion("v1.30.0")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(key(spec, chartRequested, values, otherKubeVersion, served)).NotTo(Equal(base))
+	g.Expect(key(spec, chartRequested, values, kubeVersion, chartutil.VersionSet{"v1"})).NotTo(Equal(base))
+	otherSpec := spec
+	otherSpec.ReleaseNamespace = "other"
+	g.Expect(key(otherSpec, chartRequested, values, kubeVersion, served)).NotTo(Equal(base))
+}
+
+func TestRenderCache(t *testing.T) {
+	g := NewWithT(t)
+
+	cache := &renderCache{entries: map[string]*renderedChart{}}
+	_, ok := cache.get("first")
+	g.Expect(ok).To(BeFalse())
+
+	first := &renderedChart{manifest: "kind: ConfigMap", hooks: []string{"kind: Job"}}
+	cache.set("first", first)
+	cached, ok := cache.get("first")
+	g.Expect(ok).To(BeTrue())
+	g.Expect(cached).To(Equal(first))
+	g.Expect(cached.manifests()).To(Equal([]string{"kind: ConfigMap", "kind: Job"}))
+
+	for i := 0; i < maxRenderCacheEntries; i++ {
+		cache.set(fmt.Sprintf("key-%d", i), &renderedChart{})
+	}
+	_, ok = cache.get("first")
+	g.Expect(ok).To(BeFalse(), "the oldest entry is evicted once the cache is full")
+	_, ok = cache.get(fmt.Sprintf("key-%d", maxRenderCacheEntries-1))
+	g.Expect(ok).To(BeTrue())
+	g.Expect(cache.entries).To(HaveLen(maxRenderCacheEntries))
+}
+
+func TestDescribeManifestChange(t *testing.T) {
+	g := NewWithT(t)
+
+	change, err := describeManifestChange("kind: ConfigMap\n", "kind: ConfigMap\n", nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(change).To(BeEmpty())
+
+	change, err = describeManifestChange("data:\n  key: old\n", "data:\n  key: new\n", nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(change).To(HavePrefix(", manifest diff (-existing +desired):\n"))
+	g.Expect(change).To(ContainSubstring("old"))
+	g.Expect(change).To(ContainSubstring("new"))
+
+	rules := []addonsv1alpha1.DriftIgnoreRule{{Kind: "Deployment", Paths: []string{"spec.replicas"}}}
+	change, err = describeManifestChange("kind: Deployment\nspec:\n  replicas: 1\n", "kind: Deployment\nspec:\n  replicas: 3\n", rules)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(change).To(BeEmpty(), "ignored fields are not compared")
+}
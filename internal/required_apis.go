@@ -0,0 +1,121 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"k8s.io/client-go/rest"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// MissingAPIsError is returned when a Helm chart requires APIs that are not served by the workload Cluster.
+type MissingAPIsError struct {
+	// APIs is the sorted list of missing APIs in the form group/version/Kind.
+	APIs []string
+}
+
+func (e *MissingAPIsError) Error() string {
+	return fmt.Sprintf("cluster does not serve the APIs required by the chart: %s", strings.Join(e.APIs, ", "))
+}
+
+// manifestObject is the subset of a rendered manifest needed to determine the API it requires, or the API it defines in case of
+// a CustomResourceDefinition.
+type manifestObject struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Spec       struct {
+		Group string `yaml:"group"`
+		Names struct {
+			Kind string `yaml:"kind"`
+		} `yaml:"names"`
+		Versions []struct {
+			Name string `yaml:"name"`
+		} `yaml:"versions"`
+	} `yaml:"spec"`
+}
+
+// checkRequiredAPIs renders the requested chart against the capabilities of the workload Cluster and returns a MissingAPIsError
+// if any rendered manifest uses an API that is neither served by the Cluster nor defined by a CRD shipped with the chart.
+func checkRequiredAPIs(ctx context.Context, restConfig *rest.Config, spec addonsv1alpha1.HelmReleaseProxySpec, chartRequested *chart.Chart, values map[string]interface{}) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	rendered, served, err := renderChart(ctx, restConfig, spec, chartRequested, values)
+	if err != nil {
+		return err
+	}
+
+	if missing := findMissingAPIs(rendered.manifests(), served); len(missing) > 0 {
+		log.V(2).Info("Cluster is missing APIs required by the chart", "cluster", spec.ClusterRef.Name, "missing", missing)
+		return &MissingAPIsError{APIs: missing}
+	}
+
+	return nil
+}
+
+// findMissingAPIs returns the sorted list of APIs used by the given manifests that are neither served nor defined by a
+// CustomResourceDefinition within the same manifests.
+func findMissingAPIs(manifests []string, served chartutil.VersionSet) []string {
+	objects := []manifestObject{}
+	for _, manifest := range manifests {
+		for _, doc := range releaseutil.SplitManifests(manifest) {
+			obj := manifestObject{}
+			if err := yaml.Unmarshal([]byte(doc), &obj); err != nil || obj.APIVersion == "" || obj.Kind == "" {
+				continue
+			}
+			objects = append(objects, obj)
+		}
+	}
+
+	defined := map[string]struct{}{}
+	for _, obj := range objects {
+		if obj.Kind != "CustomResourceDefinition" || !strings.HasPrefix(obj.APIVersion, "apiextensions.k8s.io/") {
+			continue
+		}
+		for _, version := range obj.Spec.Versions {
+			defined[obj.Spec.Group+"/"+version.Name+"/"+obj.Spec.Names.Kind] = struct{}{}
+		}
+	}
+
+	missing := map[string]struct{}{}
+	for _, obj := range objects {
+		api := obj.APIVersion + "/" + obj.Kind
+		if served.Has(api) {
+			continue
+		}
+		if _, ok := defined[api]; ok {
+			continue
+		}
+		missing[api] = struct{}{}
+	}
+
+	result := make([]string, 0, len(missing))
+	for api := range missing {
+		result = append(result, api)
+	}
+	sort.Strings(result)
+
+	return result
+}
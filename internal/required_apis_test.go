@@ -0,0 +1,82 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+func TestFindMissingAPIs(t *testing.T) {
+	t.Parallel()
+
+	served := chartutil.VersionSet{"v1", "v1/ConfigMap", "apps/v1", "apps/v1/Deployment", "apiextensions.k8s.io/v1", "apiextensions.k8s.io/v1/CustomResourceDefinition"}
+
+	testCases := []struct {
+		name      string
+		manifests []string
+		expected  []string
+	}{
+		{
+			name: "all APIs served",
+			manifests: []string{`apiVersion: v1
+kind: ConfigMap
+---
+apiVersion: apps/v1
+kind: Deployment`},
+			expected: []string{},
+		},
+		{
+			name: "APIs missing from the cluster",
+			manifests: []string{`apiVersion: policy/v1beta1
+kind: PodSecurityPolicy
+---
+apiVersion: v1
+kind: ConfigMap`, `apiVersion: batch/v1beta1
+kind: CronJob`},
+			expected: []string{"batch/v1beta1/CronJob", "policy/v1beta1/PodSecurityPolicy"},
+		},
+		{
+			name: "APIs defined by a CRD in the chart",
+			manifests: []string{`apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+spec:
+  group: example.com
+  names:
+    kind: Widget
+  versions:
+  - name: v1
+---
+apiVersion: example.com/v1
+kind: Widget
+---
+apiVersion: example.com/v2
+kind: Widget`},
+			expected: []string{"example.com/v2/Widget"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			g := NewWithT(t)
+			g.Expect(findMissingAPIs(tc.manifests, served)).To(Equal(tc.expected))
+		})
+	}
+}
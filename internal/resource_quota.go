@@ -0,0 +1,205 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// QuotaExceededError is returned when installing a Helm chart would exceed a ResourceQuota of the release namespace.
+type QuotaExceededError struct {
+	// Namespace is the release namespace.
+	Namespace string
+
+	// Quota is the name of the exceeded ResourceQuota.
+	Quota string
+
+	// Resources describes each exceeded resource with the amount the chart requests and the amount left in the quota.
+	Resources []string
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("installing the chart would exceed ResourceQuota %s in namespace %s: %s", e.Quota, e.Namespace, strings.Join(e.Resources, ", "))
+}
+
+// checkResourceQuota renders the requested chart and returns a QuotaExceededError if the pods of its workloads in the release
+// namespace request more pods, CPU or memory than any ResourceQuota of the namespace has left, so that the install fails
+// fast instead of waiting for pods that are never admitted.
+func checkResourceQuota(ctx context.Context, clientSet kubernetes.Interface, restConfig *rest.Config, spec addonsv1alpha1.HelmReleaseProxySpec, chartRequested *chart.Chart, values map[string]interface{}) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	quotas, err := clientSet.CoreV1().ResourceQuotas(spec.ReleaseNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to list ResourceQuotas in namespace %s", spec.ReleaseNamespace)
+	}
+	if len(quotas.Items) == 0 {
+		return nil
+	}
+
+	rendered, _, err := renderChart(ctx, restConfig, spec, chartRequested, values)
+	if err != nil {
+		return err
+	}
+	usage := manifestResourceUsage(rendered.manifest, spec.ReleaseNamespace)
+
+	for _, quota := range quotas.Items {
+		if exceeded := exceededQuotaResources(quota, usage); len(exceeded) > 0 {
+			log.V(2).Info("Installing the chart would exceed ResourceQuota", "quota", quota.Name, "namespace", spec.ReleaseNamespace, "exceeded", exceeded)
+			return &QuotaExceededError{Namespace: spec.ReleaseNamespace, Quota: quota.Name, Resources: exceeded}
+		}
+	}
+
+	return nil
+}
+
+// exceededQuotaResources returns the resources of the usage exceeding the amount left in the quota, sorted by name.
+func exceededQuotaResources(quota corev1.ResourceQuota, usage corev1.ResourceList) []string {
+	exceeded := []string{}
+	for name, hard := range quota.Status.Hard {
+		requested, ok := usage[name]
+		if !ok || requested.IsZero() {
+			continue
+		}
+
+		available := hard.DeepCopy()
+		if used, ok := quota.Status.Used[name]; ok {
+			available.Sub(used)
+		}
+		if requested.Cmp(available) > 0 {
+			exceeded = append(exceeded, fmt.Sprintf("%s requested %s, available %s", name, requested.String(), available.String()))
+		}
+	}
+	sort.Strings(exceeded)
+
+	return exceeded
+}
+
+// manifestResourceUsage returns the pods, CPU and memory the pods of the workloads in the manifest request in the
+// namespace, named as ResourceQuota resources. Objects of other namespaces are not counted, and DaemonSets are counted
+// as a single pod as the number of nodes is not known.
+func manifestResourceUsage(manifest, namespace string) corev1.ResourceList {
+	usage := corev1.ResourceList{}
+	decoder := scheme.Codecs.UniversalDeserializer()
+	for _, doc := range releaseutil.SplitManifests(manifest) {
+		obj, _, err := decoder.Decode([]byte(doc), nil, nil)
+		if err != nil {
+			continue
+		}
+		accessor, err := meta.Accessor(obj)
+		if err != nil || (accessor.GetNamespace() != "" && accessor.GetNamespace() != namespace) {
+			continue
+		}
+
+		var pods int32
+		var podSpec corev1.PodSpec
+		switch o := obj.(type) {
+		case *corev1.Pod:
+			pods, podSpec = 1, o.Spec
+		case *appsv1.Deployment:
+			pods, podSpec = replicas(o.Spec.Replicas), o.Spec.Template.Spec
+		case *appsv1.StatefulSet:
+			pods, podSpec = replicas(o.Spec.Replicas), o.Spec.Template.Spec
+		case *appsv1.ReplicaSet:
+			pods, podSpec = replicas(o.Spec.Replicas), o.Spec.Template.Spec
+		case *appsv1.DaemonSet:
+			pods, podSpec = 1, o.Spec.Template.Spec
+		case *batchv1.Job:
+			pods, podSpec = replicas(o.Spec.Parallelism), o.Spec.Template.Spec
+		default:
+			continue
+		}
+
+		addResources(usage, corev1.ResourceList{corev1.ResourcePods: *resource.NewQuantity(int64(pods), resource.DecimalSI)})
+		podUsage := podResourceUsage(podSpec)
+		for i := int32(0); i < pods; i++ {
+			addResources(usage, podUsage)
+		}
+	}
+
+	return usage
+}
+
+// podResourceUsage returns the CPU and memory requests and limits of the pod as ResourceQuota resources. Like the
+// scheduler, the usage is the sum of the containers or the largest init container, whichever is larger.
+func podResourceUsage(podSpec corev1.PodSpec) corev1.ResourceList {
+	containers := corev1.ResourceList{}
+	for _, container := range podSpec.Containers {
+		addResources(containers, containerResourceUsage(container))
+	}
+	for _, container := range podSpec.InitContainers {
+		for name, quantity := range containerResourceUsage(container) {
+			if current, ok := containers[name]; !ok || quantity.Cmp(current) > 0 {
+				containers[name] = quantity
+			}
+		}
+	}
+
+	return containers
+}
+
+// containerResourceUsage returns the CPU and memory requests and limits of the container as ResourceQuota resources. The
+// quota resources cpu and memory are aliases of requests.cpu and requests.memory.
+func containerResourceUsage(container corev1.Container) corev1.ResourceList {
+	usage := corev1.ResourceList{}
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		if request, ok := container.Resources.Requests[name]; ok {
+			usage[name] = request.DeepCopy()
+			usage[corev1.ResourceName("requests."+string(name))] = request.DeepCopy()
+		}
+		if limit, ok := container.Resources.Limits[name]; ok {
+			usage[corev1.ResourceName("limits."+string(name))] = limit.DeepCopy()
+		}
+	}
+
+	return usage
+}
+
+// addResources adds the quantities of the resources to the total.
+func addResources(total, resources corev1.ResourceList) {
+	for name, quantity := range resources {
+		sum := total[name]
+		sum.Add(quantity)
+		total[name] = sum
+	}
+}
+
+// replicas returns the number of replicas, defaulting to 1 like the API server.
+func replicas(r *int32) int32 {
+	if r == nil {
+		return 1
+	}
+
+	return *r
+}
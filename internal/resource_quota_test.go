@@ -0,0 +1,166 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+)
+
+const quotaTestManifest = `---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 2
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      initContainers:
+      - name: migrate
+        image: migrate
+        resources:
+          requests:
+            memory: 1Gi
+      containers:
+      - name: web
+        image: web
+        resources:
+          requests:
+            cpu: 500m
+            memory: 256Mi
+          limits:
+            cpu: "1"
+      - name: sidecar
+        image: sidecar
+        resources:
+          requests:
+            cpu: 100m
+            memory: 64Mi
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: agent
+spec:
+  selector:
+    matchLabels:
+      app: agent
+  template:
+    metadata:
+      labels:
+        app: agent
+    spec:
+      containers:
+      - name: agent
+        image: agent
+        resources:
+          requests:
+            cpu: 100m
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: elsewhere
+  namespace: other
+spec:
+  containers:
+  - name: pod
+    image: pod
+    resources:
+      requests:
+        cpu: "4"
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+`
+
+func TestManifestResourceUsage(t *testing.T) {
+	g := NewWithT(t)
+
+	usage := manifestResourceUsage(quotaTestManifest, "addon")
+
+	expected := map[corev1.ResourceName]string{
+		corev1.ResourcePods:           "3",
+		corev1.ResourceCPU:            "1300m",
+		corev1.ResourceRequestsCPU:    "1300m",
+		corev1.ResourceLimitsCPU:      "2",
+		corev1.ResourceMemory:         "2Gi",
+		corev1.ResourceRequestsMemory: "2Gi",
+	}
+	g.Expect(usage).To(HaveLen(len(expected)))
+	for name, quantity := range expected {
+		actual := usage[name]
+		g.Expect(actual.Cmp(resource.MustParse(quantity))).To(BeZero(), "%s is %s, expected %s", name, actual.String(), quantity)
+	}
+}
+
+func TestExceededQuotaResources(t *testing.T) {
+	g := NewWithT(t)
+
+	usage := corev1.ResourceList{
+		corev1.ResourcePods:        resource.MustParse("3"),
+		corev1.ResourceRequestsCPU: resource.MustParse("1300m"),
+	}
+	quota := corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "team-quota", Namespace: "addon"},
+		Status: corev1.ResourceQuotaStatus{
+			Hard: corev1.ResourceList{
+				corev1.ResourcePods:           resource.MustParse("10"),
+				corev1.ResourceRequestsCPU:    resource.MustParse("2"),
+				corev1.ResourceRequestsMemory: resource.MustParse("1Gi"),
+			},
+			Used: corev1.ResourceList{
+				corev1.ResourcePods:        resource.MustParse("8"),
+				corev1.ResourceRequestsCPU: resource.MustParse("1"),
+			},
+		},
+	}
+
+	g.Expect(exceededQuotaResources(quota, usage)).To(Equal([]string{
+		"pods requested 3, available 2",
+		"requests.cpu requested 1300m, available 1",
+	}))
+
+	quota.Status.Used = nil
+	g.Expect(exceededQuotaResources(quota, usage)).To(BeEmpty())
+}
+
+func TestCheckResourceQuotaWithoutQuotas(t *testing.T) {
+	g := NewWithT(t)
+
+	clientSet := fake.NewSimpleClientset(&corev1.ResourceQuota{ObjectMeta: metav1.ObjectMeta{Name: "quota", Namespace: "other"}})
+	spec := addonsv1alpha1.HelmReleaseProxySpec{ReleaseNamespace: "addon"}
+
+	// Without ResourceQuotas in the release namespace, the chart is not rendered.
+	g.Expect(checkResourceQuota(context.TODO(), clientSet, nil, spec, nil, nil)).To(Succeed())
+}
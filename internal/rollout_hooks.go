@@ -0,0 +1,89 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+)
+
+// RolloutHookRequest is the JSON body of the POST request sent to the URL of a rollout hook.
+type RolloutHookRequest struct {
+	// Name is the name of the HelmChartProxy.
+	Name string `json:"name"`
+
+	// Namespace is the namespace of the HelmChartProxy.
+	Namespace string `json:"namespace"`
+
+	// Generation is the generation of the HelmChartProxy that is rolled out.
+	Generation int64 `json:"generation"`
+
+	// Phase is the phase the hook runs in, i.e. PreRollout or PostRollout.
+	Phase addonsv1alpha1.RolloutHookPhase `json:"phase"`
+
+	// Hook is the name of the hook.
+	Hook string `json:"hook"`
+}
+
+// maxRolloutHookResponseMessage is the maximum length of the response body of a failed call reported in the error.
+const maxRolloutHookResponseMessage = 256
+
+// CallRolloutHookURL sends the request to the URL of the rollout hook. It returns an error, including the start of the
+// response body, if the call fails or the response does not have a 2xx status code.
+func CallRolloutHookURL(ctx context.Context, hook addonsv1alpha1.RolloutHook, request RolloutHookRequest) error {
+	timeout := addonsv1alpha1.DefaultRolloutHookURLTimeout
+	if hook.Timeout != nil && hook.Timeout.Duration > 0 {
+		timeout = hook.Timeout.Duration
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal rollout hook request")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrapf(err, "failed to create request to %s", hook.URL)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to call %s", hook.URL)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, maxRolloutHookResponseMessage))
+		if len(message) > 0 {
+			return errors.Errorf("%s returned %s: %s", hook.URL, resp.Status, strings.TrimSpace(string(message)))
+		}
+
+		return errors.Errorf("%s returned %s", hook.URL, resp.Status)
+	}
+
+	return nil
+}
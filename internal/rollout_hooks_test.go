@@ -0,0 +1,83 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+)
+
+func TestCallRolloutHookURL(t *testing.T) {
+	var received RolloutHookRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			_ = json.NewDecoder(r.Body).Decode(&received)
+			w.WriteHeader(http.StatusAccepted)
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		default:
+			http.Error(w, "change freeze in effect", http.StatusConflict)
+		}
+	}))
+	defer server.Close()
+
+	request := RolloutHookRequest{Name: "ingress-nginx", Namespace: "default", Generation: 3, Phase: addonsv1alpha1.RolloutHookPhasePreRollout, Hook: "ticket"}
+
+	testcases := []struct {
+		name          string
+		hook          addonsv1alpha1.RolloutHook
+		expectedError string
+	}{
+		{
+			name: "2xx response succeeds",
+			hook: addonsv1alpha1.RolloutHook{Name: "ticket", URL: server.URL + "/ok"},
+		},
+		{
+			name:          "other responses fail with the response body",
+			hook:          addonsv1alpha1.RolloutHook{Name: "ticket", URL: server.URL + "/freeze"},
+			expectedError: server.URL + "/freeze returned 409 Conflict: change freeze in effect",
+		},
+		{
+			name:          "call times out",
+			hook:          addonsv1alpha1.RolloutHook{Name: "ticket", URL: server.URL + "/slow", Timeout: &metav1.Duration{Duration: 50 * time.Millisecond}},
+			expectedError: "context deadline exceeded",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			err := CallRolloutHookURL(context.Background(), tc.hook, request)
+			if tc.expectedError != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tc.expectedError)))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(received).To(Equal(request))
+		})
+	}
+}
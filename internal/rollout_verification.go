@@ -0,0 +1,149 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	promapi "github.com/prometheus/client_golang/api"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// DefaultRolloutVerificationQueryTimeout is the default timeout of each rollout verification query.
+const DefaultRolloutVerificationQueryTimeout = 30 * time.Second
+
+// RolloutVerifier runs the verification queries of rollouts against Prometheus.
+type RolloutVerifier struct {
+	// AllowedPrometheusURLs are the addresses of the Prometheus APIs verification queries may be run against. Verifications
+	// against any other address fail, so that HelmChartProxies cannot make the controller send requests to arbitrary
+	// endpoints.
+	AllowedPrometheusURLs []string
+
+	// QueryTimeout is the timeout of each verification query. If it is 0, DefaultRolloutVerificationQueryTimeout is used.
+	QueryTimeout time.Duration
+}
+
+// isAllowed returns true if the Prometheus URL is one of the allowed Prometheus URLs, ignoring trailing slashes.
+func (v RolloutVerifier) isAllowed(prometheusURL string) bool {
+	for _, allowed := range v.AllowedPrometheusURLs {
+		if strings.TrimSuffix(allowed, "/") == strings.TrimSuffix(prometheusURL, "/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// VerifyRollout runs the verification queries against Prometheus. It returns a message describing the queries that did not
+// pass, or an empty message if all of them passed.
+func (v RolloutVerifier) VerifyRollout(ctx context.Context, verification *addonsv1alpha1.RolloutVerification) (string, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	if !v.isAllowed(verification.PrometheusURL) {
+		return "", errors.Errorf("Prometheus URL %s is not allowed for rollout verification", verification.PrometheusURL)
+	}
+
+	timeout := v.QueryTimeout
+	if timeout == 0 {
+		timeout = DefaultRolloutVerificationQueryTimeout
+	}
+
+	client, err := promapi.NewClient(promapi.Config{
+		Address: verification.PrometheusURL,
+		Client: &http.Client{
+			Transport: promapi.DefaultRoundTripper,
+			// Redirects are not followed, since they could lead to an endpoint that is not allowed.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to create Prometheus client for %s", verification.PrometheusURL)
+	}
+	api := promv1.NewAPI(client)
+
+	failed := []string{}
+	for _, query := range verification.Queries {
+		threshold, err := strconv.ParseFloat(query.Threshold, 64)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to parse threshold of verification query %s", query.Name)
+		}
+
+		queryCtx, cancel := context.WithTimeout(ctx, timeout)
+		result, warnings, err := api.Query(queryCtx, query.Query, time.Now())
+		cancel()
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to run verification query %s", query.Name)
+		}
+		if len(warnings) > 0 {
+			log.V(2).Info("Verification query returned warnings", "query", query.Name, "warnings", warnings)
+		}
+
+		value, err := singleValue(result)
+		if err != nil {
+			return "", errors.Wrapf(err, "unexpected result of verification query %s", query.Name)
+		}
+
+		if !compare(value, addonsv1alpha1.VerificationOperator(query.Operator), threshold) {
+			failed = append(failed, fmt.Sprintf("%s: %v is not %s %v", query.Name, value, query.Operator, threshold))
+		}
+	}
+
+	return strings.Join(failed, ", "), nil
+}
+
+// singleValue returns the value of a scalar or of a vector with a single sample.
+func singleValue(result model.Value) (float64, error) {
+	switch v := result.(type) {
+	case *model.Scalar:
+		return float64(v.Value), nil
+	case model.Vector:
+		if len(v) != 1 {
+			return 0, errors.Errorf("expected a single sample but got %d", len(v))
+		}
+
+		return float64(v[0].Value), nil
+	default:
+		return 0, errors.Errorf("expected a scalar or vector but got %s", result.Type())
+	}
+}
+
+// compare returns true if the value satisfies the operator against the threshold.
+func compare(value float64, operator addonsv1alpha1.VerificationOperator, threshold float64) bool {
+	switch operator {
+	case addonsv1alpha1.VerificationOperatorLessThan:
+		return value < threshold
+	case addonsv1alpha1.VerificationOperatorLessThanOrEqual:
+		return value <= threshold
+	case addonsv1alpha1.VerificationOperatorGreaterThan:
+		return value > threshold
+	case addonsv1alpha1.VerificationOperatorGreaterThanOrEqual:
+		return value >= threshold
+	default:
+		return false
+	}
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+)
+
+func TestVerifyRollout(t *testing.T) {
+	// results maps a query to the JSON result returned by the fake Prometheus server.
+	results := map[string]string{
+		"error_rate": `{"resultType":"vector","result":[{"metric":{},"value":[1700000000,"0.01"]}]}`,
+		"up":         `{"resultType":"scalar","result":[1700000000,"3"]}`,
+		"multiple":   `{"resultType":"vector","result":[{"metric":{"a":"1"},"value":[1700000000,"1"]},{"metric":{"a":"2"},"value":[1700000000,"2"]}]}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"status":"success","data":%s}`, results[r.Form.Get("query")])
+	}))
+	defer server.Close()
+
+	testcases := []struct {
+		name          string
+		queries       []addonsv1alpha1.VerificationQuery
+		expectFailed  string
+		expectedError bool
+	}{
+		{
+			name: "all queries pass",
+			queries: []addonsv1alpha1.VerificationQuery{
+				{Name: "errors", Query: "error_rate", Operator: "LessThan", Threshold: "0.05"},
+				{Name: "up", Query: "up", Operator: "GreaterThanOrEqual", Threshold: "3"},
+			},
+		},
+		{
+			name: "query over threshold fails",
+			queries: []addonsv1alpha1.VerificationQuery{
+				{Name: "errors", Query: "error_rate", Operator: "LessThan", Threshold: "0.001"},
+				{Name: "up", Query: "up", Operator: "GreaterThan", Threshold: "2"},
+			},
+			expectFailed: "errors: 0.01 is not LessThan 0.001",
+		},
+		{
+			name: "query with multiple samples returns an error",
+			queries: []addonsv1alpha1.VerificationQuery{
+				{Name: "multiple", Query: "multiple", Operator: "LessThan", Threshold: "1"},
+			},
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			verifier := RolloutVerifier{AllowedPrometheusURLs: []string{server.URL + "/"}}
+			failed, err := verifier.VerifyRollout(context.TODO(), &addonsv1alpha1.RolloutVerification{
+				PrometheusURL: server.URL,
+				Queries:       tc.queries,
+			})
+			if tc.expectedError {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(failed).To(Equal(tc.expectFailed))
+			}
+		})
+	}
+}
+
+func TestVerifyRolloutBounds(t *testing.T) {
+	g := NewWithT(t)
+
+	blocked := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-blocked:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(blocked)
+
+	verification := &addonsv1alpha1.RolloutVerification{
+		PrometheusURL: server.URL,
+		Queries:       []addonsv1alpha1.VerificationQuery{{Name: "up", Query: "up", Operator: "GreaterThan", Threshold: "0"}},
+	}
+
+	_, err := RolloutVerifier{AllowedPrometheusURLs: []string{"http://prometheus.monitoring:9090"}}.VerifyRollout(context.TODO(), verification)
+	g.Expect(err).To(MatchError(ContainSubstring("is not allowed")))
+
+	_, err = RolloutVerifier{AllowedPrometheusURLs: []string{server.URL}, QueryTimeout: 100 * time.Millisecond}.VerifyRollout(context.TODO(), verification)
+	g.Expect(err).To(MatchError(ContainSubstring("failed to run verification query up")))
+}
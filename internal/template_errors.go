@@ -0,0 +1,110 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// templateSnippetContext is the number of lines shown before and after the failing line in a TemplateError snippet.
+const templateSnippetContext = 2
+
+// TemplateError is returned when a valuesTemplate fails to parse or execute. It carries the position of the failure within
+// the template and an annotated snippet of the surrounding lines.
+type TemplateError struct {
+	// Line is the 1-based line of the failure, or 0 if it is unknown.
+	Line int
+	// Column is the 1-based column of the failure, or 0 if it is unknown.
+	Column int
+	// Message is the Go template error without the template name and position prefix.
+	Message string
+	// Snippet is the annotated excerpt of the template around Line.
+	Snippet string
+}
+
+func (e *TemplateError) Error() string {
+	var position string
+	switch {
+	case e.Line > 0 && e.Column > 0:
+		position = fmt.Sprintf(" at line %d, column %d", e.Line, e.Column)
+	case e.Line > 0:
+		position = fmt.Sprintf(" at line %d", e.Line)
+	}
+
+	msg := fmt.Sprintf("valuesTemplate error%s: %s", position, e.Message)
+	if e.Snippet != "" {
+		msg += "\n" + e.Snippet
+	}
+
+	return msg
+}
+
+// newTemplateError converts an error returned by text/template for the template with the given name and text into a
+// TemplateError. Errors that do not carry a position are returned as a TemplateError with only a message.
+func newTemplateError(name, text string, err error) *TemplateError {
+	// Parse errors have the form "template: name:line: msg" and execution errors "template: name:line:col: msg".
+	re := regexp.MustCompile(`^template: ` + regexp.QuoteMeta(name) + `:(\d+)(?::(\d+))?: (?s)(.*)$`)
+	match := re.FindStringSubmatch(err.Error())
+	if match == nil {
+		return &TemplateError{Message: err.Error()}
+	}
+
+	line, _ := strconv.Atoi(match[1])
+	column := 0
+	if match[2] != "" {
+		// text/template reports the 0-based byte offset within the line.
+		offset, _ := strconv.Atoi(match[2])
+		column = offset + 1
+	}
+
+	return &TemplateError{
+		Line:    line,
+		Column:  column,
+		Message: match[3],
+		Snippet: templateSnippet(text, line, column),
+	}
+}
+
+// templateSnippet returns the lines of text around the given line, prefixed with their line numbers. The failing line is
+// marked with '>' and, if the column is known, followed by a caret pointing at it.
+func templateSnippet(text string, line, column int) string {
+	lines := strings.Split(text, "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+
+	first := max(line-templateSnippetContext, 1)
+	last := min(line+templateSnippetContext, len(lines))
+	width := len(strconv.Itoa(last))
+
+	var b strings.Builder
+	for i := first; i <= last; i++ {
+		marker := " "
+		if i == line {
+			marker = ">"
+		}
+		fmt.Fprintf(&b, "%s %*d | %s\n", marker, width, i, lines[i-1])
+		if i == line && column > 0 {
+			fmt.Fprintf(&b, "  %*s | %s^\n", width, "", strings.Repeat(" ", column-1))
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"io"
+	"testing"
+	"text/template"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+func TestNewTemplateError(t *testing.T) {
+	testCases := []struct {
+		name        string
+		text        string
+		execute     bool
+		expectedErr string
+	}{
+		{
+			name: "parse error reports line and snippet",
+			text: "a: 1\nb: 2\nc: {{ .Cluster.invalid-path }}\nd: 4\ne: 5\nf: 6",
+			expectedErr: "valuesTemplate error at line 3: bad character U+002D '-'\n" +
+				"  1 | a: 1\n" +
+				"  2 | b: 2\n" +
+				"> 3 | c: {{ .Cluster.invalid-path }}\n" +
+				"  4 | d: 4\n" +
+				"  5 | e: 5",
+		},
+		{
+			name:    "execution error reports line, column and caret",
+			text:    "a: 1\nb: {{ fail }}",
+			execute: true,
+			expectedErr: "valuesTemplate error at line 2, column 7: executing \"test\" at <fail>: error calling fail: boom\n" +
+				"  1 | a: 1\n" +
+				"> 2 | b: {{ fail }}\n" +
+				"    |       ^",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			funcs := template.FuncMap{"fail": func() (string, error) { return "", errors.New("boom") }}
+			tmpl, err := template.New("test").Funcs(funcs).Parse(tc.text)
+			if tc.execute {
+				g.Expect(err).NotTo(HaveOccurred())
+				err = tmpl.Execute(io.Discard, nil)
+			}
+			g.Expect(err).To(HaveOccurred())
+
+			g.Expect(newTemplateError("test", tc.text, err).Error()).To(Equal(tc.expectedErr))
+		})
+	}
+}
+
+func TestNewTemplateErrorWithoutPosition(t *testing.T) {
+	g := NewWithT(t)
+
+	err := newTemplateError("test", "a: 1", errors.New("something went wrong"))
+	g.Expect(err.Error()).To(Equal("valuesTemplate error: something went wrong"))
+}
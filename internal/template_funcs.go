@@ -0,0 +1,90 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"encoding/json"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+	"sigs.k8s.io/yaml"
+)
+
+// templateFuncMap returns the functions available to a valuesTemplate. These are the Sprig functions along with the YAML and
+// JSON helpers known from Helm charts, so structured values can be built from Cluster data.
+func templateFuncMap() template.FuncMap {
+	funcs := sprig.TxtFuncMap()
+	funcs["toYaml"] = toYAML
+	funcs["fromYaml"] = fromYAML
+	funcs["fromYamlArray"] = fromYAMLArray
+	funcs["fromJson"] = fromJSON
+	funcs["fromJsonArray"] = fromJSONArray
+
+	return funcs
+}
+
+// toYAML marshals a value to a YAML document without a trailing newline. As in Helm, errors result in an empty string so
+// that they can be handled in the template.
+func toYAML(v interface{}) string {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSuffix(string(data), "\n")
+}
+
+// fromYAML unmarshals a YAML document into a map. As in Helm, errors are returned under the "Error" key of the map.
+func fromYAML(str string) map[string]interface{} {
+	m := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(str), &m); err != nil {
+		m["Error"] = err.Error()
+	}
+
+	return m
+}
+
+// fromYAMLArray unmarshals a YAML document into a slice. As in Helm, errors are returned as the only element of the slice.
+func fromYAMLArray(str string) []interface{} {
+	a := []interface{}{}
+	if err := yaml.Unmarshal([]byte(str), &a); err != nil {
+		a = []interface{}{err.Error()}
+	}
+
+	return a
+}
+
+// fromJSON unmarshals a JSON document into a map. As in Helm, errors are returned under the "Error" key of the map.
+func fromJSON(str string) map[string]interface{} {
+	m := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(str), &m); err != nil {
+		m["Error"] = err.Error()
+	}
+
+	return m
+}
+
+// fromJSONArray unmarshals a JSON document into a slice. As in Helm, errors are returned as the only element of the slice.
+func fromJSONArray(str string) []interface{} {
+	a := []interface{}{}
+	if err := json.Unmarshal([]byte(str), &a); err != nil {
+		a = []interface{}{err.Error()}
+	}
+
+	return a
+}
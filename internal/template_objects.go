@@ -0,0 +1,117 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// TemplateOptions configures the rendering of values templates.
+type TemplateOptions struct {
+	// ObjectKinds are the kinds of provider objects read for the template data. If it is nil, objects of any kind are read.
+	ObjectKinds TemplateObjectKinds
+
+	// DerivedSecretKey is the key of the HMAC the values of the derivedSecret template function are derived with. If it
+	// is empty, derivedSecret fails.
+	DerivedSecretKey []byte
+}
+
+// TemplateObjectKinds are the kinds of provider objects, e.g. infrastructure clusters and control planes, that the
+// controller reads for values templates and upgrade gating. Objects of the cluster.x-k8s.io group, whose RBAC is granted
+// explicitly, are always read. If it is nil, objects of any kind are read, relying on RBAC to limit them.
+type TemplateObjectKinds map[schema.GroupKind]struct{}
+
+// ParseTemplateObjectKinds parses kinds in the Kind.group format, e.g. AWSCluster.infrastructure.cluster.x-k8s.io.
+func ParseTemplateObjectKinds(kinds []string) ([]schema.GroupKind, error) {
+	groupKinds := make([]schema.GroupKind, 0, len(kinds))
+	for _, kind := range kinds {
+		gk := schema.ParseGroupKind(kind)
+		if gk.Kind == "" || gk.Group == "" {
+			return nil, errors.Errorf("invalid template object kind %q, expected Kind.group", kind)
+		}
+		groupKinds = append(groupKinds, gk)
+	}
+
+	return groupKinds, nil
+}
+
+// NewTemplateObjectKinds returns the TemplateObjectKinds limiting the provider objects read for values templates and
+// upgrade gating to the given kinds. Empty kinds do not limit them.
+func NewTemplateObjectKinds(kinds []schema.GroupKind) TemplateObjectKinds {
+	if len(kinds) == 0 {
+		return nil
+	}
+
+	templateObjectKinds := make(TemplateObjectKinds, len(kinds))
+	for _, gk := range kinds {
+		templateObjectKinds[gk] = struct{}{}
+	}
+
+	return templateObjectKinds
+}
+
+// allows returns true if objects of the kind are read for values templates and upgrade gating.
+func (k TemplateObjectKinds) allows(gk schema.GroupKind) bool {
+	if k == nil || gk.Group == clusterv1.GroupVersion.Group {
+		return true
+	}
+	_, ok := k[gk]
+
+	return ok
+}
+
+// TemplateObjectsClusterRole returns a ClusterRole granting read access to the resources of the template object kinds,
+// resolved with the RESTMapper, so that it can replace the role granting access to all provider resources.
+func TemplateObjectsClusterRole(mapper meta.RESTMapper, name string, kinds []schema.GroupKind) (*rbacv1.ClusterRole, error) {
+	resourcesByGroup := map[string][]string{}
+	for _, gk := range kinds {
+		mapping, err := mapper.RESTMapping(gk)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to resolve resource of template object kind %s", gk)
+		}
+		resourcesByGroup[gk.Group] = append(resourcesByGroup[gk.Group], mapping.Resource.Resource)
+	}
+
+	groups := make([]string, 0, len(resourcesByGroup))
+	for group := range resourcesByGroup {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	role := &rbacv1.ClusterRole{
+		TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRole"},
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+	}
+	for _, group := range groups {
+		resources := resourcesByGroup[group]
+		sort.Strings(resources)
+		role.Rules = append(role.Rules, rbacv1.PolicyRule{
+			APIGroups: []string{group},
+			Resources: resources,
+			Verbs:     []string{"get", "list", "watch"},
+		})
+	}
+
+	return role, nil
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestTemplateObjectKinds(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := ParseTemplateObjectKinds([]string{"AWSCluster"})
+	g.Expect(err).To(HaveOccurred(), "kinds need a group")
+
+	kinds, err := ParseTemplateObjectKinds([]string{
+		"AWSCluster.infrastructure.cluster.x-k8s.io",
+		"AWSManagedMachinePool.infrastructure.cluster.x-k8s.io",
+		"KubeadmControlPlane.controlplane.cluster.x-k8s.io",
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	awsCluster := schema.GroupKind{Group: "infrastructure.cluster.x-k8s.io", Kind: "AWSCluster"}
+	azureCluster := schema.GroupKind{Group: "infrastructure.cluster.x-k8s.io", Kind: "AzureCluster"}
+	clusterClass := schema.GroupKind{Group: "cluster.x-k8s.io", Kind: "ClusterClass"}
+
+	g.Expect(NewTemplateObjectKinds(nil).allows(azureCluster)).To(BeTrue(), "all kinds are allowed without template object kinds")
+
+	templateObjectKinds := NewTemplateObjectKinds(kinds)
+	g.Expect(templateObjectKinds.allows(awsCluster)).To(BeTrue())
+	g.Expect(templateObjectKinds.allows(azureCluster)).To(BeFalse())
+	g.Expect(templateObjectKinds.allows(clusterClass)).To(BeTrue(), "Cluster API kinds are always allowed")
+
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{
+		{Group: "infrastructure.cluster.x-k8s.io", Version: "v1beta2"},
+		{Group: "controlplane.cluster.x-k8s.io", Version: "v1beta2"},
+	})
+	for _, gk := range kinds {
+		mapper.Add(gk.WithVersion("v1beta2"), meta.RESTScopeNamespace)
+	}
+	role, err := TemplateObjectsClusterRole(mapper, "caaph-template-objects-role", kinds)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(role.Name).To(Equal("caaph-template-objects-role"))
+	g.Expect(role.Rules).To(Equal([]rbacv1.PolicyRule{
+		{
+			APIGroups: []string{"controlplane.cluster.x-k8s.io"},
+			Resources: []string{"kubeadmcontrolplanes"},
+			Verbs:     []string{"get", "list", "watch"},
+		},
+		{
+			APIGroups: []string{"infrastructure.cluster.x-k8s.io"},
+			Resources: []string{"awsclusters", "awsmanagedmachinepools"},
+			Verbs:     []string{"get", "list", "watch"},
+		},
+	}))
+
+	_, err = TemplateObjectsClusterRole(mapper, "caaph-template-objects-role", []schema.GroupKind{azureCluster})
+	g.Expect(err).To(HaveOccurred(), "kinds without a resource fail to resolve")
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"text/template"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// defaultTemplateSecretLength is the length of the values of derivedSecret and randomSecret if none is given.
+	defaultTemplateSecretLength = 32
+
+	// maxDerivedSecretLength is the maximum length of the values of derivedSecret, the length of a hex-encoded SHA-256 HMAC.
+	maxDerivedSecretLength = 2 * sha256.Size
+
+	// maxRandomSecretLength is the maximum length of the values of randomSecret.
+	maxRandomSecretLength = 256
+
+	// randomSecretCharacters are the characters of the values of randomSecret.
+	randomSecretCharacters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+)
+
+// GeneratedSecretsName returns the name of the Secret in the namespace of the Cluster the values of the randomSecret
+// template function are persisted in.
+func GeneratedSecretsName(cluster *clusterv1.Cluster) string {
+	return cluster.Name + "-caaph-generated-secrets"
+}
+
+// clusterSecretFuncMap returns the template functions generating stable per-Cluster secrets for the Cluster:
+//   - derivedSecret "name" [length] returns the hex-encoded HMAC of the UID of the Cluster and the name with the derived
+//     secret key, so it is the same on every render without being stored anywhere.
+//   - randomSecret "name" [length] returns a random alphanumeric value generated on the first render and persisted in the
+//     generated secrets Secret of the Cluster, which is owned by the Cluster.
+func clusterSecretFuncMap(ctx context.Context, c ctrlClient.Client, cluster *clusterv1.Cluster, key []byte) template.FuncMap {
+	return template.FuncMap{
+		"derivedSecret": func(name string, length ...int) (string, error) {
+			return derivedSecret(key, cluster, name, length...)
+		},
+		"randomSecret": func(name string, length ...int) (string, error) {
+			return randomSecret(ctx, c, cluster, name, length...)
+		},
+	}
+}
+
+// templateSecretLength returns the length given to a secret template function, or the default length.
+func templateSecretLength(maxLength int, length ...int) (int, error) {
+	switch {
+	case len(length) == 0:
+		return defaultTemplateSecretLength, nil
+	case len(length) > 1 || length[0] < 1 || length[0] > maxLength:
+		return 0, errors.Errorf("length must be a single value between 1 and %d", maxLength)
+	default:
+		return length[0], nil
+	}
+}
+
+// derivedSecret returns the first length characters of the hex-encoded HMAC of the UID of the Cluster and the name with
+// the key. It fails if the key is empty.
+func derivedSecret(key []byte, cluster *clusterv1.Cluster, name string, length ...int) (string, error) {
+	n, err := templateSecretLength(maxDerivedSecretLength, length...)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to derive secret %s", name)
+	}
+	if len(key) == 0 {
+		return "", errors.Errorf("failed to derive secret %s: no derived secret key is configured", name)
+	}
+	if cluster.UID == "" {
+		return "", errors.Errorf("failed to derive secret %s: cluster %s has no UID", name, cluster.Name)
+	}
+
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%s\x00%s", cluster.UID, name)
+
+	return hex.EncodeToString(mac.Sum(nil))[:n], nil
+}
+
+// randomSecret returns the value of the name in the generated secrets Secret of the Cluster, generating and persisting a
+// random value of the given length if it has none. A value is never replaced once persisted: concurrent renders that
+// generated a value fail on the conflict and retry with the persisted one.
+func randomSecret(ctx context.Context, c ctrlClient.Client, cluster *clusterv1.Cluster, name string, length ...int) (string, error) {
+	n, err := templateSecretLength(maxRandomSecretLength, length...)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to generate secret %s", name)
+	}
+
+	secret := &corev1.Secret{}
+	key := ctrlClient.ObjectKey{Namespace: cluster.Namespace, Name: GeneratedSecretsName(cluster)}
+	err = c.Get(ctx, key, secret)
+	switch {
+	case err == nil:
+		if value, ok := secret.Data[name]; ok {
+			return string(value), nil
+		}
+	case apierrors.IsNotFound(err):
+		secret = nil
+	default:
+		return "", errors.Wrapf(err, "failed to get generated secrets Secret %s", key)
+	}
+
+	value, err := randomAlphaNumeric(n)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to generate secret %s", name)
+	}
+
+	if secret == nil {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      key.Name,
+				Namespace: key.Namespace,
+				Labels:    map[string]string{clusterv1.ClusterNameLabel: cluster.Name},
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: clusterv1.GroupVersion.String(),
+					Kind:       "Cluster",
+					Name:       cluster.Name,
+					UID:        cluster.UID,
+				}},
+			},
+			Data: map[string][]byte{name: []byte(value)},
+		}
+		if err := c.Create(ctx, secret); err != nil {
+			return "", errors.Wrapf(err, "failed to create generated secrets Secret %s", key)
+		}
+
+		return value, nil
+	}
+
+	before := secret.DeepCopy()
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data[name] = []byte(value)
+	if err := c.Patch(ctx, secret, ctrlClient.MergeFromWithOptions(before, ctrlClient.MergeFromWithOptimisticLock{})); err != nil {
+		return "", errors.Wrapf(err, "failed to persist secret %s in generated secrets Secret %s", name, key)
+	}
+
+	return value, nil
+}
+
+// randomAlphaNumeric returns a random alphanumeric string of the given length.
+func randomAlphaNumeric(length int) (string, error) {
+	value := make([]byte, length)
+	limit := big.NewInt(int64(len(randomSecretCharacters)))
+	for i := range value {
+		n, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return "", err
+		}
+		value[i] = randomSecretCharacters[n.Int64()]
+	}
+
+	return string(value), nil
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	expv1 "sigs.k8s.io/cluster-api/exp/api/v1beta1"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDerivedSecret(t *testing.T) {
+	g := NewWithT(t)
+
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", UID: "test-uid"}}
+	otherCluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", UID: "other-uid"}}
+
+	_, err := derivedSecret(nil, cluster, "admin-password")
+	g.Expect(err).To(HaveOccurred(), "derived secrets require a key")
+
+	key := []byte("test-key")
+	secret, err := derivedSecret(key, cluster, "admin-password")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(secret).To(HaveLen(defaultTemplateSecretLength))
+	g.Expect(derivedSecret(key, cluster, "admin-password")).To(Equal(secret), "derived secrets are stable")
+	g.Expect(derivedSecret(key, cluster, "api-token")).NotTo(Equal(secret))
+	g.Expect(derivedSecret(key, otherCluster, "admin-password")).NotTo(Equal(secret), "a recreated cluster gets new secrets")
+	g.Expect(derivedSecret(key, cluster, "admin-password", 16)).To(Equal(secret[:16]))
+
+	_, err = derivedSecret(key, cluster, "admin-password", maxDerivedSecretLength+1)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestRandomSecret(t *testing.T) {
+	g := NewWithT(t)
+
+	scheme := runtime.NewScheme()
+	_ = clusterv1.AddToScheme(scheme)
+	_ = expv1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	cluster := &clusterv1.Cluster{
+		TypeMeta:   metav1.TypeMeta{APIVersion: clusterv1.GroupVersion.String(), Kind: "Cluster"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", Namespace: "default", UID: "test-uid"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cluster.DeepCopy()).Build()
+	spec := addonsv1alpha1.HelmChartProxySpec{
+		ChartName:      "test-chart",
+		ValuesTemplate: `password: {{ randomSecret "admin-password" }}`,
+	}
+
+	values, err := ParseValues(context.TODO(), c, spec, cluster, TemplateOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	secret := &corev1.Secret{}
+	g.Expect(c.Get(context.TODO(), ctrlClient.ObjectKey{Namespace: "default", Name: GeneratedSecretsName(cluster)}, secret)).To(Succeed())
+	g.Expect(secret.Data["admin-password"]).To(HaveLen(defaultTemplateSecretLength))
+	g.Expect(values).To(Equal("password: " + string(secret.Data["admin-password"])))
+	g.Expect(secret.OwnerReferences).To(HaveLen(1))
+	g.Expect(secret.OwnerReferences[0].UID).To(Equal(cluster.UID))
+
+	g.Expect(ParseValues(context.TODO(), c, spec, cluster, TemplateOptions{})).To(Equal(values), "random secrets are persisted")
+
+	spec.ValuesTemplate = `token: {{ randomSecret "api-token" 64 }}`
+	_, err = ParseValues(context.TODO(), c, spec, cluster, TemplateOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(c.Get(context.TODO(), ctrlClient.ObjectKey{Namespace: "default", Name: GeneratedSecretsName(cluster)}, secret)).To(Succeed())
+	g.Expect(secret.Data).To(HaveKey("admin-password"))
+	g.Expect(secret.Data["api-token"]).To(HaveLen(64))
+}
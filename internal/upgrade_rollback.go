@@ -0,0 +1,82 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	helmRelease "helm.sh/helm/v3/pkg/release"
+	"k8s.io/client-go/rest"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// UpgradeRolledBackError is returned when an atomic upgrade of a Helm release failed and the release was rolled back to
+// the revision deployed before the upgrade.
+type UpgradeRolledBackError struct {
+	// Release is the Helm release after the rollback.
+	Release *helmRelease.Release
+
+	// FailedRevision is the revision of the failed upgrade.
+	FailedRevision int
+
+	// Err is the error of the failed upgrade.
+	Err error
+}
+
+func (e *UpgradeRolledBackError) Error() string {
+	return fmt.Sprintf("upgrade to revision %d failed and was rolled back to revision %d: %v", e.FailedRevision, e.Release.Version, e.Err)
+}
+
+func (e *UpgradeRolledBackError) Unwrap() error {
+	return e.Err
+}
+
+// upgradeRollbackRevision returns the revision to roll back a failed upgrade of the existing release to, or 0 if it is
+// not rolled back. Only upgrades that created a failed revision are rolled back, and only to a deployed revision, so that
+// a release is never rolled back to a revision that did not work either.
+func upgradeRollbackRevision(existing, upgraded *helmRelease.Release) int {
+	if upgraded == nil || upgraded.Info == nil || upgraded.Info.Status != helmRelease.StatusFailed || upgraded.Version <= existing.Version {
+		return 0
+	}
+	if existing.Info == nil || existing.Info.Status != helmRelease.StatusDeployed {
+		return 0
+	}
+
+	return existing.Version
+}
+
+// rollbackFailedUpgrade rolls back a failed upgrade of the existing release in atomic mode and returns an
+// UpgradeRolledBackError, or the error of the upgrade if it is not rolled back.
+func (c *HelmClient) rollbackFailedUpgrade(ctx context.Context, restConfig *rest.Config, spec addonsv1alpha1.HelmReleaseProxySpec, existing, upgraded *helmRelease.Release, upgradeErr error) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	revision := upgradeRollbackRevision(existing, upgraded)
+	if revision == 0 {
+		return upgradeErr
+	}
+
+	log.Info("Rolling back failed atomic upgrade", "release", spec.ReleaseName, "failedRevision", upgraded.Version, "revision", revision)
+	release, err := c.RollbackHelmRelease(ctx, restConfig, spec, revision)
+	if err != nil {
+		return errors.Wrapf(err, "failed to roll back release %s to revision %d after upgrade failed: %v", spec.ReleaseName, revision, upgradeErr)
+	}
+
+	return &UpgradeRolledBackError{Release: release, FailedRevision: upgraded.Version, Err: upgradeErr}
+}
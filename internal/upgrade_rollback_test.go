@@ -0,0 +1,80 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	helmRelease "helm.sh/helm/v3/pkg/release"
+)
+
+func TestUpgradeRollbackRevision(t *testing.T) {
+	release := func(version int, status helmRelease.Status) *helmRelease.Release {
+		return &helmRelease.Release{Version: version, Info: &helmRelease.Info{Status: status}}
+	}
+
+	testcases := []struct {
+		name     string
+		existing *helmRelease.Release
+		upgraded *helmRelease.Release
+		expected int
+	}{
+		{
+			name:     "failed upgrade of a deployed release is rolled back to the deployed revision",
+			existing: release(2, helmRelease.StatusDeployed),
+			upgraded: release(3, helmRelease.StatusFailed),
+			expected: 2,
+		},
+		{
+			name:     "upgrade failing before creating a revision is not rolled back",
+			existing: release(2, helmRelease.StatusDeployed),
+		},
+		{
+			name:     "failed upgrade of a failed release is not rolled back",
+			existing: release(2, helmRelease.StatusFailed),
+			upgraded: release(3, helmRelease.StatusFailed),
+		},
+		{
+			name:     "pending upgrade is not rolled back",
+			existing: release(2, helmRelease.StatusDeployed),
+			upgraded: release(3, helmRelease.StatusPendingUpgrade),
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			g.Expect(upgradeRollbackRevision(tc.existing, tc.upgraded)).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestUpgradeRolledBackError(t *testing.T) {
+	g := NewWithT(t)
+
+	upgradeErr := errors.New("timed out waiting for the condition")
+	err := &UpgradeRolledBackError{
+		Release:        &helmRelease.Release{Version: 4},
+		FailedRevision: 3,
+		Err:            upgradeErr,
+	}
+	g.Expect(err.Error()).To(Equal("upgrade to revision 3 failed and was rolled back to revision 4: timed out waiting for the condition"))
+	g.Expect(errors.Is(err, upgradeErr)).To(BeTrue())
+}
@@ -19,21 +19,28 @@ package internal
 import (
 	"bytes"
 	"context"
+	"sort"
+	"strings"
 	"text/template"
 
-	"github.com/Masterminds/sprig/v3"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
 	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/controllers/external"
+	expv1 "sigs.k8s.io/cluster-api/exp/api/v1beta1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 // initializeBuiltins takes a map of keys to object references, attempts to get the referenced objects, and returns a map of keys to the actual objects.
-// These objects are a map[string]interface{} so that they can be used as values in the template.
-func initializeBuiltins(ctx context.Context, c ctrlClient.Client, referenceMap map[string]corev1.ObjectReference, cluster *clusterv1.Cluster) (map[string]interface{}, error) {
+// These objects are a map[string]interface{} so that they can be used as values in the template. Objects other than the
+// Cluster that the controller is not permitted to get, e.g. of an infrastructure provider its RBAC does not cover, or whose
+// kind is not one of the template object kinds, are omitted, so that only templates referring to them fail.
+func initializeBuiltins(ctx context.Context, c ctrlClient.Client, referenceMap map[string]corev1.ObjectReference, cluster *clusterv1.Cluster, kinds TemplateObjectKinds) (map[string]interface{}, error) {
 	log := ctrl.LoggerFrom(ctx)
 
 	valueLookUp := make(map[string]interface{})
@@ -42,9 +49,18 @@ func initializeBuiltins(ctx context.Context, c ctrlClient.Client, referenceMap m
 		if ref.Namespace == "" {
 			ref.Namespace = cluster.Namespace
 		}
+		if !kinds.allows(ref.GroupVersionKind().GroupKind()) {
+			log.V(2).Info("Kind of reference is not a template object kind, omitting it from the template data", "name", name, "ref", ref)
+			continue
+		}
 		log.V(2).Info("Getting object for reference", "ref", ref)
 		obj, err := external.Get(ctx, c, &ref)
 		if err != nil {
+			if name != "Cluster" && apierrors.IsForbidden(err) {
+				log.Info("Not permitted to get object for reference, omitting it from the template data", "name", name, "ref", ref)
+				continue
+			}
+
 			return nil, errors.Wrapf(err, "failed to get object %s", ref.Name)
 		}
 		valueLookUp[name] = obj.Object
@@ -53,8 +69,58 @@ func initializeBuiltins(ctx context.Context, c ctrlClient.Client, referenceMap m
 	return valueLookUp, nil
 }
 
+// machinePoolBuiltins returns the MachinePools of the Cluster by name, each as a map with the MachinePool object under
+// "MachinePool" and its infrastructure object, e.g. holding the instance type of a managed node group, under
+// "InfrastructureMachinePool". No MachinePools are returned if the MachinePool API is not installed or the controller is
+// not permitted to list them, and infrastructure objects the controller is not permitted to get or whose kind is not one of
+// the template object kinds are omitted.
+func machinePoolBuiltins(ctx context.Context, c ctrlClient.Client, cluster *clusterv1.Cluster, kinds TemplateObjectKinds) (map[string]interface{}, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	machinePoolLookUp := make(map[string]interface{})
+
+	machinePools := &expv1.MachinePoolList{}
+	if err := c.List(ctx, machinePools, ctrlClient.InNamespace(cluster.Namespace), ctrlClient.MatchingLabels{clusterv1.ClusterNameLabel: cluster.Name}); err != nil {
+		if meta.IsNoMatchError(err) || apierrors.IsForbidden(err) {
+			log.V(2).Info("Not able to list MachinePools, omitting them from the template data", "reason", err.Error())
+			return machinePoolLookUp, nil
+		}
+
+		return nil, errors.Wrapf(err, "failed to list MachinePools of cluster %s", cluster.Name)
+	}
+
+	for i := range machinePools.Items {
+		machinePool := &machinePools.Items[i]
+		obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(machinePool)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to convert MachinePool %s", machinePool.Name)
+		}
+		values := map[string]interface{}{"MachinePool": obj}
+
+		ref := machinePool.Spec.Template.Spec.InfrastructureRef
+		if ref.Name != "" && kinds.allows(ref.GroupVersionKind().GroupKind()) {
+			if ref.Namespace == "" {
+				ref.Namespace = machinePool.Namespace
+			}
+			infra, err := external.Get(ctx, c, &ref)
+			switch {
+			case apierrors.IsForbidden(err):
+				log.Info("Not permitted to get infrastructure of MachinePool, omitting it from the template data", "machinePool", machinePool.Name, "ref", ref)
+			case err != nil:
+				return nil, errors.Wrapf(err, "failed to get infrastructure %s of MachinePool %s", ref.Name, machinePool.Name)
+			default:
+				values["InfrastructureMachinePool"] = infra.Object
+			}
+		}
+
+		machinePoolLookUp[machinePool.Name] = values
+	}
+
+	return machinePoolLookUp, nil
+}
+
 // ParseValues parses the values template and returns the expanded template. It attempts to populate a map of supported templating objects.
-func ParseValues(ctx context.Context, c ctrlClient.Client, spec addonsv1alpha1.HelmChartProxySpec, cluster *clusterv1.Cluster) (string, error) {
+func ParseValues(ctx context.Context, c ctrlClient.Client, spec addonsv1alpha1.HelmChartProxySpec, cluster *clusterv1.Cluster, opts TemplateOptions) (string, error) {
 	log := ctrl.LoggerFrom(ctx)
 
 	log.V(2).Info("Rendering templating in values:", "values", spec.ValuesTemplate)
@@ -73,26 +139,88 @@ func ParseValues(ctx context.Context, c ctrlClient.Client, spec addonsv1alpha1.H
 	if cluster.Spec.InfrastructureRef != nil {
 		references["InfraCluster"] = *cluster.Spec.InfrastructureRef
 	}
+	if cluster.Spec.Topology != nil && cluster.Spec.Topology.Class != "" {
+		classKey := cluster.GetClassKey()
+		references["ClusterClass"] = corev1.ObjectReference{
+			APIVersion: clusterv1.GroupVersion.String(),
+			Kind:       "ClusterClass",
+			Namespace:  classKey.Namespace,
+			Name:       classKey.Name,
+		}
+	}
 	// TODO: would we want to add ControlPlaneMachineTemplate?
 
-	valueLookUp, err := initializeBuiltins(ctx, c, references, cluster)
+	valueLookUp, err := initializeBuiltins(ctx, c, references, cluster, opts.ObjectKinds)
 	if err != nil {
 		return "", err
 	}
-
-	tmpl, err := template.New(spec.ChartName + "-" + cluster.GetName()).
-		Funcs(sprig.TxtFuncMap()).
-		Parse(spec.ValuesTemplate)
+	machinePools, err := machinePoolBuiltins(ctx, c, cluster, opts.ObjectKinds)
 	if err != nil {
 		return "", err
 	}
+	valueLookUp["MachinePools"] = machinePools
+
+	name := spec.ChartName + "-" + cluster.GetName()
+	tmpl := template.New(name).Funcs(templateFuncMap()).Funcs(clusterSecretFuncMap(ctx, c, cluster, opts.DerivedSecretKey))
+	missingKey := addonsv1alpha1.MissingKeyPolicyDefault
+	if opts := spec.ValuesTemplateOptions; opts != nil {
+		tmpl = tmpl.Delims(opts.LeftDelimiter, opts.RightDelimiter)
+		if opts.MissingKey != "" {
+			missingKey = addonsv1alpha1.MissingKeyPolicy(opts.MissingKey)
+		}
+	}
+	tmpl = tmpl.Option("missingkey=" + strings.ToLower(string(missingKey)))
+
+	if err := loadTemplateLibrary(ctx, c, cluster.Namespace, tmpl); err != nil {
+		return "", err
+	}
+
+	tmpl, err = tmpl.Parse(spec.ValuesTemplate)
+	if err != nil {
+		return "", newTemplateError(name, spec.ValuesTemplate, err)
+	}
 	var buffer bytes.Buffer
 
 	if err := tmpl.Execute(&buffer, valueLookUp); err != nil {
-		return "", errors.Wrapf(err, "error executing template string '%s' on cluster '%s'", spec.ValuesTemplate, cluster.GetName())
+		return "", newTemplateError(name, spec.ValuesTemplate, err)
 	}
 	expandedTemplate := buffer.String()
+	if missingKey == addonsv1alpha1.MissingKeyPolicyZero {
+		// Values of the builtin objects are interface{}, whose zero value is still rendered as "<no value>".
+		expandedTemplate = strings.ReplaceAll(expandedTemplate, "<no value>", "")
+	}
 	log.V(2).Info("Expanded values to", "result", expandedTemplate)
 
-	return expandedTemplate, nil
+	return injectProxyValues(ctx, c, spec.ProxyValues, cluster, expandedTemplate)
+}
+
+// loadTemplateLibrary parses the template partials of every template library ConfigMap in the namespace into the given
+// template, so they can be invoked with the template action. Partials are parsed with the delimiters and options of the
+// given template.
+func loadTemplateLibrary(ctx context.Context, c ctrlClient.Client, namespace string, tmpl *template.Template) error {
+	configMaps := &corev1.ConfigMapList{}
+	if err := c.List(ctx, configMaps, ctrlClient.InNamespace(namespace), ctrlClient.MatchingLabels{addonsv1alpha1.TemplateLibraryLabelName: "true"}); err != nil {
+		return errors.Wrapf(err, "failed to list template library ConfigMaps in namespace %s", namespace)
+	}
+
+	// Parse in a stable order so that a partial defined more than once always resolves to the same definition.
+	sort.Slice(configMaps.Items, func(i, j int) bool {
+		return configMaps.Items[i].Name < configMaps.Items[j].Name
+	})
+
+	for _, configMap := range configMaps.Items {
+		keys := make([]string, 0, len(configMap.Data))
+		for key := range configMap.Data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if _, err := tmpl.New(configMap.Name + "/" + key).Parse(configMap.Data[key]); err != nil {
+				return errors.Wrapf(err, "failed to parse template library ConfigMap %s key %s", configMap.Name, key)
+			}
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,259 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/ptr"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	expv1 "sigs.k8s.io/cluster-api/exp/api/v1beta1"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestParseValues(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clusterv1.AddToScheme(scheme)
+	_ = expv1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	cluster := &clusterv1.Cluster{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: clusterv1.GroupVersion.String(),
+			Kind:       "Cluster",
+		},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", Namespace: "default"},
+		Spec: clusterv1.ClusterSpec{
+			Topology: &clusterv1.Topology{Class: "test-class", Version: "v1.30.0"},
+		},
+	}
+
+	clusterClass := &clusterv1.ClusterClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-class", Namespace: "default"},
+		Spec: clusterv1.ClusterClassSpec{
+			Variables: []clusterv1.ClusterClassVariable{{Name: "region"}},
+		},
+	}
+
+	templateLibrary := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "common",
+			Namespace: "default",
+			Labels:    map[string]string{addonsv1alpha1.TemplateLibraryLabelName: "true"},
+		},
+		Data: map[string]string{
+			"labels.tpl": `{{ define "common.labels" }}cluster: {{ .Cluster.metadata.name }}{{ end }}`,
+		},
+	}
+
+	testCases := []struct {
+		name           string
+		valuesTemplate string
+		opts           *addonsv1alpha1.ValuesTemplateOptions
+		expected       string
+		expectErr      bool
+	}{
+		{
+			name:           "missing key renders no value by default",
+			valuesTemplate: "foo: {{ .Cluster.metadata.missing }}",
+			expected:       "foo: <no value>",
+		},
+		{
+			name:           "missing key renders empty value with Zero",
+			valuesTemplate: "foo: {{ .Cluster.metadata.missing }}",
+			opts:           &addonsv1alpha1.ValuesTemplateOptions{MissingKey: string(addonsv1alpha1.MissingKeyPolicyZero)},
+			expected:       "foo: ",
+		},
+		{
+			name:           "missing key fails with Error",
+			valuesTemplate: "foo: {{ .Cluster.metadata.missing }}",
+			opts:           &addonsv1alpha1.ValuesTemplateOptions{MissingKey: string(addonsv1alpha1.MissingKeyPolicyError)},
+			expectErr:      true,
+		},
+		{
+			name:           "custom delimiters pass Helm expressions through",
+			valuesTemplate: "name: [[ .Cluster.metadata.name ]]\nrelease: {{ .Release.Name }}",
+			opts:           &addonsv1alpha1.ValuesTemplateOptions{LeftDelimiter: "[[", RightDelimiter: "]]"},
+			expected:       "name: test-cluster\nrelease: {{ .Release.Name }}",
+		},
+		{
+			name:           "structured values can be built with YAML and JSON helpers",
+			valuesTemplate: "tolerations:\n{{ fromJsonArray `[{\"key\":\"dedicated\",\"operator\":\"Exists\"}]` | toYaml | indent 2 }}\nname: {{ (fromYaml \"name: foo\").name }}",
+			expected:       "tolerations:\n  - key: dedicated\n    operator: Exists\nname: foo",
+		},
+		{
+			name:           "partials from template library ConfigMaps can be invoked",
+			valuesTemplate: `labels: { {{- template "common.labels" . -}} }`,
+			expected:       "labels: {cluster: test-cluster}",
+		},
+		{
+			name:           "ClusterClass of the Cluster topology is available",
+			valuesTemplate: "variable: {{ (index .ClusterClass.spec.variables 0).name }}",
+			expected:       "variable: region",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cluster.DeepCopy(), clusterClass.DeepCopy(), templateLibrary.DeepCopy()).Build()
+			spec := addonsv1alpha1.HelmChartProxySpec{
+				ChartName:             "test-chart",
+				ValuesTemplate:        tc.valuesTemplate,
+				ValuesTemplateOptions: tc.opts,
+			}
+
+			values, err := ParseValues(context.TODO(), c, spec, cluster, TemplateOptions{})
+			if tc.expectErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(values).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestParseValuesForbiddenReference(t *testing.T) {
+	g := NewWithT(t)
+
+	scheme := runtime.NewScheme()
+	_ = clusterv1.AddToScheme(scheme)
+	_ = expv1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	cluster := &clusterv1.Cluster{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: clusterv1.GroupVersion.String(),
+			Kind:       "Cluster",
+		},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", Namespace: "default"},
+		Spec: clusterv1.ClusterSpec{
+			InfrastructureRef: &corev1.ObjectReference{
+				APIVersion: "infrastructure.example.com/v1beta1",
+				Kind:       "ExampleCluster",
+				Name:       "test-cluster",
+			},
+		},
+	}
+
+	// The controller is not permitted to get the objects of the infrastructure provider.
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cluster.DeepCopy()).WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, client ctrlClient.WithWatch, key ctrlClient.ObjectKey, obj ctrlClient.Object, opts ...ctrlClient.GetOption) error {
+			if obj.GetObjectKind().GroupVersionKind().Group == "infrastructure.example.com" {
+				return apierrors.NewForbidden(schema.GroupResource{Group: "infrastructure.example.com", Resource: "exampleclusters"}, key.Name, errors.New("access denied"))
+			}
+
+			return client.Get(ctx, key, obj, opts...)
+		},
+	}).Build()
+
+	values, err := ParseValues(context.TODO(), c, addonsv1alpha1.HelmChartProxySpec{
+		ChartName:      "test-chart",
+		ValuesTemplate: "name: {{ .Cluster.metadata.name }}",
+	}, cluster, TemplateOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(values).To(Equal("name: test-cluster"))
+
+	_, err = ParseValues(context.TODO(), c, addonsv1alpha1.HelmChartProxySpec{
+		ChartName:             "test-chart",
+		ValuesTemplate:        "vpc: {{ .InfraCluster.spec.vpc }}",
+		ValuesTemplateOptions: &addonsv1alpha1.ValuesTemplateOptions{MissingKey: string(addonsv1alpha1.MissingKeyPolicyError)},
+	}, cluster, TemplateOptions{})
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestParseValuesMachinePools(t *testing.T) {
+	g := NewWithT(t)
+
+	scheme := runtime.NewScheme()
+	_ = clusterv1.AddToScheme(scheme)
+	_ = expv1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	cluster := &clusterv1.Cluster{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: clusterv1.GroupVersion.String(),
+			Kind:       "Cluster",
+		},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", Namespace: "default"},
+	}
+
+	machinePool := &expv1.MachinePool{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pool-0",
+			Namespace: "default",
+			Labels:    map[string]string{clusterv1.ClusterNameLabel: cluster.Name},
+		},
+		Spec: expv1.MachinePoolSpec{
+			ClusterName: cluster.Name,
+			Replicas:    ptr.To[int32](3),
+			Template: clusterv1.MachineTemplateSpec{
+				Spec: clusterv1.MachineSpec{
+					ClusterName: cluster.Name,
+					InfrastructureRef: corev1.ObjectReference{
+						APIVersion: "infrastructure.example.com/v1beta1",
+						Kind:       "ExampleManagedMachinePool",
+						Name:       "pool-0",
+					},
+				},
+			},
+		},
+	}
+	otherMachinePool := machinePool.DeepCopy()
+	otherMachinePool.Name = "other-pool"
+	otherMachinePool.Labels = map[string]string{clusterv1.ClusterNameLabel: "other-cluster"}
+
+	infraMachinePool := &unstructured.Unstructured{}
+	infraMachinePool.SetAPIVersion("infrastructure.example.com/v1beta1")
+	infraMachinePool.SetKind("ExampleManagedMachinePool")
+	infraMachinePool.SetNamespace("default")
+	infraMachinePool.SetName("pool-0")
+	g.Expect(unstructured.SetNestedField(infraMachinePool.Object, "m5.large", "spec", "instanceType")).To(Succeed())
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cluster.DeepCopy(), machinePool, otherMachinePool, infraMachinePool).Build()
+
+	values, err := ParseValues(context.TODO(), c, addonsv1alpha1.HelmChartProxySpec{
+		ChartName: "test-chart",
+		ValuesTemplate: `{{- range $name, $pool := .MachinePools }}
+{{ $name }}: {{ $pool.MachinePool.spec.replicas }} x {{ $pool.InfrastructureMachinePool.spec.instanceType }}
+{{- end }}`,
+	}, cluster, TemplateOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(values).To(Equal("\npool-0: 3 x m5.large"))
+
+	// Infrastructure objects whose kind is not one of the template object kinds are omitted.
+	values, err = ParseValues(context.TODO(), c, addonsv1alpha1.HelmChartProxySpec{
+		ChartName:      "test-chart",
+		ValuesTemplate: `{{ range $name, $pool := .MachinePools }}{{ $name }}: {{ $pool.InfrastructureMachinePool }}{{ end }}`,
+	}, cluster, TemplateOptions{ObjectKinds: NewTemplateObjectKinds([]schema.GroupKind{{Group: "infrastructure.example.com", Kind: "ExampleCluster"}})})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(values).To(Equal("pool-0: <no value>"))
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ValuesSourceError is returned when the values of a ValuesFrom source cannot be resolved.
+type ValuesSourceError struct {
+	// Reason is the reason of the ValuesReady condition for the error, e.g. ValuesSourceNotFoundReason.
+	Reason string
+
+	// Err is the error resolving the values.
+	Err error
+}
+
+func (e *ValuesSourceError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ValuesSourceError) Unwrap() error {
+	return e.Err
+}
+
+// GetValuesFrom reads the values of the ConfigMaps and Secrets referenced by the sources in the namespace and merges them
+// in order, with later sources taking precedence. Optional sources whose object or key does not exist are skipped. Errors
+// resolving a source are returned as a ValuesSourceError.
+func GetValuesFrom(ctx context.Context, c client.Client, namespace string, sources []addonsv1alpha1.ValuesFromSource) (string, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	values := ""
+	for _, source := range sources {
+		key := source.ValuesKey
+		if key == "" {
+			key = addonsv1alpha1.DefaultValuesFromKey
+		}
+
+		data, err := getValuesFromSource(ctx, c, namespace, source, key)
+		var sourceErr *ValuesSourceError
+		if errors.As(err, &sourceErr) && source.Optional &&
+			(sourceErr.Reason == addonsv1alpha1.ValuesSourceNotFoundReason || sourceErr.Reason == addonsv1alpha1.ValuesKeyNotFoundReason) {
+			log.V(2).Info("Skipping optional values source", "kind", source.Kind, "name", source.Name, "key", key)
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		values, err = MergeValues(values, data)
+		if err != nil {
+			return "", &ValuesSourceError{
+				Reason: addonsv1alpha1.ValuesSourceInvalidReason,
+				Err:    errors.Wrapf(err, "failed to merge values of %s %s/%s", source.Kind, namespace, source.Name),
+			}
+		}
+	}
+
+	return values, nil
+}
+
+// getValuesFromSource returns the data of the key of the object referenced by the source.
+func getValuesFromSource(ctx context.Context, c client.Client, namespace string, source addonsv1alpha1.ValuesFromSource, key string) (string, error) {
+	objKey := client.ObjectKey{Namespace: namespace, Name: source.Name}
+
+	var obj client.Object
+	switch addonsv1alpha1.ValuesFromKind(source.Kind) {
+	case addonsv1alpha1.ValuesFromKindConfigMap:
+		obj = &corev1.ConfigMap{}
+	case addonsv1alpha1.ValuesFromKindSecret:
+		obj = &corev1.Secret{}
+	default:
+		return "", &ValuesSourceError{
+			Reason: addonsv1alpha1.ValuesSourceInvalidReason,
+			Err:    errors.Errorf("unsupported values source kind %q", source.Kind),
+		}
+	}
+
+	if err := c.Get(ctx, objKey, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", &ValuesSourceError{
+				Reason: addonsv1alpha1.ValuesSourceNotFoundReason,
+				Err:    errors.Errorf("values key %s of %s %s not found: %s %s does not exist", key, source.Kind, objKey, source.Kind, objKey),
+			}
+		}
+
+		return "", &ValuesSourceError{
+			Reason: addonsv1alpha1.ValuesSourceGetFailedReason,
+			Err:    errors.Wrapf(err, "failed to get %s %s", source.Kind, objKey),
+		}
+	}
+
+	var data string
+	var ok bool
+	switch obj := obj.(type) {
+	case *corev1.ConfigMap:
+		data, ok = obj.Data[key]
+	case *corev1.Secret:
+		var b []byte
+		b, ok = obj.Data[key]
+		data = string(b)
+	}
+	if !ok {
+		return "", &ValuesSourceError{
+			Reason: addonsv1alpha1.ValuesKeyNotFoundReason,
+			Err:    errors.Errorf("values key %s of %s %s not found", key, source.Kind, objKey),
+		}
+	}
+
+	return data, nil
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGetValuesFrom(t *testing.T) {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "defaults"},
+		Data: map[string]string{
+			addonsv1alpha1.DefaultValuesFromKey: "replicas: 1\nimage:\n  tag: v1\n",
+			"overrides.yaml":                    "replicas: 2\n",
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "credentials"},
+		Data: map[string][]byte{
+			addonsv1alpha1.DefaultValuesFromKey: []byte("image:\n  pullSecret: registry\n"),
+		},
+	}
+
+	testCases := []struct {
+		name           string
+		sources        []addonsv1alpha1.ValuesFromSource
+		expectedValues string
+		expectedError  string
+		expectedReason string
+	}{
+		{
+			name:           "no sources",
+			expectedValues: "",
+		},
+		{
+			name: "sources are merged in order",
+			sources: []addonsv1alpha1.ValuesFromSource{
+				{Kind: "ConfigMap", Name: "defaults"},
+				{Kind: "Secret", Name: "credentials"},
+				{Kind: "ConfigMap", Name: "defaults", ValuesKey: "overrides.yaml"},
+			},
+			expectedValues: "image:\n  pullSecret: registry\n  tag: v1\nreplicas: 2\n",
+		},
+		{
+			name: "missing optional sources are skipped",
+			sources: []addonsv1alpha1.ValuesFromSource{
+				{Kind: "ConfigMap", Name: "defaults", ValuesKey: "overrides.yaml"},
+				{Kind: "Secret", Name: "missing", Optional: true},
+				{Kind: "ConfigMap", Name: "defaults", ValuesKey: "missing.yaml", Optional: true},
+			},
+			expectedValues: "replicas: 2\n",
+		},
+		{
+			name: "missing object fails",
+			sources: []addonsv1alpha1.ValuesFromSource{
+				{Kind: "Secret", Name: "missing"},
+			},
+			expectedError:  "values key values.yaml of Secret default/missing not found",
+			expectedReason: addonsv1alpha1.ValuesSourceNotFoundReason,
+		},
+		{
+			name: "missing key fails",
+			sources: []addonsv1alpha1.ValuesFromSource{
+				{Kind: "ConfigMap", Name: "defaults", ValuesKey: "missing.yaml"},
+			},
+			expectedError:  "values key missing.yaml of ConfigMap default/defaults not found",
+			expectedReason: addonsv1alpha1.ValuesKeyNotFoundReason,
+		},
+		{
+			name: "unsupported kind fails",
+			sources: []addonsv1alpha1.ValuesFromSource{
+				{Kind: "Deployment", Name: "defaults", Optional: true},
+			},
+			expectedError:  "unsupported values source kind",
+			expectedReason: addonsv1alpha1.ValuesSourceInvalidReason,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			scheme := runtime.NewScheme()
+			_ = corev1.AddToScheme(scheme)
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(configMap, secret).Build()
+
+			values, err := GetValuesFrom(context.TODO(), c, "default", tc.sources)
+			if tc.expectedError != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tc.expectedError)))
+				var sourceErr *ValuesSourceError
+				g.Expect(errors.As(err, &sourceErr)).To(BeTrue())
+				g.Expect(sourceErr.Reason).To(Equal(tc.expectedReason))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(values).To(Equal(tc.expectedValues))
+		})
+	}
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// MergeValues merges the overlay values over the base values and returns the YAML of the result. Maps are merged
+// recursively, while any other value of the overlay, including lists, replaces the value of the base. The base values
+// are returned unchanged if the overlay is empty.
+func MergeValues(base, overlay string) (string, error) {
+	overlayValues := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(overlay), &overlayValues); err != nil {
+		return "", errors.Wrap(err, "failed to parse overlay values")
+	}
+	if len(overlayValues) == 0 {
+		return base, nil
+	}
+
+	baseValues := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(base), &baseValues); err != nil {
+		return "", errors.Wrap(err, "failed to parse base values")
+	}
+	if baseValues == nil {
+		baseValues = map[string]interface{}{}
+	}
+
+	merged, err := yaml.Marshal(mergeMaps(baseValues, overlayValues))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal merged values")
+	}
+
+	return string(merged), nil
+}
+
+// mergeMaps merges the overlay map over the base map in place and returns it.
+func mergeMaps(base, overlay map[string]interface{}) map[string]interface{} {
+	for key, value := range overlay {
+		overlayMap, ok := value.(map[string]interface{})
+		if baseMap, isMap := base[key].(map[string]interface{}); ok && isMap {
+			base[key] = mergeMaps(baseMap, overlayMap)
+			continue
+		}
+		base[key] = value
+	}
+
+	return base
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestMergeValues(t *testing.T) {
+	testCases := []struct {
+		name           string
+		base           string
+		overlay        string
+		expectedValues string
+		expectedError  bool
+	}{
+		{
+			name:           "empty overlay",
+			base:           "replicas: 1\n",
+			expectedValues: "replicas: 1\n",
+		},
+		{
+			name:           "empty base",
+			overlay:        "replicas: 3\n",
+			expectedValues: "replicas: 3\n",
+		},
+		{
+			name:           "maps are merged recursively",
+			base:           "replicas: 1\nresources:\n  limits:\n    cpu: 100m\n    memory: 128Mi\n",
+			overlay:        "resources:\n  limits:\n    memory: 1Gi\n",
+			expectedValues: "replicas: 1\nresources:\n  limits:\n    cpu: 100m\n    memory: 1Gi\n",
+		},
+		{
+			name:           "lists and scalars are replaced",
+			base:           "args:\n- --verbose\nlabels:\n  tier: dev\n",
+			overlay:        "args:\n- --quiet\nlabels: null\n",
+			expectedValues: "args:\n- --quiet\nlabels: null\n",
+		},
+		{
+			name:          "overlay that is not a map fails",
+			base:          "replicas: 1\n",
+			overlay:       "- replicas: 3\n",
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			values, err := MergeValues(tc.base, tc.overlay)
+			if tc.expectedError {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(values).To(Equal(tc.expectedValues))
+		})
+	}
+}
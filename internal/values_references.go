@@ -0,0 +1,140 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// DefaultValuesByReferenceMinSize is the size in bytes of the YAML of a top-level value from which it is stored by
+	// reference if the HelmChartProxy does not specify one.
+	DefaultValuesByReferenceMinSize = 4096
+
+	// valuesConfigMapHashLength is the length of the prefix of the hash of a value in the name of the ConfigMap storing it.
+	valuesConfigMapHashLength = 16
+)
+
+// ValuesBlock is a top-level value split out of rendered values to be stored by reference.
+type ValuesBlock struct {
+	// Key is the top-level key of the value.
+	Key string
+
+	// Data is the YAML of the value.
+	Data string
+
+	// Hash is the hex-encoded SHA-256 hash of Data.
+	Hash string
+}
+
+// SplitValues splits the top-level values whose YAML is at least minSize bytes out of the rendered values, returning the
+// YAML of the remaining values and the blocks sorted by key. The values are returned unchanged if no value is split out.
+func SplitValues(values string, minSize int) (string, []ValuesBlock, error) {
+	parsed := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(values), &parsed); err != nil {
+		return "", nil, errors.Wrap(err, "failed to parse values to split")
+	}
+
+	blocks := []ValuesBlock{}
+	for key, value := range parsed {
+		data, err := yaml.Marshal(value)
+		if err != nil {
+			return "", nil, errors.Wrapf(err, "failed to marshal value %s", key)
+		}
+		if len(data) < minSize {
+			continue
+		}
+
+		sum := sha256.Sum256(data)
+		blocks = append(blocks, ValuesBlock{Key: key, Data: string(data), Hash: hex.EncodeToString(sum[:])})
+		delete(parsed, key)
+	}
+	if len(blocks) == 0 {
+		return values, nil, nil
+	}
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].Key < blocks[j].Key
+	})
+
+	inline := ""
+	if len(parsed) > 0 {
+		data, err := yaml.Marshal(parsed)
+		if err != nil {
+			return "", nil, errors.Wrap(err, "failed to marshal inline values")
+		}
+		inline = string(data)
+	}
+
+	return inline, blocks, nil
+}
+
+// ValuesConfigMapName returns the name of the ConfigMap storing the value with the hash for the HelmChartProxy. Values with
+// the same hash share a ConfigMap, so values that are the same on every Cluster are only stored once.
+func ValuesConfigMapName(helmChartProxyName, hash string) string {
+	return fmt.Sprintf("%s-values-%s", helmChartProxyName, hash[:valuesConfigMapHashLength])
+}
+
+// ResolveValues returns the values of the HelmReleaseProxy spec with the values it references from ConfigMaps in the
+// namespace merged in. An error is returned if a referenced value cannot be found or does not match its hash.
+func ResolveValues(ctx context.Context, c ctrlClient.Client, namespace string, spec addonsv1alpha1.HelmReleaseProxySpec) (string, error) {
+	if len(spec.ValuesRefs) == 0 {
+		return spec.Values, nil
+	}
+
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(spec.Values), &values); err != nil {
+		return "", errors.Wrap(err, "failed to parse inline values")
+	}
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+
+	for _, ref := range spec.ValuesRefs {
+		configMap := &corev1.ConfigMap{}
+		if err := c.Get(ctx, ctrlClient.ObjectKey{Namespace: namespace, Name: ref.ConfigMapName}, configMap); err != nil {
+			return "", errors.Wrapf(err, "failed to get ConfigMap %s of value %s", ref.ConfigMapName, ref.Key)
+		}
+
+		data := configMap.Data[addonsv1alpha1.ValuesBlockKey]
+		if sum := sha256.Sum256([]byte(data)); hex.EncodeToString(sum[:]) != ref.Hash {
+			return "", errors.Errorf("value %s in ConfigMap %s does not match its hash %s", ref.Key, ref.ConfigMapName, ref.Hash)
+		}
+
+		var value interface{}
+		if err := yaml.Unmarshal([]byte(data), &value); err != nil {
+			return "", errors.Wrapf(err, "failed to parse value %s in ConfigMap %s", ref.Key, ref.ConfigMapName)
+		}
+		values[ref.Key] = value
+	}
+
+	resolved, err := yaml.Marshal(values)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal resolved values")
+	}
+
+	return string(resolved), nil
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSplitValues(t *testing.T) {
+	testCases := []struct {
+		name           string
+		values         string
+		minSize        int
+		expectedValues string
+		expectedKeys   []string
+		expectedError  bool
+	}{
+		{
+			name:           "no value is large enough",
+			values:         "replicas: 2\nimage: nginx\n",
+			minSize:        64,
+			expectedValues: "replicas: 2\nimage: nginx\n",
+		},
+		{
+			name:           "large values are split out",
+			values:         "replicas: 2\nconfig:\n  a: a long configuration value\nrules:\n- a long rule definition\n",
+			minSize:        16,
+			expectedValues: "replicas: 2\n",
+			expectedKeys:   []string{"config", "rules"},
+		},
+		{
+			name:         "all values are split out",
+			values:       "config:\n  a: a long configuration value\n",
+			minSize:      16,
+			expectedKeys: []string{"config"},
+		},
+		{
+			name:          "values that are not a map fail",
+			values:        "- a\n- b\n",
+			minSize:       1,
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			values, blocks, err := SplitValues(tc.values, tc.minSize)
+			if tc.expectedError {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(values).To(Equal(tc.expectedValues))
+
+			keys := []string{}
+			for _, block := range blocks {
+				keys = append(keys, block.Key)
+				g.Expect(block.Hash).To(HaveLen(64))
+			}
+			g.Expect(keys).To(ConsistOf(tc.expectedKeys))
+		})
+	}
+}
+
+func TestResolveValues(t *testing.T) {
+	g := NewWithT(t)
+
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+
+	values, blocks, err := SplitValues("replicas: 2\nconfig:\n  a: a long configuration value\n", 16)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(blocks).To(HaveLen(1))
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: ValuesConfigMapName("test-hcp", blocks[0].Hash), Namespace: "default"},
+		Data:       map[string]string{addonsv1alpha1.ValuesBlockKey: blocks[0].Data},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(configMap).Build()
+	spec := addonsv1alpha1.HelmReleaseProxySpec{
+		Values: values,
+		ValuesRefs: []addonsv1alpha1.ValuesReference{{
+			Key:           blocks[0].Key,
+			ConfigMapName: configMap.Name,
+			Hash:          blocks[0].Hash,
+		}},
+	}
+
+	resolved, err := ResolveValues(context.TODO(), c, "default", spec)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(resolved).To(MatchYAML("replicas: 2\nconfig:\n  a: a long configuration value\n"))
+
+	spec.ValuesRefs[0].Hash = "0000"
+	_, err = ResolveValues(context.TODO(), c, "default", spec)
+	g.Expect(err).To(MatchError(ContainSubstring("does not match its hash")))
+
+	spec.ValuesRefs[0].ConfigMapName = "missing"
+	_, err = ResolveValues(context.TODO(), c, "default", spec)
+	g.Expect(err).To(HaveOccurred())
+}
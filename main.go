@@ -17,9 +17,11 @@ limitations under the License.
 package main
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/spf13/pflag"
@@ -32,13 +34,17 @@ import (
 	_ "k8s.io/component-base/logs/json/register"
 	"k8s.io/klog/v2"
 	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	addonprofilecontroller "sigs.k8s.io/cluster-api-addon-provider-helm/controllers/addonprofile"
+	bundlecontroller "sigs.k8s.io/cluster-api-addon-provider-helm/controllers/chartbundle"
 	chartcontroller "sigs.k8s.io/cluster-api-addon-provider-helm/controllers/helmchartproxy"
 	releasecontroller "sigs.k8s.io/cluster-api-addon-provider-helm/controllers/helmreleaseproxy"
 	"sigs.k8s.io/cluster-api-addon-provider-helm/internal"
 	"sigs.k8s.io/cluster-api-addon-provider-helm/version"
+	addonsv1 "sigs.k8s.io/cluster-api/api/addons/v1beta1"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/controllers/remote"
 	kcpv1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1beta1"
+	expv1 "sigs.k8s.io/cluster-api/exp/api/v1beta1"
 	"sigs.k8s.io/cluster-api/feature"
 	"sigs.k8s.io/cluster-api/util/apiwarnings"
 	"sigs.k8s.io/cluster-api/util/flags"
@@ -47,6 +53,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/yaml"
 )
 
 var (
@@ -63,6 +70,28 @@ var (
 	profilerAddress             string
 	helmChartProxyConcurrency   int
 	helmReleaseProxyConcurrency int
+	warmupCharts                bool
+	listChartVersions           bool
+	chartCacheProxyAddress      string
+	chartCacheProxyDir          string
+	chartCacheProxyRegistries   []string
+	chartCacheProxyMaxSize      int64
+	failoverIdentity            string
+	observeOnly                 bool
+	lightweightStatus           bool
+	prometheusURLs              []string
+	verificationQueryTimeout    time.Duration
+	pauseConfigMap              string
+	auditLogPath                string
+	registryFailureThreshold    int
+	registryCircuitOpenDuration time.Duration
+	stalenessThreshold          time.Duration
+	startupWarmupWindow         time.Duration
+	blastRadiusWarningThreshold int
+	clusterOperationConcurrency int
+	templateObjectKinds         []string
+	printTemplateObjectsRole    bool
+	derivedSecretKeyFile        string
 	syncPeriod                  time.Duration
 	restConfigQPS               float32
 	restConfigBurst             int
@@ -111,6 +140,72 @@ func InitFlags(fs *pflag.FlagSet) {
 	fs.IntVar(&helmReleaseProxyConcurrency, "helm-release-proxy-concurrency", 10,
 		"Number of HelmReleaseProxies to process concurrently.")
 
+	fs.BoolVar(&warmupCharts, "warmup-charts", false,
+		"Download the charts of HelmChartProxies with a pinned version in the background before they are installed on a Cluster.")
+
+	fs.BoolVar(&listChartVersions, "list-chart-versions", false,
+		"List the versions of the OCI charts of HelmChartProxies from their registries and report the version each resolves to and the versions it can be upgraded to in their status. Charts requiring credentials or custom certificates are not listed.")
+
+	fs.StringVar(&chartCacheProxyAddress, "chart-cache-proxy-bind-address", "",
+		"Address the chart cache proxy binds to (e.g. :5001). The proxy is a read-only, pull-through cache of OCI charts that co-located components can pull from over plain HTTP at oci://<proxy address>/<registry host>/<repository>, so that each chart is fetched from the registry once. Only the chart cache proxy allowed registries are proxied, and only if they allow anonymous pulls. If unspecified, the proxy is disabled.")
+
+	fs.StringVar(&chartCacheProxyDir, "chart-cache-proxy-dir", filepath.Join(os.TempDir(), "caaph-chart-cache-proxy"),
+		"Directory the chart cache proxy stores the blobs of charts in.")
+
+	fs.StringSliceVar(&chartCacheProxyRegistries, "chart-cache-proxy-allowed-registries", nil,
+		"Comma-separated list of the hosts, with their port if they have one, of the registries the chart cache proxy serves charts of (e.g. ghcr.io,registry.example.com:5000). Requests for other registries are rejected. Required if the chart cache proxy is enabled.")
+
+	fs.Int64Var(&chartCacheProxyMaxSize, "chart-cache-proxy-max-size", 10<<30,
+		"Maximum total size in bytes of the blobs the chart cache proxy stores. The blobs served the longest time ago are removed once it is exceeded.")
+
+	fs.StringVar(&failoverIdentity, "failover-identity", "",
+		"Identity of this management cluster in the ownership leases of HelmChartProxies with failover enabled. Must be unique across the management clusters sharing workload clusters.")
+
+	fs.BoolVar(&observeOnly, "observe-only", false,
+		"Compute the changes to the Helm releases on workload clusters and report them in the HelmReleaseProxy status without installing, upgrading or uninstalling anything, e.g. to audit the configuration before enforcing it. HelmReleaseProxies are not deleted until the controller enforces changes again.")
+
+	fs.BoolVar(&lightweightStatus, "lightweight-status", false,
+		"Omit the lists of matching Clusters, out of date HelmReleaseProxies and Cluster operations and the true conditions summarized by the Ready condition from the status of HelmChartProxies, to keep them small and reduce writes for very large fleets. The number of matching Clusters is still reported, and the omitted details are exposed as metrics instead.")
+
+	fs.StringSliceVar(&prometheusURLs, "rollout-verification-prometheus-urls", nil,
+		"Comma-separated list of the addresses of the Prometheus APIs the rollout verification queries of HelmChartProxies may be run against (e.g. http://prometheus.monitoring:9090). The rollout verification of HelmChartProxies with any other Prometheus URL fails. If unspecified, rollout verification always fails.")
+
+	fs.DurationVar(&verificationQueryTimeout, "rollout-verification-query-timeout", internal.DefaultRolloutVerificationQueryTimeout,
+		"Timeout of each rollout verification query.")
+
+	fs.StringVar(&pauseConfigMap, "pause-configmap", "",
+		fmt.Sprintf("ConfigMap in the form namespace/name that pauses all changes to HelmReleaseProxies and Helm releases while its %q key is \"true\", e.g. during an emergency change freeze. The status is still updated, and the changes are reported as in observe-only mode. If it is not specified, the controller is never paused.", addonsv1alpha1.GlobalPausePausedKey))
+
+	fs.StringVar(&auditLogPath, "audit-log-path", "",
+		"File to append a JSON line audit record to for every install, upgrade, uninstall and rollback of a Helm release on a workload cluster, or - for stdout. If it is not specified, no audit records are written.")
+
+	fs.IntVar(&registryFailureThreshold, "registry-failure-threshold", 5,
+		"Number of consecutive failed chart fetches from a chart repository or OCI registry after which no charts are fetched from it for the registry circuit open duration, so that reconciles fail fast during an outage. Set to 0 to disable.")
+
+	fs.DurationVar(&registryCircuitOpenDuration, "registry-circuit-open-duration", time.Minute,
+		"Duration for which no charts are fetched from a chart repository or OCI registry after it reached the registry failure threshold.")
+
+	fs.IntVar(&clusterOperationConcurrency, "cluster-operation-concurrency", 0,
+		"Maximum number of Helm operations running at once on a single workload Cluster, queueing the others. Set to 1 to serialize the Helm operations of each Cluster, or to 0 for no limit.")
+
+	fs.StringSliceVar(&templateObjectKinds, "template-object-kinds", nil,
+		"Comma-separated list of the kinds of provider objects, in the Kind.group format (e.g. AWSCluster.infrastructure.cluster.x-k8s.io), read for values templates and upgrade gating. Objects of other kinds are omitted from the template data. If unspecified, objects of any kind permitted by RBAC are read.")
+
+	fs.BoolVar(&printTemplateObjectsRole, "print-template-objects-role", false,
+		"Print the ClusterRole granting read access to the resources of the template object kinds, to replace the caaph-template-objects-role granting access to all provider resources, and exit.")
+
+	fs.StringVar(&derivedSecretKeyFile, "derived-secret-key-file", "",
+		"Path of the file with the key the values of the derivedSecret template function are derived with, e.g. mounted from a Secret. If unspecified, derivedSecret fails.")
+
+	fs.DurationVar(&stalenessThreshold, "reconcile-staleness-threshold", time.Hour,
+		"Duration without a reconcile without error after which the ReconciledRecently condition of a HelmChartProxy or HelmReleaseProxy is marked false, e.g. because of expired credentials. Set to 0 to disable.")
+
+	fs.DurationVar(&startupWarmupWindow, "startup-warmup-window", 0,
+		"Duration after startup over which the reconciles of ready HelmReleaseProxies are spread, so that a restarted controller does not reach out to all workload Clusters at once. HelmReleaseProxies that are not ready are reconciled right away. Set to 0 to disable.")
+
+	fs.IntVar(&blastRadiusWarningThreshold, "blast-radius-warning-threshold", addonsv1alpha1.DefaultBlastRadiusWarningThreshold,
+		"Number of matching Clusters above which the webhook warns about an update of a HelmChartProxy without an upgrade rollout, since it changes the Helm releases of all of them at once. Set to 0 to disable.")
+
 	fs.DurationVar(&syncPeriod, "sync-period", 10*time.Minute,
 		"Minimum interval at which watched resources are reconciled (e.g. 15m)")
 
@@ -139,6 +234,14 @@ func InitFlags(fs *pflag.FlagSet) {
 // +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == renderValuesCommand {
+		if err := runRenderValues(ctrl.SetupSignalHandler(), os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	InitFlags(pflag.CommandLine)
 	klog.InitFlags(flag.CommandLine)
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
@@ -207,28 +310,109 @@ func main() {
 	scheme := mgr.GetScheme()
 	_ = clusterv1.AddToScheme(scheme)
 	_ = kcpv1.AddToScheme(scheme)
+	_ = expv1.AddToScheme(scheme)
+	_ = addonsv1.AddToScheme(scheme)
+
+	kinds, err := internal.ParseTemplateObjectKinds(templateObjectKinds)
+	if err != nil {
+		setupLog.Error(err, "invalid template object kinds")
+		os.Exit(1)
+	}
+	if len(kinds) > 0 || printTemplateObjectsRole {
+		// Resolving the resources of the kinds at startup fails fast on kinds whose CRDs are not installed.
+		role, err := internal.TemplateObjectsClusterRole(mgr.GetRESTMapper(), "caaph-template-objects-role", kinds)
+		if err != nil {
+			setupLog.Error(err, "unable to resolve template object kinds")
+			os.Exit(1)
+		}
+		if printTemplateObjectsRole {
+			out, err := yaml.Marshal(role)
+			if err != nil {
+				setupLog.Error(err, "unable to print template objects role")
+				os.Exit(1)
+			}
+			fmt.Print(string(out))
+			os.Exit(0)
+		}
+	}
+	templateOptions := internal.TemplateOptions{ObjectKinds: internal.NewTemplateObjectKinds(kinds)}
+
+	if derivedSecretKeyFile != "" {
+		key, err := os.ReadFile(derivedSecretKeyFile)
+		if err != nil {
+			setupLog.Error(err, "unable to read derived secret key")
+			os.Exit(1)
+		}
+		templateOptions.DerivedSecretKey = bytes.TrimSpace(key)
+	}
 
 	ctx := ctrl.SetupSignalHandler()
 
+	var globalPause *internal.GlobalPause
+	if pauseConfigMap != "" {
+		key, err := internal.ParseGlobalPauseConfigMap(pauseConfigMap)
+		if err != nil {
+			setupLog.Error(err, "invalid pause ConfigMap")
+			os.Exit(1)
+		}
+		globalPause = &internal.GlobalPause{Reader: mgr.GetClient(), ConfigMap: key}
+	}
+
+	registryBreaker := internal.NewRegistryBreaker(registryFailureThreshold, registryCircuitOpenDuration)
+	helmClient := &internal.HelmClient{RegistryBreaker: registryBreaker}
+	clusterOperations := internal.NewClusterOperationQueue(clusterOperationConcurrency)
+	if auditLogPath != "" {
+		auditLog, err := newAuditLog(auditLogPath)
+		if err != nil {
+			setupLog.Error(err, "unable to open audit log", "path", auditLogPath)
+			os.Exit(1)
+		}
+		helmClient.AuditLog = auditLog
+	}
+
 	if err = (&chartcontroller.HelmChartProxyReconciler{
-		Client:           mgr.GetClient(),
-		Scheme:           scheme,
-		WatchFilterValue: watchFilterValue,
+		Client:             mgr.GetClient(),
+		Scheme:             scheme,
+		Recorder:           mgr.GetEventRecorderFor("helmchartproxy-controller"),
+		HelmClient:         helmClient,
+		WarmupCharts:       warmupCharts,
+		RegistryBreaker:    registryBreaker,
+		ClusterOperations:  clusterOperations,
+		TemplateOptions:    templateOptions,
+		ListChartVersions:  listChartVersions,
+		WatchFilterValue:   watchFilterValue,
+		GlobalPause:        globalPause,
+		StalenessThreshold: stalenessThreshold,
+		LightweightStatus:  lightweightStatus,
+		RolloutVerifier: internal.RolloutVerifier{
+			AllowedPrometheusURLs: prometheusURLs,
+			QueryTimeout:          verificationQueryTimeout,
+		},
 	}).SetupWithManager(ctx, mgr, controller.Options{MaxConcurrentReconciles: helmChartProxyConcurrency}); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "HelmChartProxy")
 		os.Exit(1)
 	}
-	if err = (&addonsv1alpha1.HelmChartProxy{}).SetupWebhookWithManager(mgr); err != nil {
+	if err = (&addonsv1alpha1.HelmChartProxyWebhook{
+		BlastRadiusWarningThreshold: blastRadiusWarningThreshold,
+	}).SetupWebhookWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create webhook", "webhook", "HelmChartProxy")
 		os.Exit(1)
 	}
 	//+kubebuilder:scaffold:builder
 
 	if err = (&releasecontroller.HelmReleaseProxyReconciler{
-		Client:           mgr.GetClient(),
-		Scheme:           scheme,
-		HelmClient:       &internal.HelmClient{},
-		WatchFilterValue: watchFilterValue,
+		Client:              mgr.GetClient(),
+		Scheme:              scheme,
+		Recorder:            mgr.GetEventRecorderFor("helmreleaseproxy-controller"),
+		HelmClient:          helmClient,
+		WatchFilterValue:    watchFilterValue,
+		FailoverIdentity:    failoverIdentity,
+		ObserveOnly:         observeOnly,
+		GlobalPause:         globalPause,
+		StalenessThreshold:  stalenessThreshold,
+		StartupWarmupWindow: startupWarmupWindow,
+		ClusterOperations:   clusterOperations,
+		TemplateObjectKinds: templateOptions.ObjectKinds,
 	}).SetupWithManager(ctx, mgr, controller.Options{MaxConcurrentReconciles: helmReleaseProxyConcurrency}); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "HelmReleaseProxy")
 		os.Exit(1)
@@ -239,6 +423,36 @@ func main() {
 	}
 	//+kubebuilder:scaffold:builder
 
+	if err = (&bundlecontroller.ChartBundleReconciler{
+		Client:           mgr.GetClient(),
+		Scheme:           scheme,
+		WatchFilterValue: watchFilterValue,
+	}).SetupWithManager(ctx, mgr, controller.Options{}); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ChartBundle")
+		os.Exit(1)
+	}
+
+	if err = (&addonprofilecontroller.AddonProfileReconciler{
+		Client:           mgr.GetClient(),
+		Scheme:           scheme,
+		WatchFilterValue: watchFilterValue,
+	}).SetupWithManager(ctx, mgr, controller.Options{}); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "AddonProfile")
+		os.Exit(1)
+	}
+
+	if chartCacheProxyAddress != "" {
+		chartCacheProxy, err := internal.NewChartCacheProxy(chartCacheProxyAddress, chartCacheProxyDir, chartCacheProxyRegistries, chartCacheProxyMaxSize)
+		if err != nil {
+			setupLog.Error(err, "unable to create chart cache proxy")
+			os.Exit(1)
+		}
+		if err := mgr.Add(chartCacheProxy); err != nil {
+			setupLog.Error(err, "unable to add chart cache proxy")
+			os.Exit(1)
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -254,3 +468,22 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// newAuditLog returns an audit log appending to the file at the path, or writing to stdout if the path is -. The actor of
+// the records is the hostname, which is the name of the controller Pod.
+func newAuditLog(path string) (*internal.AuditLog, error) {
+	actor, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+
+	if path == "-" {
+		return internal.NewAuditLog(os.Stdout, actor), nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	return internal.NewAuditLog(f, actor), nil
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package render renders the values of HelmChartProxies for Clusters offline, exactly as the HelmChartProxy controller
+// does, so that values templates can be validated, e.g. in CI, before they are applied to a management cluster.
+package render
+
+import (
+	"bufio"
+	"context"
+	"io"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/cluster-api-addon-provider-helm/controllers/helmchartproxy"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	expv1 "sigs.k8s.io/cluster-api/exp/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// Scheme is the scheme of the objects decoded into their types by ReadObjects.
+var Scheme = runtime.NewScheme()
+
+func init() {
+	_ = clientgoscheme.AddToScheme(Scheme)
+	_ = addonsv1alpha1.AddToScheme(Scheme)
+	_ = clusterv1.AddToScheme(Scheme)
+	_ = expv1.AddToScheme(Scheme)
+}
+
+// ReadObjects decodes the objects of the YAML or JSON documents. Objects of kinds of the Scheme are decoded into their
+// types, and other objects, e.g. the infrastructure and control plane of a Cluster, into unstructured objects.
+func ReadObjects(r io.Reader) ([]client.Object, error) {
+	var objects []client.Object
+	reader := utilyaml.NewYAMLOrJSONDecoder(bufio.NewReader(r), 4096)
+	for {
+		u := &unstructured.Unstructured{}
+		if err := reader.Decode(&u.Object); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objects, nil
+			}
+
+			return nil, errors.Wrap(err, "failed to decode object")
+		}
+		if len(u.Object) == 0 {
+			continue
+		}
+		if u.GetKind() == "" || u.GetAPIVersion() == "" {
+			return nil, errors.Errorf("object %s has no apiVersion or kind", u.GetName())
+		}
+
+		if !Scheme.Recognizes(u.GroupVersionKind()) {
+			objects = append(objects, u)
+			continue
+		}
+		typed, err := Scheme.New(u.GroupVersionKind())
+		if err != nil {
+			return nil, err
+		}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, typed); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode %s %s", u.GetKind(), u.GetName())
+		}
+		object, ok := typed.(client.Object)
+		if !ok {
+			return nil, errors.Errorf("%s %s is not an object", u.GetKind(), u.GetName())
+		}
+		object.GetObjectKind().SetGroupVersionKind(u.GroupVersionKind())
+		objects = append(objects, object)
+	}
+}
+
+// Values renders the values of the HelmChartProxy for the Cluster exactly as the HelmChartProxy controller sets them on
+// the HelmReleaseProxy of the Cluster. The objects the values depend on, e.g. the control plane and infrastructure of
+// the Cluster, Secrets referenced by the template, template library ConfigMaps, ValuesFrom sources, HelmValuesOverrides and
+// ChartSourceDefaults, are read from the objects instead of a management cluster. Objects that are not given are treated
+// as not found.
+func Values(ctx context.Context, helmChartProxy *addonsv1alpha1.HelmChartProxy, cluster *clusterv1.Cluster, objects ...client.Object) (string, error) {
+	// The values template refers to the Cluster by its apiVersion and kind.
+	cluster = cluster.DeepCopy()
+	cluster.SetGroupVersionKind(clusterv1.GroupVersion.WithKind("Cluster"))
+
+	objects = append([]client.Object{cluster}, withoutObject(objects, cluster)...)
+	c := fake.NewClientBuilder().
+		WithScheme(Scheme).
+		WithRESTMapper(restMapperFor(objects)).
+		WithObjects(objects...).
+		Build()
+
+	r := &helmchartproxy.HelmChartProxyReconciler{
+		Client:   c,
+		Scheme:   Scheme,
+		Recorder: &record.FakeRecorder{},
+	}
+
+	return r.RenderValues(ctx, helmChartProxy, cluster)
+}
+
+// withoutObject returns the objects without the given object, so that it is not added to the client twice.
+func withoutObject(objects []client.Object, object client.Object) []client.Object {
+	var filtered []client.Object
+	for _, o := range objects {
+		gvk := o.GetObjectKind().GroupVersionKind()
+		if gvk.GroupKind() == object.GetObjectKind().GroupVersionKind().GroupKind() &&
+			o.GetNamespace() == object.GetNamespace() && o.GetName() == object.GetName() {
+			continue
+		}
+		filtered = append(filtered, o)
+	}
+
+	return filtered
+}
+
+// restMapperFor returns a REST mapper of the kinds of the Scheme and of the unstructured objects, whose kinds are only
+// known from the objects. All kinds are namespaced except for the cluster-scoped kinds of the Scheme.
+func restMapperFor(objects []client.Object) meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	for gvk := range Scheme.AllKnownTypes() {
+		scope := meta.RESTScopeNamespace
+		if clusterScopedKinds[gvk.GroupKind()] {
+			scope = meta.RESTScopeRoot
+		}
+		mapper.Add(gvk, scope)
+	}
+	for _, object := range objects {
+		gvk := object.GetObjectKind().GroupVersionKind()
+		if !Scheme.Recognizes(gvk) {
+			mapper.Add(gvk, meta.RESTScopeNamespace)
+		}
+	}
+
+	return mapper
+}
+
+// clusterScopedKinds are the kinds of the Scheme the values of a HelmChartProxy may depend on that are not namespaced.
+var clusterScopedKinds = map[schema.GroupKind]bool{
+	{Kind: "Namespace"}: true,
+	{Kind: "Node"}:      true,
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package render
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+func TestReadObjects(t *testing.T) {
+	g := NewWithT(t)
+
+	file, err := os.Open("testdata/objects.yaml")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer file.Close()
+
+	objects, err := ReadObjects(file)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(objects).To(HaveLen(6))
+	g.Expect(objects[0]).To(BeAssignableToTypeOf(&addonsv1alpha1.HelmChartProxy{}))
+	g.Expect(objects[1]).To(BeAssignableToTypeOf(&clusterv1.Cluster{}))
+	g.Expect(objects[2]).To(BeAssignableToTypeOf(&unstructured.Unstructured{}))
+	g.Expect(objects[5]).To(BeAssignableToTypeOf(&addonsv1alpha1.HelmValuesOverride{}))
+
+	_, err = ReadObjects(strings.NewReader("metadata:\n  name: test\n"))
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestValues(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	file, err := os.Open("testdata/objects.yaml")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer file.Close()
+
+	objects, err := ReadObjects(file)
+	g.Expect(err).NotTo(HaveOccurred())
+	helmChartProxy := objects[0].(*addonsv1alpha1.HelmChartProxy)
+	cluster := objects[1].(*clusterv1.Cluster)
+
+	values, err := Values(ctx, helmChartProxy, cluster, objects...)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	rendered := map[string]interface{}{}
+	g.Expect(yaml.Unmarshal([]byte(values), &rendered)).To(Succeed())
+	g.Expect(rendered).To(Equal(map[string]interface{}{
+		"cluster":  map[string]interface{}{"name": "workload"},
+		"region":   "eu-west-1",
+		"ipam":     map[string]interface{}{"mode": "eni"},
+		"hubble":   map[string]interface{}{"enabled": false},
+		"operator": map[string]interface{}{"replicas": float64(2)},
+	}))
+
+	// Objects that are not given are not found, as in a management cluster without them.
+	_, err = Values(ctx, helmChartProxy, cluster, []client.Object{objects[0], objects[2], objects[3]}...)
+	g.Expect(err).To(MatchError(ContainSubstring("cilium-library/ipam")))
+}
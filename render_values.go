@@ -0,0 +1,172 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/cluster-api-addon-provider-helm/pkg/render"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// renderValuesCommand is the verb rendering the values of a HelmChartProxy for Clusters offline instead of running the
+// manager, e.g. `manager render-values -f hcp.yaml -f cluster.yaml`.
+const renderValuesCommand = "render-values"
+
+// runRenderValues renders the values of the HelmChartProxy in the files for each Cluster in the files it selects, or for
+// the Cluster named by the --cluster flag, and writes them to out as YAML documents. It fails if the values of a Cluster
+// cannot be rendered or are not valid YAML, as the Helm release of the Cluster would fail to install.
+func runRenderValues(ctx context.Context, args []string, out io.Writer) error {
+	var files []string
+	var helmChartProxyName, clusterName string
+	fs := pflag.NewFlagSet(renderValuesCommand, pflag.ContinueOnError)
+	fs.StringArrayVarP(&files, "file", "f", nil,
+		"File with the HelmChartProxy, the Clusters and the objects the values depend on, e.g. the infrastructure of the Clusters, ValuesFrom sources and HelmValuesOverrides, as YAML documents. Can be repeated, - reads from stdin.")
+	fs.StringVar(&helmChartProxyName, "helmchartproxy", "",
+		"Name of the HelmChartProxy to render the values of. Required if the files contain more than one HelmChartProxy.")
+	fs.StringVar(&clusterName, "cluster", "",
+		"Name of the Cluster to render the values for, even if the HelmChartProxy does not select it. If unspecified, the values are rendered for every Cluster the HelmChartProxy selects.")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return nil
+		}
+
+		return err
+	}
+	if len(files) == 0 {
+		return errors.New("at least one file must be specified with --file")
+	}
+
+	var objects []client.Object
+	for _, file := range files {
+		fileObjects, err := readObjectsFile(file)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, fileObjects...)
+	}
+
+	helmChartProxy, err := helmChartProxyToRender(objects, helmChartProxyName)
+	if err != nil {
+		return err
+	}
+	clusters, err := clustersToRender(objects, helmChartProxy, clusterName)
+	if err != nil {
+		return err
+	}
+
+	for i, cluster := range clusters {
+		values, err := render.Values(ctx, helmChartProxy, cluster, objects...)
+		if err != nil {
+			return errors.Wrapf(err, "failed to render values of HelmChartProxy %s for Cluster %s", helmChartProxy.Name, cluster.Name)
+		}
+		if err := yaml.Unmarshal([]byte(values), &map[string]interface{}{}); err != nil {
+			return errors.Wrapf(err, "values of HelmChartProxy %s rendered for Cluster %s are not valid YAML", helmChartProxy.Name, cluster.Name)
+		}
+
+		if i > 0 {
+			fmt.Fprintln(out, "---")
+		}
+		fmt.Fprintf(out, "# Values of HelmChartProxy %s/%s for Cluster %s/%s\n%s", helmChartProxy.Namespace, helmChartProxy.Name, cluster.Namespace, cluster.Name, values)
+		if len(values) > 0 && values[len(values)-1] != '\n' {
+			fmt.Fprintln(out)
+		}
+	}
+
+	return nil
+}
+
+// readObjectsFile reads the objects of the file, or of stdin if it is "-".
+func readObjectsFile(file string) ([]client.Object, error) {
+	if file == "-" {
+		return render.ReadObjects(os.Stdin)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	objects, err := render.ReadObjects(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read objects of file %s", file)
+	}
+
+	return objects, nil
+}
+
+// helmChartProxyToRender returns the HelmChartProxy with the name, or the only HelmChartProxy if the name is empty.
+func helmChartProxyToRender(objects []client.Object, name string) (*addonsv1alpha1.HelmChartProxy, error) {
+	var helmChartProxies []*addonsv1alpha1.HelmChartProxy
+	for _, object := range objects {
+		if helmChartProxy, ok := object.(*addonsv1alpha1.HelmChartProxy); ok && (name == "" || helmChartProxy.Name == name) {
+			helmChartProxies = append(helmChartProxies, helmChartProxy)
+		}
+	}
+
+	switch {
+	case len(helmChartProxies) == 1:
+		return helmChartProxies[0], nil
+	case len(helmChartProxies) == 0 && name != "":
+		return nil, errors.Errorf("files contain no HelmChartProxy %s", name)
+	case len(helmChartProxies) == 0:
+		return nil, errors.New("files contain no HelmChartProxy")
+	default:
+		return nil, errors.New("files contain more than one HelmChartProxy, specify one with --helmchartproxy")
+	}
+}
+
+// clustersToRender returns the Cluster with the name in the namespace of the HelmChartProxy, or the Clusters in its
+// namespace it selects if the name is empty.
+func clustersToRender(objects []client.Object, helmChartProxy *addonsv1alpha1.HelmChartProxy, name string) ([]*clusterv1.Cluster, error) {
+	selector, err := metav1.LabelSelectorAsSelector(&helmChartProxy.Spec.ClusterSelector)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse cluster selector of HelmChartProxy %s", helmChartProxy.Name)
+	}
+
+	var clusters []*clusterv1.Cluster
+	for _, object := range objects {
+		cluster, ok := object.(*clusterv1.Cluster)
+		if !ok || cluster.Namespace != helmChartProxy.Namespace {
+			continue
+		}
+		if (name == "" && selector.Matches(labels.Set(cluster.Labels))) || (name != "" && cluster.Name == name) {
+			clusters = append(clusters, cluster)
+		}
+	}
+
+	if len(clusters) == 0 {
+		if name != "" {
+			return nil, errors.Errorf("files contain no Cluster %s in namespace %s", name, helmChartProxy.Namespace)
+		}
+
+		return nil, errors.Errorf("files contain no Cluster selected by HelmChartProxy %s", helmChartProxy.Name)
+	}
+
+	return clusters, nil
+}
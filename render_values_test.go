@@ -0,0 +1,89 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+const renderValuesHelmChartProxy = `apiVersion: addons.cluster.x-k8s.io/v1alpha1
+kind: HelmChartProxy
+metadata:
+  name: nginx
+  namespace: default
+spec:
+  clusterSelector:
+    matchLabels:
+      ingress: nginx
+  repoURL: https://kubernetes.github.io/ingress-nginx
+  chartName: ingress-nginx
+  valuesTemplate: |
+    clusterName: {{ .Cluster.metadata.name }}
+`
+
+const renderValuesClusters = `apiVersion: cluster.x-k8s.io/v1beta1
+kind: Cluster
+metadata:
+  name: cluster-a
+  namespace: default
+  labels:
+    ingress: nginx
+---
+apiVersion: cluster.x-k8s.io/v1beta1
+kind: Cluster
+metadata:
+  name: cluster-b
+  namespace: default
+`
+
+func TestRunRenderValues(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	helmChartProxyFile := filepath.Join(dir, "hcp.yaml")
+	clustersFile := filepath.Join(dir, "clusters.yaml")
+	g.Expect(os.WriteFile(helmChartProxyFile, []byte(renderValuesHelmChartProxy), 0o600)).To(Succeed())
+	g.Expect(os.WriteFile(clustersFile, []byte(renderValuesClusters), 0o600)).To(Succeed())
+
+	// The values are rendered for the selected Clusters.
+	out := &bytes.Buffer{}
+	g.Expect(runRenderValues(ctx, []string{"-f", helmChartProxyFile, "-f", clustersFile}, out)).To(Succeed())
+	g.Expect(out.String()).To(Equal("# Values of HelmChartProxy default/nginx for Cluster default/cluster-a\nclusterName: cluster-a\n"))
+
+	// A named Cluster is rendered even if it is not selected.
+	out.Reset()
+	g.Expect(runRenderValues(ctx, []string{"-f", helmChartProxyFile, "-f", clustersFile, "--cluster", "cluster-b"}, out)).To(Succeed())
+	g.Expect(out.String()).To(ContainSubstring("clusterName: cluster-b"))
+
+	g.Expect(runRenderValues(ctx, nil, out)).To(MatchError(ContainSubstring("--file")))
+	g.Expect(runRenderValues(ctx, []string{"-f", clustersFile}, out)).To(MatchError("files contain no HelmChartProxy"))
+	g.Expect(runRenderValues(ctx, []string{"-f", helmChartProxyFile}, out)).To(MatchError(ContainSubstring("no Cluster selected")))
+	g.Expect(runRenderValues(ctx, []string{"-f", helmChartProxyFile, "-f", clustersFile, "--helmchartproxy", "other"}, out)).To(MatchError(ContainSubstring("no HelmChartProxy other")))
+
+	// Values that are not valid YAML fail, as the Helm release would.
+	invalidFile := filepath.Join(dir, "invalid.yaml")
+	invalid := bytes.Replace([]byte(renderValuesHelmChartProxy), []byte("clusterName: {{"), []byte("clusterName: [{{"), 1)
+	g.Expect(os.WriteFile(invalidFile, invalid, 0o600)).To(Succeed())
+	g.Expect(runRenderValues(ctx, []string{"-f", invalidFile, "-f", clustersFile}, out)).To(MatchError(ContainSubstring("not valid YAML")))
+}
@@ -0,0 +1,437 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package harness runs the HelmChartProxy and HelmReleaseProxy controllers and webhooks against an envtest management
+// cluster with fake workload Clusters, so that integrators and ClusterClass authors can test their HelmChartProxy specs
+// and values templates programmatically. The Helm releases are recorded by a FakeHelmClient instead of being installed.
+//
+// The envtest binaries must be installed, with KUBEBUILDER_ASSETS set to their directory, e.g. with
+// `setup-envtest use -p path`.
+package harness
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"go/build"
+	"net"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/utils/ptr"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/cluster-api-addon-provider-helm/controllers/chartbundle"
+	"sigs.k8s.io/cluster-api-addon-provider-helm/controllers/helmchartproxy"
+	"sigs.k8s.io/cluster-api-addon-provider-helm/controllers/helmreleaseproxy"
+	"sigs.k8s.io/cluster-api-addon-provider-helm/internal"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/kubeconfig"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/config"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// clusterAPIModule is the module of the Cluster API, whose Cluster CRD is installed in the management cluster.
+const clusterAPIModule = "sigs.k8s.io/cluster-api"
+
+// Options are the options of an Environment.
+type Options struct {
+	// CRDPaths are the paths of the CRD files or directories installed in the management cluster in addition to the CRDs
+	// of the provider. They must contain the Cluster CRD of the Cluster API. If it is empty, the Cluster CRD of the Cluster
+	// API module in the module cache is installed.
+	CRDPaths []string
+
+	// DisableWebhooks does not install the webhooks of the provider, so that HelmChartProxies are neither defaulted nor
+	// validated when they are created.
+	DisableWebhooks bool
+
+	// SyncPeriod is the period after which all objects are reconciled again. It defaults to the default of
+	// controller-runtime.
+	SyncPeriod *time.Duration
+}
+
+// Environment is a management cluster running the controllers of the provider, with workload Clusters whose Helm
+// releases are recorded by a FakeHelmClient.
+type Environment struct {
+	// Client is a client of the management cluster.
+	client.Client
+
+	// Config is the REST config of the management cluster.
+	Config *rest.Config
+
+	// HelmClient records the Helm releases the controllers install on the workload Clusters.
+	HelmClient *FakeHelmClient
+
+	testEnv *envtest.Environment
+	cancel  context.CancelFunc
+	done    chan error
+
+	mu sync.Mutex
+	// endpoints are the API server endpoints of the workload Clusters.
+	endpoints []*httptest.Server
+}
+
+// NewEnvironment starts a management cluster running the controllers and webhooks of the provider. It must be stopped
+// with Stop.
+func NewEnvironment(ctx context.Context, opts Options) (*Environment, error) {
+	root, err := moduleRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	crdPaths := opts.CRDPaths
+	if len(crdPaths) == 0 {
+		clusterCRD, err := ClusterAPICRDPath()
+		if err != nil {
+			return nil, err
+		}
+		crdPaths = []string{clusterCRD}
+	}
+	testEnv := &envtest.Environment{
+		CRDDirectoryPaths:     append([]string{filepath.Join(root, "config", "crd", "bases")}, crdPaths...),
+		ErrorIfCRDPathMissing: true,
+	}
+	if !opts.DisableWebhooks {
+		testEnv.WebhookInstallOptions = envtest.WebhookInstallOptions{
+			Paths: []string{filepath.Join(root, "config", "webhook")},
+		}
+	}
+
+	cfg, err := testEnv.Start()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start management cluster")
+	}
+
+	env, err := startEnvironment(ctx, testEnv, cfg, opts)
+	if err != nil {
+		_ = testEnv.Stop()
+		return nil, err
+	}
+
+	return env, nil
+}
+
+// startEnvironment starts the controllers and webhooks of the provider against the started management cluster.
+func startEnvironment(ctx context.Context, testEnv *envtest.Environment, cfg *rest.Config, opts Options) (*Environment, error) {
+	scheme := k8sruntime.NewScheme()
+	for _, addToScheme := range []func(*k8sruntime.Scheme) error{clientgoscheme.AddToScheme, addonsv1alpha1.AddToScheme, clusterv1.AddToScheme} {
+		if err := addToScheme(scheme); err != nil {
+			return nil, err
+		}
+	}
+
+	webhookOptions := testEnv.WebhookInstallOptions
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+		Scheme: scheme,
+		WebhookServer: webhook.NewServer(webhook.Options{
+			Host:    webhookOptions.LocalServingHost,
+			Port:    webhookOptions.LocalServingPort,
+			CertDir: webhookOptions.LocalServingCertDir,
+		}),
+		Cache:                  cache.Options{SyncPeriod: opts.SyncPeriod},
+		Metrics:                metricsserver.Options{BindAddress: "0"},
+		HealthProbeBindAddress: "0",
+		// Tests may run several Environments in the same process.
+		Controller: config.Controller{SkipNameValidation: ptr.To(true)},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create manager")
+	}
+
+	helmClient := NewFakeHelmClient()
+	clusterOperations := internal.NewClusterOperationQueue(0)
+	ctx, cancel := context.WithCancel(ctx)
+	env := &Environment{
+		Client:     mgr.GetClient(),
+		Config:     cfg,
+		HelmClient: helmClient,
+		testEnv:    testEnv,
+		cancel:     cancel,
+		done:       make(chan error, 1),
+	}
+
+	if err := (&helmchartproxy.HelmChartProxyReconciler{
+		Client:            mgr.GetClient(),
+		Scheme:            scheme,
+		Recorder:          mgr.GetEventRecorderFor("helmchartproxy-controller"),
+		HelmClient:        helmClient,
+		ClusterOperations: clusterOperations,
+	}).SetupWithManager(ctx, mgr, controller.Options{}); err != nil {
+		cancel()
+		return nil, errors.Wrap(err, "failed to set up HelmChartProxy controller")
+	}
+	if err := (&helmreleaseproxy.HelmReleaseProxyReconciler{
+		Client:            mgr.GetClient(),
+		Scheme:            scheme,
+		Recorder:          mgr.GetEventRecorderFor("helmreleaseproxy-controller"),
+		HelmClient:        helmClient,
+		ClusterOperations: clusterOperations,
+	}).SetupWithManager(ctx, mgr, controller.Options{}); err != nil {
+		cancel()
+		return nil, errors.Wrap(err, "failed to set up HelmReleaseProxy controller")
+	}
+	if err := (&chartbundle.ChartBundleReconciler{
+		Client: mgr.GetClient(),
+		Scheme: scheme,
+	}).SetupWithManager(ctx, mgr, controller.Options{}); err != nil {
+		cancel()
+		return nil, errors.Wrap(err, "failed to set up ChartBundle controller")
+	}
+	if !opts.DisableWebhooks {
+		if err := (&addonsv1alpha1.HelmChartProxyWebhook{BlastRadiusWarningThreshold: addonsv1alpha1.DefaultBlastRadiusWarningThreshold}).SetupWebhookWithManager(mgr); err != nil {
+			cancel()
+			return nil, errors.Wrap(err, "failed to set up HelmChartProxy webhook")
+		}
+		if err := (&addonsv1alpha1.HelmReleaseProxy{}).SetupWebhookWithManager(mgr); err != nil {
+			cancel()
+			return nil, errors.Wrap(err, "failed to set up HelmReleaseProxy webhook")
+		}
+	}
+
+	go func() {
+		env.done <- mgr.Start(ctx)
+	}()
+
+	if !mgr.GetCache().WaitForCacheSync(ctx) {
+		_ = env.stopManager()
+		return nil, errors.New("failed to sync cache of management cluster")
+	}
+	if !opts.DisableWebhooks {
+		if err := waitForWebhookServer(ctx, webhookOptions.LocalServingHost, webhookOptions.LocalServingPort); err != nil {
+			_ = env.stopManager()
+			return nil, err
+		}
+	}
+
+	return env, nil
+}
+
+// Stop stops the controllers, the workload Clusters and the management cluster.
+func (e *Environment) Stop() error {
+	managerErr := e.stopManager()
+
+	e.mu.Lock()
+	for _, endpoint := range e.endpoints {
+		endpoint.Close()
+	}
+	e.endpoints = nil
+	e.mu.Unlock()
+
+	if err := e.testEnv.Stop(); err != nil {
+		return errors.Wrap(err, "failed to stop management cluster")
+	}
+
+	return managerErr
+}
+
+// stopManager stops the manager and waits for it to return.
+func (e *Environment) stopManager() error {
+	e.cancel()
+	if err := <-e.done; err != nil {
+		return errors.Wrap(err, "failed to run manager")
+	}
+
+	return nil
+}
+
+// CreateWorkloadClusters creates n workload Clusters with the labels in the namespace, named workload-cluster-0 to
+// workload-cluster-<n-1>. See CreateWorkloadCluster.
+func (e *Environment) CreateWorkloadClusters(ctx context.Context, namespace string, n int, labels map[string]string) ([]*clusterv1.Cluster, error) {
+	clusters := make([]*clusterv1.Cluster, 0, n)
+	for i := range n {
+		cluster, err := e.CreateWorkloadCluster(ctx, namespace, fmt.Sprintf("workload-cluster-%d", i), labels)
+		if err != nil {
+			return nil, err
+		}
+		clusters = append(clusters, cluster)
+	}
+
+	return clusters, nil
+}
+
+// CreateWorkloadCluster creates a workload Cluster with the labels, creating its namespace if it does not exist. The
+// Cluster has an initialized control plane and a kubeconfig Secret, whose API server is an endpoint of its own forwarding
+// to the management cluster, so that the controllers reach each workload Cluster as a separate cluster.
+func (e *Environment) CreateWorkloadCluster(ctx context.Context, namespace, name string, labels map[string]string) (*clusterv1.Cluster, error) {
+	if err := e.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return nil, errors.Wrapf(err, "failed to create namespace %s", namespace)
+	}
+
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+	}
+	if err := e.Create(ctx, cluster); err != nil {
+		return nil, errors.Wrapf(err, "failed to create Cluster %s", name)
+	}
+
+	endpoint, err := e.newClusterEndpoint()
+	if err != nil {
+		return nil, err
+	}
+	e.HelmClient.registerCluster(endpoint, types.NamespacedName{Namespace: namespace, Name: name})
+
+	data, err := kubeconfigFor(name, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.Create(ctx, kubeconfig.GenerateSecret(cluster, data)); err != nil {
+		return nil, errors.Wrapf(err, "failed to create kubeconfig Secret of Cluster %s", name)
+	}
+
+	conditions.MarkTrue(cluster, clusterv1.InfrastructureReadyCondition)
+	conditions.MarkTrue(cluster, clusterv1.ControlPlaneInitializedCondition)
+	conditions.MarkTrue(cluster, clusterv1.ControlPlaneReadyCondition)
+	cluster.Status.InfrastructureReady = true
+	cluster.Status.ControlPlaneReady = true
+	cluster.Status.Phase = string(clusterv1.ClusterPhaseProvisioned)
+	if err := e.Status().Update(ctx, cluster); err != nil {
+		return nil, errors.Wrapf(err, "failed to update status of Cluster %s", name)
+	}
+
+	return cluster, nil
+}
+
+// HelmReleaseProxies returns the HelmReleaseProxies of the HelmChartProxy.
+func (e *Environment) HelmReleaseProxies(ctx context.Context, helmChartProxy *addonsv1alpha1.HelmChartProxy) ([]addonsv1alpha1.HelmReleaseProxy, error) {
+	list := &addonsv1alpha1.HelmReleaseProxyList{}
+	if err := e.List(ctx, list, client.InNamespace(helmChartProxy.Namespace), client.MatchingLabels{addonsv1alpha1.HelmChartProxyLabelName: helmChartProxy.Name}); err != nil {
+		return nil, err
+	}
+
+	return list.Items, nil
+}
+
+// newClusterEndpoint starts an endpoint forwarding to the API server of the management cluster with its credentials, and
+// returns its URL.
+func (e *Environment) newClusterEndpoint() (string, error) {
+	target, err := url.Parse(e.Config.Host)
+	if err != nil {
+		return "", err
+	}
+	transport, err := rest.TransportFor(e.Config)
+	if err != nil {
+		return "", err
+	}
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.Transport = transport
+	// Watches are streamed to the controllers as they happen.
+	proxy.FlushInterval = -1
+
+	endpoint := httptest.NewServer(proxy)
+	e.mu.Lock()
+	e.endpoints = append(e.endpoints, endpoint)
+	e.mu.Unlock()
+
+	return endpoint.URL, nil
+}
+
+// kubeconfigFor returns a kubeconfig of the workload Cluster with the API server endpoint. The endpoint authenticates to
+// the management cluster, so the kubeconfig has no credentials.
+func kubeconfigFor(clusterName, endpoint string) ([]byte, error) {
+	contextName := fmt.Sprintf("%s-admin@%s", clusterName, clusterName)
+	cfg := clientcmdapi.NewConfig()
+	cfg.Clusters[clusterName] = &clientcmdapi.Cluster{Server: endpoint}
+	cfg.AuthInfos[clusterName+"-admin"] = &clientcmdapi.AuthInfo{}
+	cfg.Contexts[contextName] = &clientcmdapi.Context{Cluster: clusterName, AuthInfo: clusterName + "-admin"}
+	cfg.CurrentContext = contextName
+
+	return clientcmd.Write(*cfg)
+}
+
+// waitForWebhookServer waits until the webhook server accepts TLS connections.
+func waitForWebhookServer(ctx context.Context, host string, port int) error {
+	dialer := &net.Dialer{Timeout: time.Second}
+	address := net.JoinHostPort(host, fmt.Sprint(port))
+	for {
+		conn, err := tls.DialWithDialer(dialer, "tcp", address, &tls.Config{InsecureSkipVerify: true}) //nolint:gosec // The webhook server uses a self-signed certificate.
+		if err == nil {
+			return conn.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(err, "webhook server did not become ready")
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// moduleRoot returns the root directory of the source of the provider module, which holds its CRDs and webhook
+// configurations.
+func moduleRoot() (string, error) {
+	_, file, _, ok := runtime.Caller(0)
+	if !ok {
+		return "", errors.New("failed to locate the source of the harness")
+	}
+
+	return filepath.Join(filepath.Dir(file), "..", ".."), nil
+}
+
+// ClusterAPICRDPath returns the path of the Cluster CRD of the Cluster API module the test binary is built with in the
+// module cache.
+func ClusterAPICRDPath() (string, error) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "", errors.New("failed to read build info of the test binary")
+	}
+	version := ""
+	for _, dep := range info.Deps {
+		if dep.Path != clusterAPIModule {
+			continue
+		}
+		version = dep.Version
+		if dep.Replace != nil {
+			version = dep.Replace.Version
+		}
+	}
+	if version == "" {
+		return "", errors.Errorf("test binary does not depend on %s", clusterAPIModule)
+	}
+
+	modCache := os.Getenv("GOMODCACHE")
+	if modCache == "" {
+		modCache = filepath.Join(filepath.SplitList(build.Default.GOPATH)[0], "pkg", "mod")
+	}
+	path := filepath.Join(modCache, clusterAPIModule+"@"+version, "config", "crd", "bases", "cluster.x-k8s.io_clusters.yaml")
+	if _, err := os.Stat(path); err != nil {
+		return "", errors.Wrapf(err, "failed to find Cluster CRD of %s@%s, set Options.CRDPaths instead", clusterAPIModule, version)
+	}
+
+	return path, nil
+}
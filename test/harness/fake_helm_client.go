@@ -0,0 +1,361 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harness
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart"
+	helmRelease "helm.sh/helm/v3/pkg/release"
+	helmDriver "helm.sh/helm/v3/pkg/storage/driver"
+	helmTime "helm.sh/helm/v3/pkg/time"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/cluster-api-addon-provider-helm/internal"
+	"sigs.k8s.io/yaml"
+)
+
+var _ internal.Client = &FakeHelmClient{}
+
+// FakeHelmClient is an in-memory Helm client that records the Helm releases the controllers install on the workload
+// Clusters instead of installing them. Releases keep their history of revisions, and their values are the values of the
+// HelmReleaseProxy, i.e. the values template of the HelmChartProxy rendered for the Cluster. It is safe for concurrent use.
+type FakeHelmClient struct {
+	mu sync.Mutex
+
+	// clusters are the workload Clusters by the host of their REST config.
+	clusters map[string]types.NamespacedName
+
+	// releases are the revisions of the Helm releases of each workload Cluster, oldest first.
+	releases map[releaseKey][]*helmRelease.Release
+
+	// installErrors are the errors returned for installs and upgrades on each workload Cluster.
+	installErrors map[types.NamespacedName]error
+
+	// generated counts the generated release names.
+	generated int
+}
+
+// releaseKey identifies a Helm release on a workload Cluster.
+type releaseKey struct {
+	cluster   types.NamespacedName
+	namespace string
+	name      string
+}
+
+// NewFakeHelmClient returns a FakeHelmClient without releases.
+func NewFakeHelmClient() *FakeHelmClient {
+	return &FakeHelmClient{
+		clusters:      map[string]types.NamespacedName{},
+		releases:      map[releaseKey][]*helmRelease.Release{},
+		installErrors: map[types.NamespacedName]error{},
+	}
+}
+
+// registerCluster registers the host of the REST config of a workload Cluster, so that calls without a Cluster reference,
+// e.g. listing the releases to discover, are attributed to the Cluster.
+func (c *FakeHelmClient) registerCluster(host string, cluster types.NamespacedName) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.clusters[host] = cluster
+}
+
+// FailInstalls makes installs and upgrades of Helm releases on the workload Cluster fail with the error, e.g. to test the
+// conditions of HelmChartProxies. A nil error lets them succeed again.
+func (c *FakeHelmClient) FailInstalls(cluster types.NamespacedName, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err == nil {
+		delete(c.installErrors, cluster)
+		return
+	}
+	c.installErrors[cluster] = err
+}
+
+// Release returns the last revision of the Helm release on the workload Cluster, or nil if it is not installed.
+func (c *FakeHelmClient) Release(cluster types.NamespacedName, releaseNamespace, releaseName string) *helmRelease.Release {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return copyRelease(c.last(releaseKey{cluster: cluster, namespace: releaseNamespace, name: releaseName}))
+}
+
+// History returns the revisions of the Helm release on the workload Cluster, oldest first.
+func (c *FakeHelmClient) History(cluster types.NamespacedName, releaseNamespace, releaseName string) []*helmRelease.Release {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	revisions := c.releases[releaseKey{cluster: cluster, namespace: releaseNamespace, name: releaseName}]
+	history := make([]*helmRelease.Release, 0, len(revisions))
+	for _, release := range revisions {
+		history = append(history, copyRelease(release))
+	}
+
+	return history
+}
+
+// Releases returns the last revision of each Helm release on the workload Cluster, sorted by namespace and name.
+func (c *FakeHelmClient) Releases(cluster types.NamespacedName) []*helmRelease.Release {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.list(cluster, "")
+}
+
+// InstallOrUpgradeHelmRelease installs the Helm release of the spec, or upgrades it to a new revision if its chart or
+// values changed.
+func (c *FakeHelmClient) InstallOrUpgradeHelmRelease(_ context.Context, restConfig *rest.Config, _, _ string, _ internal.RepositoryAuth, spec addonsv1alpha1.HelmReleaseProxySpec) (*helmRelease.Release, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cluster := c.clusterOf(restConfig, spec)
+	if err := c.installErrors[cluster]; err != nil {
+		return nil, err
+	}
+
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(spec.Values), &values); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse values of release %s", spec.ReleaseName)
+	}
+
+	if spec.ReleaseName == "" {
+		c.generated++
+		spec.ReleaseName = fmt.Sprintf("%s-%d", spec.ChartName, c.generated)
+	}
+	key := releaseKey{cluster: cluster, namespace: spec.ReleaseNamespace, name: spec.ReleaseName}
+	existing := c.last(key)
+	if existing != nil && existing.Chart.Metadata.Version == spec.Version && equality.Semantic.DeepEqual(existing.Config, values) {
+		return copyRelease(existing), nil
+	}
+
+	now := helmTime.Now()
+	release := &helmRelease.Release{
+		Name:      spec.ReleaseName,
+		Namespace: spec.ReleaseNamespace,
+		Version:   1,
+		Chart:     &chart.Chart{Metadata: &chart.Metadata{Name: spec.ChartName, Version: spec.Version}},
+		Config:    values,
+		Labels:    spec.ReleaseLabels,
+		Info: &helmRelease.Info{
+			FirstDeployed: now,
+			LastDeployed:  now,
+			Status:        helmRelease.StatusDeployed,
+			Description:   "Install complete",
+		},
+	}
+	if existing != nil {
+		existing.Info.Status = helmRelease.StatusSuperseded
+		release.Version = existing.Version + 1
+		release.Info.FirstDeployed = existing.Info.FirstDeployed
+		release.Info.Description = "Upgrade complete"
+	}
+	c.releases[key] = append(c.releases[key], release)
+
+	return copyRelease(release), nil
+}
+
+// DiffHelmRelease returns the existing Helm release of the spec and a description of the install or upgrade that
+// InstallOrUpgradeHelmRelease would perform, if any.
+func (c *FakeHelmClient) DiffHelmRelease(_ context.Context, restConfig *rest.Config, _, _ string, _ internal.RepositoryAuth, spec addonsv1alpha1.HelmReleaseProxySpec) (*helmRelease.Release, string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	existing := c.last(c.keyOf(restConfig, spec))
+	if existing == nil {
+		return nil, fmt.Sprintf("install chart %s version %s", spec.ChartName, spec.Version), nil
+	}
+	if existing.Chart.Metadata.Version != spec.Version {
+		return copyRelease(existing), fmt.Sprintf("upgrade chart %s from version %s to %s", spec.ChartName, existing.Chart.Metadata.Version, spec.Version), nil
+	}
+
+	return copyRelease(existing), "", nil
+}
+
+// GetHelmRelease returns the last revision of the Helm release of the spec.
+func (c *FakeHelmClient) GetHelmRelease(_ context.Context, restConfig *rest.Config, spec addonsv1alpha1.HelmReleaseProxySpec) (*helmRelease.Release, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	release := c.last(c.keyOf(restConfig, spec))
+	if release == nil {
+		return nil, helmDriver.ErrReleaseNotFound
+	}
+
+	return copyRelease(release), nil
+}
+
+// UninstallHelmRelease removes the Helm release of the spec and its history.
+func (c *FakeHelmClient) UninstallHelmRelease(_ context.Context, restConfig *rest.Config, spec addonsv1alpha1.HelmReleaseProxySpec) (*helmRelease.UninstallReleaseResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := c.keyOf(restConfig, spec)
+	release := c.last(key)
+	if release == nil {
+		return nil, helmDriver.ErrReleaseNotFound
+	}
+	delete(c.releases, key)
+	release.Info.Status = helmRelease.StatusUninstalled
+
+	return &helmRelease.UninstallReleaseResponse{Release: copyRelease(release)}, nil
+}
+
+// RollbackHelmRelease rolls the Helm release of the spec back to the revision, as a new revision.
+func (c *FakeHelmClient) RollbackHelmRelease(_ context.Context, restConfig *rest.Config, spec addonsv1alpha1.HelmReleaseProxySpec, revision int) (*helmRelease.Release, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := c.keyOf(restConfig, spec)
+	existing := c.last(key)
+	if existing == nil {
+		return nil, helmDriver.ErrReleaseNotFound
+	}
+	for _, target := range c.releases[key] {
+		if target.Version != revision {
+			continue
+		}
+
+		release := copyRelease(target)
+		release.Version = existing.Version + 1
+		release.Info.LastDeployed = helmTime.Now()
+		release.Info.Status = helmRelease.StatusDeployed
+		release.Info.Description = fmt.Sprintf("Rollback to %d", revision)
+		existing.Info.Status = helmRelease.StatusSuperseded
+		c.releases[key] = append(c.releases[key], release)
+
+		return copyRelease(release), nil
+	}
+
+	return nil, errors.Errorf("release %s has no revision %d", spec.ReleaseName, revision)
+}
+
+// TestHelmRelease returns the last revision of the Helm release of the spec. Charts installed by the FakeHelmClient have
+// no tests.
+func (c *FakeHelmClient) TestHelmRelease(ctx context.Context, restConfig *rest.Config, spec addonsv1alpha1.HelmReleaseProxySpec) (*helmRelease.Release, error) {
+	return c.GetHelmRelease(ctx, restConfig, spec)
+}
+
+// GetHelmReleaseProgress returns no progress, as the resources of releases installed by the FakeHelmClient are not
+// created.
+func (c *FakeHelmClient) GetHelmReleaseProgress(_ context.Context, _ *rest.Config, _ addonsv1alpha1.HelmReleaseProxySpec) (*addonsv1alpha1.ReleaseProgress, error) {
+	return nil, nil
+}
+
+// ReconcileHelmReleaseDrift returns no drifted resources, as the resources of releases installed by the FakeHelmClient
+// are not created.
+func (c *FakeHelmClient) ReconcileHelmReleaseDrift(_ context.Context, _ *rest.Config, _ addonsv1alpha1.HelmReleaseProxySpec, _ bool) ([]string, error) {
+	return nil, nil
+}
+
+// LabelReleaseResources does nothing, as the resources of releases installed by the FakeHelmClient are not created.
+func (c *FakeHelmClient) LabelReleaseResources(_ context.Context, _ *rest.Config, _ addonsv1alpha1.HelmReleaseProxySpec, _ int, _ map[string]string) error {
+	return nil
+}
+
+// ListHelmReleases returns the last revision of each Helm release on the workload Cluster in the release namespace of the
+// spec, or in all namespaces if it is empty.
+func (c *FakeHelmClient) ListHelmReleases(_ context.Context, restConfig *rest.Config, spec addonsv1alpha1.HelmReleaseProxySpec) ([]*helmRelease.Release, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.list(c.clusterOf(restConfig, spec), spec.ReleaseNamespace), nil
+}
+
+// GetChartSBOMs returns no SBOMs.
+func (c *FakeHelmClient) GetChartSBOMs(_ context.Context, _ addonsv1alpha1.HelmReleaseProxySpec, _, _ string) ([]addonsv1alpha1.SBOMReference, error) {
+	return nil, nil
+}
+
+// GetChartSBOM returns an error, as charts have no SBOMs.
+func (c *FakeHelmClient) GetChartSBOM(_ context.Context, spec addonsv1alpha1.HelmReleaseProxySpec, _, _ string, sbom addonsv1alpha1.SBOMReference, _ int64) ([]byte, error) {
+	return nil, errors.Errorf("chart %s has no SBOM %s", spec.ChartName, sbom.Digest)
+}
+
+// ResolveChartDigest returns the digest the chart is pinned to, as charts are not pulled from a registry.
+func (c *FakeHelmClient) ResolveChartDigest(_ context.Context, spec addonsv1alpha1.HelmReleaseProxySpec, _, _ string) (string, error) {
+	return spec.Digest, nil
+}
+
+// clusterOf returns the workload Cluster of the REST config, or the Cluster referenced by the spec if the host of the REST
+// config was not registered.
+func (c *FakeHelmClient) clusterOf(restConfig *rest.Config, spec addonsv1alpha1.HelmReleaseProxySpec) types.NamespacedName {
+	if restConfig != nil {
+		if cluster, ok := c.clusters[restConfig.Host]; ok {
+			return cluster
+		}
+	}
+
+	return types.NamespacedName{Namespace: spec.ClusterRef.Namespace, Name: spec.ClusterRef.Name}
+}
+
+// keyOf returns the key of the Helm release of the spec on the workload Cluster of the REST config.
+func (c *FakeHelmClient) keyOf(restConfig *rest.Config, spec addonsv1alpha1.HelmReleaseProxySpec) releaseKey {
+	return releaseKey{cluster: c.clusterOf(restConfig, spec), namespace: spec.ReleaseNamespace, name: spec.ReleaseName}
+}
+
+// last returns the last revision of the Helm release, or nil if it is not installed.
+func (c *FakeHelmClient) last(key releaseKey) *helmRelease.Release {
+	revisions := c.releases[key]
+	if len(revisions) == 0 {
+		return nil
+	}
+
+	return revisions[len(revisions)-1]
+}
+
+// list returns copies of the last revision of each Helm release on the workload Cluster in the namespace, or in all
+// namespaces if it is empty, sorted by namespace and name.
+func (c *FakeHelmClient) list(cluster types.NamespacedName, namespace string) []*helmRelease.Release {
+	var releases []*helmRelease.Release
+	for key := range c.releases {
+		if key.cluster == cluster && (namespace == "" || key.namespace == namespace) {
+			releases = append(releases, copyRelease(c.last(key)))
+		}
+	}
+	sort.Slice(releases, func(i, j int) bool {
+		if releases[i].Namespace != releases[j].Namespace {
+			return releases[i].Namespace < releases[j].Namespace
+		}
+
+		return releases[i].Name < releases[j].Name
+	})
+
+	return releases
+}
+
+// copyRelease returns a copy of the release whose Info can be changed without changing the recorded release.
+func copyRelease(release *helmRelease.Release) *helmRelease.Release {
+	if release == nil {
+		return nil
+	}
+
+	out := *release
+	if release.Info != nil {
+		info := *release.Info
+		out.Info = &info
+	}
+
+	return &out
+}
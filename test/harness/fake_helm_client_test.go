@@ -0,0 +1,129 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harness
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	helmRelease "helm.sh/helm/v3/pkg/release"
+	helmDriver "helm.sh/helm/v3/pkg/storage/driver"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	addonsv1alpha1 "sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"sigs.k8s.io/cluster-api-addon-provider-helm/internal"
+)
+
+func TestFakeHelmClient(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	cluster := types.NamespacedName{Namespace: "default", Name: "workload-cluster-0"}
+	restConfig := &rest.Config{Host: "https://127.0.0.1:6443"}
+	c := NewFakeHelmClient()
+	c.registerCluster(restConfig.Host, cluster)
+
+	spec := addonsv1alpha1.HelmReleaseProxySpec{
+		ClusterRef:       corev1.ObjectReference{Namespace: cluster.Namespace, Name: cluster.Name},
+		ChartName:        "nginx",
+		Version:          "1.0.0",
+		ReleaseName:      "nginx",
+		ReleaseNamespace: "nginx",
+		Values:           "replicas: 1\n",
+	}
+
+	_, err := c.GetHelmRelease(ctx, restConfig, spec)
+	g.Expect(err).To(MatchError(helmDriver.ErrReleaseNotFound))
+
+	release, err := c.InstallOrUpgradeHelmRelease(ctx, restConfig, "", "", internal.RepositoryAuth{}, spec)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(release.Version).To(Equal(1))
+	g.Expect(release.Info.Status).To(Equal(helmRelease.StatusDeployed))
+	g.Expect(release.Config).To(HaveKeyWithValue("replicas", BeEquivalentTo(1)))
+
+	// Installing the same chart and values again does not create a revision.
+	release, err = c.InstallOrUpgradeHelmRelease(ctx, restConfig, "", "", internal.RepositoryAuth{}, spec)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(release.Version).To(Equal(1))
+
+	spec.Values = "replicas: 2\n"
+	release, err = c.InstallOrUpgradeHelmRelease(ctx, restConfig, "", "", internal.RepositoryAuth{}, spec)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(release.Version).To(Equal(2))
+
+	history := c.History(cluster, "nginx", "nginx")
+	g.Expect(history).To(HaveLen(2))
+	g.Expect(history[0].Info.Status).To(Equal(helmRelease.StatusSuperseded))
+	g.Expect(history[1].Info.Status).To(Equal(helmRelease.StatusDeployed))
+
+	// Releases are listed by the host of the REST config when the spec has no Cluster reference, as in discovery.
+	releases, err := c.ListHelmReleases(ctx, restConfig, addonsv1alpha1.HelmReleaseProxySpec{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(releases).To(HaveLen(1))
+	g.Expect(releases[0].Name).To(Equal("nginx"))
+
+	release, err = c.RollbackHelmRelease(ctx, restConfig, spec, 1)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(release.Version).To(Equal(3))
+	g.Expect(release.Config).To(HaveKeyWithValue("replicas", BeEquivalentTo(1)))
+
+	c.FailInstalls(cluster, errors.New("install failed"))
+	_, err = c.InstallOrUpgradeHelmRelease(ctx, restConfig, "", "", internal.RepositoryAuth{}, spec)
+	g.Expect(err).To(MatchError("install failed"))
+	c.FailInstalls(cluster, nil)
+
+	_, err = c.UninstallHelmRelease(ctx, restConfig, spec)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(c.Release(cluster, "nginx", "nginx")).To(BeNil())
+	g.Expect(c.Releases(cluster)).To(BeEmpty())
+}
+
+func TestFakeHelmClientGeneratedReleaseName(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	cluster := types.NamespacedName{Namespace: "default", Name: "workload-cluster-0"}
+	c := NewFakeHelmClient()
+	spec := addonsv1alpha1.HelmReleaseProxySpec{
+		ClusterRef:       corev1.ObjectReference{Namespace: cluster.Namespace, Name: cluster.Name},
+		ChartName:        "nginx",
+		Version:          "1.0.0",
+		ReleaseNamespace: "default",
+	}
+
+	release, err := c.InstallOrUpgradeHelmRelease(context.Background(), &rest.Config{}, "", "", internal.RepositoryAuth{}, spec)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(release.Name).To(Equal("nginx-1"))
+	g.Expect(c.Release(cluster, "default", "nginx-1")).NotTo(BeNil())
+}
+
+func TestKubeconfigFor(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	data, err := kubeconfigFor("workload-cluster-0", "http://127.0.0.1:41234")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	restConfig, err := clientcmd.RESTConfigFromKubeConfig(data)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(restConfig.Host).To(Equal("http://127.0.0.1:41234"))
+}